@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+func runBurp(cfg *modes.Config, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing export file argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank burp <items.xml | export.har>"))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[0]))
+
+	result, err := modes.RunBurp(cfg, args[0])
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	var s summary
+	s.line("Items in export:", result.Items)
+	s.line("Scripts imported:", result.ScriptsSaved)
+	s.line("Maps imported:", result.MapsSaved)
+	s.count("Not JS or maps:", result.Skipped)
+	s.count("Out of scope:", result.OutOfScope)
+	s.count("Known third-party:", result.ThirdParty)
+	s.count("Filtered:", result.Filtered)
+	s.line("Maps processed:", result.MapsProcessed)
+	s.restored(cfg, result.SourcesRestored)
+	s.line("Assets extracted:", result.AssetsExtracted)
+	s.matched(cfg, result.SourcesMatched)
+	s.count("Sources filtered:", result.SourcesFiltered)
+	s.count("Comments flagged:", result.CommentsFound)
+	s.count("GraphQL operations:", result.GraphQLOps)
+	s.count("Hosts referenced:", result.HostsFound)
+	s.count("Secrets found:", result.SecretsFound)
+	s.count("Env vars:", result.EnvVarsExtracted)
+	s.deduped(result.DedupedBytes)
+	s.bundler(result.Maps)
+	s.metro(result.Maps)
+	s.licenses(result.Licenses, result.Copyleft)
+	s.budget(result.BudgetExceeded, result.BudgetSkipped)
+	s.errors(result.Errors, cfg.Verbose)
+	s.warnings(result.Warnings, cfg.Verbose)
+	s.outputsAll(result.OutputDirs)
+	s.print()
+	printStats(result.Stats, cfg.Verbose)
+	printNoRestoreHint(cfg, result.OutputDirs...)
+	writeSARIF(*sarifPath, cfg.OutputRoot, cfg.Verbose, result.OutputDirs...)
+	writeSBOM(*sbomPath, args[0], cfg.OutputRoot, cfg.Verbose, result.OutputDirs...)
+	openOutput(cfg, result.OutputDirs...)
+	notify("burp", localJSON(cfg, args[0], &result.LocalResult))
+	exitIfDiskStopped(result.Errors)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// requireChrome exits with installation hints when the browser needed for
+// discovery can't be found, before any output is written.
+func requireChrome(cfg *modes.Config) {
+	if _, err := fetch.FindChrome(cfg.ChromePath); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		printChromeHint(err)
+		os.Exit(1)
+	}
+}
+
+// printChromeHint explains how to fix a missing or crashing Chrome. Other
+// errors print nothing.
+func printChromeHint(err error) {
+	switch {
+	case errors.Is(err, fetch.ErrChromeNotFound):
+		fmt.Fprintln(display, ui.DimStyle.Render("url, watch, and serve load pages in headless Chrome. Either:"))
+		fmt.Fprintln(display, ui.DimStyle.Render("  - install Google Chrome or Chromium (e.g. apt install chromium)"))
+		fmt.Fprintln(display, ui.DimStyle.Render("  - point -chrome-path at an existing binary"))
+		fmt.Fprintln(display, ui.DimStyle.Render("  - use single, map, or local, which don't need a browser"))
+	case errors.Is(err, fetch.ErrChromeStart):
+		fmt.Fprintln(display, ui.DimStyle.Render("Chrome exited during startup; its last output is shown above."))
+		fmt.Fprintln(display, ui.DimStyle.Render("Missing shared libraries are the usual cause on minimal servers;"))
+		fmt.Fprintln(display, ui.DimStyle.Render("install the distribution's chromium package or set -chrome-path."))
+	}
+}
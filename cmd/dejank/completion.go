@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// commandSpec describes a subcommand for help and shell completion.
+type commandSpec struct {
+	name  string
+	desc  string
+	flags func() *flag.FlagSet // Subcommand flags, nil if the command takes none
+	args  []string             // Fixed positional values to complete, if any
+}
+
+// commands lists every subcommand. Completion scripts are generated from the
+// FlagSets returned here so new flags are picked up automatically.
+var commands = []commandSpec{
+	{name: "url", desc: "Crawl webpage, extract sourcemaps from all scripts", flags: func() *flag.FlagSet { return newURLFlags().fs }},
+	{name: "single", desc: "Extract sourcemap from a single script URL"},
+	{name: "local", desc: "Process local .js and .map files"},
+	{name: "map", desc: "Restore sources from a known .map URL or file", flags: func() *flag.FlagSet { return newMapFlags().fs }},
+	{name: "analyze", desc: "Run the analyzers (stats, endpoints, secrets, ...) over an output directory", flags: func() *flag.FlagSet { return newAnalyzeFlags().fs }},
+	{name: "verify", desc: "Check downloaded files against their recorded SHA-256"},
+	{name: "verify-map", desc: "Check that a sourcemap's mappings agree with its bundle", flags: func() *flag.FlagSet { return newVerifyMapFlags().fs }},
+	{name: "format", desc: "Pretty-print restored sources in place, vendor code included"},
+	{name: "extract-assets", desc: "Decode embedded assets from restored sources", flags: func() *flag.FlagSet { return newExtractAssetsFlags().fs }},
+	{name: "env", desc: "Extract env vars and secrets from any directory of JS", flags: func() *flag.FlagSet { return newEnvFlags().fs }},
+	{name: "burp", desc: "Restore from a Burp XML items export or a HAR file, without contacting the target"},
+	{name: "merge", desc: "Merge one domain directory's downloads into another, e.g. www. into the apex, and restore"},
+	{name: "report", desc: "Write a self-contained HTML page for browsing a run's sources and findings", flags: func() *flag.FlagSet { return newReportFlags().fs }},
+	{name: "watch", desc: "Re-run url mode on a schedule and report changes", flags: func() *flag.FlagSet { return newWatchFlags().fs }},
+	{name: "serve", desc: "Run url mode jobs submitted over HTTP", flags: func() *flag.FlagSet { return newServeFlags().fs }},
+	{name: "config", desc: "Show the effective configuration", args: []string{"show"}},
+	{name: "completion", desc: "Print a shell completion script", args: []string{"bash", "zsh", "fish"}},
+	{name: "help", desc: "Show this help"},
+}
+
+// flagValueHints lists the accepted values for flags with a fixed set of choices.
+var flagValueHints = map[string][]string{
+	"events":        {"ndjson"},
+	"scope":         {"same-origin", "same-site", "all"},
+	"normalize-eol": {"keep", "lf", "crlf"},
+	"ua":            fetch.UserAgentPresetNames(),
+	"emulate":       {"desktop", "mobile", "both"},
+	"hook":          hookPointPrefixes(),
+	"format":        reportFormats,
+}
+
+// hookPointPrefixes returns "<point>=" for every hook point.
+func hookPointPrefixes() []string {
+	var out []string
+	for _, p := range modes.HookPoints {
+		out = append(out, string(p)+"=")
+	}
+	return out
+}
+
+// completionFlag is the completion-relevant description of a single flag.
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	kind   string   // "file", "dir", or "" for free-form values
+	values []string // Fixed choices, if any
+}
+
+// collectFlags describes every flag in fs, sorted by name.
+func collectFlags(fs *flag.FlagSet) []completionFlag {
+	var out []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		placeholder, usage := flag.UnquoteUsage(f)
+		cf := completionFlag{name: f.Name, usage: usage, values: flagValueHints[f.Name]}
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			cf.isBool = true
+		}
+		switch placeholder {
+		case "file", "dir":
+			cf.kind = placeholder
+		}
+		out = append(out, cf)
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+func runCompletion(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing shell argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank completion bash|zsh|fish"))
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Fprint(os.Stdout, bashCompletion(flag.CommandLine))
+	case "zsh":
+		fmt.Fprint(os.Stdout, zshCompletion(flag.CommandLine))
+	case "fish":
+		fmt.Fprint(os.Stdout, fishCompletion(flag.CommandLine))
+	default:
+		fmt.Fprintln(display, ui.Error(fmt.Sprintf("Unsupported shell: %s (supported: bash, zsh, fish)", args[0])))
+		os.Exit(1)
+	}
+}
+
+// bashCompletion generates a bash completion script.
+func bashCompletion(global *flag.FlagSet) string {
+	globals := collectFlags(global)
+
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	// Value completion is keyed on the previous word; collect every flag that takes a value.
+	valueCases := make(map[string]string)
+	addValueCases := func(flags []completionFlag) {
+		for _, f := range flags {
+			if f.isBool {
+				continue
+			}
+			key := "-" + f.name
+			if _, exists := valueCases[key]; exists {
+				continue
+			}
+			switch {
+			case len(f.values) > 0:
+				valueCases[key] = fmt.Sprintf(`COMPREPLY=($(compgen -W "%s" -- "$cur"))`, strings.Join(f.values, " "))
+			case f.kind == "dir":
+				valueCases[key] = `COMPREPLY=($(compgen -d -- "$cur"))`
+			case f.kind == "file":
+				valueCases[key] = `COMPREPLY=($(compgen -f -- "$cur"))`
+			default:
+				valueCases[key] = `COMPREPLY=()`
+			}
+		}
+	}
+	addValueCases(globals)
+	for _, c := range commands {
+		if c.flags != nil {
+			addValueCases(collectFlags(c.flags()))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# bash completion for dejank\n")
+	sb.WriteString("_dejank() {\n")
+	sb.WriteString("    local cur prev cmd i\n")
+	sb.WriteString("    COMPREPLY=()\n")
+	sb.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	sb.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	sb.WriteString("    cmd=\"\"\n")
+	sb.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	sb.WriteString("        case \"${COMP_WORDS[i]}\" in\n")
+	fmt.Fprintf(&sb, "            %s) cmd=\"${COMP_WORDS[i]}\"; break ;;\n", strings.Join(names, "|"))
+	sb.WriteString("        esac\n")
+	sb.WriteString("    done\n\n")
+
+	sb.WriteString("    case \"$prev\" in\n")
+	keys := make([]string, 0, len(valueCases))
+	for k := range valueCases {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "        %s) %s; return 0 ;;\n", k, valueCases[k])
+	}
+	sb.WriteString("    esac\n\n")
+
+	sb.WriteString("    if [[ -z \"$cmd\" ]]; then\n")
+	fmt.Fprintf(&sb, "        COMPREPLY=($(compgen -W \"%s %s\" -- \"$cur\"))\n", flagWords(globals), strings.Join(names, " "))
+	sb.WriteString("        return 0\n")
+	sb.WriteString("    fi\n\n")
+
+	sb.WriteString("    case \"$cmd\" in\n")
+	for _, c := range commands {
+		var words []string
+		if c.flags != nil {
+			words = append(words, flagWords(collectFlags(c.flags())))
+		}
+		words = append(words, c.args...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.name, strings.Join(words, " "))
+	}
+	sb.WriteString("    esac\n")
+	sb.WriteString("    return 0\n")
+	sb.WriteString("}\n")
+	sb.WriteString("complete -o default -F _dejank dejank\n")
+
+	return sb.String()
+}
+
+// zshCompletion wraps the bash script using zsh's bash compatibility layer.
+func zshCompletion(global *flag.FlagSet) string {
+	return "#compdef dejank\n" +
+		"autoload -U +X bashcompinit && bashcompinit\n" +
+		bashCompletion(global)
+}
+
+// fishCompletion generates a fish completion script.
+func fishCompletion(global *flag.FlagSet) string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# fish completion for dejank\n")
+	sb.WriteString("complete -c dejank -f\n")
+
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "complete -c dejank -n '__fish_use_subcommand' -a %s -d %s\n", c.name, fishQuote(c.desc))
+	}
+
+	for _, f := range collectFlags(global) {
+		sb.WriteString(fishFlag(f, "__fish_use_subcommand"))
+	}
+
+	for _, c := range commands {
+		cond := "__fish_seen_subcommand_from " + c.name
+		if c.flags != nil {
+			for _, f := range collectFlags(c.flags()) {
+				sb.WriteString(fishFlag(f, cond))
+			}
+		}
+		if len(c.args) > 0 {
+			fmt.Fprintf(&sb, "complete -c dejank -n '%s' -a %s\n", cond, fishQuote(strings.Join(c.args, " ")))
+		}
+	}
+
+	return sb.String()
+}
+
+// fishFlag renders a single fish complete line for a flag.
+func fishFlag(f completionFlag, cond string) string {
+	line := fmt.Sprintf("complete -c dejank -n '%s' -o %s -d %s", cond, f.name, fishQuote(f.usage))
+	switch {
+	case f.isBool:
+	case len(f.values) > 0:
+		line += " -x -a " + fishQuote(strings.Join(f.values, " "))
+	case f.kind == "file" || f.kind == "dir":
+		line += " -r -F"
+	default:
+		line += " -x"
+	}
+	return line + "\n"
+}
+
+// flagWords returns the flags as space-separated "-name" words.
+func flagWords(flags []completionFlag) string {
+	words := make([]string, 0, len(flags))
+	for _, f := range flags {
+		words = append(words, "-"+f.name)
+	}
+	return strings.Join(words, " ")
+}
+
+// fishQuote single-quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testGlobalFlags stands in for the global flags main registers on
+// flag.CommandLine, with one flag of each kind completion distinguishes.
+func testGlobalFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("dejank", flag.ContinueOnError)
+	fs.Bool("v", false, "Enable verbose output")
+	fs.String("o", ".", "Output `dir`ectory")
+	fs.String("scope-file", "", "Read the scope from `file`")
+	fs.String("scope", "same-site", "Which hosts to fetch from")
+	fs.String("ua", "", "User-Agent preset or string")
+	return fs
+}
+
+// bashPath returns the path of bash, skipping the test without it.
+func bashPath(t *testing.T) string {
+	t.Helper()
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	return bash
+}
+
+func TestBashCompletionParses(t *testing.T) {
+	bash := bashPath(t)
+	for name, script := range map[string]string{
+		"bash": bashCompletion(testGlobalFlags()),
+		"zsh":  zshCompletion(testGlobalFlags()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dejank."+name)
+			if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command(bash, "-n", path).CombinedOutput(); err != nil {
+				t.Errorf("bash -n: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestBashCompletionCompletes(t *testing.T) {
+	bash := bashPath(t)
+	path := filepath.Join(t.TempDir(), "dejank.bash")
+	if err := os.WriteFile(path, []byte(bashCompletion(testGlobalFlags())), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		words   string // The command line, the word being completed last
+		want    []string
+		notWant []string
+	}{
+		{"dejank ur", []string{"url"}, []string{"map"}},
+		{"dejank -sc", []string{"-scope", "-scope-file"}, nil},
+		{"dejank -scope s", []string{"same-origin", "same-site"}, []string{"all"}},
+		{"dejank -ua ", []string{"chrome-win", "iphone"}, nil},
+		{"dejank completion ", []string{"bash", "zsh", "fish"}, nil},
+		{"dejank config s", []string{"show"}, nil},
+		{"dejank report -format ", reportFormats, nil},
+		{"dejank -v url -dr", []string{"-dry-run"}, []string{"-scope"}},
+		{"dejank url -plan ", nil, []string{"-dry-run"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.words, func(t *testing.T) {
+			words := strings.Split(tt.words, " ")
+			script := `source "$1"; shift; COMP_WORDS=("$@"); COMP_CWORD=$((${#COMP_WORDS[@]} - 1)); _dejank; printf '%s\n' "${COMPREPLY[@]}"`
+			out, err := exec.Command(bash, append([]string{"-c", script, "bash", path}, words...)...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			got := make(map[string]bool)
+			for _, w := range strings.Fields(string(out)) {
+				got[w] = true
+			}
+			for _, w := range tt.want {
+				if !got[w] {
+					t.Errorf("%q missing from %q", w, strings.Fields(string(out)))
+				}
+			}
+			for _, w := range tt.notWant {
+				if got[w] {
+					t.Errorf("%q offered", w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/config"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// optionAliases maps descriptive config keys to their short flag names.
+var optionAliases = map[string]string{
+	"output":  "o",
+	"force":   "f",
+	"verbose": "v",
+	"debug":   "vv",
+}
+
+// unconfigurable lists global flags that make no sense in a config file.
+var unconfigurable = map[string]bool{
+	"config":  true,
+	"profile": true,
+	"version": true,
+}
+
+// extractFlag removes every occurrence of -name/--name (with a separate or
+// "=" value) from args, returning the last value given.
+func extractFlag(args []string, name string) (string, []string) {
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if trimmed == arg {
+			rest = append(rest, arg)
+			continue
+		}
+		if trimmed == name && i+1 < len(args) {
+			value = args[i+1]
+			i++
+			continue
+		}
+		if v, ok := strings.CutPrefix(trimmed, name+"="); ok {
+			value = v
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return value, rest
+}
+
+// loadConfig reads the config file and resolves the requested profile. A
+// missing default config file is not an error; an explicit -config that
+// cannot be read is.
+func loadConfig(path, profile string) (*config.File, *config.Section, error) {
+	explicit := path != ""
+	if !explicit {
+		path = config.DefaultPath()
+	}
+
+	if path == "" {
+		if profile != "" {
+			return nil, nil, fmt.Errorf("cannot use -profile %s: no config file found", profile)
+		}
+		return nil, nil, nil
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) && !explicit {
+		if profile != "" {
+			return nil, nil, fmt.Errorf("cannot use -profile %s: %s does not exist", profile, path)
+		}
+		return nil, nil, nil
+	}
+
+	file, err := config.Load(path, optionAliases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	section, err := file.Resolve(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateSection(file.Path, section, flag.CommandLine, ""); err != nil {
+		return nil, nil, err
+	}
+	for name, sub := range section.Commands {
+		spec := findCommand(name)
+		if spec == nil || spec.flags == nil {
+			return nil, nil, fmt.Errorf("%s: unknown command section %q%s", file.Path, name, commandSectionHint())
+		}
+		fs := spec.flags()
+		if err := validateSection(file.Path, sub, fs, name); err != nil {
+			return nil, nil, err
+		}
+		// Catch bad values now rather than when the command runs
+		fs.SetOutput(io.Discard)
+		if err := fs.Parse(configArgs(section, name, nil)); err != nil {
+			return nil, nil, fmt.Errorf("%s: invalid %s options: %w", file.Path, name, err)
+		}
+	}
+
+	return file, section, nil
+}
+
+// validateSection checks that every option in section names a flag in fs.
+func validateSection(path string, section *config.Section, fs *flag.FlagSet, command string) error {
+	for _, name := range section.Keys() {
+		if fs.Lookup(name) != nil && !(command == "" && unconfigurable[name]) {
+			continue
+		}
+
+		where := "global options"
+		if command != "" {
+			where = command + " options"
+		}
+		opt := section.Options[name]
+		msg := fmt.Sprintf("%s:%d: unknown option %q in %s", path, opt.Line, opt.Key, where)
+		if suggestion := suggestOption(opt.Key, fs, command == ""); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// suggestOption returns the flag name closest to key, if any is close enough.
+func suggestOption(key string, fs *flag.FlagSet, global bool) string {
+	var candidates []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if !(global && unconfigurable[f.Name]) {
+			candidates = append(candidates, f.Name)
+		}
+	})
+	if global {
+		for alias := range optionAliases {
+			candidates = append(candidates, alias)
+		}
+	}
+	sort.Strings(candidates)
+
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(key, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// applyConfig sets global flags from the config section. Flags given on the
+// command line keep their values.
+func applyConfig(section *config.Section, explicit map[string]bool) error {
+	if section == nil {
+		return nil
+	}
+	for _, name := range section.Keys() {
+		if explicit[name] {
+			continue
+		}
+		opt := section.Options[name]
+		for _, v := range opt.Values {
+			if err := flag.Set(name, v); err != nil {
+				return fmt.Errorf("invalid value %q for %s (line %d): %w", v, opt.Key, opt.Line, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configArgs prepends a command's config options to its arguments so that
+// options given on the command line, which are parsed later, win.
+func configArgs(section *config.Section, command string, args []string) []string {
+	if section == nil {
+		return args
+	}
+	sub := section.Command(command)
+	var out []string
+	for _, name := range sub.Keys() {
+		for _, v := range sub.Options[name].Values {
+			out = append(out, "-"+name+"="+v)
+		}
+	}
+	return append(out, args...)
+}
+
+// explicitFlags returns the names of the global flags set on the command line.
+func explicitFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// findCommand returns the spec for a subcommand name.
+func findCommand(name string) *commandSpec {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// commandSectionHint lists the subcommands that accept a config section.
+func commandSectionHint() string {
+	var names []string
+	for _, c := range commands {
+		if c.flags != nil {
+			names = append(names, c.name)
+		}
+	}
+	return fmt.Sprintf(" (valid: %s)", strings.Join(names, ", "))
+}
+
+// runConfig implements "dejank config show".
+func runConfig(file *config.File, section *config.Section, profile string, explicit map[string]bool, args []string) {
+	if len(args) < 1 || args[0] != "show" {
+		fmt.Fprintln(display, ui.Error("Missing or unknown config subcommand"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank [-config file] config show [-profile name]"))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display)
+
+	if file == nil {
+		fmt.Fprintln(display, ui.SummaryLine("Config file:", "none ("+config.DefaultPath()+" not found)"))
+	} else {
+		fmt.Fprintln(display, ui.SummaryLine("Config file:", file.Path))
+		if names := file.ProfileNames(); len(names) > 0 {
+			fmt.Fprintln(display, ui.SummaryLine("Profiles:", strings.Join(names, ", ")))
+		}
+	}
+	if profile != "" {
+		fmt.Fprintln(display, ui.SummaryLine("Active profile:", profile))
+	}
+	fmt.Fprintln(display)
+
+	if section == nil {
+		section = &config.Section{}
+	}
+
+	fmt.Fprintln(display, ui.AccentStyle.Render("GLOBAL OPTIONS"))
+	printEffective(flag.CommandLine, globalSources(section, explicit))
+
+	for _, c := range commands {
+		if c.flags == nil {
+			continue
+		}
+		sub := section.Command(c.name)
+		fs := c.flags()
+		if err := fs.Parse(configArgs(section, c.name, nil)); err != nil {
+			fmt.Fprintln(display, ui.Error(fmt.Sprintf("invalid %s options: %v", c.name, err)))
+			os.Exit(1)
+		}
+
+		sources := make(map[string]string)
+		for name, v := range sub.Options {
+			sources[name] = v.Source
+		}
+
+		fmt.Fprintln(display)
+		fmt.Fprintln(display, ui.AccentStyle.Render(strings.ToUpper(c.name)+" OPTIONS"))
+		printEffective(fs, sources)
+	}
+	fmt.Fprintln(display)
+}
+
+// redactArgs returns a command line with the values of sensitive flags,
+// such as headers, cookies, and tokens, and the credentials and query
+// strings of URLs masked, for recording in run-info.json.
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	sensitiveNext := false
+	for i, arg := range args {
+		switch {
+		case sensitiveNext:
+			out[i] = config.Redact("secret", arg)
+			sensitiveNext = false
+		case i > 0 && strings.HasPrefix(arg, "-"):
+			name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if hasValue {
+				out[i] = arg[:len(arg)-len(value)] + config.Redact(name, value)
+			} else {
+				out[i] = arg
+				sensitiveNext = config.Sensitive(name)
+			}
+		default:
+			out[i] = config.Redact("", arg)
+		}
+	}
+	return out
+}
+
+// effectiveOptions returns the value of every global option after the
+// config file was applied, with secrets redacted.
+func effectiveOptions() map[string]string {
+	options := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		if !unconfigurable[f.Name] {
+			options[f.Name] = config.Redact(f.Name, f.Value.String())
+		}
+	})
+	return options
+}
+
+// globalSources reports where each configured global flag got its value.
+func globalSources(section *config.Section, explicit map[string]bool) map[string]string {
+	sources := make(map[string]string)
+	for name, v := range section.Options {
+		sources[name] = v.Source
+	}
+	for name := range explicit {
+		sources[name] = "command line"
+	}
+	return sources
+}
+
+// printEffective prints every flag in fs with its value and origin.
+func printEffective(fs *flag.FlagSet, sources map[string]string) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if unconfigurable[f.Name] {
+			return
+		}
+		source, ok := sources[f.Name]
+		if !ok {
+			source = "default"
+		}
+		value := config.Redact(f.Name, f.Value.String())
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(display, "  %s %s %s\n",
+			ui.FormatUsage(fmt.Sprintf("%-18s", "-"+f.Name)),
+			ui.TextStyle.Render(value),
+			ui.DimStyle.Render("("+source+")"))
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/config"
+)
+
+// writeConfig writes a config file into a temporary directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigAliases(t *testing.T) {
+	path := writeConfig(t, `defaults:
+  output: ~/recon
+  csv: true
+profiles:
+  acme:
+    o: /tmp/acme
+    watch:
+      interval: 1h
+`)
+	file, err := config.Load(path, optionAliases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := file.Resolve("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The profile's o replaces the defaults' output
+	if got := strings.Join(section.Keys(), ","); got != "csv,o" {
+		t.Errorf("keys = %s, want csv,o", got)
+	}
+	if o := section.Options["o"]; len(o.Values) != 1 || o.Values[0] != "/tmp/acme" || o.Key != "o" {
+		t.Errorf("o = %+v, want /tmp/acme from the profile", o)
+	}
+	if got := strings.Join(configArgs(section, "watch", []string{"https://example.com"}), " "); got != "-interval=1h https://example.com" {
+		t.Errorf("configArgs = %s", got)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"alias and flag", "defaults:\n  output: a\n  o: b\n", "o and output set the same option"},
+		{"unknown command option", "defaults:\n  watch:\n    intervall: 1h\n", `unknown option "intervall" in watch options (did you mean "interval"?)`},
+		{"alias in command", "defaults:\n  watch:\n    output: a\n", `unknown option "output" in watch options`},
+		{"invalid command value", "defaults:\n  watch:\n    interval: soon\n", "invalid watch options"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := loadConfig(writeConfig(t, tt.content), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
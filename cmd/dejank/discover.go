@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// runDiscover runs url -discover-only: it loads each target and prints the
+// script and map URLs found, one per line or as JSON, with nothing else on
+// stdout so the list can be piped to other tools.
+func runDiscover(cfg *modes.Config, targets []string, exportTo string, jsonOut bool) {
+	logToStderr(cfg)
+	requireChrome(cfg)
+
+	var urls []modes.DiscoveredURL
+	failed := false
+	for _, target := range targets {
+		found, err := modes.RunDiscover(cfg, target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, ui.Error(fmt.Sprintf("%s: %v", target, err)))
+			failed = true
+			continue
+		}
+		urls = append(urls, found...)
+	}
+
+	if jsonOut {
+		data, _ := json.MarshalIndent(schema.Items(urls), "", "  ")
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		for _, u := range modes.DiscoveredURLLines(urls) {
+			fmt.Fprintln(os.Stdout, u)
+		}
+	}
+	if exportTo != "" {
+		if err := modes.WriteDiscoveredURLs(exportTo, urls, jsonOut); err != nil {
+			fmt.Fprintln(os.Stderr, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// exportURLs writes the URLs found by a url run for -export-urls, if set.
+// With asJSON the confirmation is left off stdout.
+func exportURLs(path string, urls []modes.DiscoveredURL, asJSON bool) {
+	if path == "" {
+		return
+	}
+	if err := modes.WriteDiscoveredURLs(path, urls, asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	if !asJSON {
+		fmt.Fprintln(display, ui.Success(fmt.Sprintf("Discovered URLs written to %s", path)))
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/findings"
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// envFlags holds the flags accepted by the env command.
+type envFlags struct {
+	fs         *flag.FlagSet
+	out        *string
+	showValues *bool
+}
+
+func newEnvFlags() *envFlags {
+	fs := newFlagSet("env")
+	return &envFlags{
+		fs:         fs,
+		out:        fs.String("out", modes.DefaultEnvDir, "Write .env and env.json to `dir`"),
+		showValues: fs.Bool("show-values", false, "Print variable values in full instead of redacted"),
+	}
+}
+
+func runEnv(cfg *modes.Config, args []string) {
+	f := newEnvFlags()
+	args = parseFlags(f.fs, args)
+
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing directory argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank env [-out <dir>] [-show-values] <dir | ->"))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[0]))
+
+	result, err := modes.RunEnv(cfg, args[0], modes.EnvOptions{OutputDir: *f.out, Stdin: os.Stdin})
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	width := 0
+	for _, v := range result.Vars {
+		width = max(width, len(v.Name))
+	}
+	for _, v := range result.Vars {
+		value := v.Value
+		if !*f.showValues {
+			value = findings.Redact(value)
+		}
+		fmt.Fprintf(display, "  %s %s\n", ui.InfoStyle.Render(fmt.Sprintf("%-*s", width, v.Name)), ui.TextStyle.Render(value))
+		if cfg.Verbose {
+			fmt.Fprintf(display, "  %s\n", ui.DimStyle.Render(fmt.Sprintf("%*s from %s", width, "", v.File)))
+		}
+	}
+	for _, finding := range result.Findings {
+		fmt.Fprintln(display, ui.Warning(fmt.Sprintf("%s (%s:%d)", finding.Message, finding.File, finding.Line)))
+	}
+	if len(result.Vars) > 0 || len(result.Findings) > 0 {
+		fmt.Fprintln(display)
+	}
+
+	var s summary
+	s.line("Files scanned:", result.Scanned)
+	s.line("Env vars:", len(result.Vars))
+	s.line("Findings:", len(result.Findings))
+	s.errors(result.Errors, cfg.Verbose)
+	if result.EnvFile != "" {
+		s.line("Output:", result.EnvFile)
+		s.line("", result.JSONFile)
+	}
+	s.print()
+}
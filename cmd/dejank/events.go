@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/modes"
+)
+
+// eventSchemaVersion is bumped whenever event field names or meanings change.
+const eventSchemaVersion = 1
+
+// event is a single line of the NDJSON event stream.
+type event struct {
+	Schema int         `json:"schema"`
+	Time   string      `json:"time"`
+	Event  string      `json:"event"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// eventWriter serializes progress events as newline-delimited JSON.
+type eventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	out io.Closer
+}
+
+// display receives human-readable output: banners, progress messages and
+// summaries. It is stdout unless the event stream is written there.
+var display io.Writer = os.Stdout
+
+// setupEvents enables the event stream for the given format. Events go to
+// dest if set, otherwise to stdout; in the latter case human-readable output,
+// the log included, goes to stderr so the stream stays machine-parseable.
+func setupEvents(cfg *modes.Config, format, dest string) (*eventWriter, error) {
+	if format == "" {
+		return nil, nil
+	}
+	if format != "ndjson" {
+		return nil, fmt.Errorf("unsupported event format: %s (supported: ndjson)", format)
+	}
+
+	w := &eventWriter{}
+	if dest == "" || dest == "-" {
+		w.enc = json.NewEncoder(os.Stdout)
+		display = os.Stderr
+		logToStderr(cfg)
+	} else {
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open event output: %w", err)
+		}
+		w.enc = json.NewEncoder(f)
+		w.out = f
+	}
+
+	cfg.OnProgress = chainProgress(cfg.OnProgress, w.emit)
+	return w, nil
+}
+
+// emit writes one event line.
+func (w *eventWriter) emit(name string, data interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enc.Encode(event{
+		Schema: eventSchemaVersion,
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		Event:  name,
+		Data:   data,
+	})
+}
+
+// Close flushes and closes the event destination.
+func (w *eventWriter) Close() {
+	if w != nil && w.out != nil {
+		w.out.Close()
+	}
+}
+
+// chainProgress returns a callback that invokes each non-nil callback in order.
+func chainProgress(callbacks ...modes.ProgressCallback) modes.ProgressCallback {
+	return func(event string, data interface{}) {
+		for _, cb := range callbacks {
+			if cb != nil {
+				cb(event, data)
+			}
+		}
+	}
+}
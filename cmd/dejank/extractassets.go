@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// extractAssetsFlags holds the flags accepted by the extract-assets command.
+type extractAssetsFlags struct {
+	fs      *flag.FlagSet
+	out     *string
+	baseURL *string
+}
+
+func newExtractAssetsFlags() *extractAssetsFlags {
+	fs := newFlagSet("extract-assets")
+	return &extractAssetsFlags{
+		fs:      fs,
+		out:     fs.String("out", "", "Write decoded assets to `dir` (default: the domain's extracted_assets)"),
+		baseURL: fs.String("base-url", "", "Replace webpack asset stubs with the real files from the site at `url`"),
+	}
+}
+
+func runExtractAssets(cfg *modes.Config, args []string) {
+	f := newExtractAssetsFlags()
+	args = parseFlags(f.fs, args)
+
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing directory argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank extract-assets [-out <dir>] [-base-url <url>] <domain-or-sources-dir>"))
+		os.Exit(1)
+	}
+	if *f.baseURL != "" {
+		if u, err := url.Parse(*f.baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fmt.Fprintln(display, ui.Error("-base-url must be an http or https URL"))
+			os.Exit(1)
+		}
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[0]))
+
+	result, err := modes.RunExtractAssets(cfg, args[0], modes.ExtractAssetsOptions{
+		OutputDir: *f.out,
+		BaseURL:   *f.baseURL,
+	})
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	var s summary
+	s.line("Scanned:", result.InputDir)
+	s.line("Assets extracted:", result.Extracted)
+	if *f.baseURL != "" {
+		s.line("Assets downloaded:", result.Downloaded)
+	}
+	s.count("Files filtered:", result.Filtered)
+	s.errors(result.Errors, cfg.Verbose)
+	s.line("Output:", result.OutputDir)
+	s.print()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// newFlagSet creates a FlagSet for a subcommand that prints styled errors.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseFlags parses subcommand flags that may appear before or after
+// positional arguments (e.g. "watch <url> -interval 6h") and returns the
+// positional arguments. Exits on invalid flags.
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				os.Exit(0)
+			}
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stringList collects the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// byteSize is a size flag: a number of bytes with an optional K, M, or G
+// suffix in powers of 1024, e.g. 512K or 2MB.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return ui.FormatBytes(int64(*b))
+}
+
+func (b *byteSize) Set(value string) error {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			s = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q, e.g. 2MB or 512K", value)
+	}
+	*b = byteSize(n * float64(mult))
+	return nil
+}
+
+// hookFlags collects repeated -hook point=command flags.
+type hookFlags []string
+
+func (h *hookFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *hookFlags) Set(value string) error {
+	point, command, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(command) == "" {
+		return fmt.Errorf("expected <point>=<command>, got %q", value)
+	}
+	if _, err := modes.ParseHookPoint(point); err != nil {
+		return err
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// apply registers each hook command on cfg.
+func (h hookFlags) apply(cfg *modes.Config) error {
+	for _, value := range h {
+		point, command, _ := strings.Cut(value, "=")
+		p, err := modes.ParseHookPoint(point)
+		if err != nil {
+			return err
+		}
+		fn, err := modes.CommandHook(command)
+		if err != nil {
+			return err
+		}
+		cfg.AddHook(p, fn)
+	}
+	return nil
+}
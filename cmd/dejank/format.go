@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+func runFormat(cfg *modes.Config, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing directory argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank format <domain-dir | restored_sources subdir>"))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[0]))
+
+	result, err := modes.RunFormat(cfg, args[0])
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	var s summary
+	s.line("Files formatted:", result.Formatted)
+	s.count("Left unchanged:", result.Unchanged)
+	s.errors(result.Errors, cfg.Verbose)
+	s.warnings(result.Warnings, cfg.Verbose)
+	s.line("Directory:", result.Dir)
+	s.print()
+}
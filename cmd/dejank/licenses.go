@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/licenses"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// licenses appends third-party packages per license family and calls out
+// copyleft packages, which clients often need to know about.
+func (s *summary) licenses(families map[string]int, copyleft []string) {
+	total := 0
+	for _, n := range families {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+
+	order := []string{licenses.FamilyMIT, licenses.FamilyApache, licenses.FamilyBSD, licenses.FamilyISC,
+		licenses.FamilyGPL, licenses.FamilyMPL, licenses.FamilyOther, licenses.FamilyUnknown}
+	parts := make([]string, 0, len(order))
+	for _, family := range order {
+		if n := families[family]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", family, ui.FormatCount(n)))
+		}
+	}
+	s.line("Licenses:", fmt.Sprintf("%s packages (%s)", ui.FormatCount(total), strings.Join(parts, ", ")))
+
+	if len(copyleft) > 0 {
+		slices.Sort(copyleft)
+		copyleft = slices.Compact(copyleft)
+		s.add(ui.Warning(fmt.Sprintf("Copyleft licenses in bundle: %s (see %s)", strings.Join(copyleft, ", "), licenses.File)))
+	}
+}
@@ -1,28 +1,129 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/thesavant42/dejank/internal/comments"
+	"github.com/thesavant42/dejank/internal/disk"
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/filter"
 	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/sourcemap"
 	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/warn"
 )
 
 var version = "1.0.10"
 
+// sarifPath is the optional SARIF output path shared by all run commands.
+var sarifPath *string
+
+// sbomPath is the optional CycloneDX SBOM output path shared by all run
+// commands.
+var sbomPath *string
+
 func main() {
 	// Global flags
 	verbose := flag.Bool("v", false, "Enable verbose output")
-	output := flag.String("o", ".", "Output directory")
+	debug := flag.Bool("vv", false, "Enable verbose output with debug details")
+	noRestore := flag.Bool("no-restore", false, "Download scripts and maps only; restore later with local mode")
+	output := flag.String("o", ".", "Output `dir`ectory")
 	force := flag.Bool("f", false, "Overwrite existing output")
+	breakLock := flag.Bool("break-lock", false, "Take over an output directory's lock left by a run that started over an hour ago")
+	gitSnapshot := flag.Bool("git", false, "Commit restored sources to a git repository after each run")
+	gitAtBase := flag.Bool("git-base", false, "Create the git repository at the domain directory instead of restored_sources")
+	versioned := flag.Bool("versioned", false, "Write each run to a timestamped directory under the domain directory")
+	csvExport := flag.Bool("csv", false, "Write scripts.csv and maps.csv into the domain directory")
+	scaffold := flag.Bool("scaffold", false, "Write tsconfig/jsconfig, .vscode/settings.json, and package.json after restore")
+	formatVendor := flag.Bool("format-vendor", false, "Pretty-print node_modules and ignoreList sources too, which are restored unformatted by default")
+	formatMaxSize := byteSize(sourcemap.DefaultFormatMaxSize)
+	flag.Var(&formatMaxSize, "format-max-size", "Write JS/TS sources larger than `size`, e.g. 2MB, unformatted (0 = no limit)")
+	verifyMaps := flag.Bool("verify", false, "Check a sample of each sourcemap's mappings against its script and warn about maps for another build")
+	sarifPath = flag.String("sarif", "", "Write env var, secret, path-leak, and service config findings to a SARIF `file`")
+	sbomPath = flag.String("sbom", "", "Write the recovered third-party packages to a CycloneDX 1.5 JSON SBOM `file`")
+	events := flag.String("events", "", "Stream progress events in the given format (ndjson)")
+	eventsOut := flag.String("events-out", "", "Write events to a `file` or named pipe instead of stdout")
+	notifyWebhook = flag.String("notify-webhook", "", "POST the run's JSON summary to `url` when it finishes")
+	notifyCmd = flag.String("notify-cmd", "", "Run `command` with the run's JSON summary on stdin when it finishes")
+	notifyDesktop = flag.Bool("notify-desktop", false, "Show a desktop notification when the run finishes (notify-send or osascript)")
+	openAfter = flag.Bool("open", false, "Open the output directory in the file manager when the run finishes (xdg-open, open, or explorer)")
+	var hooks hookFlags
+	flag.Var(&hooks, "hook", "Run `point=command` at a hook point (repeatable)")
+	scope := flag.String("scope", string(filter.ScopeSameOrigin), "Hosts to download scripts from: same-origin, same-site, or all")
+	var allowHosts stringList
+	flag.Var(&allowHosts, "allow-host", "Always download from `host`; *.example.com matches subdomains (repeatable)")
+	scopeFile := flag.String("scope-file", "", "Only send requests to hosts listed in `file`, one pattern per line; overrides -scope and -allow-host")
+	var includeURL, excludeURL, includeSource, excludeSource, only stringList
+	flag.Var(&includeURL, "include-url", "Only process script and map URLs matching `regex` (repeatable)")
+	flag.Var(&excludeURL, "exclude-url", "Skip script and map URLs matching `regex` (repeatable)")
+	noDenylist := flag.Bool("no-denylist", false, "Process well-known third-party scripts such as gtag.js and hotjar that are skipped by default")
+	var denylistAdd stringList
+	flag.Var(&denylistAdd, "denylist-add", "Also skip scripts matching `host[/path]`, e.g. *.example-cdn.com/tracker/** (repeatable)")
+	flag.Var(&includeSource, "include-source", "Only restore sources matching `glob` (repeatable)")
+	flag.Var(&excludeSource, "exclude-source", "Skip sources matching `glob`, e.g. **/node_modules/** (repeatable)")
+	flag.Var(&only, "only", "Only restore sources whose sources[] path, after any webpack:// prefix, matches `glob`, e.g. src/api/** or **/api/** (repeatable)")
+	collectComments := flag.Bool("comments", false, "Collect TODO/FIXME-style comments from restored sources into comments.json")
+	var commentPatterns stringList
+	flag.Var(&commentPatterns, "comment-pattern", "Collect comments matching `regex` into comments.json, replacing the defaults (repeatable)")
+	commentsVendor := flag.Bool("comments-vendor", false, "Also collect comments from node_modules and other vendor code")
+	dumpModules := flag.Bool("dump-modules", false, "Save the live page's webpack module sources under downloaded_site/modules (url mode)")
+	coverage := flag.Bool("coverage", false, "Record which scripts and original sources executed during page load to coverage.json (url mode)")
+	respectRobots := flag.Bool("respect-robots", false, "Skip script and map URLs disallowed by the origin's robots.txt (url mode)")
+	seenFile := flag.String("seen", "", "Skip sourcemaps whose URL or SHA-256 is listed in `file`, and append the maps each run processes (url mode)")
+	mapURLTemplate := flag.String("map-url-template", "", "Also look for the map of each script that names none at `url`, e.g. https://maps.example-cdn.com/{path}.map, with {path}, {file}, {dir}, and {host} placeholders (url mode)")
+	maxScripts := flag.Int("max-scripts", 0, "Stop after processing `n` scripts, keeping partial results (0 = no limit)")
+	maxMaps := flag.Int("max-maps", 0, "Stop after processing `n` sourcemaps, keeping partial results (0 = no limit)")
+	maxDuration := flag.Duration("max-duration", 0, "Stop processing scripts and maps after `duration`, e.g. 10m (0 = no limit)")
+	requestTimeout := flag.Duration("request-timeout", fetch.DefaultRequestTimeout, "Give up on a download that gets no response within `duration`")
+	clientCert := flag.String("client-cert", "", "Present the PEM certificate in `file` to servers requiring mutual TLS (downloads only, not the browser)")
+	clientKey := flag.String("client-key", "", "PEM private key `file` for -client-cert")
+	ipv4 := flag.Bool("4", false, "Download over IPv4 only (downloads only, not the browser)")
+	ipv6 := flag.Bool("6", false, "Download over IPv6 only (downloads only, not the browser)")
+	iface := flag.String("interface", "", "Send downloads from the address of network interface `name`, e.g. wg0 (downloads only, not the browser)")
+	sourceIP := flag.String("source-ip", "", "Send downloads from the local `address` (downloads only, not the browser)")
+	idleTimeout := flag.Duration("idle-timeout", fetch.DefaultIdleTimeout, "Give up on a download that receives no data for `duration`; downloads still receiving have no limit")
+	normalizeEOL := flag.String("normalize-eol", "keep", "Rewrite restored sources' line endings: keep, lf, or crlf (lf and crlf also strip BOMs)")
+	mapMtime := flag.Bool("map-mtime", false, "Give restored sources the modification time of the map they came from, so runs compare by mtime")
+	fileMode := flag.String("file-mode", "", "Octal permissions of restored sources regardless of the umask, e.g. 0640 (default: 0644 less the umask)")
+	dirMode := flag.String("dir-mode", "", "Octal permissions of directories restored sources are written to, e.g. 0750 (default: 0755 less the umask)")
+	keepQuery := flag.Bool("keep-query", false, "Add a hash of each script's and map's query string to its filename, so cache-busted builds are kept apart")
+	keepQueryParam := flag.String("keep-query-param", "", "Add only the value of query `param`eter, e.g. build, to filenames (implies -keep-query)")
+	splitByHost := flag.Bool("split-by-host", false, "Store each script and map under the domain directory of its own host (url mode)")
+	forceScan := flag.Bool("force-scan", false, "Let local mode without a target scan / or the home directory for domain directories")
+	flat := flag.Bool("flat", false, "Treat local mode's target as a plain directory of .map and script files, read recursively, and write its output to <dir>-dejank beside it, or under -o if given")
+	casDir := flag.String("cas", "", "Store downloaded files once in a shared content-addressed `dir` and hardlink each run to it")
+	browserTimeout := flag.Duration("browser-timeout", 0, "Give up on a browser discovery attempt after `duration` (default: 1m)")
+	navTimeout := flag.Duration("nav-timeout", 0, "Give up on loading the page in the browser after `duration` (default: 30s); scripts requested until then are still used")
+	settle := flag.Duration("settle", 0, "Wait `duration` after page load for lazy-loaded scripts (default: 5s)")
+	headful := flag.Bool("headful", false, "Show the browser window during discovery")
+	userDataDir := flag.String("user-data-dir", "", "Run Chrome with the profile in `dir`, e.g. one that has passed a bot protection challenge (default: a fresh profile)")
+	pageSnapshot := flag.Bool("snapshot", false, "Save a full-page screenshot and the rendered DOM to downloaded_site/page.png and page.html")
+	interact := flag.String("interact", "", "Run the click, type, wait, scroll, and press steps in a YAML or JSON `file` after page load")
+	autoScroll := flag.Bool("auto-scroll", false, "Scroll through the page after load so lazy-loaded chunks fire")
+	emulate := flag.String("emulate", "", "Load pages in the browser as `device`: desktop (default), mobile, or both, merging what each finds")
+	userAgent := flag.String("user-agent", "", "Send `ua` as the User-Agent from the browser and with every download")
+	uaPreset := flag.String("ua", "", "Use a browser's User-Agent by `name`: "+strings.Join(fetch.UserAgentPresetNames(), ", "))
+	acceptLanguage := flag.String("accept-language", "", "Send `langs`, e.g. en-US,en, as the Accept-Language from the browser and with every download")
+	locale := flag.String("locale", "", "Emulate `locale`, e.g. de-DE, in the browser; also sets Accept-Language unless -accept-language is given")
+	timezone := flag.String("timezone", "", "Emulate the IANA timezone `tz`, e.g. Europe/Berlin, in the browser")
+	geo := flag.String("geo", "", "Report the position `lat,lon` to the page's geolocation API, e.g. 52.52,13.40")
+	chromePath := flag.String("chrome-path", "", "Chrome or Chromium executable `file` for browser discovery (default: search PATH)")
+	configPath := flag.String("config", "", "Config `file` (default: ~/.config/dejank/config.yaml)")
+	profile := flag.String("profile", "", "Apply a named profile from the config file")
 	showVersion := flag.Bool("version", false, "Show version")
 	flag.Parse()
 
 	args := flag.Args()
 
 	if *showVersion {
-		fmt.Println(ui.Banner(version))
+		fmt.Fprintln(display, ui.Banner(version))
 		return
 	}
 
@@ -34,10 +135,256 @@ func main() {
 	command := args[0]
 	cmdArgs := args[1:]
 
+	// -config and -profile may also follow the command
+	if v, rest := extractFlag(cmdArgs, "config"); v != "" {
+		*configPath, cmdArgs = v, rest
+	}
+	if v, rest := extractFlag(cmdArgs, "profile"); v != "" {
+		*profile, cmdArgs = v, rest
+	}
+
+	explicit := explicitFlags()
+	configFile, configSection, err := loadConfig(*configPath, *profile)
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	if err := applyConfig(configSection, explicit); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	cmdArgs = configArgs(configSection, command, cmdArgs)
+
 	cfg := modes.DefaultConfig()
-	cfg.Verbose = *verbose
+	cfg.RunInfo = modes.NewRunInfo(version, redactArgs(os.Args), effectiveOptions())
+	cfg.Verbose = *verbose || *debug
+	cfg.Log = newLogger(*verbose, *debug)
 	cfg.OutputRoot = *output
 	cfg.Force = *force
+	cfg.BreakLock = *breakLock
+	cfg.GitSnapshot = *gitSnapshot || *gitAtBase
+	cfg.GitAtBase = *gitAtBase
+	cfg.Versioned = *versioned
+	cfg.CSVExport = *csvExport
+	cfg.NoRestore = *noRestore
+	cfg.Scaffold = *scaffold
+	cfg.VerifyMaps = *verifyMaps
+	cfg.FormatVendor = *formatVendor
+	cfg.FormatMaxSize = int64(formatMaxSize)
+	cfg.DumpModules = *dumpModules
+	cfg.Coverage = *coverage
+	cfg.RespectRobots = *respectRobots
+	cfg.MaxScripts = *maxScripts
+	cfg.MaxMaps = *maxMaps
+	cfg.MaxDuration = *maxDuration
+	cfg.ChromePath = *chromePath
+	cfg.BrowserTimeout = *browserTimeout
+	cfg.NavTimeout = *navTimeout
+	cfg.Settle = *settle
+	cfg.Headful = *headful
+	cfg.UserDataDir = *userDataDir
+	cfg.PageSnapshot = *pageSnapshot
+	cfg.AutoScroll = *autoScroll
+	cfg.AcceptLanguage = *acceptLanguage
+	cfg.CASDir = *casDir
+	cfg.ForceScan = *forceScan
+	cfg.Flat = *flat
+	if *flat && explicit["o"] {
+		cfg.FlatOutput = cfg.OutputRoot
+	}
+	cfg.SplitByHost = *splitByHost
+	cfg.KeepQuery = *keepQuery || *keepQueryParam != ""
+	cfg.KeepQueryParam = *keepQueryParam
+
+	if err := hooks.apply(cfg); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	rules, err := filter.New(includeURL, excludeURL, includeSource, excludeSource, only)
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	if !rules.Empty() {
+		cfg.Filter = rules
+	}
+
+	if cfg.Scope, err = filter.ParseScope(*scope); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	cfg.AllowHosts = allowHosts
+	if *scopeFile != "" {
+		if cfg.ScopeList, err = filter.LoadScopeList(*scopeFile); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		cfg.Client.SetAllow(cfg.ScopeList.AllowURL)
+	}
+	if *seenFile != "" {
+		if cfg.Seen, err = modes.LoadSeen(*seenFile); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+	if *mapURLTemplate != "" {
+		if cfg.MapURLTemplate, err = modes.ParseMapURLTemplate(*mapURLTemplate); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	denied := []string(denylistAdd)
+	if !*noDenylist {
+		denied = append(filter.DefaultDenylist(), denied...)
+	}
+	if len(denied) > 0 {
+		if cfg.Denylist, err = filter.NewDenylist(denied); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if *interact != "" {
+		if cfg.Interaction, err = fetch.LoadInteraction(*interact); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if cfg.Emulate, err = modes.ParseEmulation(*emulate); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	cfg.UserAgent = *userAgent
+	if *uaPreset != "" {
+		if cfg.UserAgent != "" {
+			fmt.Fprintln(display, ui.Error("use either -user-agent or -ua, not both"))
+			os.Exit(1)
+		}
+		if cfg.UserAgent, err = fetch.UserAgentPreset(*uaPreset); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+	// Downloads are sent as the emulated phone too, so a server that varies
+	// on User-Agent serves the scripts and maps the browser saw
+	if cfg.UserAgent == "" && cfg.Emulate == modes.EmulateMobile {
+		cfg.UserAgent = fetch.MobileUserAgent()
+	}
+	if *locale != "" {
+		if err := fetch.ValidateLocale(*locale); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		cfg.Locale = *locale
+		if cfg.AcceptLanguage == "" {
+			cfg.AcceptLanguage = fetch.AcceptLanguageFor(*locale)
+		}
+	}
+	if *timezone != "" {
+		if err := fetch.ValidateTimezone(*timezone); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		cfg.Timezone = *timezone
+	}
+	if *geo != "" {
+		if cfg.Geolocation, err = fetch.ParseGeolocation(*geo); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+	cfg.Client.SetTimeouts(*requestTimeout, *idleTimeout)
+	if *clientCert != "" || *clientKey != "" {
+		if *clientCert == "" || *clientKey == "" {
+			fmt.Fprintln(display, ui.Error("-client-cert and -client-key must be given together"))
+			os.Exit(1)
+		}
+		if err := cfg.Client.SetClientCertificate(*clientCert, *clientKey); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		switch command {
+		case "url", "watch", "serve":
+			fmt.Fprintln(display, ui.Warning("The browser does not present the client certificate: pages behind mutual TLS may not load during discovery, though downloads will present it"))
+		}
+	}
+	if *ipv4 || *ipv6 || *iface != "" || *sourceIP != "" {
+		if *ipv4 && *ipv6 {
+			fmt.Fprintln(display, ui.Error("use either -4 or -6, not both"))
+			os.Exit(1)
+		}
+		if *iface != "" && *sourceIP != "" {
+			fmt.Fprintln(display, ui.Error("use either -interface or -source-ip, not both"))
+			os.Exit(1)
+		}
+		network := ""
+		switch {
+		case *ipv4:
+			network = "tcp4"
+		case *ipv6:
+			network = "tcp6"
+		}
+		var local net.IP
+		switch {
+		case *iface != "":
+			local, err = fetch.InterfaceIP(*iface, network)
+		case *sourceIP != "":
+			local, err = fetch.SourceIP(*sourceIP, network)
+		}
+		if err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		cfg.Client.SetDialer(network, local)
+		switch command {
+		case "url", "watch", "serve":
+			fmt.Fprintln(display, ui.Warning("The browser connects with the system's default address family and route; only downloads use -4, -6, -interface, and -source-ip"))
+		}
+	}
+	cfg.Client.SetHeader("User-Agent", cfg.UserAgent)
+	cfg.Client.SetHeader("Accept-Language", cfg.AcceptLanguage)
+
+	if cfg.NormalizeEOL, err = sourcemap.ParseEOL(*normalizeEOL); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	cfg.MapMtime = *mapMtime
+	if *fileMode != "" {
+		if cfg.FileMode, err = sourcemap.ParseMode(*fileMode, 0600); err != nil {
+			fmt.Fprintln(display, ui.Error("-file-mode: "+err.Error()))
+			os.Exit(1)
+		}
+	}
+	if *dirMode != "" {
+		if cfg.DirMode, err = sourcemap.ParseMode(*dirMode, 0700); err != nil {
+			fmt.Fprintln(display, ui.Error("-dir-mode: "+err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if len(commentPatterns) > 0 {
+		if cfg.CommentPatterns, err = comments.Compile(commentPatterns); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+	}
+	cfg.CommentsVendor = *commentsVendor
+	cfg.Comments = *collectComments || len(commentPatterns) > 0 || *commentsVendor
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cfg.Context = ctx
+
+	eventOutput, err := setupEvents(cfg, *events, *eventsOut)
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+	defer eventOutput.Close()
 
 	switch command {
 	case "url":
@@ -46,58 +393,247 @@ func main() {
 		runSingle(cfg, cmdArgs)
 	case "local":
 		runLocal(cfg, cmdArgs)
+	case "map":
+		runMap(cfg, cmdArgs)
+	case "analyze":
+		runAnalyze(cfg, cmdArgs)
+	case "verify":
+		runVerify(cfg, cmdArgs)
+	case "verify-map":
+		runVerifyMap(cfg, cmdArgs)
+	case "format":
+		runFormat(cfg, cmdArgs)
+	case "extract-assets":
+		runExtractAssets(cfg, cmdArgs)
+	case "env":
+		runEnv(cfg, cmdArgs)
+	case "burp":
+		runBurp(cfg, cmdArgs)
+	case "merge":
+		runMerge(cfg, cmdArgs)
+	case "report":
+		runReport(cfg, cmdArgs)
+	case "watch":
+		runWatch(cfg, cmdArgs)
+	case "serve":
+		runServe(cfg, cmdArgs)
+	case "completion":
+		runCompletion(cmdArgs)
+	case "config":
+		runConfig(configFile, configSection, *profile, explicit, cmdArgs)
 	case "help":
 		printHelp()
 	default:
-		fmt.Println(ui.Error(fmt.Sprintf("Unknown command: %s", command)))
+		fmt.Fprintln(display, ui.Error(fmt.Sprintf("Unknown command: %s", command)))
 		printHelp()
 		os.Exit(1)
 	}
 }
 
 func printHelp() {
-	fmt.Println(ui.Banner(version))
-	fmt.Println()
-	fmt.Println(ui.TextStyle.Render("A surgical tool for unpacking JavaScript bundles using their sourcemaps."))
-	fmt.Println()
-
-	fmt.Println(ui.AccentStyle.Render("USAGE"))
-	fmt.Printf("  %s\n", ui.FormatUsage("dejank <command> [options] <target>"))
-	fmt.Println()
-
-	fmt.Println(ui.AccentStyle.Render("COMMANDS"))
-	fmt.Printf("  %s    %s\n", ui.InfoStyle.Render("url"), ui.TextStyle.Render("Crawl webpage, extract sourcemaps from all scripts"))
-	fmt.Printf("  %s %s\n", ui.InfoStyle.Render("single"), ui.TextStyle.Render("Extract sourcemap from a single script URL"))
-	fmt.Printf("  %s  %s\n", ui.InfoStyle.Render("local"), ui.TextStyle.Render("Process local .js and .map files"))
-	fmt.Printf("  %s   %s\n", ui.InfoStyle.Render("help"), ui.TextStyle.Render("Show this help"))
-	fmt.Println()
-
-	fmt.Println(ui.AccentStyle.Render("OPTIONS"))
-	fmt.Printf("  %s\n", ui.FormatUsage("-v       Verbose output"))
-	fmt.Printf("  %s\n", ui.FormatUsage("-f       Force overwrite existing output"))
-	fmt.Printf("  %s\n", ui.FormatUsage("-o <dir> Output directory (default: .)"))
-	fmt.Println()
-
-	fmt.Println(ui.AccentStyle.Render("EXAMPLES"))
-	fmt.Printf("  %s\n", ui.InfoStyle.Render("dejank url https://example.com"))
-	fmt.Printf("  %s\n", ui.InfoStyle.Render("dejank single https://example.com/app.js"))
-	fmt.Printf("  %s\n", ui.InfoStyle.Render("dejank local ./example.com"))
-	fmt.Println()
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display)
+	fmt.Fprintln(display, ui.TextStyle.Render("A surgical tool for unpacking JavaScript bundles using their sourcemaps."))
+	fmt.Fprintln(display)
+
+	fmt.Fprintln(display, ui.AccentStyle.Render("USAGE"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("dejank <command> [options] <target>"))
+	fmt.Fprintln(display)
+
+	fmt.Fprintln(display, ui.AccentStyle.Render("COMMANDS"))
+	fmt.Fprintf(display, "  %s            %s\n", ui.InfoStyle.Render("url"), ui.TextStyle.Render("Crawl webpage, extract sourcemaps from all scripts"))
+	fmt.Fprintf(display, "  %s         %s\n", ui.InfoStyle.Render("single"), ui.TextStyle.Render("Extract sourcemap from a single script URL"))
+	fmt.Fprintf(display, "  %s          %s\n", ui.InfoStyle.Render("local"), ui.TextStyle.Render("Process local .js and .map files"))
+	fmt.Fprintf(display, "  %s            %s\n", ui.InfoStyle.Render("map"), ui.TextStyle.Render("Restore sources from a known .map URL or file"))
+	fmt.Fprintf(display, "  %s        %s\n", ui.InfoStyle.Render("analyze"), ui.TextStyle.Render("Run the analyzers (stats, endpoints, secrets, ...) over an output directory"))
+	fmt.Fprintf(display, "  %s         %s\n", ui.InfoStyle.Render("verify"), ui.TextStyle.Render("Check downloaded files against their recorded SHA-256"))
+	fmt.Fprintf(display, "  %s     %s\n", ui.InfoStyle.Render("verify-map"), ui.TextStyle.Render("Check that a sourcemap's mappings agree with its bundle"))
+	fmt.Fprintf(display, "  %s         %s\n", ui.InfoStyle.Render("format"), ui.TextStyle.Render("Pretty-print restored sources in place, vendor code included"))
+	fmt.Fprintf(display, "  %s %s\n", ui.InfoStyle.Render("extract-assets"), ui.TextStyle.Render("Decode embedded assets from restored sources"))
+	fmt.Fprintf(display, "  %s            %s\n", ui.InfoStyle.Render("env"), ui.TextStyle.Render("Extract env vars and secrets from any directory of JS"))
+	fmt.Fprintf(display, "  %s           %s\n", ui.InfoStyle.Render("burp"), ui.TextStyle.Render("Restore from a Burp XML items export or a HAR file, without contacting the target"))
+	fmt.Fprintf(display, "  %s          %s\n", ui.InfoStyle.Render("merge"), ui.TextStyle.Render("Merge one domain directory's downloads into another, e.g. www. into the apex, and restore"))
+	fmt.Fprintf(display, "  %s         %s\n", ui.InfoStyle.Render("report"), ui.TextStyle.Render("Write a self-contained HTML page for browsing a run's sources and findings"))
+	fmt.Fprintf(display, "  %s          %s\n", ui.InfoStyle.Render("watch"), ui.TextStyle.Render("Re-run url mode on a schedule and report changes"))
+	fmt.Fprintf(display, "  %s          %s\n", ui.InfoStyle.Render("serve"), ui.TextStyle.Render("Run url mode jobs submitted over HTTP"))
+	fmt.Fprintf(display, "  %s         %s\n", ui.InfoStyle.Render("config"), ui.TextStyle.Render("Show the effective configuration (config show)"))
+	fmt.Fprintf(display, "  %s     %s\n", ui.InfoStyle.Render("completion"), ui.TextStyle.Render("Print a shell completion script (bash, zsh, fish)"))
+	fmt.Fprintf(display, "  %s           %s\n", ui.InfoStyle.Render("help"), ui.TextStyle.Render("Show this help"))
+	fmt.Fprintln(display)
+
+	fmt.Fprintln(display, ui.AccentStyle.Render("OPTIONS"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-v                 Verbose output"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-vv                Verbose output with debug details"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-f                 Force overwrite existing output"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-break-lock        Take over a stale lock left by a crashed run"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-o <dir>           Output directory (default: .)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-no-restore        Download scripts and maps only; restore later with local"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-versioned         Keep each run in a timestamped directory"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-git               Commit restored sources to a git repository"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-git-base          Keep the git repository at the domain level"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-csv               Write scripts.csv and maps.csv per domain"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-scaffold          Write tsconfig, editor settings, and package.json for restored sources"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-format-vendor     Also pretty-print node_modules and ignoreList sources"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-format-max-size   Write larger JS/TS sources unformatted (default: 2MB, 0 = no limit)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-verify            Check maps against their scripts; warn about maps for another build"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-sarif <file>      Write security findings as SARIF 2.1.0"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-sbom <file>       Write recovered npm packages as a CycloneDX 1.5 SBOM"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-events ndjson     Stream progress events as JSON lines"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-events-out <file> Send events to a file or pipe (default: stdout)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-hook <point=cmd>  Run cmd <domain-dir> at after_download, after_restore, or after_assets"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-notify-webhook    POST the JSON summary to a URL when the run finishes"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-notify-cmd <cmd>  Run cmd with the JSON summary on stdin when the run finishes"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-notify-desktop    Show a desktop notification when the run finishes"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-open              Open the output directory in the file manager when the run finishes"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-scope <scope>     Download scripts from same-origin (default), same-site, or all hosts"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-allow-host <host> Always download from host; *.example.com matches subdomains"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-scope-file <file> Only contact hosts listed in file (one per line, *.example.com wildcards)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-include-url <re>  Only process script/map URLs matching the regex (repeatable)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-exclude-url <re>  Skip script/map URLs matching the regex (repeatable)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-no-denylist       Process known third-party scripts (gtag, fbevents, hotjar, ...) skipped by default"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-denylist-add      Also skip scripts matching host[/path], e.g. *.example-cdn.com/tracker/** (repeatable)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-only <glob>       Only restore matching sources[] entries, e.g. src/api/** (excludes win)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-include-source    Only restore sources matching a glob, e.g. **/*.ts (repeatable)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-exclude-source    Skip sources matching a glob, e.g. **/node_modules/** (repeatable)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-comments          Collect TODO/FIXME-style comments into comments.json"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-comment-pattern   Regex for comments.json, replacing TODO/FIXME/HACK/XXX/@internal/Jira (repeatable; implies -comments)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-comments-vendor   Also collect comments from node_modules (implies -comments)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-dump-modules      Save webpack module sources from the live page (url mode, no maps needed)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-coverage          Record executed scripts and sources to coverage.json (url mode)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-respect-robots    Skip script/map URLs disallowed by robots.txt (url mode)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-seen <file>       Skip maps listed in file and append new ones, for watch deltas (url mode)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-map-url-template  Also probe a URL like https://cdn/{path}.map for scripts naming no map (url mode)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-keep-query        Keep scripts that differ only by query apart, e.g. app.<hash>.js"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-keep-query-param  Name files by one query parameter's value instead, e.g. build"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-split-by-host     Store scripts and maps under their own host's directory (url mode)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-max-scripts <n>   Stop after n scripts and finish with partial results"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-max-maps <n>      Stop after n sourcemaps and finish with partial results"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-max-duration <d>  Stop processing scripts and maps after d, e.g. 10m"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-request-timeout   Limit for a download to start responding (default: 30s)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-idle-timeout <d>  Limit for a download to go without data (default: 30s)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-client-cert <pem> Client certificate for mutual TLS downloads (with -client-key)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-client-key <pem>  Private key for -client-cert"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-4, -6             Download over IPv4 or IPv6 only"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-interface <name>  Send downloads from a network interface's address, e.g. wg0"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-source-ip <addr>  Send downloads from a local address"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-normalize-eol <e> Rewrite restored line endings to lf or crlf and strip BOMs (default: keep)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-map-mtime         Give restored sources their map's modification time"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-file-mode <mode>  Permissions of restored sources, e.g. 0640, regardless of umask"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-dir-mode <mode>   Permissions of restored source directories, e.g. 0750"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-force-scan        Let local without a target scan / or your home directory"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-flat              Local target is a plain directory of maps and scripts"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-cas <dir>         Store downloads once in a shared dir; runs hardlink to it"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-chrome-path <bin> Chrome or Chromium executable for url, watch, and serve"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-browser-timeout   Limit for each browser discovery attempt, e.g. 2m (default: 1m)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-nav-timeout <dur> Limit for loading the page in the browser (default: 30s)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-settle <dur>      Wait after page load for lazy-loaded scripts (default: 5s)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-headful           Show the browser window during discovery"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-user-data-dir     Chrome profile dir to use, e.g. one past a bot challenge"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-emulate <device>  Load pages as desktop (default), mobile, or both"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-snapshot          Save a screenshot and the rendered DOM of the page"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-interact <file>   Click, type, and scroll after page load to trigger lazy chunks"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-auto-scroll       Scroll through the page after load"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-user-agent <ua>   User-Agent for the browser and downloads"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-ua <name>         Browser User-Agent preset, e.g. chrome-win or iphone"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-accept-language   Accept-Language for the browser and downloads, e.g. en-US,en"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-locale <locale>   Locale the page sees, e.g. de-DE; also sets Accept-Language"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-timezone <tz>     Timezone the page sees, e.g. Europe/Berlin"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-geo <lat,lon>     Position the page's geolocation API reports"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-config <file>     Config file (default: ~/.config/dejank/config.yaml)"))
+	fmt.Fprintf(display, "  %s\n", ui.FormatUsage("-profile <name>    Apply a named profile from the config file"))
+	fmt.Fprintln(display)
+
+	fmt.Fprintln(display, ui.AccentStyle.Render("EXAMPLES"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url https://example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank single https://example.com/app.js"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank local ./example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank -flat -o ./restored local ./harvested-maps"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank analyze -only endpoints,secrets ./example.com-dejank"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank verify ./example.com-dejank"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank verify-map main.js main.js.map"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank format ./example.com-dejank/restored_sources/node_modules/lodash"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank extract-assets -base-url https://example.com ./example.com-dejank"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("curl -s https://example.com/app.js | dejank env -"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank burp items.xml"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank merge example.com-dejank www.example.com-dejank"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank report -format html-viewer ./example.com-dejank"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank map -fetch-sources https://example.com/static/js/main.js.map"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank watch https://example.com -interval 6h"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank -o /srv/dejank serve -listen :8080 -token <secret>"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -dry-run -plan plan.json https://example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -from-plan plan.json"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -sitemap 20 https://example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -resume https://example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -discover-only https://example.com | httpx"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank -scope-file scope.txt url https://app.example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -parallel 3 https://a.example https://b.example https://c.example"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank url -profile bounty-acme https://example.com"))
+	fmt.Fprintf(display, "  %s\n", ui.InfoStyle.Render("dejank completion bash > /etc/bash_completion.d/dejank"))
+	fmt.Fprintln(display)
 }
 
 func runURL(cfg *modes.Config, args []string) {
+	f := newURLFlags()
+	args = parseFlags(f.fs, args)
+
+	var plan *modes.Plan
+	if *f.fromPlan != "" {
+		var err error
+		if plan, err = modes.ReadPlan(*f.fromPlan); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		args = []string{plan.Target}
+	}
+
 	if len(args) < 1 {
-		fmt.Println(ui.Error("Missing URL argument"))
-		fmt.Println(ui.DimStyle.Render("Usage: dejank url <webpage-url>"))
+		fmt.Fprintln(display, ui.Error("Missing URL argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank url [-sitemap <n>] [-resume] [-dry-run [-plan <file>]] <webpage-url>"))
+		fmt.Fprintln(display, ui.DimStyle.Render("       dejank url [-parallel <n>] [-json] <webpage-url>..."))
+		fmt.Fprintln(display, ui.DimStyle.Render("       dejank url -discover-only [-export-urls <file>] [-json] <webpage-url>..."))
+		fmt.Fprintln(display, ui.DimStyle.Render("       dejank url -from-plan <file>"))
 		os.Exit(1)
 	}
 
+	cfg.SitemapPages = *f.sitemap
+	cfg.Resume = *f.resume
+	if *f.discover {
+		if *f.dryRun || plan != nil || cfg.Resume {
+			fmt.Fprintln(display, ui.Error("-discover-only can't be combined with -dry-run, -from-plan, or -resume"))
+			os.Exit(1)
+		}
+		runDiscover(cfg, args, *f.exportTo, *f.jsonOut)
+		return
+	}
+	if *f.dryRun && *f.exportTo != "" {
+		fmt.Fprintln(display, ui.Error("-export-urls doesn't apply to -dry-run; use -discover-only"))
+		os.Exit(1)
+	}
+	if len(args) > 1 || *f.jsonOut {
+		if *f.dryRun || plan != nil {
+			fmt.Fprintln(display, ui.Error("-dry-run and -from-plan take a single target without -json"))
+			os.Exit(1)
+		}
+		runTargets(cfg, args, *f.parallel, *f.jsonOut, *f.exportTo)
+		return
+	}
+
 	targetURL := args[0]
-	fmt.Println(ui.Banner(version))
-	fmt.Println(ui.Target(targetURL))
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(targetURL))
+	// A resumed run doesn't load the page again, unless there is nothing
+	// to resume
+	if plan == nil && !cfg.Resume {
+		requireChrome(cfg)
+	}
+
+	if *f.dryRun {
+		runDryRun(cfg, targetURL, *f.planOut)
+		return
+	}
 
 	var progress *ui.Progress
-	cfg.OnProgress = func(event string, data interface{}) {
+	cfg.OnProgress = chainProgress(cfg.OnProgress, func(event string, data interface{}) {
 		switch event {
 		case "discovery_complete":
 			if m, ok := data.(map[string]int); ok {
@@ -111,52 +647,90 @@ func runURL(cfg *modes.Config, args []string) {
 				progress.Increment()
 			}
 		}
-	}
+	})
 
-	result, err := modes.RunURL(cfg, targetURL)
+	var result *modes.URLResult
+	var err error
+	if plan != nil {
+		result, err = modes.RunURLFromPlan(cfg, plan)
+	} else {
+		result, err = modes.RunURL(cfg, targetURL)
+	}
 
 	if progress != nil {
 		progress.Done()
 	}
 
 	if err != nil {
-		fmt.Println(ui.Error(err.Error()))
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		printChromeHint(err)
 		os.Exit(1)
 	}
 
-	printURLSummary(result, cfg.Verbose)
+	printURLSummary(cfg, result)
+	printConsoleHint(cfg, result)
+	printStats(result.Stats, cfg.Verbose)
+	printNoRestoreHint(cfg, result.OutputDirs()...)
+	writeSARIF(*sarifPath, cfg.OutputRoot, cfg.Verbose, result.OutputDirs()...)
+	writeSBOM(*sbomPath, targetURL, cfg.OutputRoot, cfg.Verbose, result.OutputDirs()...)
+	exportURLs(*f.exportTo, result.URLs, false)
+	openOutput(cfg, result.OutputDirs()...)
+	notify("url", newTargetJSON(modes.TargetResult{URL: targetURL, Result: result}))
+	exitIfDiskStopped(result.Errors)
 }
 
 func runSingle(cfg *modes.Config, args []string) {
 	if len(args) < 1 {
-		fmt.Println(ui.Error("Missing script URL argument"))
-		fmt.Println(ui.DimStyle.Render("Usage: dejank single <script-url>"))
+		fmt.Fprintln(display, ui.Error("Missing script URL argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank single <script-url>"))
 		os.Exit(1)
 	}
 
 	scriptURL := args[0]
-	fmt.Println(ui.Banner(version))
-	fmt.Println(ui.Target(scriptURL))
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(scriptURL))
 
 	result, err := modes.RunSingle(cfg, scriptURL)
 	if err != nil {
-		fmt.Println(ui.Error(err.Error()))
+		fmt.Fprintln(display, ui.Error(err.Error()))
 		os.Exit(1)
 	}
 
-	fmt.Println(ui.SummaryHeader())
-	fmt.Println(ui.SummaryLine("Sourcemap found:", result.MapFound))
-	fmt.Println(ui.SummaryLine("Sources restored:", result.SourcesRestored))
-
-	if len(result.Errors) > 0 {
-		fmt.Println(ui.SummaryLine("Errors:", len(result.Errors)))
-		if cfg.Verbose {
-			for _, e := range result.Errors {
-				fmt.Printf("      %s\n", ui.DimStyle.Render(fmt.Sprintf("- %v", e)))
-			}
-		}
+	var s summary
+	s.line("Sourcemap found:", result.MapFound)
+	s.decoys(result.Maps)
+	s.restored(cfg, result.SourcesRestored)
+	if !cfg.NoRestore {
+		s.line("Assets extracted:", result.AssetsExtracted)
 	}
-	fmt.Println()
+	s.matched(cfg, result.SourcesMatched)
+	s.count("Sources filtered:", result.SourcesFiltered)
+	s.count("Comments flagged:", result.CommentsFound)
+	s.count("GraphQL operations:", result.GraphQLOps)
+	s.count("Hosts referenced:", result.HostsFound)
+	s.count("Secrets found:", result.SecretsFound)
+	s.count("Env vars:", result.EnvVarsExtracted)
+	s.bundler(result.Maps)
+	s.metro(result.Maps)
+	s.licenses(result.Licenses, result.Copyleft)
+	s.errors(result.Errors, cfg.Verbose)
+	s.warnings(result.Warnings, cfg.Verbose)
+	s.outputs(result.OutputDir)
+	s.print()
+	printStats(result.Stats, cfg.Verbose)
+	printNoRestoreHint(cfg, result.OutputDir)
+	writeSARIF(*sarifPath, cfg.OutputRoot, cfg.Verbose, result.OutputDir)
+	writeSBOM(*sbomPath, scriptURL, cfg.OutputRoot, cfg.Verbose, result.OutputDir)
+
+	t := runJSON(scriptURL, result.OutputDir, result.Errors, result.Warnings, result.Analysis)
+	t.MapsDiscovered = modes.CountMaps(result.Maps)
+	t.DecoyMaps = decoyMaps(result.Maps)
+	t.SourcesRestored = result.SourcesRestored
+	t.AssetsExtracted = result.AssetsExtracted
+	t.EnvVarsExtracted = result.EnvVarsExtracted
+	openOutput(cfg, result.OutputDir)
+	notify("single", t)
+	exitIfDiskStopped(result.Errors)
 }
 
 func runLocal(cfg *modes.Config, args []string) {
@@ -165,50 +739,176 @@ func runLocal(cfg *modes.Config, args []string) {
 		target = args[0]
 	}
 
-	fmt.Println(ui.Banner(version))
+	fmt.Fprintln(display, ui.Banner(version))
 	if target != "" {
-		fmt.Println(ui.Target(target))
+		fmt.Fprintln(display, ui.Target(target))
 	} else {
-		fmt.Println(ui.Info(fmt.Sprintf("Processing all domains in: %s", ui.URLStyle.Render(cfg.OutputRoot))))
+		fmt.Fprintln(display, ui.Info(fmt.Sprintf("Processing all domains in: %s", ui.URLStyle.Render(cfg.OutputRoot))))
 	}
 
+	var progress *ui.Progress
+	cfg.OnProgress = chainProgress(cfg.OnProgress, func(event string, data interface{}) {
+		switch event {
+		case "discovery_complete":
+			if m, ok := data.(map[string]int); ok {
+				if total := m["maps"]; total > 0 && !cfg.Verbose {
+					progress = ui.NewProgress(total, fmt.Sprintf("Restoring maps from %d domain(s)", m["domains"]))
+				}
+			}
+		case "processing_domain":
+			if m, ok := data.(map[string]interface{}); ok && progress != nil {
+				progress.SetMessage(fmt.Sprintf("Restoring maps: %v", m["domain"]))
+			}
+		case "map_processed":
+			if progress != nil {
+				progress.Increment()
+			}
+		}
+	})
+
 	result, err := modes.RunLocal(cfg, target)
+	if progress != nil {
+		progress.Done()
+	}
 	if err != nil {
-		fmt.Println(ui.Error(err.Error()))
+		fmt.Fprintln(display, ui.Error(err.Error()))
 		os.Exit(1)
 	}
 
-	fmt.Println(ui.SummaryHeader())
-	fmt.Println(ui.SummaryLine("Targets processed:", result.TargetsProcessed))
-	fmt.Println(ui.SummaryLine("Maps processed:", result.MapsProcessed))
-	fmt.Println(ui.SummaryLine("Sources restored:", result.SourcesRestored))
-	fmt.Println(ui.SummaryLine("Assets extracted:", result.AssetsExtracted))
+	var s summary
+	s.line("Targets processed:", result.TargetsProcessed)
+	s.line("Maps processed:", result.MapsProcessed)
+	s.decoys(result.Maps)
+	s.unmapped(result.Scripts, cfg.Verbose)
+	s.restored(cfg, result.SourcesRestored)
+	s.line("Assets extracted:", result.AssetsExtracted)
+	s.matched(cfg, result.SourcesMatched)
+	s.count("Sources filtered:", result.SourcesFiltered)
+	s.count("Comments flagged:", result.CommentsFound)
+	s.count("GraphQL operations:", result.GraphQLOps)
+	s.count("Hosts referenced:", result.HostsFound)
+	s.count("Secrets found:", result.SecretsFound)
+	s.count("Env vars:", result.EnvVarsExtracted)
+	s.deduped(result.DedupedBytes)
+	s.bundler(result.Maps)
+	s.metro(result.Maps)
+	s.licenses(result.Licenses, result.Copyleft)
+	s.budget(result.BudgetExceeded, result.BudgetSkipped)
+	s.errors(result.Errors, cfg.Verbose)
+	s.warnings(result.Warnings, cfg.Verbose)
+	if cfg.Flat {
+		s.flatOutputs(target, result.OutputDirs)
+	} else {
+		s.outputsAll(result.OutputDirs)
+	}
+	s.print()
+	printStats(result.Stats, cfg.Verbose)
+	writeSARIF(*sarifPath, cfg.OutputRoot, cfg.Verbose, result.OutputDirs...)
+	if target == "" {
+		target = cfg.OutputRoot
+	}
+	writeSBOM(*sbomPath, target, cfg.OutputRoot, cfg.Verbose, result.OutputDirs...)
+	openOutput(cfg, result.OutputDirs...)
+	notify("local", localJSON(cfg, target, result))
+	exitIfDiskStopped(result.Errors)
+}
 
-	if len(result.Errors) > 0 {
-		fmt.Println(ui.SummaryLine("Errors:", len(result.Errors)))
-		if cfg.Verbose {
-			for _, e := range result.Errors {
-				fmt.Printf("      %s\n", ui.DimStyle.Render(fmt.Sprintf("- %v", e)))
-			}
-		}
+func printURLSummary(cfg *modes.Config, result *modes.URLResult) {
+	var s summary
+	s.line("Scripts discovered:", result.ScriptsFound)
+	s.line("Maps discovered:", result.MapsDiscovered)
+	s.decoys(result.Maps)
+	s.unmapped(result.Scripts, cfg.Verbose)
+	s.restored(cfg, result.SourcesRestored)
+	s.line("Assets extracted:", result.AssetsExtracted)
+	s.count("Out of scope:", result.OutOfScope)
+	s.count("Scripts filtered:", result.ScriptsFiltered)
+	s.count("Maps filtered:", result.MapsFiltered)
+	s.count("Previously seen:", result.MapsSeen)
+	s.count("Templated maps:", result.MapsTemplated)
+	s.count("Sitemap pages:", result.SitemapPages)
+	s.count("Mobile only:", result.MobileOnly)
+	s.count("Interaction loads:", result.ScriptsTriggered)
+	s.count("Prerendered:", result.Prerendered)
+	s.count("Prefetched unused:", result.PrefetchedUnused)
+	s.count("Reused from disk:", result.ScriptsReused)
+	s.deduped(result.DedupedBytes)
+	s.count("Robots disallowed:", result.RobotsDisallowed)
+	s.count("Known third-party:", result.ThirdParty)
+	s.count("Modules dumped:", result.ModulesDumped)
+	s.count("Scripts executed:", result.ScriptsExecuted)
+	s.count("Sources executed:", result.SourcesExecuted)
+	s.matched(cfg, result.SourcesMatched)
+	s.count("Sources filtered:", result.SourcesFiltered)
+	s.count("Comments flagged:", result.CommentsFound)
+	s.count("GraphQL operations:", result.GraphQLOps)
+	s.count("Hosts referenced:", result.HostsFound)
+	s.count("Secrets found:", result.SecretsFound)
+	s.count("Env vars:", result.EnvVarsExtracted)
+	s.bundler(result.Maps)
+	s.metro(result.Maps)
+	s.licenses(result.Licenses, result.Copyleft)
+	s.budget(result.BudgetExceeded, result.BudgetSkipped)
+	s.errors(result.Errors, cfg.Verbose)
+	s.warnings(result.Warnings, cfg.Verbose)
+	s.hosts(result.Hosts)
+	s.outputsAll(result.OutputDirs())
+	s.print()
+}
+
+// printConsoleHint points at the browser console when a url run found no
+// sourcemaps but the console reported problems loading scripts.
+func printConsoleHint(cfg *modes.Config, result *modes.URLResult) {
+	n := warn.Count(result.Warnings)[warn.Console]
+	if result.MapsDiscovered > 0 || n == 0 {
+		return
+	}
+	msg := fmt.Sprintf("No sourcemaps found, and the browser console reported %d problems with scripts", n)
+	if !cfg.Verbose {
+		msg += " (run with -v to list them)"
 	}
-	fmt.Println()
+	fmt.Fprintln(display, ui.Info(msg))
+	fmt.Fprintln(display)
 }
 
-func printURLSummary(result *modes.URLResult, verbose bool) {
-	fmt.Println(ui.SummaryHeader())
-	fmt.Println(ui.SummaryLine("Scripts discovered:", result.ScriptsFound))
-	fmt.Println(ui.SummaryLine("Maps discovered:", result.MapsDiscovered))
-	fmt.Println(ui.SummaryLine("Sources restored:", result.SourcesRestored))
-	fmt.Println(ui.SummaryLine("Assets extracted:", result.AssetsExtracted))
-
-	if len(result.Errors) > 0 {
-		fmt.Println(ui.SummaryLine("Errors:", len(result.Errors)))
-		if verbose {
-			for _, e := range result.Errors {
-				fmt.Printf("      %s\n", ui.DimStyle.Render(fmt.Sprintf("- %v", e)))
-			}
-		}
+// newLogger returns the logger for progress messages: warnings by default,
+// info messages with -v, and debug details with -vv. Messages go to
+// stdout; logToStderr moves them.
+func newLogger(verbose, debug bool) *slog.Logger {
+	level := slog.LevelWarn
+	switch {
+	case debug:
+		level = slog.LevelDebug
+	case verbose:
+		level = slog.LevelInfo
+	}
+	return slog.New(ui.NewLogHandler(os.Stdout, level))
+}
+
+// logToStderr sends cfg's log output to stderr, for commands whose stdout
+// carries data for other tools.
+func logToStderr(cfg *modes.Config) {
+	if h, ok := cfg.Log.Handler().(*ui.LogHandler); ok {
+		cfg.Log = slog.New(h.WithOutput(os.Stderr))
+	}
+}
+
+// exitIfDiskStopped fails a run that stopped writing because the disk
+// filled up or a write was refused, after its partial summary.
+func exitIfDiskStopped(errs []error) {
+	if err := disk.First(errs); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+}
+
+// printNoRestoreHint explains how to finish a download-only run.
+func printNoRestoreHint(cfg *modes.Config, dirs ...string) {
+	if !cfg.NoRestore {
+		return
+	}
+	for _, dir := range dirs {
+		fmt.Fprintln(display, ui.Info(fmt.Sprintf("Restore later with: dejank local %s", dir)))
 	}
-	fmt.Println()
+	fmt.Fprintln(display)
 }
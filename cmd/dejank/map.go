@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// mapFlags holds the flags accepted by the map command.
+type mapFlags struct {
+	fs           *flag.FlagSet
+	name         *string
+	fetchSources *bool
+	fetchAssets  *bool
+}
+
+func newMapFlags() *mapFlags {
+	fs := newFlagSet("map")
+	return &mapFlags{
+		fs:           fs,
+		name:         fs.String("name", "", "Domain directory `name` (default: URL host or file name)"),
+		fetchSources: fs.Bool("fetch-sources", false, "Download sources the map lists without sourcesContent"),
+		fetchAssets:  fs.Bool("fetch-assets", true, "Download real webpack assets for URL maps"),
+	}
+}
+
+func runMap(cfg *modes.Config, args []string) {
+	f := newMapFlags()
+	args = parseFlags(f.fs, args)
+
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing sourcemap argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank map [-name <domain>] [-fetch-sources] [-fetch-assets=false] <map-url-or-file>"))
+		os.Exit(1)
+	}
+
+	target := args[0]
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(target))
+
+	result, err := modes.RunMap(cfg, target, modes.MapOptions{
+		Name:         *f.name,
+		FetchSources: *f.fetchSources,
+		FetchAssets:  *f.fetchAssets,
+	})
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	var s summary
+	s.restored(cfg, result.SourcesRestored)
+	s.count("Sources fetched:", result.SourcesFetched)
+	s.matched(cfg, result.SourcesMatched)
+	s.count("Sources filtered:", result.SourcesFiltered)
+	s.count("Comments flagged:", result.CommentsFound)
+	s.count("GraphQL operations:", result.GraphQLOps)
+	s.count("Hosts referenced:", result.HostsFound)
+	s.count("Secrets found:", result.SecretsFound)
+	s.bundler(result.Maps)
+	s.metro(result.Maps)
+	s.licenses(result.Licenses, result.Copyleft)
+	if !cfg.NoRestore {
+		s.line("Assets extracted:", result.AssetsExtracted)
+	}
+	s.errors(result.Errors, cfg.Verbose)
+	s.warnings(result.Warnings, cfg.Verbose)
+	s.outputs(result.OutputDir)
+	s.print()
+	printStats(result.Stats, cfg.Verbose)
+	printNoRestoreHint(cfg, result.OutputDir)
+	writeSARIF(*sarifPath, cfg.OutputRoot, cfg.Verbose, result.OutputDir)
+	writeSBOM(*sbomPath, target, cfg.OutputRoot, cfg.Verbose, result.OutputDir)
+
+	t := runJSON(target, result.OutputDir, result.Errors, result.Warnings, result.Analysis)
+	t.MapsDiscovered = len(result.Maps)
+	t.SourcesRestored = result.SourcesRestored
+	t.AssetsExtracted = result.AssetsExtracted
+	openOutput(cfg, result.OutputDir)
+	notify("map", t)
+	exitIfDiskStopped(result.Errors)
+}
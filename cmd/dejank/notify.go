@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/launch"
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// Notification flags shared by all run commands.
+var (
+	notifyWebhook *string
+	notifyCmd     *string
+	notifyDesktop *bool
+)
+
+// notification is the payload sent when a run finishes: url -json's
+// object for the target, plus the mode and a one-line summary.
+type notification struct {
+	targetJSON
+	Mode string `json:"mode"`
+	Text string `json:"text"` // One-line summary, e.g. for a Slack incoming webhook
+}
+
+// notify sends a notification for t to each of -notify-webhook,
+// -notify-cmd, and -notify-desktop that is set. Failures are printed as
+// warnings and don't change the exit status of the run.
+func notify(mode string, t targetJSON) {
+	if *notifyWebhook == "" && *notifyCmd == "" && !*notifyDesktop {
+		return
+	}
+	n := notification{targetJSON: t, Mode: mode}
+	n.Text = notificationText(n)
+
+	if *notifyWebhook != "" {
+		// Not the run's client: its -scope-file filter is for the target,
+		// and the webhook is never in it
+		if err := fetch.New().PostJSON(*notifyWebhook, n); err != nil {
+			notifyWarning(fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if *notifyCmd != "" {
+		if err := runNotifyCmd(*notifyCmd, n); err != nil {
+			notifyWarning(err)
+		}
+	}
+	if *notifyDesktop {
+		if err := desktopNotify("dejank "+mode, n.Text); err != nil {
+			notifyWarning(fmt.Errorf("desktop: %w", err))
+		}
+	}
+}
+
+// notifyWarning prints a failed notification. It goes to stderr so it
+// doesn't break url -json output.
+func notifyWarning(err error) {
+	fmt.Fprintln(os.Stderr, ui.Warning(fmt.Sprintf("Notification failed: %v", err)))
+}
+
+// notificationText summarizes n in one line, e.g.
+// "https://example.com: 12 scripts, 3 maps, 140 sources, 2 errors (./example.com-dejank)".
+func notificationText(n notification) string {
+	if n.Error != "" {
+		return fmt.Sprintf("%s: failed: %s", n.URL, n.Error)
+	}
+	var parts []string
+	if n.Mode == "url" {
+		parts = append(parts, ui.FormatCount(n.ScriptsFound)+" scripts")
+	}
+	parts = append(parts,
+		ui.FormatCount(n.MapsDiscovered)+" maps",
+		ui.FormatCount(n.SourcesRestored)+" sources")
+	if n.SecretsFound > 0 {
+		parts = append(parts, ui.FormatCount(n.SecretsFound)+" secrets")
+	}
+	if len(n.Errors) > 0 {
+		parts = append(parts, ui.FormatCount(len(n.Errors))+" errors")
+	}
+	if len(n.Warnings) > 0 {
+		parts = append(parts, ui.FormatCount(len(n.Warnings))+" warnings")
+	}
+	text := n.URL + ": " + strings.Join(parts, ", ")
+	if n.OutputDir != "" {
+		text += " (" + n.OutputDir + ")"
+	}
+	return text
+}
+
+// runNotifyCmd runs command, split into arguments as a shell would split
+// it, with n as JSON on its stdin. Its output is passed through to stderr.
+func runNotifyCmd(command string, n notification) error {
+	argv, err := launch.SplitCommand(command)
+	if err != nil {
+		return fmt.Errorf("invalid -notify-cmd: %w", err)
+	}
+	if len(argv) == 0 {
+		return fmt.Errorf("empty -notify-cmd")
+	}
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	return nil
+}
+
+// desktopNotify shows a desktop notification with notify-send on Linux and
+// the BSDs, or osascript on macOS.
+func desktopNotify(title, text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// The text is passed as an argument rather than spliced into the
+		// script, so it needs no quoting
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run", title, text)
+	case "windows":
+		return fmt.Errorf("not supported on Windows")
+	default:
+		cmd = exec.Command("notify-send", "--app-name=dejank", title, text)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// runJSON starts url -json's object for a run of another mode.
+func runJSON(target, outputDir string, errs []error, warnings []warn.Warning, analysis modes.Analysis) targetJSON {
+	t := targetJSON{Versioned: schema.Current(), DejankVersion: version, URL: target, OutputDir: outputDir, Errors: []string{}, Warnings: []warn.Warning{}, Analysis: analysis}
+	for _, err := range errs {
+		t.Errors = append(t.Errors, err.Error())
+	}
+	if warnings != nil {
+		t.Warnings = warnings
+	}
+	return t
+}
+
+// localJSON converts a local-mode result for a notification.
+func localJSON(cfg *modes.Config, target string, r *modes.LocalResult) targetJSON {
+	dir := cfg.OutputRoot
+	if len(r.OutputDirs) == 1 {
+		dir = r.OutputDirs[0]
+	}
+	t := runJSON(target, dir, r.Errors, r.Warnings, r.Analysis)
+	t.MapsDiscovered = r.MapsProcessed
+	t.ScriptsUnmapped = unmappedURLs(r.Scripts)
+	t.DecoyMaps = decoyMaps(r.Maps)
+	t.SourcesRestored = r.SourcesRestored
+	t.AssetsExtracted = r.AssetsExtracted
+	t.EnvVarsExtracted = r.EnvVarsExtracted
+	return t
+}
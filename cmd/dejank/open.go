@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/launch"
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// openAfter is -open, shared by all run commands.
+var openAfter *bool
+
+// openOutput prints the output directory of a finished run and, with
+// -open, opens it in the file manager. A run that wrote several domain
+// directories opens the output root holding them. A failure to open is a
+// warning and doesn't change the exit status of the run.
+func openOutput(cfg *modes.Config, dirs ...string) {
+	if !*openAfter || len(dirs) == 0 {
+		return
+	}
+	dir := cfg.OutputRoot
+	if len(dirs) == 1 {
+		dir = dirs[0]
+	}
+	if dir == "" {
+		return
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	fmt.Fprintln(display, ui.Info(fmt.Sprintf("Output: %s", ui.URLStyle.Render(dir))))
+	if err := launch.Open(launch.Default, dir); err != nil {
+		fmt.Fprintln(display, ui.Warning(fmt.Sprintf("Could not open the output directory: %v", err)))
+	}
+}
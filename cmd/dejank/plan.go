@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// urlFlags holds the flags accepted by the url command.
+type urlFlags struct {
+	fs       *flag.FlagSet
+	dryRun   *bool
+	planOut  *string
+	fromPlan *string
+	sitemap  *int
+	resume   *bool
+	parallel *int
+	jsonOut  *bool
+	exportTo *string
+	discover *bool
+}
+
+func newURLFlags() *urlFlags {
+	fs := newFlagSet("url")
+	return &urlFlags{
+		fs:       fs,
+		dryRun:   fs.Bool("dry-run", false, "Discover and probe scripts without downloading or writing output"),
+		planOut:  fs.String("plan", "", "With -dry-run, write the plan to a JSON `file`"),
+		fromPlan: fs.String("from-plan", "", "Run using the scripts in a plan `file` instead of rediscovering"),
+		sitemap:  fs.Int("sitemap", 0, "Also discover scripts on up to `n` same-origin pages sampled from /sitemap.xml"),
+		resume:   fs.Bool("resume", false, "Continue an interrupted run from the run-state.json in its output directory"),
+		parallel: fs.Int("parallel", 2, "Run up to `n` targets at a time when given several URLs"),
+		jsonOut:  fs.Bool("json", false, "Print an array of per-target results as JSON instead of the summary"),
+		exportTo: fs.String("export-urls", "", "Also write every discovered script and map URL to `file`, one per line (JSON with -json)"),
+		discover: fs.Bool("discover-only", false, "Print the discovered script and map URLs and stop, downloading nothing"),
+	}
+}
+
+// runDryRun prints what a url run would download and optionally saves the plan.
+func runDryRun(cfg *modes.Config, targetURL, planPath string) {
+	var progress *ui.Progress
+	cfg.OnProgress = chainProgress(cfg.OnProgress, func(event string, data interface{}) {
+		switch event {
+		case "discovery_complete":
+			if m, ok := data.(map[string]int); ok {
+				if total := m["scripts"]; total > 0 && !cfg.Verbose {
+					progress = ui.NewProgress(total, "Probing scripts")
+				}
+			}
+		case "processing_script":
+			if progress != nil {
+				progress.Increment()
+			}
+		}
+	})
+
+	plan, err := modes.RunPlan(cfg, targetURL)
+	if progress != nil {
+		progress.Done()
+	}
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		printChromeHint(err)
+		os.Exit(1)
+	}
+
+	printPlan(plan, cfg.Verbose)
+
+	if planPath != "" {
+		if err := modes.WritePlan(planPath, plan); err != nil {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+			os.Exit(1)
+		}
+		fmt.Fprintln(display, ui.Success(fmt.Sprintf("Plan written to %s", planPath)))
+		fmt.Fprintln(display, ui.DimStyle.Render(fmt.Sprintf("Run it with: dejank url -from-plan %s", planPath)))
+		fmt.Fprintln(display)
+	}
+}
+
+func printPlan(plan *modes.Plan, verbose bool) {
+	fmt.Fprintln(display)
+	fmt.Fprintf(display, "  %s %s %s\n",
+		ui.AccentStyle.Render(fmt.Sprintf("%10s", "SIZE")),
+		ui.AccentStyle.Render(fmt.Sprintf("%-7s", "MAP")),
+		ui.AccentStyle.Render("SCRIPT"))
+
+	withMaps := 0
+	for _, s := range plan.Scripts {
+		mapState := "?"
+		if s.HasSourceMap != nil {
+			if *s.HasSourceMap {
+				mapState = "yes"
+				withMaps++
+			} else {
+				mapState = "no"
+			}
+		}
+		fmt.Fprintf(display, "  %s %s %s\n",
+			ui.TextStyle.Render(fmt.Sprintf("%10s", ui.FormatBytes(s.Bytes))),
+			ui.InfoStyle.Render(fmt.Sprintf("%-7s", mapState)),
+			ui.URLStyle.Render(s.URL))
+		if verbose && s.MapURL != "" {
+			fmt.Fprintf(display, "  %18s %s\n", "", ui.DimStyle.Render("-> "+s.MapURL))
+		}
+		if s.Error != "" {
+			fmt.Fprintf(display, "  %18s %s\n", "", ui.DimStyle.Render("- "+s.Error))
+		}
+	}
+
+	total, unknown := plan.TotalBytes()
+	var s summary
+	s.line("Scripts discovered:", plan.Discovered)
+	s.line("Scripts planned:", len(plan.Scripts))
+	s.line("With sourcemaps:", withMaps)
+	s.line("Maps discovered:", len(plan.SourceMaps))
+	size := ui.FormatBytes(total)
+	if unknown > 0 {
+		size += fmt.Sprintf(" (+%s of unknown size)", ui.FormatCount(unknown))
+	}
+	s.line("Script bytes:", size)
+	s.count("Out of scope:", plan.OutOfScope)
+	s.count("Filtered:", plan.Filtered)
+	s.count("Robots disallowed:", plan.RobotsDisallowed)
+	s.count("Known third-party:", plan.ThirdParty)
+	s.print()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/findings"
+	"github.com/thesavant42/dejank/internal/licenses"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// writeSARIF scans the given domain directories and writes a SARIF log.
+// Failures are reported but don't change the exit status of the run.
+func writeSARIF(path, outputRoot string, verbose bool, domainDirs ...string) {
+	if path == "" {
+		return
+	}
+
+	var all []findings.Finding
+	for _, dir := range domainDirs {
+		for _, sub := range []string{"downloaded_site", "restored_sources"} {
+			results, errs := findings.ScanDirectory(filepath.Join(dir, sub))
+			all = append(all, results...)
+			if verbose {
+				for _, e := range errs {
+					fmt.Fprintln(display, ui.Warning(e.Error()))
+				}
+			}
+		}
+	}
+
+	if err := findings.WriteSARIF(path, all, outputRoot, version); err != nil {
+		fmt.Fprintln(display, ui.Error(fmt.Sprintf("Failed to write SARIF: %v", err)))
+		return
+	}
+
+	fmt.Fprintln(display, ui.Success(fmt.Sprintf("Wrote %d finding(s) to %s", len(all), path)))
+	printServiceConfigs(all)
+}
+
+// writeSBOM inventories the third-party packages of the given domain
+// directories and writes them as a CycloneDX SBOM with target as the
+// top-level component. Evidence paths are relative to outputRoot, like the
+// SARIF log's. Failures are reported but don't change the exit status of
+// the run.
+func writeSBOM(path, target, outputRoot string, verbose bool, domainDirs ...string) {
+	if path == "" {
+		return
+	}
+
+	bom := licenses.NewSBOM(target, version)
+	for _, dir := range domainDirs {
+		sources := filepath.Join(dir, "restored_sources")
+		inv, err := licenses.Scan(sources)
+		if err != nil {
+			if verbose {
+				fmt.Fprintln(display, ui.Warning(fmt.Sprintf("Failed to inventory %s: %v", sources, err)))
+			}
+			continue
+		}
+		if rel, err := filepath.Rel(outputRoot, sources); err == nil {
+			sources = rel
+		}
+		bom.Add(inv, filepath.ToSlash(sources))
+	}
+
+	if err := bom.Write(path); err != nil {
+		fmt.Fprintln(display, ui.Error(fmt.Sprintf("Failed to write SBOM: %v", err)))
+		return
+	}
+	fmt.Fprintln(display, ui.Success(fmt.Sprintf("Wrote %d component(s) to %s", bom.Len(), path)))
+}
+
+// printServiceConfigs prints a one-line count of third-party service
+// configurations by service, e.g. "3 (Firebase 1, Sentry 2)".
+func printServiceConfigs(all []findings.Finding) {
+	counts := make(map[string]int)
+	total := 0
+	for _, f := range all {
+		if f.RuleID == findings.RuleServiceConfig {
+			counts[f.Kind]++
+			total++
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	services := make([]string, 0, len(counts))
+	for s := range counts {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	parts := make([]string, 0, len(services))
+	for _, s := range services {
+		parts = append(parts, fmt.Sprintf("%s %s", s, ui.FormatCount(counts[s])))
+	}
+	fmt.Fprintln(display, ui.SummaryLine("Service configs:", fmt.Sprintf("%s (%s)", ui.FormatCount(total), strings.Join(parts, ", "))))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/server"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// serveFlags holds the flags accepted by the serve command.
+type serveFlags struct {
+	fs      *flag.FlagSet
+	listen  *string
+	workers *int
+	token   *string
+}
+
+func newServeFlags() *serveFlags {
+	fs := newFlagSet("serve")
+	return &serveFlags{
+		fs:      fs,
+		listen:  fs.String("listen", ":8080", "Address to listen on"),
+		workers: fs.Int("workers", 2, "Number of jobs to run concurrently"),
+		token:   fs.String("token", "", "Bearer token required on every request (default: $DEJANK_TOKEN)"),
+	}
+}
+
+func runServe(cfg *modes.Config, args []string) {
+	f := newServeFlags()
+	parseFlags(f.fs, args)
+	listen, workers, token := f.listen, f.workers, f.token
+	if *token == "" {
+		*token = os.Getenv("DEJANK_TOKEN")
+	}
+
+	requireChrome(cfg)
+
+	srv, err := server.New(server.Options{
+		Root:    cfg.OutputRoot,
+		Workers: *workers,
+		Token:   *token,
+		Config:  cfg,
+	})
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Info(fmt.Sprintf("Serving on %s with %d worker(s)", ui.URLStyle.Render(*listen), *workers)))
+	if *token == "" {
+		fmt.Fprintln(display, ui.Warning("No -token set: the API is unauthenticated"))
+	}
+
+	if err := srv.Run(cfg.Context, *listen); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// largestShown is how many of the largest files are listed outside verbose mode.
+const largestShown = 5
+
+// notableShown is how many notable files per package are listed outside
+// verbose mode.
+const notableShown = 3
+
+// analyzeFlags holds the flags accepted by the analyze command.
+type analyzeFlags struct {
+	fs      *flag.FlagSet
+	jsonOut *bool
+	only    *string
+}
+
+func newAnalyzeFlags() *analyzeFlags {
+	fs := newFlagSet("analyze")
+	return &analyzeFlags{
+		fs:      fs,
+		jsonOut: fs.Bool("json", false, "Print the analysis as JSON"),
+		only:    fs.String("only", "", "Run only these comma-separated `analyzers`, e.g. endpoints,secrets"),
+	}
+}
+
+func runAnalyze(cfg *modes.Config, args []string) {
+	f := newAnalyzeFlags()
+	args = parseFlags(f.fs, args)
+
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing directory argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank analyze [-json] [-only <analyzers>] <domain-dir>"))
+		os.Exit(1)
+	}
+	only, err := modes.SelectAnalyzers(*f.only)
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	result, err := modes.RunAnalyze(cfg, args[0], modes.AnalyzeOptions{Only: only})
+	if err != nil {
+		if *f.jsonOut {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Fprintln(display, ui.Error(err.Error()))
+		}
+		os.Exit(1)
+	}
+
+	if *f.jsonOut {
+		data, _ := json.MarshalIndent(struct {
+			schema.Versioned
+			DejankVersion string `json:"dejank_version"`
+			modes.Analysis
+		}{schema.Current(), version, result.Analysis}, "", "  ")
+		fmt.Fprintln(os.Stdout, string(data))
+		return
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(result.SourcesDir))
+	if !result.Layout {
+		fmt.Fprintln(display, ui.Info("Not a dejank output directory; only statistics were computed"))
+	}
+	printStats(result.Stats, cfg.Verbose)
+
+	var s summary
+	s.line("Analyzers:", strings.Join(result.Analyzers, ", "))
+	s.count("Comments flagged:", result.CommentsFound)
+	s.count("GraphQL operations:", result.GraphQLOps)
+	s.count("Hosts referenced:", result.HostsFound)
+	s.count("Secrets found:", result.SecretsFound)
+	s.licenses(result.Licenses, result.Copyleft)
+	s.errors(result.Errors, cfg.Verbose)
+	if result.Layout {
+		s.outputs(result.Dir)
+	}
+	s.print()
+}
+
+// printStats prints per-language counts, the first-party/vendor split, and
+// the largest files. Nothing is printed when no sources were restored.
+func printStats(s *stats.Stats, verbose bool) {
+	if s == nil || s.Total.Files == 0 {
+		return
+	}
+
+	fmt.Fprintf(display, "  %s %s %s\n",
+		ui.AccentStyle.Render(fmt.Sprintf("%-10s", "LANGUAGE")),
+		ui.AccentStyle.Render(fmt.Sprintf("%7s", "FILES")),
+		ui.AccentStyle.Render(fmt.Sprintf("%10s", "SIZE")))
+	for _, lang := range s.Languages {
+		fmt.Fprintf(display, "  %s %s %s\n",
+			ui.InfoStyle.Render(fmt.Sprintf("%-10s", lang.Name)),
+			ui.TextStyle.Render(fmt.Sprintf("%7s", ui.FormatCount(lang.Files))),
+			ui.TextStyle.Render(fmt.Sprintf("%10s", ui.FormatBytes(lang.Bytes))))
+	}
+	fmt.Fprintf(display, "  %s %s %s\n",
+		ui.AccentStyle.Render(fmt.Sprintf("%-10s", "total")),
+		ui.TextStyle.Render(fmt.Sprintf("%7s", ui.FormatCount(s.Total.Files))),
+		ui.TextStyle.Render(fmt.Sprintf("%10s", ui.FormatBytes(s.Total.Bytes))))
+	fmt.Fprintln(display)
+
+	fmt.Fprintln(display, ui.SummaryLine("First-party:", fmt.Sprintf("%s files, %s", ui.FormatCount(s.FirstParty.Files), ui.FormatBytes(s.FirstParty.Bytes))))
+	fmt.Fprintln(display, ui.SummaryLine("Vendor:", fmt.Sprintf("%s files, %s", ui.FormatCount(s.Vendor.Files), ui.FormatBytes(s.Vendor.Bytes))))
+	if s.Components > 0 {
+		fmt.Fprintln(display, ui.SummaryLine("Components:", fmt.Sprintf("%s Vue/Svelte", ui.FormatCount(s.Components))))
+	}
+	printPackages(s.Packages, verbose)
+
+	largest := s.Largest
+	if !verbose && len(largest) > largestShown {
+		largest = largest[:largestShown]
+	}
+	if len(largest) > 0 {
+		fmt.Fprintln(display, ui.SummaryLine("Largest files:", ""))
+		for _, f := range largest {
+			fmt.Fprintf(display, "      %s %s\n",
+				ui.TextStyle.Render(fmt.Sprintf("%10s", ui.FormatBytes(f.Bytes))),
+				ui.DimStyle.Render(f.Path))
+		}
+	}
+	fmt.Fprintln(display)
+}
+
+// printPackages lists the internal packages of a monorepo-style tree with
+// their file counts and notable files. Without -v only the first few
+// notable files of each package are shown.
+func printPackages(pkgs []stats.Package, verbose bool) {
+	if len(pkgs) == 0 {
+		return
+	}
+	width := 0
+	for _, p := range pkgs {
+		width = max(width, len(p.Name))
+	}
+
+	fmt.Fprintln(display, ui.SummaryLine("Internal packages:", len(pkgs)))
+	for _, p := range pkgs {
+		fmt.Fprintf(display, "      %s %s %s\n",
+			ui.InfoStyle.Render(fmt.Sprintf("%-*s", width, p.Name)),
+			ui.TextStyle.Render(fmt.Sprintf("%5s files %10s", ui.FormatCount(p.Files), ui.FormatBytes(p.Bytes))),
+			ui.DimStyle.Render(p.Dir))
+		notable := p.Notable
+		if !verbose && len(notable) > notableShown {
+			notable = notable[:notableShown]
+		}
+		for _, n := range notable {
+			fmt.Fprintf(display, "        %s\n", ui.DimStyle.Render(n))
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// outputDirs are the standard directories of a domain directory, with the
+// labels they are listed under in the summary.
+var outputDirs = []struct {
+	name  string
+	label string
+}{
+	{"downloaded_site", "Downloaded site:"},
+	{"restored_sources", "Restored sources:"},
+	{"extracted_assets", "Extracted assets:"},
+}
+
+// summary collects the lines of a run summary so they can be rendered
+// together in a box.
+type summary struct {
+	lines   []summaryLine
+	domains []*summary // Output boxes of runs that touched several domains
+}
+
+// summaryLine is a label and value, formatted when the summary is printed
+// so counts can be aligned, or a preformatted line.
+type summaryLine struct {
+	label string
+	value interface{}
+	text  string // Preformatted line, used when set
+}
+
+// add appends a preformatted line.
+func (s *summary) add(line string) {
+	s.lines = append(s.lines, summaryLine{text: line})
+}
+
+// line appends a label and value.
+func (s *summary) line(label string, value interface{}) {
+	s.lines = append(s.lines, summaryLine{label: label, value: value})
+}
+
+// render formats the lines, right-aligning the integer values in a column
+// as wide as the widest of them.
+func (s *summary) render() []string {
+	values := make([]interface{}, 0, len(s.lines))
+	for _, l := range s.lines {
+		if l.text == "" {
+			values = append(values, l.value)
+		}
+	}
+	width := ui.SummaryWidth(values...)
+
+	lines := make([]string, len(s.lines))
+	for i, l := range s.lines {
+		lines[i] = l.text
+		if l.text == "" {
+			lines[i] = ui.SummaryLineWidth(l.label, l.value, width)
+		}
+	}
+	return lines
+}
+
+// count appends a count of items skipped by scope or filters, if any.
+func (s *summary) count(label string, n int) {
+	if n > 0 {
+		s.line(label, n)
+	}
+}
+
+// restored appends the restored source count, or that restore was skipped
+// by -no-restore.
+func (s *summary) restored(cfg *modes.Config, n int) {
+	if cfg.NoRestore {
+		s.line("Sources restored:", "skipped (-no-restore)")
+		return
+	}
+	s.line("Sources restored:", n)
+}
+
+// matched appends how many sources matched -only, when it is in use.
+func (s *summary) matched(cfg *modes.Config, n int) {
+	if cfg.Filter != nil && len(cfg.Filter.Only) > 0 {
+		s.line("Sources matched:", n)
+	}
+}
+
+// budget reports a run stopped early by a -max-* limit.
+func (s *summary) budget(limit string, skipped int) {
+	if limit == "" {
+		return
+	}
+	s.add(ui.Warning(fmt.Sprintf("Stopped early: -%s reached, %s script(s)/map(s) not processed; results are partial", limit, ui.FormatCount(skipped))))
+}
+
+// deduped appends the bytes -cas saved, if any.
+func (s *summary) deduped(n int64) {
+	if n > 0 {
+		s.line("Deduplicated:", ui.FormatBytes(n))
+	}
+}
+
+// bundler appends the bundler most maps were built with, its confidence,
+// and how many maps it was guessed for, e.g. "Webpack (high, 12 of 14
+// maps)".
+func (s *summary) bundler(maps []modes.MapRecord) {
+	counts := make(map[string]int)
+	confidence := make(map[string]string)
+	parsed := 0
+	for _, m := range maps {
+		if m.Error != "" {
+			continue
+		}
+		parsed++
+		if m.Bundler == "" {
+			continue
+		}
+		counts[m.Bundler]++
+		if confidenceRank[m.BundlerConfidence] > confidenceRank[confidence[m.Bundler]] {
+			confidence[m.Bundler] = m.BundlerConfidence
+		}
+	}
+
+	best := ""
+	for name, n := range counts {
+		if n > counts[best] || (n == counts[best] && name < best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return
+	}
+	s.line("Bundler:", fmt.Sprintf("%s (%s, %s of %s maps)", best, confidence[best], ui.FormatCount(counts[best]), ui.FormatCount(parsed)))
+}
+
+// metro appends what the Metro metadata of the maps holds, e.g. "3
+// function map(s), 8 segment(s); modules: src/App.js, LoginScreen", when
+// any map has some.
+func (s *summary) metro(maps []modes.MapRecord) {
+	var functionMaps, segments int
+	var names []string
+	found := false
+	for _, m := range maps {
+		if m.Metro == nil {
+			continue
+		}
+		found = true
+		functionMaps += m.Metro.FunctionMaps
+		segments += m.Metro.Segments
+		for _, name := range m.Metro.ModuleNames {
+			if len(names) < metroNamesShown {
+				names = append(names, name)
+			}
+		}
+	}
+	if !found {
+		return
+	}
+	value := fmt.Sprintf("%s function map(s), %s segment(s)", ui.FormatCount(functionMaps), ui.FormatCount(segments))
+	if len(names) > 0 {
+		value += "; modules: " + strings.Join(names, ", ")
+	}
+	s.line("Metro:", value)
+}
+
+// metroNamesShown caps the module names the Metro line lists.
+const metroNamesShown = 5
+
+// confidenceRank orders bundler confidence levels so the summary can show
+// the highest one seen.
+var confidenceRank = map[string]int{
+	sourcemap.ConfidenceLow:    1,
+	sourcemap.ConfidenceMedium: 2,
+	sourcemap.ConfidenceHigh:   3,
+}
+
+// errors appends the error count and, in verbose mode, each error.
+func (s *summary) errors(errs []error, verbose bool) {
+	if len(errs) == 0 {
+		return
+	}
+	s.line("Errors:", len(errs))
+	if verbose {
+		for _, e := range errs {
+			s.add(fmt.Sprintf("      %s", ui.DimStyle.Render(fmt.Sprintf("- %v", e))))
+		}
+	}
+}
+
+// unmapped appends how many scripts had no sourcemap at all and, in
+// verbose mode, each of them.
+func (s *summary) unmapped(scripts []modes.ScriptRecord, verbose bool) {
+	unmapped := modes.Unmapped(scripts)
+	if len(unmapped) == 0 {
+		return
+	}
+	s.line("Unmapped scripts:", len(unmapped))
+	if verbose {
+		for _, script := range unmapped {
+			s.add(fmt.Sprintf("      %s", ui.DimStyle.Render("- "+script.URL)))
+		}
+	}
+}
+
+// decoys appends the count of decoy maps, the placeholders and encrypted
+// blobs served in place of sourcemaps, and lists each one so it can be
+// looked at.
+func (s *summary) decoys(maps []modes.MapRecord) {
+	decoys := modes.Decoys(maps)
+	if len(decoys) == 0 {
+		return
+	}
+	s.line("Decoy maps:", len(decoys))
+	for _, m := range decoys {
+		s.add(fmt.Sprintf("      %s", ui.DimStyle.Render(fmt.Sprintf("- %s (%s)", mapName(m), m.Decoy))))
+	}
+}
+
+// mapName returns the URL of a map, or its file path for local maps.
+func mapName(m modes.MapRecord) string {
+	if m.URL != "" {
+		return m.URL
+	}
+	return m.File
+}
+
+// warnings appends the warning count per category, e.g. "5 (filtered 3,
+// hook 2)", and in verbose mode each warning.
+func (s *summary) warnings(warnings []warn.Warning, verbose bool) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	counts := warn.Count(warnings)
+	categories := make([]warn.Category, 0, len(counts))
+	for c := range counts {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if counts[categories[i]] != counts[categories[j]] {
+			return counts[categories[i]] > counts[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+	parts := make([]string, 0, len(categories))
+	for _, c := range categories {
+		parts = append(parts, fmt.Sprintf("%s %s", c, ui.FormatCount(counts[c])))
+	}
+	s.line("Warnings:", fmt.Sprintf("%s (%s)", ui.FormatCount(len(warnings)), strings.Join(parts, ", ")))
+
+	if verbose {
+		for _, w := range warnings {
+			s.add(fmt.Sprintf("      %s", ui.DimStyle.Render(fmt.Sprintf("- [%s] %s", w.Category, w))))
+		}
+	}
+}
+
+// hosts appends what a -split-by-host run stored under each host.
+func (s *summary) hosts(hosts []modes.HostOutput) {
+	label := "By host:"
+	for _, h := range hosts {
+		s.line(label, fmt.Sprintf("%s: %s script(s), %s map(s), %s source(s)", h.Host,
+			ui.FormatCount(len(h.Scripts)), ui.FormatCount(len(h.Maps)), ui.FormatCount(h.SourcesRestored)))
+		label = ""
+	}
+}
+
+// outputs appends the absolute paths of a domain directory's standard
+// directories and of the reports and manifests written beside them.
+func (s *summary) outputs(dir string) {
+	if dir == "" {
+		return
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	s.line("Output:", dir)
+	for _, d := range outputDirs {
+		s.line(d.label, filepath.Join(dir, d.name))
+	}
+
+	label := "Reports:"
+	for _, name := range reportNames(dir) {
+		s.line(label, filepath.Join(dir, name))
+		label = ""
+	}
+}
+
+// flatOutputs appends the paths of a -flat local run: the directory the
+// maps were read from and the directories restored to under dir. The
+// reports aren't listed, since dir may be a parent with unrelated files.
+func (s *summary) flatOutputs(target string, dirs []string) {
+	if len(dirs) == 0 {
+		return
+	}
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	s.line("Input:", target)
+	for _, d := range outputDirs[1:] {
+		s.line(d.label, filepath.Join(dirs[0], d.name))
+	}
+}
+
+// outputsAll appends the output paths of one domain directory, or gives
+// each of several domain directories a box of its own.
+func (s *summary) outputsAll(dirs []string) {
+	if len(dirs) == 1 {
+		s.outputs(dirs[0])
+		return
+	}
+	for _, dir := range dirs {
+		d := &summary{}
+		d.outputs(dir)
+		s.domains = append(s.domains, d)
+	}
+}
+
+// reportNames lists what a run wrote to a domain directory besides the
+// standard directories, such as scripts.csv or licenses.json. Directories
+// get a trailing slash; hidden entries are left out.
+func reportNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	standard := make(map[string]bool, len(outputDirs))
+	for _, d := range outputDirs {
+		standard[d.name] = true
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if standard[name] || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if e.IsDir() {
+			name += string(filepath.Separator)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// print renders the summary header, the collected lines in a box, and the
+// box of each domain.
+func (s *summary) print() {
+	fmt.Fprintln(display, ui.SummaryHeader())
+	for _, box := range append([]*summary{s}, s.domains...) {
+		if len(box.lines) > 0 {
+			fmt.Fprintln(display, ui.RenderSummaryBox(box.render()...))
+		}
+	}
+	fmt.Fprintln(display)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got to testdata/name, or rewrites it with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n%s", path, got)
+	}
+}
+
+// printSummary prints s as a run would and returns the output.
+func printSummary(t *testing.T, s *summary) string {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := display
+	display = &buf
+	defer func() { display = saved }()
+	s.print()
+
+	out := buf.String()
+	if strings.Contains(out, "\x1b[") {
+		t.Fatalf("summary is styled outside a terminal:\n%q", out)
+	}
+	return out
+}
+
+func TestSummaryGolden(t *testing.T) {
+	cfg := modes.DefaultConfig()
+	maps := []modes.MapRecord{
+		{Bundler: "webpack", BundlerConfidence: "high"},
+		{Bundler: "webpack", BundlerConfidence: "medium"},
+		{Bundler: "vite", BundlerConfidence: "low"},
+		{Error: "not a sourcemap"},
+		{Bundler: "metro", BundlerConfidence: "high", Metro: &sourcemap.MetroMetadata{
+			FunctionMaps: 3, Segments: 8, ModuleIDs: 3, ModuleNames: []string{"define", "src/App.js", "LoginScreen"},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		golden  string
+		verbose bool
+	}{
+		{"plain", "summary.golden", false},
+		{"verbose", "summary-verbose.golden", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &summary{}
+			s.line("Scripts found:", 1204)
+			s.line("Maps found:", 3)
+			s.restored(cfg, 12438)
+			s.line("Assets extracted:", 7)
+			s.count("Out of scope:", 0)
+			s.count("Filtered:", 1500)
+			s.deduped(3 << 20)
+			s.bundler(maps)
+			s.metro(maps)
+			s.warnings([]warn.Warning{
+				warn.New(warn.FormatSkipped, "src/locales.js", "left unformatted: %d bytes is over -format-max-size", 4<<20),
+				warn.New(warn.Filtered, "https://example.com/ads.js", "excluded by -exclude-url"),
+				warn.New(warn.Filtered, "https://example.com/tracker.js", "excluded by -exclude-url"),
+			}, tt.verbose)
+			s.errors([]error{errors.New("failed to download https://example.com/app.js.map: 404")}, tt.verbose)
+			s.budget("max-scripts", 12)
+			checkGolden(t, tt.golden, printSummary(t, s))
+		})
+	}
+}
+
+func TestSummaryNoRestore(t *testing.T) {
+	cfg := modes.DefaultConfig()
+	cfg.NoRestore = true
+	s := &summary{}
+	s.line("Scripts found:", 42)
+	s.restored(cfg, 0)
+	checkGolden(t, "summary-no-restore.golden", printSummary(t, s))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/disk"
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// targetJSON is one target of url -json.
+type targetJSON struct {
+	schema.Versioned
+	DejankVersion    string         `json:"dejank_version"`
+	URL              string         `json:"url"`
+	FinalURL         string         `json:"final_url,omitempty"`
+	OutputDir        string         `json:"output_dir,omitempty"`
+	ScriptsFound     int            `json:"scripts_found"`
+	MapsDiscovered   int            `json:"maps_discovered"`
+	MapsSeen         int            `json:"maps_seen,omitempty"`
+	MapsTemplated    int            `json:"maps_templated,omitempty"`       // Maps found at -map-url-template
+	ScriptsUnmapped  []string       `json:"scripts_without_maps,omitempty"` // Scripts that had no sourcemap at all
+	DecoyMaps        []string       `json:"decoy_maps,omitempty"`           // Maps holding a placeholder or encrypted blob, not counted in MapsDiscovered
+	SourcesRestored  int            `json:"sources_restored"`
+	AssetsExtracted  int            `json:"assets_extracted"`
+	EnvVarsExtracted int            `json:"env_vars_extracted"`
+	Errors           []string       `json:"errors"`
+	Warnings         []warn.Warning `json:"warnings"`
+	Error            string         `json:"error,omitempty"` // Why the run failed outright
+
+	modes.Analysis
+}
+
+// runTargets runs url mode over several targets, or over one with -json,
+// and prints a row per target. It exits non-zero if any target failed.
+func runTargets(cfg *modes.Config, targets []string, parallel int, jsonOut bool, exportTo string) {
+	if jsonOut {
+		logToStderr(cfg)
+	} else {
+		fmt.Fprintln(display, ui.Banner(version))
+		for _, target := range targets {
+			fmt.Fprint(display, ui.Target(target))
+		}
+		fmt.Fprintln(display)
+	}
+	if !cfg.Resume {
+		requireChrome(cfg)
+	}
+
+	var progress *ui.Progress
+	if !jsonOut && !cfg.Verbose {
+		progress = ui.NewProgress(len(targets), fmt.Sprintf("Running %d targets, %d at a time", len(targets), min(parallel, len(targets))))
+		cfg.OnProgress = chainProgress(cfg.OnProgress, func(event string, data interface{}) {
+			if event == "target_complete" {
+				progress.Increment()
+			}
+		})
+	}
+
+	results := modes.RunURLs(cfg, targets, parallel)
+	if progress != nil {
+		progress.Done()
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil || (r.Result != nil && disk.First(r.Result.Errors) != nil) {
+			failed++
+		}
+	}
+
+	if jsonOut {
+		out := make([]targetJSON, len(results))
+		for i, r := range results {
+			out[i] = newTargetJSON(r)
+		}
+		data, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		printTargets(results)
+		var dirs []string
+		for _, r := range results {
+			if r.Result != nil {
+				dirs = append(dirs, r.Result.OutputDirs()...)
+			}
+		}
+		var s summary
+		s.line("Targets:", len(results))
+		s.line("Succeeded:", len(results)-failed)
+		s.count("Failed:", failed)
+		s.outputsAll(dirs)
+		s.print()
+		printNoRestoreHint(cfg, dirs...)
+		writeSARIF(*sarifPath, cfg.OutputRoot, cfg.Verbose, dirs...)
+		writeSBOM(*sbomPath, sbomTarget(cfg, targets), cfg.OutputRoot, cfg.Verbose, dirs...)
+		openOutput(cfg, dirs...)
+	}
+
+	var urls []modes.DiscoveredURL
+	for _, r := range results {
+		if r.Result != nil {
+			urls = append(urls, r.Result.URLs...)
+		}
+	}
+	exportURLs(exportTo, urls, jsonOut)
+	for _, r := range results {
+		notify("url", newTargetJSON(r))
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// newTargetJSON converts one target's outcome for url -json.
+func newTargetJSON(r modes.TargetResult) targetJSON {
+	t := targetJSON{Versioned: schema.Current(), DejankVersion: version, URL: r.URL, Errors: []string{}, Warnings: []warn.Warning{}}
+	if r.Err != nil {
+		t.Error = r.Err.Error()
+		return t
+	}
+	res := r.Result
+	t.FinalURL = res.FinalURL
+	t.OutputDir = res.OutputDir
+	t.ScriptsFound = res.ScriptsFound
+	t.MapsDiscovered = res.MapsDiscovered
+	t.MapsSeen = res.MapsSeen
+	t.MapsTemplated = res.MapsTemplated
+	t.ScriptsUnmapped = unmappedURLs(res.Scripts)
+	t.DecoyMaps = decoyMaps(res.Maps)
+	t.SourcesRestored = res.SourcesRestored
+	t.AssetsExtracted = res.AssetsExtracted
+	t.EnvVarsExtracted = res.EnvVarsExtracted
+	t.Analysis = res.Analysis
+	for _, err := range res.Errors {
+		t.Errors = append(t.Errors, err.Error())
+	}
+	if res.Warnings != nil {
+		t.Warnings = res.Warnings
+	}
+	return t
+}
+
+// sbomTarget names the top-level component of a multi-target run's SBOM:
+// the one target, or the output directory the targets share.
+func sbomTarget(cfg *modes.Config, targets []string) string {
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	return cfg.OutputRoot
+}
+
+// unmappedURLs lists the scripts that had no sourcemap at all.
+func unmappedURLs(scripts []modes.ScriptRecord) []string {
+	var urls []string
+	for _, s := range modes.Unmapped(scripts) {
+		urls = append(urls, s.URL)
+	}
+	return urls
+}
+
+// decoyMaps lists the maps that were found to be decoys, by URL or, for
+// local files, by path.
+func decoyMaps(maps []modes.MapRecord) []string {
+	var names []string
+	for _, m := range modes.Decoys(maps) {
+		names = append(names, mapName(m))
+	}
+	return names
+}
+
+// printTargets prints a row per target of a multi-target url run.
+func printTargets(results []modes.TargetResult) {
+	width := len("TARGET")
+	for _, r := range results {
+		width = max(width, len(r.URL))
+	}
+
+	fmt.Fprintln(display)
+	fmt.Fprintf(display, "  %s %s %s %s %s\n",
+		ui.AccentStyle.Render(fmt.Sprintf("%-*s", width, "TARGET")),
+		ui.AccentStyle.Render(fmt.Sprintf("%8s", "SCRIPTS")),
+		ui.AccentStyle.Render(fmt.Sprintf("%6s", "MAPS")),
+		ui.AccentStyle.Render(fmt.Sprintf("%8s", "SOURCES")),
+		ui.AccentStyle.Render(fmt.Sprintf("%7s", "ERRORS")))
+	for _, r := range results {
+		target := ui.URLStyle.Render(fmt.Sprintf("%-*s", width, r.URL))
+		if r.Err != nil {
+			fmt.Fprintf(display, "  %s %s\n", target, ui.ErrorStyle.Render("failed: "+r.Err.Error()))
+			continue
+		}
+		res := r.Result
+		fmt.Fprintf(display, "  %s %s %s %s %s\n", target,
+			ui.TextStyle.Render(fmt.Sprintf("%8d", res.ScriptsFound)),
+			ui.TextStyle.Render(fmt.Sprintf("%6d", res.MapsDiscovered)),
+			ui.TextStyle.Render(fmt.Sprintf("%8d", res.SourcesRestored)),
+			ui.TextStyle.Render(fmt.Sprintf("%7d", len(res.Errors))))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+func runVerify(cfg *modes.Config, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing directory argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank verify <domain-dir>"))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[0]))
+
+	result, err := modes.RunVerify(args[0])
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	for _, name := range result.Modified {
+		fmt.Fprintln(display, ui.Error("Modified: "+name))
+	}
+	for _, name := range result.Missing {
+		fmt.Fprintln(display, ui.Error("Missing:  "+name))
+	}
+	if cfg.Verbose {
+		for _, name := range result.Unlisted {
+			fmt.Fprintln(display, ui.Info("Unlisted: "+name))
+		}
+	}
+
+	var s summary
+	s.line("Files checked:", result.Checked)
+	s.line("Modified:", len(result.Modified))
+	s.line("Missing:", len(result.Missing))
+	s.count("Not in checksums:", len(result.Unlisted))
+	s.print()
+
+	if !result.OK() {
+		os.Exit(1)
+	}
+}
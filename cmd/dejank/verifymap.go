@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+type verifyMapFlags struct {
+	fs     *flag.FlagSet
+	sample *int
+}
+
+func newVerifyMapFlags() *verifyMapFlags {
+	fs := newFlagSet("verify-map")
+	return &verifyMapFlags{
+		fs:     fs,
+		sample: fs.Int("sample", sourcemap.DefaultVerifySample, "Check up to `n` mappings, evenly spaced through the map"),
+	}
+}
+
+func runVerifyMap(cfg *modes.Config, args []string) {
+	f := newVerifyMapFlags()
+	args = parseFlags(f.fs, args)
+
+	if len(args) < 2 {
+		fmt.Fprintln(display, ui.Error("Missing bundle or sourcemap argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank verify-map <bundle.js> <bundle.js.map>"))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[1]))
+
+	result, err := modes.RunVerifyMap(args[0], args[1], *f.sample)
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	if cfg.Verbose {
+		for _, m := range result.Mismatches {
+			msg := fmt.Sprintf("%d:%d (%s) %s", m.GeneratedLine, m.GeneratedColumn, m.Source, m.Reason)
+			if m.Generated != "" {
+				msg += fmt.Sprintf(": %q vs %q", m.Generated, m.Original)
+			}
+			fmt.Fprintln(display, ui.Warning(msg))
+		}
+	}
+
+	var s summary
+	s.line("Mappings:", result.Mappings)
+	s.line("Checked:", result.Checked)
+	s.line("Consistent:", fmt.Sprintf("%s (%.1f%%)", ui.FormatCount(result.Matched), result.Percent()))
+	s.count("Out of range:", result.OutOfRange)
+	s.count("No sourcesContent:", result.WithoutContent)
+	s.print()
+
+	switch result.Verdict() {
+	case sourcemap.Mismatched:
+		fmt.Fprintln(display, ui.Error(fmt.Sprintf("%s does not appear to be the sourcemap of %s", args[1], args[0])))
+		os.Exit(1)
+	case sourcemap.Inconclusive:
+		fmt.Fprintln(display, ui.Warning("Too few mappings to judge whether the map matches the bundle"))
+	default:
+		fmt.Fprintln(display, ui.Success("Map matches bundle"))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/viewer"
+)
+
+// reportFormats lists the formats dejank report writes.
+var reportFormats = []string{"html-viewer"}
+
+type reportFlags struct {
+	fs         *flag.FlagSet
+	format     *string
+	out        *string
+	maxPreview byteSize
+	budget     byteSize
+}
+
+func newReportFlags() *reportFlags {
+	fs := newFlagSet("report")
+	f := &reportFlags{
+		fs:         fs,
+		format:     fs.String("format", "html-viewer", "Report `format`: html-viewer"),
+		out:        fs.String("out", "", "Write the report to `file` (default report.html in the run directory)"),
+		maxPreview: viewer.DefaultMaxPreview,
+		budget:     viewer.DefaultBudget,
+	}
+	fs.Var(&f.maxPreview, "max-preview", "Embed files up to `size` for preview; larger ones are linked")
+	fs.Var(&f.budget, "embed-budget", "Embed at most `size` of file content in total")
+	return f
+}
+
+func runReport(cfg *modes.Config, args []string) {
+	f := newReportFlags()
+	args = parseFlags(f.fs, args)
+
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing directory argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank report [-format html-viewer] [-out file] <domain-dir>"))
+		os.Exit(1)
+	}
+	if *f.format != "html-viewer" {
+		fmt.Fprintln(display, ui.Error(fmt.Sprintf("Unknown report format %q (supported: html-viewer)", *f.format)))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(args[0]))
+
+	result, err := modes.RunViewer(cfg, args[0], *f.out, int64(f.maxPreview), int64(f.budget))
+	if err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+
+	var s summary
+	s.line("Files listed:", result.Files)
+	s.line("Previewable:", result.Embedded)
+	s.line("Findings:", result.Findings)
+	s.errors(result.Errors, cfg.Verbose)
+	s.line("Report:", result.Path)
+	s.print()
+}
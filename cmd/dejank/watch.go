@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// watchFlags holds the flags accepted by the watch command.
+type watchFlags struct {
+	fs       *flag.FlagSet
+	interval *time.Duration
+	keep     *int
+	notify   *string
+}
+
+func newWatchFlags() *watchFlags {
+	fs := newFlagSet("watch")
+	return &watchFlags{
+		fs:       fs,
+		interval: fs.Duration("interval", 6*time.Hour, "Time between runs"),
+		keep:     fs.Int("keep", 10, "Number of historical runs to keep on disk (0 = unlimited)"),
+		notify:   fs.String("notify", "", "Webhook `url` that receives a JSON payload when changes are found"),
+	}
+}
+
+func runWatch(cfg *modes.Config, args []string) {
+	f := newWatchFlags()
+	args = parseFlags(f.fs, args)
+	interval, keep, notify := f.interval, f.keep, f.notify
+
+	if len(args) < 1 {
+		fmt.Fprintln(display, ui.Error("Missing URL argument"))
+		fmt.Fprintln(display, ui.DimStyle.Render("Usage: dejank watch <webpage-url> [-interval 6h] [-keep 10] [-notify <url>]"))
+		os.Exit(1)
+	}
+
+	targetURL := args[0]
+	requireChrome(cfg)
+	fmt.Fprintln(display, ui.Banner(version))
+	fmt.Fprintln(display, ui.Target(targetURL))
+	fmt.Fprintln(display, ui.Info(fmt.Sprintf("Watching every %s (Ctrl+C to stop)", ui.FormatDuration(*interval))))
+
+	opts := modes.WatchOptions{
+		Interval:  *interval,
+		Keep:      *keep,
+		NotifyURL: *notify,
+		OnRun: func(run *modes.WatchRun) {
+			printWatchRun(run, cfg.Verbose)
+		},
+	}
+
+	if err := modes.RunWatch(cfg.Context, cfg, targetURL, opts); err != nil {
+		fmt.Fprintln(display, ui.Error(err.Error()))
+		os.Exit(1)
+	}
+}
+
+func printWatchRun(run *modes.WatchRun, verbose bool) {
+	stamp := run.Started.Format("2006-01-02 15:04:05 UTC")
+	if run.Err != nil {
+		fmt.Fprintln(display, ui.Warning(fmt.Sprintf("Run %s failed: %v", stamp, run.Err)))
+		return
+	}
+
+	delta := run.Delta
+	if delta.Previous == "" {
+		fmt.Fprintln(display, ui.Success(fmt.Sprintf("Run %s: baseline captured (%s scripts, %s maps)",
+			stamp, ui.FormatCount(len(delta.NewScripts)), ui.FormatCount(len(delta.NewMaps)))))
+		return
+	}
+
+	if delta.Empty() {
+		fmt.Fprintln(display, ui.Info(fmt.Sprintf("Run %s: no changes", stamp)))
+		return
+	}
+
+	fmt.Fprintln(display, ui.Success(fmt.Sprintf("Run %s: changes detected", stamp)))
+	width := ui.SummaryWidth(len(delta.NewScripts), len(delta.NewMaps), len(delta.NewEnvVars), len(delta.NewSecrets))
+	fmt.Fprintln(display, ui.SummaryLineWidth("New scripts:", len(delta.NewScripts), width))
+	fmt.Fprintln(display, ui.SummaryLineWidth("New maps:", len(delta.NewMaps), width))
+	fmt.Fprintln(display, ui.SummaryLineWidth("New env vars:", len(delta.NewEnvVars), width))
+	fmt.Fprintln(display, ui.SummaryLineWidth("New secrets:", len(delta.NewSecrets), width))
+
+	for _, name := range delta.NewScripts {
+		fmt.Fprintf(display, "      %s\n", ui.DimStyle.Render("+ "+name))
+	}
+	for _, name := range delta.NewMaps {
+		fmt.Fprintf(display, "      %s\n", ui.DimStyle.Render("+ "+name))
+	}
+	keys := make([]string, 0, len(delta.NewEnvVars))
+	for k := range delta.NewEnvVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(display, "      %s\n", ui.DimStyle.Render(fmt.Sprintf("+ %s=%s", k, delta.NewEnvVars[k])))
+	}
+	for _, s := range delta.NewSecrets {
+		fmt.Fprintf(display, "      %s\n", ui.DimStyle.Render(fmt.Sprintf("+ %s (%s)", s.Message, s.File)))
+	}
+
+	if verbose {
+		for _, e := range run.Result.Errors {
+			fmt.Fprintf(display, "      %s\n", ui.DimStyle.Render(fmt.Sprintf("- %v", e)))
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/thesavant42/dejank/internal/disk"
 	"github.com/thesavant42/dejank/internal/fetch"
 )
 
@@ -24,12 +25,20 @@ var (
 // DownloadResult contains the results of a webpack asset download operation.
 type DownloadResult struct {
 	DownloadedCount int
+	FilteredCount   int // Files skipped by the filter
 	Errors          []error
 }
 
 // DownloadWebpackAssets scans restored sources for webpack asset references,
 // downloads the actual assets, and replaces the fake loader files in-place.
 func DownloadWebpackAssets(baseURL, inputDir string, client *fetch.Client) DownloadResult {
+	return DownloadWebpackAssetsFiltered(baseURL, inputDir, client, nil)
+}
+
+// DownloadWebpackAssetsFiltered is DownloadWebpackAssets limited to the
+// files allow accepts, given their slash-separated path relative to
+// inputDir (nil = all files).
+func DownloadWebpackAssetsFiltered(baseURL, inputDir string, client *fetch.Client, allow func(path string) bool) DownloadResult {
 	result := DownloadResult{}
 
 	// Parse base URL to construct asset URLs
@@ -51,10 +60,17 @@ func DownloadWebpackAssets(baseURL, inputDir string, client *fetch.Client) Downl
 		if d.IsDir() {
 			return nil
 		}
+		if !allowed(allow, inputDir, path) {
+			result.FilteredCount++
+			return nil
+		}
 
 		downloaded, downloadErr := processWebpackAsset(path, origin, client)
 		if downloadErr != nil {
 			result.Errors = append(result.Errors, downloadErr)
+			if disk.Is(downloadErr) {
+				return filepath.SkipAll
+			}
 			return nil
 		}
 
@@ -108,6 +124,9 @@ func processWebpackAsset(filePath, origin string, client *fetch.Client) (bool, e
 
 	// Write the actual asset content
 	if err := os.WriteFile(newPath, assetData, 0644); err != nil {
+		if err = disk.Check(newPath, err); disk.Is(err) {
+			return false, err
+		}
 		return false, fmt.Errorf("failed to write asset %s: %w", newPath, err)
 	}
 
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/thesavant42/dejank/internal/disk"
 )
 
 var (
@@ -36,11 +38,19 @@ var mimeToExt = map[string]string{
 // ExtractResult contains the results of an extraction operation.
 type ExtractResult struct {
 	ExtractedCount int
+	FilteredCount  int // Files skipped by the filter
 	Errors         []error
 }
 
 // ExtractFromDirectory walks a directory and extracts base64 assets from all files.
 func ExtractFromDirectory(inputDir, outputDir string) ExtractResult {
+	return ExtractFromDirectoryFiltered(inputDir, outputDir, nil)
+}
+
+// ExtractFromDirectoryFiltered is ExtractFromDirectory limited to the files
+// allow accepts, given their slash-separated path relative to inputDir
+// (nil = all files).
+func ExtractFromDirectoryFiltered(inputDir, outputDir string, allow func(path string) bool) ExtractResult {
 	result := ExtractResult{}
 
 	err := filepath.WalkDir(inputDir, func(path string, d os.DirEntry, err error) error {
@@ -52,10 +62,18 @@ func ExtractFromDirectory(inputDir, outputDir string) ExtractResult {
 		if d.IsDir() {
 			return nil
 		}
+		if !allowed(allow, inputDir, path) {
+			result.FilteredCount++
+			return nil
+		}
 
 		extracted, err := ExtractFromFile(path, outputDir)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
+			if disk.Is(err) {
+				// Every later write would fail the same way
+				return filepath.SkipAll
+			}
 			return nil
 		}
 
@@ -107,11 +125,17 @@ func ExtractFromFile(filePath, outputDir string) (string, error) {
 
 	// Create output directory
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		if err = disk.Check(outputDir, err); disk.Is(err) {
+			return "", err
+		}
 		return "", fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Write decoded file
 	if err := os.WriteFile(outputPath, decoded, 0644); err != nil {
+		if err = disk.Check(outputPath, err); disk.Is(err) {
+			return "", err
+		}
 		return "", fmt.Errorf("failed to write extracted asset: %w", err)
 	}
 
@@ -154,3 +178,14 @@ func stripAllExtensions(filename string) string {
 	return filename
 }
 
+// allowed reports whether allow accepts path, a file under dir.
+func allowed(allow func(path string) bool, dir, path string) bool {
+	if allow == nil {
+		return true
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return true
+	}
+	return allow(filepath.ToSlash(rel))
+}
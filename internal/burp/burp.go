@@ -0,0 +1,277 @@
+// Package burp reads the responses saved by intercepting proxies: Burp
+// Suite's XML "Save items" export and HAR exports such as ZAP's. Stored
+// responses are decoded back to their bodies, undoing chunked transfer
+// encoding and gzip or deflate content encoding.
+package burp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Item is one request/response pair of an export.
+type Item struct {
+	URL         string
+	Status      int         // HTTP status of the response, 0 if there was none
+	ContentType string      // Content-Type of the response, or the MIME type the proxy recorded
+	Header      http.Header // Response headers, nil if the export has none
+	Body        []byte      // Decoded response body
+	Err         error       // Why the response couldn't be decoded; Body is what was recovered
+}
+
+// ErrUnknownFormat is returned for a file that is neither a Burp XML
+// export nor a HAR file.
+var ErrUnknownFormat = errors.New("not a Burp XML export or a HAR file")
+
+// ReadFile reads the export at path; see Read.
+func ReadFile(path string) ([]Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	items, err := Read(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return items, nil
+}
+
+// Read parses a Burp XML export or a HAR file, telling them apart by
+// their first character.
+func Read(data []byte) ([]Item, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if len(trimmed) == 0 {
+		return nil, ErrUnknownFormat
+	}
+	switch trimmed[0] {
+	case '<':
+		return readXML(trimmed)
+	case '{':
+		return readHAR(trimmed)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// xmlItems is the layout of a Burp XML export.
+type xmlItems struct {
+	XMLName xml.Name  `xml:"items"`
+	Items   []xmlItem `xml:"item"`
+}
+
+type xmlItem struct {
+	URL      string  `xml:"url"`
+	Status   string  `xml:"status"`
+	MIMEType string  `xml:"mimetype"`
+	Response xmlBody `xml:"response"`
+}
+
+type xmlBody struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",chardata"`
+}
+
+func readXML(data []byte) ([]Item, error) {
+	var export xmlItems
+	if err := xml.Unmarshal(data, &export); err != nil {
+		// Well-formed XML with another root element
+		var other xml.UnmarshalError
+		if errors.As(err, &other) {
+			return nil, ErrUnknownFormat
+		}
+		return nil, fmt.Errorf("invalid Burp XML: %w", err)
+	}
+
+	items := make([]Item, 0, len(export.Items))
+	for _, x := range export.Items {
+		item := Item{URL: strings.TrimSpace(x.URL), ContentType: strings.TrimSpace(x.MIMEType)}
+		item.Status, _ = strconv.Atoi(strings.TrimSpace(x.Status))
+
+		raw := []byte(x.Response.Data)
+		if x.Response.Base64 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(x.Response.Data))
+			if err != nil {
+				item.Err = fmt.Errorf("invalid base64 response: %w", err)
+				items = append(items, item)
+				continue
+			}
+			raw = decoded
+		}
+		if len(raw) > 0 {
+			decodeRaw(&item, raw)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodeRaw fills in item from a raw HTTP response as the proxy stored
+// it, status line and headers included.
+func decodeRaw(item *Item, raw []byte) {
+	headerEnd, sepLen := bytes.Index(raw, []byte("\r\n\r\n")), 4
+	if headerEnd < 0 {
+		headerEnd, sepLen = bytes.Index(raw, []byte("\n\n")), 2
+	}
+	if !bytes.HasPrefix(raw, []byte("HTTP/")) || headerEnd < 0 {
+		// Some exports keep only the body
+		item.Body = raw
+		return
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw[:headerEnd+sepLen])))
+	statusLine, err := tp.ReadLine()
+	if err == nil {
+		if _, code, ok := strings.Cut(statusLine, " "); ok {
+			code, _, _ = strings.Cut(code, " ")
+			if n, err := strconv.Atoi(code); err == nil {
+				item.Status = n
+			}
+		}
+	}
+	mime, err := tp.ReadMIMEHeader()
+	if err != nil && len(mime) == 0 {
+		item.Err = fmt.Errorf("invalid response headers: %w", err)
+	}
+	item.Header = http.Header(mime)
+	if ct := item.Header.Get("Content-Type"); ct != "" {
+		item.ContentType = ct
+	}
+
+	item.Body, err = decodeBody(item.Header, raw[headerEnd+sepLen:])
+	if err != nil && item.Err == nil {
+		item.Err = err
+	}
+}
+
+// decodeBody undoes the transfer and content encodings of a stored body.
+// The proxy may have already undone them without updating the headers, so
+// a body that doesn't decode is kept as stored. A body cut off partway is
+// decoded as far as it goes, with an error.
+func decodeBody(header http.Header, body []byte) ([]byte, error) {
+	var truncated error
+	if strings.Contains(strings.ToLower(header.Get("Transfer-Encoding")), "chunked") {
+		unchunked, err := io.ReadAll(httputil.NewChunkedReader(bytes.NewReader(body)))
+		switch {
+		case err == nil:
+			body = unchunked
+		case len(unchunked) > 0:
+			body, truncated = unchunked, fmt.Errorf("truncated chunked body: %w", err)
+		}
+	}
+
+	decoded, err := decodeContent(strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))), body)
+	if err == nil {
+		err = truncated
+	}
+	return decoded, err
+}
+
+// decodeContent undoes the content encoding of a body.
+func decodeContent(encoding string, body []byte) ([]byte, error) {
+	// Stored gzip is recognized by its magic number even if the header was
+	// stripped
+	if encoding == "" && bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		encoding = "gzip"
+	}
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return body, nil
+		}
+		decoded, err := io.ReadAll(zr)
+		if err != nil && len(decoded) == 0 {
+			return body, nil
+		}
+		if err != nil {
+			return decoded, fmt.Errorf("truncated gzip body: %w", err)
+		}
+		return decoded, nil
+	case "deflate":
+		// Servers send both zlib-wrapped and raw deflate as "deflate"
+		if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			if decoded, err := io.ReadAll(zr); err == nil {
+				return decoded, nil
+			}
+		}
+		if decoded, err := io.ReadAll(flate.NewReader(bytes.NewReader(body))); err == nil {
+			return decoded, nil
+		}
+		return body, nil
+	}
+	return body, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+}
+
+// harFile is the part of a HAR file that holds responses.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		Content struct {
+			MIMEType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+func readHAR(data []byte) ([]Item, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR: %w", err)
+	}
+	if har.Log.Entries == nil {
+		return nil, ErrUnknownFormat
+	}
+
+	items := make([]Item, 0, len(har.Log.Entries))
+	for _, e := range har.Log.Entries {
+		resp := e.Response
+		item := Item{URL: e.Request.URL, Status: resp.Status, ContentType: resp.Content.MIMEType, Header: make(http.Header)}
+		for _, h := range resp.Headers {
+			item.Header.Add(h.Name, h.Value)
+		}
+
+		// HAR content is already decoded text, or base64 of the bytes
+		item.Body = []byte(resp.Content.Text)
+		if resp.Content.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(resp.Content.Text)
+			if err != nil {
+				item.Body = nil
+				item.Err = fmt.Errorf("invalid base64 content: %w", err)
+			} else {
+				item.Body = decoded
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
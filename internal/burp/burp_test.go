@@ -0,0 +1,160 @@
+package burp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadXML(t *testing.T) {
+	items, err := ReadFile("testdata/items.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("read %d items, want 3", len(items))
+	}
+
+	// Base64, chunked and gzipped
+	js := items[0]
+	if js.URL != "https://app.example.com/static/js/main.js" || js.Status != 200 || js.Err != nil {
+		t.Errorf("items[0] = %s %d %v", js.URL, js.Status, js.Err)
+	}
+	if js.ContentType != "application/javascript" {
+		t.Errorf("ContentType = %q, want the header's over the proxy's MIME type", js.ContentType)
+	}
+	if want := "console.log(\"main\");\n//# sourceMappingURL=main.js.map\n"; string(js.Body) != want {
+		t.Errorf("Body = %q, want %q", js.Body, want)
+	}
+
+	// Plain text with LF line endings
+	m := items[1]
+	if m.ContentType != "application/json" || m.Err != nil {
+		t.Errorf("items[1] = %q %v", m.ContentType, m.Err)
+	}
+	if want := `{"version":3,"sources":["src/main.js"],"mappings":"AAAA"}`; string(m.Body) != want {
+		t.Errorf("Body = %q, want %q", m.Body, want)
+	}
+
+	// No response
+	if none := items[2]; none.Status != 0 || none.Body != nil || none.Header != nil || none.Err != nil {
+		t.Errorf("items[2] = %+v, want no response", none)
+	}
+}
+
+func TestReadHAR(t *testing.T) {
+	items, err := ReadFile("testdata/export.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("read %d items, want 3", len(items))
+	}
+
+	if js := items[0]; string(js.Body) != "console.log(1);\n" || js.ContentType != "text/javascript" || js.Header.Get("SourceMap") != "/static/js/app.js.map" {
+		t.Errorf("text entry = %+v", js)
+	}
+	if m := items[1]; string(m.Body) != `{"version":3,"sources":[]}` || m.Err != nil {
+		t.Errorf("base64 entry body = %q, err %v", m.Body, m.Err)
+	}
+	if broken := items[2]; broken.Err == nil || broken.Body != nil {
+		t.Errorf("invalid base64 entry = %q, err %v, want an error", broken.Body, broken.Err)
+	}
+}
+
+func TestReadFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		items int
+		err   error
+	}{
+		{"xml", `<items><item><url>https://a.example/a.js</url><response>x</response></item></items>`, 1, nil},
+		{"xml after BOM and space", "\ufeff\n  <?xml version=\"1.0\"?><items></items>", 0, nil},
+		{"har", `{"log":{"entries":[{"request":{"url":"https://a.example/a.js"}}]}}`, 1, nil},
+		{"har without entries", `{"log":{"entries":[]}}`, 0, nil},
+		{"other xml", `<html><body></body></html>`, 0, ErrUnknownFormat},
+		{"other json", `{"version":3,"mappings":""}`, 0, ErrUnknownFormat},
+		{"javascript", `console.log(1)`, 0, ErrUnknownFormat},
+		{"empty", " \n", 0, ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		items, err := Read([]byte(tt.data))
+		if !errors.Is(err, tt.err) || len(items) != tt.items {
+			t.Errorf("%s: Read = %d items, %v, want %d, %v", tt.name, len(items), err, tt.items, tt.err)
+		}
+	}
+
+	if _, err := Read([]byte(`<items><item>`)); err == nil || errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Read of truncated XML = %v, want a parse error", err)
+	}
+	if _, err := Read([]byte(`{"log":`)); err == nil || errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Read of truncated HAR = %v, want a parse error", err)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	const js = "console.log(1);\n"
+	var gz, zl, fl bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(js))
+	gw.Close()
+	zw := zlib.NewWriter(&zl)
+	zw.Write([]byte(js))
+	zw.Close()
+	fw, _ := flate.NewWriter(&fl, flate.DefaultCompression)
+	fw.Write([]byte(js))
+	fw.Close()
+
+	chunked := "5\r\nconso\r\nb\r\nle.log(1);\n\r\n0\r\n\r\n"
+
+	tests := []struct {
+		name    string
+		header  map[string]string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{"plain", nil, js, js, false},
+		{"identity", map[string]string{"Content-Encoding": "identity"}, js, js, false},
+		{"chunked", map[string]string{"Transfer-Encoding": "chunked"}, chunked, js, false},
+		{"truncated chunked", map[string]string{"Transfer-Encoding": "chunked"}, chunked[:15], "console", true},
+		{"already unchunked", map[string]string{"Transfer-Encoding": "chunked"}, js, js, false},
+		{"gzip by header", map[string]string{"Content-Encoding": "gzip"}, gz.String(), js, false},
+		{"gzip by magic", nil, gz.String(), js, false},
+		{"truncated gzip", map[string]string{"Content-Encoding": "gzip"}, gz.String()[:gz.Len()-8], js, true},
+		{"already gunzipped", map[string]string{"Content-Encoding": "gzip"}, js, js, false},
+		{"chunked gzip", map[string]string{"Transfer-Encoding": "chunked", "Content-Encoding": "gzip"}, chunk(gz.String()), js, false},
+		{"zlib deflate", map[string]string{"Content-Encoding": "deflate"}, zl.String(), js, false},
+		{"raw deflate", map[string]string{"Content-Encoding": "deflate"}, fl.String(), js, false},
+		{"already inflated", map[string]string{"Content-Encoding": "deflate"}, js, js, false},
+		{"unsupported", map[string]string{"Content-Encoding": "br"}, "\x8b\x07", "\x8b\x07", true},
+	}
+	for _, tt := range tests {
+		header := make(http.Header)
+		for k, v := range tt.header {
+			header.Set(k, v)
+		}
+		got, err := decodeBody(header, []byte(tt.body))
+		if string(got) != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: decodeBody = %q, %v, want %q (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// chunk encodes s with chunked transfer encoding, in chunks of 10 bytes.
+func chunk(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		n := min(10, len(s))
+		fmt.Fprintf(&b, "%x\r\n%s\r\n", n, s[:n])
+		s = s[n:]
+	}
+	return b.String() + "0\r\n\r\n"
+}
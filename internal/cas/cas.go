@@ -0,0 +1,137 @@
+// Package cas keeps downloaded files once, by content, in a directory shared
+// between runs. A run's copy of a file is replaced with a hardlink to the
+// stored one, so identical vendor chunks take their space only once.
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoHardlinks is returned by Put when the file could be stored but not
+// linked to, typically because the store is on another filesystem. The
+// run's copy is left in place.
+var ErrNoHardlinks = errors.New("hardlinks not supported")
+
+// Store is a content-addressed directory of files named by their SHA-256.
+type Store struct {
+	dir string
+}
+
+// Open returns the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create content store %s: %w", dir, err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Path returns where content with the given hex SHA-256 is stored.
+func (s *Store) Path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum)
+}
+
+// Put stores the file at path and replaces it with a hardlink to the stored
+// copy. It returns the bytes saved: the file's size when the same content
+// was already stored, otherwise zero.
+func (s *Store) Put(path string) (int64, error) {
+	sum, size, err := hashFile(path)
+	if err != nil {
+		return 0, err
+	}
+	obj := s.Path(sum)
+	if err := os.MkdirAll(filepath.Dir(obj), 0755); err != nil {
+		return 0, err
+	}
+
+	stored, err := os.Stat(obj)
+	if os.IsNotExist(err) {
+		err = os.Link(path, obj)
+		switch {
+		case err == nil:
+			return 0, nil
+		case os.IsExist(err):
+			// Stored by a concurrent run in the meantime
+			stored, err = os.Stat(obj)
+		default:
+			if err := copyInto(path, obj); err != nil {
+				return 0, err
+			}
+			return 0, fmt.Errorf("%w: %v", ErrNoHardlinks, err)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if os.SameFile(stored, current) {
+		return 0, nil
+	}
+
+	// Link under a temporary name and rename over the file, so the run's
+	// copy is never missing
+	tmp := path + ".cas-tmp"
+	os.Remove(tmp)
+	if err := os.Link(obj, tmp); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrNoHardlinks, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return size, nil
+}
+
+// hashFile returns the hex SHA-256 and size of a file.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// copyInto copies src to dst through a temporary file, so a reader never
+// sees a partial stored file.
+func copyInto(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	if err := os.Rename(out.Name(), dst); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package comments collects developer comments from restored sources that
+// were never meant to ship: TODOs, ticket links, and internal notes.
+package comments
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+)
+
+// maxScanSize caps the size of files scanned for comments.
+const maxScanSize = 5 << 20
+
+// DefaultPatterns are the keyword patterns used when none are configured.
+var DefaultPatterns = []string{
+	`\bTODO\b`,
+	`\bFIXME\b`,
+	`\bHACK\b`,
+	`\bXXX\b`,
+	`@internal\b`,
+	`https?://[^\s/]*jira[^\s]*`,
+	`https?://[^\s]*/browse/[A-Z][A-Z0-9]+-\d+`,
+}
+
+// scanExtensions are the file types whose comments are scanned.
+var scanExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+	".vue": true, ".svelte": true,
+	".css": true, ".scss": true, ".less": true,
+}
+
+// Match is a comment that matched at least one pattern.
+type Match struct {
+	File     string   `json:"file"` // Slash-separated, relative to the scanned directory
+	Line     int      `json:"line"`
+	EndLine  int      `json:"end_line"`
+	Keywords []string `json:"keywords"` // Matched text for each pattern that hit
+	Comment  string   `json:"comment"`
+	Context  string   `json:"context,omitempty"` // First code line after the comment
+}
+
+// Options configures a scan.
+type Options struct {
+	Patterns      []*regexp.Regexp // Keyword patterns (nil = DefaultPatterns)
+	IncludeVendor bool             // Also scan node_modules and other vendor directories
+}
+
+// Compile compiles keyword patterns, reporting the first invalid one.
+func Compile(patterns []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid comment pattern %q: %w", p, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// ScanDirectory collects matching comments from the source files under dir.
+func ScanDirectory(dir string, opts Options) ([]Match, []error) {
+	patterns := opts.Patterns
+	if patterns == nil {
+		patterns, _ = Compile(DefaultPatterns)
+	}
+
+	var matches []Match
+	var errs []error
+
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("walk error at %s: %w", p, err))
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || (!opts.IncludeVendor && p != dir && stats.IsVendor(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !scanExtensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > maxScanSize {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", p, err))
+			return nil
+		}
+		if bytes.IndexByte(content, 0) != -1 || !utf8.Valid(content) {
+			return nil
+		}
+
+		matches = append(matches, ScanContent(string(content), rel, patterns)...)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to walk directory: %w", err))
+	}
+
+	return matches, errs
+}
+
+// ScanContent returns the comments in a single file that match a pattern.
+func ScanContent(src, file string, patterns []*regexp.Regexp) []Match {
+	var out []Match
+	for _, c := range ScanComments(src) {
+		var keywords []string
+		for _, re := range patterns {
+			if m := re.FindString(c.Text); m != "" {
+				keywords = append(keywords, m)
+			}
+		}
+		if len(keywords) == 0 {
+			continue
+		}
+		out = append(out, Match{
+			File:     file,
+			Line:     c.Line,
+			EndLine:  c.EndLine,
+			Keywords: keywords,
+			Comment:  c.Text,
+			Context:  nextCodeLine(src, c.End),
+		})
+	}
+	return out
+}
+
+// nextCodeLine returns the first non-blank line at or after offset, which
+// is usually the code a comment annotates. Code sharing the comment's line
+// comes first.
+func nextCodeLine(src string, offset int) string {
+	for offset < len(src) {
+		end := strings.IndexByte(src[offset:], '\n')
+		if end == -1 {
+			end = len(src) - offset
+		}
+		line := strings.TrimSpace(src[offset : offset+end])
+		if line != "" {
+			if len(line) > 200 {
+				line = line[:200] + "..."
+			}
+			return line
+		}
+		offset += end + 1
+	}
+	return ""
+}
+
+// WriteJSON writes matches as an indented JSON list artifact.
+func WriteJSON(path string, matches []Match) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // Comments and code are full of < and >
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema.Items(matches)); err != nil {
+		return fmt.Errorf("failed to encode comments: %w", err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,202 @@
+package comments
+
+import "strings"
+
+// Comment is a single comment block found in source code.
+type Comment struct {
+	Text    string // Comment body without the // or /* */ delimiters
+	Line    int    // 1-based line where the comment starts
+	EndLine int    // 1-based line where the comment ends
+	End     int    // Byte offset just past the comment
+}
+
+// ScanComments returns the comments in JavaScript, TypeScript, JSX, or CSS
+// source. It is a tolerant lexer rather than a parser: it tracks string,
+// template, and regular expression literals well enough not to mistake
+// "//" inside them for a comment, and recovers at the next line on
+// anything it cannot make sense of. JSX comments ({/* ... */}) are plain
+// block comments to the lexer. Consecutive // line comments are merged into
+// one block.
+func ScanComments(src string) []Comment {
+	var out []Comment
+	line := 1
+	prev := byte(0) // Last significant (non-space) character outside comments
+
+	// Template literal nesting: each entry is the brace depth at which a
+	// ${ expression started
+	var templates []int
+	depth := 0
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			continue
+
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			start := line
+			end := strings.IndexByte(src[i:], '\n')
+			if end == -1 {
+				end = len(src) - i
+			}
+			text := strings.TrimSpace(src[i+2 : i+end])
+			i += end - 1
+
+			// Merge with a // comment on the directly preceding line when
+			// both stand on their own lines
+			if n := len(out); n > 0 && out[n-1].EndLine == start-1 && ownLine(src, i-end+1) && out[n-1].lineComment(src) {
+				out[n-1].Text += "\n" + text
+				out[n-1].EndLine = start
+				out[n-1].End = i + 1
+				continue
+			}
+			out = append(out, Comment{Text: text, Line: start, EndLine: start, End: i + 1})
+			continue
+
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			start := line
+			end := strings.Index(src[i+2:], "*/")
+			body := ""
+			if end == -1 {
+				body = src[i+2:]
+				i = len(src)
+			} else {
+				body = src[i+2 : i+2+end]
+				i += end + 3
+			}
+			line += strings.Count(body, "\n")
+			out = append(out, Comment{Text: cleanBlock(body), Line: start, EndLine: line, End: min(i+1, len(src))})
+			continue
+
+		case c == '"' || c == '\'':
+			i, line = skipString(src, i, line, c)
+
+		case c == '`':
+			i, line = skipTemplate(src, i, line, &templates, depth)
+
+		case c == '/' && regexAllowed(prev):
+			i = skipRegex(src, i)
+
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			// Closing a ${ expression resumes the enclosing template
+			if n := len(templates); n > 0 && templates[n-1] == depth {
+				templates = templates[:n-1]
+				i, line = skipTemplate(src, i, line, &templates, depth)
+			}
+		}
+		prev = src[min(i, len(src)-1)]
+	}
+
+	return out
+}
+
+// ownLine reports whether only whitespace precedes offset on its line.
+func ownLine(src string, offset int) bool {
+	start := strings.LastIndexByte(src[:offset], '\n') + 1
+	return strings.TrimSpace(src[start:offset]) == ""
+}
+
+// lineComment reports whether c is a // comment on a line of its own.
+func (c Comment) lineComment(src string) bool {
+	start := c.End
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	return strings.HasPrefix(strings.TrimSpace(src[start:c.End]), "//")
+}
+
+// skipString returns the index of the closing quote of a string literal
+// starting at i. Unterminated strings end at the line break.
+func skipString(src string, i, line int, quote byte) (int, int) {
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			if j+1 < len(src) && src[j+1] == '\n' {
+				line++
+			}
+			j++
+		case quote:
+			return j, line
+		case '\n':
+			return j - 1, line
+		}
+	}
+	return len(src) - 1, line
+}
+
+// skipTemplate scans a template literal from i (the opening backtick or the
+// } closing an expression) to its closing backtick, or to the start of an
+// embedded ${ expression, which is pushed onto templates.
+func skipTemplate(src string, i, line int, templates *[]int, depth int) (int, int) {
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			if j+1 < len(src) && src[j+1] == '\n' {
+				line++
+			}
+			j++
+		case '\n':
+			line++
+		case '`':
+			return j, line
+		case '$':
+			if j+1 < len(src) && src[j+1] == '{' {
+				*templates = append(*templates, depth)
+				// The caller sees the { next and increments depth
+				return j, line
+			}
+		}
+	}
+	return len(src) - 1, line
+}
+
+// skipRegex returns the index of the closing / of a regular expression
+// literal, honoring escapes and character classes. A literal that runs to
+// the end of the line is treated as division and skipped as one character.
+func skipRegex(src string, i int) int {
+	inClass := false
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				return j
+			}
+		case '\n':
+			return i
+		}
+	}
+	return i
+}
+
+// regexAllowed reports whether a / after prev starts a regular expression
+// rather than a division.
+func regexAllowed(prev byte) bool {
+	if prev == 0 {
+		return true
+	}
+	return strings.IndexByte("(,=:[!&|?{};+-*%<>~^", prev) != -1
+}
+
+// cleanBlock strips the leading * decoration from each line of a block
+// comment.
+func cleanBlock(body string) string {
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		l = strings.TrimPrefix(l, "*")
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
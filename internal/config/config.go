@@ -0,0 +1,297 @@
+// Package config loads the dejank configuration file: default option values
+// and named profiles that are applied before command-line flags.
+//
+// Options are keyed by flag name, or by an alias Load is given for one. A
+// key whose value is a mapping holds the options of the subcommand with
+// that name:
+//
+//	defaults:
+//	  o: ~/recon/dejank
+//	  csv: true
+//	profiles:
+//	  bounty-acme:
+//	    versioned: true
+//	    hook: [after_restore=./notify.sh]
+//	    watch:
+//	      interval: 6h
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Value is a single option from the config file.
+type Value struct {
+	Values []string // One entry for scalars, several for repeatable flags
+	Key    string   // Name the option was given as, which may be an alias
+	Line   int      // Line in the config file, for error messages
+	Source string   // "defaults" or "profile <name>"
+}
+
+// Section is a set of options, with nested per-command options.
+type Section struct {
+	Options  map[string]Value // By flag name, with aliases resolved
+	Commands map[string]*Section
+}
+
+// File is a parsed config file.
+type File struct {
+	Path     string
+	Defaults *Section
+	Profiles map[string]*Section
+
+	aliases map[string]string // Flag name by alias
+}
+
+// DefaultPath returns the default config file location,
+// $XDG_CONFIG_HOME/dejank/config.yaml or ~/.config/dejank/config.yaml.
+func DefaultPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "dejank", "config.yaml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "dejank", "config.yaml")
+}
+
+// Load reads and parses a config file. aliases maps descriptive option
+// names to the flags they set, such as output to o; options are stored by
+// flag name, so a profile's output replaces the defaults' o.
+func Load(path string, aliases map[string]string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	f := &File{
+		Path:     path,
+		Defaults: newSection(),
+		Profiles: make(map[string]*Section),
+		aliases:  aliases,
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return f, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d: expected a mapping with defaults and profiles", path, root.Line)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "defaults":
+			if f.Defaults, err = f.parseSection(value, "defaults"); err != nil {
+				return nil, err
+			}
+		case "profiles":
+			if value.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%s:%d: profiles must be a mapping of profile names", path, value.Line)
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name := value.Content[j].Value
+				section, err := f.parseSection(value.Content[j+1], "profile "+name)
+				if err != nil {
+					return nil, err
+				}
+				f.Profiles[name] = section
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown top-level key %q (valid: defaults, profiles)", path, key.Line, key.Value)
+		}
+	}
+
+	return f, nil
+}
+
+// parseSection converts a mapping node into a Section.
+func (f *File) parseSection(node *yaml.Node, source string) (*Section, error) {
+	section := newSection()
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return section, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d: %s must be a mapping of options", f.Path, node.Line, source)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name := key.Value
+		if value.Kind != yaml.MappingNode {
+			if alias, ok := f.aliases[name]; ok {
+				name = alias
+			}
+			if other, ok := section.Options[name]; ok {
+				return nil, fmt.Errorf("%s:%d: %s and %s set the same option (line %d)", f.Path, key.Line, key.Value, other.Key, other.Line)
+			}
+		}
+		switch value.Kind {
+		case yaml.MappingNode:
+			sub, err := f.parseSection(value, source)
+			if err != nil {
+				return nil, err
+			}
+			section.Commands[key.Value] = sub
+		case yaml.SequenceNode:
+			v := Value{Key: key.Value, Line: key.Line, Source: source}
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("%s:%d: %s must be a list of values", f.Path, item.Line, key.Value)
+				}
+				v.Values = append(v.Values, expandHome(item.Value))
+			}
+			section.Options[name] = v
+		case yaml.ScalarNode:
+			section.Options[name] = Value{
+				Values: []string{expandHome(value.Value)},
+				Key:    key.Value,
+				Line:   key.Line,
+				Source: source,
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unsupported value for %s", f.Path, key.Line, key.Value)
+		}
+	}
+
+	return section, nil
+}
+
+// Resolve merges the defaults with the named profile. Profile options
+// replace default options of the same name. An empty name returns the
+// defaults alone.
+func (f *File) Resolve(profile string) (*Section, error) {
+	merged := newSection()
+	merged.merge(f.Defaults)
+
+	if profile == "" {
+		return merged, nil
+	}
+
+	p, ok := f.Profiles[profile]
+	if !ok {
+		names := f.ProfileNames()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown profile %q (%s defines no profiles)", profile, f.Path)
+		}
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(names, ", "))
+	}
+	merged.merge(p)
+
+	return merged, nil
+}
+
+// ProfileNames returns the defined profile names, sorted.
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Keys returns the flag names of the options in s, sorted.
+func (s *Section) Keys() []string {
+	keys := make([]string, 0, len(s.Options))
+	for k := range s.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Command returns the options for a subcommand, or an empty section.
+func (s *Section) Command(name string) *Section {
+	if sub, ok := s.Commands[name]; ok {
+		return sub
+	}
+	return newSection()
+}
+
+// merge copies the options of o into s, overriding existing keys.
+func (s *Section) merge(o *Section) {
+	for k, v := range o.Options {
+		s.Options[k] = v
+	}
+	for name, sub := range o.Commands {
+		if _, ok := s.Commands[name]; !ok {
+			s.Commands[name] = newSection()
+		}
+		s.Commands[name].merge(sub)
+	}
+}
+
+func newSection() *Section {
+	return &Section{
+		Options:  make(map[string]Value),
+		Commands: make(map[string]*Section),
+	}
+}
+
+// expandHome replaces a leading "~/" with the user's home directory.
+func expandHome(value string) string {
+	if !strings.HasPrefix(value, "~/") {
+		return value
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return value
+	}
+	return filepath.Join(home, value[2:])
+}
+
+// sensitiveWords mark option names whose values must not be printed.
+var sensitiveWords = []string{"token", "secret", "password", "passwd", "cookie", "auth", "apikey", "api-key", "api_key", "header", "webhook"}
+
+// Sensitive reports whether values of the named option should be redacted.
+func Sensitive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, w := range sensitiveWords {
+		if strings.Contains(lower, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact hides secrets in an option value for display. Sensitive options
+// are masked entirely, keeping a "Name:" prefix for header-style values.
+// Credentials and query strings in URLs are masked for all options.
+func Redact(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if Sensitive(name) {
+		if header, _, ok := strings.Cut(value, ":"); ok && !strings.Contains(header, " ") {
+			return header + ": <redacted>"
+		}
+		return "<redacted>"
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+	if u.User != nil {
+		value = strings.Replace(value, u.User.String()+"@", "<redacted>@", 1)
+	}
+	if u.RawQuery != "" {
+		value = strings.Replace(value, "?"+u.RawQuery, "?<redacted>", 1)
+	}
+	return value
+}
@@ -0,0 +1,130 @@
+// Package disk recognizes write errors that no later write in the same
+// place will get past: a full disk or exceeded quota, a read-only
+// filesystem, or missing permission. A run that hits one stops writing and
+// reports it once instead of failing every remaining file the same way.
+package disk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+)
+
+// Error is a write to Path that failed for a reason that applies to every
+// write after it.
+type Error struct {
+	Path string
+	Free int64 // Bytes free on the device, -1 if unknown
+	Err  error // The underlying error, e.g. syscall.ENOSPC
+}
+
+func (e *Error) Error() string {
+	var msg string
+	switch {
+	case errors.Is(e.Err, syscall.ENOSPC):
+		msg = "no space left on device"
+	case isQuota(e.Err):
+		msg = "disk quota exceeded"
+	case errors.Is(e.Err, syscall.EROFS):
+		msg = "read-only filesystem"
+	default:
+		msg = "permission denied"
+	}
+	msg = fmt.Sprintf("%s writing %s", msg, e.Path)
+	if e.Free >= 0 {
+		msg += fmt.Sprintf(" (%s free)", formatBytes(e.Free))
+	}
+	return msg + "; stopped writing"
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Check returns err as an *Error if it is a full-disk, quota, read-only,
+// or permission failure writing path, and err unchanged otherwise. An
+// empty path means the path of the failed file operation. Callers pass
+// only errors from writing, so a denied open counts here as it does not
+// in Is.
+func Check(path string, err error) error {
+	var e *Error
+	if !fileError(err, true) || errors.As(err, &e) {
+		return err
+	}
+	if path == "" {
+		var pe *fs.PathError
+		errors.As(err, &pe)
+		path = pe.Path
+	}
+	return &Error{Path: path, Free: freeSpace(path), Err: err}
+}
+
+// Is reports whether err is, or wraps, a failure Check classifies, whether
+// or not it went through Check. Outside Check a permission error counts
+// only from an operation that writes: the same open that fails to create
+// a file also fails to read one, and an unreadable input is an ordinary
+// per-file error.
+func Is(err error) bool {
+	var e *Error
+	return errors.As(err, &e) || fileError(err, false)
+}
+
+// First returns the first error in errs that Is reports, or nil.
+func First(errs []error) error {
+	for _, err := range errs {
+		if Is(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileError reports whether err is a file operation that failed with one
+// of the errnos that stop a run. Only file operations count: EPERM from a
+// socket, say, says nothing about the disk. Permission errors count when
+// writing is set or the operation only ever writes.
+func fileError(err error, writing bool) bool {
+	var pe *fs.PathError
+	if !errors.As(err, &pe) {
+		return false
+	}
+	op, err := pe.Op, pe.Err
+	if errors.Is(err, syscall.ENOSPC) || isQuota(err) || errors.Is(err, syscall.EROFS) {
+		return true
+	}
+	return (writing || writeOps[op]) && (errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM))
+}
+
+// writeOps are the fs.PathError operations that only occur when writing.
+// "open" is missing: it is both how files are read and how they are
+// created.
+var writeOps = map[string]bool{
+	"mkdir": true, "write": true, "truncate": true, "chmod": true, "chtimes": true,
+}
+
+// freeSpace returns the bytes available on the device holding path, which
+// may not exist yet, or -1 if that can't be determined.
+func freeSpace(path string) int64 {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if n, ok := available(dir); ok {
+			return n
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return -1
+		}
+	}
+}
+
+// formatBytes formats n in binary units, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
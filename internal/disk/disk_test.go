@@ -0,0 +1,45 @@
+package disk
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestIs(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"read denied", &fs.PathError{Op: "open", Path: "a.map", Err: syscall.EACCES}, false},
+		{"read not permitted", &fs.PathError{Op: "open", Path: "a.map", Err: syscall.EPERM}, false},
+		{"wrapped read denied", fmt.Errorf("failed to parse: %w", &fs.PathError{Op: "open", Path: "a.map", Err: syscall.EACCES}), false},
+		{"mkdir denied", &fs.PathError{Op: "mkdir", Path: "out", Err: syscall.EACCES}, true},
+		{"write denied", &fs.PathError{Op: "write", Path: "out/a.js", Err: syscall.EPERM}, true},
+		{"disk full on open", &fs.PathError{Op: "open", Path: "out/a.js", Err: syscall.ENOSPC}, true},
+		{"read-only on open", &fs.PathError{Op: "open", Path: "out/a.js", Err: syscall.EROFS}, true},
+		{"not found", &fs.PathError{Op: "open", Path: "a.map", Err: syscall.ENOENT}, false},
+		{"socket", fmt.Errorf("dial: %w", syscall.EPERM), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Is(tt.err); got != tt.want {
+				t.Errorf("Is(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCreateDenied(t *testing.T) {
+	// The same open that Is ignores stops the run once a writer passes it
+	// to Check
+	err := Check("out/a.js", &fs.PathError{Op: "open", Path: "out/a.js", Err: syscall.EACCES})
+	if !Is(err) {
+		t.Fatalf("Check(create denied) = %v, want a disk.Error", err)
+	}
+	if First([]error{fmt.Errorf("x"), err}) != err {
+		t.Errorf("First did not return the checked error")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd)
+
+package disk
+
+// available is not implemented on this platform; free space is reported
+// as unknown.
+func available(dir string) (int64, bool) {
+	return 0, false
+}
+
+func isQuota(err error) bool {
+	return false
+}
@@ -0,0 +1,22 @@
+//go:build linux || darwin || freebsd
+
+package disk
+
+import (
+	"errors"
+	"syscall"
+)
+
+// available returns the bytes available to unprivileged users on the
+// device holding dir.
+func available(dir string) (int64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return int64(st.Bavail) * int64(st.Bsize), true
+}
+
+func isQuota(err error) bool {
+	return errors.Is(err, syscall.EDQUOT)
+}
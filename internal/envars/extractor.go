@@ -103,18 +103,39 @@ func isKnownEnvVar(key string) bool {
 	return false
 }
 
-// MergeEnvVars merges multiple env var maps, with earlier maps taking precedence.
+// Conflict is a variable found with two different values.
+type Conflict struct {
+	Key     string
+	Kept    string // The value seen first, which is kept
+	Ignored string // The later value, which is dropped
+}
+
+// MergeEnvVars merges multiple env var maps. A variable keeps the value of
+// the first map that has it, so the result depends only on the order of
+// the maps.
 func MergeEnvVars(maps ...map[string]string) map[string]string {
 	result := make(map[string]string)
+	for _, m := range maps {
+		Merge(result, m)
+	}
+	return result
+}
 
-	// Process in reverse order so earlier maps overwrite later ones
-	for i := len(maps) - 1; i >= 0; i-- {
-		for k, v := range maps[i] {
-			result[k] = v
+// Merge adds the variables of src that dst lacks to dst. A variable dst
+// already has keeps its value; one src gives a different value is returned
+// as a conflict. Conflicts are sorted by key.
+func Merge(dst, src map[string]string) []Conflict {
+	var conflicts []Conflict
+	for k, v := range src {
+		kept, exists := dst[k]
+		if !exists {
+			dst[k] = v
+		} else if kept != v {
+			conflicts = append(conflicts, Conflict{Key: k, Kept: kept, Ignored: v})
 		}
 	}
-
-	return result
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Key < conflicts[j].Key })
+	return conflicts
 }
 
 // WriteEnvFile writes extracted environment variables to a .env file.
@@ -170,3 +191,54 @@ func escapeEnvValue(value string) string {
 	return "\"" + escaped + "\""
 }
 
+// ReadEnvFile parses a .env file previously written by WriteEnvFile.
+// Comment and blank lines are ignored.
+func ReadEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		vars[strings.TrimSpace(key)] = unescapeEnvValue(value)
+	}
+
+	return vars, nil
+}
+
+// unescapeEnvValue reverses escapeEnvValue.
+func unescapeEnvValue(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+
+	value = value[1 : len(value)-1]
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			sb.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(value[i])
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package envars
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvFileRoundTrip(t *testing.T) {
+	vars := map[string]string{
+		"API_URL":      "https://api.example.com/v1?a=1&b=2",
+		"EMPTY":        "",
+		"SPACES":       "  padded value  ",
+		"QUOTES":       `say "hi" and 'bye'`,
+		"WRAPPED":      `"already quoted"`,
+		"MULTILINE":    "line one\nline two\r\nline three",
+		"BACKSLASH":    `C:\path\to\n`,
+		"DOLLAR":       "$HOME and `cmd`",
+		"HASH":         "#not-a-comment",
+		"TAB":          "a\tb",
+		"EQUALS":       "key=value=more",
+		"UNICODE":      "café ☕",
+		"TRAILING_ESC": `ends with \`,
+	}
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := WriteEnvFile(vars, path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range vars {
+		if got[k] != want {
+			t.Errorf("%s = %q after a round trip, want %q", k, got[k], want)
+		}
+	}
+	if len(got) != len(vars) {
+		t.Errorf("read %d variables, want %d", len(got), len(vars))
+	}
+}
+
+func TestWriteEnvFileDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	var first []byte
+	for i := 0; i < 20; i++ {
+		// A fresh map each time, so its iteration order varies
+		vars := MergeEnvVars(
+			map[string]string{"B": "2", "A": "1", "D": "4"},
+			map[string]string{"C": "3", "A": "other"},
+		)
+		path := filepath.Join(dir, ".env")
+		if err := WriteEnvFile(vars, path); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = data
+		} else if !bytes.Equal(data, first) {
+			t.Fatalf("run %d wrote\n%s\nthe first wrote\n%s", i, data, first)
+		}
+	}
+	if !bytes.Contains(first, []byte("A=1\nB=2\nC=3\nD=4\n")) {
+		t.Errorf("variables not sorted, or not the first value kept:\n%s", first)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dst := map[string]string{"A": "1", "B": "2", "Y": "kept", "Z": "kept"}
+	src := map[string]string{"A": "1", "B": "changed", "C": "3", "M": "m", "Y": "y", "Z": "z"}
+
+	conflicts := Merge(dst, src)
+	want := []Conflict{
+		{Key: "B", Kept: "2", Ignored: "changed"},
+		{Key: "Y", Kept: "kept", Ignored: "y"},
+		{Key: "Z", Kept: "kept", Ignored: "z"},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("conflicts = %+v, want %+v", conflicts, want)
+	}
+	wantDst := map[string]string{"A": "1", "B": "2", "C": "3", "M": "m", "Y": "kept", "Z": "kept"}
+	if !reflect.DeepEqual(dst, wantDst) {
+		t.Errorf("dst = %v, want %v", dst, wantDst)
+	}
+
+	if conflicts := Merge(dst, map[string]string{"A": "1"}); conflicts != nil {
+		t.Errorf("an identical value conflicted: %+v", conflicts)
+	}
+}
@@ -3,40 +3,278 @@ package fetch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/debugger"
+	cdpfetch "github.com/chromedp/cdproto/fetch"
+	cdplog "github.com/chromedp/cdproto/log"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 )
 
 // DiscoveredResources contains all JS and sourcemap URLs found during page load.
 type DiscoveredResources struct {
-	Scripts    []string // All .js URLs loaded
-	SourceMaps []string // All .map URLs loaded
-	BaseURL    string   // The final URL after redirects
+	Scripts    []string         // All .js URLs loaded
+	SourceMaps []string         // All .map URLs loaded
+	Embedded   []EmbeddedScript // Scripts loaded from blob: and data: URLs, with their content
+	BaseURL    string           // The final URL after redirects ("" if the page never loaded)
+
+	NavigationErr error // Why the page never finished loading; the scripts it requested until then are kept
+
+	Modules       map[string]string // webpack module id to factory source (DumpModules only)
+	ModuleDumpErr error             // Why the module dump failed, e.g. ErrModuleDumpBlocked
+
+	Coverage    map[string][]ExecutedRange // Script URL to the ranges that ran (Coverage only)
+	CoverageErr error                      // Why coverage could not be collected
+
+	UserAgent string // User-Agent the browser loaded the page with
+
+	Console        []ConsoleEntry // Console errors and warnings about scripts or failed loads, capped
+	ConsoleDropped int            // Relevant console entries beyond the cap
+
+	Screenshot  []byte // Full-page PNG screenshot after load (Snapshot only)
+	PageHTML    string // Serialized DOM after load (Snapshot only)
+	SnapshotErr error  // Why the screenshot or DOM could not be captured
+
+	Triggered    []string            // Scripts first requested once Interaction or AutoScroll began
+	Interactions []InteractionResult // How each interaction step went
+
+	Prerendered []string // Scripts first requested by a page Chrome prerendered for a speculation rule
+	Prefetched  []string // Scripts only ever prefetched, which the page never loaded
+}
+
+// Merge adds the resources discovered on another page of the same site,
+// skipping scripts and maps already known. BaseURL is kept.
+func (d *DiscoveredResources) Merge(other *DiscoveredResources) {
+	d.Scripts = appendNew(d.Scripts, other.Scripts)
+	d.SourceMaps = appendNew(d.SourceMaps, other.SourceMaps)
+
+	known := make(map[string]bool, len(d.Embedded))
+	for _, e := range d.Embedded {
+		known[e.Content] = true
+	}
+	for _, e := range other.Embedded {
+		if !known[e.Content] {
+			known[e.Content] = true
+			d.Embedded = append(d.Embedded, e)
+		}
+	}
+
+	for id, src := range other.Modules {
+		if d.Modules == nil {
+			d.Modules = make(map[string]string)
+		}
+		if _, ok := d.Modules[id]; !ok {
+			d.Modules[id] = src
+		}
+	}
+	if d.ModuleDumpErr == nil {
+		d.ModuleDumpErr = other.ModuleDumpErr
+	}
+
+	for u, ranges := range other.Coverage {
+		if d.Coverage == nil {
+			d.Coverage = make(map[string][]ExecutedRange)
+		}
+		d.Coverage[u] = mergeRanges(append(d.Coverage[u], ranges...))
+	}
+	if d.CoverageErr == nil {
+		d.CoverageErr = other.CoverageErr
+	}
+
+	d.Console = append(d.Console, other.Console...)
+	d.ConsoleDropped += other.ConsoleDropped
+
+	d.Triggered = appendNew(d.Triggered, other.Triggered)
+	d.Interactions = append(d.Interactions, other.Interactions...)
+
+	d.Prerendered = appendNew(d.Prerendered, other.Prerendered)
+	d.Prefetched = appendNew(d.Prefetched, other.Prefetched)
+}
+
+// appendNew appends the entries of add that are not already in list.
+func appendNew(list, add []string) []string {
+	seen := make(map[string]bool, len(list))
+	for _, u := range list {
+		seen[u] = true
+	}
+	for _, u := range add {
+		if !seen[u] {
+			seen[u] = true
+			list = append(list, u)
+		}
+	}
+	return list
+}
+
+// BrowserOptions configures how BrowserClient launches Chrome and loads
+// pages. Zero durations take the defaults of DefaultBrowserOptions, and
+// the zero value of every other field is the default behavior, so a
+// caller can set only the options it needs.
+type BrowserOptions struct {
+	Timeout           time.Duration          // Limit for one discovery attempt, Chrome's launch included
+	NavigationTimeout time.Duration          // Limit for loading the page until its body is ready
+	SettleDuration    time.Duration          // Wait after load for lazy-loaded scripts
+	Headful           bool                   // Show Chrome's window instead of running it headless
+	ExtraFlags        map[string]interface{} // Additional Chrome flags by name, e.g. "lang": "en-US"; false drops a default one
+	UserAgent         string                 // User-Agent for the browser's requests ("" = Chrome's own)
+	AcceptLanguage    string                 // Accept-Language for the browser's requests, e.g. en-US,en ("" = Chrome's own)
+	ProxyURL          string                 // Proxy server for the browser's traffic, e.g. http://127.0.0.1:8080
+	ExecPath          string                 // Chrome executable ("" = search the usual install locations)
+	UserDataDir       string                 // Chrome profile directory ("" = a fresh temporary profile)
+	Mobile            bool                   // Emulate a phone: its screen, touch input, and (without UserAgent) User-Agent
+	Locale            string                 // Locale the page sees, e.g. de-DE ("" = Chrome's own)
+	Timezone          string                 // IANA timezone the page sees, e.g. Europe/Berlin ("" = the system's)
+	Geolocation       *Geolocation           // Position the geolocation API reports, with permission granted (nil = none)
+	ChallengeWait     time.Duration          // How long to wait for a bot protection challenge to resolve before giving up
+	Context           context.Context        // Cancels discovery and its retries, e.g. on shutdown (nil = never)
+}
+
+// DefaultBrowserOptions returns the options NewBrowserClient uses.
+func DefaultBrowserOptions() BrowserOptions {
+	return BrowserOptions{
+		Timeout:           60 * time.Second,
+		NavigationTimeout: 30 * time.Second,
+		ChallengeWait:     15 * time.Second,
+		SettleDuration:    5 * time.Second,
+	}
+}
+
+// allocatorOptions translates the options into chromedp allocator options
+// that launch execPath.
+func (o BrowserOptions) allocatorOptions(execPath string) []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(execPath))
+
+	// Sorted so Chrome gets the same command line on every run
+	flags := o.chromeFlags()
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opts = append(opts, chromedp.Flag(name, flags[name]))
+	}
+	return opts
+}
+
+// chromeFlags returns the Chrome command-line flags the options set, by
+// name, on top of chromedp's defaults. A false flag is left off.
+func (o BrowserOptions) chromeFlags() map[string]interface{} {
+	flags := map[string]interface{}{
+		"headless":                  !o.Headful,
+		"disable-gpu":               true,
+		"no-sandbox":                true,
+		"disable-dev-shm-usage":     true,
+		"ignore-certificate-errors": true,
+	}
+	if o.UserAgent != "" {
+		flags["user-agent"] = o.UserAgent
+	}
+	if o.AcceptLanguage != "" {
+		flags["accept-lang"] = o.AcceptLanguage
+	}
+	if o.ProxyURL != "" {
+		flags["proxy-server"] = o.ProxyURL
+	}
+	if o.UserDataDir != "" {
+		flags["user-data-dir"] = o.UserDataDir
+	}
+	for name, value := range o.ExtraFlags {
+		flags[name] = value
+	}
+	return flags
 }
 
 // BrowserClient uses headless Chrome to execute JavaScript and discover resources.
 type BrowserClient struct {
-	timeout time.Duration
+	opts BrowserOptions
+
+	// ChromePath is the Chrome executable to launch. Empty searches the
+	// usual install locations.
+	ChromePath string
+
+	// DumpModules serializes the webpack module registry of the loaded page
+	// into DiscoveredResources.Modules.
+	DumpModules bool
+
+	// Coverage records which parts of each script executed during page load
+	// into DiscoveredResources.Coverage.
+	Coverage bool
+
+	// Snapshot captures a full-page screenshot and the rendered DOM after
+	// load into DiscoveredResources.Screenshot and PageHTML.
+	Snapshot bool
+
+	// Interaction lists steps, such as clicks, run after load and before
+	// the settle wait to trigger chunks gated behind user interaction.
+	Interaction []InteractionStep
+
+	// AutoScroll scrolls through the page after load, before Interaction,
+	// so lazy loads behind intersection observers fire.
+	AutoScroll bool
+
+	// Allow, if set, fails the page's requests to URLs it rejects as
+	// blocked by the client. They are still reported as discovered.
+	Allow func(u string) bool
 }
 
-// NewBrowserClient creates a new browser-based client.
+// NewBrowserClient creates a new browser-based client with the default
+// options.
 func NewBrowserClient() *BrowserClient {
+	return NewBrowserClientWithOptions(DefaultBrowserOptions())
+}
+
+// NewBrowserClientWithOptions creates a browser-based client. Zero
+// durations in opts take their defaults.
+func NewBrowserClientWithOptions(opts BrowserOptions) *BrowserClient {
+	defaults := DefaultBrowserOptions()
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.NavigationTimeout <= 0 {
+		opts.NavigationTimeout = defaults.NavigationTimeout
+	}
+	if opts.SettleDuration <= 0 {
+		opts.SettleDuration = defaults.SettleDuration
+	}
+	if opts.ChallengeWait <= 0 {
+		opts.ChallengeWait = defaults.ChallengeWait
+	}
 	return &BrowserClient{
-		timeout: 60 * time.Second,
+		opts:       opts,
+		ChromePath: opts.ExecPath,
 	}
 }
 
 // DiscoverResources loads a URL in headless Chrome, executes all JavaScript,
 // and returns all discovered script and sourcemap URLs. Retries on transient errors.
+// A missing or crashing Chrome is reported at once as ErrChromeNotFound or
+// ErrChromeStart rather than retried. A page that never finishes loading,
+// such as one redirecting to a login provider or stuck behind an
+// interstitial, is only an error if it requested no scripts; otherwise
+// they are returned with the reason in NavigationErr. A bot protection
+// challenge is waited out for ChallengeWait and then reported as
+// ErrChallenge.
 func (b *BrowserClient) DiscoverResources(targetURL string) (*DiscoveredResources, error) {
+	execPath, err := FindChrome(b.ChromePath)
+	if err != nil {
+		return nil, err
+	}
+
 	const maxRetries = 3
 	baseBackoff := 2 * time.Second
 
@@ -44,42 +282,45 @@ func (b *BrowserClient) DiscoverResources(targetURL string) (*DiscoveredResource
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
 			backoff := baseBackoff * (1 << (attempt - 1)) // 2s, 4s, 8s
-			time.Sleep(backoff)
+			select {
+			case <-b.parent().Done():
+				return nil, b.parent().Err()
+			case <-time.After(backoff):
+			}
 		}
 
-		result, err := b.discoverResourcesOnce(targetURL)
+		result, err := b.discoverResourcesOnce(targetURL, execPath)
 		if err == nil {
 			return result, nil
 		}
 		lastErr = err
 
-		if !isRetryable(err) {
+		if !isRetryable(err) || b.parent().Err() != nil {
 			return nil, err
 		}
 	}
 	return nil, lastErr
 }
 
+// parent returns the context discovery runs under.
+func (b *BrowserClient) parent() context.Context {
+	if b.opts.Context != nil {
+		return b.opts.Context
+	}
+	return context.Background()
+}
+
 // discoverResourcesOnce performs a single attempt to discover resources.
-func (b *BrowserClient) discoverResourcesOnce(targetURL string) (*DiscoveredResources, error) {
+func (b *BrowserClient) discoverResourcesOnce(targetURL, execPath string) (*DiscoveredResources, error) {
 	// Suppress chromedp's noisy error logging for unknown CDP values
 	log.SetOutput(io.Discard)
 	defer log.SetOutput(log.Writer())
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	ctx, cancel := context.WithTimeout(b.parent(), b.opts.Timeout)
 	defer cancel()
 
-	// Create Chrome options
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("no-sandbox", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("ignore-certificate-errors", true),
-	)
-
-	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, opts...)
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, b.opts.allocatorOptions(execPath)...)
 	defer allocCancel()
 
 	browserCtx, browserCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(func(string, ...interface{}) {}))
@@ -92,23 +333,61 @@ func (b *BrowserClient) discoverResourcesOnce(targetURL string) (*DiscoveredReso
 
 	var mu sync.Mutex
 	seen := make(map[string]bool)
-
-	// Enable network events and listen for requests
-	chromedp.ListenTarget(browserCtx, func(ev interface{}) {
+	interacting := false
+	// Scripts are collected by request URL, or by response for those
+	// recognized by content type
+	scripts := make(map[string]bool)
+	addScript := func(u string, prerender bool) {
+		if scripts[u] {
+			return
+		}
+		scripts[u] = true
+		result.Scripts = append(result.Scripts, u)
+		if interacting {
+			result.Triggered = append(result.Triggered, u)
+		}
+		if prerender {
+			result.Prerendered = append(result.Prerendered, u)
+		}
+	}
+	// URLs so far only requested as speculative prefetches
+	prefetchOnly := make(map[string]bool)
+	var docHeaders http.Header
+	embedded := newEmbeddedCapture()
+	console := newConsoleCapture()
+
+	// Enable network events and listen for requests, on the page and on
+	// the pages Chrome prerenders from it
+	handle := func(ev interface{}, prerender bool) {
 		switch e := ev.(type) {
 		case *network.EventRequestWillBeSent:
 			reqURL := e.Request.URL
 			mu.Lock()
 			defer mu.Unlock()
 
+			// A script the page loads after prefetching it was used
+			if isPrefetch(e) {
+				if !seen[reqURL] {
+					prefetchOnly[reqURL] = true
+				}
+			} else {
+				delete(prefetchOnly, reqURL)
+			}
+
 			if seen[reqURL] {
 				return
 			}
 			seen[reqURL] = true
 
+			// blob: and data: scripts can't be downloaded later
+			if isEmbeddedURL(reqURL) {
+				embedded.requestWillBeSent(e)
+				return
+			}
+
 			// Check for JS files
-			if isJavaScriptURL(reqURL) {
-				result.Scripts = append(result.Scripts, reqURL)
+			if isScriptRequest(reqURL, e.Type == network.ResourceTypeScript) {
+				addScript(reqURL, prerender)
 			}
 
 			// Check for sourcemap files
@@ -116,7 +395,50 @@ func (b *BrowserClient) discoverResourcesOnce(targetURL string) (*DiscoveredReso
 				result.SourceMaps = append(result.SourceMaps, reqURL)
 			}
 
+		case *cdpfetch.EventRequestPaused:
+			// Answered off the event loop, which the command would block
+			go func() {
+				var action chromedp.Action = cdpfetch.ContinueRequest(e.RequestID)
+				if !b.Allow(e.Request.URL) {
+					action = cdpfetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient)
+				}
+				c := chromedp.FromContext(browserCtx)
+				_ = action.Do(cdp.WithExecutor(browserCtx, c.Target))
+			}()
+
+		case *debugger.EventScriptParsed:
+			mu.Lock()
+			embedded.scriptParsed(e)
+			mu.Unlock()
+
+		case *runtime.EventConsoleAPICalled:
+			mu.Lock()
+			console.consoleAPICalled(e)
+			mu.Unlock()
+
+		case *runtime.EventExceptionThrown:
+			mu.Lock()
+			console.exceptionThrown(e)
+			mu.Unlock()
+
+		case *cdplog.EventEntryAdded:
+			mu.Lock()
+			console.entryAdded(e)
+			mu.Unlock()
+
 		case *network.EventResponseReceived:
+			if e.Type == network.ResourceTypeDocument && e.Response != nil {
+				mu.Lock()
+				docHeaders = documentHeaders(e.Response.Headers)
+				mu.Unlock()
+			}
+			// A script served from a path without an extension is only
+			// recognizable by its content type
+			if e.Response != nil && isScriptResponse(e.Response.URL, e.Response.MimeType) {
+				mu.Lock()
+				addScript(e.Response.URL, prerender)
+				mu.Unlock()
+			}
 			// Check for sourcemap headers
 			if e.Response != nil && e.Response.Headers != nil {
 				if smURL, ok := e.Response.Headers["SourceMap"]; ok {
@@ -144,58 +466,219 @@ func (b *BrowserClient) discoverResourcesOnce(targetURL string) (*DiscoveredReso
 				}
 			}
 		}
+	}
+	chromedp.ListenTarget(browserCtx, func(ev interface{}) { handle(ev, false) })
+
+	// Prerendered pages are targets of their own, attached to as Chrome
+	// announces them
+	attached := make(map[target.ID]bool)
+	var detach []context.CancelFunc
+	chromedp.ListenBrowser(browserCtx, func(ev interface{}) {
+		id, ok := prerenderTarget(ev)
+		if !ok {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if attached[id] {
+			return
+		}
+		attached[id] = true
+		detach = append(detach, listenPrerender(browserCtx, id, func(ev interface{}) { handle(ev, true) }))
 	})
+	defer func() {
+		// Detaching waits on the browser, whose events take mu
+		mu.Lock()
+		cancels := detach
+		mu.Unlock()
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
 
 	// Navigate and wait for page to be fully loaded
 	var finalURL string
-	err := chromedp.Run(browserCtx,
+	setup := []chromedp.Action{
 		network.Enable(),
-		chromedp.Navigate(targetURL),
-		chromedp.WaitReady("body"),
-		// Wait for network to settle - longer wait for SPAs that lazy-load
-		chromedp.Sleep(5*time.Second),
-		chromedp.Location(&finalURL),
-	)
-
+		cdplog.Enable(),
+		// The debugger reports blob: scripts by ID so their source can be read;
+		// never stop on breakpoints or debugger statements
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := debugger.Enable().Do(ctx)
+			return err
+		}),
+		debugger.SetSkipAllPauses(true),
+	}
+	if b.Allow != nil {
+		setup = append(setup, cdpfetch.Enable())
+	}
+	setup = append(setup, b.opts.emulationActions()...)
+	result.UserAgent = b.opts.userAgent()
+	if result.UserAgent == "" {
+		setup = append(setup, chromedp.ActionFunc(func(ctx context.Context) error {
+			_, _, _, ua, _, err := cdpbrowser.GetVersion().Do(ctx)
+			result.UserAgent = ua
+			return err
+		}))
+	}
+	if b.Coverage {
+		setup = append(setup, startCoverage())
+	}
+	err := chromedp.Run(browserCtx, setup...)
+	if err == nil {
+		err = chromedp.Run(browserCtx, b.navigate(targetURL))
+		if err != nil {
+			mu.Lock()
+			found := len(result.Scripts)
+			mu.Unlock()
+			if found > 0 && startError(err) == err {
+				result.NavigationErr = fmt.Errorf("page did not finish loading, keeping the %d script(s) it requested: %w", found, err)
+				err = nil
+			}
+		}
+	}
 	if err != nil {
+		if start := startError(err); start != err {
+			return nil, start
+		}
 		return nil, fmt.Errorf("browser navigation failed: %w", err)
 	}
 
+	if result.NavigationErr != nil {
+		// Give late scripts the settle time if the budget allows. Where the
+		// page ended up, such as a login provider, is not the target's final URL.
+		_ = chromedp.Run(browserCtx, chromedp.Sleep(b.opts.SettleDuration))
+	} else {
+		err = chromedp.Run(browserCtx,
+			b.interact(result, func() {
+				mu.Lock()
+				interacting = true
+				mu.Unlock()
+			}),
+			// Wait for network to settle - longer wait for SPAs that lazy-load
+			chromedp.Sleep(b.opts.SettleDuration),
+			b.waitOutChallenge(func() http.Header {
+				mu.Lock()
+				defer mu.Unlock()
+				return docHeaders
+			}),
+			chromedp.Location(&finalURL),
+		)
+		if errors.Is(err, ErrChallenge) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("browser navigation failed: %w", err)
+		}
+	}
+
+	// Read blob: script sources while the page is still open. A failure here
+	// only loses those scripts.
+	mu.Lock()
+	sources := embedded.snapshot()
+	mu.Unlock()
+	_ = chromedp.Run(browserCtx, collectEmbedded(sources, &result.Embedded))
+
+	if b.DumpModules {
+		_ = chromedp.Run(browserCtx, dumpModules(&result.Modules, &result.ModuleDumpErr))
+	}
+
+	if b.Coverage {
+		if err := chromedp.Run(browserCtx, takeCoverage(&result.Coverage)); err != nil {
+			result.CoverageErr = fmt.Errorf("coverage collection failed: %w", err)
+		}
+	}
+
+	if b.Snapshot {
+		result.SnapshotErr = takeSnapshot(browserCtx, result)
+	}
+
 	result.BaseURL = finalURL
+	mu.Lock()
+	result.Console, result.ConsoleDropped = console.entries, console.dropped
+	for _, u := range result.Scripts {
+		if prefetchOnly[u] {
+			result.Prefetched = append(result.Prefetched, u)
+		}
+	}
+	mu.Unlock()
 
 	return result, nil
 }
 
-// isRetryable checks if an error is transient and worth retrying.
-func isRetryable(err error) bool {
-	msg := err.Error()
-	return strings.Contains(msg, "could not dial") ||
-		strings.Contains(msg, "connectex") ||
-		strings.Contains(msg, "connection refused") ||
-		strings.Contains(msg, "context deadline exceeded")
-}
+// snapshotAttempts is how many times each part of a -snapshot capture is
+// tried, snapshotRetryWait apart: a page that is still laying out can fail
+// a screenshot that succeeds a moment later.
+const (
+	snapshotAttempts  = 3
+	snapshotRetryWait = time.Second
+)
 
-// isJavaScriptURL checks if a URL points to a JavaScript file.
-func isJavaScriptURL(u string) bool {
-	// Parse URL to get path without query params
-	parsed, err := url.Parse(u)
-	if err != nil {
-		return false
+// takeSnapshot captures the page's screenshot and DOM into result. Each is
+// retried on failure and kept even if the other fails.
+func takeSnapshot(ctx context.Context, result *DiscoveredResources) error {
+	wait := func() error { return chromedp.Sleep(snapshotRetryWait).Do(ctx) }
+	var errs []error
+	if err := retryCapture(snapshotAttempts, wait, func() error {
+		return chromedp.Run(ctx, chromedp.FullScreenshot(&result.Screenshot, 100))
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("screenshot failed: %w", err))
 	}
+	if err := retryCapture(snapshotAttempts, wait, func() error {
+		return chromedp.Run(ctx, chromedp.OuterHTML("html", &result.PageHTML, chromedp.ByQuery))
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("DOM capture failed: %w", err))
+	}
+	return errors.Join(errs...)
+}
 
-	path := strings.ToLower(parsed.Path)
-
-	// Check extension
-	if strings.HasSuffix(path, ".js") || strings.HasSuffix(path, ".mjs") {
-		return true
+// retryCapture runs capture up to attempts times, calling wait between
+// attempts, and returns nil as soon as one succeeds, or else the last
+// error. A failed wait, as when the page is gone, ends the retries.
+func retryCapture(attempts int, wait, capture func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 && wait() != nil {
+			break
+		}
+		if err = capture(); err == nil {
+			return nil
+		}
 	}
+	return err
+}
 
-	// Check for webpack chunks and similar patterns
-	if strings.Contains(path, "/chunk") && strings.Contains(u, ".js") {
-		return true
+// navigate loads targetURL and waits for its body, within the navigation
+// timeout.
+func (b *BrowserClient) navigate(targetURL string) chromedp.Action {
+	load := chromedp.Tasks{
+		chromedp.Navigate(targetURL),
+		chromedp.WaitReady("body"),
 	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		navCtx, cancel := context.WithTimeout(ctx, b.opts.NavigationTimeout)
+		defer cancel()
+		if err := load.Do(navCtx); err != nil {
+			if navCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				return fmt.Errorf("page did not load within %s: %w", b.opts.NavigationTimeout, err)
+			}
+			return err
+		}
+		return nil
+	})
+}
 
-	return false
+// isRetryable checks if an error is transient and worth retrying.
+func isRetryable(err error) bool {
+	// Chrome's own output, quoted in these, can mention anything
+	if errors.Is(err, ErrChromeStart) || errors.Is(err, ErrChromeNotFound) {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "could not dial") ||
+		strings.Contains(msg, "connectex") ||
+		strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "context deadline exceeded")
 }
 
 // isSourceMapURL checks if a URL points to a sourcemap file.
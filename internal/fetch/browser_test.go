@@ -0,0 +1,100 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChromeFlags(t *testing.T) {
+	base := map[string]interface{}{
+		"headless":                  true,
+		"disable-gpu":               true,
+		"no-sandbox":                true,
+		"disable-dev-shm-usage":     true,
+		"ignore-certificate-errors": true,
+	}
+	with := func(changes map[string]interface{}) map[string]interface{} {
+		flags := make(map[string]interface{}, len(base))
+		for name, value := range base {
+			flags[name] = value
+		}
+		for name, value := range changes {
+			flags[name] = value
+		}
+		return flags
+	}
+
+	tests := []struct {
+		name string
+		opts BrowserOptions
+		want map[string]interface{}
+	}{
+		{"zero value", BrowserOptions{}, base},
+		{"defaults", DefaultBrowserOptions(), base},
+		{"headful", BrowserOptions{Headful: true}, with(map[string]interface{}{"headless": false})},
+		{"user agent", BrowserOptions{UserAgent: "dejank/1.0"}, with(map[string]interface{}{"user-agent": "dejank/1.0"})},
+		{"accept language", BrowserOptions{AcceptLanguage: "de-DE,de"}, with(map[string]interface{}{"accept-lang": "de-DE,de"})},
+		{"proxy", BrowserOptions{ProxyURL: "http://127.0.0.1:8080"}, with(map[string]interface{}{"proxy-server": "http://127.0.0.1:8080"})},
+		{"profile", BrowserOptions{UserDataDir: "/tmp/profile"}, with(map[string]interface{}{"user-data-dir": "/tmp/profile"})},
+		{"extra flags", BrowserOptions{ExtraFlags: map[string]interface{}{"lang": "en-US", "no-sandbox": false}},
+			with(map[string]interface{}{"lang": "en-US", "no-sandbox": false})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.chromeFlags(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chromeFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBrowserClientWithOptionsDefaults(t *testing.T) {
+	defaults := DefaultBrowserOptions()
+	b := NewBrowserClientWithOptions(BrowserOptions{SettleDuration: time.Second, ExecPath: "/opt/chrome"})
+	if b.opts.Timeout != defaults.Timeout || b.opts.NavigationTimeout != defaults.NavigationTimeout || b.opts.ChallengeWait != defaults.ChallengeWait {
+		t.Errorf("zero durations not defaulted: %+v", b.opts)
+	}
+	if b.opts.SettleDuration != time.Second {
+		t.Errorf("SettleDuration = %s, want 1s", b.opts.SettleDuration)
+	}
+	if b.ChromePath != "/opt/chrome" {
+		t.Errorf("ChromePath = %q, want /opt/chrome", b.ChromePath)
+	}
+	if b.opts.Headful {
+		t.Error("a client with unset options is headful")
+	}
+}
+
+func TestRetryCapture(t *testing.T) {
+	failing := errors.New("capture failed")
+	tests := []struct {
+		name      string
+		failures  int   // Captures that fail before one succeeds
+		waitErr   error // What wait returns
+		wantErr   bool
+		wantCalls int
+	}{
+		{"first attempt", 0, nil, false, 1},
+		{"successful retry", 2, nil, false, 3},
+		{"every attempt fails", 5, nil, true, 3},
+		{"page gone", 5, context.Canceled, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryCapture(3, func() error { return tt.waitErr }, func() error {
+				calls++
+				if calls <= tt.failures {
+					return failing
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("retryCapture() = %v after %d captures, want error %v after %d", err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+}
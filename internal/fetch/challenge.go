@@ -0,0 +1,142 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrChallenge is returned when the page is still a bot protection
+// challenge after waiting for it to resolve. Its only scripts are the
+// challenge's own, so there is nothing useful to restore.
+var ErrChallenge = errors.New("page is a bot protection challenge")
+
+// challengeSignature identifies a provider's challenge or waiting room
+// page by its host, markers in its HTML, or its response headers. Markers
+// are only ones that appear on the challenge itself, not the scripts the
+// provider adds to ordinary pages it protects.
+type challengeSignature struct {
+	provider string
+	hosts    []string          // Host suffixes the challenge is served from
+	markers  []string          // Substrings of the page HTML, lowercase
+	headers  map[string]string // Header name to a substring of its value, lowercase
+}
+
+// challengeSignatures are the challenge pages DetectChallenge knows.
+var challengeSignatures = []challengeSignature{
+	{
+		provider: "Cloudflare",
+		markers:  []string{"<title>just a moment...</title>", "<title>attention required! | cloudflare</title>", "cf_chl_opt"},
+		headers:  map[string]string{"Cf-Mitigated": "challenge"},
+	},
+	{
+		provider: "Queue-it",
+		hosts:    []string{".queue-it.net"},
+	},
+	{
+		provider: "DataDome",
+		markers:  []string{"geo.captcha-delivery.com/captcha", "ct.captcha-delivery.com/c.js"},
+	},
+	{
+		provider: "PerimeterX",
+		markers:  []string{`id="px-captcha"`, "_pxcaptcha"},
+	},
+}
+
+// DetectChallenge returns the provider whose challenge or waiting room
+// page is at pageURL with the given html, served with headers, or "" when
+// it looks like an ordinary page.
+func DetectChallenge(pageURL, html string, headers http.Header) string {
+	host := ""
+	if u, err := url.Parse(pageURL); err == nil {
+		host = "." + strings.ToLower(u.Hostname())
+	}
+	lower := strings.ToLower(html)
+	for _, sig := range challengeSignatures {
+		for _, suffix := range sig.hosts {
+			if strings.HasSuffix(host, suffix) {
+				return sig.provider
+			}
+		}
+		for _, marker := range sig.markers {
+			if strings.Contains(lower, marker) {
+				return sig.provider
+			}
+		}
+		for name, want := range sig.headers {
+			for _, v := range headers.Values(name) {
+				if strings.Contains(strings.ToLower(v), want) {
+					return sig.provider
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// challengePoll is how often a challenge page is checked again while
+// waiting for it to resolve.
+const challengePoll = 2 * time.Second
+
+// waitOutChallenge checks the loaded page for a challenge and, if it is
+// one, re-checks it until it resolves or ChallengeWait passes. headers
+// returns the headers of the latest document response. It returns an
+// ErrChallenge error when the challenge never resolves.
+func (b *BrowserClient) waitOutChallenge(headers func() http.Header) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		provider, err := pageChallenge(ctx, headers())
+		if err != nil || provider == "" {
+			return nil
+		}
+
+		deadline := time.Now().Add(b.opts.ChallengeWait)
+		for time.Now().Before(deadline) {
+			if err := chromedp.Sleep(challengePoll).Do(ctx); err != nil {
+				break
+			}
+			if provider, err = pageChallenge(ctx, headers()); err == nil && provider == "" {
+				// Let the real page load its scripts
+				return chromedp.Sleep(b.opts.SettleDuration).Do(ctx)
+			}
+		}
+		hint := "try -headful"
+		if b.opts.UserDataDir == "" {
+			hint += " with -user-data-dir set to a profile that has passed it"
+		}
+		return fmt.Errorf("%w: %s challenge did not resolve within %s (%s)", ErrChallenge, provider, b.opts.ChallengeWait, hint)
+	})
+}
+
+// pageChallenge runs DetectChallenge over the current page.
+func pageChallenge(ctx context.Context, headers http.Header) (string, error) {
+	var page struct {
+		URL  string `json:"url"`
+		HTML string `json:"html"`
+	}
+	script := `({url: location.href, html: document.documentElement ? document.documentElement.outerHTML : ""})`
+	if err := chromedp.Evaluate(script, &page).Do(ctx); err != nil {
+		return "", err
+	}
+	return DetectChallenge(page.URL, page.HTML, headers), nil
+}
+
+// documentHeaders converts the headers of a CDP response.
+func documentHeaders(h network.Headers) http.Header {
+	headers := make(http.Header, len(h))
+	for name, value := range h {
+		// CDP joins repeated headers with newlines
+		if s, ok := value.(string); ok {
+			for _, v := range strings.Split(s, "\n") {
+				headers.Add(name, v)
+			}
+		}
+	}
+	return headers
+}
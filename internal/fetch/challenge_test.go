@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestDetectChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		html    string
+		headers http.Header
+		want    string
+	}{
+		{
+			name: "ordinary page",
+			url:  "https://example.com/",
+			html: `<html><head><title>Example</title><script src="/app.js"></script></head></html>`,
+		},
+		{
+			name: "cloudflare interstitial",
+			url:  "https://example.com/",
+			html: `<html><head><title>Just a moment...</title></head><body><script>window._cf_chl_opt={cType:'managed'}</script></body></html>`,
+			want: "Cloudflare",
+		},
+		{
+			name: "cloudflare block page",
+			url:  "https://example.com/",
+			html: `<HTML><HEAD><TITLE>Attention Required! | Cloudflare</TITLE></HEAD></HTML>`,
+			want: "Cloudflare",
+		},
+		{
+			name:    "cloudflare header only",
+			url:     "https://example.com/",
+			html:    `<html></html>`,
+			headers: http.Header{"Cf-Mitigated": {"Challenge"}},
+			want:    "Cloudflare",
+		},
+		{
+			name:    "cloudflare headers on an ordinary page",
+			url:     "https://example.com/",
+			html:    `<html><head><title>Shop</title></head></html>`,
+			headers: http.Header{"Cf-Ray": {"8a1b2c3d4e5f-FRA"}, "Server": {"cloudflare"}},
+		},
+		{
+			name: "queue-it waiting room",
+			url:  "https://shop.queue-it.net/?c=shop&e=drop",
+			html: `<html><head><title>Waiting room</title></head></html>`,
+			want: "Queue-it",
+		},
+		{
+			name: "queue-it host case",
+			url:  "https://Shop.Queue-It.NET/",
+			want: "Queue-it",
+		},
+		{
+			name: "queue-it look-alike host",
+			url:  "https://notqueue-it.net/",
+		},
+		{
+			name: "datadome captcha",
+			url:  "https://example.com/",
+			html: `<iframe src="https://geo.captcha-delivery.com/captcha/?initialCid=abc"></iframe>`,
+			want: "DataDome",
+		},
+		{
+			name: "perimeterx captcha",
+			url:  "https://example.com/",
+			html: `<div id="px-captcha"></div>`,
+			want: "PerimeterX",
+		},
+		{
+			name: "unparseable url",
+			url:  "://",
+			html: `<title>just a moment...</title>`,
+			want: "Cloudflare",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectChallenge(tt.url, tt.html, tt.headers); got != tt.want {
+				t.Errorf("DetectChallenge = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentHeaders(t *testing.T) {
+	got := documentHeaders(network.Headers{
+		"cf-mitigated": "challenge",
+		"set-cookie":   "a=1\nb=2",
+		"x-count":      42.0, // Not a string; dropped
+	})
+	want := http.Header{
+		"Cf-Mitigated": {"challenge"},
+		"Set-Cookie":   {"a=1", "b=2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("documentHeaders = %v, want %v", got, want)
+	}
+}
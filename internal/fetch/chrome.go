@@ -0,0 +1,104 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var (
+	// ErrChromeNotFound is returned when no Chrome or Chromium executable
+	// can be found.
+	ErrChromeNotFound = errors.New("no Chrome or Chromium executable found")
+
+	// ErrChromeStart is returned when Chrome was found but exited before
+	// the browser could be reached.
+	ErrChromeStart = errors.New("Chrome failed to start")
+)
+
+// maxStartOutput is how many trailing lines of Chrome's output are kept
+// in an ErrChromeStart error.
+const maxStartOutput = 10
+
+// FindChrome returns the Chrome executable a BrowserClient would launch.
+// An explicit path is checked as given; otherwise the same locations
+// chromedp searches are tried in order. The error wraps ErrChromeNotFound.
+func FindChrome(path string) (string, error) {
+	if path != "" {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("%w at %s: %v", ErrChromeNotFound, path, err)
+		}
+		return found, nil
+	}
+
+	candidates := chromeCandidates()
+	for _, c := range candidates {
+		if found, err := exec.LookPath(c); err == nil {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("%w (looked for %s)", ErrChromeNotFound, strings.Join(candidates, ", "))
+}
+
+// chromeCandidates lists the executables chromedp's allocator looks for on
+// this platform, in its order.
+func chromeCandidates() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}
+	case "windows":
+		candidates := []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		}
+		if home := os.Getenv("USERPROFILE"); home != "" {
+			candidates = append(candidates,
+				filepath.Join(home, `AppData\Local\Google\Chrome\Application\chrome.exe`),
+				filepath.Join(home, `AppData\Local\Chromium\Application\chrome.exe`),
+			)
+		}
+		return candidates
+	}
+	return []string{
+		"headless_shell",
+		"headless-shell",
+		"chromium",
+		"chromium-browser",
+		"google-chrome",
+		"google-chrome-stable",
+		"google-chrome-beta",
+		"google-chrome-unstable",
+		"/usr/bin/google-chrome",
+		"/usr/local/bin/chrome",
+		"/snap/bin/chromium",
+		"chrome",
+	}
+}
+
+// startError turns the allocator's early-exit error, which carries all of
+// Chrome's output, into an ErrChromeStart error with only the last few
+// lines. Other errors are returned unchanged.
+func startError(err error) error {
+	_, output, ok := strings.Cut(err.Error(), "chrome failed to start:")
+	if !ok {
+		return err
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > maxStartOutput {
+		lines = lines[len(lines)-maxStartOutput:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return ErrChromeStart
+	}
+	return fmt.Errorf("%w:\n%s", ErrChromeStart, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,125 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeChrome writes an executable shell script standing in for Chrome.
+func fakeChrome(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script as the browser")
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindChrome(t *testing.T) {
+	dir := t.TempDir()
+	chrome := fakeChrome(t, dir, "chromium", "exit 0\n")
+
+	if got, err := FindChrome(chrome); err != nil || got != chrome {
+		t.Errorf("FindChrome(%q) = %q, %v", chrome, got, err)
+	}
+	if _, err := FindChrome(filepath.Join(dir, "missing")); !errors.Is(err, ErrChromeNotFound) {
+		t.Errorf("FindChrome of a missing path = %v, want ErrChromeNotFound", err)
+	}
+
+	t.Setenv("PATH", dir)
+	if got, err := FindChrome(""); err != nil || got != chrome {
+		t.Errorf("FindChrome on PATH = %q, %v; want %q", got, err, chrome)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	for _, c := range chromeCandidates() {
+		if filepath.IsAbs(c) {
+			if _, err := os.Stat(c); err == nil {
+				t.Skipf("%s is installed", c)
+			}
+		}
+	}
+	_, err := FindChrome("")
+	if !errors.Is(err, ErrChromeNotFound) || !strings.Contains(err.Error(), "chromium") {
+		t.Errorf("FindChrome with nothing installed = %v, want ErrChromeNotFound listing the candidates", err)
+	}
+}
+
+func TestStartError(t *testing.T) {
+	var output []string
+	for i := 1; i <= 15; i++ {
+		output = append(output, fmt.Sprintf("line %d", i))
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantStart bool
+		want      string
+	}{
+		{"other error", errors.New("context deadline exceeded"), false, "context deadline exceeded"},
+		{"no output", errors.New("chrome failed to start:\n"), true, ErrChromeStart.Error()},
+		{"short output", errors.New("chrome failed to start:\nerror while loading shared libraries: libnss3.so"), true,
+			ErrChromeStart.Error() + ":\nerror while loading shared libraries: libnss3.so"},
+		{"long output", errors.New("chrome failed to start:\n" + strings.Join(output, "\n")), true,
+			ErrChromeStart.Error() + ":\n" + strings.Join(output[5:], "\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := startError(tt.err)
+			if errors.Is(got, ErrChromeStart) != tt.wantStart || got.Error() != tt.want {
+				t.Errorf("startError = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("could not dial ws://127.0.0.1:9222"), true},
+		{errors.New("dial tcp: connection refused"), true},
+		{errors.New("context deadline exceeded"), true},
+		{errors.New("net::ERR_NAME_NOT_RESOLVED"), false},
+		{fmt.Errorf("%w:\n[0101/000000.000:ERROR] connection refused", ErrChromeStart), false},
+		{fmt.Errorf("%w (looked for chrome)", ErrChromeNotFound), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverChromeExits(t *testing.T) {
+	// chromedp waits on the process while reading its output, and loses
+	// what is unread once it exits; a short sleep lets the read finish
+	chrome := fakeChrome(t, t.TempDir(), "chrome", "echo 'ERROR: could not dial the display: connection refused' >&2\nsleep 0.2\nexit 1\n")
+	opts := DefaultBrowserOptions()
+	opts.ExecPath = chrome
+	opts.Timeout = 20 * time.Second
+
+	start := time.Now()
+	_, err := NewBrowserClientWithOptions(opts).DiscoverResources("http://127.0.0.1:1/")
+	if !errors.Is(err, ErrChromeStart) {
+		t.Fatalf("DiscoverResources = %v, want ErrChromeStart", err)
+	}
+	if !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("error lacks Chrome's output: %v", err)
+	}
+	// A retry would wait at least 2s first
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("a Chrome that can't start took %s to report", elapsed)
+	}
+}
@@ -2,18 +2,67 @@
 package fetch
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/thesavant42/dejank/internal/disk"
 )
 
 // Client wraps http.Client with insecure TLS configuration.
 type Client struct {
-	http *http.Client
+	http           *http.Client
+	transport      *http.Transport
+	headers        http.Header         // Sent with every request that doesn't set them itself
+	requestTimeout time.Duration       // Limit for a request until its response headers arrive
+	idleTimeout    time.Duration       // Limit for a response body to go without data
+	maxBodySize    int64               // Limit for a body read into memory by Get, GetBytes and GetWithStatus
+	allow          func(u string) bool // URLs that may be requested, nil for all
+	ctx            context.Context     // Cancels every request, nil for none
+}
+
+// Default limits of a new Client.
+const (
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultIdleTimeout    = 30 * time.Second
+	DefaultMaxBodySize    = 100 << 20
+)
+
+var (
+	// ErrConnectTimeout is returned when a request gets no response
+	// headers within the request timeout.
+	ErrConnectTimeout = errors.New("connect timeout")
+
+	// ErrStalled is returned when a response body stops sending data for
+	// the idle timeout. Bodies that keep arriving, however large, have no
+	// overall limit.
+	ErrStalled = errors.New("stalled")
+
+	// ErrTooLarge is returned when a body read into memory is over the
+	// client's size limit. Downloads to disk have no such limit.
+	ErrTooLarge = errors.New("response too large")
+)
+
+// OutOfScopeError is returned for a request, or a redirect, to a URL that
+// the client's allowlist refuses.
+type OutOfScopeError struct {
+	URL string
+}
+
+func (e *OutOfScopeError) Error() string {
+	return e.URL + " is outside the scope allowlist"
 }
 
 // New creates a new Client with insecure TLS (ignores cert errors).
@@ -24,17 +73,197 @@ func New() *Client {
 		},
 	}
 
+	headers := make(http.Header)
 	return &Client{
 		http: &http.Client{
-			Transport: transport,
-			Timeout:   30 * time.Second,
+			Transport: &headerTransport{base: transport, headers: headers},
 		},
+		transport:      transport,
+		headers:        headers,
+		requestTimeout: DefaultRequestTimeout,
+		idleTimeout:    DefaultIdleTimeout,
+		maxBodySize:    DefaultMaxBodySize,
+	}
+}
+
+// SetTimeouts sets how long a request may wait for its response headers
+// and how long its body may go without data. Zero keeps the current
+// value. Call it before the client is used.
+func (c *Client) SetTimeouts(request, idle time.Duration) {
+	if request > 0 {
+		c.requestTimeout = request
+	}
+	if idle > 0 {
+		c.idleTimeout = idle
+	}
+}
+
+// SetMaxBodySize sets the largest body Get, GetBytes and GetWithStatus
+// read into memory. Zero keeps the current value. Call it before the
+// client is used.
+func (c *Client) SetMaxBodySize(n int64) {
+	if n > 0 {
+		c.maxBodySize = n
+	}
+}
+
+// readBody reads resp's body into memory, failing with ErrTooLarge rather
+// than reading past the client's size limit.
+func (c *Client) readBody(resp *http.Response) ([]byte, error) {
+	if resp.ContentLength > c.maxBodySize {
+		return nil, fmt.Errorf("%w: %d bytes is over the %d byte limit", ErrTooLarge, resp.ContentLength, c.maxBodySize)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBodySize+1))
+	if err == nil && int64(len(body)) > c.maxBodySize {
+		return nil, fmt.Errorf("%w: over the %d byte limit", ErrTooLarge, c.maxBodySize)
+	}
+	return body, err
+}
+
+// SetAllow refuses requests to URLs that allow rejects, including
+// redirects to them, with an *OutOfScopeError. Call it before the client
+// is used.
+func (c *Client) SetAllow(allow func(u string) bool) {
+	c.allow = allow
+	c.http.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !allow(req.URL.String()) {
+			return &OutOfScopeError{URL: req.URL.String()}
+		}
+		// The default policy
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// do sends req under the client's timeouts. The response body fails with
+// ErrStalled if it goes without data for the idle timeout; closing it
+// releases the request.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.allow != nil && !c.allow(req.URL.String()) {
+		return nil, &OutOfScopeError{URL: req.URL.String()}
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(c.requestTimeout, func() { cancel(ErrConnectTimeout) })
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if !timer.Stop() || err != nil {
+		// Headers that arrive as the timer fires are too late too
+		if err == nil {
+			resp.Body.Close()
+			err = context.Cause(ctx)
+		}
+		cancel(nil)
+		if errors.Is(context.Cause(ctx), ErrConnectTimeout) {
+			return nil, fmt.Errorf("%w: no response within %s", ErrConnectTimeout, c.requestTimeout)
+		}
+		return nil, err
+	}
+
+	body := &idleBody{body: resp.Body, idle: c.idleTimeout, cancel: cancel, ctx: ctx}
+	body.timer = time.AfterFunc(c.idleTimeout, func() { cancel(ErrStalled) })
+	resp.Body = body
+	return resp, nil
+}
+
+// idleBody is a response body that cancels its request when no data
+// arrives for idle, restarting the wait with every read that makes
+// progress.
+type idleBody struct {
+	body   io.ReadCloser
+	idle   time.Duration
+	timer  *time.Timer
+	cancel context.CancelCauseFunc
+	ctx    context.Context
+	read   int64
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.read += int64(n)
+		b.timer.Reset(b.idle)
+	}
+	if err != nil && err != io.EOF && errors.Is(context.Cause(b.ctx), ErrStalled) {
+		err = fmt.Errorf("%w after %d bytes (no data for %s)", ErrStalled, b.read, b.idle)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	err := b.body.Close()
+	b.cancel(nil)
+	return err
+}
+
+// WithContext returns a copy of the client whose requests are cancelled
+// with ctx, such as when a long-running process shuts down.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	copied := *c
+	copied.ctx = ctx
+	return &copied
+}
+
+// newRequest creates a request under the client's context.
+func (c *Client) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return http.NewRequestWithContext(ctx, method, url, body)
+}
+
+// get sends a GET request for url under the client's timeouts.
+func (c *Client) get(url string) (*http.Response, error) {
+	req, err := c.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// SetHeader sets a header sent with every request, such as User-Agent or
+// Accept-Language. An empty value removes it. Call it before the client is
+// used.
+func (c *Client) SetHeader(name, value string) {
+	if value == "" {
+		c.headers.Del(name)
+		return
 	}
+	c.headers.Set(name, value)
+}
+
+// UserAgent returns the User-Agent set with SetHeader, or "" for Go's
+// default.
+func (c *Client) UserAgent() string {
+	return c.headers.Get("User-Agent")
+}
+
+// headerTransport adds the client's headers to requests that don't set
+// them.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	return t.base.RoundTrip(req)
 }
 
 // Get fetches a URL and returns the response body as a string.
 func (c *Client) Get(url string) (string, error) {
-	resp, err := c.http.Get(url)
+	resp, err := c.get(url)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
 	}
@@ -44,7 +273,7 @@ func (c *Client) Get(url string) (string, error) {
 		return "", fmt.Errorf("HTTP %d when fetching %s", resp.StatusCode, url)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -54,7 +283,7 @@ func (c *Client) Get(url string) (string, error) {
 
 // GetBytes fetches a URL and returns the response body as bytes.
 func (c *Client) GetBytes(url string) ([]byte, error) {
-	resp, err := c.http.Get(url)
+	resp, err := c.get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
 	}
@@ -64,7 +293,7 @@ func (c *Client) GetBytes(url string) ([]byte, error) {
 		return nil, fmt.Errorf("HTTP %d when fetching %s", resp.StatusCode, url)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -72,37 +301,262 @@ func (c *Client) GetBytes(url string) ([]byte, error) {
 	return body, nil
 }
 
+// GetWithStatus fetches a URL and returns the response body and status
+// code. Unlike Get, a non-200 status is not an error.
+func (c *Client) GetWithStatus(url string) ([]byte, int, error) {
+	resp, err := c.get(url)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := c.readBody(resp)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// DownloadInfo describes the HTTP response of a download.
+type DownloadInfo struct {
+	StatusCode  int   // HTTP status code, 0 if no response was received
+	Bytes       int64 // Bytes written to disk
+	ContentType string
+	FinalURL    string        // URL of the last request after redirects, empty if no response
+	Duration    time.Duration // Time from the request to the end of the body
+	Validators  Validators    // Cache validators the server sent
+	SourceMap   string        // Value of the SourceMap or X-SourceMap header
+	UserAgent   string        // User-Agent the request was sent with, "" for Go's default
+	SHA256      string        // Hex SHA-256 of the bytes written to disk, hashed as they arrived
+}
+
+// Validators are the cache validators of a previously downloaded resource,
+// sent back to the server to ask whether it changed.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Empty reports whether there is nothing to validate with.
+func (v Validators) Empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
 // Download fetches a URL and saves it to the specified file path.
 // Creates parent directories as needed.
 func (c *Client) Download(url, destPath string) error {
-	resp, err := c.http.Get(url)
+	_, err := c.DownloadWithInfo(url, destPath)
+	return err
+}
+
+// DownloadWithInfo behaves like Download but also reports response metadata.
+// The returned info is populated as far as the request got, even on error.
+func (c *Client) DownloadWithInfo(url, destPath string) (DownloadInfo, error) {
+	return c.DownloadIfModified(url, destPath, Validators{})
+}
+
+// DownloadIfModified behaves like DownloadWithInfo but sends v as a
+// conditional request. If the server answers 304 Not Modified, destPath is
+// left untouched and the info's StatusCode is http.StatusNotModified.
+func (c *Client) DownloadIfModified(url, destPath string, v Validators) (DownloadInfo, error) {
+	start := time.Now()
+	info, err := c.download(url, destPath, v)
+	info.Duration = time.Since(start)
+	return info, err
+}
+
+// download implements DownloadIfModified without the timing.
+func (c *Client) download(url, destPath string, v Validators) (DownloadInfo, error) {
+	info := DownloadInfo{UserAgent: c.UserAgent()}
+
+	req, err := c.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return info, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch %s: %w", url, err)
+		return info, fmt.Errorf("failed to fetch %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
+	info.StatusCode = resp.StatusCode
+	info.FinalURL = resp.Request.URL.String()
+	info.ContentType = resp.Header.Get("Content-Type")
+	info.Validators = Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	info.SourceMap = sourceMapHeader(resp.Header)
+
+	if resp.StatusCode == http.StatusNotModified && !v.Empty() {
+		return info, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d when fetching %s", resp.StatusCode, url)
+		return info, fmt.Errorf("HTTP %d when fetching %s", resp.StatusCode, url)
 	}
 
 	// Ensure parent directory exists
 	dir := filepath.Dir(destPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		if err = disk.Check(dir, err); disk.Is(err) {
+			return info, err
+		}
+		return info, fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
+	// Replace rather than truncate: an existing file may be a hardlink
+	// shared with other runs
+	os.Remove(destPath)
 	file, err := os.Create(destPath)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", destPath, err)
+		if err = disk.Check(destPath, err); disk.Is(err) {
+			return info, err
+		}
+		return info, fmt.Errorf("failed to create file %s: %w", destPath, err)
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
+	h := sha256.New()
+	info.Bytes, err = io.Copy(io.MultiWriter(file, h), resp.Body)
 	if err != nil {
 		os.Remove(destPath) // Clean up partial file
-		return fmt.Errorf("failed to write file %s: %w", destPath, err)
+		if errors.Is(err, ErrStalled) {
+			return info, fmt.Errorf("failed to fetch %s: %w", url, err)
+		}
+		if err = disk.Check(destPath, err); disk.Is(err) {
+			return info, err
+		}
+		return info, fmt.Errorf("failed to write file %s: %w", destPath, err)
+	}
+	info.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	return info, nil
+}
+
+// PostJSON marshals v and POSTs it to url as application/json.
+func (c *Client) PostJSON(url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := c.newRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d when posting to %s", resp.StatusCode, url)
 	}
 
 	return nil
 }
 
+// ProbeInfo describes a resource inspected without downloading it in full.
+type ProbeInfo struct {
+	StatusCode      int
+	Bytes           int64 // Total size, -1 if the server did not report one
+	ContentType     string
+	SourceMapHeader string // Value of the SourceMap or X-SourceMap header
+}
+
+// Head issues a HEAD request and reports the resource's size and headers.
+func (c *Client) Head(url string) (ProbeInfo, error) {
+	info := ProbeInfo{Bytes: -1}
+
+	req, err := c.newRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return info, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return info, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	resp.Body.Close()
+
+	info.StatusCode = resp.StatusCode
+	info.ContentType = resp.Header.Get("Content-Type")
+	info.Bytes = resp.ContentLength
+	info.SourceMapHeader = sourceMapHeader(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("HTTP %d when fetching %s", resp.StatusCode, url)
+	}
+
+	return info, nil
+}
+
+// ErrRangeNotSupported is returned by GetTail when the server ignores the
+// Range header. The body is not read in that case.
+var ErrRangeNotSupported = errors.New("server does not support range requests")
+
+// GetTail fetches the last n bytes of a URL with a suffix Range request. It
+// also returns the total size reported in Content-Range, or -1 if unknown.
+func (c *Client) GetTail(url string, n int64) ([]byte, int64, error) {
+	req, err := c.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, -1, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=-%d", n))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, -1, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Empty resource
+		return nil, 0, nil
+	case http.StatusOK:
+		// Small resources may legitimately be returned whole
+		if resp.ContentLength >= 0 && resp.ContentLength <= n {
+			body, err := c.readBody(resp)
+			if err != nil {
+				return nil, -1, fmt.Errorf("failed to read response body: %w", err)
+			}
+			return body, int64(len(body)), nil
+		}
+		return nil, resp.ContentLength, ErrRangeNotSupported
+	default:
+		return nil, -1, fmt.Errorf("HTTP %d when fetching %s", resp.StatusCode, url)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, n))
+	if err != nil {
+		return nil, -1, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	total := int64(-1)
+	if _, size, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+		if v, err := strconv.ParseInt(size, 10, 64); err == nil {
+			total = v
+		}
+	}
+
+	return body, total, nil
+}
+
+// sourceMapHeader returns the sourcemap location advertised in response headers.
+func sourceMapHeader(h http.Header) string {
+	if v := h.Get("SourceMap"); v != "" {
+		return v
+	}
+	return h.Get("X-SourceMap")
+}
@@ -0,0 +1,133 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := New().WithContext(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Get after cancel = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancelled request took %s", elapsed)
+	}
+}
+
+func TestGetBytesLimit(t *testing.T) {
+	body := strings.Repeat("x", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// Flushing first leaves the length unknown
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		limit int64
+		path  string
+		ok    bool
+	}{
+		{"under the limit", 4096, "/", true},
+		{"at the limit", 2048, "/", true},
+		{"over the limit", 1024, "/", false},
+		{"over the limit without a length", 1024, "/chunked", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New()
+			client.SetMaxBodySize(tt.limit)
+			data, err := client.GetBytes(srv.URL + tt.path)
+			if tt.ok {
+				if err != nil || string(data) != body {
+					t.Errorf("GetBytes = %d bytes, %v", len(data), err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTooLarge) {
+				t.Errorf("GetBytes = %d bytes, %v; want ErrTooLarge", len(data), err)
+			}
+		})
+	}
+}
+
+func TestClientTimeouts(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wait := func(d time.Duration) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(d):
+			}
+		}
+		switch r.URL.Path {
+		case "/slow-headers":
+			wait(5 * time.Second)
+		case "/stall":
+			io.WriteString(w, "partial")
+			w.(http.Flusher).Flush()
+			wait(5 * time.Second)
+		case "/trickle":
+			// Longer than the idle timeout overall, but never idle for it
+			for i := 0; i < 8; i++ {
+				io.WriteString(w, "chunk")
+				w.(http.Flusher).Flush()
+				wait(timeout / 4)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want error // nil for a complete body
+		body string
+	}{
+		{"/slow-headers", ErrConnectTimeout, ""},
+		{"/stall", ErrStalled, ""},
+		{"/trickle", nil, strings.Repeat("chunk", 8)},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimPrefix(tt.path, "/"), func(t *testing.T) {
+			client := New()
+			client.SetTimeouts(timeout, timeout)
+			start := time.Now()
+			data, err := client.GetBytes(srv.URL + tt.path)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("GetBytes took %s", elapsed)
+			}
+			if tt.want == nil {
+				if err != nil || string(data) != tt.body {
+					t.Errorf("GetBytes = %q, %v; want %q", data, err, tt.body)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("GetBytes = %q, %v; want %v", data, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SetClientCertificate makes the client present the certificate in
+// certFile, with its private key in keyFile, to servers that require
+// mutual TLS. Both are PEM files. Call it before the client is used.
+//
+// Only the client's own requests present it; Chrome launched by
+// BrowserClient does not.
+func (c *Client) SetClientCertificate(certFile, keyFile string) error {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return fmt.Errorf("cannot read client certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot read client key: %w", err)
+	}
+	if bytes.Contains(keyPEM, []byte("ENCRYPTED")) {
+		return fmt.Errorf("client key %s is encrypted; decrypt it first, e.g. openssl pkey -in %s -out key.pem", keyFile, keyFile)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid client certificate %s and key %s: %w", certFile, keyFile, err)
+	}
+	if leaf := cert.Leaf; leaf != nil {
+		now := time.Now()
+		if now.After(leaf.NotAfter) {
+			return fmt.Errorf("client certificate %s expired on %s", certFile, leaf.NotAfter.Format(time.DateOnly))
+		}
+		if now.Before(leaf.NotBefore) {
+			return fmt.Errorf("client certificate %s is not valid until %s", certFile, leaf.NotBefore.Format(time.DateOnly))
+		}
+	}
+
+	c.transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	return nil
+}
@@ -0,0 +1,172 @@
+package fetch
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+// ConsoleEntry is a browser console message or uncaught page error that may
+// explain why a script or chunk is missing, such as a CSP violation, a CORS
+// failure, or a lazy import that 404ed.
+type ConsoleEntry struct {
+	Level  string `json:"level"`         // error or warning
+	Source string `json:"source"`        // console, exception, or the log source, e.g. network or security
+	Text   string `json:"text"`          // Message, with obvious secrets redacted
+	URL    string `json:"url,omitempty"` // Resource the message is about, if known
+}
+
+// maxConsoleEntries caps the console entries kept per page.
+const maxConsoleEntries = 50
+
+// maxConsoleText caps the length of a kept message.
+const maxConsoleText = 500
+
+// scriptRefPattern matches script and sourcemap URLs in console text.
+var scriptRefPattern = regexp.MustCompile(`https?://\S+?\.(?:[mc]?js|jsx|map)\b`)
+
+// loadFailureMarkers are phrases of messages about resources that failed to
+// load, whatever URL they name.
+var loadFailureMarkers = []string{
+	"Refused to load",
+	"Refused to execute",
+	"ChunkLoadError",
+	"Loading chunk",
+	"Failed to fetch dynamically imported module",
+	"blocked by CORS policy",
+}
+
+// consoleSecretPatterns match obviously sensitive console output, with the
+// part to keep in the first group.
+var consoleSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[\w\-.~+/]+=*`),
+	regexp.MustCompile(`()eyJ[\w-]+\.[\w-]+\.[\w-]+`),
+	regexp.MustCompile(`(?i)((?:^|[?&;,\s"'])\w*(?:token|secret|passw(?:or)?d|api_?key|session|auth|signature|sig)["']?\s*[=:]\s*["']?)[^&\s"',;]+`),
+}
+
+// consoleCapture collects relevant console entries for one page load.
+type consoleCapture struct {
+	entries []ConsoleEntry
+	seen    map[string]bool
+	dropped int
+}
+
+func newConsoleCapture() *consoleCapture {
+	return &consoleCapture{seen: make(map[string]bool)}
+}
+
+// consoleAPICalled records console.error and console.warn calls. The
+// calling script is their URL, so only their text decides relevance.
+func (c *consoleCapture) consoleAPICalled(e *runtime.EventConsoleAPICalled) {
+	if e.Type != runtime.APITypeError && e.Type != runtime.APITypeWarning {
+		return
+	}
+	parts := make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		parts = append(parts, remoteObjectText(arg))
+	}
+	text := strings.Join(parts, " ")
+	if !relevantConsole(text, "") {
+		return
+	}
+	url := ""
+	if e.StackTrace != nil && len(e.StackTrace.CallFrames) > 0 {
+		url = e.StackTrace.CallFrames[0].URL
+	}
+	c.add(string(e.Type), "console", text, url)
+}
+
+// entryAdded records browser-generated errors and warnings, such as failed
+// requests and CSP violations.
+func (c *consoleCapture) entryAdded(e *cdplog.EventEntryAdded) {
+	if e.Entry == nil || (e.Entry.Level != cdplog.LevelError && e.Entry.Level != cdplog.LevelWarning) {
+		return
+	}
+	c.add(string(e.Entry.Level), string(e.Entry.Source), e.Entry.Text, e.Entry.URL)
+}
+
+// exceptionThrown records uncaught page errors. Like console calls, their
+// URL is the throwing script, so only their text decides relevance.
+func (c *consoleCapture) exceptionThrown(e *runtime.EventExceptionThrown) {
+	d := e.ExceptionDetails
+	if d == nil {
+		return
+	}
+	text := d.Text
+	if d.Exception != nil && d.Exception.Description != "" {
+		text = d.Exception.Description
+	}
+	if !relevantConsole(text, "") {
+		return
+	}
+	c.add("error", "exception", text, d.URL)
+}
+
+// add keeps an entry if it is about a script or a failed load and not
+// already kept, up to maxConsoleEntries.
+func (c *consoleCapture) add(level, source, text, url string) {
+	if !relevantConsole(text, url) {
+		return
+	}
+	entry := ConsoleEntry{Level: level, Source: source, Text: redactConsole(text), URL: redactConsole(url)}
+	key := entry.Text + "\x00" + entry.URL
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	if len(c.entries) >= maxConsoleEntries {
+		c.dropped++
+		return
+	}
+	c.entries = append(c.entries, entry)
+}
+
+// relevantConsole reports whether a console message may explain a missing
+// script: it names a script or sourcemap URL or says a load failed.
+func relevantConsole(text, url string) bool {
+	if isJavaScriptURL(url) || isSourceMapURL(url) || scriptRefPattern.MatchString(text) {
+		return true
+	}
+	for _, marker := range loadFailureMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConsole masks bearer tokens, JWTs, and secret-looking parameters in
+// console output and shortens long messages.
+func redactConsole(text string) string {
+	for _, re := range consoleSecretPatterns {
+		text = re.ReplaceAllString(text, "${1}<redacted>")
+	}
+	if len(text) > maxConsoleText {
+		text = strings.ToValidUTF8(text[:maxConsoleText], "") + "..."
+	}
+	return text
+}
+
+// remoteObjectText renders a console argument: strings as themselves,
+// other values by their description.
+func remoteObjectText(o *runtime.RemoteObject) string {
+	if o == nil {
+		return ""
+	}
+	if o.Type == runtime.TypeString {
+		var s string
+		if json.Unmarshal(o.Value, &s) == nil {
+			return s
+		}
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	if len(o.Value) > 0 {
+		return string(o.Value)
+	}
+	return string(o.UnserializableValue)
+}
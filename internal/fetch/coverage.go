@@ -0,0 +1,123 @@
+package fetch
+
+import (
+	"context"
+	"sort"
+
+	"github.com/chromedp/cdproto/profiler"
+	"github.com/chromedp/chromedp"
+)
+
+// ExecutedRange is a half-open range [Start, End) of a script that ran
+// during page load. Offsets count UTF-16 code units, as V8 reports them.
+type ExecutedRange struct {
+	Start int
+	End   int
+}
+
+// startCoverage turns on V8 precise block coverage. It must run before
+// navigation so top-level code of every script is counted.
+func startCoverage() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := profiler.Enable().Do(ctx); err != nil {
+			return err
+		}
+		_, err := profiler.StartPreciseCoverage().WithDetailed(true).Do(ctx)
+		return err
+	})
+}
+
+// takeCoverage collects the coverage recorded since startCoverage into
+// executed ranges per script URL. Scripts without a URL (eval'd code,
+// inline handlers) are dropped.
+func takeCoverage(out *map[string][]ExecutedRange) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		scripts, _, err := profiler.TakePreciseCoverage().Do(ctx)
+		if err != nil {
+			return err
+		}
+		_ = profiler.StopPreciseCoverage().Do(ctx)
+
+		coverage := make(map[string][]ExecutedRange)
+		for _, s := range scripts {
+			if s.URL == "" {
+				continue
+			}
+			var ranges []*profiler.CoverageRange
+			for _, fn := range s.Functions {
+				ranges = append(ranges, fn.Ranges...)
+			}
+			coverage[s.URL] = mergeRanges(append(coverage[s.URL], executedRanges(ranges)...))
+		}
+		*out = coverage
+		return nil
+	})
+}
+
+// executedRanges flattens V8's nested coverage ranges into disjoint ranges
+// with a non-zero count. Inner ranges override the count of the ranges
+// enclosing them, so a function that ran with a branch that didn't leaves
+// a gap for the branch.
+func executedRanges(ranges []*profiler.CoverageRange) []ExecutedRange {
+	type point struct {
+		offset int
+		start  bool
+		r      *profiler.CoverageRange
+	}
+	points := make([]point, 0, 2*len(ranges))
+	for _, r := range ranges {
+		points = append(points, point{int(r.StartOffset), true, r}, point{int(r.EndOffset), false, r})
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		if a.offset != b.offset {
+			return a.offset < b.offset
+		}
+		// Ends close before starts open at the same offset
+		if a.start != b.start {
+			return !a.start
+		}
+		la := a.r.EndOffset - a.r.StartOffset
+		lb := b.r.EndOffset - b.r.StartOffset
+		if a.start {
+			return la > lb // Outer ranges open first
+		}
+		return la < lb // Inner ranges close first
+	})
+
+	var out []ExecutedRange
+	var stack []int64
+	last := 0
+	for _, p := range points {
+		if len(stack) > 0 && stack[len(stack)-1] > 0 && last < p.offset {
+			out = appendRange(out, ExecutedRange{Start: last, End: p.offset})
+		}
+		last = p.offset
+		if p.start {
+			stack = append(stack, p.r.Count)
+		} else if len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return out
+}
+
+// mergeRanges sorts ranges and joins those that touch or overlap.
+func mergeRanges(ranges []ExecutedRange) []ExecutedRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	var out []ExecutedRange
+	for _, r := range ranges {
+		out = appendRange(out, r)
+	}
+	return out
+}
+
+// appendRange appends r to sorted ranges, extending the last range when
+// they touch.
+func appendRange(ranges []ExecutedRange, r ExecutedRange) []ExecutedRange {
+	if n := len(ranges); n > 0 && ranges[n-1].End >= r.Start {
+		ranges[n-1].End = max(ranges[n-1].End, r.End)
+		return ranges
+	}
+	return append(ranges, r)
+}
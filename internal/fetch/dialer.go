@@ -0,0 +1,160 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// SetDialer makes the client connect over network, "tcp4" or "tcp6" (""
+// for either), from the local address local (nil for the system's
+// choice). A local address also restricts connections to its family. Call
+// it before the client is used.
+//
+// Only the client's own requests are affected; Chrome launched by
+// BrowserClient uses the system's routing.
+func (c *Client) SetDialer(network string, local net.IP) {
+	d := &dialer{
+		network: network,
+		net:     &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	if local != nil {
+		d.net.LocalAddr = &net.TCPAddr{IP: local}
+		if d.network == "" {
+			d.network = ipNetwork(local)
+		}
+	}
+	c.transport.DialContext = d.DialContext
+}
+
+// dialer dials every connection over a fixed address family.
+type dialer struct {
+	network string
+	net     *net.Dialer
+}
+
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.network == "" {
+		return d.net.DialContext(ctx, network, addr)
+	}
+	conn, err := d.net.DialContext(ctx, d.network, addr)
+	var dnsErr *net.DNSError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &dnsErr) {
+		// The target may simply lack the family, which the error alone
+		// doesn't make obvious
+		if host, _, splitErr := net.SplitHostPort(addr); splitErr == nil && net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("%w (%s resolves to %s)", err, host, resolved(ctx, host))
+		}
+	}
+	return conn, err
+}
+
+// resolved lists every address host resolves to, for error messages.
+func resolved(ctx context.Context, host string) string {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return "no addresses"
+	}
+	list := make([]string, len(addrs))
+	for i, a := range addrs {
+		list[i] = a.IP.String()
+	}
+	return strings.Join(list, ", ")
+}
+
+// ipNetwork returns the dial network of ip's family.
+func ipNetwork(ip net.IP) string {
+	if ip.To4() != nil {
+		return "tcp4"
+	}
+	return "tcp6"
+}
+
+// familyName describes network for messages.
+func familyName(network string) string {
+	if network == "tcp6" {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// SourceIP parses s as the local address to connect from, checking that
+// this machine has it and that it belongs to network ("tcp4", "tcp6", or
+// "" for either).
+func SourceIP(s, network string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source IP %q", s)
+	}
+	if network != "" && ipNetwork(ip) != network {
+		return nil, fmt.Errorf("source IP %s is not an %s address", s, familyName(network))
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list local addresses: %w", err)
+	}
+	var local []string
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			if n.IP.Equal(ip) {
+				return ip, nil
+			}
+			local = append(local, n.IP.String())
+		}
+	}
+	return nil, fmt.Errorf("source IP %s is not an address of this machine (have: %s)", s, strings.Join(local, ", "))
+}
+
+// InterfaceIP returns the first address of the named network interface in
+// network's family ("tcp4", "tcp6", or "" for either, preferring IPv4).
+// IPv6 link-local addresses are skipped since they can't reach other
+// networks.
+func InterfaceIP(name, network string) (net.IP, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list addresses of interface %s: %w", name, err)
+	}
+
+	var v4, v6 net.IP
+	var all []string
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		all = append(all, n.IP.String())
+		switch {
+		case n.IP.To4() != nil:
+			if v4 == nil {
+				v4 = n.IP
+			}
+		case !n.IP.IsLinkLocalUnicast():
+			if v6 == nil {
+				v6 = n.IP
+			}
+		}
+	}
+
+	switch {
+	case network != "tcp6" && v4 != nil:
+		return v4, nil
+	case network != "tcp4" && v6 != nil:
+		return v6, nil
+	}
+	have := "none"
+	if len(all) > 0 {
+		have = strings.Join(all, ", ")
+	}
+	if network == "" {
+		return nil, fmt.Errorf("interface %s has no usable address (have: %s)", name, have)
+	}
+	return nil, fmt.Errorf("interface %s has no %s address (have: %s)", name, familyName(network), have)
+}
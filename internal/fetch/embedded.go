@@ -0,0 +1,177 @@
+package fetch
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/debugger"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Kinds of EmbeddedScript.
+const (
+	EmbeddedBlob    = "blob"
+	EmbeddedDataURI = "data"
+)
+
+// maxDataURILabel caps how much of a data: URI is kept as its label.
+const maxDataURILabel = 64
+
+// EmbeddedScript is a script loaded from a blob: or data: URL, which cannot
+// be downloaded again after the page is gone. Its content is captured from
+// the browser while the page is open.
+type EmbeddedScript struct {
+	URL     string // blob: URL, or a truncated data: URI
+	Kind    string // EmbeddedBlob or EmbeddedDataURI
+	Content string
+}
+
+// embeddedCapture collects blob: and data: scripts seen during a page load.
+// The listener records where each script can be read from; collect reads
+// them once navigation is done, since CDP commands can't be issued from
+// inside an event handler.
+type embeddedCapture struct {
+	order    []string                     // URLs in first-seen order
+	scripts  map[string]*EmbeddedScript   // URL -> script, content filled in by collect
+	parsed   map[string]runtime.ScriptID  // URL -> Debugger script ID
+	requests map[string]network.RequestID // URL -> network request ID
+}
+
+func newEmbeddedCapture() *embeddedCapture {
+	return &embeddedCapture{
+		scripts:  make(map[string]*EmbeddedScript),
+		parsed:   make(map[string]runtime.ScriptID),
+		requests: make(map[string]network.RequestID),
+	}
+}
+
+// isEmbeddedURL reports whether a script URL is a blob: URL or data: URI.
+func isEmbeddedURL(u string) bool {
+	return strings.HasPrefix(u, "blob:") || strings.HasPrefix(u, "data:")
+}
+
+// add records an embedded script URL. data: URIs carry their content and
+// are decoded right away; anything that is not JavaScript is ignored.
+func (c *embeddedCapture) add(u string) *EmbeddedScript {
+	if s, ok := c.scripts[u]; ok {
+		return s
+	}
+	s := &EmbeddedScript{URL: u, Kind: EmbeddedBlob}
+	if strings.HasPrefix(u, "data:") {
+		mime, content, ok := decodeDataURI(u)
+		if !ok || !isJavaScriptMIME(mime) {
+			return nil
+		}
+		s.Kind = EmbeddedDataURI
+		s.Content = content
+		if len(u) > maxDataURILabel {
+			s.URL = u[:maxDataURILabel] + "..."
+		}
+	}
+	c.scripts[u] = s
+	c.order = append(c.order, u)
+	return s
+}
+
+// scriptParsed handles a Debugger.scriptParsed event.
+func (c *embeddedCapture) scriptParsed(e *debugger.EventScriptParsed) {
+	if !isEmbeddedURL(e.URL) || c.add(e.URL) == nil {
+		return
+	}
+	c.parsed[e.URL] = e.ScriptID
+}
+
+// requestWillBeSent handles a Network.requestWillBeSent event for a blob:
+// or data: URL. Only script and worker requests are kept; blob: images and
+// downloads are common and uninteresting.
+func (c *embeddedCapture) requestWillBeSent(e *network.EventRequestWillBeSent) {
+	if e.Type != network.ResourceTypeScript && e.Type != network.ResourceTypeOther {
+		return
+	}
+	if c.add(e.Request.URL) == nil {
+		return
+	}
+	c.requests[e.Request.URL] = e.RequestID
+}
+
+// embeddedSource is a captured script and where its content can be read.
+type embeddedSource struct {
+	script    EmbeddedScript
+	scriptID  runtime.ScriptID  // Empty if the debugger didn't report it
+	requestID network.RequestID // Empty if no network request was seen
+}
+
+// snapshot copies the captured scripts so they can be read without holding
+// the listener's lock.
+func (c *embeddedCapture) snapshot() []embeddedSource {
+	out := make([]embeddedSource, 0, len(c.order))
+	for _, u := range c.order {
+		out = append(out, embeddedSource{script: *c.scripts[u], scriptID: c.parsed[u], requestID: c.requests[u]})
+	}
+	return out
+}
+
+// collectEmbedded reads the content of each captured blob: script,
+// preferring the parsed source from the debugger and falling back to the
+// network response body. Scripts whose content can't be read are dropped.
+func collectEmbedded(sources []embeddedSource, out *[]EmbeddedScript) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, src := range sources {
+			s := src.script
+			if s.Content == "" && src.scriptID != "" {
+				if text, _, err := debugger.GetScriptSource(src.scriptID).Do(ctx); err == nil {
+					s.Content = text
+				}
+			}
+			if s.Content == "" && src.requestID != "" {
+				if body, err := network.GetResponseBody(src.requestID).Do(ctx); err == nil {
+					s.Content = string(body)
+				}
+			}
+			if s.Content != "" {
+				*out = append(*out, s)
+			}
+		}
+		return nil
+	})
+}
+
+// decodeDataURI decodes a data: URI into its media type and content.
+func decodeDataURI(u string) (string, string, bool) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(u, "data:"), ",")
+	if !ok {
+		return "", "", false
+	}
+	params := strings.Split(meta, ";")
+	mime := strings.ToLower(strings.TrimSpace(params[0]))
+
+	if params[len(params)-1] == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			if decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "=")); err != nil {
+				return "", "", false
+			}
+		}
+		return mime, string(decoded), true
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return "", "", false
+	}
+	return mime, decoded, true
+}
+
+// isJavaScriptMIME reports whether a data: URI media type is a script type.
+// An empty type defaults to text/plain, but script tags run it anyway.
+func isJavaScriptMIME(mime string) bool {
+	switch mime {
+	case "", "text/javascript", "application/javascript", "application/x-javascript",
+		"text/ecmascript", "application/ecmascript":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// The phone emulated with BrowserOptions.Mobile, an iPhone 15 in portrait.
+const (
+	mobileWidth     = 393
+	mobileHeight    = 852
+	mobileScale     = 3
+	mobileUserAgent = "iphone" // UserAgentPresets entry
+)
+
+// Geolocation is a position reported to the page's geolocation API.
+type Geolocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// localePattern matches BCP 47 style locales such as de, de-DE, or zh-Hant-TW.
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(?:[-_][A-Za-z0-9]{2,8})*$`)
+
+// ValidateLocale checks a -locale value such as de-DE.
+func ValidateLocale(locale string) error {
+	if !localePattern.MatchString(locale) {
+		return fmt.Errorf("invalid locale %q (want e.g. de-DE)", locale)
+	}
+	return nil
+}
+
+// ValidateTimezone checks a -timezone value, an IANA name such as
+// Europe/Berlin.
+func ValidateTimezone(tz string) error {
+	if _, err := time.LoadLocation(tz); err != nil || tz == "" || tz == "Local" {
+		return fmt.Errorf("invalid timezone %q (want an IANA name, e.g. Europe/Berlin)", tz)
+	}
+	return nil
+}
+
+// ParseGeolocation parses a -geo value, "lat,lon" in decimal degrees.
+func ParseGeolocation(s string) (*Geolocation, error) {
+	latStr, lonStr, ok := strings.Cut(s, ",")
+	if ok {
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+		if latErr == nil && lonErr == nil && lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180 {
+			return &Geolocation{Latitude: lat, Longitude: lon}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid geolocation %q (want lat,lon, e.g. 52.52,13.40)", s)
+}
+
+// AcceptLanguageFor returns an Accept-Language value preferring locale,
+// e.g. "de-DE,de;q=0.9" for de-DE, so downloads match an emulated locale.
+func AcceptLanguageFor(locale string) string {
+	locale = strings.ReplaceAll(locale, "_", "-")
+	lang, _, ok := strings.Cut(locale, "-")
+	if !ok {
+		return locale
+	}
+	return fmt.Sprintf("%s,%s;q=0.9", locale, lang)
+}
+
+// MobileUserAgent returns the User-Agent of the phone emulated with
+// BrowserOptions.Mobile.
+func MobileUserAgent() string {
+	return UserAgentPresets[mobileUserAgent]
+}
+
+// userAgent returns the User-Agent the browser is made to send, "" for
+// Chrome's own.
+func (o BrowserOptions) userAgent() string {
+	if o.UserAgent == "" && o.Mobile {
+		return MobileUserAgent()
+	}
+	return o.UserAgent
+}
+
+// emulationActions returns the CDP overrides the options call for, to run
+// before navigation: device metrics and touch for Mobile, then User-Agent,
+// locale, timezone, and geolocation.
+func (o BrowserOptions) emulationActions() []chromedp.Action {
+	var actions []chromedp.Action
+	if o.Mobile {
+		actions = append(actions,
+			emulation.SetDeviceMetricsOverride(mobileWidth, mobileHeight, mobileScale, true),
+			emulation.SetTouchEmulationEnabled(true).WithMaxTouchPoints(5),
+		)
+	}
+	if ua := o.userAgent(); ua != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(ua).WithAcceptLanguage(o.AcceptLanguage))
+	}
+	if o.Locale != "" {
+		actions = append(actions, emulation.SetLocaleOverride().WithLocale(o.Locale))
+	}
+	if o.Timezone != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(o.Timezone))
+	}
+	if o.Geolocation != nil {
+		actions = append(actions,
+			// Permissions belong to the browser, not the page
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				grant := cdpbrowser.GrantPermissions([]cdpbrowser.PermissionType{cdpbrowser.PermissionTypeGeolocation})
+				return grant.Do(cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Browser))
+			}),
+			emulation.SetGeolocationOverride().
+				WithLatitude(o.Geolocation.Latitude).
+				WithLongitude(o.Geolocation.Longitude).
+				WithAccuracy(100),
+		)
+	}
+	return actions
+}
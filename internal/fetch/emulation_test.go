@@ -0,0 +1,128 @@
+package fetch
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// describeAction names a CDP override and the values it sets, so the list
+// emulationActions returns can be compared as a whole.
+func describeAction(a chromedp.Action) string {
+	switch p := a.(type) {
+	case *emulation.SetDeviceMetricsOverrideParams:
+		return fmt.Sprintf("metrics %dx%d@%v mobile=%v", p.Width, p.Height, p.DeviceScaleFactor, p.Mobile)
+	case *emulation.SetTouchEmulationEnabledParams:
+		return fmt.Sprintf("touch %v points=%d", p.Enabled, p.MaxTouchPoints)
+	case *emulation.SetUserAgentOverrideParams:
+		return fmt.Sprintf("user agent %q lang=%q", p.UserAgent, p.AcceptLanguage)
+	case *emulation.SetLocaleOverrideParams:
+		return fmt.Sprintf("locale %s", p.Locale)
+	case *emulation.SetTimezoneOverrideParams:
+		return fmt.Sprintf("timezone %s", p.TimezoneID)
+	case *emulation.SetGeolocationOverrideParams:
+		return fmt.Sprintf("geolocation %v,%v +/-%v", p.Latitude, p.Longitude, p.Accuracy)
+	case chromedp.ActionFunc:
+		return "grant geolocation" // The only ActionFunc emulationActions returns
+	}
+	return fmt.Sprintf("unexpected %T", a)
+}
+
+func TestEmulationActions(t *testing.T) {
+	iphone := UserAgentPresets[mobileUserAgent]
+	tests := []struct {
+		name string
+		opts BrowserOptions
+		want []string
+	}{
+		{"none", BrowserOptions{}, nil},
+		{"accept language alone", BrowserOptions{AcceptLanguage: "de-DE,de;q=0.9"}, nil},
+		{"user agent", BrowserOptions{UserAgent: "dejank/1.0", AcceptLanguage: "de-DE,de;q=0.9"}, []string{
+			`user agent "dejank/1.0" lang="de-DE,de;q=0.9"`,
+		}},
+		{"mobile", BrowserOptions{Mobile: true}, []string{
+			"metrics 393x852@3 mobile=true",
+			"touch true points=5",
+			fmt.Sprintf("user agent %q lang=%q", iphone, ""),
+		}},
+		{"mobile with user agent", BrowserOptions{Mobile: true, UserAgent: "dejank/1.0"}, []string{
+			"metrics 393x852@3 mobile=true",
+			"touch true points=5",
+			`user agent "dejank/1.0" lang=""`,
+		}},
+		{"locale and timezone", BrowserOptions{Locale: "de-DE", Timezone: "Europe/Berlin"}, []string{
+			"locale de-DE",
+			"timezone Europe/Berlin",
+		}},
+		{"geolocation", BrowserOptions{Geolocation: &Geolocation{Latitude: 52.52, Longitude: 13.4}}, []string{
+			"grant geolocation",
+			"geolocation 52.52,13.4 +/-100",
+		}},
+		{"everything", BrowserOptions{
+			Mobile:         true,
+			AcceptLanguage: "ja-JP,ja;q=0.9",
+			Locale:         "ja-JP",
+			Timezone:       "Asia/Tokyo",
+			Geolocation:    &Geolocation{Latitude: 35.68, Longitude: 139.69},
+		}, []string{
+			"metrics 393x852@3 mobile=true",
+			"touch true points=5",
+			fmt.Sprintf("user agent %q lang=%q", iphone, "ja-JP,ja;q=0.9"),
+			"locale ja-JP",
+			"timezone Asia/Tokyo",
+			"grant geolocation",
+			"geolocation 35.68,139.69 +/-100",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range tt.opts.emulationActions() {
+				got = append(got, describeAction(a))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("emulationActions =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmulationFlags(t *testing.T) {
+	for _, locale := range []string{"de", "de-DE", "zh-Hant-TW", "en_US"} {
+		if err := ValidateLocale(locale); err != nil {
+			t.Errorf("ValidateLocale(%q) = %v", locale, err)
+		}
+	}
+	for _, locale := range []string{"", "d", "de DE", "de-", "de-DE;q=1"} {
+		if ValidateLocale(locale) == nil {
+			t.Errorf("ValidateLocale(%q) accepted", locale)
+		}
+	}
+
+	if err := ValidateTimezone("Europe/Berlin"); err != nil {
+		t.Errorf("ValidateTimezone(Europe/Berlin) = %v", err)
+	}
+	for _, tz := range []string{"", "Local", "Mars/Olympus"} {
+		if ValidateTimezone(tz) == nil {
+			t.Errorf("ValidateTimezone(%q) accepted", tz)
+		}
+	}
+
+	if geo, err := ParseGeolocation(" 52.52, -13.4 "); err != nil || *geo != (Geolocation{52.52, -13.4}) {
+		t.Errorf("ParseGeolocation = %v, %v", geo, err)
+	}
+	for _, s := range []string{"", "52.52", "91,0", "0,181", "north,east"} {
+		if _, err := ParseGeolocation(s); err == nil {
+			t.Errorf("ParseGeolocation(%q) accepted", s)
+		}
+	}
+
+	for locale, want := range map[string]string{"de-DE": "de-DE,de;q=0.9", "en_US": "en-US,en;q=0.9", "fr": "fr"} {
+		if got := AcceptLanguageFor(locale); got != want {
+			t.Errorf("AcceptLanguageFor(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
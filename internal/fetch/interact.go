@@ -0,0 +1,227 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"gopkg.in/yaml.v3"
+)
+
+// InteractionStep is one step of an -interact script, run after the page
+// loads to trigger chunks that only load on user interaction. Exactly one
+// of Click, Type, Wait, Scroll, and Press is set.
+type InteractionStep struct {
+	Click  string `yaml:"click,omitempty"`  // CSS selector of an element to click
+	Type   string `yaml:"type,omitempty"`   // Text to type, into Into or the focused element
+	Into   string `yaml:"into,omitempty"`   // CSS selector of the element Type types into
+	Wait   int    `yaml:"wait,omitempty"`   // Milliseconds to wait
+	Scroll string `yaml:"scroll,omitempty"` // "bottom" or "top"
+	Press  string `yaml:"press,omitempty"`  // Key to press: a character or a name such as Enter or Escape
+}
+
+// InteractionResult reports how a step went.
+type InteractionResult struct {
+	Step  string `json:"step"`            // The step, e.g. "click #menu"
+	Error string `json:"error,omitempty"` // Why it failed; later steps still run
+}
+
+// interactionStepTimeout bounds each step, so a selector that never
+// appears doesn't use up the discovery timeout.
+const interactionStepTimeout = 5 * time.Second
+
+// keyNames maps the key names Press accepts to their key codes.
+var keyNames = map[string]string{
+	"enter":      kb.Enter,
+	"escape":     kb.Escape,
+	"tab":        kb.Tab,
+	"backspace":  kb.Backspace,
+	"space":      " ",
+	"arrowup":    kb.ArrowUp,
+	"arrowdown":  kb.ArrowDown,
+	"arrowleft":  kb.ArrowLeft,
+	"arrowright": kb.ArrowRight,
+	"pageup":     kb.PageUp,
+	"pagedown":   kb.PageDown,
+	"home":       kb.Home,
+	"end":        kb.End,
+}
+
+// LoadInteraction reads an -interact script: a YAML or JSON list of steps
+// such as
+//
+//	# steps.yaml
+//	- click: "#menu"
+//	- type: "shoes"
+//	  into: "input[name=q]"
+//	- press: Enter
+//	- wait: 1000
+//	- scroll: bottom
+func LoadInteraction(path string) ([]InteractionStep, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read interaction script: %w", err)
+	}
+	var steps []InteractionStep
+	if err := yaml.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("invalid interaction script %s: %w", path, err)
+	}
+	for i, step := range steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("invalid interaction script %s: step %d: %w", path, i+1, err)
+		}
+	}
+	return steps, nil
+}
+
+// validate checks that the step has exactly one action and valid values.
+func (s InteractionStep) validate() error {
+	actions := 0
+	for _, set := range []bool{s.Click != "", s.Type != "", s.Wait != 0, s.Scroll != "", s.Press != ""} {
+		if set {
+			actions++
+		}
+	}
+	switch {
+	case actions != 1:
+		return fmt.Errorf("want exactly one of click, type, wait, scroll, or press")
+	case s.Into != "" && s.Type == "":
+		return fmt.Errorf("into is only valid with type")
+	case s.Wait < 0:
+		return fmt.Errorf("wait must be positive")
+	case s.Scroll != "" && s.Scroll != "bottom" && s.Scroll != "top":
+		return fmt.Errorf("unknown scroll %q (valid: bottom, top)", s.Scroll)
+	}
+	if s.Press != "" {
+		if _, err := pressKey(s.Press); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String describes the step for logs, e.g. "click #menu".
+func (s InteractionStep) String() string {
+	switch {
+	case s.Click != "":
+		return "click " + s.Click
+	case s.Type != "" && s.Into != "":
+		return fmt.Sprintf("type %q into %s", s.Type, s.Into)
+	case s.Type != "":
+		return fmt.Sprintf("type %q", s.Type)
+	case s.Wait != 0:
+		return fmt.Sprintf("wait %dms", s.Wait)
+	case s.Scroll != "":
+		return "scroll to " + s.Scroll
+	}
+	return "press " + s.Press
+}
+
+// action returns the chromedp action that performs the step.
+func (s InteractionStep) action() chromedp.Action {
+	switch {
+	case s.Click != "":
+		return chromedp.Click(s.Click, chromedp.ByQuery)
+	case s.Type != "" && s.Into != "":
+		return chromedp.SendKeys(s.Into, s.Type, chromedp.ByQuery)
+	case s.Type != "":
+		return chromedp.KeyEvent(s.Type)
+	case s.Wait != 0:
+		return chromedp.Sleep(time.Duration(s.Wait) * time.Millisecond)
+	case s.Scroll == "top":
+		return chromedp.Evaluate(`window.scrollTo(0, 0)`, nil)
+	case s.Scroll != "":
+		return chromedp.Evaluate(`window.scrollTo(0, document.documentElement.scrollHeight)`, nil)
+	}
+	key, _ := pressKey(s.Press)
+	return chromedp.KeyEvent(key)
+}
+
+// pressKey returns the key code of a Press value.
+func pressKey(name string) (string, error) {
+	if key, ok := keyNames[strings.ToLower(name)]; ok {
+		return key, nil
+	}
+	if len([]rune(name)) == 1 {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown key %q", name)
+}
+
+// runInteraction runs the steps in order. A step that fails or times out
+// is recorded and the rest still run; the discovery context running out
+// ends the script.
+func runInteraction(ctx context.Context, steps []InteractionStep) []InteractionResult {
+	results := make([]InteractionResult, 0, len(steps))
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			break
+		}
+		timeout := interactionStepTimeout
+		if d := time.Duration(step.Wait) * time.Millisecond; d >= timeout {
+			timeout = d + time.Second
+		}
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := step.action().Do(stepCtx)
+		cancel()
+
+		result := InteractionResult{Step: step.String()}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// autoScrollScript scrolls through the page a screen at a time, pausing
+// so intersection observers fire and lazy chunks start loading, then
+// returns to the top. It gives up after 50 screens on endless feeds.
+const autoScrollScript = `(async () => {
+	for (let i = 0; i < 50; i++) {
+		const before = window.scrollY;
+		window.scrollBy(0, window.innerHeight);
+		await new Promise(r => setTimeout(r, 250));
+		if (window.scrollY === before) break;
+	}
+	window.scrollTo(0, 0);
+})()`
+
+// autoScroll runs autoScrollScript.
+func autoScroll() chromedp.Action {
+	return chromedp.Evaluate(autoScrollScript, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	})
+}
+
+// autoScrollTimeout bounds autoScrollScript.
+const autoScrollTimeout = 20 * time.Second
+
+// interact runs AutoScroll and the Interaction steps, calling begin first
+// so scripts requested from then on count as triggered by interaction.
+// Failed steps are recorded in result; the action itself never fails.
+func (b *BrowserClient) interact(result *DiscoveredResources, begin func()) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !b.AutoScroll && len(b.Interaction) == 0 {
+			return nil
+		}
+		begin()
+
+		if b.AutoScroll {
+			scrolled := InteractionResult{Step: "auto-scroll"}
+			scrollCtx, cancel := context.WithTimeout(ctx, autoScrollTimeout)
+			if err := autoScroll().Do(scrollCtx); err != nil {
+				scrolled.Error = err.Error()
+			}
+			cancel()
+			result.Interactions = append(result.Interactions, scrolled)
+		}
+		result.Interactions = append(result.Interactions, runInteraction(ctx, b.Interaction)...)
+		return nil
+	})
+}
@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrModuleDumpBlocked is reported when the page's Content Security Policy
+// prevents the module dump script from running.
+var ErrModuleDumpBlocked = errors.New("module dump blocked by the page's Content Security Policy")
+
+// dumpModulesScript collects webpack module factories from the running page
+// and returns a JSON object of module id to function source.
+//
+// Modules come from two places: every chunk pushed onto a webpackChunk* (5)
+// or webpackJsonp (4) global, and the runtime's own module table, reached
+// by pushing a chunk whose runtime callback receives __webpack_require__.
+// The latter includes modules bundled into the entry chunk.
+const dumpModulesScript = `(() => {
+	const out = {};
+	const add = (modules) => {
+		if (!modules) return;
+		for (const id of Object.keys(modules)) {
+			const fn = modules[id];
+			if (typeof fn === "function" && !(id in out)) {
+				out[id] = Function.prototype.toString.call(fn);
+			}
+		}
+	};
+	for (const key of Object.getOwnPropertyNames(self)) {
+		if (!/^webpackChunk|^webpackJsonp/.test(key)) continue;
+		const registry = self[key];
+		if (!Array.isArray(registry)) continue;
+		for (const chunk of registry) {
+			if (Array.isArray(chunk)) add(chunk[1]);
+		}
+		try {
+			const id = "__dejank_" + Math.random().toString(36).slice(2);
+			if (key.startsWith("webpackChunk")) {
+				registry.push([[id], {}, (req) => { add(req && req.m); }]);
+			} else {
+				registry.push([[id], { [id]: (m, e, req) => { add(req && req.m); } }, [[id]]]);
+			}
+		} catch (e) {}
+	}
+	if (typeof __webpack_modules__ !== "undefined") add(__webpack_modules__);
+	return JSON.stringify(out);
+})()`
+
+// dumpModules evaluates dumpModulesScript in the page. A page without a
+// webpack registry yields an empty map.
+func dumpModules(out *map[string]string, dumpErr *error) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var raw string
+		if err := chromedp.Evaluate(dumpModulesScript, &raw).Do(ctx); err != nil {
+			if isCSPError(err) {
+				*dumpErr = ErrModuleDumpBlocked
+			} else {
+				*dumpErr = fmt.Errorf("module dump failed: %w", err)
+			}
+			return nil
+		}
+		modules := make(map[string]string)
+		if err := json.Unmarshal([]byte(raw), &modules); err != nil {
+			*dumpErr = fmt.Errorf("module dump returned invalid JSON: %w", err)
+			return nil
+		}
+		*out = modules
+		return nil
+	})
+}
+
+// isCSPError reports whether an evaluation failed because of the page's
+// Content Security Policy.
+func isCSPError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Content Security Policy") || strings.Contains(msg, "EvalError") ||
+		strings.Contains(msg, "unsafe-eval")
+}
@@ -0,0 +1,64 @@
+package fetch
+
+import (
+	"context"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// prerenderTarget returns the ID of the page a target event announces if
+// Chrome is prerendering it for a speculation rule. A prerendered page is
+// a target of its own, so the page's listener never sees its requests.
+func prerenderTarget(ev interface{}) (target.ID, bool) {
+	var info *target.Info
+	switch e := ev.(type) {
+	case *target.EventTargetCreated:
+		info = e.TargetInfo
+	case *target.EventTargetInfoChanged:
+		info = e.TargetInfo
+	default:
+		return "", false
+	}
+	if info == nil || info.Type != "page" || info.Subtype != "prerender" {
+		return "", false
+	}
+	return info.TargetID, true
+}
+
+// listenPrerender attaches to the prerendered page id in a session of its
+// own and passes its events to fn. Attaching enables the network domain,
+// so the scripts the page loads before it is shown are reported. The
+// returned function detaches; the prerender is gone with the browser
+// anyway.
+func listenPrerender(browserCtx context.Context, id target.ID, fn func(ev interface{})) context.CancelFunc {
+	ctx, cancel := chromedp.NewContext(browserCtx, chromedp.WithTargetID(id))
+	chromedp.ListenTarget(ctx, fn)
+	// A prerender that is discarded or activated before the session is
+	// up is missed
+	go func() { _ = chromedp.Run(ctx) }()
+	return cancel
+}
+
+// isPrefetch reports whether a request is a speculative prefetch, by a
+// speculation rule or <link rel=prefetch>, rather than a load the page
+// uses. The requests of a prerendered page are marked as a prerender's and
+// aren't prefetches: the page runs what it loads.
+func isPrefetch(e *network.EventRequestWillBeSent) bool {
+	if e.Type == network.ResourceTypePrefetch {
+		return true
+	}
+	if e.Request == nil {
+		return false
+	}
+	for name, v := range e.Request.Headers {
+		if !strings.EqualFold(name, "Sec-Purpose") && !strings.EqualFold(name, "Purpose") {
+			continue
+		}
+		purpose, _ := v.(string)
+		return strings.HasPrefix(purpose, "prefetch") && !strings.Contains(purpose, "prerender")
+	}
+	return false
+}
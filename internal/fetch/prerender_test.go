@@ -0,0 +1,61 @@
+package fetch
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+)
+
+func TestPrerenderTarget(t *testing.T) {
+	prerender := &target.Info{TargetID: "p1", Type: "page", Subtype: "prerender"}
+	tests := []struct {
+		name   string
+		ev     interface{}
+		wantID target.ID
+		wantOK bool
+	}{
+		{"created", &target.EventTargetCreated{TargetInfo: prerender}, "p1", true},
+		{"info changed", &target.EventTargetInfoChanged{TargetInfo: prerender}, "p1", true},
+		{"plain page", &target.EventTargetCreated{TargetInfo: &target.Info{TargetID: "p2", Type: "page"}}, "", false},
+		{"worker", &target.EventTargetCreated{TargetInfo: &target.Info{TargetID: "w1", Type: "service_worker", Subtype: "prerender"}}, "", false},
+		{"no info", &target.EventTargetCreated{}, "", false},
+		{"destroyed", &target.EventTargetDestroyed{TargetID: "p1"}, "", false},
+		{"other event", &network.EventRequestWillBeSent{}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := prerenderTarget(tt.ev)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("prerenderTarget() = %q, %v, want %q, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsPrefetch(t *testing.T) {
+	request := func(headers network.Headers) *network.EventRequestWillBeSent {
+		return &network.EventRequestWillBeSent{Type: network.ResourceTypeScript, Request: &network.Request{Headers: headers}}
+	}
+	tests := []struct {
+		name string
+		e    *network.EventRequestWillBeSent
+		want bool
+	}{
+		{"prefetch type", &network.EventRequestWillBeSent{Type: network.ResourceTypePrefetch}, true},
+		{"no request", &network.EventRequestWillBeSent{Type: network.ResourceTypeScript}, false},
+		{"no purpose", request(network.Headers{"Accept": "*/*"}), false},
+		{"sec-purpose prefetch", request(network.Headers{"Sec-Purpose": "prefetch"}), true},
+		{"purpose prefetch", request(network.Headers{"Purpose": "prefetch"}), true},
+		{"lowercase header", request(network.Headers{"sec-purpose": "prefetch;anonymous-client-ip"}), true},
+		{"prerender", request(network.Headers{"Sec-Purpose": "prefetch;prerender"}), false},
+		{"not a string", request(network.Headers{"Sec-Purpose": 1}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPrefetch(tt.e); got != tt.want {
+				t.Errorf("isPrefetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
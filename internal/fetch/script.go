@@ -0,0 +1,82 @@
+package fetch
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// scriptExtensions are the extensions of files collected as scripts: plain,
+// ES module, and CommonJS bundles, and JSX or TypeScript served as-is or
+// transpiled on the fly, as by dev-style Vite deployments.
+var scriptExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".cjs": true, ".jsx": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+}
+
+// IsScriptPath reports whether p, a file name or URL path, has a script
+// extension. Local processing uses it to pick scripts out of a download
+// directory, so it agrees with what discovery collects.
+func IsScriptPath(p string) bool {
+	return scriptExtensions[strings.ToLower(path.Ext(p))]
+}
+
+// IsJavaScriptContentType reports whether a Content-Type header value is
+// one of the JavaScript MIME types, e.g. "text/javascript; charset=utf-8".
+func IsJavaScriptContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/javascript", "text/javascript", "application/x-javascript",
+		"application/ecmascript", "text/ecmascript":
+		return true
+	}
+	return false
+}
+
+// isJavaScriptURL reports whether u points to a script by its extension
+// or, for webpack-style chunks, its path.
+//
+// TypeScript's .ts is also the extension of MPEG transport stream video,
+// such as HLS segments, so a .ts URL only counts when the page loads it as
+// a script; see isScriptRequest.
+func isJavaScriptURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	p := strings.ToLower(parsed.Path)
+	if IsScriptPath(p) && path.Ext(p) != ".ts" {
+		return true
+	}
+
+	// Check for webpack chunks and similar patterns
+	return strings.Contains(p, "/chunk") && strings.Contains(u, ".js")
+}
+
+// isScriptRequest reports whether a request the page sent is for a script:
+// isJavaScriptURL, or a .ts URL loaded by a script element or import.
+func isScriptRequest(u string, asScript bool) bool {
+	if isJavaScriptURL(u) {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	return err == nil && asScript && IsScriptPath(parsed.Path)
+}
+
+// isScriptResponse reports whether a response the page received is a
+// script that isScriptRequest could not recognize: one served from a path
+// without an extension with a JavaScript content type.
+func isScriptResponse(u, mimeType string) bool {
+	return isExtensionless(u) && IsJavaScriptContentType(mimeType)
+}
+
+// isExtensionless reports whether the last segment of u's path has no
+// extension, so only the response's content type can say it is a script.
+func isExtensionless(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && path.Ext(parsed.Path) == ""
+}
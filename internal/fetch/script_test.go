@@ -0,0 +1,83 @@
+package fetch
+
+import "testing"
+
+func TestIsScriptPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"app.js", true},
+		{"/static/js/main.8f3a.MJS", true},
+		{"lib.cjs", true},
+		{"App.jsx", true},
+		{"main.ts", true},
+		{"App.tsx", true},
+		{"mod.mts", true},
+		{"mod.cts", true},
+		{"app.js.map", false},
+		{"style.css", false},
+		{"app.json", false},
+		{"api/users", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsScriptPath(tt.path); got != tt.want {
+			t.Errorf("IsScriptPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsScriptRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		asScript bool
+		want     bool
+	}{
+		{"script", "https://example.com/app.js", false, true},
+		{"script with query", "https://example.com/app.mjs?v=3", false, true},
+		{"webpack chunk", "https://example.com/chunk/42?file=x.js", false, true},
+		{"vite typescript", "https://example.com/src/main.ts", true, true},
+		{"vite tsx", "https://example.com/src/App.tsx", false, true},
+		{"hls segment", "https://cdn.example.com/video/segment_001.ts", false, false},
+		{"hls segment with query", "https://cdn.example.com/live/seg42.ts?token=abc", false, false},
+		{"sourcemap", "https://example.com/app.js.map", true, false},
+		{"stylesheet", "https://example.com/site.css", false, false},
+		{"extensionless", "https://example.com/api/bundle", true, false},
+		{"invalid", "https://example.com/%zz.js", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isScriptRequest(tt.url, tt.asScript); got != tt.want {
+				t.Errorf("isScriptRequest(%q, %v) = %v, want %v", tt.url, tt.asScript, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsScriptResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		mimeType string
+		want     bool
+	}{
+		{"javascript", "https://example.com/assets/bundle", "application/javascript", true},
+		{"text javascript with charset", "https://example.com/loader?v=2", "text/javascript; charset=utf-8", true},
+		{"legacy type", "https://example.com/x", "application/x-javascript", true},
+		{"ecmascript", "https://example.com/es", "text/ecmascript", true},
+		{"json", "https://example.com/api/config", "application/json", false},
+		{"html", "https://example.com/about", "text/html", false},
+		{"video", "https://cdn.example.com/video/segment", "video/mp2t", false},
+		{"has an extension", "https://example.com/app.js", "application/javascript", false},
+		{"bad content type", "https://example.com/x", "javascript;;", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isScriptResponse(tt.url, tt.mimeType); got != tt.want {
+				t.Errorf("isScriptResponse(%q, %q) = %v, want %v", tt.url, tt.mimeType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package fetch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UserAgentPresets are the browser User-Agents selectable by name with -ua.
+// Some sites serve differently built bundles to older or mobile browsers,
+// and some block the HeadlessChrome default outright.
+var UserAgentPresets = map[string]string{
+	"chrome-win":  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"chrome-mac":  "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"firefox-win": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:133.0) Gecko/20100101 Firefox/133.0",
+	"safari-mac":  "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1 Safari/605.1.15",
+	"iphone":      "Mozilla/5.0 (iPhone; CPU iPhone OS 18_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1 Mobile/15E148 Safari/604.1",
+	"android":     "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Mobile Safari/537.36",
+	"ie11":        "Mozilla/5.0 (Windows NT 10.0; Trident/7.0; rv:11.0) like Gecko",
+}
+
+// UserAgentPresetNames returns the preset names, sorted.
+func UserAgentPresetNames() []string {
+	names := make([]string, 0, len(UserAgentPresets))
+	for name := range UserAgentPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UserAgentPreset returns the User-Agent of a preset name.
+func UserAgentPreset(name string) (string, error) {
+	if ua, ok := UserAgentPresets[strings.ToLower(name)]; ok {
+		return ua, nil
+	}
+	return "", fmt.Errorf("unknown -ua preset %q (want %s)", name, strings.Join(UserAgentPresetNames(), ", "))
+}
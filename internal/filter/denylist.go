@@ -0,0 +1,83 @@
+package filter
+
+import (
+	_ "embed"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+//go:embed denylist.txt
+var denylistData string
+
+// Denylist matches script URLs of well-known third-party bundles by host
+// and path. A nil Denylist matches nothing.
+type Denylist struct {
+	patterns []denyPattern
+}
+
+type denyPattern struct {
+	host string   // Exact host, or "*.example.com" for it and its subdomains
+	path []string // Glob segments matched against the URL path, nil for any
+}
+
+// DefaultDenylist returns the built-in patterns from denylist.txt.
+func DefaultDenylist() []string {
+	var patterns []string
+	for _, line := range strings.Split(denylistData, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// NewDenylist compiles host[/path] patterns, as described in denylist.txt.
+func NewDenylist(patterns []string) (*Denylist, error) {
+	d := &Denylist{}
+	for _, p := range patterns {
+		host, glob, _ := strings.Cut(strings.TrimPrefix(p, "//"), "/")
+		host = strings.ToLower(host)
+		if host == "" || strings.Contains(p, "://") {
+			return nil, fmt.Errorf("invalid denylist pattern %q: expected host[/path]", p)
+		}
+
+		pattern := denyPattern{host: host}
+		if glob != "" {
+			pattern.path = strings.Split(glob, "/")
+			for _, segment := range pattern.path {
+				if _, err := path.Match(segment, ""); err != nil {
+					return nil, fmt.Errorf("invalid denylist pattern %q: %w", p, err)
+				}
+			}
+		}
+		d.patterns = append(d.patterns, pattern)
+	}
+	return d, nil
+}
+
+// Match reports whether a script URL is on the denylist.
+func (d *Denylist) Match(u string) bool {
+	if d == nil {
+		return false
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	parts := strings.Split(strings.TrimPrefix(parsed.Path, "/"), "/")
+
+	for _, p := range d.patterns {
+		if !matchHost(p.host, host) {
+			continue
+		}
+		if p.path == nil || matchSegments(p.path, parts) {
+			return true
+		}
+	}
+	return false
+}
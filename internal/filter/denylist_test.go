@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultDenylist(t *testing.T) {
+	patterns := DefaultDenylist()
+	if len(patterns) == 0 {
+		t.Fatal("DefaultDenylist is empty")
+	}
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "#") || strings.TrimSpace(p) != p {
+			t.Errorf("DefaultDenylist kept %q", p)
+		}
+	}
+	if _, err := NewDenylist(patterns); err != nil {
+		t.Fatalf("denylist.txt does not compile: %v", err)
+	}
+}
+
+func TestDenylistMatch(t *testing.T) {
+	d, err := NewDenylist(DefaultDenylist())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		// Third-party hosts
+		{"https://www.googletagmanager.com/gtag/js?id=G-XXXX", true},
+		{"https://www.google-analytics.com/analytics.js", true},
+		{"https://google-analytics.com/analytics.js", true},
+		{"https://connect.facebook.net/en_US/fbevents.js", true},
+		{"https://static.hotjar.com/c/hotjar-123.js?sv=6", true},
+		{"https://www.clarity.ms/tag/abc", true},
+		{"https://widget.intercom.io/widget/abc", true},
+		{"https://CDN.Segment.COM/analytics.js/v1/key/analytics.min.js", true},
+		{"https://www.google.com/recaptcha/api.js", true},
+		{"https://www.gstatic.com/recaptcha/releases/abc/recaptcha__en.js", true},
+		{"https://plausible.io/js/script.js", true},
+
+		// First-party scripts that share a name or a host with them
+		{"https://example.com/js/gtag.js", false},
+		{"https://example.com/vendor/fbevents.js", false},
+		{"https://example.com/static/hotjar.js", false},
+		{"https://cdn.example.com/analytics.js", false},
+		{"https://www.google.com/maps/api/js", false},
+		{"https://www.gstatic.com/firebasejs/10.0.0/firebase-app.js", false},
+		{"https://plausible.io/assets/app.js", false},
+		{"https://facebook.net.example.com/fbevents.js", false},
+		{"https://notgoogletagmanager.com/gtag/js", false},
+		{"https://example.com/proxy/www.googletagmanager.com/gtag/js", false},
+
+		// Not matchable
+		{"/gtag/js", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		if got := d.Match(tt.url); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDenylistAdd(t *testing.T) {
+	d, err := NewDenylist([]string{"cdn.example.com/vendor/**", "*.tracker.test", "EXAMPLE.org/js/ads-*.js", "//static.example.net/a/*/b.js"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://cdn.example.com/vendor/chat/widget.js", true},
+		{"https://cdn.example.com/vendor", true},
+		{"https://cdn.example.com/app/main.js", false},
+		{"https://a.b.tracker.test/t.js", true},
+		{"https://tracker.test/t.js", true},
+		{"https://example.org/js/ads-1.js", true},
+		{"https://example.org/js/sub/ads-1.js", false},
+		{"https://static.example.net/a/x/b.js", true},
+		{"https://static.example.net/a/x/y/b.js", false},
+	}
+	for _, tt := range tests {
+		if got := d.Match(tt.url); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	var none *Denylist
+	if none.Match("https://www.googletagmanager.com/gtag/js") {
+		t.Error("a nil Denylist matched")
+	}
+}
+
+func TestNewDenylistRejects(t *testing.T) {
+	for _, p := range []string{"", "/gtag/js", "https://www.googletagmanager.com/gtag/js", "example.com/[a.js"} {
+		if _, err := NewDenylist([]string{p}); err == nil {
+			t.Errorf("NewDenylist(%q) succeeded", p)
+		}
+	}
+}
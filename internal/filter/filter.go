@@ -0,0 +1,196 @@
+// Package filter decides which script, map, and source paths a run processes.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filter holds include/exclude rules for URLs and restored source paths.
+// A nil Filter allows everything.
+type Filter struct {
+	IncludeURL    []*regexp.Regexp
+	ExcludeURL    []*regexp.Regexp
+	IncludeSource []string // Glob patterns; ** matches any number of directories
+	ExcludeSource []string
+	Only          []string // Globs matched against raw sources[] entries (see MatchSource)
+}
+
+// New compiles URL regexes and validates source globs.
+func New(includeURL, excludeURL, includeSource, excludeSource, only []string) (*Filter, error) {
+	f := &Filter{}
+
+	var err error
+	if f.IncludeURL, err = compileAll(includeURL, "-include-url"); err != nil {
+		return nil, err
+	}
+	if f.ExcludeURL, err = compileAll(excludeURL, "-exclude-url"); err != nil {
+		return nil, err
+	}
+
+	for _, globs := range [][]string{includeSource, excludeSource, only} {
+		for _, g := range globs {
+			if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+				return nil, fmt.Errorf("invalid source glob %q: %w", g, err)
+			}
+		}
+	}
+	f.IncludeSource = includeSource
+	f.ExcludeSource = excludeSource
+	f.Only = only
+
+	return f, nil
+}
+
+func compileAll(patterns []string, flagName string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", flagName, p, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// Empty reports whether the filter has no rules.
+func (f *Filter) Empty() bool {
+	return f == nil || (len(f.IncludeURL) == 0 && len(f.ExcludeURL) == 0 &&
+		len(f.IncludeSource) == 0 && len(f.ExcludeSource) == 0 && len(f.Only) == 0)
+}
+
+// AllowURL reports whether a script or map URL should be processed. A URL
+// must match at least one include pattern (if any are set) and no exclude
+// pattern.
+func (f *Filter) AllowURL(u string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IncludeURL) > 0 && !anyRegexp(f.IncludeURL, u) {
+		return false
+	}
+	return !anyRegexp(f.ExcludeURL, u)
+}
+
+// AllowSource reports whether a restored source path (slash-separated,
+// relative to restored_sources) should be written.
+func (f *Filter) AllowSource(p string) bool {
+	if f == nil {
+		return true
+	}
+	p = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+	if len(f.IncludeSource) > 0 && !anyGlob(f.IncludeSource, p) {
+		return false
+	}
+	return !anyGlob(f.ExcludeSource, p)
+}
+
+func anyRegexp(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyGlob(globs []string, p string) bool {
+	for _, g := range globs {
+		if MatchGlob(g, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob matches a slash-separated path against a glob pattern. Segments
+// use path.Match syntax; a "**" segment matches zero or more directories.
+// Patterns without a slash match the final path element, so "*.ts" matches
+// TypeScript files at any depth.
+func MatchGlob(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+// MatchSource matches a raw sources[] entry such as
+// "webpack://app/./src/api/client.ts" against a glob. The pattern is
+// anchored at the start of the entry's path: after any scheme and its
+// namespace or host ("webpack://app/") and any leading "./", "../" or "/",
+// so "src/api/**" matches the example above but not
+// "webpack://app/./lib/src/api/client.ts". Use "**/src/api/**" to match
+// at any depth.
+func MatchSource(pattern, source string) bool {
+	if !strings.Contains(pattern, "/") {
+		return MatchGlob(pattern, source)
+	}
+	return matchSegments(pathSegments(pattern), pathSegments(sourcePath(source)))
+}
+
+// sourcePath returns the path of a sources[] entry: what follows its
+// scheme and namespace or host, if it has them.
+func sourcePath(source string) string {
+	source = strings.ReplaceAll(source, "\\", "/")
+	if i := strings.Index(source, "://"); i > 0 && isScheme(source[:i]) {
+		source = source[i+len("://"):]
+		if j := strings.Index(source, "/"); j >= 0 {
+			source = source[j+1:]
+		} else {
+			source = ""
+		}
+	}
+	return source
+}
+
+// pathSegments splits a slash-separated path, dropping empty and "."
+// segments, and ".." segments before the first named one.
+func pathSegments(p string) []string {
+	var segments []string
+	for _, part := range strings.Split(p, "/") {
+		if part == "" || part == "." || (part == ".." && len(segments) == 0) {
+			continue
+		}
+		segments = append(segments, part)
+	}
+	return segments
+}
+
+// isScheme reports whether s is a URL scheme, such as "webpack".
+func isScheme(s string) bool {
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
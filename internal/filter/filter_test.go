@@ -0,0 +1,104 @@
+package filter
+
+import "testing"
+
+func TestMatchSource(t *testing.T) {
+	tests := []struct {
+		pattern string
+		source  string
+		want    bool
+	}{
+		// Prefixes before the path
+		{"src/api/**", "webpack://app/./src/api/client.ts", true},
+		{"src/api/**", "webpack:///./src/api/client.ts", true},
+		{"src/api/**", "webpack:///src/api/client.ts", true},
+		{"src/api/**", "../../src/api/client.ts", true},
+		{"src/api/**", "/src/api/client.ts", true},
+		{"src/api/**", "src\\api\\client.ts", true},
+		{"./src/api/**", "webpack://app/./src/api/client.ts", true},
+		// Anchored at the start of the path, not at any segment
+		{"src/api/**", "webpack://app/./lib/src/api/client.ts", false},
+		{"src/api/**", "webpack://src/api/client.ts", false}, // src is the namespace
+		{"api/client.ts", "webpack://app/./src/api/client.ts", false},
+		// ** at the start, middle and end
+		{"**/api/client.ts", "webpack://app/./src/api/client.ts", true},
+		{"**/api/client.ts", "webpack://app/./api/client.ts", true},
+		{"**/api/client.ts", "webpack://app/./src/api/v2/client.ts", false},
+		{"src/**/client.ts", "webpack://app/./src/client.ts", true},
+		{"src/**/client.ts", "webpack://app/./src/api/v2/client.ts", true},
+		{"src/**/client.ts", "webpack://app/./lib/api/client.ts", false},
+		{"src/**", "webpack://app/./src/a/b/c.ts", true},
+		{"src/**", "webpack://app/./srcs/a.ts", false},
+		// * doesn't cross /
+		{"src/*.ts", "webpack://app/./src/index.ts", true},
+		{"src/*.ts", "webpack://app/./src/api/client.ts", false},
+		{"src/*/client.ts", "webpack://app/./src/api/client.ts", true},
+		{"src/*/client.ts", "webpack://app/./src/api/v2/client.ts", false},
+		// Patterns without a slash match the file name
+		{"*.ts", "webpack://app/./src/api/client.ts", true},
+		{"*.ts", "webpack://app/./src/api/client.js", false},
+	}
+	for _, tt := range tests {
+		if got := MatchSource(tt.pattern, tt.source); got != tt.want {
+			t.Errorf("MatchSource(%q, %q) = %v, want %v", tt.pattern, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**/*.test.ts", "src/a/b.test.ts", true},
+		{"**/*.test.ts", "b.test.ts", true},
+		{"src/**/fixtures/*", "src/fixtures/a.json", true},
+		{"src/**/fixtures/*", "src/a/b/fixtures/c.json", true},
+		{"src/**/fixtures/*", "src/a/fixtures/b/c.json", false},
+		{"node_modules/**", "node_modules/react/index.js", true},
+		{"node_modules/**", "src/node_modules/react/index.js", false},
+		{"src/*", "src/a/b.ts", false},
+		{"*.map", "a/b/c.js.map", true},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAllowSource(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+		path             string
+		want             bool
+	}{
+		{"no rules", nil, nil, "src/a.ts", true},
+		{"included", []string{"src/**"}, nil, "src/a.ts", true},
+		{"not included", []string{"src/**"}, nil, "lib/a.ts", false},
+		{"excluded", nil, []string{"**/*.test.ts"}, "src/a.test.ts", false},
+		{"exclude wins over include", []string{"src/**"}, []string{"**/*.test.ts"}, "src/a.test.ts", false},
+		{"include and other exclude", []string{"src/**"}, []string{"**/*.test.ts"}, "src/a.ts", true},
+		{"cleaned path", []string{"src/**"}, nil, "./src/../src/a.ts", true},
+		{"backslashes", nil, []string{"src/gen/**"}, "src\\gen\\a.ts", false},
+	}
+	for _, tt := range tests {
+		f, err := New(nil, nil, tt.include, tt.exclude, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.AllowSource(tt.path); got != tt.want {
+			t.Errorf("%s: AllowSource(%q) = %v, want %v", tt.name, tt.path, got, tt.want)
+		}
+	}
+
+	var f *Filter
+	if !f.AllowSource("anything") || !f.AllowURL("https://a.example/") {
+		t.Error("a nil Filter doesn't allow everything")
+	}
+	if _, err := New(nil, nil, []string{"src/[a"}, nil, nil); err == nil {
+		t.Error("an invalid glob was accepted")
+	}
+}
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Scope limits which hosts a url run downloads scripts and maps from,
+// relative to the target page.
+type Scope string
+
+// Scopes.
+const (
+	ScopeSameOrigin Scope = "same-origin" // Same scheme, host, and port as the target
+	ScopeSameSite   Scope = "same-site"   // Same registrable domain (eTLD+1) as the target
+	ScopeAll        Scope = "all"         // Any host
+)
+
+// ParseScope validates a scope name.
+func ParseScope(name string) (Scope, error) {
+	switch s := Scope(name); s {
+	case ScopeSameOrigin, ScopeSameSite, ScopeAll:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown scope %q (valid: same-origin, same-site, all)", name)
+}
+
+// InScope reports whether u may be fetched for a run against target. Hosts
+// in allowHosts are always in scope; an entry of the form "*.example.com"
+// matches every subdomain of example.com. An empty scope allows everything.
+func InScope(scope Scope, target *url.URL, u string, allowHosts []string) bool {
+	if scope == "" || scope == ScopeAll {
+		return true
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range allowHosts {
+		if matchHost(strings.ToLower(allowed), host) {
+			return true
+		}
+	}
+
+	switch scope {
+	case ScopeSameOrigin:
+		return sameOrigin(target, parsed)
+	case ScopeSameSite:
+		return SameSite(strings.ToLower(target.Hostname()), host)
+	}
+	return false
+}
+
+// matchHost matches a hostname against an -allow-host entry.
+func matchHost(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// sameOrigin compares scheme, host, and port, treating an omitted port as
+// the scheme's default.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		effectivePort(a) == effectivePort(b)
+}
+
+func effectivePort(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// SameSite compares the registrable domains (eTLD+1) of two lower-case
+// hostnames using the public suffix list. IP addresses and hosts without a
+// registrable domain (e.g. localhost) must match exactly.
+func SameSite(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if net.ParseIP(a) != nil || net.ParseIP(b) != nil {
+		return false
+	}
+	siteA, errA := publicsuffix.EffectiveTLDPlusOne(a)
+	siteB, errB := publicsuffix.EffectiveTLDPlusOne(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return siteA == siteB
+}
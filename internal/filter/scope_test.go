@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestInScope(t *testing.T) {
+	target, _ := url.Parse("https://www.example.com/app")
+	tests := []struct {
+		name  string
+		scope Scope
+		u     string
+		allow []string
+		want  bool
+	}{
+		{"all", ScopeAll, "https://cdn.other.net/a.js.map", nil, true},
+		{"empty scope", "", "https://cdn.other.net/a.js.map", nil, true},
+		{"same origin", ScopeSameOrigin, "https://www.example.com/a.js.map", nil, true},
+		{"same origin default port", ScopeSameOrigin, "https://www.example.com:443/a.js.map", nil, true},
+		{"same origin other port", ScopeSameOrigin, "https://www.example.com:8443/a.js.map", nil, false},
+		{"same origin other scheme", ScopeSameOrigin, "http://www.example.com/a.js.map", nil, false},
+		{"same origin host case", ScopeSameOrigin, "https://WWW.Example.com/a.js.map", nil, true},
+		{"same origin subdomain", ScopeSameOrigin, "https://static.example.com/a.js.map", nil, false},
+		{"same site subdomain", ScopeSameSite, "https://static.example.com/a.js.map", nil, true},
+		{"same site apex", ScopeSameSite, "http://example.com/a.js.map", nil, true},
+		{"same site other site", ScopeSameSite, "https://example.net/a.js.map", nil, false},
+		{"same site public suffix", ScopeSameSite, "https://example.com.evil.io/a.js.map", nil, false},
+		{"allow host", ScopeSameOrigin, "https://cdn.vendor.net/a.js.map", []string{"cdn.vendor.net"}, true},
+		{"allow host wildcard", ScopeSameOrigin, "https://eu.cdn.vendor.net/a.js.map", []string{"*.vendor.net"}, true},
+		{"allow host wildcard apex", ScopeSameOrigin, "https://vendor.net/a.js.map", []string{"*.vendor.net"}, true},
+		{"allow host wildcard suffix only", ScopeSameOrigin, "https://evilvendor.net/a.js.map", []string{"*.vendor.net"}, false},
+		{"relative", ScopeSameOrigin, "/a.js.map", nil, false},
+		{"invalid", ScopeSameOrigin, "https://[::1/a.js.map", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InScope(tt.scope, target, tt.u, tt.allow); got != tt.want {
+				t.Errorf("InScope(%s, %q) = %v, want %v", tt.scope, tt.u, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameSite(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"example.com", "www.example.com", true},
+		{"a.example.co.uk", "b.example.co.uk", true},
+		{"example.co.uk", "other.co.uk", false},
+		{"localhost", "localhost", true},
+		{"127.0.0.1", "127.0.0.2", false},
+		{"user.github.io", "other.github.io", false},
+	}
+	for _, tt := range tests {
+		if got := SameSite(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameSite(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	for _, name := range []string{"same-origin", "same-site", "all"} {
+		if _, err := ParseScope(name); err != nil {
+			t.Errorf("ParseScope(%q): %v", name, err)
+		}
+	}
+	if _, err := ParseScope("origin"); err == nil {
+		t.Error("ParseScope accepted an unknown scope")
+	}
+}
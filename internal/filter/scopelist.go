@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ScopeList is a host allowlist read from a scope file, such as the
+// in-scope domains of an engagement. A nil ScopeList allows every host.
+type ScopeList struct {
+	hosts     map[string]bool // Exact hostnames and IP addresses
+	wildcards map[string]bool // Domains that match along with all their subdomains
+}
+
+// LoadScopeList reads the scope file at path; see ParseScopeList.
+func LoadScopeList(path string) (*ScopeList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read scope file: %w", err)
+	}
+	defer f.Close()
+
+	list, err := ParseScopeList(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// ParseScopeList parses a scope file: one pattern per line, with blank
+// lines and lines starting with # ignored. A pattern is a hostname or IP
+// address, a wildcard such as "*.example.com" that matches example.com and
+// every subdomain of it, or a URL, whose host is used. Ports are ignored.
+// Wildcards over a public suffix, such as "*.co.uk" or "*.github.io", would
+// match unrelated sites and are rejected.
+func ParseScopeList(r io.Reader) (*ScopeList, error) {
+	list := &ScopeList{hosts: make(map[string]bool), wildcards: make(map[string]bool)}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := list.add(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if list.Len() == 0 {
+		return nil, fmt.Errorf("no host patterns")
+	}
+	return list, nil
+}
+
+// add adds one pattern to the list.
+func (l *ScopeList) add(pattern string) error {
+	host := pattern
+	if strings.Contains(pattern, "://") {
+		u, err := url.Parse(pattern)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid URL %q", pattern)
+		}
+		host = u.Host
+	}
+	host = stripPort(host)
+
+	suffix, wildcard := strings.CutPrefix(host, "*.")
+	if strings.Contains(suffix, "*") {
+		return fmt.Errorf("invalid pattern %q: only a leading \"*.\" wildcard is supported", pattern)
+	}
+	name := normalizeHost(suffix)
+	if name == "" {
+		return fmt.Errorf("invalid pattern %q", pattern)
+	}
+	if !wildcard {
+		l.hosts[name] = true
+		return nil
+	}
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("invalid pattern %q: IP addresses have no subdomains", pattern)
+	}
+	// The public suffix list has both ICANN suffixes such as co.uk and
+	// private ones such as github.io. A single label outside the list
+	// (e.g. *.localhost or *.corp) is taken as a private network name.
+	if ps, icann := publicsuffix.PublicSuffix(name); ps == name && (icann || strings.Contains(name, ".")) {
+		return fmt.Errorf("pattern %q covers the public suffix %s", pattern, name)
+	}
+	l.wildcards[name] = true
+	return nil
+}
+
+// Len returns the number of patterns in the list.
+func (l *ScopeList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.hosts) + len(l.wildcards)
+}
+
+// Allows reports whether host matches a pattern of the list. A port on
+// host is ignored.
+func (l *ScopeList) Allows(host string) bool {
+	if l == nil {
+		return true
+	}
+	host = normalizeHost(stripPort(host))
+	if host == "" {
+		return false
+	}
+	if l.hosts[host] {
+		return true
+	}
+	for name := host; ; {
+		if l.wildcards[name] {
+			return true
+		}
+		_, parent, ok := strings.Cut(name, ".")
+		if !ok {
+			return false
+		}
+		name = parent
+	}
+}
+
+// AllowURL reports whether the host of u is allowed. URLs without a host,
+// such as data: URLs, fetch nothing and are allowed.
+func (l *ScopeList) AllowURL(u string) bool {
+	if l == nil {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	if parsed.Host == "" {
+		return parsed.Scheme != "http" && parsed.Scheme != "https"
+	}
+	return l.Allows(parsed.Host)
+}
+
+// stripPort removes a port from host, and the brackets of an IPv6 address.
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// normalizeHost lowercases host, drops a trailing dot, and converts an
+// internationalized name to its ASCII form so that both spellings match.
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseScopeListRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"icann suffix", "*.com"},
+		{"two-label icann suffix", "*.co.uk"},
+		{"private suffix", "*.github.io"},
+		{"private suffix with port", "*.herokuapp.com:443"},
+		{"inner wildcard", "api.*.example.com"},
+		{"bare wildcard", "*"},
+		{"ip wildcard", "*.10.0.0.1"},
+		{"bad url", "https://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseScopeList(strings.NewReader(tt.pattern + "\n")); err == nil {
+				t.Errorf("ParseScopeList(%q) succeeded", tt.pattern)
+			}
+		})
+	}
+
+	if _, err := ParseScopeList(strings.NewReader("# only comments\n\n")); err == nil {
+		t.Error("a list with no patterns was accepted")
+	}
+}
+
+func TestScopeListAllows(t *testing.T) {
+	list, err := ParseScopeList(strings.NewReader(`
+# Engagement scope
+*.example.co.uk
+*.client.github.io
+app.example.com
+https://cdn.vendor.net:8443/path
+*.localhost
+*.bücher.de
+10.0.0.5
+[::1]:8080
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		// Wildcards under a two-label ICANN suffix match the domain and below
+		{"example.co.uk", true},
+		{"www.example.co.uk", true},
+		{"a.b.example.co.uk", true},
+		{"other.co.uk", false},
+		{"co.uk", false},
+		{"badexample.co.uk", false},
+		// and under a private suffix, without the rest of github.io
+		{"client.github.io", true},
+		{"docs.client.github.io", true},
+		{"other.github.io", false},
+		{"github.io", false},
+		// Exact hosts match only themselves, with any port
+		{"app.example.com", true},
+		{"APP.Example.COM.", true},
+		{"app.example.com:8080", true},
+		{"www.app.example.com", false},
+		{"example.com", false},
+		{"cdn.vendor.net", true},
+		{"eu.cdn.vendor.net", false},
+		// A single label outside the suffix list is a private network name
+		{"localhost", true},
+		{"api.localhost", true},
+		// Internationalized names match in either spelling
+		{"shop.bücher.de", true},
+		{"shop.xn--bcher-kva.de", true},
+		{"10.0.0.5", true},
+		{"10.0.0.6", false},
+		{"[::1]:8080", true},
+		{"::1", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := list.Allows(tt.host); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestScopeListAllowURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scope.txt")
+	if err := os.WriteFile(path, []byte("*.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadScopeList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://static.example.com/app.js", true},
+		{"https://example.com.evil.io/app.js", false},
+		{"data:application/json;base64,e30=", true},
+		{"blob:https://example.com/1234", true},
+		{"https:///nohost", false},
+		{"https://[::1/app.js", false},
+	}
+	for _, tt := range tests {
+		if got := list.AllowURL(tt.url); got != tt.want {
+			t.Errorf("AllowURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	var none *ScopeList
+	if !none.Allows("anything.test") || !none.AllowURL("https://anything.test/") {
+		t.Error("a nil list does not allow everything")
+	}
+}
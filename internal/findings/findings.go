@@ -0,0 +1,250 @@
+// Package findings scans dejank output for environment variables, hardcoded
+// secrets, leaked build paths, and third-party service configurations.
+package findings
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thesavant42/dejank/internal/envars"
+)
+
+// Rule IDs for each finding type.
+const (
+	RuleEnvVar        = "DJ001"
+	RuleSecret        = "DJ002"
+	RulePathLeak      = "DJ003"
+	RuleServiceConfig = "DJ004"
+)
+
+// maxScanSize caps the size of files scanned for findings.
+const maxScanSize = 20 << 20
+
+// Rule describes a class of finding.
+type Rule struct {
+	ID          string
+	Name        string
+	Description string
+	Level       string // SARIF level: note, warning, error
+}
+
+// Rules lists every rule that can produce findings.
+var Rules = []Rule{
+	{RuleEnvVar, "EnvironmentVariable", "Build-time environment variable inlined into the bundle", "note"},
+	{RuleSecret, "HardcodedSecret", "Credential or token embedded in client-side code", "error"},
+	{RulePathLeak, "PathLeak", "Absolute filesystem path from the build machine", "warning"},
+	{RuleServiceConfig, "ServiceConfiguration", "Third-party service configuration such as a Firebase config or Sentry DSN", "warning"},
+}
+
+// Finding is a single occurrence of a rule match.
+type Finding struct {
+	RuleID  string
+	Kind    string // Sub-type, e.g. the secret pattern or env var name
+	Message string
+	File    string // Path of the file containing the match
+	Line    int    // 1-based line, 0 if unknown
+	Column  int    // 1-based column, 0 if unknown
+	Match   string // Matched text, used for fingerprinting
+
+	Properties map[string]string // Structured details, e.g. the service and project
+}
+
+type secretPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+var (
+	secretPatterns = []secretPattern{
+		{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+		{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36,}\b`)},
+		{"Slack token", regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z\-]{10,}\b`)},
+		{"Slack webhook", regexp.MustCompile(`https://hooks\.slack\.com/services/[A-Za-z0-9/_\-]+`)},
+		{"Stripe secret key", regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{16,}\b`)},
+		{"Private key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)},
+		{"JSON Web Token", regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\b`)},
+	}
+
+	// Matches absolute home directory paths from macOS, Linux, and Windows build machines
+	pathLeakRe = regexp.MustCompile(`(?:/Users/|/home/)[A-Za-z0-9._\-]+/[^\s"'` + "`" + `<>)]*|[A-Za-z]:\\\\?Users\\\\?[A-Za-z0-9._\-]+\\[^\s"'` + "`" + `<>)]*`)
+)
+
+// ScanDirectory walks dir and returns findings from every text file.
+// Sourcemap files are skipped since their contents are restored separately.
+func ScanDirectory(dir string) ([]Finding, []error) {
+	var results []Finding
+	var errs []error
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("walk error at %s: %w", path, err))
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".map") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > maxScanSize {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", path, err))
+			return nil
+		}
+		if bytes.IndexByte(content, 0) != -1 || !utf8.Valid(content) {
+			return nil
+		}
+
+		// dejank writes its own .env of inlined variables at the top of
+		// restored_sources
+		if isEnvFile(d.Name()) && path != filepath.Join(dir, ".env") {
+			results = append(results, envFileFinding(string(content), path))
+		}
+		results = append(results, ScanContent(string(content), path)...)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to walk directory: %w", err))
+	}
+
+	return results, errs
+}
+
+// ScanContent returns findings in a single file's content.
+func ScanContent(content, file string) []Finding {
+	var results []Finding
+
+	vars := envars.ExtractEnvVars(content)
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		offset := strings.Index(content, key)
+		line, col := position(content, offset)
+		results = append(results, Finding{
+			RuleID:  RuleEnvVar,
+			Kind:    key,
+			Message: fmt.Sprintf("Environment variable %s is inlined with value %q", key, vars[key]),
+			File:    file,
+			Line:    line,
+			Column:  col,
+			Match:   key + "=" + vars[key],
+		})
+	}
+
+	for _, p := range secretPatterns {
+		for _, loc := range p.re.FindAllStringIndex(content, -1) {
+			match := content[loc[0]:loc[1]]
+			line, col := position(content, loc[0])
+			results = append(results, Finding{
+				RuleID:  RuleSecret,
+				Kind:    p.name,
+				Message: fmt.Sprintf("Possible %s: %s", p.name, Redact(match)),
+				File:    file,
+				Line:    line,
+				Column:  col,
+				Match:   match,
+			})
+		}
+	}
+
+	results = append(results, scanServices(content, file)...)
+
+	seen := make(map[string]bool)
+	for _, loc := range pathLeakRe.FindAllStringIndex(content, -1) {
+		match := content[loc[0]:loc[1]]
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		line, col := position(content, loc[0])
+		results = append(results, Finding{
+			RuleID:  RulePathLeak,
+			Kind:    "build path",
+			Message: fmt.Sprintf("Build machine path leaked: %s", match),
+			File:    file,
+			Line:    line,
+			Column:  col,
+			Match:   match,
+		})
+	}
+
+	return results
+}
+
+// isEnvFile reports whether name is a dotenv file such as .env or
+// .env.local. Templates such as .env.example hold no real values and are
+// left out.
+func isEnvFile(name string) bool {
+	if name != ".env" && !strings.HasPrefix(name, ".env.") {
+		return false
+	}
+	switch strings.TrimPrefix(name, ".env.") {
+	case "example", "sample", "template", "dist", "defaults":
+		return false
+	}
+	return true
+}
+
+// envFileFinding reports a dotenv file that shipped with the site or its
+// sourcemaps. Its values usually aren't inlined anywhere else, so the file
+// is a secret finding of its own, listing the variable names it sets.
+func envFileFinding(content, file string) Finding {
+	var names []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+		if key, _, ok := strings.Cut(line, "="); ok && key != "" && !strings.HasPrefix(key, "#") {
+			names = append(names, strings.TrimSpace(key))
+		}
+	}
+	name := filepath.Base(file)
+	f := Finding{
+		RuleID:  RuleSecret,
+		Kind:    "Environment file",
+		Message: fmt.Sprintf("Environment file %s with %d variable(s) shipped to the client", name, len(names)),
+		File:    file,
+		Match:   name,
+	}
+	if len(names) > 0 {
+		f.Properties = map[string]string{"variables": strings.Join(names, ",")}
+	}
+	return f
+}
+
+// position converts a byte offset into a 1-based line and column.
+// Returns 0, 0 for a negative offset.
+func position(content string, offset int) (int, int) {
+	if offset < 0 {
+		return 0, 0
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := offset - strings.LastIndex(before, "\n")
+	return line, col
+}
+
+// Redact hides the middle of a secret so it isn't echoed in full.
+func Redact(s string) string {
+	if len(s) <= 12 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:6] + strings.Repeat("*", len(s)-10) + s[len(s)-4:]
+}
@@ -0,0 +1,196 @@
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// sarifBaseID names the output root that artifact URIs are relative to.
+	sarifBaseID = "OUTPUTROOT"
+
+	// fingerprintKey versions the partial fingerprint algorithm.
+	fingerprintKey = "dejankContentHash/v1"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	ShortDescription     sarifMessage   `json:"shortDescription"`
+	DefaultConfiguration sarifRuleLevel `json:"defaultConfiguration"`
+}
+
+type sarifRuleLevel struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log. File paths are recorded
+// relative to baseDir so the log stays valid when the output is moved.
+func WriteSARIF(path string, results []Finding, baseDir, toolVersion string) error {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return fmt.Errorf("invalid base directory: %w", err)
+	}
+
+	ruleIndex := make(map[string]int, len(Rules))
+	rules := make([]sarifRule, 0, len(Rules))
+	for i, r := range Rules {
+		ruleIndex[r.ID] = i
+		rules = append(rules, sarifRule{
+			ID:                   r.ID,
+			Name:                 r.Name,
+			ShortDescription:     sarifMessage{Text: r.Description},
+			DefaultConfiguration: sarifRuleLevel{Level: r.Level},
+		})
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "dejank",
+			Version:        toolVersion,
+			InformationURI: "https://github.com/thesavant42/dejank",
+			Rules:          rules,
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifactLoc{
+			sarifBaseID: {URI: fileURI(absBase)},
+		},
+		Results: make([]sarifResult, 0, len(results)),
+	}
+
+	for _, f := range results {
+		idx, ok := ruleIndex[f.RuleID]
+		if !ok {
+			continue
+		}
+
+		loc := sarifPhysicalLocation{
+			ArtifactLocation: artifactLocation(absBase, f.File),
+		}
+		if f.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:              f.RuleID,
+			RuleIndex:           idx,
+			Level:               Rules[idx].Level,
+			Message:             sarifMessage{Text: f.Message},
+			Locations:           []sarifLocation{{PhysicalLocation: loc}},
+			PartialFingerprints: map[string]string{fingerprintKey: fingerprint(f)},
+			Properties:          f.Properties,
+		})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SARIF: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// artifactLocation returns a location relative to the base directory where
+// possible, falling back to an absolute file URI.
+func artifactLocation(absBase, file string) sarifArtifactLoc {
+	absFile, err := filepath.Abs(file)
+	if err == nil {
+		if rel, err := filepath.Rel(absBase, absFile); err == nil && !strings.HasPrefix(rel, "..") {
+			return sarifArtifactLoc{URI: filepath.ToSlash(rel), URIBaseID: sarifBaseID}
+		}
+	}
+	return sarifArtifactLoc{URI: fileURI(absFile)}
+}
+
+// fileURI converts an absolute path into a file:// URI with a trailing slash
+// for directories.
+func fileURI(absPath string) string {
+	p := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return "file://" + p
+}
+
+// fingerprint hashes the rule and matched content so the same finding dedupes
+// across runs regardless of where the output directory lives.
+func fingerprint(f Finding) string {
+	sum := sha256.Sum256([]byte(f.RuleID + "\x00" + f.Kind + "\x00" + f.Match))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,199 @@
+package findings
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Service names reported in Finding.Kind for RuleServiceConfig.
+const (
+	ServiceFirebase   = "Firebase"
+	ServiceSentry     = "Sentry"
+	ServiceSegment    = "Segment"
+	ServiceAmplitude  = "Amplitude"
+	ServiceGoogleMaps = "Google Maps"
+)
+
+// serviceWindow is how far around an anchor match the other keys of a
+// configuration object are looked for.
+const serviceWindow = 600
+
+var (
+	// Firebase web config: the apiKey anchors the object literal
+	firebaseAPIKeyRe = regexp.MustCompile(`["']?apiKey["']?\s*:\s*["'](AIza[0-9A-Za-z_\-]{35})["']`)
+	firebaseFieldRe  = regexp.MustCompile(`["']?(authDomain|projectId|appId|storageBucket|messagingSenderId|databaseURL|measurementId)["']?\s*:\s*["']([^"']+)["']`)
+	firebaseAppIDRe  = regexp.MustCompile(`^\d+:\d+:(?:web|android|ios):[0-9a-f]+$`)
+
+	// Sentry DSN: https://<32 hex public key>[:<secret>]@<host>/<project id>
+	sentryDSNRe = regexp.MustCompile(`https?://([0-9a-f]{32})(?::[0-9a-f]{32})?@([A-Za-z0-9.\-]+(?::\d+)?)(/[A-Za-z0-9_\-/]*)?/(\d+)\b`)
+
+	// Segment write keys appear in the snippet's load call, the CDN URL, or a writeKey option
+	segmentKeyRes = []*regexp.Regexp{
+		regexp.MustCompile(`analytics\.load\(\s*["']([A-Za-z0-9]{20,64})["']`),
+		regexp.MustCompile(`cdn\.segment\.(?:com|io)/analytics\.js/v1/([A-Za-z0-9]{20,64})/`),
+		regexp.MustCompile(`["']?writeKey["']?\s*:\s*["']([A-Za-z0-9]{20,64})["']`),
+	}
+
+	// Amplitude API keys are 32 hex characters passed to init
+	amplitudeKeyRes = []*regexp.Regexp{
+		regexp.MustCompile(`amplitude(?:\.getInstance\([^)]*\))?\.init\(\s*["']([0-9a-f]{32})["']`),
+		regexp.MustCompile(`["']?(?:amplitudeApiKey|AMPLITUDE_API_KEY|amplitudeKey)["']?\s*[:=]\s*["']([0-9a-f]{32})["']`),
+	}
+
+	// Google Maps keys appear in the Maps JS loader URL or a named option
+	googleMapsKeyRes = []*regexp.Regexp{
+		regexp.MustCompile(`maps\.googleapis\.com/maps/api/[A-Za-z/]+\?[^"'\s]*?\bkey=(AIza[0-9A-Za-z_\-]{35})`),
+		regexp.MustCompile(`["']?(?:googleMapsApiKey|googleMapsKey|mapsApiKey|GOOGLE_MAPS_API_KEY)["']?\s*[:=]\s*["'](AIza[0-9A-Za-z_\-]{35})["']`),
+	}
+)
+
+// scanServices returns findings for well-known third-party service
+// configurations: Firebase config objects, Sentry DSNs, and Segment,
+// Amplitude, and Google Maps keys. Each match is checked for the service's
+// key format before it is reported.
+func scanServices(content, file string) []Finding {
+	var results []Finding
+	seen := make(map[string]bool)
+
+	add := func(service, match string, offset int, message string, props map[string]string) {
+		if seen[service+"\x00"+match] {
+			return
+		}
+		seen[service+"\x00"+match] = true
+		line, col := position(content, offset)
+		props["service"] = service
+		results = append(results, Finding{
+			RuleID:     RuleServiceConfig,
+			Kind:       service,
+			Message:    message,
+			File:       file,
+			Line:       line,
+			Column:     col,
+			Match:      match,
+			Properties: props,
+		})
+	}
+
+	for _, loc := range firebaseAPIKeyRe.FindAllStringSubmatchIndex(content, -1) {
+		key := content[loc[2]:loc[3]]
+		fields := firebaseFields(enclosingObject(content, loc[0], loc[1]))
+		// A bare apiKey is just a Google key; require the Firebase shape
+		if fields["projectId"] == "" && fields["authDomain"] == "" && fields["appId"] == "" {
+			continue
+		}
+		if appID := fields["appId"]; appID != "" && !firebaseAppIDRe.MatchString(appID) {
+			continue
+		}
+		fields["apiKey"] = Redact(key)
+		project := fields["projectId"]
+		if project == "" {
+			project = strings.TrimSuffix(fields["authDomain"], ".firebaseapp.com")
+		}
+		add(ServiceFirebase, key, loc[0],
+			fmt.Sprintf("Firebase config for project %s (apiKey %s)", project, Redact(key)), fields)
+	}
+
+	for _, m := range sentryDSNRe.FindAllStringSubmatchIndex(content, -1) {
+		dsn := content[m[0]:m[1]]
+		host := content[m[4]:m[5]]
+		project := content[m[8]:m[9]]
+		// Self-hosted DSNs are only trusted next to a dsn key
+		if !strings.Contains(host, "sentry") && !strings.Contains(strings.ToLower(window(content, m[0], m[1])), "dsn") {
+			continue
+		}
+		add(ServiceSentry, dsn, m[0],
+			fmt.Sprintf("Sentry DSN for project %s on %s", project, host),
+			map[string]string{"host": host, "project": project, "public_key": Redact(content[m[2]:m[3]])})
+	}
+
+	keyed := []struct {
+		service string
+		res     []*regexp.Regexp
+	}{
+		{ServiceSegment, segmentKeyRes},
+		{ServiceAmplitude, amplitudeKeyRes},
+		{ServiceGoogleMaps, googleMapsKeyRes},
+	}
+	for _, k := range keyed {
+		for _, re := range k.res {
+			for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+				key := content[m[2]:m[3]]
+				if !plausibleKey(key) {
+					continue
+				}
+				add(k.service, key, m[2],
+					fmt.Sprintf("%s key: %s", k.service, Redact(key)),
+					map[string]string{"key": Redact(key)})
+			}
+		}
+	}
+
+	return results
+}
+
+// firebaseFields collects the Firebase config keys found in s.
+func firebaseFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, m := range firebaseFieldRe.FindAllStringSubmatch(s, -1) {
+		if _, ok := fields[m[1]]; !ok {
+			fields[m[1]] = m[2]
+		}
+	}
+	return fields
+}
+
+// window returns the text around content[start:end], extended by
+// serviceWindow bytes on both sides.
+func window(content string, start, end int) string {
+	start = max(0, start-serviceWindow)
+	end = min(len(content), end+serviceWindow)
+	return content[start:end]
+}
+
+// enclosingObject returns the object literal around content[start:end] by
+// brace matching within serviceWindow bytes, so keys of a neighboring object
+// are not attributed to this one. Braces inside strings are not special-cased;
+// config objects rarely contain any.
+func enclosingObject(content string, start, end int) string {
+	open := -1
+	for i, depth := start-1, 0; i >= max(0, start-serviceWindow); i-- {
+		if content[i] == '}' {
+			depth++
+		} else if content[i] == '{' {
+			if depth == 0 {
+				open = i
+				break
+			}
+			depth--
+		}
+	}
+	if open == -1 {
+		return content[start:end]
+	}
+
+	limit := min(len(content), end+serviceWindow)
+	for i, depth := end, 0; i < limit; i++ {
+		if content[i] == '{' {
+			depth++
+		} else if content[i] == '}' {
+			if depth == 0 {
+				return content[open : i+1]
+			}
+			depth--
+		}
+	}
+	return content[open:limit]
+}
+
+// plausibleKey rejects placeholders such as "YOUR_WRITE_KEY" or a run of a
+// single repeated character.
+func plausibleKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, placeholder := range []string{"your", "xxxx", "example", "placeholder", "changeme"} {
+		if strings.Contains(lower, placeholder) {
+			return false
+		}
+	}
+	return strings.Trim(key, key[:1]) != ""
+}
@@ -0,0 +1,3 @@
+import amplitude from"amplitude-js";amplitude.getInstance().init("7c3e9a1f5b2d8e4c6a0f1b3d5e7a9c2b",null,{includeUtm:!0,includeReferrer:!0});
+export const cfg={amplitudeApiKey:"7c3e9a1f5b2d8e4c6a0f1b3d5e7a9c2b"};
+export const stub={amplitudeApiKey:"00000000000000000000000000000000"};
@@ -0,0 +1,2 @@
+export function greet(n){return"Hello, "+n}const opts={apiKey:"not-a-google-key",projectId:"demo"};
+const dsn="https://sentry.io/welcome/";analytics.load("short");
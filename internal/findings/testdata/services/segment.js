@@ -0,0 +1,3 @@
+!function(){var i="analytics",analytics=window[i]=window[i]||[];if(!analytics.initialize)if(analytics.invoked)window.console&&console.error&&console.error("Segment snippet included twice.");else{analytics.invoked=!0;analytics.load=function(key,e){var t=document.createElement("script");t.type="text/javascript";t.async=!0;t.src="https://cdn.segment.com/analytics.js/v1/"+key+"/analytics.min.js"};analytics.SNIPPET_VERSION="4.15.3";analytics.load("Zq8Lm3Xv7Tn2Rb6Wk1Hc9Yd4Fp0Sg5Ja");analytics.page()}}();
+var preload="https://cdn.segment.com/analytics.js/v1/Zq8Lm3Xv7Tn2Rb6Wk1Hc9Yd4Fp0Sg5Ja/analytics.min.js";
+const docs={writeKey:"YOURWRITEKEYHERE0000000000"};
@@ -0,0 +1 @@
+var avatar="https://0a1b2c3d4e5f60718293a4b5c6d7e8f9@cdn.acme.example/7";
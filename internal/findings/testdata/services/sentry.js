@@ -0,0 +1,3 @@
+!function(){try{var e="undefined"!=typeof window?window:{};e.SENTRY_RELEASE={id:"web@4.18.2"}}catch(e){}}();
+o.S1({dsn:"https://4f1c2b9e8d7a6053b2c1e0f9a8d7c6b5@o451234.ingest.sentry.io/5678901",release:"web@4.18.2",tracesSampleRate:.1,environment:"production"});
+var selfHosted={dsn:"https://0a1b2c3d4e5f60718293a4b5c6d7e8f9@errors.acme.example/12"};
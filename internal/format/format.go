@@ -1,16 +1,25 @@
-// Package format provides pretty-printing for JavaScript/TypeScript source files.
+// Package format provides pretty-printing for JavaScript/TypeScript source
+// files and JSON.
 package format
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/ditashi/jsbeautifier-go/jsbeautifier"
 )
 
-// isJSFile returns true if the file extension indicates a JS/TS file.
-func isJSFile(ext string) bool {
-	switch strings.ToLower(ext) {
+// errChanged is returned when the formatter changed more than whitespace.
+var errChanged = errors.New("formatter changed more than whitespace")
+
+// IsJSFile reports whether filename has a JS/TS extension, the files
+// Format pretty-prints.
+func IsJSFile(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
 	case ".js", ".mjs", ".cjs", ".jsx", ".ts", ".mts", ".cts", ".tsx":
 		return true
 	default:
@@ -22,19 +31,121 @@ func isJSFile(ext string) bool {
 // Returns the formatted content, or the original content if formatting fails
 // or the file type is not supported.
 func Format(content string, filename string) string {
-	ext := filepath.Ext(filename)
+	result, _ := Beautify(content, filename)
+	return result
+}
 
-	// Not a JS/TS file, return unchanged
-	if !isJSFile(ext) {
-		return content
+// Beautify behaves like Format but also returns the error when formatting a
+// JS/TS file fails, in which case the original content is returned.
+//
+// Content that is pure JSON, JSON with comments, or JSON5, in a .json,
+// JS/TS, or extensionless file, is indented as JSON instead, since
+// bundlers store config modules that way. Formatting only ever changes
+// whitespace: output that differs from the input in anything else, or
+// JSON that no longer decodes to the same tokens, is discarded.
+func Beautify(content string, filename string) (string, error) {
+	var result string
+	switch {
+	case isJSON(content, filename):
+		result = indentJSON(content)
+		if !sameTokens(result, content) {
+			return content, errChanged
+		}
+	case IsJSFile(filename):
+		options := jsbeautifier.DefaultOptions()
+		formatted, err := jsbeautifier.Beautify(&content, options)
+		if err != nil {
+			// If beautification fails, return original content (graceful fallback)
+			return content, err
+		}
+		result = formatted
+	default:
+		// Not a JS/TS file, return unchanged
+		return content, nil
+	}
+
+	if stripSpace(result) != stripSpace(content) {
+		return content, errChanged
 	}
+	return result, nil
+}
 
-	options := jsbeautifier.DefaultOptions()
-	result, err := jsbeautifier.Beautify(&content, options)
+// isJSON reports whether content is a JSON, JSON-with-comments, or JSON5
+// object or array in a file that may hold one.
+func isJSON(content, filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != "" && ext != ".json" && !IsJSFile(filename) {
+		return false
+	}
+	trimmed := strings.TrimSpace(strings.TrimPrefix(content, "\ufeff"))
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if json.Valid([]byte(trimmed)) {
+			return true
+		}
+	}
+	// JSON with comments may start with one
+	v, err := parseJSON5(trimmed)
 	if err != nil {
-		// If beautification fails, return original content (graceful fallback)
+		return false
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// indentJSON indents JSON-like content by two spaces. Keys, numbers, and
+// string escapes are kept exactly as written. A leading BOM and a trailing
+// newline are kept.
+func indentJSON(content string) string {
+	body := strings.TrimPrefix(content, "\ufeff")
+	if !json.Valid([]byte(strings.TrimSpace(body))) {
+		indented, err := indentJSON5(content)
+		if err != nil {
+			return content
+		}
+		return indented
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(body)), "", "  "); err != nil {
 		return content
 	}
+	out := buf.String()
+	if len(body) < len(content) {
+		out = "\ufeff" + out
+	}
+	if strings.HasSuffix(content, "\n") {
+		out += "\n"
+	}
+	return out
+}
 
-	return result
+// sameTokens reports whether a and b are the same JSON-like tokens,
+// comments included, ignoring only the whitespace between them.
+func sameTokens(a, b string) bool {
+	ta, err := lexJSON5(a)
+	if err != nil {
+		return false
+	}
+	tb, err := lexJSON5(b)
+	if err != nil || len(ta) != len(tb) {
+		return false
+	}
+	for i := range ta {
+		if ta[i] != tb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// stripSpace removes all whitespace from s.
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
 }
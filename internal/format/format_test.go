@@ -0,0 +1,130 @@
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBeautifyJSONKeepsContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     string // The same document as plain JSON
+	}{
+		{
+			name:     "json",
+			filename: "data.json",
+			content:  `{"a":1,"b":[true,null,"xé"],"c":{}}` + "\n",
+			want:     `{"a":1,"b":[true,null,"xé"],"c":{}}`,
+		},
+		{
+			name:     "jsonc",
+			filename: "tsconfig.json",
+			content: `// compiler options
+{"compilerOptions":{/* strict mode */"strict":true, // always
+"paths":{"@/*":["src/*"]},},}`,
+			want: `{"compilerOptions":{"strict":true,"paths":{"@/*":["src/*"]}}}`,
+		},
+		{
+			name:     "json5 in a js file",
+			filename: "config.js",
+			content:  `{name:'app',port:0x1F90,ratio:.5,scale:+2.,tags:['a','b\'s',],empty:[],nested:{deep:{}}}`,
+			want:     `{"name":"app","port":8080,"ratio":0.5,"scale":2,"tags":["a","b's"],"empty":[],"nested":{"deep":{}}}`,
+		},
+		{
+			name:     "json5 escapes",
+			filename: "strings",
+			content:  `['\x41é😀', "line\` + "\n" + `continued", 'tab\there']`,
+			want:     `["Aé😀","linecontinued","tab\there"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !isJSON(tt.content, tt.filename) {
+				t.Fatal("not detected as JSON")
+			}
+			got, err := Beautify(tt.content, tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == tt.content {
+				t.Error("content was not indented")
+			}
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			for name, doc := range map[string]string{"input": tt.content, "output": got} {
+				parsed, err := parseJSON5(doc)
+				if err != nil {
+					t.Fatalf("%s does not parse: %v\n%s", name, err, doc)
+				}
+				if !reflect.DeepEqual(parsed, want) {
+					t.Errorf("%s decodes to %v, want %v", name, parsed, want)
+				}
+			}
+			if !sameTokens(got, tt.content) {
+				t.Errorf("tokens changed:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestBeautifyJSON5Layout(t *testing.T) {
+	content := "{a:1, // one\nb:[/* none */],c:[],}\n"
+	want := `{
+  a: 1,
+  // one
+  b: [
+    /* none */
+  ],
+  c: [],
+}
+`
+	got, err := Beautify(content, "config.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Beautify =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		content  string
+		filename string
+		want     bool
+	}{
+		{`{"a":1}`, "a.json", true},
+		{`{"a":1}`, "a", true},
+		{"\ufeff[1, 2]", "a.js", true},
+		{`{a: 1, /* c */}`, "a.ts", true},
+		{`{"a":1}`, "a.css", false},
+		{`module.exports = {a: 1}`, "a.js", false},
+		{`{a: 1}; run()`, "a.js", false},
+		{`[1, 2`, "a.js", false},
+		{`{a b}`, "a.js", false},
+		{`"just a string"`, "a.json", false},
+	}
+	for _, tt := range tests {
+		if got := isJSON(tt.content, tt.filename); got != tt.want {
+			t.Errorf("isJSON(%q, %q) = %v, want %v", tt.content, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestBeautifyJavaScript(t *testing.T) {
+	content := "function f(){return 1}"
+	got, err := Beautify(content, "f.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "\n") || stripSpace(got) != stripSpace(content) {
+		t.Errorf("Beautify = %q", got)
+	}
+}
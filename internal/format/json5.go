@@ -0,0 +1,384 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// JSON with comments and JSON5 are read as a stream of tokens, which are
+// re-indented but written back exactly as they appear: quotes, number
+// forms, comments, and trailing commas are all kept.
+
+type tokenKind int
+
+const (
+	tokPunct   tokenKind = iota // One of {}[],:
+	tokString                   // Single- or double-quoted string
+	tokBare                     // Identifier, number, or literal
+	tokLine                     // Line comment, without its newline
+	tokComment                  // Block comment
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func (t token) isComment() bool {
+	return t.kind == tokLine || t.kind == tokComment
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokPunct && t.text == punct
+}
+
+var errJSON5 = errors.New("not JSON5")
+
+// lexJSON5 splits content into tokens, dropping whitespace.
+func lexJSON5(content string) ([]token, error) {
+	var tokens []token
+	s := content
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		switch {
+		case unicode.IsSpace(r) || r == '\ufeff':
+			s = s[size:]
+		case strings.HasPrefix(s, "//"):
+			end := strings.IndexAny(s, "\r\n\u2028\u2029")
+			if end < 0 {
+				end = len(s)
+			}
+			tokens = append(tokens, token{tokLine, s[:end]})
+			s = s[end:]
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s[2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated comment", errJSON5)
+			}
+			tokens = append(tokens, token{tokComment, s[:end+4]})
+			s = s[end+4:]
+		case r == '"' || r == '\'':
+			end, err := stringEnd(s)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, s[:end]})
+			s = s[end:]
+		case strings.ContainsRune("{}[],:", r):
+			tokens = append(tokens, token{tokPunct, s[:1]})
+			s = s[1:]
+		default:
+			end := strings.IndexFunc(s, func(r rune) bool { return !isBareRune(r) })
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%w: unexpected %q", errJSON5, r)
+			}
+			tokens = append(tokens, token{tokBare, s[:end]})
+			s = s[end:]
+		}
+	}
+	return tokens, nil
+}
+
+// stringEnd returns the length of the quoted string s starts with.
+func stringEnd(s string) (int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) && s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case '\n', '\r':
+			return 0, fmt.Errorf("%w: newline in string", errJSON5)
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unterminated string", errJSON5)
+}
+
+func isBareRune(r rune) bool {
+	return r == '_' || r == '$' || r == '+' || r == '-' || r == '.' ||
+		unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// parseJSON5 decodes a JSON5 document into the values encoding/json
+// would: maps, slices, strings, float64s, bools, and nil.
+func parseJSON5(content string) (interface{}, error) {
+	tokens, err := lexJSON5(content)
+	if err != nil {
+		return nil, err
+	}
+	p := &json5Parser{}
+	for _, t := range tokens {
+		if !t.isComment() {
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("%w: %q after the value", errJSON5, p.tokens[p.pos].text)
+	}
+	return v, nil
+}
+
+type json5Parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *json5Parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("%w: unexpected end", errJSON5)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// closes reports whether the next token is punct, consuming it if so.
+func (p *json5Parser) closes(punct string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].is(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *json5Parser) value() (interface{}, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.is("{"):
+		return p.object()
+	case t.is("["):
+		return p.array()
+	case t.kind == tokString:
+		return unquoteJSON5(t.text)
+	case t.kind == tokBare:
+		return bareValue(t.text)
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", errJSON5, t.text)
+}
+
+func (p *json5Parser) object() (interface{}, error) {
+	obj := map[string]interface{}{}
+	for !p.closes("}") {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch {
+		case t.kind == tokString:
+			if key, err = unquoteJSON5(t.text); err != nil {
+				return nil, err
+			}
+		case t.kind == tokBare && identifier.MatchString(t.text):
+			key = t.text
+		default:
+			return nil, fmt.Errorf("%w: bad key %q", errJSON5, t.text)
+		}
+		if !p.closes(":") {
+			return nil, fmt.Errorf("%w: no ':' after key %q", errJSON5, key)
+		}
+		if obj[key], err = p.value(); err != nil {
+			return nil, err
+		}
+		if !p.closes(",") && !(p.pos < len(p.tokens) && p.tokens[p.pos].is("}")) {
+			return nil, fmt.Errorf("%w: no ',' after %q", errJSON5, key)
+		}
+	}
+	return obj, nil
+}
+
+func (p *json5Parser) array() (interface{}, error) {
+	arr := []interface{}{}
+	for !p.closes("]") {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+		if !p.closes(",") && !(p.pos < len(p.tokens) && p.tokens[p.pos].is("]")) {
+			return nil, fmt.Errorf("%w: no ',' between array elements", errJSON5)
+		}
+	}
+	return arr, nil
+}
+
+var (
+	identifier = regexp.MustCompile(`^[\p{L}_$][\p{L}\p{N}_$]*$`)
+	decimal    = regexp.MustCompile(`^(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+)
+
+// bareValue decodes an unquoted literal or number.
+func bareValue(text string) (interface{}, error) {
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	sign := 1.0
+	num := text
+	if strings.HasPrefix(num, "+") || strings.HasPrefix(num, "-") {
+		if num[0] == '-' {
+			sign = -1
+		}
+		num = num[1:]
+	}
+	switch {
+	case num == "Infinity":
+		return math.Inf(int(sign)), nil
+	case num == "NaN":
+		return math.NaN(), nil
+	case strings.HasPrefix(num, "0x") || strings.HasPrefix(num, "0X"):
+		n, err := strconv.ParseUint(num[2:], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad number %q", errJSON5, text)
+		}
+		return sign * float64(n), nil
+	case decimal.MatchString(num):
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad number %q", errJSON5, text)
+		}
+		return sign * f, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", errJSON5, text)
+}
+
+// unquoteJSON5 decodes a single- or double-quoted JSON5 string.
+func unquoteJSON5(text string) (string, error) {
+	body := text[1 : len(text)-1]
+	if !strings.Contains(body, `\`) {
+		return body, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(body) {
+			return "", fmt.Errorf("%w: bad escape in %s", errJSON5, text)
+		}
+		switch c = body[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case '\n':
+			// Line continuation
+		case '\r':
+			if i+1 < len(body) && body[i+1] == '\n' {
+				i++
+			}
+		case 'x', 'u':
+			n := 2
+			if c == 'u' {
+				n = 4
+			}
+			if i+1+n > len(body) {
+				return "", fmt.Errorf("%w: bad escape in %s", errJSON5, text)
+			}
+			code, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("%w: bad escape in %s", errJSON5, text)
+			}
+			i += n
+			r := rune(code)
+			// A surrogate pair is written as two \u escapes
+			if utf16.IsSurrogate(r) && strings.HasPrefix(body[i+1:], `\u`) && i+7 <= len(body) {
+				if low, err := strconv.ParseUint(body[i+3:i+7], 16, 32); err == nil {
+					if pair := utf16.DecodeRune(r, rune(low)); pair != unicode.ReplacementChar {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// indentJSON5 re-indents a JSON5 document by two spaces, in the layout
+// json.Indent gives plain JSON. Comments stay where they are, and a line
+// comment always ends its line. A leading BOM and a trailing newline are
+// kept.
+func indentJSON5(content string) (string, error) {
+	tokens, err := lexJSON5(content)
+	if err != nil {
+		return content, err
+	}
+
+	var b strings.Builder
+	if strings.HasPrefix(content, "\ufeff") {
+		b.WriteString("\ufeff")
+	}
+	depth := 0
+	lineStart := true
+	newline := false
+	var prev token
+	for i, t := range tokens {
+		closing := t.is("}") || t.is("]")
+		if closing {
+			depth--
+		}
+		switch {
+		case closing && (prev.is("{") || prev.is("[")):
+		case newline || closing && i > 0:
+			b.WriteString("\n" + strings.Repeat("  ", depth))
+			lineStart = true
+		case !lineStart && (prev.is(":") || prev.kind == tokComment || t.isComment() || prev.kind != tokPunct && t.kind != tokPunct):
+			b.WriteString(" ")
+		}
+		b.WriteString(t.text)
+		lineStart = false
+
+		newline = t.is(",") || t.kind == tokLine
+		if t.is("{") || t.is("[") {
+			depth++
+			// An empty object or array stays on one line
+			newline = i+1 < len(tokens) && !tokens[i+1].is("}") && !tokens[i+1].is("]")
+		}
+		prev = t
+	}
+	if prev.kind == tokLine || strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
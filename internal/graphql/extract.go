@@ -0,0 +1,363 @@
+// Package graphql harvests GraphQL operations and schemas embedded in
+// restored sources, such as Apollo and urql gql`...` documents.
+package graphql
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Matches the start of a tagged template: gql`, graphql`, gql(`, graphql(`
+	taggedTemplateRe = regexp.MustCompile("\\b(?:gql|graphql)\\s*(?:\\(\\s*)?`")
+
+	// Matches the opening quote of a string whose content starts like a
+	// GraphQL executable definition
+	documentStringRe = regexp.MustCompile("([\"'`])\\s*(?:query|mutation|subscription|fragment)\\b")
+
+	// Matches an introspection result, as JSON or a JS object literal
+	introspectionRe = regexp.MustCompile(`["']?__schema["']?\s*:\s*\{\s*["']?(?:queryType|description|types)["']?\s*:`)
+
+	// Matches the start of SDL inside a string
+	sdlRe = regexp.MustCompile("([\"'`])\\s*(?:schema\\s*\\{|(?:extend\\s+)?type\\s+(?:Query|Mutation|Subscription)\\s*\\{)")
+
+	// Matches a definition keyword with an optional name
+	definitionRe = regexp.MustCompile(`^(query|mutation|subscription|fragment)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`)
+)
+
+// Document is a GraphQL document found in a source file.
+type Document struct {
+	Text string // Document text with ${...} interpolations replaced by comments
+	Line int    // 1-based line where the literal starts
+}
+
+// Schema is an embedded schema: an introspection result or SDL.
+type Schema struct {
+	Kind string // "introspection" or "sdl"
+	Text string // JSON or SDL text; empty if it could not be isolated
+	Line int
+}
+
+// ExtractDocuments finds GraphQL documents in JavaScript or TypeScript
+// source: gql/graphql tagged templates and plain string literals whose
+// content starts with query, mutation, subscription, or fragment.
+func ExtractDocuments(src string) []Document {
+	var docs []Document
+	var spans [][2]int // Tagged template literals, already taken
+
+	for _, loc := range taggedTemplateRe.FindAllStringIndex(src, -1) {
+		start := loc[1] - 1 // Opening backtick
+		body, end := readTemplate(src, start)
+		if end < 0 {
+			continue
+		}
+		docs = append(docs, Document{Text: strings.TrimSpace(body), Line: lineAt(src, start)})
+		spans = append(spans, [2]int{start, end})
+	}
+
+	tagged := docs
+	for _, loc := range documentStringRe.FindAllStringSubmatchIndex(src, -1) {
+		start := loc[2]
+		if insideSpan(start, spans) || insideTagged(src, start, tagged) {
+			continue
+		}
+		var body string
+		var end int
+		if src[start] == '`' {
+			body, end = readTemplate(src, start)
+		} else {
+			body, end = readString(src, start)
+		}
+		if end < 0 || !looksExecutable(body) {
+			continue
+		}
+		docs = append(docs, Document{Text: strings.TrimSpace(body), Line: lineAt(src, start)})
+	}
+
+	return docs
+}
+
+// insideSpan reports whether offset falls within one of spans.
+func insideSpan(offset int, spans [][2]int) bool {
+	for _, s := range spans {
+		if offset >= s[0] && offset <= s[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// insideTagged reports whether offset falls on a line already claimed by a
+// tagged template; plain-string matches there are the same document.
+func insideTagged(src string, offset int, docs []Document) bool {
+	line := lineAt(src, offset)
+	for _, d := range docs {
+		if line >= d.Line && line <= d.Line+strings.Count(d.Text, "\n")+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractSchemas finds embedded introspection results and SDL strings.
+func ExtractSchemas(src string) []Schema {
+	var schemas []Schema
+
+	for _, loc := range introspectionRe.FindAllStringIndex(src, -1) {
+		s := Schema{Kind: "introspection", Line: lineAt(src, loc[0])}
+		// Walk out to the enclosing object ({"data":{"__schema":...}} or {"__schema":...})
+		if open := strings.LastIndexByte(src[:loc[0]], '{'); open != -1 {
+			if end := matchBrace(src, open); end > 0 {
+				s.Text = src[open : end+1]
+			}
+		}
+		schemas = append(schemas, s)
+	}
+
+	for _, loc := range sdlRe.FindAllStringSubmatchIndex(src, -1) {
+		start := loc[2]
+		var body string
+		var end int
+		if src[start] == '`' {
+			body, end = readTemplate(src, start)
+		} else {
+			body, end = readString(src, start)
+		}
+		if end < 0 {
+			continue
+		}
+		schemas = append(schemas, Schema{Kind: "sdl", Text: strings.TrimSpace(body), Line: lineAt(src, start)})
+	}
+
+	return schemas
+}
+
+// Operation is a single top-level definition in a document.
+type Operation struct {
+	Type string // query, mutation, subscription, or fragment
+	Name string // Empty for anonymous operations
+}
+
+// Operations lists the top-level definitions in a document. An anonymous
+// "{ ... }" shorthand is reported as an unnamed query.
+func Operations(doc string) []Operation {
+	var ops []Operation
+	depth := 0
+	atDefinition := true
+
+	for i := 0; i < len(doc); i++ {
+		switch c := doc[i]; {
+		case c == '#':
+			if nl := strings.IndexByte(doc[i:], '\n'); nl != -1 {
+				i += nl
+			} else {
+				i = len(doc)
+			}
+		case c == '"':
+			i = skipGraphQLString(doc, i)
+		case c == '{' || c == '(' || c == '[':
+			if depth == 0 && c == '{' && atDefinition {
+				ops = append(ops, Operation{Type: "query"})
+			}
+			atDefinition = false
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+			if depth == 0 {
+				atDefinition = c == '}'
+			}
+		case depth == 0 && isNameStart(c):
+			if m := definitionRe.FindStringSubmatch(doc[i:]); m != nil && atDefinition {
+				ops = append(ops, Operation{Type: m[1], Name: m[2]})
+				i += len(m[0]) - 1
+			} else {
+				// Any other word (type, on, a directive name) is not an
+				// executable definition start
+				for i+1 < len(doc) && (isNameStart(doc[i+1]) || doc[i+1] >= '0' && doc[i+1] <= '9') {
+					i++
+				}
+			}
+			atDefinition = false
+		}
+	}
+	return ops
+}
+
+// Normalize collapses whitespace and drops comments so that documents that
+// differ only in formatting compare equal.
+func Normalize(doc string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(doc); i++ {
+		c := doc[i]
+		switch {
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+			space = true
+		case c == '"':
+			end := skipGraphQLString(doc, i)
+			if space && b.Len() > 0 && !isPunct(lastByte(b.String())) {
+				b.WriteByte(' ')
+			}
+			b.WriteString(doc[i : end+1])
+			i = end
+			space = false
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			space = true
+		default:
+			if space && b.Len() > 0 && !isPunct(c) && !isPunct(lastByte(b.String())) {
+				b.WriteByte(' ')
+			}
+			b.WriteByte(c)
+			space = false
+		}
+	}
+	return b.String()
+}
+
+// readTemplate reads a template literal starting at the backtick at i,
+// replacing each ${expr} with a GraphQL comment placeholder. Returns the
+// body and the index of the closing backtick, or -1 if unterminated.
+func readTemplate(src string, i int) (string, int) {
+	var b strings.Builder
+	for j := i + 1; j < len(src); j++ {
+		switch c := src[j]; c {
+		case '\\':
+			if j+1 < len(src) {
+				b.WriteString(unescape(src[j : j+2]))
+				j++
+			}
+		case '`':
+			return b.String(), j
+		case '$':
+			if j+1 < len(src) && src[j+1] == '{' {
+				end := matchBrace(src, j+1)
+				if end < 0 {
+					return "", -1
+				}
+				expr := strings.Join(strings.Fields(src[j+2:end]), " ")
+				// A comment keeps the document parseable; the line break
+				// ends it so text after the interpolation survives
+				b.WriteString("# ${" + expr + "}\n")
+				j = end
+				continue
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", -1
+}
+
+// readString reads a quoted JS string starting at i and decodes its escapes.
+// Returns the body and the index of the closing quote, or -1.
+func readString(src string, i int) (string, int) {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '\n':
+			return "", -1
+		case quote:
+			raw := src[i : j+1]
+			if quote == '\'' {
+				raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`), `"`, `\"`) + `"`
+			}
+			if s, err := strconv.Unquote(raw); err == nil {
+				return s, j
+			}
+			return src[i+1 : j], j
+		}
+	}
+	return "", -1
+}
+
+// unescape decodes a two-character JS escape sequence inside a template.
+func unescape(seq string) string {
+	switch seq[1] {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return ""
+	}
+	return seq[1:]
+}
+
+// matchBrace returns the index of the brace closing the one at i, skipping
+// JS string literals, or -1.
+func matchBrace(src string, i int) int {
+	depth := 0
+	for j := i; j < len(src); j++ {
+		switch c := src[j]; c {
+		case '"', '\'', '`':
+			for j++; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// skipGraphQLString returns the index of the closing quote of a GraphQL
+// string or block string starting at i.
+func skipGraphQLString(doc string, i int) int {
+	if strings.HasPrefix(doc[i:], `"""`) {
+		if end := strings.Index(doc[i+3:], `"""`); end != -1 {
+			return i + 3 + end + 2
+		}
+		return len(doc) - 1
+	}
+	for j := i + 1; j < len(doc); j++ {
+		switch doc[j] {
+		case '\\':
+			j++
+		case '"', '\n':
+			return j
+		}
+	}
+	return len(doc) - 1
+}
+
+// looksExecutable reports whether a plain string is a GraphQL document
+// rather than prose that happens to start with "query": it must contain a
+// selection set.
+func looksExecutable(body string) bool {
+	ops := Operations(body)
+	return len(ops) > 0 && strings.Contains(body, "{") && strings.Contains(body, "}")
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!$():=@[]{}|&", c) != -1
+}
+
+func lastByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[len(s)-1]
+}
+
+// lineAt converts a byte offset into a 1-based line number.
+func lineAt(src string, offset int) int {
+	return strings.Count(src[:offset], "\n") + 1
+}
@@ -0,0 +1,133 @@
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Document
+	}{
+		{
+			name: "gql tag",
+			src:  "const Q = gql`\n  query GetUser($id: ID!) {\n    user(id: $id) { id name }\n  }\n`;",
+			want: []Document{{Text: "query GetUser($id: ID!) {\n    user(id: $id) { id name }\n  }", Line: 1}},
+		},
+		{
+			name: "graphql call with interpolation",
+			src:  "x;\nconst Q = graphql(`query Feed { feed { ...Post } } ${ POST_FRAGMENT }`);",
+			want: []Document{{Text: "query Feed { feed { ...Post } } # ${POST_FRAGMENT}", Line: 2}},
+		},
+		{
+			name: "interpolation mid-document",
+			src:  "gql`query A { ${fields} id }`",
+			want: []Document{{Text: "query A { # ${fields}\n id }", Line: 1}},
+		},
+		{
+			name: "minified string",
+			src:  `var q="mutation Login($u:String!){login(user:$u){token}}",r='query {\n me { id } }';`,
+			want: []Document{
+				{Text: "mutation Login($u:String!){login(user:$u){token}}", Line: 1},
+				{Text: "query {\n me { id } }", Line: 1},
+			},
+		},
+		{
+			name: "escapes in a template",
+			src:  "gql`query Q { search(q: \\\"a\\\") { id } }`",
+			want: []Document{{Text: `query Q { search(q: "a") { id } }`, Line: 1}},
+		},
+		{
+			name: "prose",
+			src:  `alert("query failed, try again"); var s = 'subscription plans';`,
+		},
+		{
+			name: "unterminated",
+			src:  "gql`query A { a }",
+		},
+		{
+			name: "tagged template not repeated as a string",
+			src:  "export const A = gql`query A { a }`;",
+			want: []Document{{Text: "query A { a }", Line: 1}},
+		},
+		{
+			name: "string before a later tagged template",
+			src:  "var q = \"query A { a }\";\nvar f = gql`fragment F on T { id }`;",
+			want: []Document{
+				{Text: "fragment F on T { id }", Line: 2},
+				{Text: "query A { a }", Line: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractDocuments(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractDocuments =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperations(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want []Operation
+	}{
+		{"query GetUser { user { id } }", []Operation{{"query", "GetUser"}}},
+		{"{ me { id } }", []Operation{{"query", ""}}},
+		{"mutation { a }", []Operation{{"mutation", ""}}},
+		{"subscription OnPost { post { id } }", []Operation{{"subscription", "OnPost"}}},
+		{
+			"query Feed { feed { ...PostFields } }\nfragment PostFields on Post { id title }",
+			[]Operation{{"query", "Feed"}, {"fragment", "PostFields"}},
+		},
+		{`query A($q: String = "query B { x }") { a }`, []Operation{{"query", "A"}}},
+		{"# query Commented { x }\nquery Real { x }", []Operation{{"query", "Real"}}},
+		{"query A { query }", []Operation{{"query", "A"}}},
+		{"type Query { a: Int }", nil},
+	}
+	for _, tt := range tests {
+		if got := Operations(tt.doc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Operations(%q) = %v, want %v", tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	same := []string{
+		"query A($id: ID!) { user(id: $id) { id, name } }",
+		"query A($id: ID!) { user(id: $id) { id name } }",
+		"query A($id:ID!){user(id:$id){id name}}",
+		"query A(\n  $id: ID!\n) {\n  # the user\n  user(id: $id) {\n    id\n    name\n  }\n}",
+	}
+	want := Normalize(same[0])
+	for _, doc := range same[1:] {
+		if got := Normalize(doc); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", doc, got, want)
+		}
+	}
+	if got := Normalize(`query { a(s: "x  ,  y") }`); got != Normalize(`query{a(s:"x  ,  y")}`) || got != `query{a(s:"x  ,  y")}` {
+		t.Errorf("Normalize changed a string: %q", got)
+	}
+	if Normalize("query A { a b }") == Normalize("query A { ab }") {
+		t.Error("Normalize joined two field names")
+	}
+}
+
+func TestExtractSchemas(t *testing.T) {
+	src := "var a = 1;\n" +
+		`var s = {"data":{"__schema":{"queryType":{"name":"Query"},"types":[{"name":"Q{"}]}}};` + "\n" +
+		"var sdl = `type Query {\n  me: User\n}`;\n" +
+		`var other = "schema { query: Query }";`
+	got := ExtractSchemas(src)
+	want := []Schema{
+		{Kind: "introspection", Text: `{"__schema":{"queryType":{"name":"Query"},"types":[{"name":"Q{"}]}}`, Line: 2},
+		{Kind: "sdl", Text: "type Query {\n  me: User\n}", Line: 3},
+		{Kind: "sdl", Text: "schema { query: Query }", Line: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractSchemas =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,324 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+)
+
+// maxScanSize caps the size of files scanned for GraphQL.
+const maxScanSize = 5 << 20
+
+// sdlFileRe matches a type system definition at the start of a line.
+var sdlFileRe = regexp.MustCompile(`(?m)^\s*(?:schema|type|interface|enum|input|scalar|union|directive|extend)\b`)
+
+// Output file names, relative to the graphql output directory.
+const (
+	OperationsFile = "operations.graphql"
+	IndexFile      = "index.json"
+)
+
+// scanExtensions are the file types scanned for documents.
+var scanExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+	".vue": true, ".svelte": true,
+	".graphql": true, ".gql": true, ".json": true,
+}
+
+// IndexEntry describes one operation or fragment in operations.graphql.
+type IndexEntry struct {
+	Name   string   `json:"name,omitempty"` // Empty for anonymous operations
+	Type   string   `json:"type"`           // query, mutation, subscription, or fragment
+	File   string   `json:"file"`           // First source file it was found in
+	Line   int      `json:"line"`
+	AlsoIn []string `json:"also_in,omitempty"` // Other files containing the same document
+}
+
+// SchemaEntry describes an embedded schema.
+type SchemaEntry struct {
+	Kind   string `json:"kind"` // "introspection" or "sdl"
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Output string `json:"output,omitempty"` // File written under the graphql directory
+}
+
+// Harvest is the deduplicated set of documents and schemas in a directory.
+type Harvest struct {
+	schema.Versioned
+	Documents  []string      `json:"-"` // Unique documents in discovery order
+	Operations []IndexEntry  `json:"operations"`
+	Schemas    []SchemaEntry `json:"schemas,omitempty"`
+
+	schemaText []string // Parallel to Schemas
+}
+
+// Empty reports whether nothing was found.
+func (h *Harvest) Empty() bool {
+	return len(h.Documents) == 0 && len(h.Schemas) == 0
+}
+
+// HarvestDirectory scans the source files under dir, skipping vendor
+// directories. Documents are deduplicated by their normalized text, so the
+// same query inlined into several chunks is reported once.
+func HarvestDirectory(dir string) (*Harvest, []error) {
+	h := &Harvest{}
+	var errs []error
+	seen := make(map[string][]int) // Normalized document -> indexes into Operations
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("walk error at %s: %w", p, err))
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || (p != dir && stats.IsVendor(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if scanExtensions[strings.ToLower(filepath.Ext(p))] {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to walk directory: %w", err))
+	}
+	sort.Strings(files)
+
+	for _, p := range files {
+		info, err := os.Stat(p)
+		if err != nil || info.Size() > maxScanSize {
+			continue
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", p, err))
+			continue
+		}
+		if bytes.IndexByte(content, 0) != -1 || !utf8.Valid(content) {
+			continue
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		src := string(content)
+
+		var docs []Document
+		switch ext := strings.ToLower(filepath.Ext(p)); ext {
+		case ".graphql", ".gql":
+			if isSDL(src) {
+				h.addSchema(Schema{Kind: "sdl", Text: strings.TrimSpace(src), Line: 1}, rel)
+			} else {
+				docs = []Document{{Text: strings.TrimSpace(src), Line: 1}}
+			}
+		case ".json":
+			for _, s := range ExtractSchemas(src) {
+				if s.Kind == "introspection" {
+					h.addSchema(s, rel)
+				}
+			}
+		default:
+			docs = ExtractDocuments(src)
+			for _, s := range ExtractSchemas(src) {
+				h.addSchema(s, rel)
+			}
+		}
+
+		for _, doc := range docs {
+			key := Normalize(doc.Text)
+			if idx, ok := seen[key]; ok {
+				for _, i := range idx {
+					if h.Operations[i].File != rel && !contains(h.Operations[i].AlsoIn, rel) {
+						h.Operations[i].AlsoIn = append(h.Operations[i].AlsoIn, rel)
+					}
+				}
+				continue
+			}
+			ops := Operations(doc.Text)
+			if len(ops) == 0 {
+				continue
+			}
+			h.Documents = append(h.Documents, doc.Text)
+			var idx []int
+			for _, op := range ops {
+				idx = append(idx, len(h.Operations))
+				h.Operations = append(h.Operations, IndexEntry{Name: op.Name, Type: op.Type, File: rel, Line: doc.Line})
+			}
+			seen[key] = idx
+		}
+	}
+
+	return h, errs
+}
+
+// addSchema records a schema unless the same text was already seen.
+func (h *Harvest) addSchema(s Schema, file string) {
+	if s.Text != "" {
+		for _, t := range h.schemaText {
+			if t == s.Text {
+				return
+			}
+		}
+	}
+	h.Schemas = append(h.Schemas, SchemaEntry{Kind: s.Kind, File: file, Line: s.Line})
+	h.schemaText = append(h.schemaText, s.Text)
+}
+
+// Write saves operations.graphql, index.json, and any isolated schemas to
+// outDir. Introspection results are only written when they parse as JSON;
+// object literals in minified code are indexed but not converted. Files
+// from an earlier Write that this one does not replace are removed; other
+// files in outDir are left alone.
+func (h *Harvest) Write(outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", outDir, err)
+	}
+	stale := make(map[string]bool)
+	for _, name := range written(outDir) {
+		stale[name] = true
+	}
+
+	if len(h.Documents) > 0 {
+		var b strings.Builder
+		for i, doc := range h.Documents {
+			if i > 0 {
+				b.WriteString("\n\n")
+			}
+			b.WriteString(dedent(doc))
+		}
+		b.WriteString("\n")
+		if err := os.WriteFile(filepath.Join(outDir, OperationsFile), []byte(b.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", OperationsFile, err)
+		}
+		delete(stale, OperationsFile)
+	}
+
+	counts := make(map[string]int)
+	for i := range h.Schemas {
+		s := &h.Schemas[i]
+		text := h.schemaText[i]
+		var name string
+		switch s.Kind {
+		case "introspection":
+			if text == "" || !json.Valid([]byte(text)) {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, []byte(text), "", "  "); err == nil {
+				text = buf.String()
+			}
+			name = "introspection"
+		case "sdl":
+			name = "schema"
+		}
+		counts[name]++
+		ext := ".graphql"
+		if s.Kind == "introspection" {
+			ext = ".json"
+		}
+		s.Output = name + ext
+		if n := counts[name]; n > 1 {
+			s.Output = fmt.Sprintf("%s-%d%s", name, n, ext)
+		}
+		if err := os.WriteFile(filepath.Join(outDir, s.Output), []byte(text+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", s.Output, err)
+		}
+		delete(stale, s.Output)
+	}
+
+	h.Versioned = schema.Current()
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", IndexFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, IndexFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", IndexFile, err)
+	}
+	delete(stale, IndexFile)
+
+	for name := range stale {
+		os.Remove(filepath.Join(outDir, name))
+	}
+	return nil
+}
+
+// Remove deletes the files an earlier Write left in outDir, and outDir
+// itself if nothing else is in it.
+func Remove(outDir string) {
+	for _, name := range written(outDir) {
+		os.Remove(filepath.Join(outDir, name))
+	}
+	os.Remove(outDir) // Fails, harmlessly, unless empty
+}
+
+// written lists the files an earlier Write left in outDir, from its index.
+func written(outDir string) []string {
+	data, err := os.ReadFile(filepath.Join(outDir, IndexFile))
+	if err != nil {
+		return nil
+	}
+	names := []string{OperationsFile, IndexFile}
+	var prev Harvest
+	if json.Unmarshal(data, &prev) == nil {
+		for _, s := range prev.Schemas {
+			// Only plain file names; the index may have been edited
+			if name := s.Output; name != "" && name != "." && name != ".." && name == filepath.Base(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// isSDL reports whether a .graphql file holds type definitions rather than
+// operations.
+func isSDL(src string) bool {
+	return sdlFileRe.MatchString(src) && len(Operations(src)) == 0
+}
+
+// dedent removes the indentation common to all non-blank lines after the
+// first, which keeps template literal bodies readable.
+func dedent(doc string) string {
+	lines := strings.Split(doc, "\n")
+	indent := -1
+	for _, l := range lines[1:] {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return doc
+	}
+	for i := 1; i < len(lines); i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = strings.TrimLeft(lines[i], " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
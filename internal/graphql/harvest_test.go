@@ -0,0 +1,180 @@
+package graphql
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files, given by slash-separated path, under dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// harvestTree is a restored tree with the same query in two chunks, a
+// fragment, a vendored document, and schemas of each kind.
+var harvestTree = map[string]string{
+	"src/a.js":                  "import gql from 'graphql-tag';\nexport const ME = gql`\n  query Me {\n    me { id name }\n  }\n`;\n",
+	"src/b.ts":                  "const ME = \"query Me { me { id, name } }\";\nconst F = gql`fragment UserFields on User { id }`;\n",
+	"src/c.js":                  `fetch("/graphql",{body:JSON.stringify({query:"mutation Logout{logout}"})});` + "\n",
+	"node_modules/apollo/x.js":  "gql`query Vendor { v }`",
+	"schema/schema.graphql":     "type Query {\n  me: User\n}\n",
+	"schema/ops.gql":            "query FromFile { a }\n",
+	"schema/introspection.json": `{"data":{"__schema":{"queryType":{"name":"Query"},"types":[]}}}`,
+	"src/notes.md":              "query NotScanned { x }",
+}
+
+func TestHarvestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, harvestTree)
+
+	h, errs := HarvestDirectory(dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	want := []IndexEntry{
+		{Name: "FromFile", Type: "query", File: "schema/ops.gql", Line: 1},
+		{Name: "Me", Type: "query", File: "src/a.js", Line: 2, AlsoIn: []string{"src/b.ts"}},
+		{Name: "UserFields", Type: "fragment", File: "src/b.ts", Line: 2},
+		{Name: "Logout", Type: "mutation", File: "src/c.js", Line: 1},
+	}
+	if !reflect.DeepEqual(h.Operations, want) {
+		t.Errorf("Operations =\n%+v\nwant\n%+v", h.Operations, want)
+	}
+	if len(h.Documents) != 4 {
+		t.Errorf("got %d documents, want 4: %q", len(h.Documents), h.Documents)
+	}
+	wantSchemas := []SchemaEntry{
+		{Kind: "introspection", File: "schema/introspection.json", Line: 1},
+		{Kind: "sdl", File: "schema/schema.graphql", Line: 1},
+	}
+	if !reflect.DeepEqual(h.Schemas, wantSchemas) {
+		t.Errorf("Schemas = %+v, want %+v", h.Schemas, wantSchemas)
+	}
+}
+
+func TestHarvestWrite(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, harvestTree)
+	h, _ := HarvestDirectory(dir)
+	out := filepath.Join(t.TempDir(), "graphql")
+	if err := h.Write(out); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := os.ReadFile(filepath.Join(out, OperationsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every document is written once, dedented, and reads back as the
+	// same operations
+	if got := Operations(string(ops)); len(got) != len(h.Operations) {
+		t.Errorf("%s holds %d operation(s), want %d:\n%s", OperationsFile, len(got), len(h.Operations), ops)
+	}
+	if !strings.Contains(string(ops), "query Me {\n  me { id name }\n}") {
+		t.Errorf("%s was not dedented:\n%s", OperationsFile, ops)
+	}
+	if strings.Count(string(ops), "query Me") != 1 {
+		t.Errorf("%s repeats a document:\n%s", OperationsFile, ops)
+	}
+
+	var index Harvest
+	data, err := os.ReadFile(filepath.Join(out, IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.Operations, h.Operations) || index.SchemaVersion == "" {
+		t.Errorf("%s = %s", IndexFile, data)
+	}
+	if index.Schemas[0].Output != "introspection.json" || index.Schemas[1].Output != "schema.graphql" {
+		t.Errorf("schema outputs = %+v", index.Schemas)
+	}
+	introspection, err := os.ReadFile(filepath.Join(out, "introspection.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(introspection) {
+		t.Errorf("introspection.json is not JSON:\n%s", introspection)
+	}
+}
+
+func TestHarvestWriteIncremental(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "graphql")
+
+	first := t.TempDir()
+	writeTree(t, first, map[string]string{
+		"a.js":               "gql`query A { a }`",
+		"s.graphql":          "type Query { a: Int }",
+		"t.graphql":          "type Query { b: Int }",
+		"introspection.json": `{"__schema":{"queryType":{"name":"Query"},"types":[]}}`,
+	})
+	h, _ := HarvestDirectory(first)
+	if err := h.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, out, map[string]string{"notes.txt": "mine"})
+
+	// A second run with fewer schemas drops the outputs it no longer
+	// writes and keeps files it does not own
+	second := t.TempDir()
+	writeTree(t, second, map[string]string{"s.graphql": "type Query { a: Int }"})
+	h, _ = HarvestDirectory(second)
+	if err := h.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listDir(t, out), []string{IndexFile, "notes.txt", "schema.graphql"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after second Write: %v, want %v", got, want)
+	}
+
+	Remove(out)
+	if got, want := listDir(t, out), []string{"notes.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after Remove: %v, want %v", got, want)
+	}
+	os.Remove(filepath.Join(out, "notes.txt"))
+	Remove(out)
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("Remove left the empty directory: %v", err)
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"query A {\n    a\n  }", "query A {\n  a\n}"},
+		{"query A { a }", "query A { a }"},
+		{"query A {\n\t\ta\n\t}", "query A {\n\ta\n}"},
+		{"query A {\n\n    a\n  }", "query A {\n\n  a\n}"},
+	}
+	for _, tt := range tests {
+		if got := dedent(tt.in); got != tt.want {
+			t.Errorf("dedent(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
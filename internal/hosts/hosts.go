@@ -0,0 +1,433 @@
+// Package hosts inventories the absolute URLs and hostnames referenced in
+// JavaScript and classifies hosts that point at internal networks or cloud
+// storage.
+package hosts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/schema"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxScanSize caps how much of each file is scanned; large bundles are
+// truncated rather than skipped.
+const maxScanSize = 8 << 20
+
+// Output file names, relative to the domain directory.
+const (
+	URLsFile      = "urls.txt"
+	HostsFile     = "hosts.txt"
+	HostsJSONFile = "hosts.json"
+)
+
+// Cloud storage providers reported in Host.CloudStorage.
+const (
+	ProviderS3       = "s3"
+	ProviderGCS      = "gcs"
+	ProviderAzure    = "azure-blob"
+	ProviderSpaces   = "do-spaces"
+	ProviderR2       = "r2"
+	ProviderFirebase = "firebase-storage"
+)
+
+// scanExtensions are the file types scanned for URLs.
+var scanExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+	".vue": true, ".svelte": true,
+}
+
+// internalTLDs are suffixes that only resolve inside private networks.
+var internalTLDs = map[string]bool{
+	"corp": true, "internal": true, "intranet": true, "intra": true,
+	"local": true, "localdomain": true, "lan": true, "home": true,
+	"private": true, "priv": true,
+}
+
+// internalLabels mark an otherwise public name as internal-looking, as in
+// api.internal.example.com.
+var internalLabels = map[string]bool{
+	"internal": true, "intranet": true, "corp": true,
+}
+
+var (
+	// Absolute URLs. Stops at quotes, whitespace, template interpolation,
+	// and escapes so string and template literal boundaries end the match.
+	urlRe = regexp.MustCompile("\\b(?:https?|wss?|ftp|s3|gs)://[^\\s\"'`<>\\\\(){}\\[\\]$^|]+")
+
+	// Start of a data: URI; see blankDataURIs
+	dataURIRe = regexp.MustCompile(`data:[a-zA-Z]+/[a-zA-Z0-9.+\-]+[;,]`)
+
+	// Bare hostnames in string literals, e.g. "api.example.corp"
+	bareHostRe = regexp.MustCompile("[\"'`]((?:[a-z0-9](?:[a-z0-9\\-]{0,61}[a-z0-9])?\\.)+[a-z][a-z0-9\\-]{1,62})(?::\\d{2,5})?[\"'`/]")
+
+	// Cloud storage hosts; the first group, when present, is the bucket
+	storageHostRes = []struct {
+		provider string
+		re       *regexp.Regexp
+	}{
+		{ProviderS3, regexp.MustCompile(`^(?:(.+)\.)?s3(?:[.\-](?:dualstack\.)?[a-z0-9\-]+)*\.amazonaws\.com(?:\.cn)?$`)},
+		{ProviderGCS, regexp.MustCompile(`^(?:(.+)\.)?storage\.googleapis\.com$`)},
+		{ProviderFirebase, regexp.MustCompile(`^()firebasestorage\.googleapis\.com$`)},
+		{ProviderAzure, regexp.MustCompile(`^([a-z0-9]+)\.blob\.core\.windows\.net$`)},
+		{ProviderSpaces, regexp.MustCompile(`^(?:(.+)\.)?[a-z0-9]+\.digitaloceanspaces\.com$`)},
+		{ProviderR2, regexp.MustCompile(`^(?:(.+)\.)?[a-z0-9\-]+\.r2\.(?:cloudflarestorage\.com|dev)$`)},
+	}
+
+	// Bucket names in S3 and GCS paths are a single lowercase segment
+	bucketNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9.\-_]{1,61}[a-z0-9]$`)
+)
+
+// fileExtensions are final labels that make a dotted string a file name
+// rather than a hostname, even where they are also real TLDs.
+var fileExtensions = map[string]bool{
+	"js": true, "ts": true, "jsx": true, "tsx": true, "mjs": true, "cjs": true,
+	"css": true, "scss": true, "json": true, "map": true, "html": true, "htm": true,
+	"png": true, "jpg": true, "jpeg": true, "gif": true, "svg": true, "webp": true,
+	"ico": true, "woff": true, "woff2": true, "ttf": true, "eot": true, "otf": true,
+	"md": true, "txt": true, "zip": true, "mov": true, "mp4": true, "mp3": true,
+	"pdf": true, "sh": true, "py": true, "rs": true, "go": true, "vue": true,
+}
+
+// Host is a hostname with its classification.
+type Host struct {
+	Name         string   `json:"host"`
+	URLs         int      `json:"urls"`                    // Distinct URLs on this host
+	Internal     bool     `json:"internal,omitempty"`      // Internal-looking TLD or label
+	Local        bool     `json:"local,omitempty"`         // localhost, loopback, or RFC 1918 address
+	CloudStorage string   `json:"cloud_storage,omitempty"` // Provider, for storage endpoints
+	Buckets      []string `json:"buckets,omitempty"`       // Buckets or containers seen on this host
+
+	pathStyle bool // Storage endpoint that takes the bucket in the URL path
+}
+
+// Inventory is the set of URLs and hosts referenced by scanned files.
+type Inventory struct {
+	urls  map[string]bool
+	hosts map[string]*Host
+	seen  map[string]map[string]bool // Host -> URLs counted
+}
+
+// New returns an empty inventory.
+func New() *Inventory {
+	return &Inventory{
+		urls:  make(map[string]bool),
+		hosts: make(map[string]*Host),
+		seen:  make(map[string]map[string]bool),
+	}
+}
+
+// ScanDirectory adds the URLs and hosts referenced by the JavaScript files
+// under dir. Sourcemaps are not scanned; their sources are restored
+// separately and their webpack:// paths are not real URLs.
+func (inv *Inventory) ScanDirectory(dir string) []error {
+	var errs []error
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("walk error at %s: %w", p, err))
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !scanExtensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		content, err := readPrefix(p, maxScanSize)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", p, err))
+			return nil
+		}
+		if bytes.IndexByte(content, 0) != -1 {
+			return nil
+		}
+		inv.ScanContent(string(content))
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to walk directory: %w", err))
+	}
+	return errs
+}
+
+// ScanContent adds the URLs and hosts referenced in src.
+func (inv *Inventory) ScanContent(src string) {
+	src = blankDataURIs(src)
+
+	for _, raw := range urlRe.FindAllString(src, -1) {
+		inv.addURL(strings.TrimRight(raw, ".,:;!?*'\""))
+	}
+
+	for _, m := range bareHostRe.FindAllStringSubmatch(src, -1) {
+		if host := m[1]; plausibleHost(host) {
+			inv.host(host)
+		}
+	}
+}
+
+// addURL records an absolute URL and its host.
+func (inv *Inventory) addURL(raw string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return
+	}
+	name := strings.ToLower(u.Hostname())
+
+	switch u.Scheme {
+	case "s3", "gs":
+		if !bucketNameRe.MatchString(name) {
+			return
+		}
+		inv.urls[raw] = true
+		if u.Scheme == "s3" {
+			inv.bucket("s3.amazonaws.com", name, raw)
+		} else {
+			inv.bucket("storage.googleapis.com", name, raw)
+		}
+		return
+	}
+
+	if !validHost(name) {
+		return
+	}
+	inv.urls[raw] = true
+	h := inv.host(name)
+	inv.count(h, raw)
+
+	if !h.pathStyle {
+		return
+	}
+	// Path-style access puts the bucket in the first path segment, or
+	// after /v0/b/ for Firebase Storage
+	segs := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if h.CloudStorage == ProviderFirebase {
+		if len(segs) < 3 || segs[0] != "v0" || segs[1] != "b" {
+			return
+		}
+		segs = segs[2:]
+	}
+	if bucketNameRe.MatchString(segs[0]) {
+		addBucket(h, segs[0])
+	}
+}
+
+// bucket records a bucket addressed by an s3:// or gs:// URI under the
+// provider's canonical host.
+func (inv *Inventory) bucket(hostName, bucket, raw string) {
+	h := inv.host(hostName)
+	addBucket(h, bucket)
+	inv.count(h, raw)
+}
+
+// host returns the entry for name, creating and classifying it if needed.
+func (inv *Inventory) host(name string) *Host {
+	if h, ok := inv.hosts[name]; ok {
+		return h
+	}
+	h := classify(name)
+	inv.hosts[name] = h
+	return h
+}
+
+func (inv *Inventory) count(h *Host, raw string) {
+	if inv.seen[h.Name] == nil {
+		inv.seen[h.Name] = make(map[string]bool)
+	}
+	if !inv.seen[h.Name][raw] {
+		inv.seen[h.Name][raw] = true
+		h.URLs++
+	}
+}
+
+// URLs returns the distinct URLs, sorted.
+func (inv *Inventory) URLs() []string {
+	out := make([]string, 0, len(inv.urls))
+	for u := range inv.urls {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Hosts returns the hosts, sorted by name.
+func (inv *Inventory) Hosts() []Host {
+	out := make([]Host, 0, len(inv.hosts))
+	for _, h := range inv.hosts {
+		sort.Strings(h.Buckets)
+		out = append(out, *h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Write saves urls.txt, hosts.txt, and hosts.json to dir.
+func (inv *Inventory) Write(dir string) error {
+	urls := inv.URLs()
+	hosts := inv.Hosts()
+
+	names := make([]string, len(hosts))
+	for i, h := range hosts {
+		names[i] = h.Name
+	}
+
+	if err := writeLines(filepath.Join(dir, URLsFile), urls); err != nil {
+		return err
+	}
+	if err := writeLines(filepath.Join(dir, HostsFile), names); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema.Items(hosts)); err != nil {
+		return fmt.Errorf("failed to encode hosts: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, HostsJSONFile), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", HostsJSONFile, err)
+	}
+	return nil
+}
+
+// classify builds a Host entry with its flags set.
+func classify(name string) *Host {
+	h := &Host{Name: name}
+
+	if ip := net.ParseIP(strings.Trim(name, "[]")); ip != nil {
+		h.Local = ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+		return h
+	}
+	if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		h.Local = true
+		return h
+	}
+
+	labels := strings.Split(name, ".")
+	if internalTLDs[labels[len(labels)-1]] {
+		h.Internal = true
+	}
+	for _, l := range labels[:len(labels)-1] {
+		if internalLabels[l] {
+			h.Internal = true
+		}
+	}
+
+	for _, s := range storageHostRes {
+		if m := s.re.FindStringSubmatch(name); m != nil {
+			h.CloudStorage = s.provider
+			if m[1] != "" {
+				addBucket(h, m[1])
+			} else {
+				h.pathStyle = true
+			}
+			break
+		}
+	}
+	return h
+}
+
+// validHost rejects hosts built from string concatenation ("https://" + x)
+// and other fragments that are not real names.
+func validHost(name string) bool {
+	if net.ParseIP(name) != nil || name == "localhost" {
+		return true
+	}
+	if !strings.Contains(name, ".") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// plausibleHost decides whether a bare dotted string literal is a hostname.
+// Public names need a real TLD and at least three labels, which rules out
+// most property paths and file names; internal-looking names need only two.
+func plausibleHost(name string) bool {
+	labels := strings.Split(name, ".")
+	tld := labels[len(labels)-1]
+	if fileExtensions[tld] {
+		return false
+	}
+	if internalTLDs[tld] {
+		return len(labels) >= 2
+	}
+	if suffix, icann := publicsuffix.PublicSuffix(name); !icann || suffix == name {
+		return false
+	}
+	return len(labels) >= 3
+}
+
+func addBucket(h *Host, bucket string) {
+	for _, b := range h.Buckets {
+		if b == bucket {
+			return
+		}
+	}
+	h.Buckets = append(h.Buckets, bucket)
+}
+
+// blankDataURIs replaces data: URIs with spaces. Inline SVGs carry
+// namespace URLs in quotes of their own, so a URI inside a string literal
+// runs to the literal's closing quote rather than the first quote.
+func blankDataURIs(src string) string {
+	locs := dataURIRe.FindAllStringIndex(src, -1)
+	if locs == nil {
+		return src
+	}
+	b := []byte(src)
+	for _, loc := range locs {
+		end := len(b)
+		if q := loc[0] - 1; q >= 0 && (b[q] == '"' || b[q] == '\'' || b[q] == '`') {
+			if i := bytes.IndexByte(b[loc[1]:], b[q]); i != -1 {
+				end = loc[1] + i
+			}
+		} else if i := bytes.IndexAny(b[loc[1]:], " \t\n\"'`)"); i != -1 {
+			end = loc[1] + i
+		}
+		for i := loc[0]; i < end; i++ {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// readPrefix reads at most limit bytes of a file.
+func readPrefix(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit))
+}
+
+func writeLines(path string, lines []string) error {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package hosts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		urls  []string
+		hosts []string
+	}{
+		{
+			name:  "string and template literals",
+			src:   "fetch(\"https://api.example.com/v1/users?id=1\");\nconst u = `wss://live.example.com/socket/${id}`;",
+			urls:  []string{"https://api.example.com/v1/users?id=1", "wss://live.example.com/socket/"},
+			hosts: []string{"api.example.com", "live.example.com"},
+		},
+		{
+			name:  "trailing punctuation",
+			src:   "// See https://docs.example.com/guide. Or https://docs.example.com/faq, maybe",
+			urls:  []string{"https://docs.example.com/faq", "https://docs.example.com/guide"},
+			hosts: []string{"docs.example.com"},
+		},
+		{
+			name: "webpack pseudo-URLs",
+			src:  `var s = "webpack://app/./src/index.js", t = "webpack-internal:///./src/a.js";`,
+		},
+		{
+			name: "data URIs with namespace URLs",
+			src:  `a.src = "data:image/svg+xml;charset=utf-8,<svg xmlns='http://www.w3.org/2000/svg'></svg>";`,
+		},
+		{
+			name: "string concatenation",
+			src:  `var u = "https://" + host + "/api";`,
+		},
+		{
+			name:  "bare internal and public hosts",
+			src:   `var a = "vault.corp", b = "api.internal.example.com", c = "cdn.example.com", d = "app.bundle.js", e = "example.com";`,
+			hosts: []string{"api.internal.example.com", "cdn.example.com", "vault.corp"},
+		},
+		{
+			name:  "storage URIs",
+			src:   `var a = "s3://my-bucket/key", b = "gs://other-bucket/obj", c = "s3://${x}/key";`,
+			urls:  []string{"gs://other-bucket/obj", "s3://my-bucket/key"},
+			hosts: []string{"s3.amazonaws.com", "storage.googleapis.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := New()
+			inv.ScanContent(tt.src)
+			if got := inv.URLs(); !equalStrings(got, tt.urls) {
+				t.Errorf("URLs = %q, want %q", got, tt.urls)
+			}
+			var names []string
+			for _, h := range inv.Hosts() {
+				names = append(names, h.Name)
+			}
+			if !equalStrings(names, tt.hosts) {
+				t.Errorf("Hosts = %q, want %q", names, tt.hosts)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		want Host
+	}{
+		{"api.example.com", Host{Name: "api.example.com"}},
+		{"jenkins.corp", Host{Name: "jenkins.corp", Internal: true}},
+		{"db.internal.example.com", Host{Name: "db.internal.example.com", Internal: true}},
+		{"localhost", Host{Name: "localhost", Local: true}},
+		{"app.localhost", Host{Name: "app.localhost", Local: true}},
+		{"127.0.0.1", Host{Name: "127.0.0.1", Local: true}},
+		{"10.1.2.3", Host{Name: "10.1.2.3", Local: true}},
+		{"192.168.0.10", Host{Name: "192.168.0.10", Local: true}},
+		{"172.16.5.4", Host{Name: "172.16.5.4", Local: true}},
+		{"8.8.8.8", Host{Name: "8.8.8.8"}},
+		{"[::1]", Host{Name: "[::1]", Local: true}},
+		{"assets.s3.amazonaws.com", Host{Name: "assets.s3.amazonaws.com", CloudStorage: ProviderS3, Buckets: []string{"assets"}}},
+		{"logs.s3.us-west-2.amazonaws.com", Host{Name: "logs.s3.us-west-2.amazonaws.com", CloudStorage: ProviderS3, Buckets: []string{"logs"}}},
+		{"s3.amazonaws.com", Host{Name: "s3.amazonaws.com", CloudStorage: ProviderS3, pathStyle: true}},
+		{"media.storage.googleapis.com", Host{Name: "media.storage.googleapis.com", CloudStorage: ProviderGCS, Buckets: []string{"media"}}},
+		{"firebasestorage.googleapis.com", Host{Name: "firebasestorage.googleapis.com", CloudStorage: ProviderFirebase, pathStyle: true}},
+		{"acct.blob.core.windows.net", Host{Name: "acct.blob.core.windows.net", CloudStorage: ProviderAzure, Buckets: []string{"acct"}}},
+		{"files.nyc3.digitaloceanspaces.com", Host{Name: "files.nyc3.digitaloceanspaces.com", CloudStorage: ProviderSpaces, Buckets: []string{"files"}}},
+		{"pub-abc.r2.dev", Host{Name: "pub-abc.r2.dev", CloudStorage: ProviderR2, pathStyle: true}},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.name); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("classify(%q) = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestPathStyleBuckets(t *testing.T) {
+	inv := New()
+	inv.ScanContent(`
+		a = "https://s3.amazonaws.com/first-bucket/img.png";
+		b = "https://s3.amazonaws.com/first-bucket/other.png";
+		c = "https://firebasestorage.googleapis.com/v0/b/app.appspot.com/o/x";
+		d = "https://firebasestorage.googleapis.com/other";
+	`)
+	got := make(map[string]Host)
+	for _, h := range inv.Hosts() {
+		got[h.Name] = h
+	}
+	if h := got["s3.amazonaws.com"]; !reflect.DeepEqual(h.Buckets, []string{"first-bucket"}) || h.URLs != 2 {
+		t.Errorf("s3.amazonaws.com = %+v", h)
+	}
+	if h := got["firebasestorage.googleapis.com"]; !reflect.DeepEqual(h.Buckets, []string{"app.appspot.com"}) || h.URLs != 2 {
+		t.Errorf("firebasestorage.googleapis.com = %+v", h)
+	}
+}
+
+func TestScanDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"static/js/main.js":     `fetch("https://api.example.com/a")`,
+		"static/js/main.js.map": `{"sources":["webpack://app/./src/a.js"],"sourcesContent":["fetch('https://mapped.example.com/')"]}`,
+		"src/App.tsx":           "const x = 'https://api.example.com/a'; const y = \"vault.corp\";",
+		"index.html":            `<a href="https://html.example.com/">`,
+		"vendor.bin.js":         "https://binary.example.com/\x00",
+		".git/hooks/a.js":       `"https://git.example.com/"`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inv := New()
+	if errs := inv.ScanDirectory(dir); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got, want := inv.URLs(), []string{"https://api.example.com/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs = %q, want %q", got, want)
+	}
+	hosts := inv.Hosts()
+	if len(hosts) != 2 || hosts[0].Name != "api.example.com" || hosts[0].URLs != 1 || hosts[1].Name != "vault.corp" {
+		t.Errorf("Hosts = %+v", hosts)
+	}
+}
+
+func TestScanDirectoryCap(t *testing.T) {
+	dir := t.TempDir()
+	pad := strings.Repeat(" ", maxScanSize)
+	content := `"https://early.example.com/"` + pad + `"https://late.example.com/"`
+	if err := os.WriteFile(filepath.Join(dir, "big.js"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inv := New()
+	inv.ScanDirectory(dir)
+	if got, want := inv.URLs(), []string{"https://early.example.com/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs = %q, want %q", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	inv := New()
+	inv.ScanContent(`a = "https://b.example.com/x?a=1&b=2"; b = "https://a.example.com/"; c = "jenkins.corp";`)
+	dir := t.TempDir()
+	if err := inv.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if got, want := string(read(URLsFile)), "https://a.example.com/\nhttps://b.example.com/x?a=1&b=2\n"; got != want {
+		t.Errorf("%s = %q, want %q", URLsFile, got, want)
+	}
+	if got, want := string(read(HostsFile)), "a.example.com\nb.example.com\njenkins.corp\n"; got != want {
+		t.Errorf("%s = %q, want %q", HostsFile, got, want)
+	}
+
+	data := read(HostsJSONFile)
+	var got []Host
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Host{
+		{Name: "a.example.com", URLs: 1},
+		{Name: "b.example.com", URLs: 1},
+		{Name: "jenkins.corp", Internal: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %s", HostsJSONFile, data)
+	}
+
+	// An empty inventory writes an empty array, not null
+	if err := New().Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(read(HostsJSONFile))); got != "[]" {
+		t.Errorf("empty %s = %s", HostsJSONFile, got)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
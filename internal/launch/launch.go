@@ -0,0 +1,163 @@
+// Package launch opens output in the programs the user has chosen for it:
+// a directory in the OS file manager, a file in $VISUAL or $EDITOR. The
+// commands run directly, never through a shell, so paths need no quoting.
+package launch
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Launcher starts a command. Start runs programs that outlive the call,
+// such as a file manager; Run waits for one attached to the terminal, such
+// as a terminal editor.
+type Launcher interface {
+	Start(name string, args ...string) error
+	Run(name string, args ...string) error
+}
+
+// Default runs commands as child processes of dejank.
+var Default Launcher = execLauncher{}
+
+type execLauncher struct{}
+
+func (execLauncher) Start(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the process when it exits; its status is of no interest, as
+	// some openers (explorer) exit non-zero on success
+	go cmd.Wait()
+	return nil
+}
+
+func (execLauncher) Run(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// Open opens dir in the OS file manager: xdg-open on Linux and the BSDs,
+// open on macOS, and explorer on Windows.
+func Open(l Launcher, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	name, args := openCommand(abs)
+	if err := l.Start(name, args...); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Edit opens path in $VISUAL, or $EDITOR if that is unset, at line if it
+// is positive and the editor is one known to take a line number. It waits
+// for the editor to exit.
+func Edit(l Launcher, path string, line int) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		return fmt.Errorf("neither $VISUAL nor $EDITOR is set")
+	}
+	argv, err := SplitCommand(editor)
+	if err != nil {
+		return fmt.Errorf("invalid editor %q: %w", editor, err)
+	}
+	if len(argv) == 0 {
+		return fmt.Errorf("empty editor command")
+	}
+	argv = append(argv, editorArgs(argv[0], path, line)...)
+	if err := l.Run(argv[0], argv[1:]...); err != nil {
+		return fmt.Errorf("%s: %w", argv[0], err)
+	}
+	return nil
+}
+
+// editorArgs returns the arguments that open path at line in editor.
+func editorArgs(editor, path string, line int) []string {
+	if line <= 0 {
+		return []string{path}
+	}
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(editor)), ".exe")
+	switch name {
+	case "vi", "vim", "nvim", "nano", "emacs", "emacsclient", "micro", "kak", "hx", "mg", "joe":
+		return []string{"+" + strconv.Itoa(line), path}
+	case "code", "code-insiders", "codium", "cursor":
+		return []string{"--goto", path + ":" + strconv.Itoa(line)}
+	case "subl", "zed":
+		return []string{path + ":" + strconv.Itoa(line)}
+	}
+	return []string{path}
+}
+
+// SplitCommand splits an editor setting such as `code --wait` or
+// `"C:\Program Files\Vim\gvim.exe" -f` into words the way a POSIX shell
+// would, honoring single quotes, double quotes, and backslash escapes
+// outside single quotes, but without expanding anything.
+func SplitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && (quote == '"' || quote == 0) && !isWindowsPath(s):
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// isWindowsPath reports whether s looks like it names a Windows program
+// by drive letter, where backslashes separate path elements instead of
+// escaping.
+func isWindowsPath(s string) bool {
+	s = strings.TrimLeft(s, `"' `)
+	return len(s) >= 3 && s[1] == ':' && s[2] == '\\'
+}
@@ -0,0 +1,118 @@
+package launch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeLauncher records the commands it is asked to run instead of running
+// them.
+type fakeLauncher struct {
+	method string // "start" or "run"
+	argv   []string
+	err    error
+}
+
+func (f *fakeLauncher) Start(name string, args ...string) error {
+	f.method, f.argv = "start", append([]string{name}, args...)
+	return f.err
+}
+
+func (f *fakeLauncher) Run(name string, args ...string) error {
+	f.method, f.argv = "run", append([]string{name}, args...)
+	return f.err
+}
+
+func TestOpen(t *testing.T) {
+	var l fakeLauncher
+	if err := Open(&l, "out"); err != nil {
+		t.Fatal(err)
+	}
+	abs, _ := filepath.Abs("out")
+	name, args := openCommand(abs)
+	want := append([]string{name}, args...)
+	if l.method != "start" || !reflect.DeepEqual(l.argv, want) {
+		t.Errorf("Open ran %s %q, want start %q", l.method, l.argv, want)
+	}
+
+	l.err = errors.New("not found")
+	if err := Open(&l, "out"); !errors.Is(err, l.err) {
+		t.Errorf("Open error = %v, want the launcher's", err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		name    string
+		visual  string
+		editor  string
+		line    int
+		want    []string
+		wantErr bool
+	}{
+		{"editor", "", "vim", 0, []string{"vim", "a.js"}, false},
+		{"visual first", "nano", "vim", 0, []string{"nano", "a.js"}, false},
+		{"line", "", "vim", 12, []string{"vim", "+12", "a.js"}, false},
+		{"vscode line", "", "code --wait", 3, []string{"code", "--wait", "--goto", "a.js:3"}, false},
+		{"sublime line", "", "subl", 3, []string{"subl", "a.js:3"}, false},
+		{"unknown editor ignores line", "", "ed", 3, []string{"ed", "a.js"}, false},
+		{"quoted path", "", `"/opt/My Editor/bin/vim" -f`, 5, []string{"/opt/My Editor/bin/vim", "-f", "+5", "a.js"}, false},
+		{"windows path", "", `C:\Vim\vim.exe`, 0, []string{`C:\Vim\vim.exe`, "a.js"}, false},
+		{"unset", "", "", 0, nil, true},
+		{"blank", "", "  ", 0, nil, true},
+		{"unterminated quote", "", `"vim`, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+			var l fakeLauncher
+			err := Edit(&l, "a.js", tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Edit error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if l.argv != nil {
+					t.Errorf("Edit ran %q after failing", l.argv)
+				}
+				return
+			}
+			if l.method != "run" || !reflect.DeepEqual(l.argv, tt.want) {
+				t.Errorf("Edit ran %s %q, want run %q", l.method, l.argv, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"code --wait", []string{"code", "--wait"}, false},
+		{"  vim\t-f  ", []string{"vim", "-f"}, false},
+		{`"/Applications/Sublime Text.app/subl" -w`, []string{"/Applications/Sublime Text.app/subl", "-w"}, false},
+		{`'it''s' "a \"b\""`, []string{"its", `a "b"`}, false},
+		{`emacs -nw\ x`, []string{"emacs", "-nw x"}, false},
+		{`'\n'`, []string{`\n`}, false},
+		{`""`, []string{""}, false},
+		{`"C:\Program Files\Vim\gvim.exe" -f`, []string{`C:\Program Files\Vim\gvim.exe`, "-f"}, false},
+		{`"vim`, nil, true},
+		{`vim 'x`, nil, true},
+		{`vim\`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := SplitCommand(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitCommand(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
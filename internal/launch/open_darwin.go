@@ -0,0 +1,6 @@
+package launch
+
+// openCommand returns the command that opens dir in Finder.
+func openCommand(dir string) (string, []string) {
+	return "open", []string{dir}
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package launch
+
+// openCommand returns the command that opens dir in the desktop's file
+// manager.
+func openCommand(dir string) (string, []string) {
+	return "xdg-open", []string{dir}
+}
@@ -0,0 +1,6 @@
+package launch
+
+// openCommand returns the command that opens dir in Explorer.
+func openCommand(dir string) (string, []string) {
+	return "explorer", []string{dir}
+}
@@ -0,0 +1,254 @@
+package licenses
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	cdxFormat  = "CycloneDX"
+	cdxVersion = "1.5"
+	cdxSchema  = "http://cyclonedx.org/schema/bom-1.5.schema.json"
+
+	// targetRef is the bom-ref of the top-level component, the scanned site.
+	targetRef = "target"
+
+	// Properties that mark what a component's data rests on.
+	propVersion  = "dejank:version"  // "unknown" when no version was recovered
+	propRestored = "dejank:restored" // Whether vendor sources were restored for the package
+	propSource   = "dejank:license_source"
+)
+
+type cdxBOM struct {
+	Schema       string          `json:"$schema"`
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type               string           `json:"type"`
+	BOMRef             string           `json:"bom-ref,omitempty"`
+	Group              string           `json:"group,omitempty"`
+	Name               string           `json:"name"`
+	Version            string           `json:"version,omitempty"`
+	PURL               string           `json:"purl,omitempty"`
+	Licenses           []cdxLicense     `json:"licenses,omitempty"`
+	ExternalReferences []cdxExternalRef `json:"externalReferences,omitempty"`
+	Evidence           *cdxEvidence     `json:"evidence,omitempty"`
+	Properties         []cdxProperty    `json:"properties,omitempty"`
+}
+
+// cdxLicense is one entry of a component's licenses: a named license, or
+// a single SPDX expression for a choice or conjunction.
+type cdxLicense struct {
+	License    *cdxLicenseName `json:"license,omitempty"`
+	Expression string          `json:"expression,omitempty"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cdxExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxEvidence struct {
+	Occurrences []cdxOccurrence `json:"occurrences"`
+}
+
+type cdxOccurrence struct {
+	Location string `json:"location"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// SBOM assembles a CycloneDX 1.5 bill of materials from the inventories of
+// one or more domain directories. The scanned site is the top-level
+// component and every package found is a library it depends on.
+type SBOM struct {
+	target      string
+	toolVersion string
+	components  map[string]*cdxComponent // By bom-ref
+}
+
+// NewSBOM starts a bill of materials for target, the URL or path a run
+// was given, written by dejank toolVersion.
+func NewSBOM(target, toolVersion string) *SBOM {
+	return &SBOM{target: target, toolVersion: toolVersion, components: make(map[string]*cdxComponent)}
+}
+
+// Add adds the packages of inv. Evidence file paths are joined to
+// sourcesDir, the slash-separated location of the restored_sources the
+// inventory was scanned from, so they can be found from the document.
+// A package already added from another directory gains the new evidence.
+func (b *SBOM) Add(inv *Inventory, sourcesDir string) {
+	for _, pkg := range inv.Packages {
+		ref := npmPURL(pkg.Name, pkg.Version)
+		c, ok := b.components[ref]
+		if !ok {
+			c = newComponent(pkg, ref)
+			b.components[ref] = c
+		}
+		for _, e := range pkg.Evidence {
+			loc := cdxOccurrence{Location: path.Join(sourcesDir, e.File)}
+			if c.Evidence == nil {
+				c.Evidence = &cdxEvidence{}
+			}
+			if !containsOccurrence(c.Evidence.Occurrences, loc) {
+				c.Evidence.Occurrences = append(c.Evidence.Occurrences, loc)
+			}
+		}
+	}
+}
+
+// Len returns the number of components added.
+func (b *SBOM) Len() int {
+	return len(b.components)
+}
+
+// Write saves the bill of materials as indented JSON.
+func (b *SBOM) Write(filename string) error {
+	serial, err := newUUID()
+	if err != nil {
+		return err
+	}
+
+	refs := make([]string, 0, len(b.components))
+	for ref := range b.components {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	components := make([]cdxComponent, 0, len(refs))
+	for _, ref := range refs {
+		components = append(components, *b.components[ref])
+	}
+
+	target := cdxComponent{Type: "application", BOMRef: targetRef, Name: b.target}
+	if u, err := url.Parse(b.target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		target.ExternalReferences = []cdxExternalRef{{Type: "website", URL: b.target}}
+	}
+
+	bom := cdxBOM{
+		Schema:       cdxSchema,
+		BOMFormat:    cdxFormat,
+		SpecVersion:  cdxVersion,
+		SerialNumber: "urn:uuid:" + serial,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    "dejank",
+				Version: b.toolVersion,
+				ExternalReferences: []cdxExternalRef{
+					{Type: "vcs", URL: "https://github.com/thesavant42/dejank"},
+				},
+			}}},
+			Component: target,
+		},
+		Components:   components,
+		Dependencies: []cdxDependency{{Ref: targetRef, DependsOn: refs}},
+	}
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SBOM: %w", err)
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// newComponent converts a package to a library component. A package whose
+// version wasn't recovered gets a name-only purl and is marked with
+// dejank:version=unknown, so it isn't matched against a guessed version.
+func newComponent(pkg Package, ref string) *cdxComponent {
+	c := &cdxComponent{Type: "library", BOMRef: ref, Name: pkg.Name, Version: pkg.Version, PURL: ref}
+	if scope, name, ok := strings.Cut(pkg.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+		c.Group, c.Name = scope, name
+	}
+
+	switch {
+	case pkg.License == "":
+	case strings.Contains(pkg.License, " OR ") || strings.Contains(pkg.License, " AND "):
+		c.Licenses = []cdxLicense{{Expression: pkg.License}}
+	default:
+		// Banner-derived names such as "GPL" or "BSD" aren't SPDX
+		// identifiers, so licenses are given by name
+		c.Licenses = []cdxLicense{{License: &cdxLicenseName{Name: pkg.License}}}
+	}
+
+	version := pkg.Version
+	if version == "" {
+		version = "unknown"
+	}
+	c.Properties = []cdxProperty{
+		{Name: propVersion, Value: version},
+		{Name: propRestored, Value: fmt.Sprint(pkg.Restored)},
+	}
+	if len(pkg.Evidence) > 0 {
+		c.Properties = append(c.Properties, cdxProperty{Name: propSource, Value: pkg.Evidence[0].Source})
+	}
+	return c
+}
+
+// npmPURL returns the package URL of an npm package, with the version
+// when it is known. The @ of a scope is percent-encoded as the purl spec
+// requires.
+func npmPURL(name, version string) string {
+	purl := "pkg:npm/" + strings.Replace(name, "@", "%40", 1)
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+	return purl
+}
+
+func containsOccurrence(list []cdxOccurrence, o cdxOccurrence) bool {
+	for _, have := range list {
+		if have == o {
+			return true
+		}
+	}
+	return false
+}
+
+// newUUID returns a random (version 4) UUID for the serial number.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
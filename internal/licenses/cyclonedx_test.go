@@ -0,0 +1,91 @@
+package licenses
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// testSBOM writes a bill of materials covering each kind of license entry
+// and returns its content.
+func testSBOM(t *testing.T) []byte {
+	t.Helper()
+	b := NewSBOM("https://example.com/", "1.2.3")
+	b.Add(&Inventory{Packages: []Package{
+		{Name: "react", Version: "18.2.0", License: "MIT", Restored: true, Evidence: []Evidence{
+			{Source: SourcePackageJSON, File: "node_modules/react/package.json", License: "MIT"},
+		}},
+		{Name: "@scope/pkg", Version: "1.0.0-beta+1", License: "(MIT OR Apache-2.0)", Restored: true, Evidence: []Evidence{
+			{Source: SourceSPDX, File: "node_modules/@scope/pkg/index.js", License: "(MIT OR Apache-2.0)"},
+		}},
+		{Name: "unknown-version", License: "GPL"},
+		{Name: "no-license", Version: "2.0.0"},
+	}}, "example.com/restored_sources")
+	b.Add(&Inventory{Packages: []Package{
+		{Name: "react", Version: "18.2.0", License: "MIT", Restored: true, Evidence: []Evidence{
+			{Source: SourcePackageJSON, File: "node_modules/react/package.json", License: "MIT"},
+		}},
+	}}, "cdn.example.com/restored_sources")
+
+	path := filepath.Join(t.TempDir(), "sbom.cdx.json")
+	if err := b.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// validateBOM checks a CycloneDX document against the 1.5 schema.
+func validateBOM(t *testing.T, doc []byte) []gojsonschema.ResultError {
+	t.Helper()
+	schema, err := filepath.Abs(filepath.Join("testdata", "bom-1.5.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewReferenceLoader("file://"+filepath.ToSlash(schema)),
+		gojsonschema.NewBytesLoader(doc),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Errors()
+}
+
+func TestSBOMGolden(t *testing.T) {
+	// The serial number and timestamp differ on every run
+	got := testSBOM(t)
+	got = regexp.MustCompile(`"urn:uuid:[0-9a-f-]+"`).ReplaceAll(got, []byte(`"urn:uuid:00000000-0000-4000-8000-000000000000"`))
+	got = regexp.MustCompile(`"timestamp": "[^"]+"`).ReplaceAll(got, []byte(`"timestamp": "2006-01-02T15:04:05Z"`))
+
+	want, err := os.ReadFile(filepath.Join("testdata", "sbom.cdx.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("SBOM differs from testdata/sbom.cdx.json:\n%s", got)
+	}
+	for _, e := range validateBOM(t, want) {
+		t.Errorf("testdata/sbom.cdx.json: %s", e)
+	}
+}
+
+func TestSBOMSchema(t *testing.T) {
+	doc := testSBOM(t)
+	for _, e := range validateBOM(t, doc) {
+		t.Error(e)
+	}
+
+	// The schema is strict enough to catch a malformed component
+	bad := bytes.Replace(doc, []byte(`"type": "library"`), []byte(`"type": "package"`), 1)
+	if len(validateBOM(t, bad)) == 0 {
+		t.Error("a component of an unknown type passed validation")
+	}
+}
@@ -0,0 +1,396 @@
+// Package licenses builds a license inventory of the third-party packages
+// in restored sources from package.json files, SPDX tags, and banners.
+package licenses
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/scaffold"
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+// File is the inventory written to the domain directory.
+const File = "licenses.json"
+
+// headerSize is how much of each vendor file is searched for a banner.
+const headerSize = 16 << 10
+
+// License families used in the summary.
+const (
+	FamilyMIT     = "MIT"
+	FamilyApache  = "Apache"
+	FamilyBSD     = "BSD"
+	FamilyISC     = "ISC"
+	FamilyGPL     = "GPL" // GPL, LGPL, and AGPL
+	FamilyMPL     = "MPL"
+	FamilyOther   = "other"
+	FamilyUnknown = "unknown"
+)
+
+// Copyleft strengths reported in Package.Copyleft.
+const (
+	CopyleftStrong = "strong" // GPL, AGPL: obligations reach the combined work
+	CopyleftWeak   = "weak"   // LGPL, MPL, EPL, CDDL: obligations stay with the library
+)
+
+// Evidence sources, strongest first.
+const (
+	SourcePackageJSON = "package.json"
+	SourceSPDX        = "spdx"
+	SourceTag         = "@license"
+	SourceBanner      = "banner"
+)
+
+var sourceRank = map[string]int{SourcePackageJSON: 0, SourceSPDX: 1, SourceTag: 2, SourceBanner: 3}
+
+var (
+	spdxRe       = regexp.MustCompile(`(?m)SPDX-License-Identifier:[ \t]*([A-Za-z0-9.+\-() ]+?)[ \t]*(?:\*/|-->|\r?$)`)
+	licenseTagRe = regexp.MustCompile(`@license\s+([A-Za-z][A-Za-z0-9.+\-]*(?:\s+(?:OR|AND)\s+[A-Za-z][A-Za-z0-9.+\-]*)*)`)
+)
+
+// bannerTable maps distinctive license text to an SPDX identifier. More
+// specific phrases come first: "Lesser General Public" before "General
+// Public".
+var bannerTable = []struct {
+	re *regexp.Regexp
+	id string
+}{
+	{regexp.MustCompile(`(?i)GNU Affero General Public License`), "AGPL-3.0"},
+	{regexp.MustCompile(`(?i)GNU Lesser General Public License`), "LGPL"},
+	{regexp.MustCompile(`(?i)GNU Library General Public License`), "LGPL"},
+	{regexp.MustCompile(`(?i)GNU General Public License,? (?:as published[^.]*)?version 3`), "GPL-3.0"},
+	{regexp.MustCompile(`(?i)GNU General Public License,? (?:as published[^.]*)?version 2`), "GPL-2.0"},
+	{regexp.MustCompile(`(?i)GNU General Public License`), "GPL"},
+	{regexp.MustCompile(`(?i)Mozilla Public License,? (?:v\.?|version )?2\.0`), "MPL-2.0"},
+	{regexp.MustCompile(`(?i)Eclipse Public License`), "EPL"},
+	{regexp.MustCompile(`(?i)Apache License,? Version 2\.0`), "Apache-2.0"},
+	{regexp.MustCompile(`(?i)(?:released|licensed|available) under (?:the )?MIT`), "MIT"},
+	{regexp.MustCompile(`(?i)Permission is hereby granted, free of charge`), "MIT"},
+	{regexp.MustCompile(`(?i)Permission to use, copy, modify, and/or distribute this software for any\s+purpose`), "ISC"},
+	{regexp.MustCompile(`(?i)Redistribution and use in source and binary forms`), "BSD"},
+	{regexp.MustCompile(`(?i)free and unencumbered software released into the public domain`), "Unlicense"},
+	{regexp.MustCompile(`(?i)(?:released|licensed) under (?:the )?(?:BSD|ISC|Apache|MPL)`), ""}, // Resolved from the match
+}
+
+// Evidence is one place a package's license was found.
+type Evidence struct {
+	Source  string `json:"source"` // package.json, spdx, @license, or banner
+	File    string `json:"file"`
+	License string `json:"license"`
+}
+
+// Package is the license determination for one third-party package.
+type Package struct {
+	Name     string     `json:"name"`
+	Version  string     `json:"version,omitempty"`
+	License  string     `json:"license"` // SPDX identifier or expression; "" if unknown
+	Family   string     `json:"family"`
+	Copyleft string     `json:"copyleft,omitempty"` // strong or weak
+	Restored bool       `json:"restored"`           // Vendor sources were restored for it
+	Evidence []Evidence `json:"evidence,omitempty"`
+}
+
+// Inventory is the content of licenses.json.
+type Inventory struct {
+	schema.Versioned
+	Summary  map[string]int `json:"summary"`  // Packages per license family
+	Copyleft []string       `json:"copyleft"` // Names of copyleft packages
+	Packages []Package      `json:"packages"`
+}
+
+// Scan builds the inventory for a restored_sources directory. Every
+// dependency the scaffold inference knows about is listed, including
+// packages that were only imported; those without restored sources are
+// reported with an unknown license.
+func Scan(root string) (*Inventory, error) {
+	inf, err := scaffold.Infer(root)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make(map[string]*Package)
+	get := func(name string) *Package {
+		if p, ok := pkgs[name]; ok {
+			return p
+		}
+		p := &Package{Name: name}
+		if v := inf.Dependencies[name]; v != "*" {
+			p.Version = v
+		}
+		pkgs[name] = p
+		return p
+	}
+	for name := range inf.Dependencies {
+		get(name)
+	}
+
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		name, inner, ok := packageOf(rel)
+		if !ok {
+			return nil
+		}
+		pkg := get(name)
+		pkg.Restored = true
+
+		if inner == "package.json" {
+			if id := packageJSONLicense(p); id != "" {
+				addEvidence(pkg, Evidence{SourcePackageJSON, rel, id})
+			}
+			return nil
+		}
+		for _, e := range scanHeader(p) {
+			e.File = rel
+			addEvidence(pkg, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{Summary: make(map[string]int), Copyleft: []string{}}
+	for _, pkg := range pkgs {
+		decide(pkg)
+		inv.Summary[pkg.Family]++
+		if pkg.Copyleft != "" {
+			inv.Copyleft = append(inv.Copyleft, pkg.Name)
+		}
+		inv.Packages = append(inv.Packages, *pkg)
+	}
+	sort.Strings(inv.Copyleft)
+	sort.Slice(inv.Packages, func(i, j int) bool { return inv.Packages[i].Name < inv.Packages[j].Name })
+	return inv, nil
+}
+
+// Write saves the inventory as indented JSON.
+func (inv *Inventory) Write(path string) error {
+	inv.Versioned = schema.Current()
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode licenses: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Family classifies an SPDX identifier or expression. For a choice ("MIT OR
+// GPL-3.0") the first alternative names the family.
+func Family(id string) string {
+	id = strings.Trim(strings.TrimSpace(id), "()")
+	if id == "" {
+		return FamilyUnknown
+	}
+	first := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(id))[0]
+	upper := strings.ToUpper(first)
+	switch {
+	case upper == "MIT" || strings.HasPrefix(upper, "MIT-"):
+		return FamilyMIT
+	case strings.HasPrefix(upper, "APACHE"):
+		return FamilyApache
+	case strings.HasPrefix(upper, "BSD") || upper == "0BSD":
+		return FamilyBSD
+	case upper == "ISC":
+		return FamilyISC
+	case strings.Contains(upper, "GPL"):
+		return FamilyGPL
+	case strings.HasPrefix(upper, "MPL"):
+		return FamilyMPL
+	}
+	return FamilyOther
+}
+
+// Copyleft reports the copyleft strength of an SPDX identifier or
+// expression, or "" for permissive licenses. A choice is only copyleft if
+// every alternative is; a conjunction is if any part is.
+func Copyleft(id string) string {
+	id = strings.NewReplacer("(", " ", ")", " ").Replace(id)
+	strength := ""
+	for _, alt := range strings.Split(id, " OR ") {
+		s := ""
+		for _, part := range strings.Split(alt, " AND ") {
+			s = stronger(s, copyleftOf(strings.TrimSpace(part)))
+		}
+		if s == "" {
+			return ""
+		}
+		if strength == "" || s == CopyleftWeak {
+			strength = s
+		}
+	}
+	return strength
+}
+
+func copyleftOf(id string) string {
+	upper := strings.ToUpper(id)
+	switch {
+	case strings.HasPrefix(upper, "LGPL"):
+		return CopyleftWeak
+	case strings.HasPrefix(upper, "GPL") || strings.HasPrefix(upper, "AGPL"):
+		return CopyleftStrong
+	case strings.HasPrefix(upper, "MPL") || strings.HasPrefix(upper, "EPL") ||
+		strings.HasPrefix(upper, "CDDL") || strings.HasPrefix(upper, "EUPL") ||
+		strings.HasPrefix(upper, "OSL") || strings.HasPrefix(upper, "CC-BY-SA"):
+		return CopyleftWeak
+	}
+	return ""
+}
+
+func stronger(a, b string) string {
+	if a == CopyleftStrong || b == CopyleftStrong {
+		return CopyleftStrong
+	}
+	if a == CopyleftWeak || b == CopyleftWeak {
+		return CopyleftWeak
+	}
+	return ""
+}
+
+// decide picks a package's license from its strongest evidence and fills in
+// the family and copyleft flag. Any copyleft evidence wins over permissive
+// evidence of the same rank, since a bundled GPL file taints the package.
+func decide(pkg *Package) {
+	sort.SliceStable(pkg.Evidence, func(i, j int) bool {
+		return sourceRank[pkg.Evidence[i].Source] < sourceRank[pkg.Evidence[j].Source]
+	})
+	if len(pkg.Evidence) > 0 {
+		best := pkg.Evidence[0]
+		for _, e := range pkg.Evidence[1:] {
+			if e.Source == best.Source && Copyleft(e.License) != "" && Copyleft(best.License) == "" {
+				best = e
+			}
+		}
+		pkg.License = best.License
+	}
+	pkg.Family = Family(pkg.License)
+	pkg.Copyleft = Copyleft(pkg.License)
+}
+
+// addEvidence records one piece of evidence per distinct source and license.
+func addEvidence(pkg *Package, e Evidence) {
+	for _, have := range pkg.Evidence {
+		if have.Source == e.Source && have.License == e.License {
+			return
+		}
+	}
+	pkg.Evidence = append(pkg.Evidence, e)
+}
+
+// scanHeader looks for an SPDX tag, an @license tag, or banner text in the
+// start of a file.
+func scanHeader(path string) []Evidence {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, headerSize))
+	if err != nil || bytes.IndexByte(head, 0) != -1 {
+		return nil
+	}
+	return scanText(string(head))
+}
+
+// scanText is scanHeader over text already read.
+func scanText(text string) []Evidence {
+	var out []Evidence
+	for _, m := range spdxRe.FindAllStringSubmatch(text, -1) {
+		out = append(out, Evidence{Source: SourceSPDX, License: strings.TrimSpace(m[1])})
+	}
+	for _, m := range licenseTagRe.FindAllStringSubmatch(text, -1) {
+		// "@license React" and similar product names are not identifiers
+		if Family(m[1]) != FamilyOther || Copyleft(m[1]) != "" {
+			out = append(out, Evidence{Source: SourceTag, License: m[1]})
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	for _, b := range bannerTable {
+		m := b.re.FindString(text)
+		if m == "" {
+			continue
+		}
+		id := b.id
+		if id == "" {
+			id = lastWord(m)
+		}
+		return []Evidence{{Source: SourceBanner, License: id}}
+	}
+	return nil
+}
+
+// packageJSONLicense reads the license from a restored package.json,
+// accepting the modern string form and the deprecated object and array
+// forms.
+func packageJSONLicense(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		License  json.RawMessage `json:"license"`
+		Licenses []struct {
+			Type string `json:"type"`
+		} `json:"licenses"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(pkg.License, &s) == nil && s != "" {
+		return s
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(pkg.License, &obj) == nil && obj.Type != "" {
+		return obj.Type
+	}
+	var types []string
+	for _, l := range pkg.Licenses {
+		if l.Type != "" {
+			types = append(types, l.Type)
+		}
+	}
+	if len(types) > 0 {
+		return strings.Join(types, " OR ")
+	}
+	return ""
+}
+
+// packageOf splits a path inside node_modules into the innermost package
+// name and the path within the package.
+func packageOf(rel string) (name, inner string, ok bool) {
+	i := strings.LastIndex("/"+rel, "/node_modules/")
+	if i == -1 {
+		return "", "", false
+	}
+	parts := strings.SplitN(rel[i+len("node_modules/"):], "/", 3)
+	if strings.HasPrefix(parts[0], "@") {
+		if len(parts) < 3 {
+			return "", "", false
+		}
+		return parts[0] + "/" + parts[1], parts[2], true
+	}
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	return parts[0], strings.Join(parts[1:], "/"), true
+}
+
+func lastWord(s string) string {
+	fields := strings.Fields(s)
+	return fields[len(fields)-1]
+}
@@ -0,0 +1,43 @@
+package licenses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanTextSPDX(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"line comment", "// SPDX-License-Identifier: GPL-3.0\nmodule.exports = 1;\n", []string{"GPL-3.0"}},
+		{"line comment crlf", "// SPDX-License-Identifier: MIT\r\nvar a;\r\n", []string{"MIT"}},
+		{"line comment at eof", "// SPDX-License-Identifier: Apache-2.0", []string{"Apache-2.0"}},
+		{"block comment", "/* SPDX-License-Identifier: LGPL-2.1-or-later */\nvar a;\n", []string{"LGPL-2.1-or-later"}},
+		{"jsdoc block", "/**\n * SPDX-License-Identifier: MPL-2.0\n */\n", []string{"MPL-2.0"}},
+		{"expression", "// SPDX-License-Identifier: (MIT OR GPL-2.0)\n", []string{"(MIT OR GPL-2.0)"}},
+		{"html comment", "<!-- SPDX-License-Identifier: BSD-3-Clause -->\n", []string{"BSD-3-Clause"}},
+		{"two files concatenated", "// SPDX-License-Identifier: MIT\na();\n// SPDX-License-Identifier: AGPL-3.0-only\nb();\n", []string{"MIT", "AGPL-3.0-only"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range scanText(tt.text) {
+				if e.Source == SourceSPDX {
+					got = append(got, e.License)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanText(%q) SPDX = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTextSPDXCopyleft(t *testing.T) {
+	ev := scanText("// SPDX-License-Identifier: GPL-3.0\n")
+	if len(ev) != 1 || Copyleft(ev[0].License) != CopyleftStrong {
+		t.Fatalf("GPL-3.0 line comment: evidence %+v, want strong copyleft", ev)
+	}
+}
@@ -0,0 +1,60 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AnalyzeOptions configures RunAnalyze.
+type AnalyzeOptions struct {
+	Only []string // Analyzers to run, by name (empty = all)
+}
+
+// AnalyzeResult contains what the analyzers found in an existing output
+// directory.
+type AnalyzeResult struct {
+	Dir        string // Domain or run directory that was analyzed
+	SourcesDir string // restored_sources directory that was scanned
+	Layout     bool   // Dir has dejank's layout; without it only stats are computed
+	Errors     []error
+
+	Analysis
+}
+
+// RunAnalyze runs the registered analyzers over an existing domain
+// directory (flat or versioned) without re-running any download or
+// restore, writing their artifacts as a run would. Given a restored_sources
+// directory, its domain directory is analyzed. Any other directory is
+// treated as restored sources and only gets statistics, since there is no
+// domain directory to write artifacts to.
+func RunAnalyze(cfg *Config, dir string, opts AnalyzeOptions) (*AnalyzeResult, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	runDir := resolveRunDir(dir)
+	if !isDir(filepath.Join(runDir, "restored_sources")) && filepath.Base(filepath.Clean(dir)) == "restored_sources" {
+		runDir = filepath.Dir(filepath.Clean(dir))
+	}
+	if !isDir(filepath.Join(runDir, "restored_sources")) {
+		result := &AnalyzeResult{Dir: dir, SourcesDir: dir}
+		cfg.analyze(DomainPaths{Base: dir, RestoredSources: dir}, &result.Analysis, &result.Errors, "stats")
+		return result, nil
+	}
+
+	paths := pathsAt(runDir)
+	release, err := cfg.lockOutput(paths.Base)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := &AnalyzeResult{Dir: runDir, SourcesDir: paths.RestoredSources, Layout: true}
+	cfg.analyze(paths, &result.Analysis, &result.Errors, opts.Only...)
+	return result, nil
+}
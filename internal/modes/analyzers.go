@@ -0,0 +1,143 @@
+package modes
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/stats"
+)
+
+// Analysis is what the analyzers found in a domain directory. Mode results
+// embed it.
+type Analysis struct {
+	Stats         *stats.Stats   `json:"stats,omitempty"`     // Language and size statistics for restored sources
+	CommentsFound int            `json:"comments"`            // Comments matching TODO/FIXME-style patterns (see comments.json)
+	GraphQLOps    int            `json:"graphql_operations"`  // GraphQL operations and fragments harvested (see graphql/)
+	HostsFound    int            `json:"hosts"`               // Hosts referenced by downloaded and restored JS (see hosts.txt)
+	Licenses      map[string]int `json:"licenses,omitempty"`  // Third-party packages per license family (see licenses.json)
+	Copyleft      []string       `json:"copyleft,omitempty"`  // Third-party packages under a copyleft license
+	SecretsFound  int            `json:"secrets"`             // Hardcoded secrets and service configs (see secrets.json)
+	Analyzers     []string       `json:"analyzers,omitempty"` // Names of the analyzers that ran
+}
+
+// add merges the counts of another domain's analysis into a. Stats are
+// left alone; they are computed over the whole run.
+func (a *Analysis) add(b Analysis) {
+	a.CommentsFound += b.CommentsFound
+	a.GraphQLOps += b.GraphQLOps
+	a.HostsFound += b.HostsFound
+	a.SecretsFound += b.SecretsFound
+	a.Copyleft = append(a.Copyleft, b.Copyleft...)
+	for family, n := range b.Licenses {
+		if a.Licenses == nil {
+			a.Licenses = make(map[string]int)
+		}
+		a.Licenses[family] += n
+	}
+}
+
+// Analyzer is an analysis pass over the restored sources and downloaded
+// files of a domain directory. It writes its artifacts to the domain
+// directory and records what it found in the Analysis.
+type Analyzer interface {
+	Name() string        // Name analyze -only selects it by
+	Description() string // One line for help output
+	Analyze(c *Config, paths DomainPaths, a *Analysis, errs *[]error)
+}
+
+// analyzers are run in name order by url, single, local, and map mode after
+// restoring, and by the analyze command over existing output.
+var analyzers = map[string]Analyzer{}
+
+// RegisterAnalyzer adds an analyzer to every run. Names must be unique.
+func RegisterAnalyzer(a Analyzer) {
+	if _, ok := analyzers[a.Name()]; ok {
+		panic("modes: analyzer " + a.Name() + " registered twice")
+	}
+	analyzers[a.Name()] = a
+}
+
+// Analyzers returns the registered analyzers in name order.
+func Analyzers() []Analyzer {
+	list := make([]Analyzer, 0, len(analyzers))
+	for _, a := range analyzers {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// SelectAnalyzers checks a comma-separated list of analyzer names, as given
+// to analyze -only, and returns them. An empty list selects all.
+func SelectAnalyzers(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := analyzers[name]; !ok {
+			known := make([]string, 0, len(analyzers))
+			for _, a := range Analyzers() {
+				known = append(known, a.Name())
+			}
+			return nil, fmt.Errorf("unknown analyzer %q (valid: %s)", name, strings.Join(known, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// analyze runs the analyzers named in only, or all of them, over a domain
+// directory. Optional analyzers run without being named only when their
+// flag is set.
+func (c *Config) analyze(paths DomainPaths, a *Analysis, errs *[]error, only ...string) {
+	for _, an := range Analyzers() {
+		if len(only) > 0 && !slices.Contains(only, an.Name()) {
+			continue
+		}
+		if opt, ok := an.(optionalAnalyzer); ok && len(only) == 0 && !opt.enabled(c) {
+			continue
+		}
+		an.Analyze(c, paths, a, errs)
+		a.Analyzers = append(a.Analyzers, an.Name())
+	}
+}
+
+// analyzer adapts a function to Analyzer.
+type analyzer struct {
+	name string
+	desc string
+	run  func(c *Config, paths DomainPaths, a *Analysis, errs *[]error)
+}
+
+func (an analyzer) Name() string        { return an.name }
+func (an analyzer) Description() string { return an.desc }
+func (an analyzer) Analyze(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+	an.run(c, paths, a, errs)
+}
+
+// optionalAnalyzer is an analyzer that is off unless enabled by its Config
+// flag or named in analyze -only.
+type optionalAnalyzer struct {
+	analyzer
+	enabled func(c *Config) bool
+}
+
+func init() {
+	RegisterAnalyzer(analyzer{"stats", "Language and size statistics of restored sources", func(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+		// Runs compute stats from what they restored; only standalone
+		// analysis reads them back from disk
+		if a.Stats != nil {
+			return
+		}
+		s, err := stats.FromDirectory(paths.RestoredSources)
+		if err != nil {
+			c.addErrors(errs, fmt.Errorf("failed to scan %s: %w", paths.RestoredSources, err))
+			return
+		}
+		a.Stats = s
+	}})
+}
@@ -0,0 +1,124 @@
+package modes
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// Limits reported in a result's BudgetExceeded.
+const (
+	LimitScripts  = "max-scripts"
+	LimitMaps     = "max-maps"
+	LimitDuration = "max-duration"
+)
+
+// budget enforces MaxScripts, MaxMaps, and MaxDuration over one run. Once
+// any limit is reached, no further script or map is processed; what was
+// already restored still goes through the post-restore steps.
+type budget struct {
+	maxScripts, maxMaps int
+	deadline            time.Time // Zero for no limit
+	scripts, maps       int
+	exceeded            string // The limit that stopped the run, "" if none
+	skipped             int    // Scripts and maps not processed after that
+	log                 *slog.Logger
+}
+
+// newBudget starts the budget for a run. A nil budget allows everything.
+func (c *Config) newBudget() *budget {
+	if c.MaxScripts <= 0 && c.MaxMaps <= 0 && c.MaxDuration <= 0 {
+		return nil
+	}
+	b := &budget{maxScripts: c.MaxScripts, maxMaps: c.MaxMaps, log: c.logger()}
+	if c.MaxDuration > 0 {
+		b.deadline = time.Now().Add(c.MaxDuration)
+	}
+	return b
+}
+
+// script reports whether another script may be processed, and counts it.
+func (b *budget) script() bool {
+	if b == nil {
+		return true
+	}
+	if b.check(b.maxScripts > 0 && b.scripts >= b.maxScripts, LimitScripts) {
+		b.scripts++
+		return true
+	}
+	return false
+}
+
+// sourceMap reports whether another sourcemap may be processed, and counts
+// it.
+func (b *budget) sourceMap() bool {
+	if b == nil {
+		return true
+	}
+	if b.check(b.maxMaps > 0 && b.maps >= b.maxMaps, LimitMaps) {
+		b.maps++
+		return true
+	}
+	return false
+}
+
+// check records the limit that was hit, if any, and reports whether the
+// run may go on. Items refused after a limit is hit count as skipped.
+func (b *budget) check(full bool, limit string) bool {
+	if b.exceeded == "" {
+		switch {
+		case !b.deadline.IsZero() && time.Now().After(b.deadline):
+			b.stop(LimitDuration)
+		case full:
+			b.stop(limit)
+		}
+	}
+	if b.exceeded != "" {
+		b.skipped++
+		return false
+	}
+	return true
+}
+
+// stop records that limit was reached.
+func (b *budget) stop(limit string) {
+	b.exceeded = limit
+	b.log.Log(context.Background(), ui.LevelNotice, "Limit reached, stopping", "limit", "-"+limit)
+}
+
+// report returns the limit that stopped the run and how many scripts and
+// maps were skipped because of it.
+func (b *budget) report() (string, int) {
+	if b == nil {
+		return "", 0
+	}
+	return b.exceeded, b.skipped
+}
+
+// budgetState is a budget's use so far, kept in run-state.json. The
+// -max-duration deadline is not kept; a resumed run gets the full duration
+// again.
+type budgetState struct {
+	Scripts  int    `json:"scripts"`
+	Maps     int    `json:"maps"`
+	Exceeded string `json:"exceeded,omitempty"`
+	Skipped  int    `json:"skipped,omitempty"`
+}
+
+// state returns the budget's use so far.
+func (b *budget) state() budgetState {
+	if b == nil {
+		return budgetState{}
+	}
+	return budgetState{Scripts: b.scripts, Maps: b.maps, Exceeded: b.exceeded, Skipped: b.skipped}
+}
+
+// resume continues from the use recorded by an interrupted run.
+func (b *budget) resume(s budgetState) {
+	if b == nil {
+		return
+	}
+	b.scripts, b.maps, b.exceeded, b.skipped = s.Scripts, s.Maps, s.Exceeded, s.Skipped
+}
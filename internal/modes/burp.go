@@ -0,0 +1,164 @@
+package modes
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/burp"
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// BurpResult contains the results of importing a proxy export.
+type BurpResult struct {
+	File         string // The export that was imported
+	Items        int    // Request/response pairs in the export
+	ScriptsSaved int    // JavaScript responses written to downloaded_site
+	MapsSaved    int    // Sourcemap responses written to downloaded_site
+	Skipped      int    // Items that are not a successful JavaScript or sourcemap response
+	OutOfScope   int    // Items on hosts outside -scope-file
+	ThirdParty   int    // Known third-party scripts and maps skipped by the denylist
+	Filtered     int    // Scripts and maps skipped by -include-url/-exclude-url
+
+	LocalResult // The local-mode run over the domain directories written
+}
+
+// RunBurp imports the JavaScript and sourcemap responses of a Burp XML
+// items export, or of a HAR file such as ZAP's, into the per-domain layout
+// and restores them as local mode does. Nothing is fetched from the
+// target: sourcemaps missing from the export are not downloaded.
+func RunBurp(cfg *Config, file string) (*BurpResult, error) {
+	items, err := burp.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BurpResult{File: file, Items: len(items)}
+	result.budget = cfg.newBudget()
+
+	// Responses by host, hosts in the order the export first has them
+	byHost := make(map[string][]burp.Item)
+	var hosts []string
+	for _, item := range items {
+		parsed, err := url.Parse(item.URL)
+		if err != nil || parsed.Host == "" || !importable(item) {
+			result.Skipped++
+			continue
+		}
+		switch {
+		case !cfg.ScopeList.AllowURL(item.URL):
+			result.OutOfScope++
+			cfg.addWarnings(&result.Warnings, scopeFileWarning(item.URL))
+			continue
+		case cfg.Denylist.Match(item.URL):
+			result.ThirdParty++
+			continue
+		case !cfg.Filter.AllowURL(item.URL):
+			result.Filtered++
+			cfg.addWarnings(&result.Warnings, filteredWarning(item.URL))
+			continue
+		}
+		if item.Err != nil {
+			cfg.addWarnings(&result.Warnings, warn.FromError(warn.Import, item.URL, item.Err))
+		}
+		if _, ok := byHost[parsed.Host]; !ok {
+			hosts = append(hosts, parsed.Host)
+		}
+		byHost[parsed.Host] = append(byHost[parsed.Host], item)
+	}
+
+	var targets []string
+	for _, host := range hosts {
+		paths := cfg.domainPaths(host)
+		if err := saveImported(cfg, paths, byHost[host], result); err != nil {
+			cfg.addErrors(&result.Errors, err)
+			continue
+		}
+		targets = append(targets, paths.Base)
+	}
+	cfg.logger().Info("Imported scripts and maps from Burp",
+		"scripts", result.ScriptsSaved, "maps", result.MapsSaved, "items", result.Items)
+
+	processLocal(cfg, targets, len(targets) > 1, &result.LocalResult)
+	return result, nil
+}
+
+// saveImported writes a host's imported responses to the downloaded_site
+// folder of paths. A URL that appears more than once keeps its last
+// response.
+func saveImported(cfg *Config, paths DomainPaths, items []burp.Item, result *BurpResult) error {
+	release, err := cfg.prepareOutput(paths)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	saved := make(map[string]bool)
+	for _, item := range items {
+		var filename string
+		if isImportedMap(item) {
+			filename = cfg.mapFilename(item.URL, "")
+		} else {
+			filename = cfg.scriptFilename(item.URL)
+		}
+		if err := writeDownload(filepath.Join(paths.DownloadedSite, filename), item.Body); err != nil {
+			cfg.addErrors(&result.Errors, fmt.Errorf("failed to save %s: %w", filename, err))
+			continue
+		}
+		if saved[filename] {
+			continue
+		}
+		saved[filename] = true
+		if isImportedMap(item) {
+			result.MapsSaved++
+		} else {
+			result.ScriptsSaved++
+		}
+		cfg.success("Imported", "url", item.URL, "path", filename)
+	}
+
+	cfg.writeChecksums(paths, nil, &result.Errors)
+	return nil
+}
+
+// importable reports whether item holds a successful JavaScript or
+// sourcemap response with a body.
+func importable(item burp.Item) bool {
+	if len(item.Body) == 0 || (item.Status != 0 && (item.Status < 200 || item.Status > 299)) {
+		return false
+	}
+	return isImportedMap(item) || isImportedScript(item)
+}
+
+// isImportedMap reports whether item is a sourcemap, by its URL or, for
+// maps served from other paths, by its content.
+func isImportedMap(item burp.Item) bool {
+	if strings.HasSuffix(strings.ToLower(urlPath(item.URL)), ".map") {
+		return true
+	}
+	body := bytes.TrimSpace(item.Body)
+	return bytes.HasPrefix(body, []byte("{")) && bytes.Contains(body, []byte(`"mappings"`)) && bytes.Contains(body, []byte(`"sources"`))
+}
+
+// isImportedScript reports whether item is JavaScript, by its content type
+// (Burp records "script" for these) or by its URL.
+func isImportedScript(item burp.Item) bool {
+	ct := strings.ToLower(item.ContentType)
+	if strings.Contains(ct, "javascript") || strings.Contains(ct, "ecmascript") || ct == "script" {
+		return true
+	}
+	// A video segment can share TypeScript's .ts extension
+	return !strings.HasPrefix(ct, "video/") && fetch.IsScriptPath(urlPath(item.URL))
+}
+
+// urlPath returns the path of rawURL, or "" if it doesn't parse.
+func urlPath(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Path
+}
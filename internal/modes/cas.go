@@ -0,0 +1,60 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/cas"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// storeDownloads moves every file under dir into the -cas store, leaving
+// hardlinks behind, and returns the bytes saved by content that was already
+// stored. Where hardlinks can't be made the files are stored as copies and
+// a single warning is added.
+func (c *Config) storeDownloads(dir string, errs *[]error, warnings *[]warn.Warning) int64 {
+	if c.CASDir == "" {
+		return 0
+	}
+	store, err := cas.Open(c.CASDir)
+	if err != nil {
+		c.addErrors(errs, err)
+		return 0
+	}
+
+	var saved int64
+	copied := false
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		n, err := store.Put(path)
+		switch {
+		case errors.Is(err, cas.ErrNoHardlinks):
+			copied = true
+		case err != nil:
+			c.addErrors(errs, fmt.Errorf("failed to store %s in %s: %w", path, c.CASDir, err))
+		}
+		saved += n
+		return nil
+	})
+
+	if copied {
+		c.addWarnings(warnings, warn.New(warn.CAS, c.CASDir, "hardlinks from %s are not supported; stored copies instead, so nothing was deduplicated", dir))
+	}
+	if saved > 0 {
+		c.logger().Info("Deduplicated against the content-addressed store", "bytes", saved, "path", c.CASDir)
+	}
+	return saved
+}
+
+// writeDownload writes a file under downloaded_site. An existing file is
+// removed first rather than overwritten, since it may be a hardlink into
+// the -cas store shared with other runs.
+func writeDownload(path string, data []byte) error {
+	os.Remove(path)
+	return os.WriteFile(path, data, 0644)
+}
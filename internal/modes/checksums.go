@@ -0,0 +1,189 @@
+package modes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// checksumsFile lists, per domain directory, the SHA-256 of every file
+// under downloaded_site in sha256sum format, so "sha256sum -c" run from the
+// domain directory checks them too.
+const checksumsFile = "checksums.txt"
+
+// ErrNoChecksums is returned by RunVerify for output written before
+// checksums were recorded.
+var ErrNoChecksums = errors.New("no " + checksumsFile + " found")
+
+// checksums maps file paths to the SHA-256 they were downloaded with.
+type checksums map[string]string
+
+// add records the hash of a file taken while downloading it.
+func (s *checksums) add(path, sum string) {
+	if sum == "" {
+		return
+	}
+	if *s == nil {
+		*s = make(checksums)
+	}
+	(*s)[path] = sum
+}
+
+// writeChecksums writes checksums.txt for every file under
+// paths.DownloadedSite. Downloads use the hash taken as they arrived;
+// files written from memory or copied in, such as inline maps and page
+// snapshots, are hashed now.
+func (c *Config) writeChecksums(paths DomainPaths, sums checksums, errs *[]error) {
+	var lines []string
+	err := filepath.WalkDir(paths.DownloadedSite, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		sum, ok := sums[path]
+		if !ok {
+			if sum, err = fileSHA256(path); err != nil {
+				return err
+			}
+		}
+		rel, err := filepath.Rel(paths.Base, path)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum+"  "+filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to hash downloads for %s: %w", checksumsFile, err))
+		return
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i][66:] < lines[j][66:] })
+	data := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, checksumsFile), []byte(data), 0644); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", checksumsFile, err))
+	}
+}
+
+// readChecksums parses checksums.txt in dir into hashes by path relative
+// to dir.
+func readChecksums(dir string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(dir, checksumsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoChecksums
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(sum) != 64 || len(name) < 2 {
+			return nil, fmt.Errorf("%s line %d: not a sha256sum entry", checksumsFile, n)
+		}
+		// sha256sum marks the mode with " " (text) or "*" (binary)
+		sums[filepath.FromSlash(name[1:])] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// VerifyResult reports how the files of a domain directory compare with
+// the hashes recorded when they were downloaded. Paths are relative to Dir.
+type VerifyResult struct {
+	Dir      string   // Domain directory that was checked
+	Checked  int      // Files listed in checksums.txt
+	Modified []string // Listed files whose content changed since download
+	Missing  []string // Listed files no longer on disk
+	Unlisted []string // Files under downloaded_site that checksums.txt doesn't list
+}
+
+// OK reports whether every listed file is present and unchanged. Unlisted
+// files, such as maps added by hand for local mode, don't count against it.
+func (r *VerifyResult) OK() bool {
+	return len(r.Modified) == 0 && len(r.Missing) == 0
+}
+
+// RunVerify re-hashes the downloads of a domain directory (flat or
+// versioned) against its checksums.txt. It fails with ErrNoChecksums when
+// the directory has none.
+func RunVerify(dir string) (*VerifyResult, error) {
+	dir = resolveRunDir(dir)
+	sums, err := readChecksums(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify %s: %w", dir, err)
+	}
+	result := &VerifyResult{Dir: dir, Checked: len(sums)}
+
+	for name, want := range sums {
+		got, err := fileSHA256(filepath.Join(dir, name))
+		switch {
+		case os.IsNotExist(err):
+			result.Missing = append(result.Missing, name)
+		case err != nil:
+			return nil, fmt.Errorf("cannot verify %s: %w", name, err)
+		case got != want:
+			result.Modified = append(result.Modified, name)
+		}
+	}
+
+	filepath.WalkDir(filepath.Join(dir, "downloaded_site"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			if _, ok := sums[rel]; !ok {
+				result.Unlisted = append(result.Unlisted, rel)
+			}
+		}
+		return nil
+	})
+
+	sort.Strings(result.Modified)
+	sort.Strings(result.Missing)
+	sort.Strings(result.Unlisted)
+	return result, nil
+}
+
+// verifyDownloads checks a domain directory's downloads against its
+// checksums.txt before local mode processes them, warning about every file
+// modified or removed since download. Output without checksums.txt is
+// processed as before.
+func (c *Config) verifyDownloads(dir string, warnings *[]warn.Warning) {
+	result, err := RunVerify(dir)
+	if errors.Is(err, ErrNoChecksums) {
+		return
+	}
+	if err != nil {
+		c.addWarnings(warnings, warn.FromError(warn.Checksum, dir, err))
+		return
+	}
+
+	for _, name := range result.Modified {
+		c.addWarnings(warnings, warn.New(warn.Checksum, filepath.Join(dir, name), "modified since download; its SHA-256 no longer matches %s", checksumsFile))
+	}
+	for _, name := range result.Missing {
+		c.addWarnings(warnings, warn.New(warn.Checksum, filepath.Join(dir, name), "listed in %s but missing", checksumsFile))
+	}
+	if result.OK() {
+		c.success("Verified downloads against "+checksumsFile, "count", result.Checked, "path", dir)
+	}
+}
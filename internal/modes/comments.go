@@ -0,0 +1,43 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/comments"
+)
+
+// commentsFile is written to the domain directory when comments match.
+const commentsFile = "comments.json"
+
+func init() {
+	RegisterAnalyzer(optionalAnalyzer{analyzer{"comments", "TODO/FIXME-style comments in restored sources (comments.json; -comments)", func(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+		a.CommentsFound = c.scanComments(paths, errs)
+	}}, func(c *Config) bool { return c.Comments }})
+}
+
+// scanComments collects TODO/FIXME-style comments from a domain's restored
+// sources into comments.json and returns how many were found.
+func (c *Config) scanComments(paths DomainPaths, errs *[]error) int {
+	matches, scanErrs := comments.ScanDirectory(paths.RestoredSources, comments.Options{
+		Patterns:      c.CommentPatterns,
+		IncludeVendor: c.CommentsVendor,
+	})
+	for _, err := range scanErrs {
+		c.notice("Comment scan error", "error", err)
+	}
+	outPath := filepath.Join(paths.Base, commentsFile)
+	if len(matches) == 0 {
+		os.Remove(outPath) // Drop results from an earlier run
+		return 0
+	}
+
+	if err := comments.WriteJSON(outPath, matches); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", commentsFile, err))
+		return 0
+	}
+
+	c.success("Collected flagged comments", "count", len(matches), "path", outPath)
+	return len(matches)
+}
@@ -0,0 +1,50 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCommentsAnalyzerGated(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments bool
+		only     []string
+		want     int
+	}{
+		{name: "off by default"},
+		{name: "-comments", comments: true, want: 1},
+		{name: "named in -only", only: []string{"comments"}, want: 1},
+		{name: "other analyzers in -only", comments: true, only: []string{"graphql"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputRoot = t.TempDir()
+			cfg.Comments = tt.comments
+			paths := GetDomainPaths(cfg.OutputRoot, "example.com")
+			writeFiles(t, paths.RestoredSources, map[string]string{
+				"src/app.js": "// TODO: remove before launch\nvar a = 1;\n",
+			})
+
+			var a Analysis
+			var errs []error
+			cfg.analyze(paths, &a, &errs, tt.only...)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if a.CommentsFound != tt.want {
+				t.Errorf("CommentsFound = %d, want %d", a.CommentsFound, tt.want)
+			}
+			if ran := slices.Contains(a.Analyzers, "comments"); ran != (tt.want > 0) {
+				t.Errorf("Analyzers = %v", a.Analyzers)
+			}
+			_, err := os.Stat(filepath.Join(paths.Base, commentsFile))
+			if exists := err == nil; exists != (tt.want > 0) {
+				t.Errorf("%s exists = %v", commentsFile, exists)
+			}
+		})
+	}
+}
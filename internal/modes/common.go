@@ -2,13 +2,26 @@
 package modes
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/thesavant42/dejank/internal/disk"
 	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/filter"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/warn"
 )
 
 // ProgressCallback is called to report progress during operations.
@@ -16,11 +29,71 @@ type ProgressCallback func(event string, data interface{})
 
 // Config holds configuration for all modes.
 type Config struct {
-	OutputRoot string       // Root output directory (default: .)
-	Client     *fetch.Client
-	Verbose    bool
-	Force      bool         // Overwrite existing output directory
-	OnProgress ProgressCallback // Optional callback for progress events
+	OutputRoot      string // Root output directory (default: .)
+	Client          *fetch.Client
+	Verbose         bool
+	Log             *slog.Logger             // Receives progress messages, e.g. the CLI's -v output (nil = discarded)
+	Force           bool                     // Overwrite existing output directory
+	OnProgress      ProgressCallback         // Optional callback for progress events
+	RunDir          string                   // Optional subdirectory under the domain directory for this run
+	GitSnapshot     bool                     // Commit restored sources to a git repository after each run
+	GitAtBase       bool                     // Keep the snapshot repository at the domain directory level
+	Versioned       bool                     // Write each run to its own timestamped directory
+	CSVExport       bool                     // Write scripts.csv and maps.csv into the domain directory
+	Hooks           map[HookPoint][]HookFunc // Callbacks run at each hook point, in order
+	Context         context.Context          // Cancellation context passed to hooks (default: Background)
+	Filter          *filter.Filter           // Include/exclude rules for URLs and sources (nil = allow all)
+	Scope           filter.Scope             // Hosts url mode downloads from, relative to the target ("" = all)
+	AllowHosts      []string                 // Hosts always in scope; "*.example.com" matches subdomains
+	ScopeList       *filter.ScopeList        // -scope-file allowlist every url mode download must match (nil = all)
+	NoRestore       bool                     // Stop after downloading scripts and maps; restore later with local mode
+	Scaffold        bool                     // Write tsconfig/jsconfig, editor settings, and package.json after restore
+	VerifyMaps      bool                     // Check each map's mappings against the script it was found for
+	FormatVendor    bool                     // Pretty-print node_modules and ignoreList sources too
+	FormatMaxSize   int64                    // Write JS/TS files over this many bytes unformatted (0 = no limit)
+	RunInfo         *RunInfo                 // Written to run-info.json in every domain directory a run writes (nil = not written)
+	Comments        bool                     // Collect TODO/FIXME-style comments from restored sources into comments.json
+	CommentPatterns []*regexp.Regexp         // Keyword patterns for comments.json (nil = comments.DefaultPatterns)
+	CommentsVendor  bool                     // Also collect comments from node_modules and other vendor code
+	DumpModules     bool                     // Serialize the live page's webpack modules in url mode
+	Coverage        bool                     // Record which scripts and sources ran during page load in url mode
+	RespectRobots   bool                     // Skip script and map URLs disallowed by each origin's robots.txt in url mode
+	Seen            *SeenSet                 // Maps processed by earlier runs, skipped and appended to in url mode (nil = none)
+	MapURLTemplate  MapURLTemplate           // Where url mode also looks for the maps of scripts that name none ("" = nowhere)
+	MaxScripts      int                      // Stop after processing this many scripts (0 = no limit)
+	MaxMaps         int                      // Stop after processing this many sourcemaps (0 = no limit)
+	MaxDuration     time.Duration            // Stop processing scripts and maps after this long (0 = no limit)
+	SitemapPages    int                      // Also load up to this many pages sampled from sitemap.xml in url mode
+	ChromePath      string                   // Chrome executable for browser discovery ("" = search the usual locations)
+	BrowserTimeout  time.Duration            // Limit for each browser discovery attempt (0 = 60s)
+	NavTimeout      time.Duration            // Limit for loading the page in the browser (0 = 30s)
+	Settle          time.Duration            // Wait after page load for lazy-loaded scripts (0 = 5s)
+	Headful         bool                     // Show the browser window during discovery
+	UserDataDir     string                   // Chrome profile directory for discovery, e.g. one that has passed a challenge ("" = a fresh one)
+	UserAgent       string                   // User-Agent for the browser; set it on Client too for downloads ("" = defaults)
+	AcceptLanguage  string                   // Accept-Language for the browser; set it on Client too for downloads ("" = defaults)
+	Emulate         Emulation                // Device browser discovery loads the page as ("" = desktop)
+	Locale          string                   // Locale the page sees in the browser, e.g. de-DE ("" = Chrome's own)
+	Timezone        string                   // IANA timezone the page sees in the browser ("" = the system's)
+	Geolocation     *fetch.Geolocation       // Position the page's geolocation API reports (nil = none)
+	PageSnapshot    bool                     // Save a screenshot and the rendered DOM of the page to downloaded_site in url mode
+	Interaction     []fetch.InteractionStep  // Steps run in the browser after load to trigger lazy chunks (-interact)
+	AutoScroll      bool                     // Scroll through the page after load so lazy loads fire
+	ReuseDir        string                   // Earlier run directory whose unchanged scripts url mode reuses ("" = the run's own directory)
+	CASDir          string                   // Shared content-addressed store that downloaded files are hardlinked into ("" = off)
+	NormalizeEOL    string                   // Line endings for restored text sources: sourcemap.EOLLF or EOLCRLF ("" = keep)
+	MapMtime        bool                     // Give restored sources the modification time of the map they came from
+	FileMode        os.FileMode              // Permissions of restored sources regardless of the umask (0 = default)
+	DirMode         os.FileMode              // Permissions of the directories restored sources are written to (0 = default)
+	Denylist        *filter.Denylist         // Known third-party scripts url mode skips (nil = none)
+	ForceScan       bool                     // Let local mode scan / or the home directory for domain directories
+	Flat            bool                     // Local mode's target is a plain directory of maps and scripts, read recursively
+	FlatOutput      string                   // Domain directory Flat writes its output to ("" = <target>-dejank)
+	SplitByHost     bool                     // Store each script and map under the domain directory of its own host in url mode
+	KeepQuery       bool                     // Add a hash of a script's or map's query string to its filename
+	KeepQueryParam  string                   // Add only this query parameter's value to filenames ("" = the whole query with KeepQuery)
+	BreakLock       bool                     // Take over an output directory's lock left by a run that has not finished in an hour
+	Resume          bool                     // Continue an interrupted url run from the run-state.json in its domain directory
 }
 
 // emit sends a progress event if a callback is configured.
@@ -30,29 +103,240 @@ func (c *Config) emit(event string, data interface{}) {
 	}
 }
 
+// addErrors appends errors to dst and reports each one as an "error" event.
+// Of the full-disk and permission errors that stop a run, only the first
+// is kept: every write after it fails the same way.
+func (c *Config) addErrors(dst *[]error, errs ...error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if disk.Is(err) {
+			if disk.First(*dst) != nil {
+				continue
+			}
+			err = disk.Check("", err)
+		}
+		*dst = append(*dst, err)
+		c.logger().Debug("Error", "error", err)
+		c.emit("error", map[string]interface{}{
+			"message": err.Error(),
+		})
+	}
+}
+
+// diskStopped reports whether errs hold a full-disk or permission error,
+// after which a run writes nothing more.
+func diskStopped(errs []error) bool {
+	return disk.First(errs) != nil
+}
+
+// addWarnings appends warnings to dst and reports each one as a "warning"
+// event.
+func (c *Config) addWarnings(dst *[]warn.Warning, warnings ...warn.Warning) {
+	for _, w := range warnings {
+		*dst = append(*dst, w)
+		c.logger().Debug("Warning", "category", string(w.Category), "warning", w.String())
+		c.emit("warning", w)
+	}
+}
+
+// restoreOptions returns the options for restoring the sources of the map
+// in mapFile, applying the configured source filter, permissions, and
+// -map-mtime. baseURL may be empty to skip asset fetching.
+func (c *Config) restoreOptions(baseURL, mapFile string) *sourcemap.RestoreOptions {
+	opts := &sourcemap.RestoreOptions{
+		Filter:   c.Filter.AllowSource,
+		EOL:      c.NormalizeEOL,
+		FileMode: c.FileMode,
+		DirMode:  c.DirMode,
+
+		FormatVendor:  c.FormatVendor,
+		FormatMaxSize: c.FormatMaxSize,
+	}
+	if c.MapMtime {
+		if info, err := os.Stat(mapFile); err == nil {
+			opts.ModTime = info.ModTime()
+		}
+	}
+	if c.Filter != nil {
+		opts.Only = c.Filter.Only
+	}
+	if baseURL != "" {
+		opts.BaseURL = baseURL
+		opts.Fetcher = c.Client
+	}
+	return opts
+}
+
+// mapLastModified gives a map downloaded to path the Last-Modified time the
+// server sent for -map-mtime, so that the sources restored from it date
+// from the map's build rather than the download and runs compare. A map
+// served without one keeps the download time.
+func (c *Config) mapLastModified(path string, info fetch.DownloadInfo) {
+	if !c.MapMtime || info.Validators.LastModified == "" {
+		return
+	}
+	t, err := http.ParseTime(info.Validators.LastModified)
+	if err != nil {
+		return
+	}
+	if err := os.Chtimes(path, t, t); err != nil {
+		c.logger().Debug("Failed to set map modification time", "path", path, "error", err)
+	}
+}
+
+// restore writes the sources of the map in mapFile under dir. With
+// NoRestore set nothing is written and an empty result is returned.
+func (c *Config) restore(sm *sourcemap.SourceMap, dir, baseURL, mapFile string) sourcemap.RestoreResult {
+	return c.restoreWith(sm, dir, c.restoreOptions(baseURL, mapFile))
+}
+
+// restoreWith is restore with caller-built options.
+func (c *Config) restoreWith(sm *sourcemap.SourceMap, dir string, opts *sourcemap.RestoreOptions) sourcemap.RestoreResult {
+	if c.NoRestore {
+		return sourcemap.RestoreResult{}
+	}
+	result := sourcemap.RestoreSourcesWithOptions(sm, dir, opts)
+	c.logger().Debug("Wrote sources",
+		"path", dir, "sources", len(sm.Sources), "restored", result.RestoredCount, "skipped", result.SkippedCount, "filtered", result.FilteredCount,
+		"fetched", result.SourcesFetched, "formatted", result.FormattedCount, "verbatim", result.VerbatimCount)
+	if result.VerbatimCount > 0 {
+		c.logger().Info("Left vendor or oversized sources unformatted",
+			"path", dir, "formatted", result.FormattedCount, "verbatim", result.VerbatimCount)
+	}
+	return result
+}
+
+// newBrowser returns a browser client for discovery with the configured
+// options.
+func (c *Config) newBrowser() *fetch.BrowserClient {
+	opts := fetch.DefaultBrowserOptions()
+	opts.ExecPath = c.ChromePath
+	opts.Headful = c.Headful
+	opts.UserDataDir = c.UserDataDir
+	opts.NavigationTimeout = c.NavTimeout
+	opts.UserAgent = c.UserAgent
+	opts.AcceptLanguage = c.AcceptLanguage
+	opts.Mobile = c.Emulate == EmulateMobile
+	opts.Locale = c.Locale
+	opts.Timezone = c.Timezone
+	opts.Geolocation = c.Geolocation
+	opts.Context = c.Context
+	if c.BrowserTimeout > 0 {
+		opts.Timeout = c.BrowserTimeout
+	}
+	if c.Settle > 0 {
+		opts.SettleDuration = c.Settle
+	}
+	browser := fetch.NewBrowserClientWithOptions(opts)
+	browser.Interaction = c.Interaction
+	browser.AutoScroll = c.AutoScroll
+	if c.ScopeList != nil {
+		browser.Allow = c.ScopeList.AllowURL
+	}
+	return browser
+}
+
+// printUserAgent logs the User-Agent downloads are sent with.
+func (c *Config) printUserAgent() {
+	ua := c.Client.UserAgent()
+	if ua == "" {
+		ua = "Go's default"
+	}
+	c.logger().Info("Downloading with User-Agent", "user_agent", ua)
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
 		OutputRoot: ".",
 		Client:     fetch.New(),
 		Verbose:    false,
+		Scope:      filter.ScopeSameOrigin,
 	}
 }
 
 // DomainPaths holds the standard directory structure for a domain.
 type DomainPaths struct {
-	Base           string // output/<domain>
-	DownloadedSite string // output/<domain>/downloaded_site
+	Base            string // output/<domain>
+	DownloadedSite  string // output/<domain>/downloaded_site
 	RestoredSources string // output/<domain>/restored_sources
 	ExtractedAssets string // output/<domain>/extracted_assets
 }
 
-// GetDomainPaths returns the standard directory paths for a domain.
+// GetDomainPaths returns the standard directory paths for a domain. The
+// output root is resolved through symlinks first, so the paths name where
+// output really goes and -f never writes through a link out of that tree.
 func GetDomainPaths(outputRoot, domain string) DomainPaths {
-	base := filepath.Join(outputRoot, sanitizeDomain(domain))
+	if resolved, err := filepath.EvalSymlinks(outputRoot); err == nil {
+		outputRoot = resolved
+	}
+	return pathsAt(filepath.Join(outputRoot, sanitizeDomain(domain)))
+}
+
+// domainPaths returns the directory paths for a domain, honoring RunDir and
+// Versioned. In versioned mode each call yields a new timestamped run directory.
+func (c *Config) domainPaths(domain string) DomainPaths {
+	paths := GetDomainPaths(c.OutputRoot, domain)
+	switch {
+	case c.RunDir != "":
+		paths = pathsAt(filepath.Join(paths.Base, c.RunDir))
+	case c.Versioned:
+		paths = pathsAt(filepath.Join(paths.Base, newVersionName(paths.Base)))
+	}
+	return paths
+}
+
+// prepareOutput creates the output directory and its layout and locks it
+// for the run, returning a func that releases the lock. The directory is
+// created exclusively, so without -f a concurrent run that created it first
+// is reported rather than written over. Versioned runs also update the
+// domain's latest pointer.
+func (c *Config) prepareOutput(paths DomainPaths) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(paths.Base), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(paths.Base), err)
+	}
+	if err := os.Mkdir(paths.Base, 0755); err != nil {
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create directory %s: %w", paths.Base, err)
+		}
+		if !c.Force && c.Resume {
+			return nil, fmt.Errorf("nothing to resume in %s: it has no %s, so its run finished or was not a url run (use -f to overwrite)", paths.Base, runStateFile)
+		}
+		if !c.Force {
+			return nil, fmt.Errorf("output directory already exists: %s (use -f to overwrite or -versioned to keep each run)", paths.Base)
+		}
+		if info, err := os.Lstat(paths.Base); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("output directory is a symlink, refusing to overwrite it: %s", paths.Base)
+		}
+	}
+
+	release, err := c.lockOutput(paths.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paths.EnsureDirs(); err != nil {
+		release()
+		return nil, err
+	}
+
+	if c.Versioned && c.RunDir == "" {
+		if err := updateLatest(paths.Base); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return c.recordRun(paths.Base, release), nil
+}
+
+// pathsAt returns the standard layout rooted at base.
+func pathsAt(base string) DomainPaths {
 	return DomainPaths{
-		Base:           base,
-		DownloadedSite: filepath.Join(base, "downloaded_site"),
+		Base:            base,
+		DownloadedSite:  filepath.Join(base, "downloaded_site"),
 		RestoredSources: filepath.Join(base, "restored_sources"),
 		ExtractedAssets: filepath.Join(base, "extracted_assets"),
 	}
@@ -99,19 +383,131 @@ func resolveURL(baseURL, ref string) (string, error) {
 	return base.ResolveReference(refURL).String(), nil
 }
 
-// filenameFromURL extracts a clean filename from a URL.
+// maxFilenameBytes caps download filenames below the usual 255-byte limit,
+// leaving room for the .map and .inline.map suffixes added to them.
+const maxFilenameBytes = 200
+
+// filenameFromURL extracts a clean filename from a URL: the last path
+// segment, percent-decoded and sanitized like restored source paths.
 func filenameFromURL(rawURL string) string {
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return "unknown.js"
 	}
 
-	// Get path basename, strip query params
-	base := filepath.Base(parsed.Path)
-	if base == "" || base == "/" || base == "." {
+	// Split before decoding so an encoded slash can't introduce a directory.
+	// Names that don't decode to valid UTF-8 keep their escapes.
+	base := path.Base(parsed.EscapedPath())
+	if base == "/" || base == "." {
+		return "index.js"
+	}
+	if decoded, err := url.PathUnescape(base); err == nil && utf8.ValidString(decoded) {
+		base = decoded
+	}
+	base = strings.NewReplacer("/", "_", "\\", "_").Replace(base)
+
+	name := sourcemap.SanitizePathSegment(base)
+	if name == "" || name == "." {
 		return "index.js"
 	}
+	return name
+}
+
+// shortenFilename truncates a name longer than maxFilenameBytes, keeping
+// its extension and appending a hash of the full name so that different
+// long names stay distinct. The same name always shortens the same way.
+func shortenFilename(name string) string {
+	if len(name) <= maxFilenameBytes {
+		return name
+	}
+	ext := filepath.Ext(name)
+	if len(ext) > 16 {
+		ext = ""
+	}
+	suffix := "-" + shortHash(name) + ext
 
-	return base
+	cut := maxFilenameBytes - len(suffix)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut] + suffix
 }
 
+// scriptFilenameFromURL returns the download filename for a script, adding a .js
+// extension when the URL has none so local mode picks the file up.
+func scriptFilenameFromURL(rawURL string) string {
+	name := filenameFromURL(rawURL)
+	if filepath.Ext(name) == "" {
+		name += ".js"
+	}
+	return shortenFilename(name)
+}
+
+// mapFilenameFromURL returns the download filename for a sourcemap, ensuring a
+// .map suffix so local mode picks the file up. The rest of the name is
+// shortened like a script's, so app.js and app.js.map stay paired.
+func mapFilenameFromURL(rawURL string) string {
+	name := filenameFromURL(rawURL)
+	return shortenFilename(strings.TrimSuffix(name, ".map")) + ".map"
+}
+
+// scriptFilename returns the download filename for a script, with its
+// query suffix when -keep-query or -keep-query-param is set.
+func (c *Config) scriptFilename(rawURL string) string {
+	return insertSuffix(scriptFilenameFromURL(rawURL), c.querySuffix(rawURL))
+}
+
+// mapFilename returns the download filename for a sourcemap. Under
+// -keep-query a map without a query of its own takes the suffix of the
+// script that referenced it, if any, so app.js?build=1 and its app.js.map
+// are stored as app.<suffix>.js and app.<suffix>.js.map.
+func (c *Config) mapFilename(rawURL, scriptURL string) string {
+	suffix := c.querySuffix(rawURL)
+	if suffix == "" && scriptURL != "" {
+		suffix = c.querySuffix(scriptURL)
+	}
+	name := mapFilenameFromURL(rawURL)
+	return insertSuffix(strings.TrimSuffix(name, ".map"), suffix) + ".map"
+}
+
+// querySuffix returns what -keep-query adds to the filename of rawURL: the
+// sanitized value of KeepQueryParam, or a short hash of it or of the whole
+// query. It is "" when neither option is set or there is nothing to add.
+func (c *Config) querySuffix(rawURL string) string {
+	if !c.KeepQuery && c.KeepQueryParam == "" {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" {
+		return ""
+	}
+
+	if c.KeepQueryParam == "" {
+		return shortHash(parsed.RawQuery)
+	}
+	value := parsed.Query().Get(c.KeepQueryParam)
+	if value == "" {
+		return ""
+	}
+	clean := sourcemap.SanitizePathSegment(strings.NewReplacer("/", "_", "\\", "_").Replace(value))
+	if clean == "" || len(clean) > 32 {
+		return shortHash(value)
+	}
+	return clean
+}
+
+// insertSuffix adds suffix before the extension of name: app.js becomes
+// app.<suffix>.js.
+func insertSuffix(name, suffix string) string {
+	if suffix == "" {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + suffix + ext
+}
+
+// shortHash returns the first 8 hex digits of the SHA-256 of s.
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:4])
+}
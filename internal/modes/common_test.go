@@ -0,0 +1,107 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+)
+
+func TestMapLastModified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.js.map")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info := fetch.DownloadInfo{Validators: fetch.Validators{LastModified: "Tue, 03 Sep 2024 10:00:00 GMT"}}
+	want := time.Date(2024, 9, 3, 10, 0, 0, 0, time.UTC)
+
+	cfg := DefaultConfig()
+	cfg.mapLastModified(path, info)
+	if opts := cfg.restoreOptions("", path); !opts.ModTime.IsZero() {
+		t.Errorf("without -map-mtime ModTime = %v, want zero", opts.ModTime)
+	}
+
+	cfg.MapMtime = true
+	cfg.mapLastModified(path, info)
+	if got := cfg.restoreOptions("", path).ModTime; !got.Equal(want) {
+		t.Errorf("ModTime = %v, want the map's Last-Modified %v", got, want)
+	}
+
+	// Without Last-Modified the map keeps the time it was written
+	other := filepath.Join(t.TempDir(), "b.js.map")
+	os.WriteFile(other, []byte("{}"), 0644)
+	cfg.mapLastModified(other, fetch.DownloadInfo{})
+	if got := cfg.restoreOptions("", other).ModTime; time.Since(got) > time.Minute {
+		t.Errorf("ModTime = %v, want the download time", got)
+	}
+}
+
+func TestFilenameFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/js/app.js", "app.js"},
+		{"https://example.com/js/app.js?v=3#top", "app.js"},
+		{"https://example.com/js/bundle%20v2.js", "bundle_v2.js"},
+		{"https://example.com/js/%E4%B8%AD%E6%96%87.js", "中文.js"},
+		{"https://example.com/js/中文.js", "中文.js"},
+		{"https://example.com/js/a%2Fb.js", "a_b.js"},
+		{"https://example.com/js/a%5Cb.js", "a_b.js"},
+		{"https://example.com/js/%3Cx%3E.js", "x.js"},
+		{"https://example.com/js/%FF.js", "%FF.js"},
+		{"https://example.com/js/%2E%2E", "index.js"},
+		{"https://example.com/", "index.js"},
+		{"https://example.com", "index.js"},
+		{"%zz", "unknown.js"},
+	}
+	for _, tt := range tests {
+		if got := filenameFromURL(tt.url); got != tt.want {
+			t.Errorf("filenameFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestScriptAndMapFilenamesPair(t *testing.T) {
+	long := strings.Repeat("%E4%B8%AD", 100) // 300 bytes once decoded
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"plain", "https://example.com/js/app.js", "app.js"},
+		{"encoded space", "https://example.com/js/bundle%20v2.js", "bundle_v2.js"},
+		{"cjk", "https://example.com/js/%E4%B8%AD%E6%96%87.js", "中文.js"},
+		{"no extension", "https://example.com/gtag/js", "js.js"},
+		{"overlong encoded", "https://example.com/js/" + long + ".js", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := scriptFilenameFromURL(tt.script)
+			if tt.want != "" && script != tt.want {
+				t.Errorf("scriptFilenameFromURL = %q, want %q", script, tt.want)
+			}
+			if len(script) > maxFilenameBytes || !utf8.ValidString(script) {
+				t.Errorf("scriptFilenameFromURL = %q (%d bytes)", script, len(script))
+			}
+			mapURL := tt.script + ".map"
+			if filepath.Ext(filenameFromURL(tt.script)) == "" {
+				mapURL = tt.script + ".js.map"
+			}
+			if got := mapFilenameFromURL(mapURL); got != script+".map" {
+				t.Errorf("mapFilenameFromURL = %q, want %q", got, script+".map")
+			}
+		})
+	}
+
+	// Different overlong names stay distinct
+	a := scriptFilenameFromURL("https://example.com/" + long + "a.js")
+	b := scriptFilenameFromURL("https://example.com/" + long + "b.js")
+	if a == b {
+		t.Errorf("overlong names both shortened to %q", a)
+	}
+}
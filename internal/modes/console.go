@@ -0,0 +1,24 @@
+package modes
+
+import (
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// consoleWarnings turns what the browser console said about scripts and
+// failed loads during discovery into warnings. They often explain missing
+// chunks: a CSP or CORS block, or a lazy import that 404ed.
+func consoleWarnings(targetURL string, discovered *fetch.DiscoveredResources) []warn.Warning {
+	var warnings []warn.Warning
+	for _, e := range discovered.Console {
+		subject := e.URL
+		if subject == "" {
+			subject = targetURL
+		}
+		warnings = append(warnings, warn.New(warn.Console, subject, "%s %s: %s", e.Source, e.Level, e.Text))
+	}
+	if discovered.ConsoleDropped > 0 {
+		warnings = append(warnings, warn.New(warn.Console, targetURL, "%d more console messages not kept", discovered.ConsoleDropped))
+	}
+	return warnings
+}
@@ -0,0 +1,191 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// coverageFile is written to the domain directory by -coverage.
+const coverageFile = "coverage.json"
+
+// scriptCoverage is one script's entry in coverage.json.
+type scriptCoverage struct {
+	URL             string   `json:"url"`
+	File            string   `json:"file"`             // Saved file under downloaded_site
+	Length          int      `json:"length"`           // UTF-16 code units, the unit of V8 offsets
+	Executed        int      `json:"executed"`         // Code units inside executed ranges
+	ExecutedPercent float64  `json:"executed_percent"` // Executed / Length, 0-100
+	Map             string   `json:"map,omitempty"`    // Sourcemap used to attribute ranges to sources
+	SourcesExecuted []string `json:"sources_executed,omitempty"`
+	SourcesIdle     []string `json:"sources_idle,omitempty"` // Mapped sources with no executed code
+}
+
+// coverageReport is the content of coverage.json.
+type coverageReport struct {
+	schema.Versioned
+	Scripts         []scriptCoverage `json:"scripts"`
+	SourcesExecuted []string         `json:"sources_executed"` // Every source that ran, across all scripts
+}
+
+// writeCoverage annotates each downloaded script with the share of it that
+// ran during page load and, when the script's sourcemap was processed,
+// attributes the executed ranges to original sources. The result is
+// written to coverage.json. It returns the number of scripts and sources
+// with executed code.
+func (c *Config) writeCoverage(paths DomainPaths, discovered *fetch.DiscoveredResources, result *URLResult) (int, int) {
+	if discovered.CoverageErr != nil {
+		c.logger().Warn("Coverage unavailable", "error", discovered.CoverageErr)
+		c.addWarnings(&result.Warnings, warn.FromError(warn.Coverage, "", discovered.CoverageErr))
+		return 0, 0
+	}
+
+	mapFiles := make(map[string]string, len(result.Maps))
+	for _, m := range result.Maps {
+		if m.URL != "" {
+			mapFiles[m.URL] = m.File
+		}
+	}
+
+	report := coverageReport{Versioned: schema.Current(), Scripts: []scriptCoverage{}, SourcesExecuted: []string{}}
+	executedSources := make(map[string]bool)
+	for i := range result.Scripts {
+		record := &result.Scripts[i]
+		ranges, ok := discovered.Coverage[record.URL]
+		if !ok {
+			continue
+		}
+		file := record.File
+		if file == "" {
+			file = c.scriptFilename(record.URL)
+		}
+		scriptPath := filepath.Join(paths.DownloadedSite, file)
+		content, err := os.ReadFile(scriptPath)
+		if err != nil {
+			continue
+		}
+
+		lineStarts, length := utf16LineStarts(string(content))
+		executed := 0
+		for _, r := range ranges {
+			executed += min(r.End, length) - min(r.Start, length)
+		}
+		entry := scriptCoverage{URL: record.URL, File: file, Length: length, Executed: executed}
+		if length > 0 {
+			entry.ExecutedPercent = math.Round(float64(executed)*1000/float64(length)) / 10
+		}
+		percent := entry.ExecutedPercent
+		record.ExecutedPercent = &percent
+
+		mapPath := mapFiles[record.MapURL]
+		if record.MapURL == "inline" {
+			mapPath = scriptPath + ".inline.map"
+		}
+		if mapPath != "" {
+			ran, idle, err := executedSourcesOf(mapPath, lineStarts, ranges)
+			if err != nil {
+				c.notice("Coverage failed", "path", file, "error", err)
+			} else {
+				entry.Map = filepath.Base(mapPath)
+				entry.SourcesExecuted, entry.SourcesIdle = ran, idle
+				for _, s := range ran {
+					executedSources[s] = true
+				}
+			}
+		}
+		report.Scripts = append(report.Scripts, entry)
+	}
+
+	scriptsRan := 0
+	for _, s := range report.Scripts {
+		if s.Executed > 0 {
+			scriptsRan++
+		}
+	}
+	for s := range executedSources {
+		report.SourcesExecuted = append(report.SourcesExecuted, s)
+	}
+	sort.Strings(report.SourcesExecuted)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to encode %s: %w", coverageFile, err))
+		return scriptsRan, len(executedSources)
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, coverageFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to write %s: %w", coverageFile, err))
+	}
+
+	c.success("Recorded coverage to "+coverageFile,
+		"count", len(report.Scripts), "ran", scriptsRan, "sources", len(executedSources))
+	return scriptsRan, len(executedSources)
+}
+
+// executedSourcesOf splits the sources of the sourcemap at mapPath into
+// those with at least one mapping inside an executed range and those
+// without. Sources with no mappings at all are left out of both.
+func executedSourcesOf(mapPath string, lineStarts []int, ranges []fetch.ExecutedRange) ([]string, []string, error) {
+	sm, err := sourcemap.ParseFile(mapPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	mappings, err := sm.DecodeMappings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mapped := make(map[int]bool)
+	ran := make(map[int]bool)
+	for _, m := range mappings {
+		if m.Source < 0 || m.Source >= len(sm.Sources) || m.GeneratedLine >= len(lineStarts) {
+			continue
+		}
+		mapped[m.Source] = true
+		if !ran[m.Source] && inRanges(lineStarts[m.GeneratedLine]+m.GeneratedColumn, ranges) {
+			ran[m.Source] = true
+		}
+	}
+
+	var executed, idle []string
+	for i := range mapped {
+		if ran[i] {
+			executed = append(executed, sm.Sources[i])
+		} else {
+			idle = append(idle, sm.Sources[i])
+		}
+	}
+	sort.Strings(executed)
+	sort.Strings(idle)
+	return executed, idle, nil
+}
+
+// inRanges reports whether offset falls inside one of the sorted, disjoint
+// ranges.
+func inRanges(offset int, ranges []fetch.ExecutedRange) bool {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].End > offset })
+	return i < len(ranges) && ranges[i].Start <= offset
+}
+
+// utf16LineStarts returns the UTF-16 offset at which each line of s starts,
+// and the UTF-16 length of s. Sourcemap columns and V8 coverage offsets
+// both count UTF-16 code units.
+func utf16LineStarts(s string) ([]int, int) {
+	starts := []int{0}
+	offset := 0
+	for _, r := range s {
+		offset += utf16.RuneLen(r)
+		if r == '\n' {
+			starts = append(starts, offset)
+		}
+	}
+	return starts, offset
+}
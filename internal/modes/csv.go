@@ -0,0 +1,101 @@
+package modes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	scriptsCSVHeader = []string{"url", "status", "bytes", "has_source_mapping_url", "map_url", "sources_restored", "file", "executed_percent",
+		"final_url", "content_type", "duration_ms", "error", "reused", "user_agent", "interaction_triggered", "map_source",
+		"prerendered", "prefetched_unused"}
+	mapsCSVHeader = []string{"map_url", "file", "version", "source_count", "has_sources_content", "toolchain_hints", "sources_restored",
+		"status", "final_url", "content_type", "bytes", "duration_ms", "error", "user_agent"}
+)
+
+// writeCSVReports writes scripts.csv and maps.csv into the domain directory
+// when CSV export is enabled.
+func writeCSVReports(cfg *Config, domainDir string, scripts []ScriptRecord, maps []MapRecord) error {
+	if !cfg.CSVExport {
+		return nil
+	}
+
+	scriptRows := make([][]string, 0, len(scripts))
+	for _, s := range scripts {
+		scriptRows = append(scriptRows, []string{
+			s.URL,
+			strconv.Itoa(s.Status),
+			strconv.FormatInt(s.Bytes, 10),
+			strconv.FormatBool(s.HasSourceMappingURL),
+			s.MapURL,
+			strconv.Itoa(s.SourcesRestored),
+			s.File,
+			formatPercent(s.ExecutedPercent),
+			s.FinalURL,
+			s.ContentType,
+			strconv.FormatInt(s.DurationMS, 10),
+			s.Error,
+			strconv.FormatBool(s.Reused),
+			s.UserAgent,
+			strconv.FormatBool(s.Triggered),
+			s.MapSource,
+			strconv.FormatBool(s.Prerendered),
+			strconv.FormatBool(s.PrefetchedUnused),
+		})
+	}
+	if err := writeCSV(filepath.Join(domainDir, "scripts.csv"), scriptsCSVHeader, scriptRows); err != nil {
+		return err
+	}
+
+	mapRows := make([][]string, 0, len(maps))
+	for _, m := range maps {
+		mapRows = append(mapRows, []string{
+			m.URL,
+			m.File,
+			strconv.Itoa(m.Version),
+			strconv.Itoa(m.SourceCount),
+			strconv.FormatBool(m.HasSourcesContent),
+			strings.Join(m.ToolchainHints, ";"),
+			strconv.Itoa(m.SourcesRestored),
+			strconv.Itoa(m.Status),
+			m.FinalURL,
+			m.ContentType,
+			strconv.FormatInt(m.Bytes, 10),
+			strconv.FormatInt(m.DurationMS, 10),
+			m.Error,
+			m.UserAgent,
+		})
+	}
+	return writeCSV(filepath.Join(domainDir, "maps.csv"), mapsCSVHeader, mapRows)
+}
+
+// formatPercent formats an optional percentage, empty when unset.
+func formatPercent(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p, 'f', 1, 64)
+}
+
+// writeCSV writes a header and rows to path using RFC 4180 quoting.
+func writeCSV(path string, header []string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package modes
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readCSV reads back a file writeCSVReports wrote. The reader also checks
+// that every row has as many fields as the header.
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("%s does not parse: %v", filepath.Base(path), err)
+	}
+	return rows
+}
+
+func TestWriteCSVReports(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	cfg.CSVExport = true
+	dir := t.TempDir()
+
+	executed := 37.5
+	scripts := []ScriptRecord{
+		{
+			URL:                 `https://example.com/app.js?a=1,b="2"`,
+			Status:              200,
+			Bytes:               1234,
+			HasSourceMappingURL: true,
+			MapURL:              "https://example.com/app.js.map",
+			SourcesRestored:     12,
+			File:                "app.js",
+			ExecutedPercent:     &executed,
+			ContentType:         "application/javascript; charset=utf-8",
+			DurationMS:          85,
+			Triggered:           true,
+			MapSource:           "comment",
+		},
+		{
+			URL:       "https://example.com/broken.js",
+			Error:     "failed to download:\nline two, with a comma",
+			UserAgent: "Mozilla/5.0 (X11; Linux x86_64) \"quoted\"",
+		},
+	}
+	maps := []MapRecord{
+		{
+			URL:               "https://example.com/app.js.map",
+			File:              "app.js.map",
+			Version:           3,
+			SourceCount:       14,
+			HasSourcesContent: true,
+			ToolchainHints:    []string{"webpack", "babel"},
+			SourcesRestored:   12,
+			Status:            200,
+			Bytes:             98765,
+		},
+		{File: "中文 map.js.map", Error: `parse error: "}" expected`},
+	}
+	if err := writeCSVReports(cfg, dir, scripts, maps); err != nil {
+		t.Fatal(err)
+	}
+
+	wantScripts := [][]string{
+		scriptsCSVHeader,
+		{`https://example.com/app.js?a=1,b="2"`, "200", "1234", "true", "https://example.com/app.js.map", "12", "app.js", "37.5",
+			"", "application/javascript; charset=utf-8", "85", "", "false", "", "true", "comment", "false", "false"},
+		{"https://example.com/broken.js", "0", "0", "false", "", "0", "", "",
+			"", "", "0", "failed to download:\nline two, with a comma", "false", "Mozilla/5.0 (X11; Linux x86_64) \"quoted\"", "false", "", "false", "false"},
+	}
+	if got := readCSV(t, filepath.Join(dir, "scripts.csv")); !reflect.DeepEqual(got, wantScripts) {
+		t.Errorf("scripts.csv =\n%q\nwant\n%q", got, wantScripts)
+	}
+
+	wantMaps := [][]string{
+		mapsCSVHeader,
+		{"https://example.com/app.js.map", "app.js.map", "3", "14", "true", "webpack;babel", "12", "200", "", "", "98765", "0", "", ""},
+		{"", "中文 map.js.map", "0", "0", "false", "", "0", "0", "", "", "0", "0", `parse error: "}" expected`, ""},
+	}
+	if got := readCSV(t, filepath.Join(dir, "maps.csv")); !reflect.DeepEqual(got, wantMaps) {
+		t.Errorf("maps.csv =\n%q\nwant\n%q", got, wantMaps)
+	}
+}
+
+func TestWriteCSVReportsDisabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	dir := t.TempDir()
+	if err := writeCSVReports(cfg, dir, []ScriptRecord{{URL: "https://example.com/app.js"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("wrote %d file(s) without -csv", len(entries))
+	}
+}
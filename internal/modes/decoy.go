@@ -0,0 +1,60 @@
+package modes
+
+import (
+	"os"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// decoyOf classifies the map at mapPath, which parsed into sm or failed to
+// parse with err. Only a map that failed or lists no sources is read
+// again; a map with sources is never a decoy.
+func decoyOf(mapPath string, sm *sourcemap.SourceMap, err error) sourcemap.Decoy {
+	if err == nil && (len(sm.Sources) > 0 || len(sm.Sections) > 0) {
+		return ""
+	}
+	data, rerr := os.ReadFile(mapPath)
+	if rerr != nil {
+		return ""
+	}
+	return sourcemap.ClassifyDecoy(data)
+}
+
+// decoyMapRecord builds the MapRecord of a decoy map, reports it as a
+// "map_decoy" event, and returns the warning that lists it.
+func (c *Config) decoyMapRecord(mapURL, file string, info fetch.DownloadInfo, decoy sourcemap.Decoy) (MapRecord, warn.Warning) {
+	id := mapIdentifier(mapURL, file)
+	c.emit("map_decoy", map[string]interface{}{
+		"map":   id,
+		"decoy": string(decoy),
+	})
+	c.notice("Skipping decoy sourcemap", "map", id, "decoy", string(decoy), "reason", decoy.Describe())
+	m := MapRecord{URL: mapURL, File: file, Decoy: string(decoy)}
+	m.setDownload(info)
+	return m, warn.New(warn.DecoyMap, id, "decoy sourcemap skipped: %s", decoy.Describe())
+}
+
+// CountMaps returns how many of maps are sourcemaps rather than decoys,
+// whether or not they could be processed.
+func CountMaps(maps []MapRecord) int {
+	n := 0
+	for _, m := range maps {
+		if m.Decoy == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Decoys returns the maps that were found to be decoys.
+func Decoys(maps []MapRecord) []MapRecord {
+	var decoys []MapRecord
+	for _, m := range maps {
+		if m.Decoy != "" {
+			decoys = append(decoys, m)
+		}
+	}
+	return decoys
+}
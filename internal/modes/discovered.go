@@ -0,0 +1,145 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/filter"
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+// Reasons a discovered URL is left out of processing.
+const (
+	skipScopeFile  = "scope_file"  // Host not in -scope-file
+	skipScope      = "scope"       // Outside -scope
+	skipThirdParty = "third_party" // Known third party on the denylist
+	skipFiltered   = "filtered"    // Excluded by -include-url/-exclude-url
+	skipRobots     = "robots"      // Disallowed by robots.txt
+	skipSeen       = "seen"        // Processed by an earlier run (-seen)
+)
+
+// Ways a URL is discovered.
+const (
+	SourceBrowser   = "browser"   // Requested while the browser loaded the page
+	SourceReference = "reference" // A map named by a script's sourceMappingURL or SourceMap header
+	SourceTemplate  = "template"  // A map found at -map-url-template for a script that names none
+)
+
+// DiscoveredURL is a script or sourcemap URL found for a target, whether
+// or not it was processed.
+type DiscoveredURL struct {
+	URL      string `json:"url"`
+	Kind     string `json:"kind"`               // "script" or "map"
+	Source   string `json:"source"`             // SourceBrowser, SourceReference, or SourceTemplate
+	Referrer string `json:"referrer,omitempty"` // Script that named a referenced map
+	Skipped  string `json:"skipped,omitempty"`  // Why it was left out of processing, "" if it wasn't
+	Target   string `json:"target"`             // Page it was discovered for
+}
+
+// skipReason returns why u, found for a run against target, is left out
+// of processing, or "" if it is selected. robots.txt is checked separately.
+func (c *Config) skipReason(target *url.URL, u string) string {
+	switch {
+	case !c.ScopeList.AllowURL(u):
+		return skipScopeFile
+	case !filter.InScope(c.Scope, target, u, c.AllowHosts):
+		return skipScope
+	case c.Denylist.Match(u):
+		return skipThirdParty
+	case !c.Filter.AllowURL(u):
+		return skipFiltered
+	}
+	return ""
+}
+
+// discoveredURLs lists the scripts and maps of discovered found for a run
+// against target, given the ones that were selected for processing.
+func (c *Config) discoveredURLs(target *url.URL, targetURL string, discovered []string, kind string, selected []string) []DiscoveredURL {
+	chosen := make(map[string]bool, len(selected))
+	for _, u := range selected {
+		chosen[u] = true
+	}
+	out := make([]DiscoveredURL, 0, len(discovered))
+	for _, u := range discovered {
+		d := DiscoveredURL{URL: u, Kind: kind, Source: SourceBrowser, Target: targetURL}
+		if !chosen[u] {
+			if d.Skipped = c.skipReason(target, u); d.Skipped == "" {
+				d.Skipped = skipRobots
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// RunDiscover loads a page like RunURL and returns the scripts and maps it
+// requested, without downloading any of them or writing output.
+func RunDiscover(cfg *Config, targetURL string) ([]DiscoveredURL, error) {
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		return nil, fmt.Errorf("invalid URL: must include http:// or https:// scheme")
+	}
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if !cfg.ScopeList.Allows(parsed.Host) {
+		return nil, fmt.Errorf("%s is not in the scope file", parsed.Hostname())
+	}
+
+	parsed, discovered, robots, err := cfg.discoverPage(targetURL, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	var outOfScope, thirdParty, filtered, disallowed int
+	scripts := cfg.selectURLs(parsed, discovered.Scripts, &outOfScope, &thirdParty, &filtered, nil)
+	maps := cfg.selectURLs(parsed, discovered.SourceMaps, &outOfScope, &thirdParty, &filtered, nil)
+	scripts = robots.filter(scripts, &disallowed)
+	maps = robots.filter(maps, &disallowed)
+
+	urls := cfg.discoveredURLs(parsed, targetURL, discovered.Scripts, "script", scripts)
+	urls = append(urls, cfg.discoveredURLs(parsed, targetURL, discovered.SourceMaps, "map", maps)...)
+	cfg.emit("discovery_complete", map[string]int{
+		"scripts":  len(scripts),
+		"filtered": filtered,
+	})
+	return urls, nil
+}
+
+// WriteDiscoveredURLs writes urls to path: one URL per line, or with
+// asJSON a list with each URL's metadata. The plain list leaves out URLs
+// on hosts outside -scope-file, so it can be handed to other tools as is.
+func WriteDiscoveredURLs(path string, urls []DiscoveredURL, asJSON bool) error {
+	var data []byte
+	if asJSON {
+		var err error
+		if data, err = json.MarshalIndent(schema.Items(urls), "", "  "); err != nil {
+			return fmt.Errorf("failed to encode URL list: %w", err)
+		}
+		data = append(data, '\n')
+	} else if lines := DiscoveredURLLines(urls); len(lines) > 0 {
+		data = []byte(strings.Join(lines, "\n") + "\n")
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write URL list: %w", err)
+	}
+	return nil
+}
+
+// DiscoveredURLLines returns the URLs of urls, each once, leaving out
+// those outside -scope-file.
+func DiscoveredURLLines(urls []DiscoveredURL) []string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, u := range urls {
+		if seen[u.URL] || u.Skipped == skipScopeFile {
+			continue
+		}
+		seen[u.URL] = true
+		lines = append(lines, u.URL)
+	}
+	return lines
+}
@@ -0,0 +1,60 @@
+package modes
+
+import (
+	"fmt"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// Emulation selects the device browser discovery loads the page as.
+// Responsive apps may lazy-load other chunks at mobile breakpoints, and
+// m-dot sites may serve other bundles altogether.
+type Emulation string
+
+// Emulations.
+const (
+	EmulateDesktop Emulation = "desktop" // Chrome's own window and User-Agent
+	EmulateMobile  Emulation = "mobile"  // A phone's screen, touch input, and User-Agent
+	EmulateBoth    Emulation = "both"    // Desktop, then mobile, merging what each finds
+)
+
+// ParseEmulation validates an emulation name. An empty name is desktop.
+func ParseEmulation(name string) (Emulation, error) {
+	if name == "" {
+		return EmulateDesktop, nil
+	}
+	switch e := Emulation(name); e {
+	case EmulateDesktop, EmulateMobile, EmulateBoth:
+		return e, nil
+	}
+	return "", fmt.Errorf("unknown emulation %q (valid: desktop, mobile, both)", name)
+}
+
+// discoverMobile loads the page again as a phone for -emulate both and
+// merges what it finds into discovered. It returns the number of scripts
+// only the mobile pass found; a failed pass is a warning.
+func (c *Config) discoverMobile(targetURL string, discovered *fetch.DiscoveredResources) (int, []warn.Warning) {
+	if c.Emulate != EmulateBoth {
+		return 0, nil
+	}
+	c.logger().Info("Loading the page again as a mobile browser...", "url", targetURL)
+
+	mobile := *c
+	mobile.Emulate = EmulateMobile
+	browser := mobile.newBrowser()
+	browser.DumpModules = c.DumpModules
+	browser.Coverage = c.Coverage
+	found, err := browser.DiscoverResources(targetURL)
+	if err != nil {
+		return 0, []warn.Warning{warn.FromError(warn.Emulation, targetURL, err)}
+	}
+	var warnings []warn.Warning
+	if found.NavigationErr != nil {
+		warnings = append(warnings, warn.New(warn.Navigation, targetURL, "mobile pass: %v", found.NavigationErr))
+	}
+
+	before := len(discovered.Scripts) + len(discovered.Embedded)
+	discovered.Merge(found)
+	return len(discovered.Scripts) + len(discovered.Embedded) - before, warnings
+}
@@ -0,0 +1,168 @@
+package modes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thesavant42/dejank/internal/envars"
+	"github.com/thesavant42/dejank/internal/findings"
+)
+
+// DefaultEnvDir is where the env command writes when no output directory
+// is given, so that running it from a project root leaves the project's own
+// .env alone.
+const DefaultEnvDir = "dejank-env"
+
+// envJSONFile is where the env command writes the variables it found, with
+// the file each came from.
+const envJSONFile = "env.json"
+
+// envScanExts are the files the env command reads.
+var envScanExts = map[string]bool{
+	".js": true, ".mjs": true, ".cjs": true, ".jsx": true,
+	".ts": true, ".tsx": true, ".html": true, ".htm": true,
+}
+
+// envMaxFileSize caps the size of files the env command reads, skipping
+// media and data blobs with a scriptable extension.
+const envMaxFileSize = 20 << 20
+
+// EnvOptions are the options of the env command.
+type EnvOptions struct {
+	OutputDir string    // Where .env and env.json are written
+	Stdin     io.Reader // Read when the target is "-"
+}
+
+// EnvVar is an environment variable found by the env command.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	File  string `json:"file"` // First file it was found in, "-" for stdin
+}
+
+// EnvResult contains the outcome of an env run.
+type EnvResult struct {
+	Vars     []EnvVar           // Sorted by name
+	Findings []findings.Finding // Secrets and service configs; env vars are in Vars
+	Scanned  int                // Files read
+	EnvFile  string             // Path of the .env written, "" if no vars were found
+	JSONFile string             // Path of env.json, "" if no vars were found
+	Errors   []error
+}
+
+// RunEnv extracts inlined environment variables and scans for secrets in
+// every JS, TS, and HTML file under dir, or in stdin when dir is "-". The
+// directory does not need dejank's layout, so any mirror or unpacked app
+// works.
+func RunEnv(cfg *Config, dir string, opts EnvOptions) (*EnvResult, error) {
+	result := &EnvResult{}
+	vars := make(map[string]EnvVar)
+	scan := func(content, file string) {
+		result.Scanned++
+		for name, value := range envars.ExtractEnvVars(content) {
+			if _, ok := vars[name]; !ok {
+				vars[name] = EnvVar{Name: name, Value: value, File: file}
+			}
+		}
+		for _, f := range findings.ScanContent(content, file) {
+			if f.RuleID != findings.RuleEnvVar {
+				result.Findings = append(result.Findings, f)
+			}
+		}
+	}
+
+	if dir == "-" {
+		content, err := io.ReadAll(opts.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		scan(string(content), "-")
+	} else {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", dir)
+		}
+		err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				cfg.addErrors(&result.Errors, fmt.Errorf("walk error at %s: %w", path, err))
+				return nil
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !envScanExts[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			if info, err := d.Info(); err != nil || info.Size() > envMaxFileSize {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				cfg.addErrors(&result.Errors, fmt.Errorf("failed to read %s: %w", path, err))
+				return nil
+			}
+			if bytes.IndexByte(content, 0) != -1 || !utf8.Valid(content) {
+				return nil
+			}
+			scan(string(content), path)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+		}
+	}
+
+	for _, v := range vars {
+		result.Vars = append(result.Vars, v)
+	}
+	sort.Slice(result.Vars, func(i, j int) bool { return result.Vars[i].Name < result.Vars[j].Name })
+
+	if len(result.Vars) == 0 {
+		return result, nil
+	}
+	if err := writeEnv(cfg, result, opts.OutputDir); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+	return result, nil
+}
+
+// writeEnv writes the variables of result to .env and env.json in dir and
+// records their paths. An existing .env is only replaced with -f.
+func writeEnv(cfg *Config, result *EnvResult, dir string) error {
+	envPath := filepath.Join(dir, ".env")
+	if _, err := os.Stat(envPath); err == nil && !cfg.Force {
+		return fmt.Errorf("%s already exists (use -f to overwrite, or -out to write elsewhere)", envPath)
+	}
+	values := make(map[string]string, len(result.Vars))
+	for _, v := range result.Vars {
+		values[v.Name] = v.Value
+	}
+	if err := envars.WriteEnvFile(values, envPath); err != nil {
+		return fmt.Errorf("failed to write .env file: %w", err)
+	}
+	result.EnvFile = envPath
+
+	data, err := json.MarshalIndent(result.Vars, "", "  ")
+	if err != nil {
+		return err
+	}
+	jsonPath := filepath.Join(dir, envJSONFile)
+	if err := os.WriteFile(jsonPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", envJSONFile, err)
+	}
+	result.JSONFile = jsonPath
+	return nil
+}
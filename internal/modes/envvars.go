@@ -0,0 +1,82 @@
+package modes
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/envars"
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// extractEnvVars collects the environment variables inlined into the
+// downloaded scripts and the restored sources and writes them to
+// restored_sources/.env. It returns how many were written. Both trees are
+// walked in name order, downloads first, and the first value found for a
+// variable is kept; later different values are warnings.
+func (c *Config) extractEnvVars(paths DomainPaths, errs *[]error, warnings *[]warn.Warning) int {
+	c.logger().Info("Extracting environment variables from bundled JS...", "path", paths.DownloadedSite)
+	allEnvVars := make(map[string]string)
+	for _, dir := range []string{paths.DownloadedSite, paths.RestoredSources} {
+		filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				// Vendored packages are full of example values
+				if d.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !fetch.IsScriptPath(d.Name()) {
+				return nil
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return nil
+			}
+			c.mergeEnvVars(allEnvVars, envars.ExtractEnvVars(string(content)), p, warnings)
+			return nil
+		})
+	}
+
+	// Write .env file if we found any environment variables
+	if len(allEnvVars) == 0 {
+		return 0
+	}
+	envPath := filepath.Join(paths.RestoredSources, ".env")
+	if err := envars.WriteEnvFile(allEnvVars, envPath); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write .env file: %w", err))
+		return 0
+	}
+	c.success("Extracted environment variables", "count", len(allEnvVars), "path", envPath)
+	return len(allEnvVars)
+}
+
+// mergeEnvVars adds the variables found in file to all, warning about each
+// one all already has with a different value.
+func (c *Config) mergeEnvVars(all, found map[string]string, file string, warnings *[]warn.Warning) {
+	for _, conflict := range envars.Merge(all, found) {
+		c.addWarnings(warnings, warn.New(warn.EnvConflict, file, "%s is %q here but %q in an earlier script; kept the first",
+			conflict.Key, conflict.Ignored, conflict.Kept))
+	}
+}
+
+// reconcileEnvFile merges the variables of the .env at envPath from an
+// earlier run into found. Values in the file win, since they were seen
+// first; a variable found with a different value now is a warning. A
+// missing file leaves found as is.
+func (c *Config) reconcileEnvFile(envPath string, found map[string]string, warnings *[]warn.Warning) map[string]string {
+	existing, err := envars.ReadEnvFile(envPath)
+	if err != nil {
+		return found
+	}
+	for _, conflict := range envars.Merge(existing, found) {
+		c.addWarnings(warnings, warn.New(warn.EnvConflict, envPath, "%s is %q in the downloads but %q in the existing .env; kept the existing value",
+			conflict.Key, conflict.Ignored, conflict.Kept))
+	}
+	return existing
+}
@@ -0,0 +1,48 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/envars"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+func TestExtractEnvVarsRecursive(t *testing.T) {
+	paths := pathsAt(t.TempDir())
+	files := map[string]string{
+		filepath.Join(paths.DownloadedSite, "main.js"):                            `{NODE_ENV:"production"}`,
+		filepath.Join(paths.DownloadedSite, "static", "js", "chunk.js"):           `{REACT_APP_API:"https://api.example.com"}`,
+		filepath.Join(paths.RestoredSources, "src", "config", "env.ts"):           `const env={VITE_KEY:"abc"}`,
+		filepath.Join(paths.RestoredSources, "node_modules", "pkg", "example.js"): `{REACT_APP_SAMPLE:"example"}`,
+		filepath.Join(paths.RestoredSources, "src", "notes.md"):                   `{VITE_DOC:"ignored"}`,
+		filepath.Join(paths.DownloadedSite, "static", "js", "chunk.js.map"):       `{"VITE_MAP":"ignored"}`,
+	}
+	for p, content := range files {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var errs []error
+	var warnings []warn.Warning
+	cfg := DefaultConfig()
+	if n := cfg.extractEnvVars(paths, &errs, &warnings); n != 3 {
+		t.Errorf("extracted %d variables, want 3", n)
+	}
+	got, err := envars.ReadEnvFile(filepath.Join(paths.RestoredSources, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"NODE_ENV": "production", "REACT_APP_API": "https://api.example.com", "VITE_KEY": "abc"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf(".env = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/assets"
+)
+
+// ExtractAssetsOptions are the options of the extract-assets command.
+type ExtractAssetsOptions struct {
+	OutputDir string // Where decoded assets are written ("" = the domain directory's extracted_assets, or <dir>/extracted_assets)
+	BaseURL   string // Site the sources came from; webpack asset stubs are replaced with the real files from it ("" = skip)
+}
+
+// ExtractAssetsResult contains the outcome of an extract-assets run.
+type ExtractAssetsResult struct {
+	InputDir   string // Directory that was scanned
+	OutputDir  string // Directory decoded assets were written to
+	Extracted  int    // base64 assets decoded
+	Downloaded int    // webpack asset stubs replaced with the real file
+	Filtered   int    // Files skipped by -include-source/-exclude-source
+	Errors     []error
+}
+
+// RunExtractAssets runs only the asset extraction step over an existing
+// tree: a domain directory (flat or versioned), whose restored_sources is
+// scanned, or any directory of restored sources.
+func RunExtractAssets(cfg *Config, dir string, opts ExtractAssetsOptions) (*ExtractAssetsResult, error) {
+	inputDir := dir
+	outputDir := filepath.Join(dir, "extracted_assets")
+	if runDir := resolveRunDir(dir); isDir(filepath.Join(runDir, "restored_sources")) {
+		paths := pathsAt(runDir)
+		inputDir, outputDir = paths.RestoredSources, paths.ExtractedAssets
+	} else if !isDir(dir) {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if opts.OutputDir != "" {
+		outputDir = opts.OutputDir
+	}
+
+	result := &ExtractAssetsResult{InputDir: inputDir, OutputDir: outputDir}
+	allow := cfg.Filter.AllowSource
+
+	if opts.BaseURL != "" {
+		cfg.logger().Info("Downloading webpack static assets...", "url", opts.BaseURL)
+		downloaded := assets.DownloadWebpackAssetsFiltered(opts.BaseURL, inputDir, cfg.Client, allow)
+		result.Downloaded = downloaded.DownloadedCount
+		cfg.addErrors(&result.Errors, downloaded.Errors...)
+	}
+
+	cfg.logger().Info("Scanning for embedded assets", "path", inputDir)
+	extracted := assets.ExtractFromDirectoryFiltered(inputDir, outputDir, allow)
+	result.Extracted = extracted.ExtractedCount
+	result.Filtered = extracted.FilteredCount
+	cfg.addErrors(&result.Errors, extracted.Errors...)
+
+	return result, nil
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
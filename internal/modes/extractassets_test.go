@@ -0,0 +1,123 @@
+package modes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/filter"
+)
+
+// assetSources are restored sources holding two inlined assets, one that
+// does not decode, a webpack asset stub, and ordinary code.
+var assetSources = map[string]string{
+	"src/logo.png.js":         `export default "data:image/png;base64,iVBORw0KGgo=";`,
+	"src/icons/check.svg.js":  `export default "data:image/svg+xml;base64,PHN2Zy8+";`,
+	"src/hero.jpg":            `module.exports = __webpack_public_path__ + "static/media/hero.1a2b3c.jpg";`,
+	"src/app.js":              `console.log("app");`,
+	"node_modules/lib/bad.js": `export default "data:image/png;base64,!!!";`,
+}
+
+func TestRunExtractAssets(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string // "domain" for a domain directory, "plain" for a bare tree
+		out      bool   // Set -out
+		exclude  []string
+		want     []string // Files written to the output directory
+		filtered int
+		errors   int
+	}{
+		{name: "domain directory", layout: "domain", want: []string{"check.svg", "logo.png"}, errors: 1},
+		{name: "plain tree", layout: "plain", want: []string{"check.svg", "logo.png"}, errors: 1},
+		{name: "out", layout: "domain", out: true, want: []string{"check.svg", "logo.png"}, errors: 1},
+		{name: "exclude", layout: "domain", exclude: []string{"node_modules/**", "src/icons/**"}, want: []string{"logo.png"}, filtered: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputRoot = t.TempDir()
+			if tt.exclude != nil {
+				f, err := filter.New(nil, nil, nil, tt.exclude, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				cfg.Filter = f
+			}
+
+			dir := filepath.Join(cfg.OutputRoot, "example.com")
+			sources, wantOut := dir, filepath.Join(dir, "extracted_assets")
+			if tt.layout == "domain" {
+				sources = filepath.Join(dir, "restored_sources")
+			}
+			writeFiles(t, sources, assetSources)
+			opts := ExtractAssetsOptions{}
+			if tt.out {
+				opts.OutputDir = filepath.Join(t.TempDir(), "assets")
+				wantOut = opts.OutputDir
+			}
+
+			result, err := RunExtractAssets(cfg, dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.InputDir != sources || result.OutputDir != wantOut {
+				t.Errorf("scanned %s into %s, want %s into %s", result.InputDir, result.OutputDir, sources, wantOut)
+			}
+			if result.Extracted != len(tt.want) || result.Filtered != tt.filtered || len(result.Errors) != tt.errors {
+				t.Errorf("extracted %d, filtered %d, errors %v; want %d, %d, %d errors",
+					result.Extracted, result.Filtered, result.Errors, len(tt.want), tt.filtered, tt.errors)
+			}
+			if got := listFiles(t, wantOut); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrote %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExtractAssetsBaseURL(t *testing.T) {
+	jpeg := "\xff\xd8\xff\xe0 hero"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/static/media/hero.1a2b3c.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, jpeg)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	dir := filepath.Join(cfg.OutputRoot, "example.com")
+	writeFiles(t, filepath.Join(dir, "restored_sources"), map[string]string{
+		"src/hero.jpg": assetSources["src/hero.jpg"],
+		"src/app.js":   assetSources["src/app.js"],
+	})
+
+	result, err := RunExtractAssets(cfg, dir, ExtractAssetsOptions{BaseURL: srv.URL + "/app/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Downloaded != 1 || len(result.Errors) != 0 {
+		t.Fatalf("downloaded %d, errors %v", result.Downloaded, result.Errors)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "restored_sources", "src", "hero.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != jpeg {
+		t.Errorf("hero.jpg = %q", data)
+	}
+}
+
+func TestRunExtractAssetsMissingDir(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	if _, err := RunExtractAssets(cfg, filepath.Join(cfg.OutputRoot, "missing"), ExtractAssetsOptions{}); err == nil {
+		t.Error("RunExtractAssets succeeded on a missing directory")
+	}
+}
@@ -0,0 +1,149 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/format"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// FormatResult contains the results of formatting a restored tree.
+type FormatResult struct {
+	Dir       string // The directory that was formatted
+	Formatted int    // JS/TS files pretty-printed
+	Unchanged int    // JS/TS files the formatter left as they were
+	Errors    []error
+	Warnings  []warn.Warning
+}
+
+// RunFormat pretty-prints the JS/TS files under dir in place, vendor code
+// included, for sources restored without -format-vendor. dir may be a
+// domain directory, whose restored_sources are formatted, or any directory
+// inside restored_sources. Files over -format-max-size are left as they
+// are. Files keep their permissions and modification times.
+func RunFormat(cfg *Config, dir string) (*FormatResult, error) {
+	dir = resolveRunDir(dir)
+	if restored := filepath.Join(dir, "restored_sources"); isDir(restored) {
+		dir = restored
+	}
+	if !isDir(dir) {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	result := &FormatResult{Dir: dir}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			cfg.addErrors(&result.Errors, err)
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !format.IsJSFile(path) {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if info, err := d.Info(); err == nil && cfg.FormatMaxSize > 0 && info.Size() > cfg.FormatMaxSize {
+			cfg.addWarnings(&result.Warnings, warn.New(warn.FormatSkipped, filepath.ToSlash(rel), "left unformatted: %d bytes is over -format-max-size", info.Size()))
+			result.Unchanged++
+			return nil
+		}
+		changed, err := formatFile(path)
+		if err != nil {
+			cfg.addWarnings(&result.Warnings, warn.New(warn.FormatFallback, filepath.ToSlash(rel), "left unformatted: %v", err))
+			result.Unchanged++
+			return nil
+		}
+		if !changed {
+			result.Unchanged++
+			return nil
+		}
+		result.Formatted++
+		cfg.success("Formatted", "path", path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// formatFile pretty-prints the file at path, keeping CRLF line endings,
+// its permissions, and its modification time. It reports whether the file
+// changed.
+func formatFile(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	content := string(data)
+	formatted, err := format.Beautify(content, path)
+	if err != nil {
+		return false, err
+	}
+	if strings.Contains(content, "\r\n") {
+		formatted = strings.ReplaceAll(formatted, "\n", "\r\n")
+	}
+	if formatted == content {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(formatted), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// unformattedFile is written to the domain directory when restored JS/TS
+// sources were too large to format or the formatter failed on them.
+const unformattedFile = "unformatted.json"
+
+// writeUnformatted records which of a run's restored files were written
+// unformatted for their size or a formatter failure. Vendor code, which
+// is unformatted by default, is left out.
+func (c *Config) writeUnformatted(paths DomainPaths, files []stats.File, errs *[]error) {
+	// The last map to write a path decides how it was written
+	last := make(map[string]stats.File)
+	var order []string
+	for _, f := range files {
+		if _, ok := last[f.Path]; !ok {
+			order = append(order, f.Path)
+		}
+		last[f.Path] = f
+	}
+	var entries []schema.Unformatted
+	for _, p := range order {
+		f := last[p]
+		if f.Unformatted == stats.UnformattedSize || f.Unformatted == stats.UnformattedError {
+			entries = append(entries, schema.Unformatted{Path: f.Path, Bytes: f.Bytes, Reason: f.Unformatted})
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to encode %s: %w", unformattedFile, err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, unformattedFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", unformattedFile, err))
+		return
+	}
+
+	c.logger().Info("Left large or unformattable files unformatted; see "+unformattedFile, "count", len(entries))
+}
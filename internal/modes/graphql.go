@@ -0,0 +1,39 @@
+package modes
+
+import (
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/graphql"
+)
+
+// graphqlDir is created in the domain directory when GraphQL is found.
+const graphqlDir = "graphql"
+
+func init() {
+	RegisterAnalyzer(analyzer{"graphql", "GraphQL operations and schemas in restored sources (graphql/)", func(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+		a.GraphQLOps = c.harvestGraphQL(paths, errs)
+	}})
+}
+
+// harvestGraphQL collects GraphQL operations and embedded schemas from a
+// domain's restored sources into graphql/ and returns the operation count.
+func (c *Config) harvestGraphQL(paths DomainPaths, errs *[]error) int {
+	h, scanErrs := graphql.HarvestDirectory(paths.RestoredSources)
+	for _, err := range scanErrs {
+		c.notice("GraphQL scan error", "error", err)
+	}
+	outDir := filepath.Join(paths.Base, graphqlDir)
+	if h.Empty() {
+		graphql.Remove(outDir) // Drop results from an earlier run
+		return 0
+	}
+
+	if err := h.Write(outDir); err != nil {
+		c.addErrors(errs, err)
+		return 0
+	}
+
+	c.success("Harvested GraphQL operations and schemas",
+		"operations", len(h.Operations), "schemas", len(h.Schemas), "path", outDir)
+	return len(h.Operations)
+}
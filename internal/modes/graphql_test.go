@@ -0,0 +1,45 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/graphql"
+)
+
+func TestHarvestGraphQLRerun(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	paths := GetDomainPaths(cfg.OutputRoot, "example.com")
+	outDir := filepath.Join(paths.Base, graphqlDir)
+
+	writeFiles(t, paths.RestoredSources, map[string]string{
+		"src/api.js":     "export const ME = gql`query Me { me { id } }`;",
+		"schema.graphql": "type Query { me: User }",
+	})
+	var errs []error
+	if n := cfg.harvestGraphQL(paths, &errs); n != 1 || len(errs) > 0 {
+		t.Fatalf("harvestGraphQL = %d, %v", n, errs)
+	}
+	writeFiles(t, outDir, map[string]string{"notes.md": "kept"})
+
+	// A rerun replaces the files it owns and leaves the rest
+	os.Remove(filepath.Join(paths.RestoredSources, "schema.graphql"))
+	if n := cfg.harvestGraphQL(paths, &errs); n != 1 || len(errs) > 0 {
+		t.Fatalf("second harvestGraphQL = %d, %v", n, errs)
+	}
+	if got, want := listFiles(t, outDir), []string{graphql.IndexFile, "notes.md", graphql.OperationsFile}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after rerun: %v, want %v", got, want)
+	}
+
+	// Nothing found removes only the earlier results
+	os.RemoveAll(filepath.Join(paths.RestoredSources, "src"))
+	if n := cfg.harvestGraphQL(paths, &errs); n != 0 || len(errs) > 0 {
+		t.Fatalf("empty harvestGraphQL = %d, %v", n, errs)
+	}
+	if got, want := listFiles(t, outDir), []string{"notes.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after empty run: %v, want %v", got, want)
+	}
+}
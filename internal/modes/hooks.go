@@ -0,0 +1,134 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/thesavant42/dejank/internal/launch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// HookPoint identifies a stage of a run at which hooks are invoked.
+type HookPoint string
+
+// Hook points, in the order they fire during a run.
+const (
+	HookAfterDownload HookPoint = "after_download" // Scripts and maps are on disk
+	HookAfterRestore  HookPoint = "after_restore"  // Sources are restored
+	HookAfterAssets   HookPoint = "after_assets"   // Env vars and assets are extracted
+)
+
+// HookPoints lists every valid hook point.
+var HookPoints = []HookPoint{HookAfterDownload, HookAfterRestore, HookAfterAssets}
+
+// HookFunc is called at a hook point with the run's directory layout and
+// result so far. The result is a *URLResult, *SingleResult, or *LocalResult
+// depending on the mode. Returned errors are recorded as warnings.
+type HookFunc func(ctx context.Context, point HookPoint, paths DomainPaths, result interface{}) error
+
+// ParseHookPoint validates a hook point name.
+func ParseHookPoint(name string) (HookPoint, error) {
+	for _, p := range HookPoints {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hook point %q (valid: after_download, after_restore, after_assets)", name)
+}
+
+// AddHook registers fn to run at point, after any hooks already registered.
+func (c *Config) AddHook(point HookPoint, fn HookFunc) {
+	if c.Hooks == nil {
+		c.Hooks = make(map[HookPoint][]HookFunc)
+	}
+	c.Hooks[point] = append(c.Hooks[point], fn)
+}
+
+// ctx returns the run's context, defaulting to Background.
+func (c *Config) ctx() context.Context {
+	if c.Context != nil {
+		return c.Context
+	}
+	return context.Background()
+}
+
+// runHooks invokes the hooks registered for point in order, appending any
+// failures to warnings. A cancelled context stops further hooks.
+func (c *Config) runHooks(point HookPoint, paths DomainPaths, result interface{}, warnings *[]warn.Warning) {
+	ctx := c.ctx()
+	for i, fn := range c.Hooks[point] {
+		if err := ctx.Err(); err != nil {
+			c.addWarnings(warnings, warn.New(warn.Hook, string(point), "hooks cancelled: %v", err))
+			return
+		}
+		if err := fn(ctx, point, paths, result); err != nil {
+			c.addWarnings(warnings, warn.New(warn.Hook, string(point), "hook #%d failed: %v", i+1, err))
+		}
+	}
+}
+
+// CommandHook returns a hook that runs an external command with the domain
+// directory appended as its final argument. The command is split into
+// words like a shell would, so arguments may be quoted, but nothing is
+// expanded. It also receives DEJANK_* environment variables describing
+// the run. Its output is passed through to stderr.
+func CommandHook(command string) (HookFunc, error) {
+	argv, err := launch.SplitCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hook command: %w", err)
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("empty hook command")
+	}
+
+	return func(ctx context.Context, point HookPoint, paths DomainPaths, result interface{}) error {
+		cmd := exec.CommandContext(ctx, argv[0], append(argv[1:], paths.Base)...)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), hookEnv(point, paths, result)...)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+		return nil
+	}, nil
+}
+
+// hookEnv describes a run as environment variables for external hooks.
+func hookEnv(point HookPoint, paths DomainPaths, result interface{}) []string {
+	env := []string{
+		"DEJANK_HOOK=" + string(point),
+		"DEJANK_DOMAIN_DIR=" + paths.Base,
+		"DEJANK_DOWNLOADED_SITE=" + paths.DownloadedSite,
+		"DEJANK_RESTORED_SOURCES=" + paths.RestoredSources,
+		"DEJANK_EXTRACTED_ASSETS=" + paths.ExtractedAssets,
+	}
+
+	switch r := result.(type) {
+	case *URLResult:
+		env = append(env,
+			"DEJANK_MODE=url",
+			"DEJANK_TARGET="+r.URL,
+			fmt.Sprintf("DEJANK_SCRIPTS=%d", r.ScriptsFound),
+			fmt.Sprintf("DEJANK_MAPS=%d", r.MapsDiscovered),
+			fmt.Sprintf("DEJANK_SOURCES=%d", r.SourcesRestored),
+		)
+	case *SingleResult:
+		env = append(env,
+			"DEJANK_MODE=single",
+			"DEJANK_TARGET="+r.URL,
+			fmt.Sprintf("DEJANK_MAPS=%d", CountMaps(r.Maps)),
+			fmt.Sprintf("DEJANK_SOURCES=%d", r.SourcesRestored),
+		)
+	case *LocalResult:
+		env = append(env,
+			"DEJANK_MODE=local",
+			"DEJANK_TARGET="+paths.Base,
+			fmt.Sprintf("DEJANK_MAPS=%d", r.MapsProcessed),
+			fmt.Sprintf("DEJANK_SOURCES=%d", r.SourcesRestored),
+		)
+	}
+
+	return env
+}
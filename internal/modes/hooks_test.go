@@ -0,0 +1,118 @@
+package modes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hookServer serves a script that names a map with one source.
+func hookServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.js":
+			io.WriteString(w, "console.log(1);\n//# sourceMappingURL=app.js.map\n")
+		case "/app.js.map":
+			io.WriteString(w, `{"version":3,"sources":["src/a.js"],"sourcesContent":["console.log(1)\n"],"mappings":"AAAA"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// stageHooks registers a hook at every point that records whether the map
+// was downloaded and its source restored when the hook ran.
+func stageHooks(t *testing.T, cfg *Config) map[HookPoint][2]bool {
+	seen := make(map[HookPoint][2]bool)
+	for _, point := range HookPoints {
+		cfg.AddHook(point, func(ctx context.Context, point HookPoint, paths DomainPaths, result interface{}) error {
+			_, mapErr := os.Stat(filepath.Join(paths.DownloadedSite, "app.js.map"))
+			_, srcErr := os.Stat(filepath.Join(paths.RestoredSources, "src", "a.js"))
+			seen[point] = [2]bool{mapErr == nil, srcErr == nil}
+			return nil
+		})
+	}
+	return seen
+}
+
+func TestHookStages(t *testing.T) {
+	srv := hookServer()
+	defer srv.Close()
+
+	want := map[HookPoint][2]bool{
+		HookAfterDownload: {true, false},
+		HookAfterRestore:  {true, true},
+		HookAfterAssets:   {true, true},
+	}
+	runs := map[string]func(cfg *Config) error{
+		"single": func(cfg *Config) error {
+			_, err := RunSingle(cfg, srv.URL+"/app.js")
+			return err
+		},
+		"url": func(cfg *Config) error {
+			plan := &Plan{Version: PlanVersion, Target: srv.URL + "/", Scripts: []PlannedScript{{URL: srv.URL + "/app.js"}}}
+			result, err := RunURLFromPlan(cfg, plan)
+			if err == nil && (result.SourcesRestored != 1 || result.Scripts[0].SourcesRestored != 1) {
+				t.Errorf("restored %d source(s), %d for the script; want 1", result.SourcesRestored, result.Scripts[0].SourcesRestored)
+			}
+			return err
+		},
+		"map": func(cfg *Config) error {
+			_, err := RunMap(cfg, srv.URL+"/app.js.map", MapOptions{})
+			return err
+		},
+	}
+	for name, run := range runs {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputRoot = t.TempDir()
+			seen := stageHooks(t, cfg)
+			if err := run(cfg); err != nil {
+				t.Fatal(err)
+			}
+			for point, w := range want {
+				if got := seen[point]; got != w {
+					t.Errorf("%s saw map=%v source=%v, want map=%v source=%v", point, got[0], got[1], w[0], w[1])
+				}
+			}
+		})
+	}
+}
+
+func TestCommandHookQuoting(t *testing.T) {
+	dir := t.TempDir()
+	hook, err := CommandHook(`sh -c 'printf "%s|%s" "$1" "$2" > "$2/args.txt"' hook "two words"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook(context.Background(), HookAfterAssets, pathsAt(dir), &URLResult{}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "two words|" + dir; string(got) != want {
+		t.Errorf("hook arguments = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"", "   ", `sh -c "unterminated`} {
+		if _, err := CommandHook(bad); err == nil {
+			t.Errorf("CommandHook(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestHookEnv(t *testing.T) {
+	env := strings.Join(hookEnv(HookAfterRestore, pathsAt("/out/example.com"), &URLResult{URL: "https://example.com/", SourcesRestored: 3}), "\n")
+	for _, want := range []string{"DEJANK_HOOK=after_restore", "DEJANK_MODE=url", "DEJANK_TARGET=https://example.com/", "DEJANK_SOURCES=3", "DEJANK_DOMAIN_DIR=/out/example.com"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("hook environment lacks %s:\n%s", want, env)
+		}
+	}
+}
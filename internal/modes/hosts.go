@@ -0,0 +1,60 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/hosts"
+)
+
+func init() {
+	RegisterAnalyzer(analyzer{"endpoints", "URLs and hosts referenced by downloaded and restored JS (hosts.json)", func(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+		a.HostsFound = c.inventoryHosts(paths, errs)
+	}})
+}
+
+// inventoryHosts writes urls.txt, hosts.txt, and hosts.json for the URLs
+// referenced by a domain's downloaded and restored JavaScript and returns
+// the number of hosts found.
+func (c *Config) inventoryHosts(paths DomainPaths, errs *[]error) int {
+	inv := hosts.New()
+	for _, dir := range []string{paths.DownloadedSite, paths.RestoredSources} {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		scanErrs := inv.ScanDirectory(dir)
+		for _, err := range scanErrs {
+			c.notice("URL scan error", "error", err)
+		}
+	}
+
+	found := inv.Hosts()
+	if len(found) == 0 {
+		// Drop results from an earlier run
+		for _, name := range []string{hosts.URLsFile, hosts.HostsFile, hosts.HostsJSONFile} {
+			os.Remove(filepath.Join(paths.Base, name))
+		}
+		return 0
+	}
+
+	if err := inv.Write(paths.Base); err != nil {
+		c.addErrors(errs, err)
+		return 0
+	}
+
+	var internal, local, storage int
+	for _, h := range found {
+		if h.Internal {
+			internal++
+		}
+		if h.Local {
+			local++
+		}
+		if h.CloudStorage != "" {
+			storage++
+		}
+	}
+	c.success("Inventoried URLs and hosts to "+hosts.HostsFile,
+		"urls", len(inv.URLs()), "hosts", len(found), "internal", internal, "local", local, "cloud_storage", storage)
+	return len(found)
+}
@@ -0,0 +1,46 @@
+package modes
+
+import (
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// interactionWarnings logs how each -interact and -auto-scroll step went
+// and returns the failed ones as warnings.
+func (c *Config) interactionWarnings(targetURL string, discovered *fetch.DiscoveredResources) []warn.Warning {
+	var warnings []warn.Warning
+	for _, r := range discovered.Interactions {
+		if r.Error != "" {
+			warnings = append(warnings, warn.New(warn.Interaction, targetURL, "%s: %s", r.Step, r.Error))
+		}
+		if r.Error != "" {
+			c.notice("Interaction failed", "step", r.Step, "error", r.Error)
+		} else {
+			c.logger().Info("Interaction", "step", r.Step)
+		}
+	}
+	if len(discovered.Triggered) > 0 {
+		c.logger().Info("Scripts loaded after interaction began", "count", len(discovered.Triggered))
+	}
+	return warnings
+}
+
+// tagScripts marks the records of the scripts in urls, such as those first
+// requested after interaction began, and returns how many there are.
+func tagScripts(scripts []ScriptRecord, urls []string, mark func(*ScriptRecord)) int {
+	if len(urls) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		set[u] = true
+	}
+	n := 0
+	for i := range scripts {
+		if set[scripts[i].URL] {
+			mark(&scripts[i])
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,39 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/licenses"
+)
+
+func init() {
+	RegisterAnalyzer(analyzer{"dependencies", "Third-party packages and their licenses (licenses.json)", func(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+		a.Licenses, a.Copyleft = c.inventoryLicenses(paths, errs)
+	}})
+}
+
+// inventoryLicenses writes licenses.json for a domain's third-party
+// packages and returns the package count per license family and the names
+// of copyleft packages.
+func (c *Config) inventoryLicenses(paths DomainPaths, errs *[]error) (map[string]int, []string) {
+	outPath := filepath.Join(paths.Base, licenses.File)
+	inv, err := licenses.Scan(paths.RestoredSources)
+	if err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to inventory licenses: %w", err))
+		return nil, nil
+	}
+	if len(inv.Packages) == 0 {
+		os.Remove(outPath) // Drop results from an earlier run
+		return nil, nil
+	}
+
+	if err := inv.Write(outPath); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", licenses.File, err))
+		return nil, nil
+	}
+
+	c.success("Inventoried package licenses to "+licenses.File, "count", len(inv.Packages))
+	return inv.Summary, inv.Copyleft
+}
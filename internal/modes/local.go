@@ -3,31 +3,54 @@ package modes
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/thesavant42/dejank/internal/assets"
 	"github.com/thesavant42/dejank/internal/envars"
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/schema"
 	"github.com/thesavant42/dejank/internal/sourcemap"
-	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
 )
 
 // LocalResult contains the results of processing local files.
 type LocalResult struct {
+	OutputDirs       []string // Domain directories that were processed
 	TargetsProcessed int
 	MapsProcessed    int
 	SourcesRestored  int
 	AssetsExtracted  int
 	EnvVarsExtracted int
+	SourcesMatched   int // Sources that passed -only and source filters
+	SourcesFiltered  int // Sources skipped by -only/-include-source/-exclude-source
+	Scripts          []ScriptRecord
+	Maps             []MapRecord
 	Errors           []error
+	Warnings         []warn.Warning // Non-fatal problems such as skipped sources and failed hooks
+	BudgetExceeded   string         // The -max-* limit that stopped the run early, "" if none
+	BudgetSkipped    int            // Scripts and maps left unprocessed because of BudgetExceeded
+	DedupedBytes     int64          // Downloaded bytes already in the -cas store and hardlinked to it
+
+	Analysis // Counts from the analyzers, and restored-source statistics
+
+	manifest []stats.File // Every source restored this run
+	budget   *budget      // -max-* limits, started with the run
 }
 
 // RunLocal processes local .js and .map files in the output directory.
 // If target is empty, processes all domain directories under outputRoot.
-// If target is specified, processes only that directory.
+// If target is specified, processes only that directory. With Flat, target
+// is a plain directory of maps and scripts instead; see runLocalFlat.
 func RunLocal(cfg *Config, target string) (*LocalResult, error) {
-	result := &LocalResult{}
+	result := &LocalResult{budget: cfg.newBudget()}
+	if cfg.Flat {
+		return result, runLocalFlat(cfg, target, result)
+	}
 
 	var targets []string
 
@@ -39,118 +62,377 @@ func RunLocal(cfg *Config, target string) (*LocalResult, error) {
 		}
 		targets = append(targets, absTarget)
 	} else {
+		if err := cfg.checkScanRoot(); err != nil {
+			return nil, err
+		}
+
 		// Find all domain directories in output root
 		entries, err := os.ReadDir(cfg.OutputRoot)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read output directory: %w", err)
 		}
 
+		skipped := 0
 		for _, entry := range entries {
-			if entry.IsDir() {
-				targets = append(targets, filepath.Join(cfg.OutputRoot, entry.Name()))
+			if !entry.IsDir() {
+				continue
+			}
+			dir := filepath.Join(cfg.OutputRoot, entry.Name())
+			if !isDomainDir(dir) {
+				skipped++
+				continue
 			}
+			targets = append(targets, dir)
 		}
+		if skipped > 0 {
+			cfg.logger().Info("Skipped directories that are not dejank output", "count", skipped)
+		}
+		cfg.duplicateWarnings(targets, &result.Warnings)
 	}
 
+	processLocal(cfg, targets, target == "", result)
+	return result, nil
+}
+
+// processLocal restores the domain directories in targets into result.
+// With prefix, manifest paths are prefixed with each directory's name to
+// keep the domains apart in the statistics.
+func processLocal(cfg *Config, targets []string, prefix bool, result *LocalResult) {
 	if len(targets) == 0 {
-		return result, nil
+		return
 	}
 
+	cfg.emit("discovery_complete", map[string]int{
+		"domains": len(targets),
+		"maps":    countLocalMaps(targets),
+	})
+
 	for _, domainPath := range targets {
+		if diskStopped(result.Errors) {
+			break
+		}
+		manifestStart := len(result.manifest)
 		if err := processLocalDomain(cfg, domainPath, result); err != nil {
-			result.Errors = append(result.Errors, err)
+			cfg.addErrors(&result.Errors, err)
 		}
-		result.TargetsProcessed++
+
+		if prefix {
+			for i := manifestStart; i < len(result.manifest); i++ {
+				result.manifest[i].Path = path.Join(filepath.Base(domainPath), result.manifest[i].Path)
+			}
+		}
+	}
+	finishLocal(cfg, result)
+}
+
+// runLocalFlat processes dir, a directory of .map and script files without
+// the dejank layout, such as maps harvested by another tool. It is read
+// recursively as the download directory and left unchanged; the run's
+// output is written to <dir>-dejank beside it, or into FlatOutput.
+func runLocalFlat(cfg *Config, dir string, result *LocalResult) error {
+	if dir == "" {
+		return fmt.Errorf("-flat needs the directory of maps and scripts to process")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid target path: %w", err)
+	}
+	if !isDir(dir) {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	paths := cfg.flatPaths(dir)
+	if err := os.MkdirAll(paths.Base, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	return result, nil
+	cfg.emit("discovery_complete", map[string]int{
+		"domains": 1,
+		"maps":    countMapFiles(paths, true),
+	})
+	if err := processLocalPaths(cfg, filepath.Base(dir), paths, true, result); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+	finishLocal(cfg, result)
+	return nil
+}
+
+// flatPaths returns where -flat reads dir and writes its output: dir is
+// the download directory, and the domain directory is FlatOutput or, by
+// default, <dir>-dejank. A directory of its own keeps the artifacts of two
+// flat directories with the same parent apart, and out of the parent.
+func (c *Config) flatPaths(dir string) DomainPaths {
+	base := c.FlatOutput
+	if base == "" {
+		base = dir + "-dejank"
+	}
+	base, _ = filepath.Abs(base)
+	paths := pathsAt(base)
+	paths.DownloadedSite = dir
+	return paths
+}
+
+// finishLocal totals a local run's results and reports it complete.
+func finishLocal(cfg *Config, result *LocalResult) {
+	result.TargetsProcessed = len(result.OutputDirs)
+	result.MapsProcessed, result.SourcesRestored = restoredFrom(result.Maps)
+	if !cfg.NoRestore {
+		result.Stats = stats.Compute(result.manifest)
+	}
+
+	result.BudgetExceeded, result.BudgetSkipped = result.budget.report()
+	if result.BudgetExceeded != "" {
+		cfg.emit("budget_exceeded", map[string]interface{}{
+			"limit":   result.BudgetExceeded,
+			"skipped": result.BudgetSkipped,
+		})
+	}
+
+	cfg.emit("run_complete", map[string]interface{}{
+		"targets":  result.TargetsProcessed,
+		"maps":     result.MapsProcessed,
+		"sources":  result.SourcesRestored,
+		"assets":   result.AssetsExtracted,
+		"env_vars": result.EnvVarsExtracted,
+		"errors":   len(result.Errors),
+		"warnings": len(result.Warnings),
+	})
+}
+
+// checkScanRoot refuses to look for domain directories in the filesystem
+// root or the home directory, where OutputRoot usually points by mistake,
+// unless ForceScan is set.
+func (c *Config) checkScanRoot() error {
+	if c.ForceScan {
+		return nil
+	}
+	root, err := filepath.Abs(c.OutputRoot)
+	if err != nil {
+		return fmt.Errorf("invalid output directory: %w", err)
+	}
+	home, _ := os.UserHomeDir()
+	if root == filepath.Dir(root) || (home != "" && root == filepath.Clean(home)) {
+		return fmt.Errorf("refusing to scan %s for domain directories; pass a target directory, set -o, or use -force-scan", root)
+	}
+	return nil
+}
+
+// isDomainDir reports whether dir looks like dejank output: a visible
+// directory named <host>-dejank or holding a downloaded_site folder.
+func isDomainDir(dir string) bool {
+	name := filepath.Base(dir)
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	if strings.HasSuffix(name, "-dejank") {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(resolveRunDir(dir), "downloaded_site"))
+	return err == nil
+}
+
+// countLocalMaps counts the .map files in the downloaded_site folders of
+// the targets, so progress can be reported against a known total.
+func countLocalMaps(targets []string) int {
+	count := 0
+	for _, domainPath := range targets {
+		count += countMapFiles(pathsAt(resolveRunDir(domainPath)), false)
+	}
+	return count
+}
+
+// countMapFiles counts the .map files localFiles finds.
+func countMapFiles(paths DomainPaths, recursive bool) int {
+	files, _ := localFiles(paths, recursive)
+	count := 0
+	for _, f := range files {
+		if strings.HasSuffix(f, ".map") {
+			count++
+		}
+	}
+	return count
+}
+
+// localFiles lists the files of paths.DownloadedSite and, if recursive,
+// of its subdirectories, in lexical order. Hidden directories and the
+// restore and asset directories, should they be inside it, are left out.
+func localFiles(paths DomainPaths, recursive bool) ([]string, error) {
+	if !recursive {
+		entries, err := os.ReadDir(paths.DownloadedSite)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(paths.DownloadedSite, entry.Name()))
+			}
+		}
+		return files, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(paths.DownloadedSite, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != paths.DownloadedSite && (strings.HasPrefix(d.Name(), ".") || p == paths.RestoredSources || p == paths.ExtractedAssets) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
 }
 
 // processLocalDomain processes a single domain directory.
 func processLocalDomain(cfg *Config, domainPath string, result *LocalResult) error {
 	domain := filepath.Base(domainPath)
-	downloadDir := filepath.Join(domainPath, "downloaded_site")
-	restoreDir := filepath.Join(domainPath, "restored_sources")
-	assetsDir := filepath.Join(domainPath, "extracted_assets")
+	paths := pathsAt(resolveRunDir(domainPath))
 
 	// Check if downloaded_site exists
-	if _, err := os.Stat(downloadDir); os.IsNotExist(err) {
-		if cfg.Verbose {
-			fmt.Println(ui.Warning(fmt.Sprintf("Skipping %s: no downloaded_site folder", domain)))
-		}
+	if _, err := os.Stat(paths.DownloadedSite); os.IsNotExist(err) {
+		cfg.notice("Skipping directory with no downloaded_site folder; for a plain directory of maps and scripts, use -flat", "domain", domain, "path", paths.Base)
 		return nil
 	}
+	return processLocalPaths(cfg, domain, paths, false, result)
+}
+
+// processLocalPaths restores the maps and scripts in paths.DownloadedSite,
+// with recursive in its subdirectories too, and writes the domain's
+// artifacts. domain names the directory in messages and the scaffold.
+func processLocalPaths(cfg *Config, domain string, paths DomainPaths, recursive bool, result *LocalResult) error {
+	domainPath := paths.Base
+	downloadDir := paths.DownloadedSite
+	restoreDir := paths.RestoredSources
+	assetsDir := paths.ExtractedAssets
+
+	release, err := cfg.lockOutput(domainPath)
+	if err != nil {
+		return err
+	}
+	defer cfg.recordRun(domainPath, release)()
+	result.OutputDirs = append(result.OutputDirs, domainPath)
+	cfg.emit("processing_domain", map[string]interface{}{
+		"domain": domain,
+		"path":   domainPath,
+	})
 
 	// Ensure output directories exist
 	os.MkdirAll(restoreDir, 0755)
 	os.MkdirAll(assetsDir, 0755)
 
 	// Read files in downloaded_site
-	entries, err := os.ReadDir(downloadDir)
+	files, err := localFiles(paths, recursive)
 	if err != nil {
 		return fmt.Errorf("failed to read download directory: %w", err)
 	}
 
 	// Collect environment variables from all JS files
 	allEnvVars := make(map[string]string)
+	scriptsStart, mapsStart, manifestStart := len(result.Scripts), len(result.Maps), len(result.manifest)
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	cfg.verifyDownloads(domainPath, &result.Warnings)
+	cfg.runHooks(HookAfterDownload, paths, result, &result.Warnings)
+	origin := loadOrigin(paths)
+
+	for _, fullPath := range files {
+		if diskStopped(result.Errors) {
+			break
 		}
 
-		filename := entry.Name()
-		fullPath := filepath.Join(downloadDir, filename)
+		filename := filepath.Base(fullPath)
 
 		// Process .map files
-		if strings.HasSuffix(filename, ".map") {
-			if err := processMapFile(cfg, fullPath, restoreDir, result); err != nil {
-				result.Errors = append(result.Errors, err)
+		if strings.HasSuffix(filename, ".map") && result.budget.sourceMap() {
+			if err := processMapFile(cfg, fullPath, restoreDir, origin, result); err != nil {
+				cfg.addErrors(&result.Errors, err)
 			}
+			processed, restored := restoredFrom(result.Maps)
+			cfg.emit("map_processed", map[string]interface{}{
+				"domain":    domain,
+				"map":       fullPath,
+				"processed": processed,
+				"sources":   restored,
+			})
 		}
 
-		// Process .js files (check for inline sourcemaps and extract env vars)
-		if strings.HasSuffix(filename, ".js") {
-			if err := processJSFile(cfg, fullPath, downloadDir, restoreDir, result); err != nil {
-				result.Errors = append(result.Errors, err)
+		// Process scripts (check for inline sourcemaps and extract env vars)
+		if fetch.IsScriptPath(filename) && result.budget.script() {
+			if err := processJSFile(cfg, fullPath, restoreDir, origin, result); err != nil {
+				cfg.addErrors(&result.Errors, err)
 			}
 
 			// Extract environment variables from bundled JS
 			extractedVars, err := extractEnvVarsFromFile(fullPath)
 			if err != nil {
-				result.Errors = append(result.Errors, err)
+				cfg.addErrors(&result.Errors, err)
 			} else {
-				allEnvVars = envars.MergeEnvVars(allEnvVars, extractedVars)
+				cfg.mergeEnvVars(allEnvVars, extractedVars, fullPath, &result.Warnings)
 			}
 		}
 	}
 
+	if diskStopped(result.Errors) {
+		// Every later write would fail the same way; what was written is
+		// kept
+		return nil
+	}
+	result.DedupedBytes += cfg.storeDownloads(downloadDir, &result.Errors, &result.Warnings)
+
+	if !cfg.NoRestore {
+		cfg.runHooks(HookAfterRestore, paths, result, &result.Warnings)
+		extractLocalArtifacts(cfg, paths, allEnvVars, result)
+		cfg.writeNormalized(paths, result.manifest[manifestStart:], &result.Errors)
+		cfg.writeUnformatted(paths, result.manifest[manifestStart:], &result.Errors)
+		cfg.writeScaffold(paths, strings.TrimSuffix(domain, "-dejank"), &result.Errors)
+		analysis := Analysis{Stats: stats.Compute(result.manifest[manifestStart:])}
+		cfg.analyze(paths, &analysis, &result.Errors)
+		result.Analysis.add(analysis)
+		cfg.runHooks(HookAfterAssets, paths, result, &result.Warnings)
+
+		processed, _ := restoredFrom(result.Maps[mapsStart:])
+		if err := commitSnapshot(cfg, paths, domain, processed); err != nil {
+			cfg.addErrors(&result.Errors, err)
+		}
+	}
+
+	if err := writeCSVReports(cfg, domainPath, result.Scripts[scriptsStart:], result.Maps[mapsStart:]); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+
+	return nil
+}
+
+// extractLocalArtifacts writes the collected environment variables, merged
+// with the .env an earlier run left, and extracts embedded assets from a
+// domain's restored sources.
+func extractLocalArtifacts(cfg *Config, paths DomainPaths, allEnvVars map[string]string, result *LocalResult) {
 	// Write .env file if we found any environment variables
 	if len(allEnvVars) > 0 {
-		envPath := filepath.Join(restoreDir, ".env")
-		if err := envars.WriteEnvFile(allEnvVars, envPath); err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to write .env file: %w", err))
+		envPath := filepath.Join(paths.RestoredSources, ".env")
+		merged := cfg.reconcileEnvFile(envPath, allEnvVars, &result.Warnings)
+		if err := envars.WriteEnvFile(merged, envPath); err != nil {
+			cfg.addErrors(&result.Errors, fmt.Errorf("failed to write .env file: %w", err))
 		} else {
 			result.EnvVarsExtracted += len(allEnvVars)
-			if cfg.Verbose {
-				fmt.Println(ui.Success(fmt.Sprintf("Extracted %d environment variable(s) to .env", len(allEnvVars))))
-			}
+			cfg.success("Extracted environment variables", "count", len(allEnvVars), "path", envPath)
 		}
 	}
 
 	// Extract embedded assets
-	if cfg.Verbose {
-		fmt.Println(ui.Info(fmt.Sprintf("Scanning for embedded assets in: %s", restoreDir)))
-	}
-	assetResult := assets.ExtractFromDirectory(restoreDir, assetsDir)
+	cfg.logger().Info("Scanning for embedded assets", "path", paths.RestoredSources)
+	assetResult := assets.ExtractFromDirectory(paths.RestoredSources, paths.ExtractedAssets)
 	result.AssetsExtracted += assetResult.ExtractedCount
-	result.Errors = append(result.Errors, assetResult.Errors...)
+	cfg.addErrors(&result.Errors, assetResult.Errors...)
 
-	if cfg.Verbose && assetResult.ExtractedCount > 0 {
-		fmt.Println(ui.Success(fmt.Sprintf("Extracted %d asset(s)", assetResult.ExtractedCount)))
+	if assetResult.ExtractedCount > 0 {
+		cfg.success("Extracted assets", "count", assetResult.ExtractedCount, "path", paths.ExtractedAssets)
 	}
-
-	return nil
 }
 
 // extractEnvVarsFromFile reads a JS file and extracts inlined environment variables.
@@ -163,31 +445,88 @@ func extractEnvVarsFromFile(jsPath string) (map[string]string, error) {
 	return envars.ExtractEnvVars(string(content)), nil
 }
 
-// processMapFile parses a .map file and restores sources.
-func processMapFile(cfg *Config, mapPath, restoreDir string, result *LocalResult) error {
-	if cfg.Verbose {
-		fmt.Println(ui.Info(fmt.Sprintf("Processing: %s", filepath.Base(mapPath))))
+// localOrigin is what a url or single run recorded about where the files
+// of its domain directory came from, so that restoring them later, as
+// after -no-restore, resolves sources as the run itself would have.
+type localOrigin struct {
+	page string            // Page a url run was against, "" if unknown
+	urls map[string]string // Download URL by file name under downloaded_site
+}
+
+// loadOrigin reads downloads.json and target.json from paths.Base. Either
+// may be missing, as in directories that were not downloaded by dejank.
+func loadOrigin(paths DomainPaths) localOrigin {
+	origin := localOrigin{urls: make(map[string]string)}
+	if data, err := os.ReadFile(filepath.Join(paths.Base, downloadsFile)); err == nil {
+		entries, _ := schema.DecodeList[schema.Download](data, downloadsFile)
+		for _, e := range entries {
+			origin.urls[e.File] = e.URL
+		}
 	}
+	if data, err := os.ReadFile(filepath.Join(paths.Base, targetFile)); err == nil {
+		var target schema.Target
+		if schema.Check(data, targetFile) == nil && json.Unmarshal(data, &target) == nil {
+			origin.page = target.RequestedURL
+		}
+	}
+	return origin
+}
 
-	sm, err := sourcemap.ParseFile(mapPath)
-	if err != nil {
-		return fmt.Errorf("failed to parse %s: %w", filepath.Base(mapPath), err)
+// url returns the URL the file at p was downloaded from, or "".
+func (o localOrigin) url(p string) string {
+	return o.urls[filepath.Base(p)]
+}
+
+// baseURL returns the URL the sources of the map at p, or of a map taken
+// from the script at p, resolve against: the page, as in url mode, or else
+// the file's own URL.
+func (o localOrigin) baseURL(p string) string {
+	if o.page != "" {
+		return o.page
 	}
+	return o.url(p)
+}
 
-	restoreResult := sourcemap.RestoreSources(sm, restoreDir)
-	result.MapsProcessed++
-	result.SourcesRestored += restoreResult.RestoredCount
-	result.Errors = append(result.Errors, restoreResult.Errors...)
+// processMapFile parses a .map file and restores sources.
+func processMapFile(cfg *Config, mapPath, restoreDir string, origin localOrigin, result *LocalResult) error {
+	cfg.logger().Info("Processing sourcemap", "path", mapPath)
 
-	if cfg.Verbose {
-		fmt.Println(ui.Success(fmt.Sprintf("Restored %d source(s) from %s", restoreResult.RestoredCount, filepath.Base(mapPath))))
+	mapURL := origin.url(mapPath)
+	sm, err := sourcemap.ParseFile(mapPath)
+	if decoy := decoyOf(mapPath, sm, err); decoy != "" {
+		mapRecord, warning := cfg.decoyMapRecord(mapURL, mapPath, fetch.DownloadInfo{}, decoy)
+		result.Maps = append(result.Maps, mapRecord)
+		cfg.addWarnings(&result.Warnings, warning)
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to parse %s: %w", filepath.Base(mapPath), err)
+		result.Maps = append(result.Maps, cfg.failedMapRecord(mapURL, mapPath, fetch.DownloadInfo{}, err))
+		return err
 	}
 
+	restoreResult := cfg.restore(sm, restoreDir, origin.baseURL(mapPath), mapPath)
+	result.SourcesFiltered += restoreResult.FilteredCount
+	result.SourcesMatched += restoreResult.MatchedCount
+	result.manifest = append(result.manifest, restoreResult.Files...)
+	cfg.addErrors(&result.Errors, restoreResult.Errors...)
+	cfg.addWarnings(&result.Warnings, restoreResult.Warnings...)
+	mapRecord := cfg.newMapRecord(sm, mapURL, mapPath, restoreResult.RestoredCount)
+	cfg.addWarnings(&result.Warnings, mapRecord.issues()...)
+	// The script a map was downloaded for is saved beside it as app.js
+	// for app.js.map
+	scriptBundler(sm, strings.TrimSuffix(mapPath, ".map"), &mapRecord)
+	cfg.addWarnings(&result.Warnings, cfg.verifyMap(sm, strings.TrimSuffix(mapPath, ".map"), &mapRecord)...)
+	result.Maps = append(result.Maps, mapRecord)
+
+	cfg.success("Restored sources", "count", restoreResult.RestoredCount, "path", mapPath)
+
 	return nil
 }
 
-// processJSFile checks for inline sourcemaps and extracts them.
-func processJSFile(cfg *Config, jsPath, _ /* downloadDir */, restoreDir string, result *LocalResult) error {
+// processJSFile checks for inline sourcemaps and extracts them. The script
+// is recorded by the URL it was downloaded from, when origin knows it.
+func processJSFile(cfg *Config, jsPath, restoreDir string, origin localOrigin, result *LocalResult) error {
 	content, err := os.ReadFile(jsPath)
 	if err != nil {
 		return fmt.Errorf("failed to read %s: %w", filepath.Base(jsPath), err)
@@ -195,9 +534,38 @@ func processJSFile(cfg *Config, jsPath, _ /* downloadDir */, restoreDir string,
 
 	jsContent := string(content)
 
+	record := ScriptRecord{URL: jsPath, Bytes: int64(len(content))}
+	scriptURL := origin.url(jsPath)
+	if scriptURL != "" {
+		record.URL = scriptURL
+		record.File = filepath.Base(jsPath)
+	}
+	record.findMapSource(jsContent, "")
+	defer func() {
+		result.Scripts = append(result.Scripts, record)
+	}()
+
 	if !sourcemap.HasInlineSourceMap(jsContent) {
+		if mapURL := sourcemap.ExtractSourceMappingURL(jsContent); mapURL != "" {
+			record.HasSourceMappingURL = true
+			record.MapURL = mapURL
+			if scriptURL != "" {
+				if resolved, err := resolveURL(scriptURL, mapURL); err == nil {
+					record.MapURL = resolved
+				}
+			}
+		} else if _, err := os.Stat(jsPath + ".map"); err == nil {
+			// Processed with the directory's other .map files
+			record.MapSource = MapSourceExternal
+			record.MapURL = filepath.Base(jsPath) + ".map"
+		}
+		if sourcemap.HasEmbeddedSourceMap(jsContent) {
+			return restoreLocalEmbedded(cfg, jsContent, jsPath, restoreDir, origin.baseURL(jsPath), result, &record)
+		}
 		return nil
 	}
+	record.HasSourceMappingURL = true
+	record.MapURL = "inline"
 
 	sm, err := sourcemap.ExtractInlineSourceMap(jsContent)
 	if err != nil {
@@ -210,20 +578,51 @@ func processJSFile(cfg *Config, jsPath, _ /* downloadDir */, restoreDir string,
 
 	// Save the extracted sourcemap
 	mapPath := jsPath + ".inline.map"
-	mapJSON, _ := json.MarshalIndent(sm, "", "  ")
-	if err := os.WriteFile(mapPath, mapJSON, 0644); err != nil {
+	restored, err := restoreLocalScriptMap(cfg, sm, mapPath, jsPath, restoreDir, origin.baseURL(jsPath), result)
+	if err != nil {
 		return fmt.Errorf("failed to save inline map: %w", err)
 	}
+	cfg.success("Extracted inline sourcemap", "path", mapPath)
+	record.SourcesRestored = restored
+	return nil
+}
 
-	if cfg.Verbose {
-		fmt.Println(ui.Success(fmt.Sprintf("Extracted inline sourcemap: %s", filepath.Base(mapPath))))
+// restoreLocalEmbedded restores the sourcemaps a script embeds as JSON
+// string literals, saving each beside it as <script>.embedded-<n>.map.
+func restoreLocalEmbedded(cfg *Config, jsContent, jsPath, restoreDir, baseURL string, result *LocalResult, record *ScriptRecord) error {
+	for i, em := range sourcemap.ExtractEmbeddedSourceMaps(jsContent) {
+		record.embeddedFound()
+		mapPath := fmt.Sprintf("%s.embedded-%d.map", jsPath, i+1)
+		restored, err := restoreLocalScriptMap(cfg, em.SourceMap, mapPath, jsPath, restoreDir, baseURL, result)
+		if err != nil {
+			return fmt.Errorf("failed to save embedded map: %w", err)
+		}
+		cfg.success("Extracted embedded sourcemap", "path", mapPath)
+		record.SourcesRestored += restored
 	}
+	return nil
+}
 
-	// Restore sources
-	restoreResult := sourcemap.RestoreSources(sm, restoreDir)
-	result.MapsProcessed++
-	result.SourcesRestored += restoreResult.RestoredCount
-	result.Errors = append(result.Errors, restoreResult.Errors...)
+// restoreLocalScriptMap saves a sourcemap taken from the script at jsPath
+// to mapPath, restores it, and records it. It returns the number of
+// sources restored. Relative sources resolve against baseURL.
+func restoreLocalScriptMap(cfg *Config, sm *sourcemap.SourceMap, mapPath, jsPath, restoreDir, baseURL string, result *LocalResult) (int, error) {
+	mapJSON, _ := json.MarshalIndent(sm, "", "  ")
+	if err := writeDownload(mapPath, mapJSON); err != nil {
+		return 0, err
+	}
 
-	return nil
+	// Restore sources
+	restoreResult := cfg.restore(sm, restoreDir, baseURL, jsPath)
+	result.SourcesFiltered += restoreResult.FilteredCount
+	result.SourcesMatched += restoreResult.MatchedCount
+	result.manifest = append(result.manifest, restoreResult.Files...)
+	cfg.addErrors(&result.Errors, restoreResult.Errors...)
+	cfg.addWarnings(&result.Warnings, restoreResult.Warnings...)
+	mapRecord := cfg.newMapRecord(sm, "", mapPath, restoreResult.RestoredCount)
+	scriptBundler(sm, jsPath, &mapRecord)
+	cfg.addWarnings(&result.Warnings, cfg.verifyMap(sm, jsPath, &mapRecord)...)
+	cfg.addWarnings(&result.Warnings, mapRecord.issues()...)
+	result.Maps = append(result.Maps, mapRecord)
+	return restoreResult.RestoredCount, nil
 }
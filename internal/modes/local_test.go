@@ -0,0 +1,167 @@
+package modes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const localMap = `{"version":3,"sources":["src/a.js"],"sourcesContent":["console.log(1)\n"],"mappings":"AAAA"}`
+
+// writeFiles creates files, relative to root, with the given contents.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listFiles returns the regular files under dir, slash-relative to it.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRunLocalStructured(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	domain := filepath.Join(cfg.OutputRoot, "example.com-dejank")
+	writeFiles(t, domain, map[string]string{
+		"downloaded_site/app.js":     "console.log(1);\n//# sourceMappingURL=app.js.map\n",
+		"downloaded_site/app.js.map": localMap,
+	})
+
+	result, err := RunLocal(cfg, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SourcesRestored != 1 {
+		t.Errorf("SourcesRestored = %d, want 1", result.SourcesRestored)
+	}
+	if _, err := os.Stat(filepath.Join(domain, "restored_sources", "src", "a.js")); err != nil {
+		t.Errorf("source not restored into the domain directory: %v", err)
+	}
+}
+
+func TestRunLocalFlat(t *testing.T) {
+	parent := t.TempDir()
+	inputs := map[string]map[string]string{
+		"harvest": {"nested/app.js.map": localMap},
+		"other":   {"lib.js.map": localMap},
+	}
+	for name, files := range inputs {
+		writeFiles(t, filepath.Join(parent, name), files)
+	}
+
+	for name, files := range inputs {
+		cfg := DefaultConfig()
+		cfg.OutputRoot = t.TempDir()
+		cfg.Flat = true
+		result, err := RunLocal(cfg, filepath.Join(parent, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.SourcesRestored != 1 {
+			t.Errorf("%s: SourcesRestored = %d, want 1", name, result.SourcesRestored)
+		}
+
+		// The input directory is read, never written to
+		if got := listFiles(t, filepath.Join(parent, name)); len(got) != len(files) {
+			t.Errorf("%s: input directory now holds %v", name, got)
+		}
+		if _, err := os.Stat(filepath.Join(parent, name+"-dejank", "restored_sources", "src", "a.js")); err != nil {
+			t.Errorf("%s: source not restored into %s-dejank: %v", name, name, err)
+		}
+	}
+
+	// Each run kept to its own directory, leaving nothing in the parent
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{"harvest", "harvest-dejank", "other", "other-dejank"}
+	if len(got) != len(want) {
+		t.Fatalf("parent holds %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("parent holds %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRunLocalAfterNoRestore(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte("console.log(1);\n//# sourceMappingURL=app.js.map\n"))
+		case "/static/app.js.map":
+			w.Write([]byte(localMap))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	cfg.NoRestore = true
+	result, err := RunURLFromPlan(cfg, &Plan{
+		Version: PlanVersion,
+		Target:  srv.URL + "/",
+		Scripts: []PlannedScript{{URL: srv.URL + "/static/app.js"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	if got := listFiles(t, filepath.Join(result.OutputDir, "restored_sources")); len(got) > 0 {
+		t.Fatalf("-no-restore wrote %v", got)
+	}
+
+	// Restoring later finds both downloads and knows where they came from
+	cfg.NoRestore = false
+	local, err := RunLocal(cfg, result.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local.SourcesRestored != 1 {
+		t.Errorf("SourcesRestored = %d, want 1", local.SourcesRestored)
+	}
+	if _, err := os.Stat(filepath.Join(result.OutputDir, "restored_sources", "src", "a.js")); err != nil {
+		t.Errorf("source not restored: %v", err)
+	}
+	if len(local.Maps) != 1 || local.Maps[0].URL != srv.URL+"/static/app.js.map" {
+		t.Errorf("Maps = %+v, want the map recorded by its URL", local.Maps)
+	}
+	if len(local.Scripts) != 1 || local.Scripts[0].URL != srv.URL+"/static/app.js" || local.Scripts[0].MapURL != srv.URL+"/static/app.js.map" {
+		t.Errorf("Scripts = %+v, want the script and its map recorded by URL", local.Scripts)
+	}
+}
@@ -0,0 +1,137 @@
+package modes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// lockFile is held in an output directory while a run writes to it, so
+// concurrent dejank processes (watch mode, parallel CI jobs) don't write
+// over each other.
+const lockFile = ".dejank.lock"
+
+// staleLockAge is how old a lock must be before -break-lock may take it
+// over. Runs that crash or are killed leave their lock behind.
+const staleLockAge = time.Hour
+
+// lockRecord is the content of the lock file.
+type lockRecord struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Started  time.Time `json:"started"`
+}
+
+// lockOutput takes the lock in dir and returns a func that releases it. A
+// lock held by another run is an error. One left by a run on this machine
+// that has exited, such as one that crashed, is taken over; a stale one
+// from elsewhere only with BreakLock.
+func (c *Config) lockOutput(dir string) (func(), error) {
+	path := filepath.Join(dir, lockFile)
+	var mine lockRecord
+	for broken := false; ; broken = true {
+		var err error
+		mine, err = writeLock(path)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) || broken {
+			return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+		}
+
+		held := readLock(path)
+		age := time.Since(held.Started).Round(time.Second)
+		if held.exited() {
+			// A crashed or interrupted run, such as the one being resumed,
+			// left its lock behind
+			c.logger().Info("Taking over lock from exited process", "pid", held.PID, "path", path)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove lock: %w", err)
+			}
+			continue
+		}
+		if age < staleLockAge {
+			return nil, fmt.Errorf("another dejank run is active for this domain (pid %d on %s, started %s ago): %s", held.PID, held.Hostname, age, path)
+		}
+		if !c.BreakLock {
+			return nil, fmt.Errorf("found a stale lock from pid %d on %s, started %s ago: %s (use -break-lock to take it over)", held.PID, held.Hostname, age, path)
+		}
+		c.notice("Taking over stale lock", "pid", held.PID, "age", age, "path", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale lock: %w", err)
+		}
+	}
+	return func() { releaseLock(path, mine) }, nil
+}
+
+// writeLock creates the lock file, failing if it already exists, and
+// returns what it wrote.
+func writeLock(path string) (lockRecord, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return lockRecord{}, err
+	}
+	hostname, _ := os.Hostname()
+	mine := lockRecord{PID: os.Getpid(), Hostname: hostname, Started: time.Now().Round(0)}
+	err = json.NewEncoder(f).Encode(mine)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return mine, err
+}
+
+// releaseLock removes the lock at path if it is still mine. Another run
+// may have taken it over with -break-lock while this one was stalled; its
+// lock is left for it to release.
+func releaseLock(path string, mine lockRecord) {
+	held := readLock(path)
+	if held.PID != mine.PID || held.Hostname != mine.Hostname || !held.Started.Equal(mine.Started) {
+		return
+	}
+	os.Remove(path)
+}
+
+// readLock reads a held lock. A lock that can't be parsed, such as one
+// being written, dates from its modification time.
+func readLock(path string) lockRecord {
+	var held lockRecord
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &held) == nil && !held.Started.IsZero() {
+		return held
+	}
+	held = lockRecord{}
+	if info, err := os.Stat(path); err == nil {
+		held.Started = info.ModTime()
+	} else {
+		held.Started = time.Now()
+	}
+	return held
+}
+
+// exited reports whether the lock was taken by a run on this machine that
+// is no longer running.
+func (held lockRecord) exited() bool {
+	hostname, _ := os.Hostname()
+	if held.PID <= 0 || held.Hostname != hostname {
+		return false
+	}
+	p, err := os.FindProcess(held.PID)
+	if err != nil {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens the process, so it succeeding means it runs
+		p.Release()
+		return false
+	}
+	// EPERM means the process runs as another user: it is alive
+	err = p.Signal(syscall.Signal(0))
+	return errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH)
+}
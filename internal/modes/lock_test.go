@@ -0,0 +1,107 @@
+package modes
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLockRecordExited(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	if (lockRecord{PID: os.Getpid(), Hostname: hostname}).exited() {
+		t.Error("this process reported as exited")
+	}
+	if (lockRecord{PID: 1, Hostname: "elsewhere"}).exited() {
+		t.Error("a lock from another machine reported as exited")
+	}
+	if runtime.GOOS != "windows" && os.Getuid() != 0 {
+		// init runs as root, so signalling it fails with EPERM
+		if (lockRecord{PID: 1, Hostname: hostname}).exited() {
+			t.Error("a live process of another user reported as exited")
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !(lockRecord{PID: cmd.Process.Pid, Hostname: hostname}).exited() {
+		t.Error("an exited process reported as running")
+	}
+}
+
+func TestLockOutput(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	unlock, err := cfg.lockOutput(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.lockOutput(dir); err == nil {
+		t.Error("a second lock was taken while the first is held")
+	}
+	unlock()
+	unlock, err = cfg.lockOutput(dir)
+	if err != nil {
+		t.Fatalf("lock not taken after release: %v", err)
+	}
+	unlock()
+}
+
+func TestLockOutputExitedHolder(t *testing.T) {
+	// A run that crashed leaves its lock behind; the next run, resumed or
+	// not, takes it over without waiting for it to go stale
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+	data, _ := json.Marshal(lockRecord{PID: cmd.Process.Pid, Hostname: hostname, Started: time.Now()})
+	if err := os.WriteFile(filepath.Join(dir, lockFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := DefaultConfig().lockOutput(dir)
+	if err != nil {
+		t.Fatalf("lock of exited pid %d not taken over: %v", cmd.Process.Pid, err)
+	}
+	unlock()
+}
+
+func TestLockOutputReleaseTakenOver(t *testing.T) {
+	// A run whose stale lock was broken by another must not remove the
+	// other run's lock when it finishes
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	unlock, err := cfg.lockOutput(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, lockFile)
+	data, _ := json.Marshal(lockRecord{PID: os.Getpid() + 1, Hostname: "elsewhere", Started: time.Now().Add(-2 * staleLockAge)})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.BreakLock = true
+	unlockOther, err := cfg.lockOutput(dir)
+	if err != nil {
+		t.Fatalf("stale lock not broken: %v", err)
+	}
+	taken := readLock(path)
+
+	unlock()
+	if held := readLock(path); !held.Started.Equal(taken.Started) {
+		t.Fatal("releasing a lock that was taken over removed the new holder's lock")
+	}
+	unlockOther()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock not released by its holder: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package modes
+
+import (
+	"log/slog"
+
+	"github.com/thesavant42/dejank/internal/ui"
+)
+
+// discardLogger is used when Config.Log is unset, so library callers get
+// no output unless they ask for it.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// logger returns the logger for progress messages.
+func (c *Config) logger() *slog.Logger {
+	if c.Log == nil {
+		return discardLogger
+	}
+	return c.Log
+}
+
+// success logs that a step finished, at ui.LevelSuccess.
+func (c *Config) success(msg string, args ...interface{}) {
+	c.logger().Log(c.ctx(), ui.LevelSuccess, msg, args...)
+}
+
+// notice logs a minor problem at ui.LevelNotice, which is shown with
+// info messages rather than with warnings.
+func (c *Config) notice(msg string, args ...interface{}) {
+	c.logger().Log(c.ctx(), ui.LevelNotice, msg, args...)
+}
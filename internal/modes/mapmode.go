@@ -0,0 +1,224 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/assets"
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// MapOptions configures RunMap.
+type MapOptions struct {
+	Name         string // Domain directory name; defaults to the URL host or the file name
+	FetchSources bool   // Download sources the map lists without sourcesContent
+	FetchAssets  bool   // Download real webpack assets in place of loader stubs (URL maps only)
+}
+
+// MapResult contains the results of processing a single sourcemap.
+type MapResult struct {
+	Target          string
+	OutputDir       string // Domain directory the run was written to
+	SourcesRestored int
+	SourcesFetched  int // Sources downloaded because the map did not embed them
+	SourcesMatched  int // Sources that passed -only and source filters
+	SourcesFiltered int // Sources skipped by -only/-include-source/-exclude-source
+	AssetsExtracted int
+	Maps            []MapRecord
+	Errors          []error
+	Warnings        []warn.Warning // Non-fatal problems such as skipped sources and failed hooks
+
+	Analysis // Counts from the analyzers, and restored-source statistics
+
+	manifest []stats.File // Every source restored this run
+}
+
+// RunMap processes a known sourcemap directly, skipping script discovery.
+// target is either an http(s) URL or a local .map file.
+func RunMap(cfg *Config, target string, opts MapOptions) (*MapResult, error) {
+	isURL := strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+	result := &MapResult{Target: target}
+
+	domain := opts.Name
+	if isURL {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL: %w", err)
+		}
+		if domain == "" {
+			domain = parsed.Host
+		}
+	} else {
+		if _, err := os.Stat(target); err != nil {
+			return nil, fmt.Errorf("cannot read sourcemap: %w", err)
+		}
+		if domain == "" {
+			domain = strings.TrimSuffix(filepath.Base(target), ".map")
+		}
+	}
+
+	paths := cfg.domainPaths(domain)
+	release, err := cfg.prepareOutput(paths)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	result.OutputDir = paths.Base
+
+	// Keep a copy in downloaded_site so local mode can re-run the restore
+	var mapPath string
+	var info fetch.DownloadInfo
+	if isURL {
+		cfg.printUserAgent()
+		mapPath = filepath.Join(paths.DownloadedSite, cfg.mapFilename(target, ""))
+		var err error
+		if info, err = cfg.Client.DownloadWithInfo(target, mapPath); err != nil {
+			return nil, fmt.Errorf("failed to download sourcemap: %w", err)
+		}
+		cfg.mapLastModified(mapPath, info)
+	} else {
+		name := filepath.Base(target)
+		if !strings.HasSuffix(name, ".map") {
+			name += ".map"
+		}
+		mapPath = filepath.Join(paths.DownloadedSite, name)
+		if err := copyFile(target, mapPath); err != nil {
+			return nil, fmt.Errorf("failed to copy sourcemap: %w", err)
+		}
+	}
+
+	cfg.success("Saved sourcemap", "path", mapPath)
+	var sums checksums
+	sums.add(mapPath, info.SHA256)
+	cfg.writeChecksums(paths, sums, &result.Errors)
+	cfg.runHooks(HookAfterDownload, paths, result, &result.Warnings)
+
+	sm, err := sourcemap.ParseFile(mapPath)
+	if decoy := decoyOf(mapPath, sm, err); decoy != "" {
+		return nil, fmt.Errorf("not a usable sourcemap: %s (decoy %s)", decoy.Describe(), decoy)
+	}
+	if err != nil {
+		// Don't leave an HTML error page behind for local mode to trip over
+		if errors.Is(err, sourcemap.ErrHTMLResponse) {
+			os.Remove(mapPath)
+		}
+		return nil, fmt.Errorf("failed to parse sourcemap: %w", err)
+	}
+
+	// The copy is new; -map-mtime goes by the map it was copied from
+	mtimeFrom := mapPath
+	if !isURL {
+		mtimeFrom = target
+	}
+	// Sources and assets resolve against the map's URL, after redirects
+	baseURL := ""
+	if isURL && (opts.FetchAssets || opts.FetchSources) {
+		baseURL = target
+		if info.FinalURL != "" {
+			baseURL = info.FinalURL
+		}
+	}
+	restoreOpts := cfg.restoreOptions(baseURL, mtimeFrom)
+	if opts.FetchSources {
+		restoreOpts.Fetcher = cfg.Client
+		restoreOpts.SourceBaseURL = baseURL
+		if !isURL {
+			// Only sources with absolute URLs can be fetched for a local file
+			restoreOpts.SourceBaseURL = "file:///"
+		}
+	}
+
+	restoreResult := cfg.restoreWith(sm, paths.RestoredSources, restoreOpts)
+	result.SourcesRestored = restoreResult.RestoredCount
+	result.SourcesFetched = restoreResult.SourcesFetched
+	result.SourcesFiltered = restoreResult.FilteredCount
+	result.SourcesMatched = restoreResult.MatchedCount
+	result.manifest = append(result.manifest, restoreResult.Files...)
+	cfg.addErrors(&result.Errors, restoreResult.Errors...)
+	cfg.addWarnings(&result.Warnings, restoreResult.Warnings...)
+
+	mapURL := ""
+	if isURL {
+		mapURL = target
+	}
+	record := cfg.newMapRecord(sm, mapURL, mapPath, restoreResult.RestoredCount)
+	cfg.addWarnings(&result.Warnings, record.issues()...)
+	if isURL {
+		record.setDownload(info)
+		cfg.addWarnings(&result.Warnings, record.redirect()...)
+	}
+	result.Maps = append(result.Maps, record)
+
+	if !cfg.NoRestore {
+		cfg.success("Restored sources", "count", restoreResult.RestoredCount, "path", mapPath)
+	}
+
+	if !cfg.NoRestore {
+		cfg.runHooks(HookAfterRestore, paths, result, &result.Warnings)
+		result.Stats = stats.Compute(result.manifest)
+
+		assetResult := assets.ExtractFromDirectory(paths.RestoredSources, paths.ExtractedAssets)
+		result.AssetsExtracted = assetResult.ExtractedCount
+		cfg.addErrors(&result.Errors, assetResult.Errors...)
+		if isURL && opts.FetchAssets {
+			downloadResult := assets.DownloadWebpackAssets(target, paths.RestoredSources, cfg.Client)
+			result.AssetsExtracted += downloadResult.DownloadedCount
+			cfg.addErrors(&result.Errors, downloadResult.Errors...)
+		}
+
+		cfg.writeNormalized(paths, result.manifest, &result.Errors)
+		cfg.writeUnformatted(paths, result.manifest, &result.Errors)
+		cfg.writeScaffold(paths, domain, &result.Errors)
+		cfg.analyze(paths, &result.Analysis, &result.Errors)
+		cfg.runHooks(HookAfterAssets, paths, result, &result.Warnings)
+
+		if err := commitSnapshot(cfg, paths, target, len(result.Maps)); err != nil {
+			cfg.addErrors(&result.Errors, err)
+		}
+	}
+
+	if err := writeCSVReports(cfg, paths.Base, nil, result.Maps); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+
+	cfg.emit("run_complete", map[string]interface{}{
+		"output_dir": result.OutputDir,
+		"sources":    result.SourcesRestored,
+		"assets":     result.AssetsExtracted,
+		"errors":     len(result.Errors),
+		"warnings":   len(result.Warnings),
+	})
+
+	return result, nil
+}
+
+// copyFile copies src to dst, creating dst's parent directory.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	os.Remove(dst) // May be a hardlink into the -cas store
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
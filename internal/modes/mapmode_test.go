@@ -0,0 +1,45 @@
+package modes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunMapFetchSources(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old/app.js.map":
+			http.Redirect(w, r, "/static/js/app.js.map", http.StatusFound)
+		case "/static/js/app.js.map":
+			w.Write([]byte(`{"version":3,"sources":["../src/a.js"],"mappings":""}`))
+		case "/static/src/a.js":
+			w.Write([]byte("console.log(1);\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, fetchAssets := range []bool{true, false} {
+		cfg := DefaultConfig()
+		cfg.OutputRoot = t.TempDir()
+		result, err := RunMap(cfg, srv.URL+"/old/app.js.map", MapOptions{FetchSources: true, FetchAssets: fetchAssets})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors)
+		}
+
+		// The relative source resolves against where the map was served from
+		if result.SourcesFetched != 1 {
+			t.Errorf("fetch assets %v: SourcesFetched = %d, want 1", fetchAssets, result.SourcesFetched)
+		}
+		if _, err := os.Stat(filepath.Join(result.OutputDir, "restored_sources", "src", "a.js")); err != nil {
+			t.Errorf("fetch assets %v: source not restored: %v", fetchAssets, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/sourcemap"
+)
+
+// mapTemplatePlaceholderRe matches a {name} placeholder of a map URL
+// template.
+var mapTemplatePlaceholderRe = regexp.MustCompile(`\{[a-z]*\}`)
+
+// MapURLTemplate is where a CDN keeps the maps of scripts it doesn't
+// reference them from, e.g. https://maps.example-cdn.com/{path}.map.
+// For the script https://app.example.com/assets/js/x.js?v=2 the
+// placeholders are:
+//
+//	{path}  assets/js/x.js
+//	{file}  x.js
+//	{dir}   assets/js (empty for a script at the root)
+//	{host}  app.example.com, with the port if the URL has one
+//
+// The query string and fragment of the script are never part of the map
+// URL.
+type MapURLTemplate string
+
+// ParseMapURLTemplate checks that s is an absolute http or https URL
+// template with at least one placeholder and no unknown ones.
+func ParseMapURLTemplate(s string) (MapURLTemplate, error) {
+	placeholders := mapTemplatePlaceholderRe.FindAllString(s, -1)
+	if len(placeholders) == 0 {
+		return "", fmt.Errorf("invalid map URL template %q: no {path}, {file}, {dir}, or {host} placeholder", s)
+	}
+	for _, p := range placeholders {
+		switch p {
+		case "{path}", "{file}", "{dir}", "{host}":
+		default:
+			return "", fmt.Errorf("invalid map URL template %q: unknown placeholder %s", s, p)
+		}
+	}
+	sample, err := MapURLTemplate(s).Expand("https://example.com/dir/app.js")
+	if err != nil {
+		return "", fmt.Errorf("invalid map URL template %q: %w", s, err)
+	}
+	if u, _ := url.Parse(sample); u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid map URL template %q: must be an absolute http or https URL", s)
+	}
+	return MapURLTemplate(s), nil
+}
+
+// Expand returns the map URL the template gives for scriptURL. Slashes
+// doubled by an empty {dir} are collapsed.
+func (t MapURLTemplate) Expand(scriptURL string) (string, error) {
+	u, err := url.Parse(scriptURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%s has no host", scriptURL)
+	}
+	p := strings.TrimPrefix(u.EscapedPath(), "/")
+	dir, file := path.Split(p)
+	expanded := strings.NewReplacer(
+		"{path}", p,
+		"{file}", file,
+		"{dir}", strings.TrimSuffix(dir, "/"),
+		"{host}", u.Host,
+	).Replace(string(t))
+
+	// Collapse // left in the path by an empty placeholder, but not the
+	// one after the scheme
+	scheme, rest, ok := strings.Cut(expanded, "://")
+	if !ok {
+		return expanded, nil
+	}
+	host, p, ok := strings.Cut(rest, "/")
+	if !ok {
+		return expanded, nil
+	}
+	for strings.Contains(p, "//") {
+		p = strings.ReplaceAll(p, "//", "/")
+	}
+	return scheme + "://" + host + "/" + strings.TrimPrefix(p, "/"), nil
+}
+
+// errNoMap is returned by processSourceMapIf for a URL that answered with
+// something other than a sourcemap.
+var errNoMap = errors.New("not a sourcemap")
+
+// probeTemplatedMap looks for the map of a script that names none at the
+// URL -map-url-template gives, and processes it if it is there. A map
+// that isn't there is expected and not an error, and neither is a page
+// that isn't a map: a host that answers every URL, with an SPA's index.html
+// or a soft 404, holds no maps.
+func probeTemplatedMap(cfg *Config, scriptURL string, paths DomainPaths, result *URLResult, processedMaps map[string]bool, baseURL string, record *ScriptRecord) error {
+	mapURL, err := cfg.MapURLTemplate.Expand(scriptURL)
+	if err != nil {
+		cfg.logger().Debug("No map URL template expansion", "url", scriptURL, "error", err)
+		return nil
+	}
+	if processedMaps[mapURL] {
+		return nil
+	}
+	processedMaps[mapURL] = true
+
+	found := DiscoveredURL{URL: mapURL, Kind: "map", Source: SourceTemplate, Referrer: record.URL, Target: result.URL}
+	if found.Skipped = cfg.skipMap(mapURL, found.Source, result); found.Skipped != "" {
+		result.URLs = append(result.URLs, found)
+		return nil
+	}
+	// Servers that refuse HEAD are asked with GET
+	probe, err := cfg.Client.Head(mapURL)
+	if err != nil && probe.StatusCode != http.StatusMethodNotAllowed && probe.StatusCode != http.StatusNotImplemented {
+		cfg.logger().Debug("No map at template URL", "url", mapURL, "status", probe.StatusCode)
+		return nil
+	}
+	if !result.budget.sourceMap() {
+		return nil
+	}
+
+	isMap := func(mapPath string, sm *sourcemap.SourceMap, err error) bool {
+		if err == nil && decoyOf(mapPath, sm, err) == "" {
+			return true
+		}
+		cfg.logger().Debug("Template URL is not a sourcemap", "url", mapURL, "error", err)
+		return false
+	}
+	err = processSourceMapIf(cfg, mapURL, scriptURL, paths, result, baseURL, isMap)
+	if errors.Is(err, errNoMap) {
+		return nil
+	}
+	result.URLs = append(result.URLs, found)
+	if err != nil {
+		return err
+	}
+	record.MapURL = mapURL
+	record.MapSource = MapSourceTemplate
+	result.MapsTemplated++
+	cfg.logger().Info("Found sourcemap at template URL", "url", mapURL, "script", scriptURL)
+	return nil
+}
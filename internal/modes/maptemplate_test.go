@@ -0,0 +1,91 @@
+package modes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMapURLTemplateExpand(t *testing.T) {
+	tests := []struct {
+		name, template, script, want string
+	}{
+		{"path", "https://maps.example-cdn.com/{path}.map", "https://app.example.com/assets/js/x.js", "https://maps.example-cdn.com/assets/js/x.js.map"},
+		{"query dropped", "https://maps.example-cdn.com/{path}.map", "https://app.example.com/assets/js/x.js?v=2&t=1", "https://maps.example-cdn.com/assets/js/x.js.map"},
+		{"fragment dropped", "https://maps.example-cdn.com/{file}.map", "https://app.example.com/x.js#main", "https://maps.example-cdn.com/x.js.map"},
+		{"nested dir and file", "https://maps.example.com/{host}/{dir}/maps/{file}.map", "https://app.example.com/static/js/chunks/vendor.js", "https://maps.example.com/app.example.com/static/js/chunks/maps/vendor.js.map"},
+		{"root script empty dir", "https://maps.example.com/{dir}/{file}.map", "https://app.example.com/main.js", "https://maps.example.com/main.js.map"},
+		{"host with port", "https://maps.example.com/{host}/{path}.map", "http://localhost:8080/a/b.js", "https://maps.example.com/localhost:8080/a/b.js.map"},
+		{"escaped path", "https://maps.example.com/{path}.map", "https://app.example.com/a%20b/c.js", "https://maps.example.com/a%20b/c.js.map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseMapURLTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tmpl.Expand(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMapURLTemplateErrors(t *testing.T) {
+	for _, s := range []string{
+		"https://maps.example.com/static.map", // no placeholder
+		"https://maps.example.com/{name}.map", // unknown placeholder
+		"/maps/{path}.map",                    // not absolute
+		"ftp://maps.example.com/{path}.map",   // not http
+	} {
+		if _, err := ParseMapURLTemplate(s); err == nil {
+			t.Errorf("ParseMapURLTemplate(%q) succeeded", s)
+		}
+	}
+}
+
+func TestProbeTemplatedMap(t *testing.T) {
+	// A host that answers every URL: a map for app.js, and an SPA's
+	// index.html for everything else
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/maps/app.js.map" {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"version":3,"sources":["src/app.js"],"sourcesContent":["console.log(1)\n"],"mappings":"AAAA"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<!doctype html><html><body><div id=root></div></body></html>")
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	tmpl, err := ParseMapURLTemplate(srv.URL + "/maps/{file}.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.MapURLTemplate = tmpl
+	paths := pathsAt(t.TempDir())
+	result := &URLResult{URL: srv.URL}
+	processed := make(map[string]bool)
+
+	for _, script := range []string{"https://app.example.com/app.js", "https://app.example.com/vendor.js", "https://app.example.com/runtime.js"} {
+		record := ScriptRecord{URL: script}
+		if err := probeTemplatedMap(cfg, script, paths, result, processed, srv.URL, &record); err != nil {
+			t.Errorf("%s: %v", script, err)
+		}
+		if found := record.MapSource == MapSourceTemplate; found != (script == "https://app.example.com/app.js") {
+			t.Errorf("%s: map source %q", script, record.MapSource)
+		}
+	}
+	if result.MapsTemplated != 1 {
+		t.Errorf("MapsTemplated = %d, want 1", result.MapsTemplated)
+	}
+	if len(result.Maps) != 1 || len(result.Errors) != 0 {
+		t.Errorf("maps %+v, errors %v; want the one map and no errors", result.Maps, result.Errors)
+	}
+}
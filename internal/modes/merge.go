@@ -0,0 +1,260 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/warn"
+	"golang.org/x/net/publicsuffix"
+)
+
+// mergedFile is written to a domain directory another one was merged into
+// and lists where each of its downloads came from.
+const mergedFile = "merged.json"
+
+// MergeResult contains the results of merging one domain directory into
+// another.
+type MergeResult struct {
+	Into      string // Domain directory merged into, and restored again
+	From      string // Domain directory whose downloads were copied in; left unchanged
+	Copied    int    // Downloads copied from From
+	Identical int    // Downloads From shares byte for byte with Into, not copied
+	Renamed   int    // Downloads that differ from Into's file of the same name, copied under a suffixed name
+
+	LocalResult // The local-mode run over the merged directory
+}
+
+// RunMerge merges the downloads of the domain directory from into into,
+// such as www.example.com-dejank into example.com-dejank, and restores the
+// combined set with local mode. Files the two share are kept once; a file
+// of the same name with different content is copied as name~host, e.g.
+// main~www.example.com.js and main~www.example.com.js.map, so a script
+// and its map still pair up. merged.json records where each download came
+// from. from is not modified.
+func RunMerge(cfg *Config, into, from string) (*MergeResult, error) {
+	intoDir, err := filepath.Abs(resolveRunDir(into))
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", into, err)
+	}
+	fromDir, err := filepath.Abs(resolveRunDir(from))
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", from, err)
+	}
+	if intoDir == fromDir {
+		return nil, fmt.Errorf("cannot merge %s into itself", into)
+	}
+	for _, dir := range []string{intoDir, fromDir} {
+		if !isDir(filepath.Join(dir, "downloaded_site")) {
+			return nil, fmt.Errorf("%s has no downloaded_site folder", dir)
+		}
+	}
+
+	result := &MergeResult{Into: intoDir, From: fromDir}
+	result.budget = cfg.newBudget()
+	if err := mergeDownloads(cfg, pathsAt(intoDir), pathsAt(fromDir), result); err != nil {
+		return nil, err
+	}
+	cfg.logger().Info("Merged downloads",
+		"copied", result.Copied, "renamed", result.Renamed, "identical", result.Identical)
+
+	processLocal(cfg, []string{intoDir}, false, &result.LocalResult)
+	return result, nil
+}
+
+// mergeDownloads copies the downloads of from into into, holding the lock
+// of both, and writes into's checksums.txt and merged.json.
+func mergeDownloads(cfg *Config, into, from DomainPaths, result *MergeResult) error {
+	for _, dir := range []string{into.Base, from.Base} {
+		release, err := cfg.lockOutput(dir)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	entries := readMerged(into.Base)
+	intoName, fromName := domainDirName(into.Base), domainDirName(from.Base)
+	host := strings.TrimSuffix(fromName, "-dejank")
+
+	err := filepath.WalkDir(into.DownloadedSite, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, _ := filepath.Rel(into.Base, path)
+		rel = filepath.ToSlash(rel)
+		if _, ok := entries[rel]; ok {
+			return nil
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		entries[rel] = schema.Merged{File: rel, From: intoName, SHA256: sum}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", into.DownloadedSite, err)
+	}
+
+	err = filepath.WalkDir(from.DownloadedSite, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, _ := filepath.Rel(from.DownloadedSite, path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(filepath.Join("downloaded_site", rel))
+		entry := schema.Merged{File: name, From: fromName, SHA256: sum}
+		if existing, ok := entries[name]; ok {
+			if existing.SHA256 == sum {
+				result.Identical++
+				return nil
+			}
+			renamed := conflictName(rel, host)
+			entry.File = filepath.ToSlash(filepath.Join("downloaded_site", renamed))
+			entry.RenamedFrom = name
+			if prior, ok := entries[entry.File]; ok && prior.SHA256 == sum {
+				result.Identical++
+				return nil
+			}
+			cfg.addWarnings(&result.Warnings, warn.New(warn.MergeConflict, name,
+				"differs between %s and %s; %s's copy was saved as %s", intoName, fromName, fromName, renamed))
+			result.Renamed++
+		}
+
+		target := filepath.Join(into.Base, filepath.FromSlash(entry.File))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := writeDownload(target, data); err != nil {
+			return err
+		}
+		entries[entry.File] = entry
+		result.Copied++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge %s: %w", from.DownloadedSite, err)
+	}
+
+	cfg.writeChecksums(into, nil, &result.Errors)
+	cfg.writeMerged(into, entries, &result.Errors)
+	return nil
+}
+
+// conflictName inserts ~host before the extensions of a file name, so
+// main.js and main.js.map become main~host.js and main~host.js.map.
+func conflictName(rel, host string) string {
+	dir, file := filepath.Split(rel)
+	stem, ext, _ := strings.Cut(file, ".")
+	if ext != "" {
+		ext = "." + ext
+	}
+	return filepath.Join(dir, stem+"~"+host+ext)
+}
+
+// readMerged reads the entries of a previous merge into dir by file, or
+// returns an empty set.
+func readMerged(dir string) map[string]schema.Merged {
+	entries := make(map[string]schema.Merged)
+	data, err := os.ReadFile(filepath.Join(dir, mergedFile))
+	if err != nil {
+		return entries
+	}
+	list, err := schema.DecodeList[schema.Merged](data, mergedFile)
+	if err != nil {
+		return entries
+	}
+	for _, e := range list {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(e.File))); err == nil {
+			entries[e.File] = e
+		}
+	}
+	return entries
+}
+
+// writeMerged writes merged.json to the domain directory of paths.
+func (c *Config) writeMerged(paths DomainPaths, entries map[string]schema.Merged, errs *[]error) {
+	list := make([]schema.Merged, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].File < list[j].File })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to encode %s: %w", mergedFile, err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, mergedFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", mergedFile, err))
+	}
+}
+
+// domainDirName returns the name of a domain directory, or for a versioned
+// run directory that of the domain directory holding it.
+func domainDirName(dir string) string {
+	name := filepath.Base(dir)
+	if parent := filepath.Base(filepath.Dir(dir)); !strings.HasSuffix(name, "-dejank") && strings.HasSuffix(parent, "-dejank") {
+		return parent
+	}
+	return name
+}
+
+// siteOf returns the registrable domain (eTLD+1) of a domain directory,
+// e.g. example.com for www.example.com-dejank, or "" for directories not
+// named after a domain.
+func siteOf(dir string) string {
+	host := strings.TrimSuffix(domainDirName(dir), "-dejank")
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return ""
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host))
+	if err != nil {
+		return ""
+	}
+	return site
+}
+
+// duplicateWarnings warns about domain directories among dirs that likely
+// hold the same site, such as example.com-dejank and
+// www.example.com-dejank, suggesting the merge command for each pair.
+func (c *Config) duplicateWarnings(dirs []string, warnings *[]warn.Warning) {
+	bySite := make(map[string][]string)
+	var sites []string
+	for _, dir := range dirs {
+		site := siteOf(dir)
+		if site == "" {
+			continue
+		}
+		if _, ok := bySite[site]; !ok {
+			sites = append(sites, site)
+		}
+		bySite[site] = append(bySite[site], dir)
+	}
+	for _, site := range sites {
+		group := bySite[site]
+		// Suggest merging into the directory named for the site itself, if
+		// there is one
+		isSite := func(dir string) bool { return strings.TrimSuffix(domainDirName(dir), "-dejank") == site }
+		sort.SliceStable(group, func(i, j int) bool { return isSite(group[i]) && !isSite(group[j]) })
+		for _, dir := range group[1:] {
+			c.addWarnings(warnings, warn.New(warn.DuplicateDomain, dir,
+				"may hold the same site as %s; combine them with: dejank merge %s %s", group[0], group[0], dir))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/format"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// modulesDir is created under downloaded_site for -dump-modules output.
+const modulesDir = "modules"
+
+// maxModuleName caps the length of a module file name derived from its id.
+const maxModuleName = 100
+
+var unsafeModuleIDRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// saveModules writes the webpack modules dumped from the live page to
+// downloaded_site/modules/<id>.js, formatted, and returns how many were
+// saved. Each factory is written as an assignment so the file parses.
+func (c *Config) saveModules(paths DomainPaths, discovered *fetch.DiscoveredResources, errs *[]error, warnings *[]warn.Warning) int {
+	if discovered.ModuleDumpErr != nil {
+		c.logger().Warn("Module dump failed", "error", discovered.ModuleDumpErr)
+		c.addWarnings(warnings, warn.FromError(warn.Modules, "", discovered.ModuleDumpErr))
+		return 0
+	}
+	if len(discovered.Modules) == 0 {
+		c.logger().Info("No webpack module registry found in the page")
+		return 0
+	}
+
+	dir := filepath.Join(paths.DownloadedSite, modulesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to create %s: %w", dir, err))
+		return 0
+	}
+
+	ids := make([]string, 0, len(discovered.Modules))
+	for id := range discovered.Modules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	used := make(map[string]bool)
+	saved := 0
+	for _, id := range ids {
+		name := moduleFilename(id, used)
+		content := fmt.Sprintf("__webpack_modules__[%q] = %s;\n", id, discovered.Modules[id])
+		if (c.FormatVendor || !stats.IsVendor(id)) && (c.FormatMaxSize == 0 || int64(len(content)) <= c.FormatMaxSize) {
+			content = format.Format(content, name)
+		}
+		if err := writeDownload(filepath.Join(dir, name), []byte(content)); err != nil {
+			c.addErrors(errs, fmt.Errorf("failed to write module %s: %w", id, err))
+			continue
+		}
+		saved++
+	}
+
+	c.success("Dumped webpack modules", "count", saved, "path", filepath.Join(paths.DownloadedSite, modulesDir))
+	return saved
+}
+
+// moduleFilename turns a module id, a number or a path in development
+// builds, into a unique file name.
+func moduleFilename(id string, used map[string]bool) string {
+	base := unsafeModuleIDRe.ReplaceAllString(id, "_")
+	if len(base) > maxModuleName {
+		base = base[len(base)-maxModuleName:]
+	}
+	if base == "" || base == "." || base == ".." {
+		base = "module"
+	}
+	name := base + ".js"
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s_%d.js", base, n)
+	}
+	used[name] = true
+	return name
+}
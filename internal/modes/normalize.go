@@ -0,0 +1,56 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+)
+
+// normalizedFile is written to the domain directory by -normalize-eol and
+// lists the restored sources whose BOM or line endings were rewritten.
+const normalizedFile = "normalized.json"
+
+// writeNormalized records which of a run's restored files -normalize-eol
+// changed, so the rewrite can be audited.
+func (c *Config) writeNormalized(paths DomainPaths, files []stats.File, errs *[]error) {
+	if c.NormalizeEOL == "" {
+		return
+	}
+
+	changed := make(map[string]stats.File)
+	for _, f := range files {
+		if f.BOMStripped || f.EOLConverted != "" {
+			changed[f.Path] = f
+		} else {
+			// A later map rewrote the file without changes
+			delete(changed, f.Path)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	entries := make([]schema.Normalized, 0, len(changed))
+	for _, f := range files {
+		if g, ok := changed[f.Path]; ok {
+			entries = append(entries, schema.Normalized{Path: g.Path, BOMStripped: g.BOMStripped, EOLConverted: g.EOLConverted})
+			delete(changed, f.Path)
+		}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to encode %s: %w", normalizedFile, err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, normalizedFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", normalizedFile, err))
+		return
+	}
+
+	c.success("Normalized restored files; see "+normalizedFile, "count", len(entries))
+}
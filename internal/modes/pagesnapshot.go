@@ -0,0 +1,42 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// Files -snapshot writes to downloaded_site.
+const (
+	screenshotFile = "page.png"
+	pageHTMLFile   = "page.html"
+)
+
+// savePageSnapshot writes the screenshot and rendered DOM taken during
+// discovery to downloaded_site and records their paths in result. Anything
+// that could not be captured or written is a warning.
+func (c *Config) savePageSnapshot(paths DomainPaths, discovered *fetch.DiscoveredResources, result *URLResult) {
+	if !c.PageSnapshot {
+		return
+	}
+	if discovered.SnapshotErr != nil {
+		c.addWarnings(&result.Warnings, warn.FromError(warn.PageSnapshot, result.URL, discovered.SnapshotErr))
+	}
+
+	save := func(name string, data []byte) string {
+		if len(data) == 0 {
+			return ""
+		}
+		path := filepath.Join(paths.DownloadedSite, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			c.addWarnings(&result.Warnings, warn.FromError(warn.PageSnapshot, result.URL, fmt.Errorf("failed to write %s: %w", name, err)))
+			return ""
+		}
+		return path
+	}
+	result.Screenshot = save(screenshotFile, discovered.Screenshot)
+	result.PageHTML = save(pageHTMLFile, []byte(discovered.PageHTML))
+}
@@ -0,0 +1,292 @@
+package modes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+)
+
+// PlanVersion is the plan format version written to plan files. It predates
+// schema_version and is still checked for exact match.
+const PlanVersion = 1
+
+// probeTailBytes is how much of each script is fetched to look for a
+// trailing sourceMappingURL comment.
+const probeTailBytes = 8 * 1024
+
+// Plan is the result of a dry run: what a url run would download.
+type Plan struct {
+	schema.Versioned
+	Version          int             `json:"version"`
+	Target           string          `json:"target"`
+	FinalURL         string          `json:"final_url,omitempty"` // Page the target redirected to, if any
+	Created          time.Time       `json:"created"`
+	Discovered       int             `json:"discovered"`                  // Scripts found before scope and filters
+	OutOfScope       int             `json:"out_of_scope"`                // Scripts and maps outside -scope or -scope-file
+	Filtered         int             `json:"filtered"`                    // Scripts and maps skipped by filters
+	RobotsDisallowed int             `json:"robots_disallowed,omitempty"` // Scripts and maps disallowed by robots.txt
+	ThirdParty       int             `json:"third_party,omitempty"`       // Known third-party scripts skipped by the denylist
+	Scripts          []PlannedScript `json:"scripts"`
+	SourceMaps       []string        `json:"source_maps"` // Maps seen directly during discovery
+}
+
+// PlannedScript is a probed script in a Plan.
+type PlannedScript struct {
+	URL          string `json:"url"`
+	Bytes        int64  `json:"bytes"`             // -1 if unknown
+	HasSourceMap *bool  `json:"has_source_map"`    // nil if the probe could not tell
+	MapURL       string `json:"map_url,omitempty"` // Resolved map URL, or "inline"
+	Error        string `json:"error,omitempty"`   // Probe failure
+}
+
+// TotalBytes returns the summed size of scripts with a known size and the
+// number of scripts whose size is unknown.
+func (p *Plan) TotalBytes() (total int64, unknown int) {
+	for _, s := range p.Scripts {
+		if s.Bytes < 0 {
+			unknown++
+			continue
+		}
+		total += s.Bytes
+	}
+	return total, unknown
+}
+
+// RunPlan discovers a page's scripts like RunURL but only probes each one
+// for its size and sourcemap reference. Nothing is written to disk.
+func RunPlan(cfg *Config, targetURL string) (*Plan, error) {
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		return nil, fmt.Errorf("invalid URL: must include http:// or https:// scheme")
+	}
+
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	parsed, discovered, robots, err := cfg.discoverPage(targetURL, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Version:    PlanVersion,
+		Target:     targetURL,
+		Created:    time.Now().UTC(),
+		Discovered: len(discovered.Scripts),
+	}
+	if parsed.String() != targetURL {
+		plan.FinalURL = parsed.String()
+	}
+
+	scripts := cfg.selectURLs(parsed, discovered.Scripts, &plan.OutOfScope, &plan.ThirdParty, &plan.Filtered, nil)
+	plan.SourceMaps = cfg.selectURLs(parsed, discovered.SourceMaps, &plan.OutOfScope, &plan.ThirdParty, &plan.Filtered, nil)
+
+	scripts = robots.filter(scripts, &plan.RobotsDisallowed)
+	plan.SourceMaps = robots.filter(plan.SourceMaps, &plan.RobotsDisallowed)
+
+	cfg.emit("discovery_complete", map[string]int{
+		"scripts":  len(scripts),
+		"filtered": plan.Filtered,
+	})
+
+	for i, scriptURL := range scripts {
+		cfg.emit("processing_script", map[string]interface{}{
+			"index": i,
+			"total": len(scripts),
+			"url":   scriptURL,
+		})
+		plan.Scripts = append(plan.Scripts, probeScript(cfg, scriptURL))
+	}
+
+	return plan, nil
+}
+
+// discoverPage loads targetURL, parsed as target, in the browser for a run
+// that writes nothing, printing what would be warnings of a url run. It
+// returns the page's final URL, what it requested, and the robots.txt
+// rules the run follows.
+func (c *Config) discoverPage(targetURL string, target *url.URL) (*url.URL, *fetch.DiscoveredResources, *robotsCache, error) {
+	c.logger().Info("Launching headless browser...", "url", targetURL)
+
+	browser := c.newBrowser()
+	discovered, err := browser.DiscoverResources(targetURL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to discover resources: %w", err)
+	}
+	if discovered.NavigationErr != nil {
+		c.logger().Warn("Navigation failed", "url", targetURL, "error", discovered.NavigationErr)
+	}
+	_, warnings := c.discoverMobile(targetURL, discovered)
+	for _, w := range warnings {
+		c.logger().Warn("Warning", "url", targetURL, "category", string(w.Category), "warning", w.String())
+	}
+	target = finalTarget(target, discovered.BaseURL)
+
+	robots := c.newRobots()
+	if c.SitemapPages > 0 {
+		_, warnings = c.discoverSitemap(browser, target, discovered, robots)
+		for _, w := range warnings {
+			c.logger().Warn("Warning", "url", targetURL, "category", string(w.Category), "warning", w.String())
+		}
+	}
+	for _, w := range append(consoleWarnings(targetURL, discovered), c.interactionWarnings(targetURL, discovered)...) {
+		c.logger().Warn("Warning", "url", targetURL, "category", string(w.Category), "warning", w.String())
+	}
+	return target, discovered, robots, nil
+}
+
+// probeScript determines a script's size with HEAD and checks the last few
+// KB for a sourceMappingURL comment with a Range request.
+func probeScript(cfg *Config, scriptURL string) PlannedScript {
+	ps := PlannedScript{URL: scriptURL, Bytes: -1}
+	found := func(mapURL string) {
+		has := true
+		ps.HasSourceMap = &has
+		ps.MapURL = mapURL
+	}
+
+	head, err := cfg.Client.Head(scriptURL)
+	if err == nil {
+		ps.Bytes = head.Bytes
+		if head.SourceMapHeader != "" {
+			if resolved, err := resolveURL(scriptURL, head.SourceMapHeader); err == nil {
+				found(resolved)
+				return ps
+			}
+		}
+	}
+
+	tail, total, err := cfg.Client.GetTail(scriptURL, probeTailBytes)
+	if ps.Bytes < 0 && total >= 0 {
+		ps.Bytes = total
+	}
+	if err != nil {
+		if !errors.Is(err, fetch.ErrRangeNotSupported) {
+			ps.Error = err.Error()
+		}
+		return ps
+	}
+
+	content := string(tail)
+	if strings.Contains(content, "sourceMappingURL=data:") {
+		found("inline")
+		return ps
+	}
+	if mapURL := sourcemap.ExtractSourceMappingURL(content); mapURL != "" {
+		if resolved, err := resolveURL(scriptURL, mapURL); err == nil {
+			found(resolved)
+			return ps
+		}
+	}
+
+	has := false
+	ps.HasSourceMap = &has
+	return ps
+}
+
+// WritePlan saves a plan as indented JSON.
+func WritePlan(path string, plan *Plan) error {
+	plan.Versioned = schema.Current()
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadPlan loads a plan written by WritePlan.
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	if err := schema.Check(data, path); err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s (expected %d)", plan.Version, path, PlanVersion)
+	}
+	if plan.Target == "" {
+		return nil, fmt.Errorf("plan %s has no target", path)
+	}
+
+	return &plan, nil
+}
+
+// RunURLFromPlan performs a url run using the scripts and maps recorded in a
+// plan instead of launching a browser for discovery.
+func RunURLFromPlan(cfg *Config, plan *Plan) (*URLResult, error) {
+	if !strings.HasPrefix(plan.Target, "http://") && !strings.HasPrefix(plan.Target, "https://") {
+		return nil, fmt.Errorf("invalid URL in plan: must include http:// or https:// scheme")
+	}
+
+	parsed, err := url.Parse(plan.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL in plan: %w", err)
+	}
+	parsed = finalTarget(parsed, plan.FinalURL)
+
+	if cfg.Resume {
+		st, paths, err := cfg.loadRunState(parsed.Host)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			return resumeURL(cfg, paths, st)
+		}
+	}
+
+	result := &URLResult{
+		URL:              plan.Target,
+		FinalURL:         parsed.String(),
+		ScriptsFound:     plan.Discovered,
+		OutOfScope:       plan.OutOfScope,
+		ScriptsFiltered:  plan.Filtered,
+		RobotsDisallowed: plan.RobotsDisallowed,
+		ThirdParty:       plan.ThirdParty,
+		robots:           cfg.newRobots(),
+		budget:           cfg.newBudget(),
+	}
+
+	paths := cfg.domainPaths(parsed.Host)
+	release, err := cfg.prepareOutput(paths)
+	if err != nil {
+		return nil, err
+	}
+	result.unlocks = append(result.unlocks, release)
+	defer result.releaseLocks()
+	result.OutputDir = paths.Base
+
+	discovered := &fetch.DiscoveredResources{
+		SourceMaps: plan.SourceMaps,
+		BaseURL:    parsed.String(),
+	}
+	for _, s := range plan.Scripts {
+		discovered.Scripts = append(discovered.Scripts, s.URL)
+	}
+
+	cfg.logger().Info("Loaded scripts and maps from plan",
+		"scripts", len(discovered.Scripts), "maps", len(discovered.SourceMaps))
+
+	// The plan already applied scope, filters, and robots.txt and counted
+	// what they skipped; selecting again would count those skips twice
+	return processChosen(cfg, parsed, paths, discovered, discovered.Scripts, discovered.SourceMaps, result)
+}
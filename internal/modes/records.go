@@ -0,0 +1,281 @@
+package modes
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// ScriptRecord describes a single script processed during a run.
+type ScriptRecord struct {
+	URL                 string   `json:"url"`    // Script URL, or in local mode the file path if downloads.json lacks it
+	Status              int      `json:"status"` // HTTP status code, 0 if not fetched
+	Bytes               int64    `json:"bytes"`
+	HasSourceMappingURL bool     `json:"has_source_mapping_url"` // Script references a sourcemap (inline or external)
+	MapURL              string   `json:"map_url,omitempty"`      // Resolved sourcemap URL, or "inline" or "embedded"
+	MapSource           string   `json:"map_source,omitempty"`   // Where the sourcemap was referenced, one of the MapSource constants; "" if the script couldn't be read
+	SourcesRestored     int      `json:"sources_restored"`
+	File                string   `json:"file,omitempty"`             // Saved file name under downloaded_site
+	ExecutedPercent     *float64 `json:"executed_percent,omitempty"` // Share of the script that ran during page load (-coverage)
+	FinalURL            string   `json:"final_url,omitempty"`        // URL after redirects, when it differs from URL
+	ContentType         string   `json:"content_type,omitempty"`
+	DurationMS          int64    `json:"duration_ms,omitempty"`           // Download time
+	Error               string   `json:"error,omitempty"`                 // Why the script could not be downloaded or read
+	Reused              bool     `json:"reused,omitempty"`                // Unchanged since an earlier run and taken from disk
+	UserAgent           string   `json:"user_agent,omitempty"`            // User-Agent the script was downloaded with (-user-agent)
+	Triggered           bool     `json:"interaction_triggered,omitempty"` // First requested after -interact or -auto-scroll began
+	Prerendered         bool     `json:"prerendered,omitempty"`           // First requested by a page Chrome prerendered for a speculation rule
+	PrefetchedUnused    bool     `json:"prefetched_unused,omitempty"`     // Only prefetched, never loaded by the page
+	SHA256              string   `json:"sha256,omitempty"`                // Hex SHA-256 of the downloaded file
+}
+
+// Where a script's sourcemap was referenced, see ScriptRecord.MapSource.
+const (
+	MapSourceInline   = "inline"   // A data: URL in the script
+	MapSourceHeader   = "header"   // The SourceMap or X-SourceMap response header
+	MapSourceExternal = "external" // A sourceMappingURL comment, or in local mode a .map file beside the script
+	MapSourceEmbedded = "embedded" // A JSON string literal in the script, e.g. var __SOURCEMAP__ = "{...}"
+	MapSourceTemplate = "template" // Not referenced, but found at -map-url-template
+	MapSourceNone     = "none"     // No sourcemap at all; the script is minified-only
+)
+
+// MapRecord describes a single sourcemap processed during a run.
+type MapRecord struct {
+	URL               string   `json:"url,omitempty"` // Sourcemap URL, empty for local files downloads.json lacks
+	File              string   `json:"file"`          // Path of the sourcemap on disk
+	Version           int      `json:"version"`
+	SourceCount       int      `json:"source_count"`
+	HasSourcesContent bool     `json:"has_sources_content"`
+	ToolchainHints    []string `json:"toolchain_hints"`
+	SourcesRestored   int      `json:"sources_restored"`
+	Status            int      `json:"status,omitempty"` // HTTP status code, 0 for local and inline maps
+	FinalURL          string   `json:"final_url,omitempty"`
+	ContentType       string   `json:"content_type,omitempty"`
+	Bytes             int64    `json:"bytes,omitempty"`
+	DurationMS        int64    `json:"duration_ms,omitempty"`
+	Error             string   `json:"error,omitempty"`      // Why the map could not be downloaded or parsed
+	UserAgent         string   `json:"user_agent,omitempty"` // User-Agent the map was downloaded with (-user-agent)
+	SHA256            string   `json:"sha256,omitempty"`     // Hex SHA-256 of the downloaded file
+	Issues            []string `json:"issues,omitempty"`     // Structural problems such as an unsupported version or stripped sources
+	Wrapper           string   `json:"wrapper,omitempty"`    // XSSI guard or JSONP callback stripped before parsing
+	Bundler           string   `json:"bundler,omitempty"`    // Best guess at the bundler that built the map, e.g. "Vite"
+	BundlerConfidence string   `json:"bundler_confidence,omitempty"`
+	Minified          bool     `json:"minified,omitempty"`    // A minifier such as terser or uglify renamed identifiers
+	Consistency       *float64 `json:"consistency,omitempty"` // Percent of sampled mappings that agree with the script (-verify)
+	Decoy             string   `json:"decoy,omitempty"`       // What a decoy map holds instead of a sourcemap, one of the sourcemap.Decoy kinds
+
+	Metro *sourcemap.MetroMetadata `json:"metro,omitempty"` // Metro function maps and module names (React Native)
+}
+
+// setDownload records the HTTP response a map was downloaded with.
+func (m *MapRecord) setDownload(info fetch.DownloadInfo) {
+	m.Status = info.StatusCode
+	m.FinalURL = finalURL(m.URL, info)
+	m.ContentType = info.ContentType
+	m.Bytes = info.Bytes
+	m.DurationMS = info.Duration.Milliseconds()
+	m.UserAgent = info.UserAgent
+	m.SHA256 = info.SHA256
+}
+
+// redirect returns a warning if the map was served from a different URL
+// than the one requested, which may not belong to the same build.
+func (m *MapRecord) redirect() []warn.Warning {
+	if m.URL == "" || m.FinalURL == "" {
+		return nil
+	}
+	return []warn.Warning{warn.New(warn.MapRedirected, m.URL, "redirected to %s", m.FinalURL)}
+}
+
+// issues returns a warning for each structural problem of the map, which
+// explain why it restored fewer sources than it lists, or none.
+func (m *MapRecord) issues() []warn.Warning {
+	warnings := make([]warn.Warning, 0, len(m.Issues))
+	for _, issue := range m.Issues {
+		warnings = append(warnings, warn.New(warn.MapFormat, mapIdentifier(m.URL, m.File), "%s", issue))
+	}
+	return warnings
+}
+
+// failedMapRecord builds the MapRecord of a map that could not be
+// downloaded or parsed and reports it as a "map_failed" event.
+func (c *Config) failedMapRecord(mapURL, file string, info fetch.DownloadInfo, err error) MapRecord {
+	c.emit("map_failed", map[string]interface{}{
+		"map":    mapIdentifier(mapURL, file),
+		"status": info.StatusCode,
+		"error":  err.Error(),
+	})
+	m := MapRecord{URL: mapURL, File: file, Error: err.Error()}
+	m.setDownload(info)
+	return m
+}
+
+// newMapRecord builds a MapRecord from a parsed sourcemap whose sources
+// were restored, and reports it as "map_parsed" and "sources_restored"
+// events.
+func (c *Config) newMapRecord(sm *sourcemap.SourceMap, mapURL, file string, restored int) MapRecord {
+	record := c.parsedMapRecord(sm, mapURL, file)
+	c.setRestored(&record, restored)
+	return record
+}
+
+// parsedMapRecord builds a MapRecord from a parsed sourcemap whose sources
+// are yet to be restored, and reports it as a "map_parsed" event.
+func (c *Config) parsedMapRecord(sm *sourcemap.SourceMap, mapURL, file string) MapRecord {
+	meta := sm.ExtractMetadata()
+	c.emit("map_parsed", map[string]interface{}{
+		"url":                 mapURL,
+		"file":                file,
+		"version":             meta.Version,
+		"sources":             meta.SourceCount,
+		"has_sources_content": meta.HasSourcesContent,
+	})
+	if meta.Metro != nil {
+		c.logger().Info("Metro metadata",
+			"path", file, "function_maps", meta.Metro.FunctionMaps, "segments", meta.Metro.Segments,
+			"modules", strings.Join(meta.Metro.ModuleNames, ","))
+	}
+
+	return MapRecord{
+		URL:               mapURL,
+		File:              file,
+		Version:           meta.Version,
+		SourceCount:       meta.SourceCount,
+		HasSourcesContent: meta.HasSourcesContent,
+		ToolchainHints:    meta.ToolchainHints,
+		Issues:            meta.Issues,
+		Wrapper:           meta.Wrapper,
+		Metro:             meta.Metro,
+		Bundler:           meta.Bundler.Name,
+		BundlerConfidence: meta.Bundler.Confidence,
+		Minified:          meta.Bundler.Minified,
+	}
+}
+
+// setRestored records how many sources of a map were restored and reports
+// it as a "sources_restored" event.
+func (c *Config) setRestored(record *MapRecord, restored int) {
+	record.SourcesRestored = restored
+	c.emit("sources_restored", map[string]interface{}{
+		"map":   mapIdentifier(record.URL, record.File),
+		"count": restored,
+	})
+}
+
+// scriptHeadSize is how much of a script is searched for a bundler's banner.
+const scriptHeadSize = 16 << 10
+
+// scriptBundler refines the bundler guess of record with the start of the
+// script at scriptPath, where bundlers such as esbuild leave their mark.
+// A missing script leaves the guess from the map alone.
+func scriptBundler(sm *sourcemap.SourceMap, scriptPath string, record *MapRecord) {
+	if scriptPath == "" {
+		return
+	}
+	f, err := os.Open(scriptPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, scriptHeadSize))
+	if err != nil {
+		return
+	}
+	guess := sm.DetectBundlerWithScript(string(head))
+	record.Bundler, record.BundlerConfidence = guess.Name, guess.Confidence
+}
+
+// newScriptRecord builds a ScriptRecord for a downloaded script and reports
+// it as a "script_downloaded" event.
+func (c *Config) newScriptRecord(scriptURL string, info fetch.DownloadInfo) ScriptRecord {
+	c.emit("script_downloaded", map[string]interface{}{
+		"url":    scriptURL,
+		"status": info.StatusCode,
+		"bytes":  info.Bytes,
+	})
+	return ScriptRecord{
+		URL:         scriptURL,
+		Status:      info.StatusCode,
+		Bytes:       info.Bytes,
+		FinalURL:    finalURL(scriptURL, info),
+		ContentType: info.ContentType,
+		DurationMS:  info.Duration.Milliseconds(),
+		UserAgent:   info.UserAgent,
+		SHA256:      info.SHA256,
+	}
+}
+
+// findMapSource sets MapSource from content, the script, and header, the
+// SourceMap header it was served with. Maps embedded as string literals
+// are only known once extracted, see embeddedFound. An inline map is preferred, as it is
+// what the script is processed with first, then the header, which browsers
+// prefer to a comment.
+func (r *ScriptRecord) findMapSource(content, header string) {
+	switch {
+	case sourcemap.HasInlineSourceMap(content):
+		r.MapSource = MapSourceInline
+	case header != "":
+		r.MapSource = MapSourceHeader
+	case len(sourcemap.ExtractSourceMappingURLs(content)) > 0:
+		r.MapSource = MapSourceExternal
+	default:
+		r.MapSource = MapSourceNone
+	}
+}
+
+// embeddedFound records that the script embeds a sourcemap, unless it
+// references one another way.
+func (r *ScriptRecord) embeddedFound() {
+	if r.MapURL == "" {
+		r.MapURL = MapSourceEmbedded
+	}
+	if r.MapSource == MapSourceNone {
+		r.MapSource = MapSourceEmbedded
+	}
+}
+
+// Unmapped returns the scripts that were read and have no sourcemap, the
+// ones that remain minified-only.
+func Unmapped(scripts []ScriptRecord) []ScriptRecord {
+	var unmapped []ScriptRecord
+	for _, s := range scripts {
+		if s.MapSource == MapSourceNone {
+			unmapped = append(unmapped, s)
+		}
+	}
+	return unmapped
+}
+
+// finalURL returns the URL a download ended at, or "" if it was not
+// redirected.
+func finalURL(requested string, info fetch.DownloadInfo) string {
+	if info.FinalURL == requested {
+		return ""
+	}
+	return info.FinalURL
+}
+
+// restoredFrom sums the sources restored from maps that were processed
+// and counts those maps; failed and decoy maps are left out of both.
+func restoredFrom(maps []MapRecord) (processed, restored int) {
+	for _, m := range maps {
+		if m.Error != "" || m.Decoy != "" {
+			continue
+		}
+		processed++
+		restored += m.SourcesRestored
+	}
+	return processed, restored
+}
+
+// mapIdentifier returns the URL of a map, or its file path for local maps.
+func mapIdentifier(mapURL, file string) string {
+	if mapURL != "" {
+		return mapURL
+	}
+	return file
+}
@@ -0,0 +1,80 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/findings"
+	"github.com/thesavant42/dejank/internal/viewer"
+)
+
+// ViewerResult contains the results of writing an HTML viewer for a run.
+type ViewerResult struct {
+	Path     string // The page that was written
+	Files    int    // Restored files listed
+	Embedded int    // Files previewable in the page
+	Findings int
+	Errors   []error
+}
+
+// RunViewer writes a self-contained HTML page for browsing the restored
+// sources and findings of the run in domainDir, to out or, if out is "",
+// to report.html in the run directory. Files up to maxPreview bytes are
+// embedded for preview until budget bytes have been embedded; the rest
+// are linked by their path relative to the page.
+func RunViewer(cfg *Config, domainDir, out string, maxPreview, budget int64) (*ViewerResult, error) {
+	dir, err := filepath.Abs(resolveRunDir(domainDir))
+	if err != nil {
+		return nil, err
+	}
+	sources := filepath.Join(dir, "restored_sources")
+	if !isDir(sources) {
+		return nil, fmt.Errorf("%s has no restored_sources", domainDir)
+	}
+	title, err := filepath.Abs(domainDir)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		out = filepath.Join(dir, "report.html")
+	}
+
+	result := &ViewerResult{Path: out}
+	var all []findings.Finding
+	for _, sub := range []string{"downloaded_site", "restored_sources"} {
+		found, errs := findings.ScanDirectory(filepath.Join(dir, sub))
+		all = append(all, found...)
+		cfg.addErrors(&result.Errors, errs...)
+	}
+
+	pageDir, err := filepath.Abs(filepath.Dir(out))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", out, err)
+	}
+	page, err := viewer.Write(f, viewer.Options{
+		Title:      filepath.Base(title) + " - dejank",
+		SourcesDir: sources,
+		PageDir:    pageDir,
+		MaxPreview: maxPreview,
+		Budget:     budget,
+		Findings:   all,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out)
+		return nil, fmt.Errorf("failed to write %s: %w", out, err)
+	}
+
+	result.Files, result.Embedded, result.Findings = page.Files, page.Embedded, page.Findings
+	cfg.logger().Info("Embedded files in the report",
+		"path", out, "files", page.Files, "embedded", page.Embedded, "bytes", page.Bytes)
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package modes
+
+import (
+	"fmt"
+
+	"github.com/thesavant42/dejank/internal/sourcemap"
+)
+
+// restoreJob is a downloaded sourcemap whose sources are restored once
+// every download of the run is done, so that the after-download hooks run
+// between the two stages. The map is parsed again from its file then,
+// which keeps memory flat however many maps a run downloads and lets
+// -resume pick up the jobs left.
+type restoreJob struct {
+	Map     int    `json:"map"`      // Index of the map's record in Maps
+	Script  int    `json:"script"`   // Index of the record of the script that named the map in Scripts, -1 if none
+	Dir     string `json:"dir"`      // restored_sources directory the sources are written to
+	BaseURL string `json:"base_url"` // Base URL of webpack assets to fetch, "" for none
+	MtimeOf string `json:"mtime_of"` // File whose mtime -map-mtime gives the sources
+}
+
+// run parses the job's map from file and restores its sources.
+func (j restoreJob) run(c *Config, file string) (sourcemap.RestoreResult, error) {
+	sm, err := sourcemap.ParseFile(file)
+	if err != nil {
+		return sourcemap.RestoreResult{}, fmt.Errorf("failed to parse sourcemap %s: %w", file, err)
+	}
+	return c.restore(sm, j.Dir, j.BaseURL, j.MtimeOf), nil
+}
+
+// queueRestore adds record to the run's maps and queues the restore of its
+// sources into dir. fromScript tells whether the script being processed
+// named the map; that script's record is added once it is processed.
+func (r *URLResult) queueRestore(record MapRecord, fromScript bool, dir, baseURL, mtimeOf string) {
+	script := -1
+	if fromScript {
+		script = len(r.Scripts)
+	}
+	r.restores = append(r.restores, restoreJob{Map: len(r.Maps), Script: script, Dir: dir, BaseURL: baseURL, MtimeOf: mtimeOf})
+	r.Maps = append(r.Maps, record)
+}
+
+// restoreQueued restores the sources of the maps queued while downloading,
+// recording its progress in st. Jobs st records as done are skipped.
+// Manifest paths are made relative to the page's restored_sources, also for
+// sources restored under other hosts' directories by -split-by-host.
+func restoreQueued(cfg *Config, st *runState, page DomainPaths, result *URLResult) {
+	for i := st.RestoresDone; i < len(result.restores) && !diskStopped(result.Errors); i++ {
+		st.RestoresDone = i
+		st.checkpoint(cfg, result)
+		job := result.restores[i]
+		record := &result.Maps[job.Map]
+
+		restoreResult, err := job.run(cfg, record.File)
+		if err != nil {
+			cfg.addErrors(&result.Errors, err)
+			continue
+		}
+		result.SourcesFiltered += restoreResult.FilteredCount
+		result.SourcesMatched += restoreResult.MatchedCount
+		rebaseManifest(restoreResult.Files, job.Dir, page.RestoredSources)
+		result.manifest = append(result.manifest, restoreResult.Files...)
+		result.AssetsExtracted += restoreResult.AssetsFetched
+		cfg.addFetchErrors(result, restoreResult.Errors...)
+		cfg.addWarnings(&result.Warnings, restoreResult.Warnings...)
+		cfg.setRestored(record, restoreResult.RestoredCount)
+		if job.Script >= 0 && job.Script < len(result.Scripts) {
+			result.Scripts[job.Script].SourcesRestored += restoreResult.RestoredCount
+		}
+	}
+	if !diskStopped(result.Errors) {
+		st.RestoresDone = len(result.restores)
+	}
+}
@@ -0,0 +1,389 @@
+package modes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// runStateFile records a url run's progress in its domain directory so that
+// an interrupted run can be continued with -resume. It is removed once the
+// run finishes.
+const runStateFile = "run-state.json"
+
+// runStateInterval is the least time between two writes of run-state.json
+// while scripts and maps are processed. Items finished since the last write
+// are processed again on -resume.
+const runStateInterval = 2 * time.Second
+
+// Post-processing phases of a url run, recorded in run-state.json as they
+// finish so that -resume only runs those that had not.
+const (
+	phaseDownloads     = "downloads"      // downloads.json, modules, coverage, checksums, and the content store
+	phaseDownloadHooks = "download_hooks" // after-download hooks
+	phaseSources       = "sources"        // Sources of the downloaded maps restored
+	phaseRestore       = "restore"        // Artifacts, analyzers, restore and asset hooks, and the git snapshot
+)
+
+// runState is the content of run-state.json: what a url run selected to
+// process, how far it got, and what it found on the way.
+type runState struct {
+	schema.Versioned
+	Target       string          `json:"target"`
+	FinalURL     string          `json:"final_url"`
+	Scripts      []string        `json:"scripts"`     // Selected scripts, in processing order
+	SourceMaps   []string        `json:"source_maps"` // Selected maps seen directly during discovery
+	Embedded     []embeddedState `json:"embedded,omitempty"`
+	Triggered    []string        `json:"triggered,omitempty"`
+	Prerendered  []string        `json:"prerendered,omitempty"`
+	Prefetched   []string        `json:"prefetched,omitempty"`
+	MapsDone     int             `json:"maps_done"` // Leading SourceMaps fully processed
+	ScriptsDone  int             `json:"scripts_done"`
+	EmbeddedDone int             `json:"embedded_done"`
+	RestoresDone int             `json:"restores_done,omitempty"` // Leading queued restores done
+	Processed    []string        `json:"processed_maps"`          // Maps handled, by URL or "<script URL>:inline"
+	Phases       []string        `json:"phases"`                  // Post-processing phases that finished
+	Progress     runProgress     `json:"progress"`
+
+	path      string
+	processed map[string]bool
+	saved     time.Time
+	failed    bool // A write failed; later ones are not tried
+}
+
+// embeddedState is a blob: or data: script in run-state.json.
+type embeddedState struct {
+	URL     string `json:"url"`
+	Kind    string `json:"kind"`
+	Content string `json:"content"`
+}
+
+// runProgress is what a url run has found so far, restored into its
+// URLResult on -resume.
+type runProgress struct {
+	ScriptsFound     int               `json:"scripts_found"`
+	OutOfScope       int               `json:"out_of_scope,omitempty"`
+	ThirdParty       int               `json:"third_party,omitempty"`
+	ScriptsFiltered  int               `json:"scripts_filtered,omitempty"`
+	MapsFiltered     int               `json:"maps_filtered,omitempty"`
+	RobotsDisallowed int               `json:"robots_disallowed,omitempty"`
+	MobileOnly       int               `json:"mobile_only,omitempty"`
+	SitemapPages     int               `json:"sitemap_pages,omitempty"`
+	ScriptsReused    int               `json:"scripts_reused,omitempty"`
+	ScriptsTriggered int               `json:"scripts_triggered,omitempty"`
+	MapsTemplated    int               `json:"maps_templated,omitempty"`
+	Prerendered      int               `json:"prerendered,omitempty"`
+	PrefetchedUnused int               `json:"prefetched_unused,omitempty"`
+	SourcesMatched   int               `json:"sources_matched,omitempty"`
+	SourcesFiltered  int               `json:"sources_filtered,omitempty"`
+	AssetsExtracted  int               `json:"assets_extracted,omitempty"`
+	EnvVarsExtracted int               `json:"env_vars_extracted,omitempty"`
+	ModulesDumped    int               `json:"modules_dumped,omitempty"`
+	ScriptsExecuted  int               `json:"scripts_executed,omitempty"`
+	SourcesExecuted  int               `json:"sources_executed,omitempty"`
+	DedupedBytes     int64             `json:"deduped_bytes,omitempty"`
+	Screenshot       string            `json:"screenshot,omitempty"`
+	PageHTML         string            `json:"page_html,omitempty"`
+	Scripts          []ScriptRecord    `json:"script_records"`
+	Maps             []MapRecord       `json:"map_records"`
+	Restores         []restoreJob      `json:"restores,omitempty"`
+	Hosts            []string          `json:"hosts,omitempty"` // Other hosts with a domain directory of their own
+	Manifest         []stats.File      `json:"manifest"`
+	Downloads        []schema.Download `json:"downloads"`
+	Budget           budgetState       `json:"budget"`
+	Errors           []string          `json:"errors"`
+	Warnings         []warn.Warning    `json:"warnings"`
+	URLs             []DiscoveredURL   `json:"urls"`
+	Analysis         Analysis          `json:"analysis"`
+}
+
+// newRunState starts the state of a run that selected scripts and
+// sourceMaps from discovered, and writes it.
+func (c *Config) newRunState(paths DomainPaths, scripts, sourceMaps []string, discovered *fetch.DiscoveredResources, result *URLResult) *runState {
+	s := &runState{
+		Versioned:   schema.Current(),
+		Target:      result.URL,
+		FinalURL:    result.FinalURL,
+		Scripts:     scripts,
+		SourceMaps:  sourceMaps,
+		Triggered:   discovered.Triggered,
+		Prerendered: discovered.Prerendered,
+		Prefetched:  discovered.Prefetched,
+		path:        filepath.Join(paths.Base, runStateFile),
+		processed:   make(map[string]bool),
+	}
+	for _, e := range discovered.Embedded {
+		s.Embedded = append(s.Embedded, embeddedState{URL: e.URL, Kind: e.Kind, Content: e.Content})
+	}
+	s.save(c, result)
+	return s
+}
+
+// resumePaths returns the domain directory an interrupted run for host was
+// written to: RunDir, or the latest run with -versioned.
+func (c *Config) resumePaths(host string) DomainPaths {
+	base := GetDomainPaths(c.OutputRoot, host).Base
+	if c.RunDir != "" {
+		return pathsAt(filepath.Join(base, c.RunDir))
+	}
+	return pathsAt(resolveRunDir(base))
+}
+
+// loadRunState reads the run-state.json of an interrupted run for host. It
+// returns nil if there is none.
+func (c *Config) loadRunState(host string) (*runState, DomainPaths, error) {
+	paths := c.resumePaths(host)
+	path := filepath.Join(paths.Base, runStateFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, paths, nil
+	}
+	if err != nil {
+		return nil, paths, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := schema.Check(data, path); err != nil {
+		return nil, paths, err
+	}
+
+	var s runState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, paths, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if s.FinalURL == "" {
+		return nil, paths, fmt.Errorf("%s has no final URL", path)
+	}
+	s.path = path
+	s.processed = make(map[string]bool, len(s.Processed))
+	for _, key := range s.Processed {
+		s.processed[key] = true
+	}
+	return &s, paths, nil
+}
+
+// save writes the state atomically. A failed write is reported once; the
+// run goes on but can't be resumed.
+func (s *runState) save(c *Config, result *URLResult) {
+	if s.failed {
+		return
+	}
+	s.saved = time.Now()
+	s.Progress = progressOf(result)
+	s.Processed = make([]string, 0, len(s.processed))
+	for key := range s.processed {
+		s.Processed = append(s.Processed, key)
+	}
+	sort.Strings(s.Processed)
+
+	data, err := json.Marshal(s)
+	if err == nil {
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+	}
+	if err != nil {
+		s.failed = true
+		c.addErrors(&result.Errors, fmt.Errorf("failed to write %s, the run can't be resumed: %w", runStateFile, err))
+	}
+}
+
+// checkpoint saves the state if runStateInterval has passed since the last
+// write.
+func (s *runState) checkpoint(c *Config, result *URLResult) {
+	if time.Since(s.saved) >= runStateInterval {
+		s.save(c, result)
+	}
+}
+
+// pending reports whether a post-processing phase has yet to run.
+func (s *runState) pending(phase string) bool {
+	return !slices.Contains(s.Phases, phase)
+}
+
+// finish records that a post-processing phase ran and saves the state.
+func (s *runState) finish(c *Config, phase string, result *URLResult) {
+	s.Phases = append(s.Phases, phase)
+	s.save(c, result)
+}
+
+// remove deletes run-state.json once the run has finished.
+func (s *runState) remove() {
+	os.Remove(s.path)
+}
+
+// progressOf captures what a run has found so far.
+func progressOf(r *URLResult) runProgress {
+	p := runProgress{
+		ScriptsFound:     r.ScriptsFound,
+		OutOfScope:       r.OutOfScope,
+		ThirdParty:       r.ThirdParty,
+		ScriptsFiltered:  r.ScriptsFiltered,
+		MapsFiltered:     r.MapsFiltered,
+		RobotsDisallowed: r.RobotsDisallowed,
+		MobileOnly:       r.MobileOnly,
+		SitemapPages:     r.SitemapPages,
+		ScriptsReused:    r.ScriptsReused,
+		ScriptsTriggered: r.ScriptsTriggered,
+		MapsTemplated:    r.MapsTemplated,
+		Prerendered:      r.Prerendered,
+		PrefetchedUnused: r.PrefetchedUnused,
+		SourcesMatched:   r.SourcesMatched,
+		SourcesFiltered:  r.SourcesFiltered,
+		AssetsExtracted:  r.AssetsExtracted,
+		EnvVarsExtracted: r.EnvVarsExtracted,
+		ModulesDumped:    r.ModulesDumped,
+		ScriptsExecuted:  r.ScriptsExecuted,
+		SourcesExecuted:  r.SourcesExecuted,
+		DedupedBytes:     r.DedupedBytes,
+		Screenshot:       r.Screenshot,
+		PageHTML:         r.PageHTML,
+		Scripts:          r.Scripts,
+		Maps:             r.Maps,
+		Restores:         r.restores,
+		Manifest:         r.manifest,
+		Budget:           r.budget.state(),
+		Errors:           make([]string, 0, len(r.Errors)),
+		Warnings:         r.Warnings,
+		URLs:             r.URLs,
+		Analysis:         r.Analysis,
+	}
+	for host, paths := range r.hosts {
+		if paths.Base != r.OutputDir {
+			p.Hosts = append(p.Hosts, host)
+		}
+	}
+	sort.Strings(p.Hosts)
+	if r.downloads != nil {
+		for _, e := range r.downloads.current {
+			p.Downloads = append(p.Downloads, e)
+		}
+	}
+	for _, err := range r.Errors {
+		p.Errors = append(p.Errors, err.Error())
+	}
+	return p
+}
+
+// restore puts what an interrupted run found back into r.
+func (p *runProgress) restore(r *URLResult) {
+	r.ScriptsFound = p.ScriptsFound
+	r.OutOfScope = p.OutOfScope
+	r.ThirdParty = p.ThirdParty
+	r.ScriptsFiltered = p.ScriptsFiltered
+	r.MapsFiltered = p.MapsFiltered
+	r.RobotsDisallowed = p.RobotsDisallowed
+	r.MobileOnly = p.MobileOnly
+	r.SitemapPages = p.SitemapPages
+	r.ScriptsReused = p.ScriptsReused
+	r.ScriptsTriggered = p.ScriptsTriggered
+	r.MapsTemplated = p.MapsTemplated
+	r.Prerendered = p.Prerendered
+	r.PrefetchedUnused = p.PrefetchedUnused
+	r.SourcesMatched = p.SourcesMatched
+	r.SourcesFiltered = p.SourcesFiltered
+	r.AssetsExtracted = p.AssetsExtracted
+	r.EnvVarsExtracted = p.EnvVarsExtracted
+	r.ModulesDumped = p.ModulesDumped
+	r.ScriptsExecuted = p.ScriptsExecuted
+	r.SourcesExecuted = p.SourcesExecuted
+	r.DedupedBytes = p.DedupedBytes
+	r.Screenshot = p.Screenshot
+	r.PageHTML = p.PageHTML
+	r.Scripts = p.Scripts
+	r.Maps = p.Maps
+	r.restores = p.Restores
+	r.manifest = p.Manifest
+	r.budget.resume(p.Budget)
+	r.Warnings = p.Warnings
+	r.URLs = p.URLs
+	r.Analysis = p.Analysis
+	for _, msg := range p.Errors {
+		r.Errors = append(r.Errors, errors.New(msg))
+	}
+	for _, e := range p.Downloads {
+		r.downloads.current[e.URL] = e
+	}
+}
+
+// resumeURL continues an interrupted url run in paths from its state,
+// skipping the scripts, maps, and post-processing phases it finished.
+// Discovery is not repeated, so -dump-modules and -coverage, which need the
+// live page, are skipped if the run stopped before saving them.
+func resumeURL(cfg *Config, paths DomainPaths, st *runState) (*URLResult, error) {
+	parsed, err := url.Parse(st.FinalURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL in %s: %w", st.path, err)
+	}
+
+	release, err := cfg.reopenOutput(paths)
+	if err != nil {
+		return nil, err
+	}
+	result := &URLResult{URL: st.Target, FinalURL: st.FinalURL, OutputDir: paths.Base, budget: cfg.newBudget()}
+	result.unlocks = append(result.unlocks, release)
+	defer result.releaseLocks()
+
+	result.downloads = cfg.loadDownloads(paths)
+	st.Progress.restore(result)
+	result.pageHost = strings.ToLower(parsed.Hostname())
+	result.robots = cfg.newRobots()
+	for _, host := range st.Progress.Hosts {
+		hostPaths := cfg.resumePaths(host)
+		release, err := cfg.reopenOutput(hostPaths)
+		if err != nil {
+			cfg.addErrors(&result.Errors, fmt.Errorf("failed to reopen output for %s: %w", host, err))
+			continue
+		}
+		result.unlocks = append(result.unlocks, release)
+		if result.hosts == nil {
+			result.hosts = make(map[string]DomainPaths)
+		}
+		result.hosts[host] = hostPaths
+	}
+
+	cfg.logger().Info("Resuming run", "path", paths.Base,
+		"scripts_done", st.ScriptsDone, "scripts", len(st.Scripts), "maps_done", st.MapsDone, "maps", len(st.SourceMaps))
+
+	discovered := &fetch.DiscoveredResources{
+		BaseURL:     st.FinalURL,
+		Triggered:   st.Triggered,
+		Prerendered: st.Prerendered,
+		Prefetched:  st.Prefetched,
+	}
+	for _, e := range st.Embedded {
+		discovered.Embedded = append(discovered.Embedded, fetch.EmbeddedScript{URL: e.URL, Kind: e.Kind, Content: e.Content})
+	}
+	if cfg.DumpModules {
+		discovered.ModuleDumpErr = errors.New("webpack modules are only dumped while the page is loaded; the resumed run skipped them")
+	}
+	if cfg.Coverage {
+		discovered.CoverageErr = errors.New("coverage is only recorded while the page is loaded; the resumed run skipped it")
+	}
+
+	return processSelected(cfg, parsed, paths, discovered, st, result)
+}
+
+// reopenOutput locks an existing output directory to continue a run in it.
+func (c *Config) reopenOutput(paths DomainPaths) (func(), error) {
+	release, err := c.lockOutput(paths.Base)
+	if err != nil {
+		return nil, err
+	}
+	if err := paths.EnsureDirs(); err != nil {
+		release()
+		return nil, err
+	}
+	return c.recordRun(paths.Base, release), nil
+}
@@ -0,0 +1,181 @@
+package modes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+// downloadsFile records, per domain directory, what each script was
+// downloaded as so that a later run into the same directory can reuse it.
+const downloadsFile = "downloads.json"
+
+// downloadCache decides whether scripts from an earlier run can be reused
+// instead of downloaded again, and collects the entries of this run.
+type downloadCache struct {
+	dir      string                     // downloaded_site of the earlier run
+	previous map[string]schema.Download // By URL
+	current  map[string]schema.Download
+	log      *slog.Logger
+}
+
+// newDownloads returns a downloadCache with nothing to reuse, which only
+// collects the entries of this run.
+func (c *Config) newDownloads() *downloadCache {
+	return &downloadCache{
+		previous: make(map[string]schema.Download),
+		current:  make(map[string]schema.Download),
+		log:      c.logger(),
+	}
+}
+
+// loadDownloads reads downloads.json from ReuseDir, or from the run's own
+// directory when it is being written over with -f. A missing or unreadable
+// file just means nothing is reused.
+func (c *Config) loadDownloads(paths DomainPaths) *downloadCache {
+	from := paths
+	if c.ReuseDir != "" {
+		from = pathsAt(c.ReuseDir)
+	}
+	d := c.newDownloads()
+	d.dir = from.DownloadedSite
+
+	data, err := os.ReadFile(filepath.Join(from.Base, downloadsFile))
+	if err != nil {
+		return d
+	}
+	entries, err := schema.DecodeList[schema.Download](data, downloadsFile)
+	if err != nil {
+		c.notice("Not reusing downloads", "path", from.Base, "error", err)
+		return d
+	}
+	for _, e := range entries {
+		d.previous[e.URL] = e
+	}
+	return d
+}
+
+// download saves scriptURL to destPath, reusing the earlier run's copy when
+// the server confirms it is unchanged: by a 304 to a conditional request
+// when validators were stored, or else by a HEAD reporting the stored size.
+// Either way the earlier file must still hash to what was recorded. It
+// reports whether the file was reused.
+func (d *downloadCache) download(client *fetch.Client, scriptURL, destPath string) (fetch.DownloadInfo, bool, error) {
+	file := filepath.Base(destPath)
+	prev, ok := d.previous[scriptURL]
+	src := filepath.Join(d.dir, prev.File)
+	if !ok || prev.File != file || !hashMatches(src, prev.SHA256) {
+		return d.fetch(client, scriptURL, destPath, fetch.Validators{})
+	}
+
+	if prev.Validators.Empty() {
+		probe, err := client.Head(scriptURL)
+		if err != nil || probe.Bytes != prev.Bytes {
+			return d.fetch(client, scriptURL, destPath, fetch.Validators{})
+		}
+		info := fetch.DownloadInfo{StatusCode: probe.StatusCode, ContentType: probe.ContentType, SourceMap: probe.SourceMapHeader}
+		return d.reuse(info, prev, src, destPath)
+	}
+
+	info, _, err := d.fetch(client, scriptURL, destPath, prev.Validators)
+	if err != nil || info.StatusCode != http.StatusNotModified {
+		return info, false, err
+	}
+	info.Validators = prev.Validators
+	return d.reuse(info, prev, src, destPath)
+}
+
+// fetch downloads scriptURL and records the result for downloads.json.
+func (d *downloadCache) fetch(client *fetch.Client, scriptURL, destPath string, v fetch.Validators) (fetch.DownloadInfo, bool, error) {
+	info, err := client.DownloadIfModified(scriptURL, destPath, v)
+	if err != nil || info.StatusCode == http.StatusNotModified {
+		return info, false, err
+	}
+	d.record(scriptURL, destPath, info)
+	return info, false, nil
+}
+
+// record adds a file downloaded from rawURL to destPath to this run's
+// entries. Sourcemaps are recorded this way, as they are never reused, so
+// that local mode knows the URL of every file it restores later. A nil
+// cache records nothing.
+func (d *downloadCache) record(rawURL, destPath string, info fetch.DownloadInfo) {
+	if d == nil {
+		return
+	}
+	d.current[rawURL] = schema.Download{
+		URL:        rawURL,
+		File:       filepath.Base(destPath),
+		Bytes:      info.Bytes,
+		SHA256:     info.SHA256,
+		Validators: info.Validators,
+	}
+}
+
+// reuse copies the earlier run's file into place, unless it is already
+// there, and carries its entry over to this run.
+func (d *downloadCache) reuse(info fetch.DownloadInfo, prev schema.Download, src, destPath string) (fetch.DownloadInfo, bool, error) {
+	if src != destPath {
+		if err := copyFile(src, destPath); err != nil {
+			return info, false, fmt.Errorf("failed to reuse %s: %w", prev.File, err)
+		}
+	}
+	info.Bytes = prev.Bytes
+	info.SHA256 = prev.SHA256
+	d.current[prev.URL] = prev
+	d.log.Info("Unchanged, reused from disk", "url", prev.URL, "path", destPath)
+	return info, true, nil
+}
+
+// write saves this run's entries to downloads.json in the domain directory.
+func (d *downloadCache) write(paths DomainPaths) error {
+	entries := make([]schema.Download, 0, len(d.current))
+	for _, e := range d.current {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", downloadsFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, downloadsFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", downloadsFile, err)
+	}
+	return nil
+}
+
+// hashMatches reports whether the file at path exists and has the given
+// SHA-256 digest.
+func hashMatches(path, want string) bool {
+	if want == "" {
+		return false
+	}
+	got, err := fileSHA256(path)
+	return err == nil && got == want
+}
+
+// fileSHA256 returns the hex SHA-256 digest of a file.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
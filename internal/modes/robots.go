@@ -0,0 +1,87 @@
+package modes
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/robots"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// robotsCache holds the robots.txt rules of each origin a run downloads
+// from, fetched on first use.
+type robotsCache struct {
+	client   *fetch.Client
+	rules    map[string]*robots.Rules // scheme://host -> rules
+	warnings []warn.Warning           // robots.txt files that could not be fetched
+	log      *slog.Logger
+}
+
+// newRobots returns a robots.txt cache for a run, or nil when
+// RespectRobots is off. A nil cache allows everything.
+func (c *Config) newRobots() *robotsCache {
+	if !c.RespectRobots {
+		return nil
+	}
+	return &robotsCache{client: c.Client, rules: make(map[string]*robots.Rules), log: c.logger()}
+}
+
+// allowed reports whether robots.txt allows fetching u. A missing
+// robots.txt (4xx) allows everything; one that can't be fetched because of
+// a server or network error disallows everything on that origin, as
+// RFC 9309 asks.
+func (r *robotsCache) allowed(u string) bool {
+	if r == nil {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	rules, ok := r.rules[origin]
+	if !ok {
+		rules = r.fetch(origin)
+		r.rules[origin] = rules
+	}
+	return rules.Allowed(u)
+}
+
+// fetch downloads and parses the robots.txt of an origin.
+func (r *robotsCache) fetch(origin string) *robots.Rules {
+	robotsURL := origin + "/robots.txt"
+	body, status, err := r.client.GetWithStatus(robotsURL)
+	switch {
+	case err == nil && status == http.StatusOK:
+		return robots.Parse(string(body))
+	case err == nil && status >= 400 && status < 500:
+		return robots.AllowAll()
+	case err == nil:
+		err = fmt.Errorf("HTTP %d when fetching %s", status, robotsURL)
+	}
+	err = fmt.Errorf("robots.txt unavailable, skipping everything on %s: %w", origin, err)
+	r.log.Warn("robots.txt unavailable, skipping its origin", "url", robotsURL, "error", err)
+	r.warnings = append(r.warnings, warn.FromError(warn.Robots, robotsURL, err))
+	return robots.DisallowAll()
+}
+
+// filter returns the URLs robots.txt allows, counting the others.
+func (r *robotsCache) filter(urls []string, disallowed *int) []string {
+	if r == nil {
+		return urls
+	}
+	allowed := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if !r.allowed(u) {
+			*disallowed++
+			r.log.Info("Disallowed by robots.txt", "url", u)
+			continue
+		}
+		allowed = append(allowed, u)
+	}
+	return allowed
+}
@@ -0,0 +1,105 @@
+package modes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRobotsCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+		warns  int
+	}{
+		{"rules", http.StatusOK, "User-agent: *\nDisallow: /private/", false, 0},
+		{"missing", http.StatusNotFound, "", true, 0},
+		{"server error", http.StatusServiceUnavailable, "", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			cfg := DefaultConfig()
+			cfg.RespectRobots = true
+			r := cfg.newRobots()
+			for i := 0; i < 2; i++ {
+				if got := r.allowed(srv.URL + "/private/app.js"); got != tt.want {
+					t.Errorf("allowed = %v, want %v", got, tt.want)
+				}
+			}
+			if fetches != 1 {
+				t.Errorf("robots.txt fetched %d times, want once", fetches)
+			}
+			if len(r.warnings) != tt.warns {
+				t.Errorf("warnings = %v, want %d", r.warnings, tt.warns)
+			}
+		})
+	}
+}
+
+func TestRobotsFilter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "User-agent: *\nDisallow: /*.map$")
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	urls := []string{srv.URL + "/app.js", srv.URL + "/app.js.map"}
+
+	disallowed := 0
+	if got := cfg.newRobots().filter(urls, &disallowed); len(got) != len(urls) || disallowed != 0 {
+		t.Errorf("without -respect-robots filter = %v (%d disallowed), want everything", got, disallowed)
+	}
+
+	cfg.RespectRobots = true
+	got := cfg.newRobots().filter(urls, &disallowed)
+	if len(got) != 1 || got[0] != urls[0] || disallowed != 1 {
+		t.Errorf("filter = %v (%d disallowed), want only %s", got, disallowed, urls[0])
+	}
+}
+
+func TestRunURLFromPlanRobots(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path] = true
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			io.WriteString(w, "User-agent: *\nDisallow: /private/")
+		case "/app.js":
+			io.WriteString(w, "console.log(1);\n//# sourceMappingURL=/private/app.js.map\n")
+		default:
+			io.WriteString(w, `{"version":3,"sources":["a.js"],"sourcesContent":["a"],"mappings":"AAAA"}`)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	cfg.RespectRobots = true
+	plan := &Plan{Version: PlanVersion, Target: srv.URL + "/", Discovered: 1, Scripts: []PlannedScript{{URL: srv.URL + "/app.js"}}}
+
+	result, err := RunURLFromPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requested["/private/app.js.map"] {
+		t.Error("fetched a map robots.txt disallows")
+	}
+	if result.RobotsDisallowed != 1 {
+		t.Errorf("RobotsDisallowed = %d, want 1", result.RobotsDisallowed)
+	}
+}
@@ -0,0 +1,71 @@
+package modes
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+// runInfoFile records, per domain directory, the dejank build and
+// invocation that last wrote it.
+const runInfoFile = "run-info.json"
+
+// RunInfo describes the dejank invocation of a run. Secrets in Args and
+// Options must be redacted by whoever fills them in.
+type RunInfo struct {
+	schema.Versioned
+	Version    string            `json:"version"`               // dejank version
+	Args       []string          `json:"args"`                  // Command line, program name first
+	Options    map[string]string `json:"options"`               // Effective value of every global option
+	StartedAt  time.Time         `json:"started_at"`            // When the process started
+	FinishedAt *time.Time        `json:"finished_at,omitempty"` // When the run released the directory, nil while running
+	GoVersion  string            `json:"go_version"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+}
+
+// NewRunInfo returns the RunInfo of a run of version started now, with the
+// Go version and platform of this build.
+func NewRunInfo(version string, args []string, options map[string]string) *RunInfo {
+	return &RunInfo{
+		Versioned: schema.Current(),
+		Version:   version,
+		Args:      args,
+		Options:   options,
+		StartedAt: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
+
+// recordRun writes run-info.json into dir for a run that has just locked
+// it, and returns release wrapped to record when the run finished before
+// releasing the lock. It is a no-op without Config.RunInfo.
+func (c *Config) recordRun(dir string, release func()) func() {
+	if c.RunInfo == nil {
+		return release
+	}
+	info := *c.RunInfo
+	writeRunInfo(dir, &info)
+	return func() {
+		finished := time.Now().UTC()
+		info.FinishedAt = &finished
+		writeRunInfo(dir, &info)
+		release()
+	}
+}
+
+// writeRunInfo writes info to run-info.json in dir. It is informational, so
+// a failure to write it doesn't fail the run.
+func writeRunInfo(dir string, info *RunInfo) {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(dir, runInfoFile), append(data, '\n'), 0644)
+}
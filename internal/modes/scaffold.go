@@ -0,0 +1,26 @@
+package modes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/scaffold"
+)
+
+// writeScaffold writes editor project files into the domain directory when
+// Scaffold is set.
+func (c *Config) writeScaffold(paths DomainPaths, name string, errs *[]error) {
+	if !c.Scaffold {
+		return
+	}
+
+	result, err := scaffold.Write(paths.Base, paths.RestoredSources, name)
+	if err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write project scaffold: %w", err))
+		return
+	}
+
+	inf := result.Inference
+	c.success("Wrote project scaffolding",
+		"path", paths.RestoredSources, "files", strings.Join(result.Files, ","), "aliases", len(inf.Aliases), "dependencies", len(inf.Dependencies))
+}
@@ -0,0 +1,69 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/findings"
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+// secretsFile is written to the domain directory when secrets or service
+// configurations are found.
+const secretsFile = "secrets.json"
+
+func init() {
+	RegisterAnalyzer(analyzer{"secrets", "Hardcoded secrets and service configs in downloaded and restored JS (secrets.json)", func(c *Config, paths DomainPaths, a *Analysis, errs *[]error) {
+		a.SecretsFound = c.scanSecrets(paths, errs)
+	}})
+}
+
+// scanSecrets writes the hardcoded secrets and service configurations in a
+// domain's downloaded and restored files to secrets.json and returns how
+// many were found. Inlined env vars are left to .env.
+func (c *Config) scanSecrets(paths DomainPaths, errs *[]error) int {
+	var records []schema.Secret
+	for _, dir := range []string{paths.DownloadedSite, paths.RestoredSources} {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		results, scanErrs := findings.ScanDirectory(dir)
+		for _, err := range scanErrs {
+			c.notice("Secret scan error", "error", err)
+		}
+		for _, f := range results {
+			if f.RuleID != findings.RuleSecret && f.RuleID != findings.RuleServiceConfig {
+				continue
+			}
+			file := f.File
+			if rel, err := filepath.Rel(paths.Base, f.File); err == nil {
+				file = filepath.ToSlash(rel)
+			}
+			records = append(records, schema.Secret{
+				Rule: f.RuleID, Kind: f.Kind, Message: f.Message, File: file,
+				Line: f.Line, Column: f.Column, Properties: f.Properties,
+			})
+		}
+	}
+
+	outPath := filepath.Join(paths.Base, secretsFile)
+	if len(records) == 0 {
+		os.Remove(outPath) // Drop results from an earlier run
+		return 0
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		c.addErrors(errs, err)
+		return 0
+	}
+	if err := os.WriteFile(outPath, append(data, '\n'), 0644); err != nil {
+		c.addErrors(errs, fmt.Errorf("failed to write %s: %w", secretsFile, err))
+		return 0
+	}
+
+	c.success("Recorded secrets and service configs", "count", len(records), "path", outPath)
+	return len(records)
+}
@@ -0,0 +1,182 @@
+package modes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// seenLockWait is how long appending to a seen file waits for another
+// process appending to the same file.
+const seenLockWait = 30 * time.Second
+
+// SeenSet is the sourcemaps already processed by earlier runs, read from a
+// -seen file. Each line of the file holds a map URL, a hex SHA-256 of a
+// map, or both separated by a space, which is how runs append the maps they
+// process. Blank lines and lines starting with # are ignored.
+//
+// A SeenSet is safe for use by concurrent runs, e.g. url -parallel.
+type SeenSet struct {
+	path   string
+	mu     sync.Mutex
+	urls   map[string]bool
+	hashes map[string]bool
+}
+
+// LoadSeen reads the seen file at path. A file that doesn't exist yet is
+// an empty set; it is created when a run first appends to it.
+func LoadSeen(path string) (*SeenSet, error) {
+	s := &SeenSet{path: path, urls: make(map[string]bool), hashes: make(map[string]bool)}
+	if err := s.read(); err != nil {
+		return nil, fmt.Errorf("failed to read seen file: %w", err)
+	}
+	return s, nil
+}
+
+// read adds the entries in the file to s.
+func (s *SeenSet) read() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if isSHA256(field) {
+				s.hashes[strings.ToLower(field)] = true
+			} else {
+				s.urls[field] = true
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// seenURL reports whether the map at u was processed by an earlier run.
+func (s *SeenSet) seenURL(u string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.urls[u]
+}
+
+// seenHash reports whether a map with the hex SHA-256 sum was processed by
+// an earlier run, under any URL.
+func (s *SeenSet) seenHash(sum string) bool {
+	if s == nil || sum == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hashes[strings.ToLower(sum)]
+}
+
+// record appends the maps of a run that were processed and aren't in the
+// file yet. The file is locked while it is re-read and appended to, so
+// runs sharing it, in this process or another, don't append an entry
+// twice, and the new lines go out in a single write.
+func (s *SeenSet) record(maps []MapRecord) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	release, err := lockSeen(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Pick up what other processes appended since the file was read
+	if err := s.read(); err != nil {
+		return fmt.Errorf("failed to read seen file: %w", err)
+	}
+
+	var lines strings.Builder
+	for _, m := range maps {
+		if m.URL == "" || m.Error != "" || s.urls[m.URL] {
+			continue
+		}
+		s.urls[m.URL] = true
+		if m.SHA256 != "" {
+			s.hashes[strings.ToLower(m.SHA256)] = true
+			lines.WriteString(m.SHA256 + " ")
+		}
+		lines.WriteString(m.URL + "\n")
+	}
+	if lines.Len() == 0 {
+		return nil
+	}
+
+	out := lines.String()
+	if data, err := os.ReadFile(s.path); err == nil && len(data) > 0 && data[len(data)-1] != '\n' {
+		// A hand-written file may lack its final newline
+		out = "\n" + out
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to update seen file: %w", err)
+	}
+	if _, err := f.WriteString(out); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to update seen file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to update seen file: %w", err)
+	}
+	return nil
+}
+
+// lockSeen creates the lock file at path, waiting for up to seenLockWait
+// while another run holds it. A lock left by a run on this machine that
+// has exited, such as one that crashed, is taken over at once; one from
+// another machine only once it is older than staleLockAge.
+func lockSeen(path string) (func(), error) {
+	deadline := time.Now().Add(seenLockWait)
+	for {
+		mine, err := writeLock(path)
+		if err == nil {
+			return func() { releaseLock(path, mine) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock seen file: %w", err)
+		}
+		if held := readLock(path); held.exited() || time.Since(held.Started) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("seen file is locked by another run: %s", path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// isSHA256 reports whether s is a hex SHA-256 sum.
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
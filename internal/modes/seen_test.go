@@ -0,0 +1,164 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadSeen(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	upper := strings.Repeat("CD", 32)
+	path := filepath.Join(t.TempDir(), "seen.txt")
+	content := "# maps from the last audit\n\n" +
+		hash + " https://a.example/app.js.map\n" +
+		"https://b.example/vendor.js.map\n" +
+		upper + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"https://a.example/app.js.map", "https://b.example/vendor.js.map"} {
+		if !s.seenURL(u) {
+			t.Errorf("%s not seen", u)
+		}
+	}
+	if s.seenURL("https://a.example/other.js.map") || s.seenURL("# maps from the last audit") {
+		t.Error("a URL not in the file, or a comment, is seen")
+	}
+	if !s.seenHash(hash) || !s.seenHash(strings.ToUpper(hash)) || !s.seenHash(strings.ToLower(upper)) {
+		t.Error("hashes are not seen in either case")
+	}
+	if s.seenHash(strings.Repeat("ef", 32)) || s.seenHash("") {
+		t.Error("a hash not in the file is seen")
+	}
+
+	// A missing file is an empty set
+	if s, err := LoadSeen(filepath.Join(t.TempDir(), "missing.txt")); err != nil || s.seenURL("https://a.example/app.js.map") {
+		t.Errorf("LoadSeen of a missing file = %v, want an empty set", err)
+	}
+}
+
+func TestSeenRecord(t *testing.T) {
+	hash := strings.Repeat("0f", 32)
+	path := filepath.Join(t.TempDir(), "seen.txt")
+	// Hand-written, without a final newline
+	if err := os.WriteFile(path, []byte("https://a.example/old.js.map"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.record([]MapRecord{
+		{URL: "https://a.example/old.js.map"},
+		{URL: "https://a.example/new.js.map", SHA256: hash},
+		{URL: "https://a.example/inline.js"},
+		{URL: "https://a.example/failed.js.map", Error: "HTTP 404"},
+		{File: "local.js.map"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://a.example/old.js.map\n" +
+		hash + " https://a.example/new.js.map\n" +
+		"https://a.example/inline.js\n"
+	if string(data) != want {
+		t.Errorf("seen file = %q, want %q", data, want)
+	}
+
+	reloaded, err := LoadSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.seenURL("https://a.example/new.js.map") || !reloaded.seenHash(hash) || reloaded.seenURL("https://a.example/failed.js.map") {
+		t.Error("recorded maps not read back")
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock left behind: %v", err)
+	}
+}
+
+func TestSeenRecordConcurrent(t *testing.T) {
+	// Two runs sharing a seen file each append what they process; a map
+	// both process is written once
+	path := filepath.Join(t.TempDir(), "seen.txt")
+	a, err := LoadSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	for _, s := range []*SeenSet{a, b} {
+		wg.Add(1)
+		go func(s *SeenSet) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if err := s.record([]MapRecord{{URL: fmt.Sprintf("https://a.example/%d.js.map", i)}}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	counts := make(map[string]int)
+	for _, line := range lines {
+		counts[line]++
+	}
+	if len(lines) != n || len(counts) != n {
+		t.Errorf("seen file has %d lines, %d distinct, want %d of each:\n%s", len(lines), len(counts), n, data)
+	}
+}
+
+func TestSeenRecordExitedLock(t *testing.T) {
+	// A run that crashed while appending leaves its lock behind; the next
+	// run takes it over instead of waiting and giving up
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "seen.txt")
+	hostname, _ := os.Hostname()
+	data, _ := json.Marshal(lockRecord{PID: cmd.Process.Pid, Hostname: hostname, Started: time.Now()})
+	if err := os.WriteFile(path+".lock", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSeen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := s.record([]MapRecord{{URL: "https://a.example/app.js.map"}}); err != nil {
+		t.Fatalf("lock of exited pid %d not taken over: %v", cmd.Process.Pid, err)
+	}
+	if elapsed := time.Since(start); elapsed > seenLockWait/2 {
+		t.Errorf("took %s to take over the lock of an exited run", elapsed)
+	}
+}
@@ -8,16 +8,33 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/thesavant42/dejank/internal/assets"
 	"github.com/thesavant42/dejank/internal/sourcemap"
-	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
 )
 
 // SingleResult contains the results of processing a single script URL.
 type SingleResult struct {
-	URL             string
-	SourcesRestored int
-	MapFound        bool
-	Errors          []error
+	URL              string
+	OutputDir        string // Domain directory the run was written to
+	SourcesRestored  int
+	SourcesMatched   int // Sources that passed -only and source filters
+	SourcesFiltered  int // Sources skipped by -only/-include-source/-exclude-source
+	AssetsExtracted  int // Embedded base64 assets written to extracted_assets
+	EnvVarsExtracted int // Environment variables inlined into the script (see .env)
+	MapFound         bool
+	Scripts          []ScriptRecord
+	Maps             []MapRecord
+	Errors           []error
+	Warnings         []warn.Warning // Non-fatal problems such as skipped sources and failed hooks
+
+	Analysis // Counts from the analyzers, and restored-source statistics
+
+	manifest  []stats.File   // Every source restored this run
+	sums      checksums      // SHA-256 of each download, taken as it arrived
+	downloads *downloadCache // The script and maps, for downloads.json
+	restores  []restoreJob   // Maps whose sources are restored once the downloads are done
 }
 
 // RunSingle downloads a single script URL, finds its sourcemap, and restores sources.
@@ -27,7 +44,7 @@ func RunSingle(cfg *Config, scriptURL string) (*SingleResult, error) {
 		return nil, fmt.Errorf("invalid URL: must include http:// or https:// scheme")
 	}
 
-	result := &SingleResult{URL: scriptURL}
+	result := &SingleResult{URL: scriptURL, downloads: cfg.newDownloads()}
 
 	// Parse URL to get hostname
 	parsed, err := url.Parse(scriptURL)
@@ -35,28 +52,31 @@ func RunSingle(cfg *Config, scriptURL string) (*SingleResult, error) {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
-	paths := GetDomainPaths(cfg.OutputRoot, parsed.Host)
-
-	// Check for existing directory
-	if paths.Exists() && !cfg.Force {
-		return nil, fmt.Errorf("output directory already exists: %s (use -f to overwrite)", paths.Base)
-	}
+	paths := cfg.domainPaths(parsed.Host)
 
-	if err := paths.EnsureDirs(); err != nil {
+	release, err := cfg.prepareOutput(paths)
+	if err != nil {
 		return nil, err
 	}
+	defer release()
+	result.OutputDir = paths.Base
 
 	// Download the script
-	filename := filenameFromURL(scriptURL)
+	cfg.printUserAgent()
+	filename := cfg.scriptFilename(scriptURL)
 	scriptPath := filepath.Join(paths.DownloadedSite, filename)
 
-	if err := cfg.Client.Download(scriptURL, scriptPath); err != nil {
+	info, err := cfg.Client.DownloadWithInfo(scriptURL, scriptPath)
+	if err != nil {
 		return nil, fmt.Errorf("failed to download script: %w", err)
 	}
+	result.Scripts = []ScriptRecord{cfg.newScriptRecord(scriptURL, info)}
+	result.sums.add(scriptPath, info.SHA256)
+	result.downloads.record(scriptURL, scriptPath, info)
+	record := &result.Scripts[0]
+	record.File = filename
 
-	if cfg.Verbose {
-		fmt.Println(ui.Success(fmt.Sprintf("Downloaded: %s", filename)))
-	}
+	cfg.success("Downloaded script", "url", scriptURL, "path", scriptPath)
 
 	// Read script content
 	content, err := os.ReadFile(scriptPath)
@@ -65,85 +85,232 @@ func RunSingle(cfg *Config, scriptURL string) (*SingleResult, error) {
 	}
 
 	jsContent := string(content)
+	record.findMapSource(jsContent, info.SourceMap)
 
-	// Check for inline sourcemap first
+	// Check for inline sourcemap first. A broken one is recorded and the
+	// external references are still tried.
 	if sourcemap.HasInlineSourceMap(jsContent) {
-		sm, err := sourcemap.ExtractInlineSourceMap(jsContent)
+		found, err := restoreSingleInline(cfg, jsContent, scriptURL, scriptPath, paths, result)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract inline sourcemap: %w", err)
-		}
-		if sm != nil {
-			result.MapFound = true
-
-			// Save the inline map for reference
-			mapPath := scriptPath + ".inline.map"
-			mapJSON, _ := json.MarshalIndent(sm, "", "  ")
-			os.WriteFile(mapPath, mapJSON, 0644)
-
-			if cfg.Verbose {
-				fmt.Println(ui.Success(fmt.Sprintf("Extracted inline sourcemap: %s", filepath.Base(mapPath))))
-			}
-
-			// Use options to enable real asset fetching
-			opts := &sourcemap.RestoreOptions{
-				BaseURL: scriptURL,
-				Fetcher: cfg.Client,
-			}
-			restoreResult := sourcemap.RestoreSourcesWithOptions(sm, paths.RestoredSources, opts)
-			result.SourcesRestored = restoreResult.RestoredCount
-			result.Errors = restoreResult.Errors
+			cfg.addErrors(&result.Errors, err)
+		} else if found {
+			finishSingle(cfg, paths, result)
 			return result, nil
 		}
 	}
+	if sourcemap.HasEmbeddedSourceMap(jsContent) {
+		restoreSingleEmbedded(cfg, jsContent, scriptURL, scriptPath, paths, result)
+	}
 
-	// Look for external sourcemap URL
-	mapURL := sourcemap.ExtractSourceMappingURL(jsContent)
-	if mapURL == "" {
-		if cfg.Verbose {
-			fmt.Println(ui.Warning(fmt.Sprintf("No sourcemap found in: %s", filename)))
+	// Look for external sourcemaps: the SourceMap header and every
+	// sourceMappingURL comment. A map that fails to download or parse is
+	// recorded and the rest of the run still completes.
+	mapURLs := sourcemap.ExtractSourceMappingURLs(jsContent)
+	if len(mapURLs) > 0 {
+		record.HasSourceMappingURL = true
+	}
+	if info.SourceMap != "" {
+		mapURLs = append([]string{info.SourceMap}, mapURLs...)
+	}
+	if len(mapURLs) == 0 && !result.MapFound {
+		cfg.notice("No sourcemap found", "url", scriptURL, "path", filename)
+	}
+
+	seen := make(map[string]bool)
+	for _, mapURL := range mapURLs {
+		found, err := restoreSingleMap(cfg, scriptURL, mapURL, paths, result, seen)
+		if err != nil {
+			cfg.addErrors(&result.Errors, err)
 		}
-		return result, nil
+		result.MapFound = result.MapFound || found
 	}
 
+	finishSingle(cfg, paths, result)
+	return result, nil
+}
+
+// restoreSingleInline restores the inline sourcemap of the script. It
+// reports whether one was found.
+func restoreSingleInline(cfg *Config, jsContent, scriptURL, scriptPath string, paths DomainPaths, result *SingleResult) (bool, error) {
+	sm, err := sourcemap.ExtractInlineSourceMap(jsContent)
+	if err != nil {
+		return false, fmt.Errorf("failed to extract inline sourcemap: %w", err)
+	}
+	if sm == nil {
+		return false, nil
+	}
+	record := &result.Scripts[0]
 	result.MapFound = true
+	record.HasSourceMappingURL = true
+	record.MapURL = "inline"
+
+	// Save the inline map for reference
+	mapPath := scriptPath + ".inline.map"
+	cfg.success("Extracted inline sourcemap", "path", mapPath)
+	restoreSingleScriptMap(cfg, sm, mapPath, scriptURL, scriptPath, paths, result)
+	return true, nil
+}
+
+// restoreSingleEmbedded restores the sourcemaps the script embeds as JSON
+// string literals, saving each beside it as <script>.embedded-<n>.map.
+func restoreSingleEmbedded(cfg *Config, jsContent, scriptURL, scriptPath string, paths DomainPaths, result *SingleResult) {
+	for i, em := range sourcemap.ExtractEmbeddedSourceMaps(jsContent) {
+		result.MapFound = true
+		result.Scripts[0].embeddedFound()
+		mapPath := fmt.Sprintf("%s.embedded-%d.map", scriptPath, i+1)
+		cfg.success("Extracted embedded sourcemap", "path", mapPath)
+		restoreSingleScriptMap(cfg, em.SourceMap, mapPath, scriptURL, scriptPath, paths, result)
+	}
+}
+
+// restoreSingleScriptMap saves a sourcemap taken from the script to
+// mapPath, where its restore reads it from, records it, and queues the
+// restore of its sources.
+func restoreSingleScriptMap(cfg *Config, sm *sourcemap.SourceMap, mapPath, scriptURL, scriptPath string, paths DomainPaths, result *SingleResult) {
+	mapJSON, _ := json.MarshalIndent(sm, "", "  ")
+	if err := writeDownload(mapPath, mapJSON); err != nil {
+		cfg.addErrors(&result.Errors, fmt.Errorf("failed to save %s: %w", filepath.Base(mapPath), err))
+		return
+	}
+
+	mapRecord := cfg.parsedMapRecord(sm, "", mapPath)
+	scriptBundler(sm, scriptPath, &mapRecord)
+	cfg.addWarnings(&result.Warnings, cfg.verifyMap(sm, scriptPath, &mapRecord)...)
+	cfg.addWarnings(&result.Warnings, mapRecord.issues()...)
+	result.queueRestore(mapRecord, paths.RestoredSources, scriptURL, scriptPath)
+}
+
+// restoreSingleMap downloads a sourcemap referenced by the script and
+// queues the restore of its sources, reporting whether it was a usable
+// map. Maps already in seen are skipped.
+func restoreSingleMap(cfg *Config, scriptURL, mapURL string, paths DomainPaths, result *SingleResult, seen map[string]bool) (bool, error) {
+	record := &result.Scripts[0]
 
 	// Resolve relative map URL
 	resolvedMapURL, err := resolveURL(scriptURL, mapURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve map URL: %w", err)
+		return false, fmt.Errorf("failed to resolve map URL %s: %w", mapURL, err)
 	}
-
-	if cfg.Verbose {
-		fmt.Println(ui.Info(fmt.Sprintf("Found sourcemap: %s", resolvedMapURL)))
+	if record.MapURL == "" {
+		record.MapURL = resolvedMapURL
+	}
+	if seen[resolvedMapURL] {
+		return false, nil
 	}
+	seen[resolvedMapURL] = true
+
+	cfg.logger().Info("Found sourcemap", "url", resolvedMapURL)
 
 	// Download the sourcemap
-	mapFilename := filenameFromURL(resolvedMapURL)
+	mapFilename := cfg.mapFilename(resolvedMapURL, scriptURL)
 	mapPath := filepath.Join(paths.DownloadedSite, mapFilename)
 
-	if err := cfg.Client.Download(resolvedMapURL, mapPath); err != nil {
-		return nil, fmt.Errorf("failed to download sourcemap: %w", err)
+	mapInfo, err := cfg.Client.DownloadWithInfo(resolvedMapURL, mapPath)
+	if err != nil {
+		err = fmt.Errorf("failed to download sourcemap %s: %w", resolvedMapURL, err)
+		result.Maps = append(result.Maps, cfg.failedMapRecord(resolvedMapURL, mapPath, mapInfo, err))
+		return false, err
 	}
+	result.sums.add(mapPath, mapInfo.SHA256)
+	result.downloads.record(resolvedMapURL, mapPath, mapInfo)
+	cfg.mapLastModified(mapPath, mapInfo)
 
-	if cfg.Verbose {
-		fmt.Println(ui.Success(fmt.Sprintf("Downloaded: %s", mapFilename)))
-	}
+	cfg.success("Downloaded sourcemap", "url", resolvedMapURL, "path", mapPath)
 
 	// Parse and restore
 	sm, err := sourcemap.ParseFile(mapPath)
+	if decoy := decoyOf(mapPath, sm, err); decoy != "" {
+		mapRecord, warning := cfg.decoyMapRecord(resolvedMapURL, mapPath, mapInfo, decoy)
+		result.Maps = append(result.Maps, mapRecord)
+		cfg.addWarnings(&result.Warnings, warning)
+		return false, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse sourcemap: %w", err)
+		err = fmt.Errorf("failed to parse sourcemap %s: %w", resolvedMapURL, err)
+		result.Maps = append(result.Maps, cfg.failedMapRecord(resolvedMapURL, mapPath, mapInfo, err))
+		return false, err
 	}
 
-	// Use options to enable real asset fetching
-	opts := &sourcemap.RestoreOptions{
-		BaseURL: scriptURL,
-		Fetcher: cfg.Client,
-	}
-	restoreResult := sourcemap.RestoreSourcesWithOptions(sm, paths.RestoredSources, opts)
-	result.SourcesRestored = restoreResult.RestoredCount
-	result.Errors = restoreResult.Errors
+	mapRecord := cfg.parsedMapRecord(sm, resolvedMapURL, mapPath)
+	cfg.addWarnings(&result.Warnings, mapRecord.issues()...)
+	scriptBundler(sm, filepath.Join(paths.DownloadedSite, record.File), &mapRecord)
+	cfg.addWarnings(&result.Warnings, cfg.verifyMap(sm, filepath.Join(paths.DownloadedSite, record.File), &mapRecord)...)
+	mapRecord.setDownload(mapInfo)
+	cfg.addWarnings(&result.Warnings, mapRecord.redirect()...)
+	result.queueRestore(mapRecord, paths.RestoredSources, scriptURL, mapPath)
+	return true, nil
+}
 
-	return result, nil
+// queueRestore adds record to the run's maps and queues the restore of its
+// sources into dir.
+func (r *SingleResult) queueRestore(record MapRecord, dir, baseURL, mtimeOf string) {
+	r.restores = append(r.restores, restoreJob{Map: len(r.Maps), Dir: dir, BaseURL: baseURL, MtimeOf: mtimeOf})
+	r.Maps = append(r.Maps, record)
 }
 
+// restoreSingleQueued restores the sources of the maps queued while
+// downloading.
+func restoreSingleQueued(cfg *Config, result *SingleResult) {
+	for _, job := range result.restores {
+		record := &result.Maps[job.Map]
+		restoreResult, err := job.run(cfg, record.File)
+		if err != nil {
+			cfg.addErrors(&result.Errors, err)
+			continue
+		}
+		result.SourcesRestored += restoreResult.RestoredCount
+		result.SourcesFiltered += restoreResult.FilteredCount
+		result.SourcesMatched += restoreResult.MatchedCount
+		result.manifest = append(result.manifest, restoreResult.Files...)
+		cfg.addErrors(&result.Errors, restoreResult.Errors...)
+		cfg.addWarnings(&result.Warnings, restoreResult.Warnings...)
+		cfg.setRestored(record, restoreResult.RestoredCount)
+		result.Scripts[0].SourcesRestored += restoreResult.RestoredCount
+	}
+}
+
+// finishSingle runs the post-processing steps shared by every successful exit.
+func finishSingle(cfg *Config, paths DomainPaths, result *SingleResult) {
+	cfg.writeChecksums(paths, result.sums, &result.Errors)
+	if err := result.downloads.write(paths); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+	cfg.runHooks(HookAfterDownload, paths, result, &result.Warnings)
+	if !cfg.NoRestore {
+		restoreSingleQueued(cfg, result)
+		cfg.runHooks(HookAfterRestore, paths, result, &result.Warnings)
+		result.EnvVarsExtracted = cfg.extractEnvVars(paths, &result.Errors, &result.Warnings)
+
+		cfg.logger().Info("Scanning for embedded base64 assets...", "path", paths.RestoredSources)
+		assetResult := assets.ExtractFromDirectory(paths.RestoredSources, paths.ExtractedAssets)
+		result.AssetsExtracted = assetResult.ExtractedCount
+		cfg.addErrors(&result.Errors, assetResult.Errors...)
+
+		result.Stats = stats.Compute(result.manifest)
+		cfg.writeNormalized(paths, result.manifest, &result.Errors)
+		cfg.writeUnformatted(paths, result.manifest, &result.Errors)
+		if parsed, err := url.Parse(result.URL); err == nil {
+			cfg.writeScaffold(paths, parsed.Host, &result.Errors)
+		}
+		cfg.analyze(paths, &result.Analysis, &result.Errors)
+		cfg.runHooks(HookAfterAssets, paths, result, &result.Warnings)
+
+		if err := commitSnapshot(cfg, paths, result.URL, CountMaps(result.Maps)); err != nil {
+			cfg.addErrors(&result.Errors, err)
+		}
+	}
+
+	if err := writeCSVReports(cfg, paths.Base, result.Scripts, result.Maps); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+
+	cfg.emit("run_complete", map[string]interface{}{
+		"output_dir": result.OutputDir,
+		"map_found":  result.MapFound,
+		"sources":    result.SourcesRestored,
+		"assets":     result.AssetsExtracted,
+		"env_vars":   result.EnvVarsExtracted,
+		"errors":     len(result.Errors),
+		"warnings":   len(result.Warnings),
+	})
+}
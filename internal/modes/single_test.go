@@ -0,0 +1,106 @@
+package modes
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunSingleMapFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken.js":
+			io.WriteString(w, "var API_URL=\"https://api.example.com\";\n//# sourceMappingURL=missing.js.map\n//# sourceMappingURL=html.js.map\n")
+		case "/app.js":
+			io.WriteString(w, "console.log(1);\n//# sourceMappingURL=missing.js.map\n//# sourceMappingURL=app.js.map\n")
+		case "/app.js.map":
+			io.WriteString(w, `{"version":3,"sources":["src/a.js"],"sourcesContent":["console.log(1)\n"],"mappings":"AAAA"}`)
+		case "/html.js.map":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<!DOCTYPE html><html><body>app</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		script    string
+		wantFound bool
+	}{
+		{"/broken.js", false},
+		{"/app.js", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputRoot = t.TempDir()
+			result, err := RunSingle(cfg, srv.URL+tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.MapFound != tt.wantFound {
+				t.Errorf("MapFound = %v, want %v", result.MapFound, tt.wantFound)
+			}
+			if len(result.Errors) == 0 {
+				t.Error("the missing map was not reported")
+			}
+			if tt.wantFound && result.SourcesRestored != 1 {
+				t.Errorf("SourcesRestored = %d, want the working map's source", result.SourcesRestored)
+			}
+		})
+	}
+}
+
+func TestRestoreLogsVerbatim(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.js":
+			io.WriteString(w, "console.log(1);\n//# sourceMappingURL=app.js.map\n")
+		case "/app.js.map":
+			io.WriteString(w, `{"version":3,"sources":["src/a.js","node_modules/lib/index.js"],"sourcesContent":["console.log(1)\n","module.exports=1\n"],"mappings":""}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	runs := map[string]func(cfg *Config) error{
+		"single": func(cfg *Config) error {
+			_, err := RunSingle(cfg, srv.URL+"/app.js")
+			return err
+		},
+		"url": func(cfg *Config) error {
+			_, err := RunURLFromPlan(cfg, &Plan{
+				Version: PlanVersion,
+				Target:  srv.URL + "/",
+				Scripts: []PlannedScript{{URL: srv.URL + "/app.js"}},
+			})
+			return err
+		},
+	}
+	for name, run := range runs {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := DefaultConfig()
+			cfg.OutputRoot = t.TempDir()
+			cfg.Log = slog.New(slog.NewTextHandler(&buf, nil))
+			if err := run(cfg); err != nil {
+				t.Fatal(err)
+			}
+			var line string
+			for _, l := range strings.Split(buf.String(), "\n") {
+				if strings.Contains(l, "Left vendor or oversized sources unformatted") {
+					line = l
+				}
+			}
+			if !strings.Contains(line, "formatted=1") || !strings.Contains(line, "verbatim=1") {
+				t.Errorf("no formatted and verbatim counts logged:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package modes
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/filter"
+	"github.com/thesavant42/dejank/internal/robots"
+	"github.com/thesavant42/dejank/internal/sitemap"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// discoverSitemap loads up to SitemapPages pages of the target's site,
+// sampled from the sitemaps its robots.txt lists and its /sitemap.xml, and
+// merges what the browser finds on them into discovered. Sections built
+// as separate bundles are often never referenced from the entry page. It
+// returns the number of pages loaded and the problems met along the way,
+// none of which stop the run.
+func (c *Config) discoverSitemap(browser *fetch.BrowserClient, target *url.URL, discovered *fetch.DiscoveredResources, robots *robotsCache) (int, []warn.Warning) {
+	origin := target.Scheme + "://" + target.Host
+	sitemapURLs := c.sitemapURLs(origin)
+	c.logger().Info("Reading sitemaps...", "urls", sitemapURLs)
+
+	listed, errs := sitemap.FetchAll(c.Client, sitemapURLs)
+	warnings := make([]warn.Warning, 0, len(errs))
+	for _, err := range errs {
+		// Errors name the sitemap file that failed
+		warnings = append(warnings, warn.FromError(warn.Sitemap, origin, err))
+	}
+	candidates := sitemapCandidates(target, listed, robots)
+	pages := sitemap.Sample(candidates, c.SitemapPages)
+
+	c.logger().Info("Loading pages from sitemap",
+		"listed", len(listed), "candidates", len(candidates), "count", len(pages))
+
+	c.emit("sitemap_sampled", map[string]int{
+		"listed":  len(listed),
+		"sampled": len(pages),
+	})
+
+	loaded := 0
+	for i, page := range pages {
+		c.emit("sitemap_page", map[string]interface{}{
+			"index": i,
+			"total": len(pages),
+			"url":   page,
+		})
+		c.logger().Info("Discovering scripts", "url", page)
+
+		found, err := browser.DiscoverResources(page)
+		if err != nil {
+			warnings = append(warnings, warn.FromError(warn.Sitemap, page, err))
+			continue
+		}
+		if found.NavigationErr != nil {
+			warnings = append(warnings, warn.FromError(warn.Navigation, page, found.NavigationErr))
+		}
+		before := len(discovered.Scripts) + len(discovered.Embedded)
+		discovered.Merge(found)
+		loaded++
+
+		added := len(discovered.Scripts) + len(discovered.Embedded) - before
+		c.success("Found new scripts", "url", page, "count", added)
+	}
+	return loaded, warnings
+}
+
+// sitemapURLs returns the sitemaps to read for an origin: those listed on
+// Sitemap lines of its robots.txt, then /sitemap.xml.
+func (c *Config) sitemapURLs(origin string) []string {
+	defaultURL := origin + "/sitemap.xml"
+
+	var urls []string
+	body, status, err := c.Client.GetWithStatus(origin + "/robots.txt")
+	if err == nil && status == http.StatusOK {
+		for _, u := range robots.Sitemaps(string(body)) {
+			if resolved, err := resolveURL(origin+"/robots.txt", u); err == nil && resolved != defaultURL {
+				urls = append(urls, resolved)
+			}
+		}
+	}
+	return append(urls, defaultURL)
+}
+
+// sitemapCandidates returns the pages listed in a sitemap that are worth
+// loading for target: http(s) pages of the same site, www and apex
+// variants included, other than the target itself, that robots.txt allows.
+func sitemapCandidates(target *url.URL, listed []string, robots *robotsCache) []string {
+	var candidates []string
+	for _, page := range listed {
+		u, err := url.Parse(page)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		if !filter.SameSite(strings.ToLower(target.Hostname()), strings.ToLower(u.Hostname())) {
+			continue
+		}
+		if strings.EqualFold(u.Host, target.Host) && samePage(u, target) {
+			continue
+		}
+		if !robots.allowed(page) {
+			continue
+		}
+		candidates = append(candidates, page)
+	}
+	return candidates
+}
+
+// samePage reports whether two URLs name the same page, ignoring a
+// trailing slash and the fragment.
+func samePage(a, b *url.URL) bool {
+	return strings.TrimSuffix(a.Path, "/") == strings.TrimSuffix(b.Path, "/") && a.RawQuery == b.RawQuery
+}
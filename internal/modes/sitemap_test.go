@@ -0,0 +1,59 @@
+package modes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSitemapURLs(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "User-agent: *\nDisallow:\nSitemap: "+srv.URL+"/sitemap.xml\nSitemap: /sitemaps/blog.xml\n")
+	}))
+	defer srv.Close()
+
+	got := DefaultConfig().sitemapURLs(srv.URL)
+	want := []string{srv.URL + "/sitemaps/blog.xml", srv.URL + "/sitemap.xml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sitemapURLs = %q, want %q", got, want)
+	}
+}
+
+func TestSitemapURLsNoRobots(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	got := DefaultConfig().sitemapURLs(srv.URL)
+	if want := []string{srv.URL + "/sitemap.xml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sitemapURLs = %q, want %q", got, want)
+	}
+}
+
+func TestSitemapCandidates(t *testing.T) {
+	target, _ := url.Parse("https://example.com/")
+	listed := []string{
+		"https://example.com/",          // the target itself
+		"https://example.com/pricing",   // same origin
+		"https://www.example.com/blog",  // www variant
+		"http://example.com/docs",       // plain http
+		"https://shop.example.com/cart", // subdomain of the same site
+		"https://example.org/about",     // another site
+		"https://example.co.uk/about",   // another site under a public suffix
+		"ftp://example.com/files",       // not a page
+		"https://www.example.com/",      // the target's www variant
+	}
+	want := []string{
+		"https://example.com/pricing",
+		"https://www.example.com/blog",
+		"http://example.com/docs",
+		"https://shop.example.com/cart",
+		"https://www.example.com/",
+	}
+	if got := sitemapCandidates(target, listed, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("sitemapCandidates = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// snapshotIgnore is written to .gitignore when the snapshot repository lives at
+// the domain directory, so raw downloads and the run's lock file don't end
+// up in the history.
+const snapshotIgnore = "downloaded_site/\n" + lockFile + "\n"
+
+// commitSnapshot records the current restored sources in a git repository.
+// The repository is created in restored_sources (or the domain directory when
+// GitAtBase is set) on first use. Runs that change nothing produce no commit.
+func commitSnapshot(cfg *Config, paths DomainPaths, target string, mapCount int) error {
+	if !cfg.GitSnapshot {
+		return nil
+	}
+
+	repoDir := paths.RestoredSources
+	if cfg.GitAtBase {
+		repoDir = paths.Base
+	}
+
+	repo, err := git.PlainOpen(repoDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInit(repoDir, false)
+		if err == nil && cfg.GitAtBase {
+			err = writeSnapshotIgnore(repoDir)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot repository: %w", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot worktree: %w", err)
+	}
+
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot status: %w", err)
+	}
+	if status.IsClean() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	msg := fmt.Sprintf("dejank run %s\n\nTarget: %s\nMaps: %d\n", now.Format(time.RFC3339), target, mapCount)
+	_, err = wt.Commit(msg, &git.CommitOptions{
+		All: true,
+		Author: &object.Signature{
+			Name:  "dejank",
+			Email: "dejank@localhost",
+			When:  now,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	cfg.success("Committed snapshot", "path", repoDir)
+
+	return nil
+}
+
+// writeSnapshotIgnore writes the default .gitignore unless one already exists.
+func writeSnapshotIgnore(repoDir string) error {
+	path := filepath.Join(repoDir, ".gitignore")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return os.WriteFile(path, []byte(snapshotIgnore), 0644)
+}
@@ -0,0 +1,190 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thesavant42/dejank/internal/assets"
+	"github.com/thesavant42/dejank/internal/stats"
+)
+
+// splitHostsFile is written to the page's domain directory by
+// -split-by-host and lists the domain directories the run's scripts and
+// maps were stored under.
+const splitHostsFile = "split_hosts.json"
+
+// HostOutput is the share of a -split-by-host run stored under one host's
+// domain directory.
+type HostOutput struct {
+	Host            string   `json:"host"`
+	OutputDir       string   `json:"output_dir"`
+	Scripts         []string `json:"scripts"` // Script URLs stored under OutputDir
+	Maps            []string `json:"maps"`    // Sourcemap URLs or, for inline maps, files stored under OutputDir
+	SourcesRestored int      `json:"sources_restored"`
+}
+
+// hostPaths returns the domain directory that files from u are stored
+// under: with -split-by-host, that of u's own host, created on first use;
+// otherwise, or for the page's host and non-http URLs, the page's. A host
+// whose directory cannot be created falls back to the page's.
+func (c *Config) hostPaths(u string, page DomainPaths, result *URLResult) DomainPaths {
+	if !c.SplitByHost {
+		return page
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return page
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" || host == result.pageHost {
+		return page
+	}
+	if paths, ok := result.hosts[host]; ok {
+		return paths
+	}
+
+	paths := c.domainPaths(host)
+	if release, err := c.prepareOutput(paths); err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to create output for %s, storing its files with the page: %w", host, err))
+		paths = page
+	} else {
+		result.unlocks = append(result.unlocks, release)
+		c.logger().Info("Storing files under their host's directory", "host", host, "path", paths.Base)
+	}
+	if result.hosts == nil {
+		result.hosts = make(map[string]DomainPaths)
+	}
+	result.hosts[host] = paths
+	return paths
+}
+
+// otherHosts returns, sorted, the hosts that got a domain directory of
+// their own.
+func (r *URLResult) otherHosts(page DomainPaths) []string {
+	hosts := make([]string, 0, len(r.hosts))
+	for host, paths := range r.hosts {
+		if paths.Base != page.Base {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// OutputDirs returns the page's domain directory followed by those of other
+// hosts with -split-by-host.
+func (r *URLResult) OutputDirs() []string {
+	dirs := []string{r.OutputDir}
+	for _, h := range r.Hosts {
+		if h.OutputDir != r.OutputDir {
+			dirs = append(dirs, h.OutputDir)
+		}
+	}
+	return dirs
+}
+
+// splitByHost groups the run's scripts and maps by the domain directory
+// they were stored under, page first.
+func (r *URLResult) splitByHost(page DomainPaths) []HostOutput {
+	others := r.otherHosts(page)
+	if len(others) == 0 {
+		return nil
+	}
+
+	outputs := []HostOutput{{Host: r.pageHost, OutputDir: page.Base}}
+	byDir := map[string]int{page.Base: 0}
+	for _, host := range others {
+		dir := r.hosts[host].Base
+		byDir[dir] = len(outputs)
+		outputs = append(outputs, HostOutput{Host: host, OutputDir: dir})
+	}
+
+	for _, s := range r.Scripts {
+		i := 0
+		if parsed, err := url.Parse(s.URL); err == nil {
+			if paths, ok := r.hosts[strings.ToLower(parsed.Hostname())]; ok {
+				i = byDir[paths.Base]
+			}
+		}
+		outputs[i].Scripts = append(outputs[i].Scripts, s.URL)
+	}
+	for _, m := range r.Maps {
+		// Maps are saved under <domain dir>/downloaded_site
+		i := byDir[filepath.Dir(filepath.Dir(m.File))]
+		name := m.URL
+		if name == "" {
+			name = filepath.Base(m.File)
+		}
+		outputs[i].Maps = append(outputs[i].Maps, name)
+		if m.Error == "" {
+			outputs[i].SourcesRestored += m.SourcesRestored
+		}
+	}
+	return outputs
+}
+
+// rebaseManifest rewrites the paths of files, relative to dir, to be
+// relative to root, so that a run's manifest has one root when
+// -split-by-host restores into several domain directories.
+func rebaseManifest(files []stats.File, dir, root string) {
+	if dir == root {
+		return
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return
+	}
+	for i := range files {
+		files[i].Path = path.Join(filepath.ToSlash(rel), files[i].Path)
+	}
+}
+
+// finishHostDirs runs the per-directory steps of url mode on the domain
+// directories of other hosts: env var and embedded asset extraction and
+// the analyzers.
+func (c *Config) finishHostDirs(page DomainPaths, result *URLResult) {
+	for _, host := range result.otherHosts(page) {
+		paths := result.hosts[host]
+		result.EnvVarsExtracted += c.extractEnvVars(paths, &result.Errors, &result.Warnings)
+
+		assetResult := assets.ExtractFromDirectory(paths.RestoredSources, paths.ExtractedAssets)
+		result.AssetsExtracted += assetResult.ExtractedCount
+		c.addErrors(&result.Errors, assetResult.Errors...)
+
+		// Stats already cover the sources of every host
+		analysis := Analysis{Stats: result.Stats}
+		c.analyze(paths, &analysis, &result.Errors)
+		result.Analysis.add(analysis)
+	}
+}
+
+// writeSplitHosts writes split_hosts.json to the page's domain directory,
+// with each directory relative to it.
+func (c *Config) writeSplitHosts(page DomainPaths, result *URLResult) {
+	if len(result.Hosts) == 0 {
+		return
+	}
+
+	entries := make([]HostOutput, len(result.Hosts))
+	for i, h := range result.Hosts {
+		if rel, err := filepath.Rel(page.Base, h.OutputDir); err == nil {
+			h.OutputDir = filepath.ToSlash(rel)
+		}
+		entries[i] = h
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to encode %s: %w", splitHostsFile, err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(page.Base, splitHostsFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to write %s: %w", splitHostsFile, err))
+	}
+}
@@ -0,0 +1,84 @@
+package modes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/filter"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/sourcemap"
+)
+
+func TestSplitByHostManifestRoot(t *testing.T) {
+	maps := map[string]string{
+		"/app.js.map": `{"version":3,"sources":["webpack:///src/app.js"],"sourcesContent":["var a = 1;\r\nvar b = 2;\r\n"],"mappings":""}`,
+		"/cdn.js.map": `{"version":3,"sources":["webpack:///src/cdn.js"],"sourcesContent":["var c = 1;\r\nvar d = 2;\r\n"],"mappings":""}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m, ok := maps[r.URL.Path]; ok {
+			w.Write([]byte(m))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".js") {
+			w.Header().Set("Content-Type", "application/javascript")
+			w.Write([]byte("var x;\n//# sourceMappingURL=" + strings.TrimPrefix(r.URL.Path, "/") + ".map\n"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	// 127.0.0.1 and localhost are two hosts on the same server
+	page := srv.URL
+	other := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	cfg.Scope = filter.ScopeAll
+	cfg.SplitByHost = true
+	cfg.NormalizeEOL = sourcemap.EOLLF
+	result, err := RunURLFromPlan(cfg, &Plan{
+		Version: PlanVersion,
+		Target:  page + "/",
+		Scripts: []PlannedScript{{URL: page + "/app.js"}, {URL: other + "/cdn.js"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	if len(result.Hosts) != 2 {
+		t.Fatalf("Hosts = %+v, want the page and localhost", result.Hosts)
+	}
+
+	// Every path in normalized.json is relative to the page's
+	// restored_sources, including the other host's
+	data, err := os.ReadFile(filepath.Join(result.OutputDir, normalizedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []schema.Normalized
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	root := filepath.Join(result.OutputDir, "restored_sources")
+	for _, e := range entries {
+		got = append(got, e.Path)
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(e.Path))); err != nil {
+			t.Errorf("%s is not relative to the page's restored_sources: %v", e.Path, err)
+		}
+	}
+	sort.Strings(got)
+	want := []string{"../../localhost-dejank/restored_sources/src/cdn.js", "src/app.js"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("%s paths = %q, want %q", normalizedFile, got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// targetFile records, per domain directory, which URL was requested and
+// which page it ended up on after redirects.
+const targetFile = "target.json"
+
+// finalTarget returns the page URL discovery ended on after redirects, such
+// as http://example.com to https://www.example.com. Its host names the
+// domain directory, so a site is kept in one directory however it is
+// requested. The requested URL is returned when finalURL is empty or not
+// an http(s) URL.
+func finalTarget(requested *url.URL, finalURL string) *url.URL {
+	if finalURL == "" {
+		return requested
+	}
+	final, err := url.Parse(finalURL)
+	if err != nil || final.Host == "" || (final.Scheme != "http" && final.Scheme != "https") {
+		return requested
+	}
+	return final
+}
+
+// recordTarget writes target.json and warns when the target redirected to
+// another host.
+func (c *Config) recordTarget(paths DomainPaths, result *URLResult) {
+	requested, err := url.Parse(result.URL)
+	if err != nil {
+		return
+	}
+	final := finalTarget(requested, result.FinalURL)
+
+	if final.Host != requested.Host {
+		c.addWarnings(&result.Warnings, warn.New(warn.TargetRedirected, result.URL,
+			"redirected to %s; output is written under %s", final, filepath.Base(GetDomainPaths(c.OutputRoot, final.Host).Base)))
+	}
+
+	data, err := json.MarshalIndent(schema.Target{
+		Versioned:     schema.Current(),
+		RequestedURL:  result.URL,
+		RequestedHost: requested.Host,
+		FinalURL:      final.String(),
+		FinalHost:     final.Host,
+	}, "", "  ")
+	if err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to encode %s: %w", targetFile, err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, targetFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to write %s: %w", targetFile, err))
+	}
+}
@@ -0,0 +1,67 @@
+package modes
+
+import (
+	"fmt"
+	"sync"
+)
+
+// TargetResult is the outcome of one target of RunURLs.
+type TargetResult struct {
+	URL    string
+	Result *URLResult // nil if Err is set
+	Err    error
+}
+
+// RunURLs runs RunURL for each target, with up to parallel targets in
+// flight, and returns their results in the order of targets. Each run gets
+// its own copy of cfg and its own domain directory; one that fails, or
+// panics, doesn't stop the others. A "target_complete" event is emitted as
+// each finishes.
+func RunURLs(cfg *Config, targets []string, parallel int) []TargetResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]TargetResult, len(targets))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	// target_complete goes to callbacks, such as a progress bar, that are
+	// not safe to call from several goroutines at once
+	var emitMu sync.Mutex
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = runTarget(cfg, target)
+			data := map[string]interface{}{
+				"url":   target,
+				"index": i,
+				"total": len(targets),
+			}
+			if err := results[i].Err; err != nil {
+				data["error"] = err.Error()
+			}
+			emitMu.Lock()
+			cfg.emit("target_complete", data)
+			emitMu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runTarget runs one target of RunURLs, turning a panic into its error.
+func runTarget(cfg *Config, target string) (tr TargetResult) {
+	tr.URL = target
+	defer func() {
+		if r := recover(); r != nil {
+			tr.Result, tr.Err = nil, fmt.Errorf("run panicked: %v", r)
+		}
+	}()
+	c := *cfg
+	tr.Result, tr.Err = RunURL(&c, target)
+	return tr
+}
@@ -1,7 +1,10 @@
 package modes
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -9,21 +12,73 @@ import (
 	"strings"
 
 	"github.com/thesavant42/dejank/internal/assets"
-	"github.com/thesavant42/dejank/internal/envars"
 	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/filter"
+	"github.com/thesavant42/dejank/internal/schema"
 	"github.com/thesavant42/dejank/internal/sourcemap"
-	"github.com/thesavant42/dejank/internal/ui"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
 )
 
 // URLResult contains the results of processing a URL.
 type URLResult struct {
 	URL              string
+	FinalURL         string       // Page URL after redirects; its host names OutputDir
+	OutputDir        string       // Domain directory the run was written to
+	Hosts            []HostOutput // Per-host share of a -split-by-host run, page first (nil if none)
+	Screenshot       string       // Full-page screenshot saved by -snapshot, "" if none
+	PageHTML         string       // Rendered DOM saved by -snapshot, "" if none
 	ScriptsFound     int
 	MapsDiscovered   int
 	SourcesRestored  int
 	AssetsExtracted  int
 	EnvVarsExtracted int
+	OutOfScope       int    // Scripts, maps, and assets on hosts outside -scope or -scope-file
+	ModulesDumped    int    // webpack modules saved by -dump-modules
+	ScriptsExecuted  int    // Scripts with code that ran during page load (-coverage)
+	SourcesExecuted  int    // Original sources with code that ran during page load (-coverage)
+	RobotsDisallowed int    // Scripts and maps skipped because robots.txt disallows them
+	ThirdParty       int    // Known third-party scripts and maps skipped by the denylist
+	MobileOnly       int    // Scripts only the mobile pass of -emulate both found
+	ScriptsTriggered int    // Scripts first requested after -interact or -auto-scroll began
+	Prerendered      int    // Scripts first requested by a prerendered page
+	PrefetchedUnused int    // Scripts only prefetched, never loaded by the page
+	SitemapPages     int    // Pages from sitemap.xml loaded for discovery (-sitemap)
+	ScriptsReused    int    // Scripts unchanged since an earlier run, taken from disk instead of downloaded
+	DedupedBytes     int64  // Downloaded bytes already in the -cas store and hardlinked to it
+	BudgetExceeded   string // The -max-* limit that stopped the run early, "" if none
+	BudgetSkipped    int    // Scripts and maps left unprocessed because of BudgetExceeded
+	ScriptsFiltered  int    // Scripts skipped by -include-url/-exclude-url
+	MapsFiltered     int    // Maps skipped by -include-url/-exclude-url
+	MapsSeen         int    // Maps skipped because an earlier run processed them (-seen)
+	MapsTemplated    int    // Maps found at -map-url-template for scripts that name none
+	SourcesMatched   int    // Sources that passed -only and source filters
+	SourcesFiltered  int    // Sources skipped by -only/-include-source/-exclude-source
+	Scripts          []ScriptRecord
+	Maps             []MapRecord
 	Errors           []error
+	Warnings         []warn.Warning  // Non-fatal problems such as skipped sources and failed hooks
+	URLs             []DiscoveredURL // Every script and map URL found, processed or not
+
+	Analysis // Counts from the analyzers, and restored-source statistics
+
+	manifest  []stats.File           // Every source restored this run
+	budget    *budget                // -max-* limits, started with the run
+	robots    *robotsCache           // robots.txt rules with -respect-robots
+	restores  []restoreJob           // Maps whose sources are restored once the downloads are done
+	downloads *downloadCache         // Scripts from earlier runs that may be reused
+	sums      checksums              // SHA-256 of each download, taken as it arrived
+	pageHost  string                 // Lowercased host of the page, without port
+	hosts     map[string]DomainPaths // Domain directories of other hosts with -split-by-host
+	unlocks   []func()               // Release the locks on the run's output directories
+}
+
+// releaseLocks releases the locks on the run's output directories.
+func (r *URLResult) releaseLocks() {
+	for _, unlock := range r.unlocks {
+		unlock()
+	}
+	r.unlocks = nil
 }
 
 // RunURL crawls a webpage using headless Chrome, discovers all scripts and sourcemaps,
@@ -34,236 +89,640 @@ func RunURL(cfg *Config, targetURL string) (*URLResult, error) {
 		return nil, fmt.Errorf("invalid URL: must include http:// or https:// scheme")
 	}
 
-	result := &URLResult{URL: targetURL}
+	result := &URLResult{URL: targetURL, budget: cfg.newBudget()}
 
 	// Parse URL to get hostname
 	parsed, err := url.Parse(targetURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
-
-	paths := GetDomainPaths(cfg.OutputRoot, parsed.Host)
-
-	// Check for existing directory
-	if paths.Exists() && !cfg.Force {
-		return nil, fmt.Errorf("output directory already exists: %s (use -f to overwrite)", paths.Base)
+	if !cfg.ScopeList.Allows(parsed.Host) {
+		return nil, fmt.Errorf("%s is not in the scope file", parsed.Hostname())
 	}
 
-	if err := paths.EnsureDirs(); err != nil {
-		return nil, err
+	if cfg.Resume {
+		st, paths, err := cfg.loadRunState(parsed.Host)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			return resumeURL(cfg, paths, st)
+		}
 	}
 
 	// Use browser client to discover resources via JS execution
-	if cfg.Verbose {
-		fmt.Println(ui.Info("Launching headless browser..."))
-	}
+	cfg.logger().Info("Launching headless browser...", "url", targetURL)
 
-	browser := fetch.NewBrowserClient()
+	browser := cfg.newBrowser()
+	browser.DumpModules = cfg.DumpModules
+	browser.Coverage = cfg.Coverage
+	browser.Snapshot = cfg.PageSnapshot
 	discovered, err := browser.DiscoverResources(targetURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover resources: %w", err)
 	}
 
-	result.ScriptsFound = len(discovered.Scripts)
+	if discovered.NavigationErr != nil {
+		cfg.addWarnings(&result.Warnings, warn.FromError(warn.Navigation, targetURL, discovered.NavigationErr))
+	}
+
+	var warnings []warn.Warning
+	result.MobileOnly, warnings = cfg.discoverMobile(targetURL, discovered)
+	cfg.addWarnings(&result.Warnings, warnings...)
+
+	// Name the output after the page the target redirected to
+	parsed = finalTarget(parsed, discovered.BaseURL)
+	result.FinalURL = parsed.String()
 
-	if cfg.Verbose {
-		fmt.Println(ui.Info(fmt.Sprintf("Discovered %d scripts via browser", result.ScriptsFound)))
+	// An interrupted run is found under the host the target redirected to
+	// only once the page has loaded
+	if cfg.Resume {
+		st, paths, err := cfg.loadRunState(parsed.Host)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			return resumeURL(cfg, paths, st)
+		}
+	}
+
+	paths := cfg.domainPaths(parsed.Host)
+	release, err := cfg.prepareOutput(paths)
+	if err != nil {
+		return nil, err
 	}
+	result.unlocks = append(result.unlocks, release)
+	defer result.releaseLocks()
+	result.OutputDir = paths.Base
+	cfg.savePageSnapshot(paths, discovered, result)
+
+	result.robots = cfg.newRobots()
+	if cfg.SitemapPages > 0 {
+		result.SitemapPages, warnings = cfg.discoverSitemap(browser, parsed, discovered, result.robots)
+		cfg.addWarnings(&result.Warnings, warnings...)
+	}
+	cfg.addWarnings(&result.Warnings, consoleWarnings(targetURL, discovered)...)
+	cfg.addWarnings(&result.Warnings, cfg.interactionWarnings(targetURL, discovered)...)
+
+	result.ScriptsFound = len(discovered.Scripts) + len(discovered.Embedded)
+
+	cfg.logger().Info("Discovered scripts via browser", "url", targetURL, "count", result.ScriptsFound)
+	cfg.logger().Info("Browser User-Agent", "user_agent", discovered.UserAgent)
+
+	return processDiscovered(cfg, parsed, paths, discovered, result)
+}
+
+// processDiscovered selects the discovered scripts and maps to process and
+// starts the run's state, then processes them.
+func processDiscovered(cfg *Config, parsed *url.URL, paths DomainPaths, discovered *fetch.DiscoveredResources, result *URLResult) (*URLResult, error) {
+	scripts := cfg.selectURLs(parsed, discovered.Scripts, &result.OutOfScope, &result.ThirdParty, &result.ScriptsFiltered, &result.Warnings)
+	sourceMaps := cfg.selectURLs(parsed, discovered.SourceMaps, &result.OutOfScope, &result.ThirdParty, &result.MapsFiltered, &result.Warnings)
+
+	if result.robots == nil {
+		result.robots = cfg.newRobots()
+	}
+	scripts = result.robots.filter(scripts, &result.RobotsDisallowed)
+	sourceMaps = result.robots.filter(sourceMaps, &result.RobotsDisallowed)
+
+	return processChosen(cfg, parsed, paths, discovered, scripts, sourceMaps, result)
+}
+
+// processChosen processes the scripts and maps selected from discovered,
+// whose skips are already counted in result.
+func processChosen(cfg *Config, parsed *url.URL, paths DomainPaths, discovered *fetch.DiscoveredResources, scripts, sourceMaps []string, result *URLResult) (*URLResult, error) {
+	cfg.recordTarget(paths, result)
+
+	if result.RobotsDisallowed > 0 {
+		cfg.logger().Info("Skipped scripts and maps disallowed by robots.txt", "count", result.RobotsDisallowed)
+	}
+
+	result.URLs = cfg.discoveredURLs(parsed, result.URL, discovered.Scripts, "script", scripts)
+	result.URLs = append(result.URLs, cfg.discoveredURLs(parsed, result.URL, discovered.SourceMaps, "map", sourceMaps)...)
+
+	if result.OutOfScope > 0 {
+		cfg.logger().Info("Skipped scripts and maps out of scope", "count", result.OutOfScope, "scope", string(cfg.Scope))
+	}
+	if result.ThirdParty > 0 {
+		cfg.logger().Info("Skipped known third-party scripts and maps", "count", result.ThirdParty)
+	}
+	if result.ScriptsFiltered > 0 || result.MapsFiltered > 0 {
+		cfg.logger().Info("Skipped scripts and maps by filter",
+			"scripts", result.ScriptsFiltered, "maps", result.MapsFiltered)
+	}
+
+	result.downloads = cfg.loadDownloads(paths)
+	st := cfg.newRunState(paths, scripts, sourceMaps, discovered, result)
+
+	return processSelected(cfg, parsed, paths, discovered, st, result)
+}
+
+// processSelected downloads the selected scripts and maps, restores
+// sources, and runs the post-processing steps, recording its progress in
+// st. Scripts, maps, and phases that st records as done are skipped.
+func processSelected(cfg *Config, parsed *url.URL, paths DomainPaths, discovered *fetch.DiscoveredResources, st *runState, result *URLResult) (*URLResult, error) {
+	targetURL := result.URL
+	cfg.printUserAgent()
+	result.pageHost = strings.ToLower(parsed.Hostname())
 
 	cfg.emit("discovery_complete", map[string]int{
-		"scripts": result.ScriptsFound,
+		"scripts":  len(st.Scripts) - st.ScriptsDone,
+		"filtered": result.ScriptsFiltered,
 	})
 
 	// Track discovered maps to avoid duplicates
-	processedMaps := make(map[string]bool)
+	processedMaps := st.processed
 
 	// Process sourcemaps discovered via network interception and response headers
-	for _, mapURL := range discovered.SourceMaps {
+	for i := st.MapsDone; i < len(st.SourceMaps) && !diskStopped(result.Errors); i++ {
+		st.MapsDone = i
+		st.checkpoint(cfg, result)
+		mapURL := st.SourceMaps[i]
 		if processedMaps[mapURL] {
 			continue
 		}
 		processedMaps[mapURL] = true
-
-		if cfg.Verbose {
-			fmt.Println(ui.Info(fmt.Sprintf("Processing discovered sourcemap: %s", mapURL)))
+		if cfg.Seen.seenURL(mapURL) {
+			result.seen(cfg, mapURL)
+			continue
+		}
+		if !result.budget.sourceMap() {
+			continue
 		}
 
-		if err := processSourceMap(cfg, mapURL, paths, result, targetURL); err != nil {
-			result.Errors = append(result.Errors, err)
+		cfg.logger().Info("Processing discovered sourcemap", "url", mapURL)
+
+		if err := processSourceMap(cfg, mapURL, "", paths, result, targetURL); err != nil {
+			cfg.addFetchErrors(result, err)
 		}
 	}
+	if !diskStopped(result.Errors) {
+		st.MapsDone = len(st.SourceMaps)
+	}
 
 	// Process scripts to find additional sourcemaps via inline/header references
-	for i, scriptURL := range discovered.Scripts {
+	for i := st.ScriptsDone; i < len(st.Scripts) && !diskStopped(result.Errors); i++ {
+		st.ScriptsDone = i
+		st.checkpoint(cfg, result)
+		scriptURL := st.Scripts[i]
 		cfg.emit("processing_script", map[string]interface{}{
 			"index": i,
-			"total": len(discovered.Scripts),
+			"total": len(st.Scripts),
 			"url":   scriptURL,
 		})
+		if !result.budget.script() {
+			continue
+		}
 
 		if err := processScriptForMaps(cfg, scriptURL, paths, result, processedMaps, targetURL); err != nil {
-			result.Errors = append(result.Errors, err)
+			cfg.addFetchErrors(result, err)
+		}
+	}
+	if !diskStopped(result.Errors) {
+		st.ScriptsDone = len(st.Scripts)
+	}
+
+	// Scripts from blob: and data: URLs were captured by the browser
+	counts := make(map[string]int)
+	for i, script := range discovered.Embedded {
+		if i < st.EmbeddedDone {
+			counts[script.Kind]++
+			continue
+		}
+		if diskStopped(result.Errors) {
+			break
+		}
+		st.EmbeddedDone = i
+		st.checkpoint(cfg, result)
+		if !result.budget.script() {
+			continue
 		}
+		counts[script.Kind]++
+		if err := processEmbeddedScript(cfg, script, counts[script.Kind], paths, result, processedMaps, targetURL); err != nil {
+			cfg.addFetchErrors(result, err)
+		}
+	}
+	if diskStopped(result.Errors) {
+		// Nothing more can be written; what is on disk is kept, and the
+		// run state lets -resume continue once there is room
+		return stopOnDisk(cfg, result), nil
 	}
+	st.EmbeddedDone = len(discovered.Embedded)
+
+	if st.pending(phaseDownloads) {
+		result.ScriptsTriggered = tagScripts(result.Scripts, discovered.Triggered, func(r *ScriptRecord) { r.Triggered = true })
+		result.Prerendered = tagScripts(result.Scripts, discovered.Prerendered, func(r *ScriptRecord) { r.Prerendered = true })
+		result.PrefetchedUnused = tagScripts(result.Scripts, discovered.Prefetched, func(r *ScriptRecord) { r.PrefetchedUnused = true })
+		if result.Prerendered > 0 {
+			cfg.logger().Info("Scripts loaded by prerendered pages", "count", result.Prerendered)
+		}
+		if result.PrefetchedUnused > 0 {
+			cfg.logger().Info("Scripts prefetched but never loaded by the page", "count", result.PrefetchedUnused)
+		}
+
+		if err := result.downloads.write(paths); err != nil {
+			cfg.addErrors(&result.Errors, err)
+		}
+		if result.ScriptsReused > 0 {
+			cfg.logger().Info("Reused unchanged scripts from disk", "count", result.ScriptsReused)
+		}
 
-	// MapsDiscovered is the count of unique maps we found and processed
-	result.MapsDiscovered = len(processedMaps)
+		if cfg.DumpModules {
+			result.ModulesDumped = cfg.saveModules(paths, discovered, &result.Errors, &result.Warnings)
+		}
 
-	// Extract environment variables from all downloaded JS files
-	if cfg.Verbose {
-		fmt.Println(ui.Info("Extracting environment variables from bundled JS..."))
-	}
-	allEnvVars := make(map[string]string)
-	entries, err := os.ReadDir(paths.DownloadedSite)
-	if err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".js") {
-				continue
-			}
-			jsPath := filepath.Join(paths.DownloadedSite, entry.Name())
-			content, err := os.ReadFile(jsPath)
-			if err != nil {
-				continue
-			}
-			extractedVars := envars.ExtractEnvVars(string(content))
-			allEnvVars = envars.MergeEnvVars(allEnvVars, extractedVars)
+		if cfg.Coverage {
+			result.ScriptsExecuted, result.SourcesExecuted = cfg.writeCoverage(paths, discovered, result)
+		}
+
+		cfg.writeChecksums(paths, result.sums, &result.Errors)
+		result.DedupedBytes = cfg.storeDownloads(paths.DownloadedSite, &result.Errors, &result.Warnings)
+		for _, host := range result.otherHosts(paths) {
+			cfg.writeChecksums(result.hosts[host], result.sums, &result.Errors)
+			result.DedupedBytes += cfg.storeDownloads(result.hosts[host].DownloadedSite, &result.Errors, &result.Warnings)
 		}
+		st.finish(cfg, phaseDownloads, result)
 	}
+	// Maps skipped by filters, robots.txt, or the budget have no record and
+	// are counted separately
+	result.MapsDiscovered = CountMaps(result.Maps)
 
-	// Write .env file if we found any environment variables
-	if len(allEnvVars) > 0 {
-		envPath := filepath.Join(paths.RestoredSources, ".env")
-		if err := envars.WriteEnvFile(allEnvVars, envPath); err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to write .env file: %w", err))
-		} else {
-			result.EnvVarsExtracted = len(allEnvVars)
-			if cfg.Verbose {
-				fmt.Println(ui.Success(fmt.Sprintf("Extracted %d environment variable(s) to .env", len(allEnvVars))))
-			}
+	if result.robots != nil {
+		cfg.addWarnings(&result.Warnings, result.robots.warnings...)
+	}
+	result.BudgetExceeded, result.BudgetSkipped = result.budget.report()
+	if result.BudgetExceeded != "" {
+		cfg.emit("budget_exceeded", map[string]interface{}{
+			"limit":   result.BudgetExceeded,
+			"skipped": result.BudgetSkipped,
+		})
+	}
+
+	if st.pending(phaseDownloadHooks) {
+		cfg.runHooks(HookAfterDownload, paths, result, &result.Warnings)
+		st.finish(cfg, phaseDownloadHooks, result)
+	}
+	if !cfg.NoRestore && st.pending(phaseSources) {
+		restoreQueued(cfg, st, paths, result)
+		if diskStopped(result.Errors) {
+			return stopOnDisk(cfg, result), nil
 		}
+		st.finish(cfg, phaseSources, result)
+	}
+	_, result.SourcesRestored = restoredFrom(result.Maps)
+	result.Hosts = result.splitByHost(paths)
+
+	if !cfg.NoRestore && st.pending(phaseRestore) {
+		cfg.runHooks(HookAfterRestore, paths, result, &result.Warnings)
+		extractURLArtifacts(cfg, paths, result)
+		result.Stats = stats.Compute(result.manifest)
+		cfg.writeNormalized(paths, result.manifest, &result.Errors)
+		cfg.writeUnformatted(paths, result.manifest, &result.Errors)
+		cfg.writeScaffold(paths, parsed.Host, &result.Errors)
+		cfg.analyze(paths, &result.Analysis, &result.Errors)
+		cfg.finishHostDirs(paths, result)
+		cfg.runHooks(HookAfterAssets, paths, result, &result.Warnings)
+
+		if err := commitSnapshot(cfg, paths, targetURL, result.MapsDiscovered); err != nil {
+			cfg.addErrors(&result.Errors, err)
+		}
+		st.finish(cfg, phaseRestore, result)
 	}
 
-	// Extract embedded assets from restored sources
-	if cfg.Verbose {
-		fmt.Println(ui.Info("Scanning for embedded base64 assets..."))
+	if err := writeCSVReports(cfg, paths.Base, result.Scripts, result.Maps); err != nil {
+		cfg.addErrors(&result.Errors, err)
+	}
+	cfg.writeSplitHosts(paths, result)
+	cfg.writeOutOfScope(paths, result)
+	if err := cfg.Seen.record(result.Maps); err != nil {
+		cfg.addErrors(&result.Errors, err)
 	}
+	st.remove()
+
+	cfg.emit("run_complete", map[string]interface{}{
+		"output_dir": result.OutputDir,
+		"final_url":  result.FinalURL,
+		"scripts":    result.ScriptsFound,
+		"maps":       result.MapsDiscovered,
+		"sources":    result.SourcesRestored,
+		"assets":     result.AssetsExtracted,
+		"env_vars":   result.EnvVarsExtracted,
+		"errors":     len(result.Errors),
+		"warnings":   len(result.Warnings),
+	})
+
+	return result, nil
+}
+
+// extractURLArtifacts extracts environment variables from the downloaded
+// scripts and embedded and webpack assets from the restored sources.
+func extractURLArtifacts(cfg *Config, paths DomainPaths, result *URLResult) {
+	targetURL := result.URL
+
+	// Extract environment variables from all downloaded JS files
+	result.EnvVarsExtracted = cfg.extractEnvVars(paths, &result.Errors, &result.Warnings)
+
+	// Extract embedded assets from restored sources
+	cfg.logger().Info("Scanning for embedded base64 assets...", "path", paths.RestoredSources)
 	assetResult := assets.ExtractFromDirectory(paths.RestoredSources, paths.ExtractedAssets)
 	result.AssetsExtracted = assetResult.ExtractedCount
-	result.Errors = append(result.Errors, assetResult.Errors...)
+	cfg.addErrors(&result.Errors, assetResult.Errors...)
 
 	// Download webpack static assets (SVGs, images, etc.) and replace fake loader files
-	if cfg.Verbose {
-		fmt.Println(ui.Info("Downloading webpack static assets..."))
-	}
+	cfg.logger().Info("Downloading webpack static assets...", "url", targetURL)
 	downloadResult := assets.DownloadWebpackAssets(targetURL, paths.RestoredSources, cfg.Client)
 	result.AssetsExtracted += downloadResult.DownloadedCount
-	result.Errors = append(result.Errors, downloadResult.Errors...)
+	cfg.addFetchErrors(result, downloadResult.Errors...)
+}
 
-	return result, nil
+// processSourceMap downloads and records a sourcemap URL referenced by
+// scriptURL, or discovered directly when scriptURL is "", and queues the
+// restore of its sources.
+func processSourceMap(cfg *Config, mapURL, scriptURL string, paths DomainPaths, result *URLResult, baseURL string) error {
+	return processSourceMapIf(cfg, mapURL, scriptURL, paths, result, baseURL, nil)
 }
 
-// processSourceMap downloads and processes a sourcemap URL.
-func processSourceMap(cfg *Config, mapURL string, paths DomainPaths, result *URLResult, baseURL string) error {
-	mapFilename := filenameFromURL(mapURL)
+// processSourceMapIf is processSourceMap for a URL that may not hold a map
+// at all. Unless accept, given the parsed download, reports it as a map,
+// the download is discarded with no record, error, or warning, and
+// processSourceMapIf returns errNoMap.
+func processSourceMapIf(cfg *Config, mapURL, scriptURL string, paths DomainPaths, result *URLResult, baseURL string, accept func(mapPath string, sm *sourcemap.SourceMap, err error) bool) error {
+	var scriptPath string
+	if scriptURL != "" {
+		scriptPath = filepath.Join(paths.DownloadedSite, cfg.scriptFilename(scriptURL))
+	}
+	paths = cfg.hostPaths(mapURL, paths, result)
+	mapFilename := cfg.mapFilename(mapURL, scriptURL)
 	mapPath := filepath.Join(paths.DownloadedSite, mapFilename)
 
-	if cfg.Verbose {
-		fmt.Println(ui.Info(fmt.Sprintf("Downloading sourcemap: %s", mapFilename)))
+	cfg.logger().Info("Downloading sourcemap", "url", mapURL)
+
+	info, err := cfg.Client.DownloadWithInfo(mapURL, mapPath)
+	if err != nil {
+		err = fmt.Errorf("failed to download sourcemap %s: %w", mapURL, err)
+		result.Maps = append(result.Maps, cfg.failedMapRecord(mapURL, mapPath, info, err))
+		return err
+	}
+	var sm *sourcemap.SourceMap
+	parsed := false
+	if accept != nil {
+		sm, err = sourcemap.ParseFile(mapPath)
+		parsed = true
+		if !accept(mapPath, sm, err) {
+			os.Remove(mapPath)
+			return errNoMap
+		}
+	}
+	result.sums.add(mapPath, info.SHA256)
+	result.downloads.record(mapURL, mapPath, info)
+	cfg.mapLastModified(mapPath, info)
+
+	cfg.success("Downloaded sourcemap", "url", mapURL, "path", mapPath, "bytes", info.Bytes)
+	// The same map served under a new URL, e.g. with a cache-busting query
+	if cfg.Seen.seenHash(info.SHA256) {
+		result.seen(cfg, mapURL)
+		return nil
 	}
 
-	if err := cfg.Client.Download(mapURL, mapPath); err != nil {
-		return fmt.Errorf("failed to download sourcemap %s: %w", mapURL, err)
+	// Parse now, restore once every download is done
+	if !parsed {
+		sm, err = sourcemap.ParseFile(mapPath)
+	}
+	if decoy := decoyOf(mapPath, sm, err); decoy != "" {
+		record, warning := cfg.decoyMapRecord(mapURL, mapPath, info, decoy)
+		result.Maps = append(result.Maps, record)
+		cfg.addWarnings(&result.Warnings, warning)
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to parse sourcemap: %w", err)
+		result.Maps = append(result.Maps, cfg.failedMapRecord(mapURL, mapPath, info, err))
+		return err
 	}
 
-	if cfg.Verbose {
-		fmt.Println(ui.Success(fmt.Sprintf("Downloaded: %s", mapFilename)))
+	record := cfg.parsedMapRecord(sm, mapURL, mapPath)
+	cfg.addWarnings(&result.Warnings, record.issues()...)
+	scriptBundler(sm, scriptPath, &record)
+	cfg.addWarnings(&result.Warnings, cfg.verifyMap(sm, scriptPath, &record)...)
+	record.setDownload(info)
+	cfg.addWarnings(&result.Warnings, record.redirect()...)
+	result.queueRestore(record, scriptURL != "", paths.RestoredSources, baseURL, mapPath)
+	return nil
+}
+
+// restoreInlineMap restores the inline sourcemap of a script saved at
+// scriptPath. It reports whether the script's inline map was handled, either
+// now or earlier in the run.
+func restoreInlineMap(cfg *Config, jsContent, scriptPath string, paths DomainPaths, result *URLResult, processedMaps map[string]bool, baseURL string, record *ScriptRecord) (bool, error) {
+	record.HasSourceMappingURL = true
+	record.MapURL = "inline"
+
+	// Key on the script's content: the URL of a data: script is only a
+	// truncated label that many scripts share
+	sum := sha256.Sum256([]byte(jsContent))
+	inlineKey := "inline:" + hex.EncodeToString(sum[:])
+	if processedMaps[inlineKey] {
+		return true, nil
 	}
 
-	// Parse and restore
-	sm, err := sourcemap.ParseFile(mapPath)
+	sm, err := sourcemap.ExtractInlineSourceMap(jsContent)
 	if err != nil {
-		return fmt.Errorf("failed to parse sourcemap: %w", err)
+		return false, fmt.Errorf("failed to extract inline sourcemap: %w", err)
+	}
+	if sm == nil {
+		return false, nil
+	}
+	processedMaps[inlineKey] = true
+	if !result.budget.sourceMap() {
+		return true, nil
+	}
+
+	// Save the inline map for reference
+	mapPath := scriptPath + ".inline.map"
+	cfg.success("Extracted inline sourcemap", "path", mapPath)
+	restoreScriptMap(cfg, sm, mapPath, scriptPath, paths, result, baseURL)
+	return true, nil
+}
+
+// restoreEmbeddedMaps restores the sourcemaps a script embeds as JSON
+// string literals, saving each beside the script as
+// <script>.embedded-<n>.map. They are keyed by content, so a map embedded
+// in several scripts is restored once.
+func restoreEmbeddedMaps(cfg *Config, jsContent, scriptPath string, paths DomainPaths, result *URLResult, processedMaps map[string]bool, baseURL string, record *ScriptRecord) {
+	for i, em := range sourcemap.ExtractEmbeddedSourceMaps(jsContent) {
+		record.embeddedFound()
+		key := "embedded:" + em.Key
+		if processedMaps[key] {
+			continue
+		}
+		processedMaps[key] = true
+		if !result.budget.sourceMap() {
+			return
+		}
+
+		mapPath := fmt.Sprintf("%s.embedded-%d.map", scriptPath, i+1)
+		cfg.success("Extracted embedded sourcemap", "path", mapPath)
+		restoreScriptMap(cfg, em.SourceMap, mapPath, scriptPath, paths, result, baseURL)
 	}
+}
 
-	// Use options to enable real asset fetching
-	opts := &sourcemap.RestoreOptions{
-		BaseURL: baseURL,
-		Fetcher: cfg.Client,
+// restoreScriptMap saves a sourcemap taken from a script to mapPath, where
+// its restore reads it from, records it, and queues the restore of its
+// sources.
+func restoreScriptMap(cfg *Config, sm *sourcemap.SourceMap, mapPath, scriptPath string, paths DomainPaths, result *URLResult, baseURL string) {
+	mapJSON, _ := json.MarshalIndent(sm, "", "  ")
+	if err := writeDownload(mapPath, mapJSON); err != nil {
+		cfg.addErrors(&result.Errors, fmt.Errorf("failed to save %s: %w", filepath.Base(mapPath), err))
+		return
 	}
-	restoreResult := sourcemap.RestoreSourcesWithOptions(sm, paths.RestoredSources, opts)
-	result.SourcesRestored += restoreResult.RestoredCount
-	result.AssetsExtracted += restoreResult.AssetsFetched
-	result.Errors = append(result.Errors, restoreResult.Errors...)
 
+	mapRecord := cfg.parsedMapRecord(sm, "", mapPath)
+	scriptBundler(sm, scriptPath, &mapRecord)
+	cfg.addWarnings(&result.Warnings, cfg.verifyMap(sm, scriptPath, &mapRecord)...)
+	cfg.addWarnings(&result.Warnings, mapRecord.issues()...)
+	result.queueRestore(mapRecord, true, paths.RestoredSources, baseURL, scriptPath)
+}
+
+// processEmbeddedScript saves a script captured from a blob: or data: URL
+// as blob_<n>.js or datauri_<n>.js and restores its inline sourcemap, if
+// any. An external sourceMappingURL is followed only when it is absolute,
+// since neither kind of URL can serve as a base for a relative one.
+func processEmbeddedScript(cfg *Config, script fetch.EmbeddedScript, n int, paths DomainPaths, result *URLResult, processedMaps map[string]bool, baseURL string) error {
+	prefix := "blob"
+	if script.Kind == fetch.EmbeddedDataURI {
+		prefix = "datauri"
+	}
+	filename := fmt.Sprintf("%s_%d.js", prefix, n)
+	scriptPath := filepath.Join(paths.DownloadedSite, filename)
+
+	record := cfg.newScriptRecord(script.URL, fetch.DownloadInfo{Bytes: int64(len(script.Content))})
+	record.File = filename
+	defer func() {
+		result.Scripts = append(result.Scripts, record)
+	}()
+
+	if err := writeDownload(scriptPath, []byte(script.Content)); err != nil {
+		err = fmt.Errorf("failed to save %s: %w", filename, err)
+		record.Error = err.Error()
+		return err
+	}
+	cfg.success("Captured script", "kind", script.Kind, "path", filename)
+	record.findMapSource(script.Content, "")
+
+	if sourcemap.HasInlineSourceMap(script.Content) {
+		found, err := restoreInlineMap(cfg, script.Content, scriptPath, paths, result, processedMaps, baseURL, &record)
+		if err != nil {
+			cfg.addErrors(&result.Errors, err)
+		} else if found {
+			return nil
+		}
+	}
+	if sourcemap.HasEmbeddedSourceMap(script.Content) {
+		restoreEmbeddedMaps(cfg, script.Content, scriptPath, paths, result, processedMaps, baseURL, &record)
+	}
+
+	for _, mapURL := range sourcemap.ExtractSourceMappingURLs(script.Content) {
+		record.HasSourceMappingURL = true
+		// Relative references can't be resolved against a blob: or data: URL
+		if !strings.HasPrefix(mapURL, "http://") && !strings.HasPrefix(mapURL, "https://") {
+			if record.MapURL == "" {
+				record.MapURL = mapURL
+			}
+			continue
+		}
+		if err := processMapReference(cfg, mapURL, mapURL, paths, result, processedMaps, baseURL, &record); err != nil {
+			cfg.addFetchErrors(result, err)
+		}
+	}
 	return nil
 }
 
 // processScriptForMaps downloads a script and checks for inline/external sourcemaps
 // that weren't caught by network interception.
 func processScriptForMaps(cfg *Config, scriptURL string, paths DomainPaths, result *URLResult, processedMaps map[string]bool, baseURL string) error {
-	filename := filenameFromURL(scriptURL)
+	paths = cfg.hostPaths(scriptURL, paths, result)
+	filename := cfg.scriptFilename(scriptURL)
 	scriptPath := filepath.Join(paths.DownloadedSite, filename)
 
-	// Download the script
-	if err := cfg.Client.Download(scriptURL, scriptPath); err != nil {
-		return fmt.Errorf("failed to download %s: %w", scriptURL, err)
+	// Download the script, unless it is unchanged since an earlier run
+	info, reused, err := result.downloads.download(cfg.Client, scriptURL, scriptPath)
+	record := cfg.newScriptRecord(scriptURL, info)
+	record.File = filename
+	record.Reused = reused
+	defer func() {
+		result.Scripts = append(result.Scripts, record)
+	}()
+	if err != nil {
+		err = fmt.Errorf("failed to download %s: %w", scriptURL, err)
+		record.Error = err.Error()
+		return err
+	}
+	if reused {
+		result.ScriptsReused++
 	}
+	result.sums.add(scriptPath, info.SHA256)
+	cfg.logger().Debug("Downloaded script",
+		"url", scriptURL, "path", scriptPath, "status", info.StatusCode, "bytes", info.Bytes, "reused", reused)
 
 	// Read script content
 	content, err := os.ReadFile(scriptPath)
 	if err != nil {
-		return fmt.Errorf("failed to read downloaded script: %w", err)
+		err = fmt.Errorf("failed to read downloaded script: %w", err)
+		record.Error = err.Error()
+		return err
 	}
 
 	jsContent := string(content)
+	record.findMapSource(jsContent, info.SourceMap)
 
-	// Check for inline sourcemap first
+	// Check for inline sourcemap first. A broken one is recorded and the
+	// external candidates are still tried.
 	if sourcemap.HasInlineSourceMap(jsContent) {
-		// Use script URL as unique key for inline maps
-		inlineKey := scriptURL + ":inline"
-		if processedMaps[inlineKey] {
-			return nil
-		}
-
-		sm, err := sourcemap.ExtractInlineSourceMap(jsContent)
+		found, err := restoreInlineMap(cfg, jsContent, scriptPath, paths, result, processedMaps, baseURL, &record)
 		if err != nil {
-			return fmt.Errorf("failed to extract inline sourcemap: %w", err)
+			cfg.addErrors(&result.Errors, err)
+		} else if found {
+			return nil
 		}
-		if sm != nil {
-			processedMaps[inlineKey] = true
-
-			// Save the inline map for reference
-			mapPath := scriptPath + ".inline.map"
-			mapJSON, _ := json.MarshalIndent(sm, "", "  ")
-			os.WriteFile(mapPath, mapJSON, 0644)
-
-			if cfg.Verbose {
-				fmt.Println(ui.Success(fmt.Sprintf("Extracted inline sourcemap: %s", filepath.Base(mapPath))))
-			}
+	}
+	if sourcemap.HasEmbeddedSourceMap(jsContent) {
+		restoreEmbeddedMaps(cfg, jsContent, scriptPath, paths, result, processedMaps, baseURL, &record)
+	}
 
-			// Use options to enable real asset fetching
-			opts := &sourcemap.RestoreOptions{
-				BaseURL: baseURL,
-				Fetcher: cfg.Client,
-			}
-			restoreResult := sourcemap.RestoreSourcesWithOptions(sm, paths.RestoredSources, opts)
-			result.SourcesRestored += restoreResult.RestoredCount
-			result.AssetsExtracted += restoreResult.AssetsFetched
-			result.Errors = append(result.Errors, restoreResult.Errors...)
-			return nil
+	// Look for external sourcemaps that weren't caught by network
+	// interception: the SourceMap header and every sourceMappingURL comment.
+	// Each is processed on its own, so one failing doesn't lose the others.
+	mapURLs := sourcemap.ExtractSourceMappingURLs(jsContent)
+	if len(mapURLs) > 0 {
+		record.HasSourceMappingURL = true
+	}
+	if info.SourceMap != "" {
+		mapURLs = append([]string{info.SourceMap}, mapURLs...)
+	}
+	for _, mapURL := range mapURLs {
+		if err := processMapReference(cfg, scriptURL, mapURL, paths, result, processedMaps, baseURL, &record); err != nil {
+			cfg.addFetchErrors(result, err)
 		}
 	}
 
-	// Look for external sourcemap URL that wasn't caught by network interception
-	mapURL := sourcemap.ExtractSourceMappingURL(jsContent)
-	if mapURL == "" {
-		return nil
+	// A script that names no map may still have one where the CDN keeps them
+	if len(mapURLs) == 0 && record.MapURL == "" && cfg.MapURLTemplate != "" {
+		if err := probeTemplatedMap(cfg, scriptURL, paths, result, processedMaps, baseURL, &record); err != nil {
+			cfg.addFetchErrors(result, err)
+		}
 	}
+	return nil
+}
 
-	// Resolve relative map URL
+// processMapReference resolves a sourcemap URL referenced by a script and
+// processes it, unless it was already processed or is skipped by filters,
+// robots.txt, or the budget.
+func processMapReference(cfg *Config, scriptURL, mapURL string, paths DomainPaths, result *URLResult, processedMaps map[string]bool, baseURL string, record *ScriptRecord) error {
 	resolvedMapURL, err := resolveURL(scriptURL, mapURL)
 	if err != nil {
-		return fmt.Errorf("failed to resolve map URL: %w", err)
+		return fmt.Errorf("failed to resolve map URL %s: %w", mapURL, err)
+	}
+	if record.MapURL == "" {
+		record.MapURL = resolvedMapURL
 	}
 
 	// Skip if already processed
@@ -271,15 +730,173 @@ func processScriptForMaps(cfg *Config, scriptURL string, paths DomainPaths, resu
 		return nil
 	}
 	processedMaps[resolvedMapURL] = true
+	found := DiscoveredURL{URL: resolvedMapURL, Kind: "map", Source: SourceReference, Referrer: record.URL, Target: result.URL}
+	defer func() {
+		result.URLs = append(result.URLs, found)
+	}()
 
-	if cfg.Verbose {
-		fmt.Println(ui.Info(fmt.Sprintf("Found additional sourcemap: %s", resolvedMapURL)))
+	if found.Skipped = cfg.skipMap(resolvedMapURL, found.Source, result); found.Skipped != "" {
+		return nil
 	}
+	if !result.budget.sourceMap() {
+		return nil
+	}
+
+	cfg.logger().Info("Found additional sourcemap", "url", resolvedMapURL)
 
 	// Process this map
-	if err := processSourceMap(cfg, resolvedMapURL, paths, result, baseURL); err != nil {
-		return err
+	return processSourceMap(cfg, resolvedMapURL, scriptURL, paths, result, baseURL)
+}
+
+// skipMap returns why a map named by a script, or found at
+// -map-url-template, is left out of processing, counting and logging it,
+// or "" if it is processed. source is how the map was found; a template
+// names its host explicitly, so templated maps are not held to -scope.
+func (c *Config) skipMap(mapURL, source string, result *URLResult) string {
+	switch {
+	case !c.ScopeList.AllowURL(mapURL):
+		result.OutOfScope++
+		c.addWarnings(&result.Warnings, scopeFileWarning(mapURL))
+		c.logger().Info("Sourcemap not in scope file", "url", mapURL)
+		return skipScopeFile
+	case source != SourceTemplate && !filter.InScope(c.Scope, result.target(), mapURL, c.AllowHosts):
+		result.OutOfScope++
+		c.logger().Info("Sourcemap out of scope", "url", mapURL)
+		return skipScope
+	case !c.Filter.AllowURL(mapURL):
+		result.MapsFiltered++
+		c.addWarnings(&result.Warnings, filteredWarning(mapURL))
+		c.logger().Info("Skipping filtered sourcemap", "url", mapURL)
+		return skipFiltered
+	case !result.robots.allowed(mapURL):
+		result.RobotsDisallowed++
+		c.logger().Info("Disallowed by robots.txt", "url", mapURL)
+		return skipRobots
+	case c.Seen.seenURL(mapURL):
+		result.seen(c, mapURL)
+		return skipSeen
 	}
+	return ""
+}
 
-	return nil
+// target returns the page a url run is against: the page the target
+// redirected to, if it did.
+func (r *URLResult) target() *url.URL {
+	requested, err := url.Parse(r.URL)
+	if err != nil {
+		requested = &url.URL{}
+	}
+	return finalTarget(requested, r.FinalURL)
+}
+
+// stopOnDisk finishes a url run stopped by a full-disk or permission
+// error after its downloads, with the counts of what was written.
+func stopOnDisk(cfg *Config, result *URLResult) *URLResult {
+	result.MapsDiscovered = CountMaps(result.Maps)
+	_, result.SourcesRestored = restoredFrom(result.Maps)
+	cfg.emit("run_complete", map[string]interface{}{
+		"output_dir": result.OutputDir,
+		"final_url":  result.FinalURL,
+		"scripts":    result.ScriptsFound,
+		"maps":       result.MapsDiscovered,
+		"sources":    result.SourcesRestored,
+		"errors":     len(result.Errors),
+		"warnings":   len(result.Warnings),
+	})
+	return result
+}
+
+// seen counts a map skipped because an earlier run processed it.
+func (r *URLResult) seen(cfg *Config, mapURL string) {
+	r.MapsSeen++
+	cfg.logger().Info("Skipping previously seen sourcemap", "url", mapURL)
+}
+
+// filteredWarning records a script or map skipped by -include-url or
+// -exclude-url.
+func filteredWarning(u string) warn.Warning {
+	return warn.New(warn.Filtered, u, "skipped by -include-url/-exclude-url")
+}
+
+// scopeFileWarning records a URL not fetched because -scope-file doesn't
+// list its host.
+func scopeFileWarning(u string) warn.Warning {
+	return warn.New(warn.OutOfScope, u, "host not in -scope-file")
+}
+
+// addFetchErrors adds errs to the result's errors, except refusals by the
+// -scope-file allowlist, which are counted as out of scope and recorded as
+// warnings instead.
+func (c *Config) addFetchErrors(result *URLResult, errs ...error) {
+	for _, err := range errs {
+		var refused *fetch.OutOfScopeError
+		if errors.As(err, &refused) {
+			result.OutOfScope++
+			c.addWarnings(&result.Warnings, warn.New(warn.OutOfScope, refused.URL, "request or redirect refused: host not in -scope-file"))
+			continue
+		}
+		c.addErrors(&result.Errors, err)
+	}
+}
+
+// outOfScopeFile is written to the domain directory of a -scope-file run
+// and lists the URLs the scope file kept the run from fetching.
+const outOfScopeFile = "out_of_scope.json"
+
+// writeOutOfScope writes outOfScopeFile, for auditing what a scoped run
+// left alone.
+func (c *Config) writeOutOfScope(paths DomainPaths, result *URLResult) {
+	if c.ScopeList == nil {
+		return
+	}
+	seen := make(map[string]bool)
+	entries := []schema.OutOfScope{}
+	for _, w := range result.Warnings {
+		if w.Category != warn.OutOfScope || seen[w.Subject] {
+			continue
+		}
+		seen[w.Subject] = true
+		entries = append(entries, schema.OutOfScope{URL: w.Subject, Reason: w.Message})
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to encode %s: %w", outOfScopeFile, err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(paths.Base, outOfScopeFile), append(data, '\n'), 0644); err != nil {
+		c.addErrors(&result.Errors, fmt.Errorf("failed to write %s: %w", outOfScopeFile, err))
+	}
+}
+
+// selectURLs returns the URLs that are within the configured scope of target
+// and -scope-file, not on the denylist, and allowed by the configured
+// filter, counting those skipped by each. URLs skipped by -scope-file or the
+// filter are also added to warnings, unless it is nil.
+func (c *Config) selectURLs(target *url.URL, urls []string, outOfScope, thirdParty, filtered *int, warnings *[]warn.Warning) []string {
+	selected := make([]string, 0, len(urls))
+	for _, u := range urls {
+		switch c.skipReason(target, u) {
+		case skipScopeFile:
+			*outOfScope++
+			if warnings != nil {
+				c.addWarnings(warnings, scopeFileWarning(u))
+			}
+			c.logger().Info("Not in scope file", "url", u)
+		case skipScope:
+			*outOfScope++
+			c.logger().Info("Out of scope", "url", u)
+		case skipThirdParty:
+			*thirdParty++
+			c.logger().Info("Skipped known third-party script", "url", u)
+		case skipFiltered:
+			*filtered++
+			if warnings != nil {
+				c.addWarnings(warnings, filteredWarning(u))
+			}
+		default:
+			selected = append(selected, u)
+		}
+	}
+	return selected
 }
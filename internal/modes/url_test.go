@@ -0,0 +1,37 @@
+package modes
+
+import (
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/filter"
+)
+
+func TestSkipMapScope(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AllowHosts = []string{"maps.vendor.net"}
+	tests := []struct {
+		name, mapURL, source, want string
+	}{
+		{"same origin", "https://app.example.com/js/a.js.map", SourceReference, ""},
+		{"other host", "https://maps.other.net/a.js.map", SourceReference, skipScope},
+		{"redirected origin", "https://example.com/a.js.map", SourceReference, skipScope},
+		{"allowed host", "https://maps.vendor.net/a.js.map", SourceReference, ""},
+		{"template host", "https://maps.other.net/a.js.map", SourceTemplate, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &URLResult{URL: "https://example.com/", FinalURL: "https://app.example.com/"}
+			if got := cfg.skipMap(tt.mapURL, tt.source, result); got != tt.want {
+				t.Errorf("skipMap(%q) = %q, want %q", tt.mapURL, got, tt.want)
+			}
+			if want := tt.want != ""; (result.OutOfScope == 1) != want {
+				t.Errorf("OutOfScope = %d", result.OutOfScope)
+			}
+		})
+	}
+
+	cfg.Scope = filter.ScopeAll
+	if got := cfg.skipMap("https://maps.other.net/a.js.map", SourceReference, &URLResult{URL: "https://example.com/"}); got != "" {
+		t.Errorf("with -scope all skipMap = %q, want processed", got)
+	}
+}
@@ -0,0 +1,69 @@
+package modes
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/thesavant42/dejank/internal/sourcemap"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// VerifyMapResult is the outcome of checking a sourcemap against the
+// bundle it is for.
+type VerifyMapResult struct {
+	Bundle string
+	Map    string
+
+	sourcemap.Consistency
+}
+
+// RunVerifyMap checks a sample of the mappings of the map at mapPath
+// against the bundle at bundlePath, to catch maps served for a different
+// build than the bundle. A sample of 0 uses sourcemap.DefaultVerifySample.
+func RunVerifyMap(bundlePath, mapPath string, sample int) (*VerifyMapResult, error) {
+	bundle, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read bundle: %w", err)
+	}
+	sm, err := sourcemap.ParseFile(mapPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", mapPath, err)
+	}
+	consistency, err := sourcemap.Verify(sm, string(bundle), sample)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyMapResult{Bundle: bundlePath, Map: mapPath, Consistency: consistency}, nil
+}
+
+// verifyMap checks sm against the script saved at scriptPath with -verify,
+// recording the share of consistent mappings in record. It returns a
+// warning if the map doesn't belong to the script. A script that can't be
+// read, such as one never downloaded, is not checked.
+func (c *Config) verifyMap(sm *sourcemap.SourceMap, scriptPath string, record *MapRecord) []warn.Warning {
+	if !c.VerifyMaps || scriptPath == "" {
+		return nil
+	}
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil
+	}
+	subject := mapIdentifier(record.URL, record.File)
+	consistency, err := sourcemap.Verify(sm, string(script), 0)
+	if err != nil {
+		return []warn.Warning{warn.FromError(warn.MapMismatch, subject, err)}
+	}
+	if consistency.Checked == 0 {
+		return nil
+	}
+
+	percent := math.Round(consistency.Percent()*10) / 10
+	record.Consistency = &percent
+	if consistency.Verdict() != sourcemap.Mismatched {
+		return nil
+	}
+	return []warn.Warning{warn.New(warn.MapMismatch, subject,
+		"only %.1f%% of %d sampled mappings agree with the script (%d past the end of a line); the map is likely for a different build",
+		percent, consistency.Checked, consistency.OutOfRange)}
+}
@@ -0,0 +1,81 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// versionTimeFormat is RFC 3339 with filesystem-safe separators and
+	// milliseconds, fixed width so that names sort chronologically.
+	versionTimeFormat = "2006-01-02T15-04-05.000Z"
+
+	latestLink    = "latest"
+	latestPointer = "latest.txt"
+)
+
+// newVersionName returns the directory name for a new versioned run in
+// domainDir. A run started in the same millisecond as an existing one gets
+// a -2, -3, ... suffix.
+func newVersionName(domainDir string) string {
+	name := time.Now().UTC().Format(versionTimeFormat)
+	unique := name
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(filepath.Join(domainDir, unique)); os.IsNotExist(err) {
+			return unique
+		}
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+}
+
+// updateLatest points <domain>/latest at the given run directory. A symlink is
+// used where supported; on Windows, or if symlinking fails, latest.txt holds
+// the run directory name instead.
+func updateLatest(runDir string) error {
+	domainDir := filepath.Dir(runDir)
+	name := filepath.Base(runDir)
+
+	if runtime.GOOS != "windows" {
+		link := filepath.Join(domainDir, latestLink)
+		tmp := link + ".tmp"
+		os.Remove(tmp)
+		if err := os.Symlink(name, tmp); err == nil {
+			if err := os.Rename(tmp, link); err == nil {
+				return nil
+			}
+			os.Remove(tmp)
+		}
+	}
+
+	pointer := filepath.Join(domainDir, latestPointer)
+	if err := os.WriteFile(pointer, []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to update latest pointer: %w", err)
+	}
+	return nil
+}
+
+// resolveRunDir returns the directory holding the standard layout for a
+// domain. Flat layouts are returned unchanged; versioned layouts resolve
+// through the latest symlink or latest.txt pointer.
+func resolveRunDir(domainDir string) string {
+	if _, err := os.Stat(filepath.Join(domainDir, "downloaded_site")); err == nil {
+		return domainDir
+	}
+
+	if _, err := os.Stat(filepath.Join(domainDir, latestLink, "downloaded_site")); err == nil {
+		return filepath.Join(domainDir, latestLink)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(domainDir, latestPointer)); err == nil {
+		name := strings.TrimSpace(string(data))
+		if name != "" && filepath.Base(name) == name {
+			return filepath.Join(domainDir, name)
+		}
+	}
+
+	return domainDir
+}
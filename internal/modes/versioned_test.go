@@ -0,0 +1,41 @@
+package modes
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestVersionedRunsSameMillisecond(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputRoot = t.TempDir()
+	cfg.Versioned = true
+
+	var names []string
+	for i := 0; i < 5; i++ {
+		paths := cfg.domainPaths("example.com")
+		release, err := cfg.prepareOutput(paths)
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		release()
+		names = append(names, filepath.Base(paths.Base))
+	}
+
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("run names don't sort in creation order: %v", names)
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i] == names[i-1] {
+			t.Fatalf("two runs share the directory %s", names[i])
+		}
+	}
+	domainDir := GetDomainPaths(cfg.OutputRoot, "example.com").Base
+	latest, err := filepath.EvalSymlinks(resolveRunDir(domainDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filepath.Base(latest); got != names[len(names)-1] {
+		t.Errorf("latest run = %s, want %s", got, names[len(names)-1])
+	}
+}
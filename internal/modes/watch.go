@@ -0,0 +1,311 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/envars"
+	"github.com/thesavant42/dejank/internal/fetch"
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+// runTimeFormat names run directories; it sorts chronologically and is safe on all filesystems.
+const runTimeFormat = "20060102T150405Z"
+
+// WatchOptions configures continuous monitoring of a target.
+type WatchOptions struct {
+	Interval  time.Duration   // Time between the start of consecutive runs
+	Keep      int             // Number of historical runs kept on disk (0 = unlimited)
+	NotifyURL string          // Optional webhook that receives non-empty deltas as JSON
+	OnRun     func(*WatchRun) // Optional callback invoked after every run
+}
+
+// WatchRun is the outcome of a single scheduled run.
+type WatchRun struct {
+	Started time.Time
+	Dir     string
+	Result  *URLResult
+	Delta   *RunDelta
+	Err     error
+}
+
+// RunDelta describes what a run found that the previous run did not.
+type RunDelta struct {
+	Target     string            `json:"target"`
+	Run        string            `json:"run"`
+	Previous   string            `json:"previous,omitempty"`
+	NewScripts []string          `json:"new_scripts"`
+	NewMaps    []string          `json:"new_maps"`
+	NewEnvVars map[string]string `json:"new_env_vars"`
+	NewSecrets []schema.Secret   `json:"new_secrets"`
+}
+
+// Empty reports whether the delta contains no changes.
+func (d *RunDelta) Empty() bool {
+	return len(d.NewScripts) == 0 && len(d.NewMaps) == 0 && len(d.NewEnvVars) == 0 && len(d.NewSecrets) == 0
+}
+
+// RunWatch re-runs the url pipeline every opts.Interval until ctx is cancelled.
+// Each run is written to <domain>-dejank/runs/<timestamp>/ and diffed against
+// the previous successful run. Failed runs are reported and discarded.
+func RunWatch(ctx context.Context, cfg *Config, targetURL string, opts WatchOptions) error {
+	if opts.Interval <= 0 {
+		return fmt.Errorf("watch interval must be positive")
+	}
+
+	parsed, err := url.Parse(targetURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid URL: %s", targetURL)
+	}
+
+	// Runs go under the domain the target redirects to, which is only known
+	// once the first run has loaded the page
+	runsDir := filepath.Join(GetDomainPaths(cfg.OutputRoot, parsed.Host).Base, "runs")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		// Runs start every interval, however long each one takes
+		timer.Reset(opts.Interval)
+
+		run := watchOnce(ctx, cfg, targetURL, runsDir, opts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if opts.OnRun != nil {
+			opts.OnRun(run)
+		}
+
+		if run.Err == nil {
+			runsDir = filepath.Dir(run.Dir)
+			pruneRuns(runsDir, opts.Keep)
+		}
+	}
+}
+
+// watchOnce performs a single run and computes its delta against the previous run.
+// Cancelling ctx stops the run's downloads and browser, and discards the run.
+func watchOnce(ctx context.Context, cfg *Config, targetURL, runsDir string, opts WatchOptions) *WatchRun {
+	started := time.Now().UTC()
+	name := started.Format(runTimeFormat)
+	run := &WatchRun{Started: started, Dir: filepath.Join(runsDir, name)}
+
+	previous := latestRun(runsDir)
+
+	runCfg := *cfg
+	runCfg.RunDir = filepath.Join("runs", name)
+	runCfg.Force = true
+	runCfg.Context = ctx
+	runCfg.Client = cfg.Client.WithContext(ctx)
+	if previous != "" {
+		runCfg.ReuseDir = filepath.Join(runsDir, previous)
+	}
+
+	result, err := runURLSafely(&runCfg, targetURL)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+		os.RemoveAll(result.OutputDir)
+	}
+	if err != nil {
+		os.RemoveAll(run.Dir)
+		run.Err = err
+		return run
+	}
+	run.Result = result
+
+	// A redirect to another host moves the run into that host's runs
+	if dir := filepath.Dir(result.OutputDir); dir != runsDir {
+		runsDir = dir
+		previous = previousRun(runsDir, name)
+	}
+	run.Dir = result.OutputDir
+
+	delta := diffRuns(filepath.Join(runsDir, previous), run.Dir)
+	delta.Target = targetURL
+	delta.Run = name
+	delta.Previous = previous
+	run.Delta = delta
+
+	if previous != "" && !delta.Empty() && opts.NotifyURL != "" {
+		if err := cfg.Client.PostJSON(opts.NotifyURL, delta); err != nil {
+			cfg.addErrors(&result.Errors, fmt.Errorf("failed to send notification: %w", err))
+		}
+	}
+
+	return run
+}
+
+// runURLSafely runs the url pipeline, converting panics into errors so a
+// single bad run cannot take down a long-lived watch process.
+func runURLSafely(cfg *Config, targetURL string) (result *URLResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("run panicked: %v", r)
+		}
+	}()
+	return RunURL(cfg, targetURL)
+}
+
+// listRuns returns the run directory names in chronological order.
+func listRuns(runsDir string) []string {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		return nil
+	}
+
+	var runs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(runTimeFormat, entry.Name()); err == nil {
+			runs = append(runs, entry.Name())
+		}
+	}
+	sort.Strings(runs)
+	return runs
+}
+
+// latestRun returns the most recent run directory name, or "" if there is none.
+func latestRun(runsDir string) string {
+	runs := listRuns(runsDir)
+	if len(runs) == 0 {
+		return ""
+	}
+	return runs[len(runs)-1]
+}
+
+// previousRun returns the most recent run directory name before name, or ""
+// if there is none.
+func previousRun(runsDir, name string) string {
+	previous := ""
+	for _, run := range listRuns(runsDir) {
+		if run < name {
+			previous = run
+		}
+	}
+	return previous
+}
+
+// pruneRuns removes the oldest runs so that at most keep remain.
+func pruneRuns(runsDir string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	runs := listRuns(runsDir)
+	for len(runs) > keep {
+		os.RemoveAll(filepath.Join(runsDir, runs[0]))
+		runs = runs[1:]
+	}
+}
+
+// diffRuns compares two run directories and returns what is new in current.
+// An empty or missing previous directory yields everything in current.
+func diffRuns(previousDir, currentDir string) *RunDelta {
+	prev := pathsAt(previousDir)
+	cur := pathsAt(currentDir)
+
+	prevScripts, prevMaps := listDownloads(prev.DownloadedSite)
+	curScripts, curMaps := listDownloads(cur.DownloadedSite)
+
+	prevEnv, _ := envars.ReadEnvFile(filepath.Join(prev.RestoredSources, ".env"))
+	curEnv, _ := envars.ReadEnvFile(filepath.Join(cur.RestoredSources, ".env"))
+
+	delta := &RunDelta{
+		NewScripts: newEntries(prevScripts, curScripts),
+		NewMaps:    newEntries(prevMaps, curMaps),
+		NewEnvVars: make(map[string]string),
+		NewSecrets: newSecrets(readSecrets(prev.Base), readSecrets(cur.Base)),
+	}
+
+	for key, value := range curEnv {
+		if old, ok := prevEnv[key]; !ok || old != value {
+			delta.NewEnvVars[key] = value
+		}
+	}
+
+	return delta
+}
+
+// listDownloads returns the script and sourcemap paths in a download
+// directory, relative to it, including those in subdirectories.
+func listDownloads(dir string) (scripts, maps map[string]bool) {
+	scripts = make(map[string]bool)
+	maps = make(map[string]bool)
+
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		name := filepath.ToSlash(rel)
+		switch {
+		case strings.HasSuffix(name, ".map"):
+			maps[name] = true
+		case fetch.IsScriptPath(name):
+			scripts[name] = true
+		}
+		return nil
+	})
+	return scripts, maps
+}
+
+// newEntries returns the sorted keys of current that are absent from previous.
+func newEntries(previous, current map[string]bool) []string {
+	added := []string{}
+	for name := range current {
+		if !previous[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	return added
+}
+
+// readSecrets returns the findings in a run's secrets.json, or none if it
+// has no secrets.json.
+func readSecrets(dir string) []schema.Secret {
+	data, err := os.ReadFile(filepath.Join(dir, secretsFile))
+	if err != nil {
+		return nil
+	}
+	secrets, _ := schema.DecodeList[schema.Secret](data, secretsFile)
+	return secrets
+}
+
+// newSecrets returns the secrets in current that previous has nowhere.
+// A secret is the same one wherever it is found, so one that moved to
+// another file or line, as when a bundle is rebuilt, is not new.
+func newSecrets(previous, current []schema.Secret) []schema.Secret {
+	key := func(s schema.Secret) string {
+		return s.Rule + "\x00" + s.Kind + "\x00" + s.Message + "\x00" + fmt.Sprint(s.Properties)
+	}
+	known := make(map[string]bool, len(previous))
+	for _, s := range previous {
+		known[key(s)] = true
+	}
+	added := []schema.Secret{}
+	for _, s := range current {
+		if !known[key(s)] {
+			known[key(s)] = true // Report each secret once, however many files hold it
+			added = append(added, s)
+		}
+	}
+	return added
+}
@@ -0,0 +1,88 @@
+package modes
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/findings"
+	"github.com/thesavant42/dejank/internal/schema"
+)
+
+func TestDiffRunsNested(t *testing.T) {
+	root := t.TempDir()
+	write := func(run, name, content string) {
+		path := filepath.Join(pathsAt(filepath.Join(root, run)).DownloadedSite, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "main.js", "")
+	write("a", "static/js/app.js", "")
+	write("a", "static/js/app.js.map", "")
+	write("b", "main.js", "")
+	write("b", "static/js/app.js", "")
+	write("b", "static/js/app.js.map", "")
+	write("b", "static/js/chunk.js", "")
+	write("b", "cdn.example.com/lib.js.map", "")
+	write("b", "static/css/site.css", "")
+
+	delta := diffRuns(filepath.Join(root, "a"), filepath.Join(root, "b"))
+	if want := []string{"static/js/chunk.js"}; !reflect.DeepEqual(delta.NewScripts, want) {
+		t.Errorf("new scripts = %v, want %v", delta.NewScripts, want)
+	}
+	if want := []string{"cdn.example.com/lib.js.map"}; !reflect.DeepEqual(delta.NewMaps, want) {
+		t.Errorf("new maps = %v, want %v", delta.NewMaps, want)
+	}
+}
+
+func TestDiffRunsSecrets(t *testing.T) {
+	root := t.TempDir()
+	write := func(run string, secrets []schema.Secret) {
+		dir := filepath.Join(root, run)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(secrets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, secretsFile), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aws := schema.Secret{Rule: findings.RuleSecret, Kind: "AWS access key", Message: "Possible AWS access key: AKIA************MPLE", File: "downloaded_site/main.js", Line: 3}
+	moved := aws
+	moved.File, moved.Line = "downloaded_site/main.4f2a.js", 9
+	slack := schema.Secret{Rule: findings.RuleSecret, Kind: "Slack token", Message: "Possible Slack token: xoxb************abcd", File: "downloaded_site/chat.js"}
+	write("a", []schema.Secret{aws})
+	write("b", []schema.Secret{moved, slack, slack})
+
+	delta := diffRuns(filepath.Join(root, "a"), filepath.Join(root, "b"))
+	if want := []schema.Secret{slack}; !reflect.DeepEqual(delta.NewSecrets, want) {
+		t.Errorf("new secrets = %+v, want %+v", delta.NewSecrets, want)
+	}
+	if delta.Empty() {
+		t.Error("a delta with a new secret is Empty")
+	}
+	// The webhook payload carries them
+	payload, err := json.Marshal(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(payload), `"new_secrets":[{"rule":"`+findings.RuleSecret+`","kind":"Slack token"`) {
+		t.Errorf("payload = %s, want the new secret", payload)
+	}
+
+	// A secret that was already there is no change
+	delta = diffRuns(filepath.Join(root, "b"), filepath.Join(root, "a"))
+	if len(delta.NewSecrets) != 0 || !delta.Empty() {
+		t.Errorf("new secrets = %+v going back to a run with fewer, want none", delta.NewSecrets)
+	}
+}
@@ -0,0 +1,237 @@
+// Package robots parses robots.txt files and matches URL paths against
+// their rules, following RFC 9309.
+package robots
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// UserAgent is the product token matched against user-agent lines.
+const UserAgent = "dejank"
+
+// maxSize is how much of a robots.txt is parsed; RFC 9309 requires
+// crawlers to read at least 500 KiB.
+const maxSize = 500 * 1024
+
+// rule is a single Allow or Disallow line.
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// Rules are the robots.txt rules that apply to UserAgent.
+type Rules struct {
+	rules []rule
+}
+
+// AllowAll returns rules that allow every path, as for a missing robots.txt.
+func AllowAll() *Rules {
+	return &Rules{}
+}
+
+// DisallowAll returns rules that disallow every path, as for a robots.txt
+// that could not be fetched because of a server error.
+func DisallowAll() *Rules {
+	return &Rules{rules: []rule{{pattern: "/"}}}
+}
+
+// Parse reads a robots.txt and keeps the rules of the groups naming
+// UserAgent, or of the "*" groups if none does. Groups naming the same
+// agent are merged. Lines other than user-agent, allow, and disallow are
+// ignored.
+func Parse(content string) *Rules {
+	if len(content) > maxSize {
+		content = content[:maxSize]
+	}
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	var specific, wildcard []rule
+	var agents []string
+	named := false   // Some group names UserAgent, even if it has no rules
+	inRules := false // The current group has seen a rule, so the next user-agent starts a new group
+	for _, line := range strings.FieldsFunc(content, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+			named = named || matchesAgent(strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// An empty Disallow allows everything; an empty Allow means nothing
+				continue
+			}
+			r := rule{pattern: normalize(value), allow: key == "allow"}
+			for _, agent := range agents {
+				switch {
+				case matchesAgent(agent):
+					specific = append(specific, r)
+				case agent == "*":
+					wildcard = append(wildcard, r)
+				}
+			}
+		}
+	}
+
+	if named {
+		return &Rules{rules: specific}
+	}
+	return &Rules{rules: wildcard}
+}
+
+// Sitemaps returns the sitemap URLs a robots.txt lists on Sitemap lines,
+// in order. Sitemap lines belong to no group, so every one is returned.
+func Sitemaps(content string) []string {
+	if len(content) > maxSize {
+		content = content[:maxSize]
+	}
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	var urls []string
+	for _, line := range strings.FieldsFunc(content, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			urls = append(urls, value)
+		}
+	}
+	return urls
+}
+
+// matchesAgent reports whether a user-agent line names UserAgent. Lines
+// may carry a version, e.g. "dejank/1.0".
+func matchesAgent(agent string) bool {
+	token, _, _ := strings.Cut(agent, "/")
+	return strings.TrimSpace(token) == UserAgent
+}
+
+// Allowed reports whether a URL may be fetched. The longest matching rule
+// wins; when an Allow and a Disallow rule match with the same length, the
+// Allow rule wins. /robots.txt itself is always allowed.
+func (r *Rules) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	path = normalize(path)
+	if path == "/robots.txt" {
+		return true
+	}
+
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !match(rule.pattern, path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > best || (n == best && rule.allow) {
+			best, allowed = n, rule.allow
+		}
+	}
+	return allowed
+}
+
+// match reports whether path matches a robots.txt pattern. Patterns match
+// path prefixes; "*" matches any sequence of characters and a trailing "$"
+// anchors the pattern at the end of the path.
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+
+	// The first part must be a prefix, each later part must follow in
+	// order; taking the earliest match of each leaves the most room for
+	// the rest
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			// The last part must end the path; take its latest occurrence
+			return len(path)-len(part) >= pos && strings.HasSuffix(path, part)
+		}
+		j := strings.Index(path[pos:], part)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(part)
+	}
+	return !anchored || pos == len(path)
+}
+
+// normalize puts percent-encoded octets in a consistent form so that
+// "/a%2fb" and "/a%2Fb" compare equal: unreserved characters are decoded,
+// other escapes are upper-cased, and non-ASCII bytes are encoded.
+func normalize(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			c := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('%')
+				b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			}
+			i += 2
+			continue
+		}
+		if s[i] >= 0x80 {
+			// Raw UTF-8 in a rule matches its encoded form in a URL
+			fmt.Fprintf(&b, "%%%02X", s[i])
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	}
+	return c - 'a' + 10
+}
+
+// isUnreserved reports whether c may appear in a URL path unencoded
+// (RFC 3986 unreserved characters).
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,79 @@
+package robots
+
+import "testing"
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		url     string
+		want    bool
+	}{
+		{"no rules", "", "https://example.com/app.js", true},
+		{"prefix disallow", "User-agent: *\nDisallow: /static/", "https://example.com/static/app.js", false},
+		{"prefix other path", "User-agent: *\nDisallow: /static/", "https://example.com/assets/app.js", true},
+		{"empty disallow allows all", "User-agent: *\nDisallow:", "https://example.com/app.js", true},
+		{"wildcard", "User-agent: *\nDisallow: /*.map", "https://example.com/js/app.js.map", false},
+		{"wildcard middle", "User-agent: *\nDisallow: /js/*/private", "https://example.com/js/v1/private/a.js", false},
+		{"anchored matches end", "User-agent: *\nDisallow: /*.map$", "https://example.com/app.js.map", false},
+		{"anchored rejects suffix", "User-agent: *\nDisallow: /*.map$", "https://example.com/app.js.map?v=1", true},
+		{"anchored wildcard repeat", "User-agent: *\nDisallow: /*a$", "https://example.com/banana", false},
+		{"longest rule wins", "User-agent: *\nDisallow: /js/\nAllow: /js/public/", "https://example.com/js/public/app.js", true},
+		{"longer disallow wins", "User-agent: *\nAllow: /js/\nDisallow: /js/private/", "https://example.com/js/private/app.js", false},
+		{"allow wins tie", "User-agent: *\nDisallow: /app\nAllow: /app", "https://example.com/app.js", true},
+		{"query matched", "User-agent: *\nDisallow: /*?debug", "https://example.com/app.js?debug=1", false},
+		{"robots.txt always allowed", "User-agent: *\nDisallow: /", "https://example.com/robots.txt", true},
+		{"comments ignored", "User-agent: * # everyone\nDisallow: /js/ # scripts", "https://example.com/js/app.js", false},
+		{"case-insensitive keys", "USER-AGENT: *\nDISALLOW: /js/", "https://example.com/js/app.js", false},
+		{"crlf lines", "User-agent: *\r\nDisallow: /js/\r\n", "https://example.com/js/app.js", false},
+		{"bom", "\ufeffUser-agent: *\nDisallow: /js/", "https://example.com/js/app.js", false},
+		{"percent-encoding case", "User-agent: *\nDisallow: /a%2fb", "https://example.com/a%2Fb/c.js", false},
+		{"unreserved escape decoded", "User-agent: *\nDisallow: /%7Euser/", "https://example.com/~user/app.js", false},
+		{"utf-8 rule", "User-agent: *\nDisallow: /ü/", "https://example.com/%C3%BC/app.js", false},
+		{"named group wins", "User-agent: *\nDisallow: /\n\nUser-agent: dejank\nDisallow: /private/", "https://example.com/app.js", true},
+		{"named group with version", "User-agent: *\nDisallow: /\n\nUser-agent: Dejank/1.0\nAllow: /", "https://example.com/app.js", true},
+		{"named group without rules", "User-agent: dejank\nAllow:\n\nUser-agent: *\nDisallow: /", "https://example.com/app.js", true},
+		{"other agent ignored", "User-agent: googlebot\nDisallow: /", "https://example.com/app.js", true},
+		{"shared group", "User-agent: googlebot\nUser-agent: dejank\nDisallow: /js/", "https://example.com/js/app.js", false},
+		{"named groups merged", "User-agent: dejank\nDisallow: /a/\n\nUser-agent: dejank\nDisallow: /b/", "https://example.com/b/app.js", false},
+		{"new group after rules", "User-agent: dejank\nDisallow: /a/\nUser-agent: other\nDisallow: /b/", "https://example.com/b/app.js", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.content).Allowed(tt.url); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowAllDisallowAll(t *testing.T) {
+	if !AllowAll().Allowed("https://example.com/app.js") {
+		t.Error("AllowAll disallowed a URL")
+	}
+	if DisallowAll().Allowed("https://example.com/app.js") {
+		t.Error("DisallowAll allowed a URL")
+	}
+	if !DisallowAll().Allowed("https://example.com/robots.txt") {
+		t.Error("DisallowAll disallowed /robots.txt")
+	}
+}
+
+func TestSitemaps(t *testing.T) {
+	content := "Sitemap: https://example.com/sitemap-pages.xml\n" +
+		"User-agent: *\nDisallow: /admin/\n" +
+		"sitemap:https://cdn.example.com/sitemaps/index.xml.gz # mirrored\n" +
+		"Sitemap:\n" +
+		"User-agent: dejank\nSITEMAP: /relative.xml\n"
+	want := []string{"https://example.com/sitemap-pages.xml", "https://cdn.example.com/sitemaps/index.xml.gz", "/relative.xml"}
+
+	got := Sitemaps(content)
+	if len(got) != len(want) {
+		t.Fatalf("Sitemaps = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Sitemaps[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// Matches the specifier in import/export ... from "x", import "x",
+	// import("x"), and require("x")
+	importSpecifierRe = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*|\brequire\s*\(\s*)["']([^"'\s]+)["']`)
+
+	// Extensions tried when resolving a specifier, in the order bundlers use
+	resolveExtensions = []string{".d.ts", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".vue", ".svelte", ".json"}
+
+	// Node built-in modules, which are never dependencies
+	nodeBuiltins = map[string]bool{
+		"assert": true, "buffer": true, "child_process": true, "crypto": true, "events": true,
+		"fs": true, "http": true, "https": true, "net": true, "os": true, "path": true,
+		"process": true, "querystring": true, "stream": true, "string_decoder": true,
+		"timers": true, "tty": true, "url": true, "util": true, "vm": true, "zlib": true,
+	}
+)
+
+// Alias maps an import prefix such as "@" or "~" to a directory.
+type Alias struct {
+	Prefix string // Specifier prefix without the trailing slash, e.g. "@"
+	Dir    string // Directory relative to the restored root, slash-separated
+	Hits   int    // Imports that resolved through this alias
+}
+
+// Inference is what the scaffold learned about a restored tree.
+type Inference struct {
+	BaseDir      string            // Directory bare non-package imports resolve from ("" = root)
+	BaseHits     int               // Imports that resolved through BaseDir
+	Aliases      []Alias           // Sorted by prefix
+	Dependencies map[string]string // Package name to version ("*" if unknown)
+	TypeScript   bool              // Tree contains .ts or .tsx files
+}
+
+// Infer walks a restored_sources directory and infers path aliases, the
+// base directory for non-relative imports, and third-party dependencies.
+//
+// Each non-relative import specifier is classified in turn: an installed
+// package (a node_modules directory in the tree), a path that resolves from
+// the base directory (a baseUrl-style import), an alias whose remainder
+// resolves ("@/x", "~/x", "@app/x"), or otherwise a dependency. The base
+// directory is the one most multi-segment imports resolve from; a bare
+// "name" alone is too likely to match some file by chance to choose it,
+// and counts as local only if it resolves from there. The directory with
+// the most resolved imports wins for each alias.
+func Infer(root string) (*Inference, error) {
+	inf := &Inference{Dependencies: make(map[string]string)}
+
+	var files []string                   // First-party modules, slash-separated relative paths
+	modules := make(map[string][]string) // segment suffix -> directories it resolves from
+	installed := make(map[string]string) // package -> version from node_modules
+
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if pkg, ok := nodeModulesPackage(rel); ok {
+				if _, seen := installed[pkg]; !seen {
+					installed[pkg] = packageVersion(filepath.Join(p, "package.json"))
+				}
+			}
+			return nil
+		}
+
+		ext := path.Ext(rel)
+		if ext == ".ts" || ext == ".tsx" {
+			inf.TypeScript = true
+		}
+		if !isResolvable(rel) || strings.Contains("/"+rel, "/node_modules/") {
+			return nil
+		}
+
+		files = append(files, rel)
+		for _, id := range moduleIDs(rel) {
+			indexSuffixes(modules, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for pkg, version := range installed {
+		inf.Dependencies[pkg] = version
+	}
+
+	var specs []string
+	for _, rel := range files {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		for _, m := range importSpecifierRe.FindAllStringSubmatch(string(content), -1) {
+			if spec := cleanSpecifier(m[1]); !isExternal(spec) {
+				specs = append(specs, spec)
+			}
+		}
+	}
+
+	baseVotes := make(map[string]int)
+	for _, spec := range specs {
+		if strings.Contains(spec, "/") && canBeBase(spec, installed) {
+			for _, d := range resolve(modules, spec) {
+				baseVotes[d]++
+			}
+		}
+	}
+	inf.BaseDir, _ = bestDir(baseVotes)
+
+	aliasVotes := make(map[string]map[string]int)
+	for _, spec := range specs {
+		classify(spec, modules, installed, len(baseVotes) > 0, inf, aliasVotes)
+	}
+
+	for prefix, votes := range aliasVotes {
+		dir, hits := bestDir(votes)
+		inf.Aliases = append(inf.Aliases, Alias{Prefix: prefix, Dir: dir, Hits: hits})
+	}
+	sort.Slice(inf.Aliases, func(i, j int) bool { return inf.Aliases[i].Prefix < inf.Aliases[j].Prefix })
+
+	return inf, nil
+}
+
+// cleanSpecifier strips webpack loader prefixes ("style-loader!css-loader!./x")
+// and resource queries ("./x?raw") from an import specifier.
+func cleanSpecifier(spec string) string {
+	if i := strings.LastIndexByte(spec, '!'); i >= 0 {
+		spec = spec[i+1:]
+	}
+	spec, _, _ = strings.Cut(spec, "?")
+	return spec
+}
+
+// isExternal reports whether a specifier never names a module of the tree
+// or a package: relative and absolute paths, URLs, and node: built-ins.
+func isExternal(spec string) bool {
+	return spec == "" || strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") ||
+		strings.Contains(spec, "://") || strings.HasPrefix(spec, "node:")
+}
+
+// canBeBase reports whether spec may be a baseUrl-style path: it names no
+// installed package, starts with no alias prefix, and isn't scoped; an
+// "@scope/pkg/..." path that resolves is a vendored copy of the package.
+func canBeBase(spec string, installed map[string]string) bool {
+	if _, ok := installed[packageName(spec)]; ok {
+		return false
+	}
+	first, _, _ := strings.Cut(spec, "/")
+	return !isAliasPrefix(first) && !strings.HasPrefix(first, "@")
+}
+
+// classify records a single import specifier. based tells whether
+// multi-segment imports chose inf.BaseDir.
+func classify(spec string, modules map[string][]string, installed map[string]string, based bool,
+	inf *Inference, aliasVotes map[string]map[string]int) {
+	pkg := packageName(spec)
+	if _, ok := installed[pkg]; ok {
+		return
+	}
+
+	first, rest, hasRest := strings.Cut(spec, "/")
+
+	// baseUrl-style import: the whole specifier is a path under the base
+	// directory
+	if based && canBeBase(spec, installed) && containsString(resolve(modules, spec), inf.BaseDir) {
+		inf.BaseHits++
+		return
+	}
+
+	// Alias import: "@/x", "~/x", or a scope-like "@app/x" standing for a
+	// directory. A scoped package's subpath ("@mui/material/Button") that
+	// the tree holds a copy of is a dependency, not an alias.
+	if hasRest && (isAliasPrefix(first) || strings.HasPrefix(first, "@")) {
+		vendored := !isAliasPrefix(first) && len(resolve(modules, spec)) > 0
+		if dirs := resolve(modules, rest); len(dirs) > 0 && !vendored {
+			if aliasVotes[first] == nil {
+				aliasVotes[first] = make(map[string]int)
+			}
+			for _, d := range dirs {
+				aliasVotes[first][d]++
+			}
+			return
+		}
+	}
+
+	if nodeBuiltins[pkg] || !looksLikePackage(spec) {
+		return
+	}
+	if _, ok := inf.Dependencies[pkg]; !ok {
+		inf.Dependencies[pkg] = "*"
+	}
+}
+
+// resolve returns the directories from which spec resolves to a module.
+func resolve(modules map[string][]string, spec string) []string {
+	return modules[strings.TrimSuffix(path.Clean(spec), "/")]
+}
+
+// indexSuffixes records every segment suffix of a module id together with
+// the directory prefix it would be resolved from. "app/src/a/b" registers
+// "b" from "app/src/a", "a/b" from "app/src", and so on up to the root.
+func indexSuffixes(modules map[string][]string, id string) {
+	parts := strings.Split(id, "/")
+	for i := range parts {
+		suffix := strings.Join(parts[i:], "/")
+		dir := strings.Join(parts[:i], "/")
+		if !containsString(modules[suffix], dir) {
+			modules[suffix] = append(modules[suffix], dir)
+		}
+	}
+}
+
+// moduleIDs returns the specifiers (relative to the root) that resolve to a
+// file: its path, its path without the extension, and its directory for
+// index files.
+func moduleIDs(rel string) []string {
+	ids := []string{rel}
+	stem := rel
+	for _, ext := range resolveExtensions {
+		if strings.HasSuffix(rel, ext) {
+			stem = strings.TrimSuffix(rel, ext)
+			break
+		}
+	}
+	if stem != rel {
+		ids = append(ids, stem)
+	}
+	if path.Base(stem) == "index" && path.Dir(stem) != "." {
+		ids = append(ids, path.Dir(stem))
+	}
+	return ids
+}
+
+func isResolvable(rel string) bool {
+	for _, ext := range resolveExtensions {
+		if strings.HasSuffix(rel, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAliasPrefix reports whether a first segment is a conventional alias
+// rather than a package or directory name: "@", "~", "#", "$", "@@", or a
+// name starting with "~", "#", or "$".
+func isAliasPrefix(first string) bool {
+	switch first {
+	case "@", "~", "#", "$", "@@":
+		return true
+	}
+	return strings.HasPrefix(first, "~") || strings.HasPrefix(first, "#") || strings.HasPrefix(first, "$")
+}
+
+// looksLikePackage reports whether spec is shaped like an npm package import.
+func looksLikePackage(spec string) bool {
+	name := packageName(spec)
+	if name == "" || isAliasPrefix(strings.SplitN(spec, "/", 2)[0]) {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || strings.ContainsRune("-._@/", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// packageName returns the npm package a specifier refers to: the first
+// segment, or the first two for scoped packages.
+func packageName(spec string) string {
+	parts := strings.SplitN(spec, "/", 3)
+	if strings.HasPrefix(parts[0], "@") {
+		if len(parts) < 2 {
+			return ""
+		}
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// nodeModulesPackage reports whether a directory is a package root directly
+// under a node_modules directory.
+func nodeModulesPackage(rel string) (string, bool) {
+	parts := strings.Split(rel, "/")
+	n := len(parts)
+	switch {
+	case n >= 2 && parts[n-2] == "node_modules" && !strings.HasPrefix(parts[n-1], "@"):
+		return parts[n-1], true
+	case n >= 3 && parts[n-3] == "node_modules" && strings.HasPrefix(parts[n-2], "@"):
+		return parts[n-2] + "/" + parts[n-1], true
+	}
+	return "", false
+}
+
+// packageVersion reads the version from a restored package.json, if any.
+func packageVersion(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "*"
+	}
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || pkg.Version == "" {
+		return "*"
+	}
+	return pkg.Version
+}
+
+// bestDir returns the directory with the most votes, preferring the
+// shallowest on ties.
+func bestDir(votes map[string]int) (string, int) {
+	best, hits := "", 0
+	for dir, n := range votes {
+		if n > hits || n == hits && (len(dir) < len(best) || len(dir) == len(best) && dir < best) {
+			best, hits = dir, n
+		}
+	}
+	return best, hits
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
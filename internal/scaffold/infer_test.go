@@ -0,0 +1,143 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates files, keyed by slash-separated path, under a
+// temporary restored_sources directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		baseDir  string
+		baseHits int
+		aliases  []Alias
+		deps     map[string]string
+	}{
+		{
+			name: "bare package imports",
+			files: map[string]string{
+				"src/main.ts":          `import React from "react"; import { debounce } from "lodash/debounce"; import fs from "fs"; import p from "node:path"`,
+				"src/components/x.tsx": `import "./y"`,
+			},
+			deps: map[string]string{"react": "*", "lodash": "*"},
+		},
+		{
+			name: "bare import matching a file by chance",
+			files: map[string]string{
+				"src/main.js":             `import React from "react"`,
+				"src/components/react.js": `export default 1`,
+			},
+			deps: map[string]string{"react": "*"},
+		},
+		{
+			name: "baseUrl imports",
+			files: map[string]string{
+				"src/main.ts":               `import Button from "components/Button"; import api from "services/api"; import store from "store"; import vue from "vue"`,
+				"src/components/Button.vue": ``,
+				"src/services/api/index.ts": ``,
+				"src/store.ts":              ``,
+			},
+			baseDir:  "src",
+			baseHits: 3,
+			deps:     map[string]string{"vue": "*"},
+		},
+		{
+			name: "alias imports",
+			files: map[string]string{
+				"app/src/main.ts":    `import a from "@/utils/a"; import b from "~/utils/b"; import c from "@app/utils/a"`,
+				"app/src/utils/a.ts": ``,
+				"app/src/utils/b.ts": ``,
+			},
+			aliases: []Alias{{Prefix: "@", Dir: "app/src", Hits: 1}, {Prefix: "@app", Dir: "app/src", Hits: 1}, {Prefix: "~", Dir: "app/src", Hits: 1}},
+			deps:    map[string]string{},
+		},
+		{
+			name: "scoped package subpaths",
+			files: map[string]string{
+				"src/main.ts": `import Button from "@mui/material/Button"; import { x } from "@tanstack/react-query/build/lib"; import "@fontsource/roboto"`,
+			},
+			deps: map[string]string{"@mui/material": "*", "@tanstack/react-query": "*", "@fontsource/roboto": "*"},
+		},
+		{
+			name: "vendored scoped package",
+			files: map[string]string{
+				"src/main.ts":                    `import Button from "@mui/material/Button"`,
+				"vendor/@mui/material/Button.js": ``,
+			},
+			deps: map[string]string{"@mui/material": "*"},
+		},
+		{
+			name: "installed packages",
+			files: map[string]string{
+				"src/main.ts":                          `import { h } from "preact/hooks"; import s from "@scope/pkg/sub"`,
+				"node_modules/preact/package.json":     `{"version":"10.19.3"}`,
+				"node_modules/preact/hooks/index.js":   ``,
+				"node_modules/@scope/pkg/package.json": `{"version":"2.0.0"}`,
+				"node_modules/@scope/pkg/sub/index.js": ``,
+			},
+			deps: map[string]string{"preact": "10.19.3", "@scope/pkg": "2.0.0"},
+		},
+		{
+			name: "loader prefixes and queries",
+			files: map[string]string{
+				"src/main.js": `require("style-loader!css-loader!normalize.css"); import raw from "raw-loader!./a.txt"; import svg from "feather-icons?inline"`,
+			},
+			deps: map[string]string{"normalize.css": "*", "feather-icons": "*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inf, err := Infer(writeTree(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inf.BaseDir != tt.baseDir || inf.BaseHits != tt.baseHits {
+				t.Errorf("base = %q (%d hits), want %q (%d)", inf.BaseDir, inf.BaseHits, tt.baseDir, tt.baseHits)
+			}
+			if len(inf.Aliases) != 0 || len(tt.aliases) != 0 {
+				if !reflect.DeepEqual(inf.Aliases, tt.aliases) {
+					t.Errorf("aliases = %+v, want %+v", inf.Aliases, tt.aliases)
+				}
+			}
+			if !reflect.DeepEqual(inf.Dependencies, tt.deps) {
+				t.Errorf("dependencies = %v, want %v", inf.Dependencies, tt.deps)
+			}
+		})
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"react":                    "react",
+		"lodash/debounce":          "lodash",
+		"@mui/material":            "@mui/material",
+		"@mui/material/Button":     "@mui/material",
+		"@tanstack/query/a/b/c.js": "@tanstack/query",
+		"@scope":                   "",
+	}
+	for spec, want := range tests {
+		if got := packageName(spec); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
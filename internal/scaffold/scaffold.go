@@ -0,0 +1,167 @@
+// Package scaffold writes editor project files around restored sources so
+// go-to-definition and import resolution work when browsing them.
+package scaffold
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Result describes a generated scaffold.
+type Result struct {
+	Files     []string // Files written, relative to the domain directory
+	Inference *Inference
+}
+
+// Write infers aliases and dependencies from sourcesDir and writes a
+// tsconfig.json (or jsconfig.json for JavaScript-only trees),
+// .vscode/settings.json, and a stub package.json into dir. Existing files
+// are overwritten.
+func Write(dir, sourcesDir, name string) (*Result, error) {
+	inf, err := Infer(sourcesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", sourcesDir, err)
+	}
+	result := &Result{Inference: inf}
+
+	sourcesRel, err := filepath.Rel(dir, sourcesDir)
+	if err != nil {
+		return nil, err
+	}
+	sourcesRel = filepath.ToSlash(sourcesRel)
+
+	configName := "jsconfig.json"
+	if inf.TypeScript {
+		configName = "tsconfig.json"
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{configName, projectConfig(inf, sourcesRel)},
+		{filepath.Join(".vscode", "settings.json"), editorSettings()},
+		{"package.json", packageManifest(inf, name)},
+	}
+
+	for _, f := range files {
+		if err := writeJSON(filepath.Join(dir, f.name), f.data); err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, filepath.ToSlash(f.name))
+	}
+
+	return result, nil
+}
+
+// projectConfig builds the tsconfig/jsconfig contents. baseUrl points at the
+// inferred base directory and each alias becomes a "prefix/*" path mapping.
+func projectConfig(inf *Inference, sourcesRel string) map[string]interface{} {
+	baseURL := joinSlash(sourcesRel, inf.BaseDir)
+
+	paths := make(map[string][]string)
+	for _, a := range inf.Aliases {
+		target := relSlash(inf.BaseDir, a.Dir)
+		paths[a.Prefix+"/*"] = []string{joinSlash(target, "*")}
+	}
+
+	options := map[string]interface{}{
+		"baseUrl":          "./" + baseURL,
+		"target":           "esnext",
+		"module":           "esnext",
+		"moduleResolution": "bundler",
+		"jsx":              "preserve",
+		"allowJs":          true,
+		"checkJs":          false,
+		"noEmit":           true,
+		"skipLibCheck":     true,
+	}
+	if len(paths) > 0 {
+		options["paths"] = paths
+	}
+
+	return map[string]interface{}{
+		"compilerOptions": options,
+		"include":         []string{sourcesRel + "/**/*"},
+		"exclude":         []string{"downloaded_site", "extracted_assets", "**/node_modules"},
+	}
+}
+
+// editorSettings keeps raw downloads and vendored packages out of search.
+func editorSettings() map[string]interface{} {
+	excluded := map[string]bool{
+		"downloaded_site":  true,
+		"extracted_assets": true,
+		"**/node_modules":  true,
+	}
+	return map[string]interface{}{
+		"search.exclude":       excluded,
+		"files.watcherExclude": excluded,
+	}
+}
+
+// packageManifest lists the recovered dependency inventory so editors know
+// which packages the sources import.
+func packageManifest(inf *Inference, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         packageSlug(name),
+		"private":      true,
+		"description":  "Sources restored by dejank",
+		"dependencies": inf.Dependencies,
+	}
+}
+
+// packageSlug turns a domain name into a valid npm package name.
+func packageSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	slug := strings.Trim(b.String(), "-.")
+	if slug == "" {
+		return "restored-sources"
+	}
+	return slug
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// joinSlash joins slash-separated path parts, skipping empty ones.
+func joinSlash(parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p != "" && p != "." {
+			out = append(out, strings.Trim(p, "/"))
+		}
+	}
+	if len(out) == 0 {
+		return "."
+	}
+	return strings.Join(out, "/")
+}
+
+// relSlash returns target relative to base, both slash-separated paths
+// relative to the same root.
+func relSlash(base, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash("/"+base), filepath.FromSlash("/"+target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,86 @@
+package schema
+
+import "github.com/thesavant42/dejank/internal/fetch"
+
+// The records below are the artifacts, and entries of list artifacts,
+// that exist only to be written and read back. Artifacts whose records
+// are also a package's API keep them there:
+//
+//	run-info.json       modes.RunInfo
+//	plan.json           modes.Plan
+//	run-state.json      modes runState
+//	hosts.json          hosts.Host
+//	comments.json       comments.Match
+//	env.json            modes.EnvVar
+//	split_hosts.json    modes.HostOutput
+//	licenses.json       licenses.Inventory
+//	graphql/index.json  graphql.Harvest
+//	jobs.json           server jobIndex
+//
+// Fields that are always meaningful are written even when zero; optional
+// details are omitempty.
+
+// Download is one script or sourcemap in downloads.json, recorded so that
+// a later run into the same domain directory can reuse it, and local mode
+// can tell which URL it restores.
+type Download struct {
+	URL    string `json:"url"`
+	File   string `json:"file"` // Name under downloaded_site
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+	fetch.Validators
+}
+
+// Merged is one download in merged.json, recording which domain
+// directory a merged file came from.
+type Merged struct {
+	File        string `json:"file"` // Relative to the domain directory
+	From        string `json:"from"` // Name of the domain directory it was downloaded to
+	SHA256      string `json:"sha256"`
+	RenamedFrom string `json:"renamed_from,omitempty"` // Its name in From, when that was taken by a different file
+}
+
+// Secret is a finding in secrets.json. The matched text is left out;
+// messages carry it redacted.
+type Secret struct {
+	Rule       string            `json:"rule"`
+	Kind       string            `json:"kind"`
+	Message    string            `json:"message"`
+	File       string            `json:"file"` // Relative to the domain directory
+	Line       int               `json:"line,omitempty"`
+	Column     int               `json:"column,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// OutOfScope is a URL in out_of_scope.json that -scope-file kept a run
+// from fetching.
+type OutOfScope struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// Normalized is a restored source in normalized.json whose BOM or line
+// endings -normalize-eol rewrote.
+type Normalized struct {
+	Path         string `json:"path"`
+	BOMStripped  bool   `json:"bom_stripped,omitempty"`
+	EOLConverted string `json:"eol_converted,omitempty"`
+}
+
+// Unformatted is a restored source in unformatted.json that was written
+// without formatting.
+type Unformatted struct {
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	Reason string `json:"reason"` // "size" or "error"
+}
+
+// Target is the content of target.json: the URL a run requested and the
+// page it ended up on after redirects.
+type Target struct {
+	Versioned
+	RequestedURL  string `json:"requested_url"`
+	RequestedHost string `json:"requested_host"`
+	FinalURL      string `json:"final_url"`
+	FinalHost     string `json:"final_host"`
+}
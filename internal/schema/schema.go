@@ -0,0 +1,97 @@
+// Package schema versions the JSON artifacts dejank writes, such as
+// licenses.json, hosts.json, and downloads.json, so that tools reading them
+// can tell which layout they have.
+//
+// The major version changes when a field is removed, renamed, or changes
+// meaning; the minor version when fields are added. Readers accept any
+// artifact of their major version or older, including artifacts written
+// before versioning, and refuse newer majors.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is the schema version of the artifacts this build writes.
+const Version = "1.0"
+
+// major is the major part of Version, the newest major this build reads.
+const major = 1
+
+// Versioned is embedded first in every JSON artifact object.
+type Versioned struct {
+	SchemaVersion string `json:"schema_version"`
+}
+
+// Current returns the header for an artifact written now.
+func Current() Versioned {
+	return Versioned{SchemaVersion: Version}
+}
+
+// List artifacts, which hold a list of records such as hosts.json and
+// downloads.json, are written as bare JSON arrays, the shape they had
+// before versioning, and take their version from the run-info.json beside
+// them. List is the object form some builds wrote them in instead, which
+// DecodeList still reads.
+type List[T any] struct {
+	Versioned
+	Items []T `json:"items"`
+}
+
+// Items returns items for writing as a list artifact: a nil slice becomes
+// an empty one, so the artifact is [] rather than null.
+func Items[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// Check returns an error if data, the contents of the artifact name,
+// declares a major schema version newer than this build reads. Artifacts
+// without a version predate versioning and pass.
+func Check(data []byte, name string) error {
+	var v Versioned
+	if json.Unmarshal(data, &v) != nil || v.SchemaVersion == "" {
+		return nil
+	}
+	m, err := Major(v.SchemaVersion)
+	if err != nil {
+		return fmt.Errorf("%s has an invalid schema_version %q", name, v.SchemaVersion)
+	}
+	if m > major {
+		return fmt.Errorf("%s was written with schema version %s, newer than this dejank reads (%d.x); upgrade dejank to read it", name, v.SchemaVersion, major)
+	}
+	return nil
+}
+
+// Major returns the major part of a schema version such as "1.0".
+func Major(version string) (int, error) {
+	m, _, _ := strings.Cut(version, ".")
+	return strconv.Atoi(m)
+}
+
+// DecodeList reads a list artifact written as a bare array or as a List,
+// after checking the version of the latter with Check.
+func DecodeList[T any](data []byte, name string) ([]T, error) {
+	if err := Check(data, name); err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return items, nil
+	}
+	var list List[T]
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return list.Items, nil
+}
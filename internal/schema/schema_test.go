@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/fetch"
+)
+
+// The golden files in testdata were written by builds before versioning
+// (-v0) and by builds that wrote list artifacts as objects (-v1-object).
+
+var goldenDownloads = []Download{
+	{
+		URL:        "https://example.com/static/js/main.js",
+		File:       "main.js",
+		Bytes:      1234,
+		SHA256:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Validators: fetch.Validators{ETag: `"abc"`},
+	},
+	{
+		URL:        "https://example.com/static/js/main.js.map",
+		File:       "main.js.map",
+		Bytes:      5678,
+		SHA256:     "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752",
+		Validators: fetch.Validators{LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+	},
+}
+
+func readGolden(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeListGolden(t *testing.T) {
+	for _, name := range []string{"downloads-v0.json", "downloads-v1-object.json"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DecodeList[Download](readGolden(t, name), name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, goldenDownloads) {
+				t.Errorf("DecodeList = %+v, want %+v", got, goldenDownloads)
+			}
+		})
+	}
+
+	merged, err := DecodeList[Merged](readGolden(t, "merged-v0.json"), "merged.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 || merged[1].RenamedFrom != "downloaded_site/main.js" || merged[0].From != "example.com-dejank" {
+		t.Errorf("merged.json decoded as %+v", merged)
+	}
+
+	_, err = DecodeList[Download](readGolden(t, "downloads-v2.json"), "downloads.json")
+	if err == nil || !strings.Contains(err.Error(), "upgrade dejank") {
+		t.Errorf("a newer major was read: %v", err)
+	}
+}
+
+// TestListShape checks that list artifacts are still written in the shape
+// of the golden files from before versioning.
+func TestListShape(t *testing.T) {
+	data, err := json.MarshalIndent(Items(goldenDownloads), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.TrimSpace(readGolden(t, "downloads-v0.json")); !bytes.Equal(data, want) {
+		t.Errorf("downloads.json written as\n%s\nwant\n%s", data, want)
+	}
+
+	empty, _ := json.Marshal(Items[Download](nil))
+	if string(empty) != "[]" {
+		t.Errorf("empty list written as %s, want []", empty)
+	}
+}
+
+func TestTargetGolden(t *testing.T) {
+	data := readGolden(t, "target-v0.json")
+	if err := Check(data, "target.json"); err != nil {
+		t.Fatal(err)
+	}
+	var target Target
+	if err := json.Unmarshal(data, &target); err != nil {
+		t.Fatal(err)
+	}
+	want := Target{RequestedURL: "http://example.com/", RequestedHost: "example.com", FinalURL: "https://www.example.com/", FinalHost: "www.example.com"}
+	if target != want {
+		t.Errorf("target.json decoded as %+v, want %+v", target, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		data string
+		ok   bool
+	}{
+		{`{"name": "unversioned"}`, true},
+		{`[1, 2, 3]`, true},
+		{`{"schema_version": "1.0"}`, true},
+		{`{"schema_version": "1.7"}`, true},
+		{`{"schema_version": "0.9"}`, true},
+		{`{"schema_version": "2.0"}`, false},
+		{`{"schema_version": "one"}`, false},
+	}
+	for _, tt := range tests {
+		if err := Check([]byte(tt.data), "artifact.json"); (err == nil) != tt.ok {
+			t.Errorf("Check(%s) = %v, want ok=%v", tt.data, err, tt.ok)
+		}
+	}
+}
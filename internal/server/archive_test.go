@@ -0,0 +1,68 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteArchiveFile(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "example.com")
+	if err := os.MkdirAll(filepath.Join(dir, "restored_sources"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "restored_sources", "app.js"), []byte("x"), 0644)
+
+	path := filepath.Join(root, "output.tar.gz")
+	if err := writeArchiveFile(path, dir); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	want := []string{"example.com/", "example.com/restored_sources/", "example.com/restored_sources/app.js"}
+	if len(names) != len(want) {
+		t.Fatalf("entries %q, want %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("entries %q, want %q", names, want)
+		}
+	}
+}
+
+func TestWriteArchiveFileError(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "output.tar.gz")
+	if err := writeArchiveFile(path, filepath.Join(root, "missing")); err == nil {
+		t.Fatal("archiving a missing directory succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("failed archive left %s behind", path)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(root, ".archive-*")); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %q", leftovers)
+	}
+}
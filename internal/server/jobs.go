@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/modes"
+	"github.com/thesavant42/dejank/internal/schema"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
+)
+
+// Job states.
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Job is a single url-mode run submitted to the server.
+type Job struct {
+	ID        string     `json:"id"`
+	URL       string     `json:"url"`
+	Status    string     `json:"status"`
+	Created   time.Time  `json:"created"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	Progress  Progress   `json:"progress"`
+	Error     string     `json:"error,omitempty"`
+	OutputDir string     `json:"output_dir,omitempty"`
+
+	ArchiveError string `json:"archive_error,omitempty"` // Why the output archive of a done job could not be written
+}
+
+// Progress mirrors the progress events emitted while a job runs.
+type Progress struct {
+	LastEvent        string `json:"last_event,omitempty"`
+	ScriptsTotal     int    `json:"scripts_total"`
+	ScriptsProcessed int    `json:"scripts_processed"`
+	MapsParsed       int    `json:"maps_parsed"`
+	SourcesRestored  int    `json:"sources_restored"`
+	Errors           int    `json:"errors"`
+}
+
+// Report is the JSON-serializable summary of a finished job.
+type Report struct {
+	schema.Versioned
+	URL              string               `json:"url"`
+	FinalURL         string               `json:"final_url"`
+	ScriptsFound     int                  `json:"scripts_found"`
+	MapsDiscovered   int                  `json:"maps_discovered"`
+	SourcesRestored  int                  `json:"sources_restored"`
+	AssetsExtracted  int                  `json:"assets_extracted"`
+	EnvVarsExtracted int                  `json:"env_vars_extracted"`
+	Scripts          []modes.ScriptRecord `json:"scripts"`
+	Maps             []modes.MapRecord    `json:"maps"`
+	Hosts            []modes.HostOutput   `json:"hosts,omitempty"`
+	Screenshot       string               `json:"screenshot,omitempty"`
+	PageHTML         string               `json:"page_html,omitempty"`
+	Stats            *stats.Stats         `json:"stats,omitempty"`
+	Errors           []string             `json:"errors"`
+	Warnings         []warn.Warning       `json:"warnings"`
+}
+
+// newReport converts a URLResult into a Report.
+func newReport(result *modes.URLResult) *Report {
+	report := &Report{
+		Versioned:        schema.Current(),
+		URL:              result.URL,
+		FinalURL:         result.FinalURL,
+		ScriptsFound:     result.ScriptsFound,
+		MapsDiscovered:   result.MapsDiscovered,
+		SourcesRestored:  result.SourcesRestored,
+		AssetsExtracted:  result.AssetsExtracted,
+		EnvVarsExtracted: result.EnvVarsExtracted,
+		Scripts:          result.Scripts,
+		Maps:             result.Maps,
+		Hosts:            result.Hosts,
+		Screenshot:       result.Screenshot,
+		PageHTML:         result.PageHTML,
+		Stats:            result.Stats,
+		Errors:           make([]string, 0, len(result.Errors)),
+		Warnings:         result.Warnings,
+	}
+	if report.Warnings == nil {
+		report.Warnings = []warn.Warning{}
+	}
+	for _, err := range result.Errors {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	return report
+}
+
+// writeReport writes a job report as indented JSON.
+func writeReport(path string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// apply updates progress counters from a progress event.
+func (p *Progress) apply(event string, data interface{}) {
+	p.LastEvent = event
+	switch event {
+	case "discovery_complete":
+		if m, ok := data.(map[string]int); ok {
+			p.ScriptsTotal = m["scripts"]
+		}
+	case "processing_script":
+		p.ScriptsProcessed++
+	case "sources_restored":
+		p.MapsParsed++
+		if m, ok := data.(map[string]interface{}); ok {
+			if n, ok := m["count"].(int); ok {
+				p.SourcesRestored += n
+			}
+		}
+	case "error":
+		p.Errors++
+	}
+}
+
+// jobIndex is the on-disk list of jobs, persisted so restarts keep history.
+type jobIndex struct {
+	schema.Versioned
+	Jobs []*Job `json:"jobs"`
+}
+
+// loadIndex reads the job index. Jobs that were queued or running when the
+// server stopped are marked failed since their work was lost.
+func loadIndex(path string) (map[string]*Job, error) {
+	jobs := make(map[string]*Job)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return jobs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job index: %w", err)
+	}
+
+	if err := schema.Check(data, "job index"); err != nil {
+		return nil, err
+	}
+	var idx jobIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse job index: %w", err)
+	}
+
+	for _, job := range idx.Jobs {
+		if job.Status == StatusQueued || job.Status == StatusRunning {
+			job.Status = StatusFailed
+			job.Error = "interrupted by server restart"
+		}
+		jobs[job.ID] = job
+	}
+
+	return jobs, nil
+}
+
+// saveIndex atomically writes the job index.
+func saveIndex(path string, jobs map[string]*Job) error {
+	idx := jobIndex{Versioned: schema.Current(), Jobs: sortedJobs(jobs)}
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job index: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// sortedJobs returns jobs ordered by creation time.
+func sortedJobs(jobs map[string]*Job) []*Job {
+	list := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
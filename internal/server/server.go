@@ -0,0 +1,364 @@
+// Package server exposes the url pipeline as an HTTP job service.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thesavant42/dejank/internal/modes"
+)
+
+// Options configures a Server.
+type Options struct {
+	Root    string        // Directory holding the job index and per-job output
+	Workers int           // Number of jobs processed concurrently
+	Token   string        // Required bearer token; empty disables auth
+	Config  *modes.Config // Template configuration copied for every job
+}
+
+// Server queues url-mode jobs and serves their status and results.
+type Server struct {
+	opts      Options
+	indexPath string
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	queue chan *Job
+}
+
+// New creates a Server, loading any previously persisted jobs.
+func New(opts Options) (*Server, error) {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	indexPath := filepath.Join(opts.Root, "jobs.json")
+	jobs, err := loadIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		opts:      opts,
+		indexPath: indexPath,
+		jobs:      jobs,
+		queue:     make(chan *Job, 1024),
+	}
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Run starts the workers and serves HTTP on addr until ctx is cancelled.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	for i := 0; i < s.opts.Workers; i++ {
+		go s.worker(ctx)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Handler returns the HTTP routes for the server.
+//
+//	POST /jobs                 submit {"url": "..."}
+//	GET  /jobs                 list jobs
+//	GET  /jobs/{id}            job status and progress
+//	GET  /jobs/{id}/report     JSON report of a finished job
+//	GET  /jobs/{id}/archive    tar.gz of a finished job's output
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleStatus)
+	mux.HandleFunc("GET /jobs/{id}/report", s.handleReport)
+	mux.HandleFunc("GET /jobs/{id}/archive", s.handleArchive)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the configured bearer token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	expected := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+		writeError(w, http.StatusBadRequest, "url must include http:// or https:// scheme")
+		return
+	}
+
+	job := &Job{
+		ID:      newJobID(),
+		URL:     req.URL,
+		Status:  StatusQueued,
+		Created: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- job:
+	default:
+		s.finish(job, nil, fmt.Errorf("job queue is full"))
+		writeError(w, http.StatusServiceUnavailable, "job queue is full")
+		return
+	}
+
+	s.save()
+	writeJSON(w, http.StatusAccepted, s.snapshot(job))
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	list := sortedJobs(s.jobs)
+	out := make([]Job, 0, len(list))
+	for _, job := range list {
+		out = append(out, *job)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": out})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	job := s.lookup(w, r)
+	if job == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(job))
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	job := s.lookup(w, r)
+	if job == nil {
+		return
+	}
+	snap := s.snapshot(job)
+	if snap.Status != StatusDone {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s", snap.Status))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, s.reportPath(snap.ID))
+}
+
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	job := s.lookup(w, r)
+	if job == nil {
+		return
+	}
+	snap := s.snapshot(job)
+	if snap.Status != StatusDone || snap.OutputDir == "" {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s", snap.Status))
+		return
+	}
+
+	if snap.ArchiveError != "" {
+		writeError(w, http.StatusInternalServerError, snap.ArchiveError)
+		return
+	}
+	path := s.archivePath(snap.ID)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// A job finished before archives were written with it
+		if err := writeArchiveFile(path, snap.OutputDir); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.tar.gz"`, snap.ID))
+	http.ServeFile(w, r, path)
+}
+
+// lookup finds the job named in the request path, writing a 404 if absent.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *Job {
+	s.mu.Lock()
+	job := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+	}
+	return job
+}
+
+// snapshot returns a copy of job taken under the lock.
+func (s *Server) snapshot(job *Job) Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *job
+}
+
+// worker runs queued jobs until ctx is cancelled.
+func (s *Server) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.runJob(job)
+		}
+	}
+}
+
+// runJob executes a job using a copy of the template configuration.
+func (s *Server) runJob(job *Job) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	job.Status = StatusRunning
+	job.Started = &now
+	s.mu.Unlock()
+	s.save()
+
+	cfg := *s.opts.Config
+	cfg.OutputRoot = s.jobDir(job.ID)
+	cfg.Force = true
+	cfg.Verbose = false
+	// Events still reach the template's callback, such as -events output
+	events := cfg.OnProgress
+	cfg.OnProgress = func(event string, data interface{}) {
+		s.mu.Lock()
+		job.Progress.apply(event, data)
+		s.mu.Unlock()
+		if events != nil {
+			events(event, data)
+		}
+	}
+
+	result, err := runSafely(&cfg, job.URL)
+	s.finish(job, result, err)
+}
+
+// finish records the outcome of a job, writes its report and archive, and
+// persists the index. A job whose archive fails is still done, with the
+// failure in ArchiveError.
+func (s *Server) finish(job *Job, result *modes.URLResult, err error) {
+	if err == nil {
+		err = writeReport(s.reportPath(job.ID), newReport(result))
+	}
+	var archiveErr error
+	if err == nil && result.OutputDir != "" {
+		archiveErr = writeArchiveFile(s.archivePath(job.ID), result.OutputDir)
+	}
+
+	now := time.Now().UTC()
+	s.mu.Lock()
+	job.Finished = &now
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusDone
+		job.OutputDir = result.OutputDir
+		if archiveErr != nil {
+			job.ArchiveError = archiveErr.Error()
+		}
+	}
+	s.mu.Unlock()
+	s.save()
+}
+
+// jobDir returns the output root for a job.
+func (s *Server) jobDir(id string) string {
+	return filepath.Join(s.opts.Root, "jobs", id)
+}
+
+// archivePath returns the location of a job's output archive.
+func (s *Server) archivePath(id string) string {
+	return filepath.Join(s.jobDir(id), "output.tar.gz")
+}
+
+// reportPath returns the location of a job's JSON report.
+func (s *Server) reportPath(id string) string {
+	return filepath.Join(s.jobDir(id), "report.json")
+}
+
+// save persists the job index, logging failures to stderr.
+func (s *Server) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := saveIndex(s.indexPath, s.jobs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dejank: %v\n", err)
+	}
+	return err
+}
+
+// runSafely runs the url pipeline, converting panics into errors.
+func runSafely(cfg *modes.Config, targetURL string) (result *modes.URLResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return modes.RunURL(cfg, targetURL)
+}
+
+// newJobID returns a random 16-character hex identifier.
+func newJobID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,231 @@
+// Package sitemap reads XML sitemaps and sitemap indexes and samples the
+// pages they list.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Limits on how much of a site's sitemaps are read. The protocol allows
+// 50,000 URLs and 50 MB per file; a large site can chain thousands of
+// files through its indexes.
+const (
+	maxFiles = 25               // Sitemap files fetched, indexes included
+	maxDepth = 3                // Levels of nested sitemap indexes followed
+	maxURLs  = 200000           // Page URLs collected across all files
+	maxBytes = 50 * 1024 * 1024 // Decompressed size read per file
+)
+
+// Fetcher downloads a URL. *fetch.Client satisfies it.
+type Fetcher interface {
+	GetBytes(url string) ([]byte, error)
+}
+
+// document is either a <urlset> or a <sitemapindex>; element names are
+// matched regardless of namespace.
+type document struct {
+	XMLName  xml.Name
+	URLs     []loc `xml:"url"`
+	Sitemaps []loc `xml:"sitemap"`
+}
+
+type loc struct {
+	Loc string `xml:"loc"`
+}
+
+// Fetch reads the sitemap at sitemapURL, following nested sitemap indexes,
+// and returns the page URLs it lists in order, without duplicates. Files
+// that can't be fetched or parsed are reported as errors; the URLs from
+// the others are still returned.
+func Fetch(f Fetcher, sitemapURL string) ([]string, []error) {
+	return FetchAll(f, []string{sitemapURL})
+}
+
+// FetchAll reads several sitemaps like Fetch, in order, sharing one set of
+// limits and one list of pages.
+func FetchAll(f Fetcher, sitemapURLs []string) ([]string, []error) {
+	var pages []string
+	var errs []error
+	seenPages := make(map[string]bool)
+	seenFiles := make(map[string]bool)
+	files := 0
+
+	var visit func(u string, depth int)
+	visit = func(u string, depth int) {
+		if seenFiles[u] || files >= maxFiles || len(pages) >= maxURLs {
+			return
+		}
+		seenFiles[u] = true
+		files++
+
+		body, err := f.GetBytes(u)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		doc, err := parse(body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse sitemap %s: %w", u, err))
+			return
+		}
+
+		for _, l := range doc.URLs {
+			page := strings.TrimSpace(l.Loc)
+			if page == "" || seenPages[page] {
+				continue
+			}
+			seenPages[page] = true
+			pages = append(pages, page)
+			if len(pages) >= maxURLs {
+				return
+			}
+		}
+		if depth >= maxDepth {
+			return
+		}
+		for _, l := range doc.Sitemaps {
+			if child := strings.TrimSpace(l.Loc); child != "" {
+				visit(resolve(u, child), depth+1)
+			}
+		}
+	}
+	for _, u := range sitemapURLs {
+		visit(u, 0)
+	}
+
+	return pages, errs
+}
+
+// parse decodes a sitemap, sitemap index, or plain-text sitemap (one URL
+// per line). Gzip-compressed content is decompressed first.
+func parse(data []byte) (*document, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(io.LimitReader(zr, maxBytes)); err != nil {
+			return nil, err
+		}
+	} else if len(data) > maxBytes {
+		data = data[:maxBytes]
+	}
+
+	trimmed := bytes.TrimLeft(data, "\ufeff \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] != '<' {
+		return parseText(trimmed), nil
+	}
+
+	var doc document
+	if err := xml.Unmarshal(trimmed, &doc); err != nil {
+		return nil, err
+	}
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+		return &doc, nil
+	}
+	return nil, fmt.Errorf("unexpected root element <%s>", doc.XMLName.Local)
+}
+
+// parseText reads a plain-text sitemap.
+func parseText(data []byte) *document {
+	doc := &document{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			doc.URLs = append(doc.URLs, loc{Loc: line})
+		}
+	}
+	return doc
+}
+
+// resolve resolves a possibly relative sitemap location against the
+// sitemap that listed it.
+func resolve(base, ref string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// Sample picks up to n pages, spreading the picks across site sections so
+// that separately built areas (/blog, /docs, /pricing) are all covered
+// before any one is sampled twice. Pages are grouped by their first path
+// segment; larger sections are visited first, and within a section
+// shallower pages come first since they are usually section landing pages.
+func Sample(pages []string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	type page struct {
+		url   string
+		depth int
+		path  string
+	}
+	groups := make(map[string][]page)
+	for _, p := range pages {
+		u, err := url.Parse(p)
+		if err != nil {
+			continue
+		}
+		path := strings.Trim(u.Path, "/")
+		section, _, _ := strings.Cut(path, "/")
+		depth := 0
+		if path != "" {
+			depth = strings.Count(path, "/") + 1
+		}
+		groups[section] = append(groups[section], page{url: p, depth: depth, path: path})
+	}
+
+	sections := make([]string, 0, len(groups))
+	for s, g := range groups {
+		sections = append(sections, s)
+		sort.SliceStable(g, func(i, j int) bool {
+			if g[i].depth != g[j].depth {
+				return g[i].depth < g[j].depth
+			}
+			return g[i].path < g[j].path
+		})
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		a, b := groups[sections[i]], groups[sections[j]]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return sections[i] < sections[j]
+	})
+
+	var out []string
+	for round := 0; len(out) < n; round++ {
+		picked := false
+		for _, s := range sections {
+			if g := groups[s]; round < len(g) {
+				out = append(out, g[round].url)
+				picked = true
+				if len(out) == n {
+					break
+				}
+			}
+		}
+		if !picked {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,152 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeFetcher serves sitemaps from memory and records what was fetched.
+type fakeFetcher struct {
+	files   map[string]string
+	fetched []string
+}
+
+func (f *fakeFetcher) GetBytes(u string) ([]byte, error) {
+	f.fetched = append(f.fetched, u)
+	body, ok := f.files[u]
+	if !ok {
+		return nil, fmt.Errorf("HTTP 404 for %s", u)
+	}
+	return []byte(body), nil
+}
+
+func urlset(pages ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, p := range pages {
+		b.WriteString("<url><loc>" + p + "</loc></url>")
+	}
+	b.WriteString("</urlset>")
+	return b.String()
+}
+
+func index(sitemaps ...string) string {
+	var b strings.Builder
+	b.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, s := range sitemaps {
+		b.WriteString("<sitemap><loc> " + s + " </loc></sitemap>")
+	}
+	b.WriteString("</sitemapindex>")
+	return b.String()
+}
+
+func gzipped(s string) string {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.String()
+}
+
+func TestFetch(t *testing.T) {
+	f := &fakeFetcher{files: map[string]string{
+		"https://a.example/sitemap.xml": index("/sitemaps/pages.xml.gz", "blog.xml", "https://a.example/missing.xml", "/sitemap.xml"),
+		// Gzipped, with a byte order mark
+		"https://a.example/sitemaps/pages.xml.gz": gzipped("\ufeff" + urlset("https://a.example/", "https://a.example/pricing")),
+		// Relative to the index's directory; lists itself and the index
+		"https://a.example/blog.xml": index("blog-posts.txt", "/sitemap.xml", "blog.xml"),
+		"https://a.example/blog-posts.txt": "\ufeff# posts\nhttps://a.example/blog/one\n\n  https://a.example/blog/two  \nnot a url\nhttps://a.example/pricing\n",
+	}}
+
+	pages, errs := Fetch(f, "https://a.example/sitemap.xml")
+	want := []string{"https://a.example/", "https://a.example/pricing", "https://a.example/blog/one", "https://a.example/blog/two"}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing.xml") {
+		t.Errorf("errs = %v, want the missing sitemap", errs)
+	}
+	// The cycle back to sitemap.xml and blog.xml is not followed
+	if len(f.fetched) != 5 {
+		t.Errorf("fetched %v, want each file once", f.fetched)
+	}
+}
+
+func TestFetchInvalid(t *testing.T) {
+	f := &fakeFetcher{files: map[string]string{
+		"https://a.example/html.xml":   "<html><body>Not found</body></html>",
+		"https://a.example/broken.xml": "<urlset><url><loc>https://a.example/</loc>",
+		"https://a.example/good.xml":   urlset("https://a.example/ok"),
+	}}
+	pages, errs := FetchAll(f, []string{"https://a.example/html.xml", "https://a.example/broken.xml", "https://a.example/good.xml"})
+	if want := []string{"https://a.example/ok"}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "unexpected root element <html>") {
+		t.Errorf("errs = %v, want the html and truncated sitemaps", errs)
+	}
+}
+
+func TestFetchLimits(t *testing.T) {
+	// A chain of nested indexes is followed maxDepth levels deep
+	f := &fakeFetcher{files: make(map[string]string)}
+	for i := 0; i <= maxDepth+2; i++ {
+		f.files[fmt.Sprintf("https://a.example/%d.xml", i)] = strings.Replace(index(fmt.Sprintf("%d.xml", i+1)),
+			"</sitemapindex>", fmt.Sprintf("<url><loc>https://a.example/page%d</loc></url></sitemapindex>", i), 1)
+	}
+	pages, _ := Fetch(f, "https://a.example/0.xml")
+	if len(pages) != maxDepth+1 || pages[maxDepth] != fmt.Sprintf("https://a.example/page%d", maxDepth) {
+		t.Errorf("pages = %v, want those of the first %d levels", pages, maxDepth+1)
+	}
+
+	// An index of many sitemaps is read maxFiles files in
+	var children []string
+	f = &fakeFetcher{files: make(map[string]string)}
+	for i := 0; i < maxFiles*2; i++ {
+		children = append(children, fmt.Sprintf("/s%d.xml", i))
+		f.files[fmt.Sprintf("https://a.example/s%d.xml", i)] = urlset(fmt.Sprintf("https://a.example/p%d", i))
+	}
+	f.files["https://a.example/index.xml"] = index(children...)
+	pages, _ = Fetch(f, "https://a.example/index.xml")
+	if len(f.fetched) != maxFiles || len(pages) != maxFiles-1 {
+		t.Errorf("fetched %d files for %d pages, want %d files", len(f.fetched), len(pages), maxFiles)
+	}
+}
+
+func TestSample(t *testing.T) {
+	pages := []string{
+		"https://a.example/blog/2024/post-b",
+		"https://a.example/blog/2024/post-a",
+		"https://a.example/blog",
+		"https://a.example/blog/archive",
+		"https://a.example/docs/install",
+		"https://a.example/docs",
+		"https://a.example/pricing",
+		"https://a.example/",
+	}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, nil},
+		// Largest section first, each section's landing page before deeper ones
+		{3, []string{"https://a.example/blog", "https://a.example/docs", "https://a.example/"}},
+		{5, []string{"https://a.example/blog", "https://a.example/docs", "https://a.example/", "https://a.example/pricing", "https://a.example/blog/archive"}},
+		{20, []string{
+			"https://a.example/blog", "https://a.example/docs", "https://a.example/", "https://a.example/pricing",
+			"https://a.example/blog/archive", "https://a.example/docs/install",
+			"https://a.example/blog/2024/post-a",
+			"https://a.example/blog/2024/post-b",
+		}},
+	}
+	for _, tt := range tests {
+		if got := Sample(pages, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Sample(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package sourcemap
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// binaryMagic maps file signatures, found at offset after prefix at the
+// start, to the extension a restored binary source is given.
+var binaryMagic = []struct {
+	magic  string
+	offset int
+	ext    string
+	prefix string
+}{
+	{"\x00asm", 0, ".wasm", ""},
+	{"\x89PNG\r\n\x1a\n", 0, ".png", ""},
+	{"GIF87a", 0, ".gif", ""},
+	{"GIF89a", 0, ".gif", ""},
+	{"\xff\xd8\xff", 0, ".jpg", ""},
+	{"WEBP", 8, ".webp", "RIFF"},
+	{"wOFF", 0, ".woff", ""},
+	{"wOF2", 0, ".woff2", ""},
+	{"OTTO", 0, ".otf", ""},
+	{"\x00\x01\x00\x00", 0, ".ttf", ""},
+	{"\x00\x00\x01\x00", 0, ".ico", ""},
+	{"%PDF-", 0, ".pdf", ""},
+	{"PK\x03\x04", 0, ".zip", ""},
+	{"\x1f\x8b", 0, ".gz", ""},
+}
+
+// sniffLength is how much of a source is inspected to decide whether it
+// is binary.
+const sniffLength = 8192
+
+// binarySource decides whether a source is binary and, if so, returns the
+// bytes to write and the extension its signature indicates ("" if
+// unknown). Binary data embedded in sourcesContent survives JSON only as
+// one character per byte, so such content is mapped back to bytes; raw
+// bytes fetched from a server are used as they are.
+func binarySource(content string) ([]byte, string, bool) {
+	data := []byte(content)
+	if utf8.ValidString(content) {
+		if b, ok := latin1Bytes(content); ok {
+			data = b
+		}
+	}
+
+	for _, m := range binaryMagic {
+		if len(data) >= m.offset+len(m.magic) && string(data[m.offset:m.offset+len(m.magic)]) == m.magic &&
+			strings.HasPrefix(string(data), m.prefix) {
+			return data, m.ext, true
+		}
+	}
+	if looksBinary(content) {
+		return data, "", true
+	}
+	return nil, "", false
+}
+
+// latin1Bytes maps a string whose characters are all U+0000 to U+00FF back
+// to one byte per character. It fails for strings with other characters,
+// and for plain ASCII, which needs no mapping.
+func latin1Bytes(s string) ([]byte, bool) {
+	b := make([]byte, 0, len(s))
+	high := false
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, false
+		}
+		if r >= 0x80 {
+			high = true
+		}
+		b = append(b, byte(r))
+	}
+	return b, high
+}
+
+// looksBinary reports whether the start of content is mostly control
+// characters, invalid UTF-8, or replacement characters left by decoding
+// binary data as text.
+func looksBinary(content string) bool {
+	if len(content) > sniffLength {
+		content = content[:sniffLength]
+	}
+	if strings.IndexByte(content, 0) >= 0 {
+		return true
+	}
+
+	total, odd := 0, 0
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		i += size
+		total++
+		switch {
+		case r == utf8.RuneError:
+			odd++
+		case r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f':
+			odd++
+		case r >= 0x7F && r <= 0x9F:
+			odd++
+		}
+	}
+	return total > 0 && odd*10 > total
+}
+
+// binaryPath gives a binary source the extension its signature indicates,
+// appending it unless the path already ends with it. Unrecognized binary
+// content named like text gets ".bin".
+func binaryPath(path, ext string) string {
+	current := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == "" && (current == "" || textExtensions[current]):
+		return path + ".bin"
+	case ext == "" || current == ext || (ext == ".jpg" && current == ".jpeg"):
+		return path
+	}
+	return path + ext
+}
+
+// textExtensions are extensions whose files are expected to hold text.
+var textExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".cjs": true, ".jsx": true,
+	".ts": true, ".mts": true, ".cts": true, ".tsx": true,
+	".vue": true, ".svelte": true, ".css": true, ".scss": true, ".less": true,
+	".html": true, ".json": true, ".md": true, ".txt": true,
+}
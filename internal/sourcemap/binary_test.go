@@ -0,0 +1,101 @@
+package sourcemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// latin1 encodes data one character per byte, as binary data survives in
+// sourcesContent.
+func latin1(data []byte) string {
+	r := make([]rune, len(data))
+	for i, b := range data {
+		r[i] = rune(b)
+	}
+	return string(r)
+}
+
+var (
+	wasmModule = []byte("\x00asm\x01\x00\x00\x00\x01\x07\x01\x60\x02\x7f\x7f\x01\x7f")
+	pngImage   = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+	webpImage  = []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x18\x00\x00\x00\x30\x01\x00\x9d\x01\x2a\x01\x00\x01\x00")
+)
+
+func TestBinarySource(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		binary  bool
+		ext     string
+		want    []byte
+	}{
+		{"wasm as latin1", latin1(wasmModule), true, ".wasm", wasmModule},
+		{"wasm raw", string(wasmModule), true, ".wasm", wasmModule},
+		{"png as latin1", latin1(pngImage), true, ".png", pngImage},
+		{"png raw", string(pngImage), true, ".png", pngImage},
+		{"webp", latin1(webpImage), true, ".webp", webpImage},
+		{"WEBP without RIFF", "// uses WEBP images\nexport const webp = true;\n", false, "", nil},
+		{"text", "export default function App() {}\n", false, "", nil},
+		{"non-latin text", "export const greeting = \"こんにちは\";\n", false, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, ext, ok := binarySource(tt.content)
+			if ok != tt.binary || ext != tt.ext {
+				t.Fatalf("binarySource = %q, %v; want %q, %v", ext, ok, tt.ext, tt.binary)
+			}
+			if tt.want != nil && !bytes.Equal(data, tt.want) {
+				t.Errorf("bytes = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreBinarySources(t *testing.T) {
+	raw, err := json.Marshal(map[string]interface{}{
+		"version":        3,
+		"sources":        []string{"src/add.wasm", "src/logo.png", "src/index.js"},
+		"sourcesContent": []string{latin1(wasmModule), latin1(pngImage), "export {}\n"},
+		"mappings":       "AAAA",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm, err := Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	result := RestoreSources(sm, dir)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	for path, want := range map[string][]byte{"src/add.wasm": wasmModule, "src/logo.png": pngImage} {
+		got, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestBinaryPath(t *testing.T) {
+	tests := []struct{ path, ext, want string }{
+		{"a/add.wasm", ".wasm", "a/add.wasm"},
+		{"a/logo", ".png", "a/logo.png"},
+		{"a/logo.js", ".png", "a/logo.js.png"},
+		{"a/photo.jpeg", ".jpg", "a/photo.jpeg"},
+		{"a/blob.js", "", "a/blob.js.bin"},
+		{"a/blob.dat", "", "a/blob.dat"},
+	}
+	for _, tt := range tests {
+		if got := binaryPath(tt.path, tt.ext); got != tt.want {
+			t.Errorf("binaryPath(%q, %q) = %q, want %q", tt.path, tt.ext, got, tt.want)
+		}
+	}
+}
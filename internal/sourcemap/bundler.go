@@ -0,0 +1,182 @@
+package sourcemap
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Bundler names reported by DetectBundler.
+const (
+	BundlerNext       = "Next.js"
+	BundlerAngular    = "Angular CLI"
+	BundlerMetro      = "Metro"
+	BundlerTurbopack  = "Turbopack"
+	BundlerVite       = "Vite"
+	BundlerParcel     = "Parcel"
+	BundlerBrowserify = "Browserify"
+	BundlerEsbuild    = "esbuild"
+	BundlerRollup     = "Rollup"
+	BundlerWebpack    = "Webpack"
+)
+
+// refines maps a bundler to the one it builds on: a Next.js or Angular CLI
+// map also carries webpack's markers, and a Vite map Rollup's. Once one of
+// its own markers is found, a bundler's score includes the one it refines,
+// so that a Next.js map with many webpack markers is still Next.js.
+var refines = map[string]string{
+	BundlerNext:    BundlerWebpack,
+	BundlerAngular: BundlerWebpack,
+	BundlerVite:    BundlerRollup,
+}
+
+// bundlerOrder breaks ties between bundlers with the same score, most
+// specific first: a Next.js build also looks like webpack, and a Vite
+// build like Rollup.
+var bundlerOrder = []string{
+	BundlerNext, BundlerAngular, BundlerMetro, BundlerTurbopack, BundlerVite,
+	BundlerParcel, BundlerBrowserify, BundlerEsbuild, BundlerRollup, BundlerWebpack,
+}
+
+// Confidence levels of a bundler guess.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Marker weights: a strong marker is only produced by its bundler, a weak
+// one is typical of it but not conclusive.
+const (
+	strongMarker = 3
+	weakMarker   = 1
+)
+
+// sourceMarker identifies a bundler by a substring of a sources[] entry or
+// of sourceRoot.
+type sourceMarker struct {
+	bundler string
+	substr  string
+	weight  int
+}
+
+var sourceMarkers = []sourceMarker{
+	{BundlerWebpack, "webpack://", strongMarker},
+	{BundlerWebpack, "webpack/bootstrap", strongMarker},
+	{BundlerWebpack, "webpack/runtime/", strongMarker},
+	{BundlerNext, "webpack://_N_E/", strongMarker},
+	{BundlerNext, "next/dist/", weakMarker},
+	{BundlerTurbopack, "turbopack://", strongMarker},
+	{BundlerTurbopack, "[turbopack]", strongMarker},
+	{BundlerVite, "\x00vite/", strongMarker},
+	{BundlerVite, "vite/modulepreload-polyfill", strongMarker},
+	{BundlerVite, "/@vite/", strongMarker},
+	{BundlerRollup, "rollup://", strongMarker},
+	{BundlerRollup, "\x00commonjsHelpers", strongMarker},
+	{BundlerRollup, "\x00rollupPluginBabelHelpers", strongMarker},
+	{BundlerRollup, "commonjsHelpers.js", weakMarker},
+	{BundlerEsbuild, "<stdin>", strongMarker},
+	{BundlerEsbuild, "<define:", strongMarker},
+	{BundlerParcel, "@parcel/runtime", strongMarker},
+	{BundlerParcel, "@parcel/", weakMarker},
+	{BundlerAngular, "ng://", strongMarker},
+	{BundlerAngular, "@angular-devkit/", strongMarker},
+	{BundlerAngular, "@angular/core", weakMarker},
+	{BundlerMetro, "__prelude__", strongMarker},
+	{BundlerMetro, "metro-runtime/", strongMarker},
+	{BundlerBrowserify, "browser-pack/_prelude.js", strongMarker},
+}
+
+// BundlerGuess is the bundler that most likely produced a map.
+type BundlerGuess struct {
+	Name       string // One of the Bundler* names, "" if nothing matched
+	Confidence string // ConfidenceHigh, ConfidenceMedium, or ConfidenceLow
+	Minified   bool   // Names are dense enough that a minifier such as terser or uglify ran
+}
+
+// esbuildBannerRe matches the runtime helpers esbuild writes at the top of
+// an unminified bundle, e.g. "var __toESM = (mod, isNodeMode, target) =>".
+var esbuildBannerRe = regexp.MustCompile(`\bvar __(?:toESM|commonJS|esm|toCommonJS|export) = \(`)
+
+// DetectBundler guesses the bundler of the map from markers in its
+// sources, sourceRoot, file name, and Metro fields. The bundler whose
+// markers score highest wins.
+func (sm *SourceMap) DetectBundler() BundlerGuess {
+	return sm.guessBundler(sm.bundlerScores())
+}
+
+// DetectBundlerWithScript is DetectBundler with the start of the script
+// the map belongs to also searched, for bundlers such as esbuild that mark
+// the generated code rather than the map.
+func (sm *SourceMap) DetectBundlerWithScript(script string) BundlerGuess {
+	scores := sm.bundlerScores()
+	if esbuildBannerRe.MatchString(script) {
+		scores[BundlerEsbuild] += strongMarker
+	}
+	return sm.guessBundler(scores)
+}
+
+// guessBundler picks the bundler with the highest score.
+func (sm *SourceMap) guessBundler(scores map[string]int) BundlerGuess {
+	for name, base := range refines {
+		if scores[name] > 0 {
+			scores[name] += scores[base]
+		}
+	}
+	guess := BundlerGuess{Minified: sm.minified()}
+	best := 0
+	for _, name := range bundlerOrder {
+		if scores[name] > best {
+			best = scores[name]
+			guess.Name = name
+		}
+	}
+	switch {
+	case best == 0:
+		return guess
+	case best >= 2*strongMarker:
+		guess.Confidence = ConfidenceHigh
+	case best >= strongMarker:
+		guess.Confidence = ConfidenceMedium
+	default:
+		guess.Confidence = ConfidenceLow
+	}
+	return guess
+}
+
+// bundlerScores adds up the weights of the markers found for each bundler,
+// counting each marker once.
+func (sm *SourceMap) bundlerScores() map[string]int {
+	scores := make(map[string]int)
+	found := make(map[sourceMarker]bool)
+	check := func(s string) {
+		for _, m := range sourceMarkers {
+			if !found[m] && strings.Contains(s, m.substr) {
+				found[m] = true
+				scores[m.bundler] += m.weight
+			}
+		}
+	}
+	check(sm.SourceRoot)
+	for _, src := range sm.Sources {
+		check(src)
+	}
+
+	if strings.Contains(sm.File, "/_next/") || strings.HasPrefix(sm.File, "_next/") {
+		scores[BundlerNext] += weakMarker
+	}
+	if len(sm.XFacebookSources) > 0 || len(sm.XMetroModulePaths) > 0 {
+		scores[BundlerMetro] += strongMarker
+	}
+	return scores
+}
+
+// minified reports whether the map's names are dense relative to its
+// mapping segments, as when a minifier renamed most identifiers.
+// Unminified bundles map few segments to names.
+func (sm *SourceMap) minified() bool {
+	if len(sm.Names) < 10 {
+		return false
+	}
+	segments := strings.Count(sm.Mappings, ",") + strings.Count(sm.Mappings, ";")
+	return segments > 0 && len(sm.Names)*10 >= segments
+}
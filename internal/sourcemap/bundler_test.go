@@ -0,0 +1,144 @@
+package sourcemap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectBundler(t *testing.T) {
+	tests := []struct {
+		name       string
+		sm         SourceMap
+		script     string
+		want       string
+		confidence string
+	}{
+		{
+			name: "webpack",
+			sm: SourceMap{Sources: []string{
+				"webpack://app/webpack/bootstrap", "webpack://app/./src/index.js", "webpack://app/webpack/runtime/define property getters",
+			}},
+			want: BundlerWebpack, confidence: ConfidenceHigh,
+		},
+		{
+			name: "next.js",
+			sm: SourceMap{File: "static/chunks/pages/_app-1a2b.js", Sources: []string{
+				"webpack://_N_E/./pages/_app.js", "webpack://_N_E/webpack/bootstrap", "webpack://_N_E/webpack/runtime/compat",
+				"webpack://_N_E/./node_modules/next/dist/client/index.js",
+			}},
+			want: BundlerNext, confidence: ConfidenceHigh,
+		},
+		{
+			name: "next.js from next/dist alone",
+			sm: SourceMap{Sources: []string{
+				"webpack://app/webpack/bootstrap", "webpack://app/./node_modules/next/dist/shared/lib/router.js",
+			}},
+			want: BundlerNext, confidence: ConfidenceHigh,
+		},
+		{
+			name: "angular cli",
+			sm:   SourceMap{Sources: []string{"webpack:///./src/main.ts", "ng://app/app.component.ts", "webpack:///./node_modules/@angular/core/fesm2015/core.js"}},
+			want: BundlerAngular, confidence: ConfidenceHigh,
+		},
+		{
+			name: "vite",
+			sm:   SourceMap{Sources: []string{"\x00vite/preload-helper", "\x00commonjsHelpers.js", "../../src/main.ts"}},
+			want: BundlerVite, confidence: ConfidenceHigh,
+		},
+		{
+			name: "rollup",
+			sm:   SourceMap{Sources: []string{"\x00rollupPluginBabelHelpers.js", "src/index.js"}},
+			want: BundlerRollup, confidence: ConfidenceMedium,
+		},
+		{
+			name: "turbopack",
+			sm:   SourceMap{Sources: []string{"turbopack://[project]/app/page.tsx", "turbopack://[turbopack]/shared/runtime-utils.ts"}},
+			want: BundlerTurbopack, confidence: ConfidenceHigh,
+		},
+		{
+			name: "esbuild from sources",
+			sm:   SourceMap{Sources: []string{"<stdin>", "src/util.ts"}},
+			want: BundlerEsbuild, confidence: ConfidenceMedium,
+		},
+		{
+			name:   "esbuild from banner",
+			sm:     SourceMap{Sources: []string{"../src/index.ts", "../node_modules/react/index.js"}},
+			script: "\"use strict\";\n(() => {\n  var __create = Object.create;\n  var __commonJS = (cb, mod) => function __require() {\n",
+			want:   BundlerEsbuild, confidence: ConfidenceMedium,
+		},
+		{
+			name: "parcel",
+			sm:   SourceMap{Sources: []string{"node_modules/@parcel/runtime-browser-hmr/lib/runtime.js", "src/index.js"}},
+			want: BundlerParcel, confidence: ConfidenceMedium,
+		},
+		{
+			name: "metro",
+			sm:   SourceMap{Sources: []string{"__prelude__", "node_modules/metro-runtime/src/polyfills/require.js"}},
+			want: BundlerMetro, confidence: ConfidenceHigh,
+		},
+		{
+			name: "browserify",
+			sm:   SourceMap{Sources: []string{"node_modules/browser-pack/_prelude.js", "src/app.js"}},
+			want: BundlerBrowserify, confidence: ConfidenceMedium,
+		},
+		{
+			name: "unknown",
+			sm:   SourceMap{Sources: []string{"src/app.js"}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.sm.DetectBundlerWithScript(tt.script)
+			if got.Name != tt.want || got.Confidence != tt.confidence {
+				t.Errorf("got %s (%s), want %s (%s)", got.Name, got.Confidence, tt.want, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestDetectBundlerMinified(t *testing.T) {
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = "n"
+	}
+	sm := SourceMap{Sources: []string{"src/a.js"}, Names: names, Mappings: "AAAA,CAAC,CAAC;AAAA,CAAC"}
+	if !sm.DetectBundler().Minified {
+		t.Error("dense names not reported as minified")
+	}
+	sm.Mappings = strings.Repeat("AAAA,", 300)
+	if sm.DetectBundler().Minified {
+		t.Error("sparse names reported as minified")
+	}
+}
+
+func TestExtractAssetURL(t *testing.T) {
+	tests := []struct {
+		name, content, bundler, want string
+	}{
+		{"webpack public path", `module.exports = __webpack_public_path__ + "static/media/logo.5d5d9eef.svg";`, BundlerWebpack, "static/media/logo.5d5d9eef.svg"},
+		{"webpack export default", `export default "static/media/logo.5d5d9eef.svg";`, "", "static/media/logo.5d5d9eef.svg"},
+		{"vite import.meta.url", `export default new URL("/assets/logo.8c1f.png", import.meta.url).href`, BundlerVite, "/assets/logo.8c1f.png"},
+		{"esbuild file loader", "var logo_default = \"./logo-QWERTY12.png\";\nexport {\n  logo_default as default\n};\n", BundlerEsbuild, "./logo-QWERTY12.png"},
+		{"parcel bundle url", `module.exports = require("./helpers/bundle-url").getBundleURL("a1b2") + "logo.9f8e.png";`, BundlerParcel, "logo.9f8e.png"},
+		{"no stub", `import React from "react";`, BundlerWebpack, ""},
+		// A Vite stub that also reads as a webpack export default: each
+		// bundler's own form wins
+		{"vite form first", `export default "x.png"; export const u = new URL("assets/y.png", import.meta.url)`, BundlerVite, "assets/y.png"},
+		{"webpack form first", `export default "x.png"; export const u = new URL("assets/y.png", import.meta.url)`, BundlerWebpack, "x.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractAssetURL(tt.content, tt.bundler); got != tt.want {
+				t.Errorf("extractAssetURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAssetURL(t *testing.T) {
+	got, err := resolveAssetURL("https://example.com/app/index.html", "./logo-QWERTY12.png")
+	if err != nil || got != "https://example.com/logo-QWERTY12.png" {
+		t.Errorf("resolveAssetURL = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,85 @@
+package sourcemap
+
+import (
+	"net/url"
+	"strings"
+)
+
+// componentExts are the single-file component formats whose blocks
+// bundlers list as separate sources.
+var componentExts = []string{".vue", ".svelte"}
+
+// componentPath rewrites a source naming one block of a single-file
+// component, such as vue-loader's "App.vue?vue&type=script&lang=ts" or
+// svelte-loader's "App.svelte?svelte&type=style&lang.css", to a file next
+// to the component named for the block: "App.vue.script.ts". Other query
+// suffixes on a component, such as webpack's "App.vue?4a2f" for the
+// compiled module vue-loader lists next to the component's own source, are
+// kept in the name, "App.vue.4a2f.js", so the two don't overwrite each
+// other. Other sources are returned as is.
+func componentPath(source string) string {
+	base, query, ok := strings.Cut(source, "?")
+	if !ok || !isComponent(base) {
+		return source
+	}
+	if query == "" {
+		return base
+	}
+	values, _ := url.ParseQuery(query)
+	block := values.Get("type")
+	switch block {
+	case "script", "template", "style", "custom":
+	default:
+		return base + "." + queryName(query) + ".js"
+	}
+
+	lang := values.Get("lang")
+	for key := range values {
+		// svelte-loader writes lang.css, lang.scss, ...
+		if l, ok := strings.CutPrefix(key, "lang."); ok && lang == "" {
+			lang = l
+		}
+	}
+	if lang == "" {
+		switch block {
+		case "script":
+			lang = "js"
+		case "template":
+			lang = "html"
+		case "style":
+			lang = "css"
+		default:
+			lang = "txt"
+		}
+	}
+
+	name := base + "." + block
+	// A component may have several style blocks
+	if index := values.Get("index"); index != "" && index != "0" {
+		name += "." + index
+	}
+	return name + "." + lang
+}
+
+// isComponent reports whether path names a single-file component.
+func isComponent(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range componentExts {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryName turns a query string into a file name part, replacing
+// anything other than letters, digits, '-', and '_'.
+func queryName(query string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, query)
+}
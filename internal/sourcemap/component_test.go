@@ -0,0 +1,97 @@
+package sourcemap
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestComponentPath(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"src/App.vue", "src/App.vue"},
+		{"src/App.vue?", "src/App.vue"},
+		{"src/App.vue?vue&type=script&lang=ts", "src/App.vue.script.ts"},
+		{"src/App.vue?vue&type=script", "src/App.vue.script.js"},
+		{"src/App.vue?vue&type=template&id=7ba5bd90", "src/App.vue.template.html"},
+		{"src/App.vue?vue&type=style&index=0&lang=scss", "src/App.vue.style.scss"},
+		{"src/App.vue?vue&type=style&index=1&lang=css", "src/App.vue.style.1.css"},
+		{"src/App.vue?vue&type=custom&blockType=i18n", "src/App.vue.custom.txt"},
+		{"src/App.vue?4a2f", "src/App.vue.4a2f.js"},
+		{"src/App.svelte?svelte&type=style&lang.css", "src/App.svelte.style.css"},
+		{"src/app.js?v=2", "src/app.js?v=2"},
+	}
+	for _, tt := range tests {
+		if got := componentPath(tt.source); got != tt.want {
+			t.Errorf("componentPath(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreComponentFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string]string // Restored path to a line of its content
+	}{
+		{"vuecli.js.map", map[string]string{
+			"src/App.vue":               "<template>",
+			"src/App.vue.4a2f.js":       "script.render = render",
+			"src/App.vue.script.ts":     "export default",
+			"src/App.vue.template.html": "export function render",
+			"src/App.vue.style.scss":    "#app",
+			"src/App.vue.style.1.css":   "data-v-7ba5bd90",
+			"src/main.ts":               "createApp",
+		}},
+		{"sveltekit.js.map", map[string]string{
+			"src/routes/+page.svelte":           "<h1>Welcome</h1>",
+			"src/routes/+page.svelte.style.css": "font-size",
+			"src/lib/Counter.svelte":            "let count",
+			"src/lib/Counter.svelte.style.scss": "padding",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			sm, err := ParseFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			result := RestoreSources(sm, dir)
+			if len(result.Errors) > 0 {
+				t.Fatalf("restore errors: %v", result.Errors)
+			}
+
+			var got []string
+			for _, f := range result.Files {
+				got = append(got, f.Path)
+			}
+			sort.Strings(got)
+			var want []string
+			for p := range tt.want {
+				want = append(want, p)
+			}
+			sort.Strings(want)
+			if len(got) != len(want) {
+				t.Fatalf("restored %q, want %q", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("restored %q, want %q", got, want)
+				}
+			}
+
+			for p, line := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, p))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), line) {
+					t.Errorf("%s does not contain %q:\n%s", p, line, data)
+				}
+			}
+		})
+	}
+}
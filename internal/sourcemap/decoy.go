@@ -0,0 +1,136 @@
+package sourcemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"unicode/utf8"
+)
+
+// Decoy is why a downloaded .map file can't be a usable sourcemap, such as
+// the placeholders and encrypted blobs some vendors serve to scrapers.
+type Decoy string
+
+// Kinds of decoy map; "" is a file that isn't one.
+const (
+	DecoyEmpty      Decoy = "empty"       // No content at all
+	DecoyNoSources  Decoy = "no_sources"  // A sourcemap that lists no sources and has no sections
+	DecoyWrongShape Decoy = "wrong_shape" // Valid JSON that isn't a sourcemap, e.g. {"error":"source maps disabled"}
+	DecoyNotice     Decoy = "notice"      // Short plain text, e.g. "Source maps are disabled"
+	DecoyBinary     Decoy = "binary"      // Binary, high-entropy, or base64 data, such as an encrypted blob
+)
+
+const (
+	// maxNoticeBytes is the longest plain text taken for a notice; longer
+	// text is more likely a script or a page served by mistake.
+	maxNoticeBytes = 4096
+
+	// minEntropyBytes is the shortest content judged by its entropy.
+	minEntropyBytes = 256
+
+	// binaryEntropy is the Shannon entropy, in bits per byte, above which
+	// content is taken for compressed or encrypted data. JSON and source
+	// text stay well below it.
+	binaryEntropy = 7.0
+)
+
+// Describe returns a short description of what the decoy holds.
+func (d Decoy) Describe() string {
+	switch d {
+	case DecoyEmpty:
+		return "empty file"
+	case DecoyNoSources:
+		return "sourcemap that lists no sources"
+	case DecoyWrongShape:
+		return "JSON that is not a sourcemap"
+	case DecoyNotice:
+		return "plain-text notice instead of a sourcemap"
+	case DecoyBinary:
+		return "binary or encoded data, possibly encrypted"
+	}
+	return string(d)
+}
+
+// ClassifyDecoy reports whether data, the content of a .map file, is a
+// decoy rather than a sourcemap, and which kind. It returns "" for a
+// usable map and for content that is broken in an ordinary way, such as
+// truncated JSON or an HTML page served for a missing file.
+func ClassifyDecoy(data []byte) Decoy {
+	content := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(content) == 0 {
+		return DecoyEmpty
+	}
+
+	sm, err := Parse(content)
+	switch {
+	case err == nil && len(sm.Sources) == 0 && len(sm.Sections) == 0:
+		return DecoyNoSources
+	case err == nil, errors.Is(err, ErrHTMLResponse):
+		return ""
+	case json.Valid(content):
+		return DecoyWrongShape
+	case isBinary(content):
+		return DecoyBinary
+	case content[0] == '{' || content[0] == '[':
+		// JSON that is cut short or malformed
+		return ""
+	case len(content) <= maxNoticeBytes && utf8.Valid(content):
+		return DecoyNotice
+	}
+	return ""
+}
+
+// isBinary reports whether content looks like binary data rather than
+// text: it has NUL or other control bytes, isn't UTF-8, has the entropy
+// of compressed or encrypted data, or is one long base64 string.
+func isBinary(content []byte) bool {
+	if bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content) {
+		return true
+	}
+	control := 0
+	for _, c := range content {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' {
+			control++
+		}
+	}
+	if control*10 > len(content) {
+		return true
+	}
+	if len(content) < minEntropyBytes {
+		return false
+	}
+	return entropy(content) > binaryEntropy || isBase64(content)
+}
+
+// entropy returns the Shannon entropy of the byte values of data in bits
+// per byte, from 0 to 8.
+func entropy(data []byte) float64 {
+	var counts [256]int
+	for _, c := range data {
+		counts[c]++
+	}
+	var h float64
+	n := float64(len(data))
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// isBase64 reports whether content is base64 or base64url, optionally
+// broken into lines, with no other characters.
+func isBase64(content []byte) bool {
+	for _, c := range content {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '+', c == '/', c == '-', c == '_', c == '=', c == '\n', c == '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package sourcemap
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// randomBytes returns n bytes that look like encrypted data.
+func randomBytes(n int) []byte {
+	var out []byte
+	block := sha256.Sum256([]byte("decoy"))
+	for len(out) < n {
+		out = append(out, block[:]...)
+		block = sha256.Sum256(block[:])
+	}
+	return out[:n]
+}
+
+func TestClassifyDecoy(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Decoy
+	}{
+		{"map", `{"version":3,"sources":["a.js"],"mappings":"AAAA"}`, ""},
+		{"indexed map", `{"version":3,"sections":[{"offset":{"line":0,"column":0},"map":{"version":3,"sources":["a.js"],"mappings":"AAAA"}}]}`, ""},
+		{"empty", "", DecoyEmpty},
+		{"whitespace and BOM", "\ufeff \n\t", DecoyEmpty},
+		{"no sources", `{"version":3,"sources":[],"mappings":""}`, DecoyNoSources},
+		{"wrong shape object", `{"error":"source maps disabled"}`, DecoyWrongShape},
+		{"wrong shape array", `["a.js","b.js"]`, DecoyWrongShape},
+		{"notice", "Source maps are disabled for this site.\n", DecoyNotice},
+		{"encrypted", string(randomBytes(1024)), DecoyBinary},
+		{"base64", base64.StdEncoding.EncodeToString(randomBytes(1024)), DecoyBinary},
+		{"control bytes", "ab\x01\x02\x03\x04\x05\x06\x07\x08cd", DecoyBinary},
+		{"truncated JSON", `{"version":3,"sources":["a.js"],"mappi`, ""},
+		{"HTML", "<!DOCTYPE html><html><body>Not found</body></html>", ""},
+		{"long text", strings.Repeat("console.log('not a map');\n", 200), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyDecoy([]byte(tt.data)); got != tt.want {
+				t.Errorf("ClassifyDecoy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package sourcemap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxEmbeddedScan is how much of a script is searched for embedded
+	// maps; the rest of a larger script is ignored.
+	maxEmbeddedScan = 64 << 20
+
+	// minEmbeddedMap is the shortest string literal taken for a map. A
+	// real map with sourcesContent is far longer.
+	minEmbeddedMap = 64
+
+	// maxEmbeddedMaps caps the maps taken from one script.
+	maxEmbeddedMaps = 32
+)
+
+// embeddedMapStartRe matches the opening quote of a string literal holding
+// a JSON object whose first key is a sourcemap key, escaped or not, e.g.
+// "{\"version\":3 or '{"mappings":.
+var embeddedMapStartRe = regexp.MustCompile("[\"'`]\\s*\\{\\s*\\\\?\"(?:version|mappings|sources|sourcesContent|names|file|sourceRoot|sections)\\\\?\"\\s*:")
+
+// EmbeddedSourceMap is a sourcemap found in a script as a JSON string
+// literal.
+type EmbeddedSourceMap struct {
+	*SourceMap
+	Key string // Hex SHA-256 of the map JSON, the same wherever the map is embedded
+}
+
+// HasEmbeddedSourceMap reports whether JS content may contain a sourcemap
+// as a JSON string literal, e.g. var __SOURCEMAP__ = "{\"version\":3,...}"
+// or window.__sourcemaps__["app"] = '{"version":3,...}'. It is a cheap
+// check; ExtractEmbeddedSourceMaps decides.
+func HasEmbeddedSourceMap(jsContent string) bool {
+	if len(jsContent) > maxEmbeddedScan {
+		jsContent = jsContent[:maxEmbeddedScan]
+	}
+	return strings.Contains(jsContent, "sourcesContent") &&
+		strings.Contains(jsContent, "mappings") &&
+		embeddedMapStartRe.MatchString(jsContent)
+}
+
+// ExtractEmbeddedSourceMaps returns the sourcemaps embedded in JS content as
+// JSON string literals. A literal is a candidate when it starts like a
+// JSON object with a sourcemap key and contains both "mappings" and
+// "sourcesContent"; candidates that don't unescape and parse are skipped,
+// since the scan is a heuristic. Identical maps are returned once.
+func ExtractEmbeddedSourceMaps(jsContent string) []EmbeddedSourceMap {
+	if len(jsContent) > maxEmbeddedScan {
+		jsContent = jsContent[:maxEmbeddedScan]
+	}
+	if !strings.Contains(jsContent, "sourcesContent") || !strings.Contains(jsContent, "mappings") {
+		return nil
+	}
+
+	var maps []EmbeddedSourceMap
+	seen := make(map[string]bool)
+	for pos := 0; pos < len(jsContent) && len(maps) < maxEmbeddedMaps; {
+		loc := embeddedMapStartRe.FindStringIndex(jsContent[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		literal, end := stringLiteral(jsContent, start)
+		if end < 0 {
+			// The quote wasn't the start of a literal after all
+			pos = start + 1
+			continue
+		}
+		pos = end
+
+		if len(literal) < minEmbeddedMap ||
+			!strings.Contains(literal, "mappings") || !strings.Contains(literal, "sourcesContent") {
+			continue
+		}
+		if jsContent[start] == '`' && hasSubstitution(literal) {
+			continue // Not a constant
+		}
+		text, err := unescapeJS(literal)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256([]byte(text))
+		key := hex.EncodeToString(sum[:])
+		if seen[key] {
+			continue
+		}
+		sm, err := Parse([]byte(text))
+		if err != nil || sm.Mappings == "" && len(sm.Sections) == 0 {
+			continue
+		}
+		seen[key] = true
+		maps = append(maps, EmbeddedSourceMap{SourceMap: sm, Key: key})
+	}
+	return maps
+}
+
+// stringLiteral returns the body of the JS string literal whose opening
+// quote is at s[start], still escaped, and the index just past its closing
+// quote, or -1 if it isn't closed.
+func stringLiteral(s string, start int) (string, int) {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return s[start+1 : i], i + 1
+		case '\n':
+			if quote != '`' {
+				return "", -1
+			}
+		}
+	}
+	return "", -1
+}
+
+// hasSubstitution reports whether the body of a template literal has an
+// unescaped ${...} substitution.
+func hasSubstitution(s string) bool {
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '$' && s[i+1] == '{':
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeJS decodes the escape sequences of a JS string literal body.
+func unescapeJS(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+		switch c = s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case '\n':
+			// Line continuation
+		case 'x':
+			if i+2 >= len(s) {
+				return "", fmt.Errorf("short \\x escape")
+			}
+			n, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\x escape")
+			}
+			b.WriteRune(rune(n))
+			i += 2
+		case 'u':
+			r, n, err := unicodeEscape(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+			i += n
+		default:
+			// \" \' \\ \/ and any other character stand for themselves
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// unicodeEscape decodes the hex digits after \u, either XXXX or {X...},
+// combining a surrogate pair written as two escapes. It returns the rune
+// and how many bytes of s it used.
+func unicodeEscape(s string) (rune, int, error) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, 0, fmt.Errorf("unterminated \\u{ escape")
+		}
+		n, err := strconv.ParseUint(s[1:end], 16, 32)
+		if err != nil || n > utf8.MaxRune {
+			return 0, 0, fmt.Errorf("invalid \\u{ escape")
+		}
+		return rune(n), end + 1, nil
+	}
+	if len(s) < 4 {
+		return 0, 0, fmt.Errorf("short \\u escape")
+	}
+	n, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid \\u escape")
+	}
+	r := rune(n)
+	// A high surrogate followed by \uDC00-\uDFFF is one character
+	if r >= 0xD800 && r < 0xDC00 && len(s) >= 10 && s[4:6] == `\u` {
+		if low, err := strconv.ParseUint(s[6:10], 16, 16); err == nil && low >= 0xDC00 && low < 0xE000 {
+			return (r-0xD800)<<10 + (rune(low) - 0xDC00) + 0x10000, 10, nil
+		}
+	}
+	return r, 4, nil
+}
@@ -0,0 +1,77 @@
+package sourcemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractEmbeddedSourceMaps(t *testing.T) {
+	tests := []struct {
+		file    string
+		source  string
+		content string
+	}{
+		// var __SOURCEMAP__ = "...", with \x, \u{} and surrogate pair escapes
+		{"embedded_var.js", "src/main.js", "const s = '\U0001F600 \U0001F600';\n"},
+		// window.__sourcemaps__["app"] = '...', embedded twice
+		{"embedded_window.js", "src/app.js", "export const x = 'café';\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !HasEmbeddedSourceMap(string(data)) {
+				t.Error("HasEmbeddedSourceMap = false")
+			}
+			maps := ExtractEmbeddedSourceMaps(string(data))
+			if len(maps) != 1 {
+				t.Fatalf("found %d maps, want 1", len(maps))
+			}
+			sm := maps[0]
+			if len(sm.Sources) != 1 || sm.Sources[0] != tt.source {
+				t.Errorf("Sources = %q, want [%q]", sm.Sources, tt.source)
+			}
+			if len(sm.SourcesContent) != 1 || sm.SourcesContent[0] != tt.content {
+				t.Errorf("SourcesContent = %q, want [%q]", sm.SourcesContent, tt.content)
+			}
+		})
+	}
+}
+
+func TestUnescapeJS(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{`plain`, "plain", false},
+		{`\"q\" \'s\' \\ \/`, `"q" 's' \ /`, false},
+		{`a\nb\tc\0`, "a\nb\tc\x00", false},
+		{"line\\\ncontinued", "linecontinued", false},
+		{`\x41\x7e`, "A~", false},
+		{`caf\xe9`, "café", false},
+		{`\u00e9\u4e2d`, "é中", false},
+		{`\u{1F600}\u{41}`, "\U0001F600A", false},
+		{`\uD83D\uDE00`, "\U0001F600", false},
+		{`\uD83Dx`, "\uFFFDx", false},
+		{`\x4`, "", true},
+		{`\xZZ`, "", true},
+		{`\u12`, "", true},
+		{`\u{1F600`, "", true},
+		{`\u{110000}`, "", true},
+		{`trailing\`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := unescapeJS(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unescapeJS(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("unescapeJS(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
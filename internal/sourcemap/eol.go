@@ -0,0 +1,67 @@
+package sourcemap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line ending styles for RestoreOptions.EOL.
+const (
+	EOLKeep = ""     // Write sources as the map has them
+	EOLLF   = "lf"   // Unix line endings
+	EOLCRLF = "crlf" // Windows line endings
+)
+
+// textEdits records how normalizeText changed a source, why writeFile had
+// to leave it unformatted, if it did, and how much it wrote.
+type textEdits struct {
+	bomStripped  bool
+	eolConverted bool
+	formatErr    error
+	written      int64 // Bytes written, after normalizing and formatting
+}
+
+// normalizeText strips a leading UTF-8 byte order mark and rewrites every
+// line ending (CRLF, LF, or a lone CR) to eol. EOLKeep leaves content
+// untouched.
+func normalizeText(content, eol string) (string, textEdits) {
+	var edits textEdits
+	if eol == EOLKeep {
+		return content, edits
+	}
+
+	if trimmed := strings.TrimPrefix(content, "\ufeff"); trimmed != content {
+		content = trimmed
+		edits.bomStripped = true
+	}
+
+	out := convertEOL(content, eol)
+	edits.eolConverted = out != content
+	return out, edits
+}
+
+// convertEOL rewrites every line ending in s to eol.
+func convertEOL(s, eol string) string {
+	if !strings.ContainsRune(s, '\r') && eol == EOLLF {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	if eol == EOLCRLF {
+		s = strings.ReplaceAll(s, "\n", "\r\n")
+	}
+	return s
+}
+
+// ParseEOL returns the line ending style named keep, lf, or crlf.
+func ParseEOL(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "keep":
+		return EOLKeep, nil
+	case EOLLF:
+		return EOLLF, nil
+	case EOLCRLF:
+		return EOLCRLF, nil
+	}
+	return "", fmt.Errorf("unknown line ending %q (valid: keep, lf, crlf)", name)
+}
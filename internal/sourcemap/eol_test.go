@@ -0,0 +1,140 @@
+package sourcemap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	const mixed = "\ufeffa\r\nb\nc\rd\r\n"
+	tests := []struct {
+		name    string
+		content string
+		eol     string
+		want    string
+		bom     bool
+		changed bool
+	}{
+		{"keep", mixed, EOLKeep, mixed, false, false},
+		{"lf", mixed, EOLLF, "a\nb\nc\nd\n", true, true},
+		{"crlf", mixed, EOLCRLF, "a\r\nb\r\nc\r\nd\r\n", true, true},
+		{"lf already", "a\nb\n", EOLLF, "a\nb\n", false, false},
+		{"crlf already", "a\r\nb\r\n", EOLCRLF, "a\r\nb\r\n", false, false},
+		{"bom only", "\ufeffa\n", EOLLF, "a\n", true, false},
+		{"bom not leading", "a\ufeff\n", EOLLF, "a\ufeff\n", false, false},
+		{"lone cr", "a\rb", EOLLF, "a\nb", false, true},
+		{"cr before crlf", "a\r\r\nb", EOLCRLF, "a\r\n\r\nb", false, true},
+		{"empty", "", EOLCRLF, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, edits := normalizeText(tt.content, tt.eol)
+			if got != tt.want {
+				t.Errorf("normalizeText = %q, want %q", got, tt.want)
+			}
+			if edits.bomStripped != tt.bom || edits.eolConverted != tt.changed {
+				t.Errorf("edits = %+v, want bomStripped %v, eolConverted %v", edits, tt.bom, tt.changed)
+			}
+		})
+	}
+}
+
+func TestParseEOL(t *testing.T) {
+	for name, want := range map[string]string{"": EOLKeep, "keep": EOLKeep, "lf": EOLLF, "LF": EOLLF, "crlf": EOLCRLF} {
+		if got, err := ParseEOL(name); err != nil || got != want {
+			t.Errorf("ParseEOL(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseEOL("cr"); err == nil {
+		t.Error("ParseEOL(cr) succeeded")
+	}
+}
+
+// lineEndings counts the CRLF, lone LF, and lone CR endings in s.
+func lineEndings(s string) (crlf, lf, cr int) {
+	crlf = strings.Count(s, "\r\n")
+	lf = strings.Count(s, "\n") - crlf
+	cr = strings.Count(s, "\r") - crlf
+	return crlf, lf, cr
+}
+
+func TestRestoreMixedEOL(t *testing.T) {
+	sm := &SourceMap{
+		Version: 3,
+		Sources: []string{"src/app.js", "src/style.css", "src/unix.js", "src/logo.png"},
+		SourcesContent: []string{
+			"\ufefffunction a() {\r\n  return 1;\n}\rfunction b() {\r\n  return 2;\n}\n",
+			"body {\r\n  margin: 0;\n}\r\n",
+			"var x = 1;\nvar y = 2;\n",
+			"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\n\r\x00",
+		},
+	}
+
+	for _, eol := range []string{EOLKeep, EOLLF, EOLCRLF} {
+		t.Run("eol="+eol, func(t *testing.T) {
+			dir := t.TempDir()
+			result := RestoreSourcesWithOptions(sm, dir, &RestoreOptions{EOL: eol})
+			if len(result.Errors) > 0 {
+				t.Fatal(result.Errors)
+			}
+			files := make(map[string]int)
+			for i, f := range result.Files {
+				files[f.Path] = i
+			}
+
+			for _, path := range []string{"src/app.js", "src/style.css", "src/unix.js"} {
+				data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				// The manifest records what was written, not what the map had
+				if got := result.Files[files[path]].Bytes; got != int64(len(data)) {
+					t.Errorf("%s: manifest Bytes = %d, file has %d", path, got, len(data))
+				}
+				crlf, lf, cr := lineEndings(string(data))
+				switch eol {
+				case EOLLF:
+					if crlf+cr > 0 {
+						t.Errorf("%s has %d CRLF and %d CR endings", path, crlf, cr)
+					}
+				case EOLCRLF:
+					if lf+cr > 0 {
+						t.Errorf("%s has %d LF and %d CR endings", path, lf, cr)
+					}
+				}
+				if eol != EOLKeep && strings.HasPrefix(string(data), "\ufeff") {
+					t.Errorf("%s kept its BOM", path)
+				}
+			}
+
+			// Text that was not formatted is written as the map has it
+			if eol == EOLKeep {
+				data, _ := os.ReadFile(filepath.Join(dir, "src", "style.css"))
+				if string(data) != sm.SourcesContent[1] {
+					t.Errorf("style.css = %q", data)
+				}
+			}
+
+			app := result.Files[files["src/app.js"]]
+			css := result.Files[files["src/style.css"]]
+			unix := result.Files[files["src/unix.js"]]
+			if app.BOMStripped != (eol != EOLKeep) || app.EOLConverted != eol || css.EOLConverted != eol {
+				t.Errorf("manifest: app.js %+v, style.css %+v", app, css)
+			}
+			if want := map[string]string{EOLCRLF: EOLCRLF}[eol]; unix.EOLConverted != want || unix.BOMStripped {
+				t.Errorf("manifest: unix.js %+v, want EOLConverted %q", unix, want)
+			}
+
+			// Binary sources are never rewritten
+			data, err := os.ReadFile(filepath.Join(dir, "src", "logo.png"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != sm.SourcesContent[3] {
+				t.Errorf("logo.png was rewritten: %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package sourcemap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mapping is one decoded segment of a sourcemap's mappings. Lines and
+// columns are 0-based; columns count UTF-16 code units, as in the spec.
+type Mapping struct {
+	GeneratedLine   int
+	GeneratedColumn int
+	Source          int // Index into Sources, -1 for a segment with no source
+	OriginalLine    int
+	OriginalColumn  int
+	Name            int // Index into Names, -1 for a segment that names nothing
+}
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+var base64Values = func() [256]int {
+	var v [256]int
+	for i := range v {
+		v[i] = -1
+	}
+	for i := 0; i < len(base64Chars); i++ {
+		v[base64Chars[i]] = i
+	}
+	return v
+}()
+
+// DecodeMappings decodes the Base64 VLQ mappings string of a sourcemap.
+// Segments are returned in generated order.
+func (sm *SourceMap) DecodeMappings() ([]Mapping, error) {
+	var out []Mapping
+	source, origLine, origCol, name := 0, 0, 0, 0
+
+	for line, group := range strings.Split(sm.Mappings, ";") {
+		col := 0
+		if group == "" {
+			continue
+		}
+		for _, seg := range strings.Split(group, ",") {
+			if seg == "" {
+				continue
+			}
+			fields, err := decodeVLQ(seg)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line+1, err)
+			}
+			col += fields[0]
+			m := Mapping{GeneratedLine: line, GeneratedColumn: col, Source: -1, Name: -1}
+			if len(fields) >= 4 {
+				source += fields[1]
+				origLine += fields[2]
+				origCol += fields[3]
+				m.Source, m.OriginalLine, m.OriginalColumn = source, origLine, origCol
+			}
+			if len(fields) >= 5 {
+				name += fields[4]
+				m.Name = name
+			}
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// decodeVLQ decodes the signed values of one mappings segment.
+func decodeVLQ(seg string) ([]int, error) {
+	var fields []int
+	value, shift := 0, 0
+	for i := 0; i < len(seg); i++ {
+		digit := base64Values[seg[i]]
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base64 character %q in mappings", seg[i])
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			fields = append(fields, -(value >> 1))
+		} else {
+			fields = append(fields, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated VLQ value in mappings")
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty mappings segment")
+	}
+	return fields, nil
+}
@@ -0,0 +1,150 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FacebookSources is Metro's x_facebook_sources: one entry per sources[]
+// entry, in the same order.
+type FacebookSources []FacebookSource
+
+// FacebookSource is the metadata Metro recorded for one source. In the map
+// it is null or a tuple whose first element is the function map; later
+// elements are ignored.
+type FacebookSource struct {
+	FunctionMap *FunctionMap // nil when Metro recorded none
+}
+
+// FunctionMap names the functions of a source and maps them to its
+// generated code, for symbolicated stack traces.
+type FunctionMap struct {
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// UnmarshalJSON reads the tuple form. Entries of an unexpected shape are
+// left empty rather than failing the whole map.
+func (s *FacebookSource) UnmarshalJSON(data []byte) error {
+	var tuple []json.RawMessage
+	if json.Unmarshal(data, &tuple) != nil || len(tuple) == 0 {
+		return nil
+	}
+	var fm FunctionMap
+	if json.Unmarshal(tuple[0], &fm) == nil && (len(fm.Names) > 0 || fm.Mappings != "") {
+		s.FunctionMap = &fm
+	}
+	return nil
+}
+
+// MarshalJSON writes the tuple form back.
+func (s FacebookSource) MarshalJSON() ([]byte, error) {
+	if s.FunctionMap == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal([]*FunctionMap{s.FunctionMap})
+}
+
+// segments counts the mapping segments of the function map.
+func (fm *FunctionMap) segments() int {
+	n := 0
+	for _, line := range strings.Split(fm.Mappings, ";") {
+		for _, seg := range strings.Split(line, ",") {
+			if seg != "" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// MetroMetadata summarizes the Metro-specific fields of a map.
+type MetroMetadata struct {
+	FunctionMaps int      `json:"function_maps"`          // Sources with a function map
+	Segments     int      `json:"segments"`               // Mapping segments across all function maps
+	ModuleIDs    int      `json:"module_ids,omitempty"`   // sources[] entries that are bare numeric module ids
+	ModuleNames  []string `json:"module_names,omitempty"` // A sample of the module names found
+}
+
+// moduleNamesShown caps the sample of module names in MetroMetadata.
+const moduleNamesShown = 5
+
+// metroMetadata returns the Metro metadata of the map, or nil if it has
+// none.
+func (sm *SourceMap) metroMetadata() *MetroMetadata {
+	if len(sm.XFacebookSources) == 0 && len(sm.XMetroModulePaths) == 0 {
+		return nil
+	}
+	meta := &MetroMetadata{}
+	for _, s := range sm.XFacebookSources {
+		if s.FunctionMap != nil {
+			meta.FunctionMaps++
+			meta.Segments += s.FunctionMap.segments()
+		}
+	}
+	for i, source := range sm.Sources {
+		if _, ok := moduleID(source); ok {
+			meta.ModuleIDs++
+		}
+		if len(meta.ModuleNames) < moduleNamesShown {
+			if name := sm.ModuleName(i); name != "" {
+				meta.ModuleNames = append(meta.ModuleNames, name)
+			}
+		}
+	}
+	return meta
+}
+
+// ModuleName returns Metro's name for the module of sources[i]: its
+// x_metro_module_paths entry, or else the first named function of its
+// function map, usually the module's component. It is "" when the map
+// has neither.
+func (sm *SourceMap) ModuleName(i int) string {
+	if i < len(sm.XMetroModulePaths) && sm.XMetroModulePaths[i] != "" {
+		return sm.XMetroModulePaths[i]
+	}
+	if i >= len(sm.XFacebookSources) || sm.XFacebookSources[i].FunctionMap == nil {
+		return ""
+	}
+	for _, name := range sm.XFacebookSources[i].FunctionMap.Names {
+		if name != "" && !strings.HasPrefix(name, "<") {
+			return name
+		}
+	}
+	return ""
+}
+
+// moduleID reports whether a sources[] entry is a bare numeric module id
+// such as "42" or "42.js", as React Native web exports often have, and
+// returns the id.
+func moduleID(source string) (string, bool) {
+	id := strings.TrimSuffix(strings.TrimPrefix(source, "/"), ".js")
+	if id == "" {
+		return "", false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	return id, true
+}
+
+// metroPath returns the output path of sources[i] when its entry is a bare
+// module id and Metro named the module: the module path itself, or the id
+// followed by the name, e.g. 42_LoginScreen.js. It is "" otherwise.
+func (sm *SourceMap) metroPath(i int) string {
+	id, ok := moduleID(sm.Sources[i])
+	if !ok {
+		return ""
+	}
+	name := sm.ModuleName(i)
+	if name == "" {
+		return ""
+	}
+	if strings.Contains(name, "/") {
+		return sanitizePath(name)
+	}
+	return sanitizePath(fmt.Sprintf("%s_%s.js", id, name))
+}
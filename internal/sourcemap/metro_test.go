@@ -0,0 +1,81 @@
+package sourcemap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testdata/metro.js.map is laid out like the map of a React Native web
+// export: Metro's prelude and runtime, then modules listed by numeric id
+// with x_facebook_sources function maps and x_metro_module_paths.
+
+func TestMetroFixture(t *testing.T) {
+	sm, err := ParseFile(filepath.Join("testdata", "metro.js.map"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if guess := sm.DetectBundler(); guess.Name != BundlerMetro || guess.Confidence != ConfidenceHigh {
+		t.Errorf("DetectBundler = %+v, want Metro with high confidence", guess)
+	}
+
+	meta := sm.ExtractMetadata().Metro
+	want := &MetroMetadata{
+		FunctionMaps: 3,
+		Segments:     8,
+		ModuleIDs:    3,
+		ModuleNames:  []string{"define", "src/App.js", "LoginScreen"},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("Metro metadata = %+v, want %+v", meta, want)
+	}
+
+	names := []string{"", "define", "src/App.js", "LoginScreen", ""}
+	for i, name := range names {
+		if got := sm.ModuleName(i); got != name {
+			t.Errorf("ModuleName(%d) = %q, want %q", i, got, name)
+		}
+	}
+
+	dir := t.TempDir()
+	result := RestoreSources(sm, dir)
+	if result.RestoredCount != len(sm.Sources) {
+		t.Errorf("RestoredCount = %d, want %d", result.RestoredCount, len(sm.Sources))
+	}
+	for _, path := range []string{
+		"__prelude__",
+		"node_modules/metro-runtime/src/polyfills/require.js",
+		"src/App.js",        // Named by x_metro_module_paths
+		"42_LoginScreen.js", // Named after its function map
+		"57",                // Nothing names it
+	} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path))); err != nil {
+			t.Errorf("%s not restored: %v", path, err)
+		}
+	}
+}
+
+func TestFacebookSourceJSON(t *testing.T) {
+	sm, err := Parse([]byte(`{"version":3,"sources":["a","b","c","d"],"mappings":"",
+		"x_facebook_sources":[null,[{"names":["global","f"],"mappings":"AAA"}],"unexpected",[]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.XFacebookSources) != 4 {
+		t.Fatalf("XFacebookSources has %d entries, want 4", len(sm.XFacebookSources))
+	}
+	for i, s := range sm.XFacebookSources {
+		if got := s.FunctionMap != nil; got != (i == 1) {
+			t.Errorf("entry %d has a function map: %v", i, got)
+		}
+	}
+	data, err := sm.XFacebookSources[1].MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"names":["global","f"],"mappings":"AAA"}]` {
+		t.Errorf("MarshalJSON = %s", data)
+	}
+}
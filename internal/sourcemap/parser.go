@@ -1,59 +1,307 @@
 package sourcemap
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
 )
 
+// ErrHTMLResponse is returned by Parse when the data is an HTML page rather
+// than a sourcemap.
+var ErrHTMLResponse = errors.New("response is HTML, not a sourcemap")
+
 var (
-	// Matches //# sourceMappingURL=... or //@ sourceMappingURL=...
-	sourceMappingURLRe = regexp.MustCompile(`//[#@]\s*sourceMappingURL\s*=\s*([^\s]+)`)
+	// Matches //# sourceMappingURL=..., //@ sourceMappingURL=... and the
+	// /*# sourceMappingURL=... */ form
+	sourceMappingURLRe = regexp.MustCompile(`(?://|/\*)[#@]\s*sourceMappingURL\s*=\s*([^\s]+)`)
+
+	// Matches a following //# sourceURL=... or similar comment run into the
+	// same line as the sourceMappingURL
+	trailingCommentRe = regexp.MustCompile(`(?://|/\*)[#@]`)
 
 	// Matches inline base64 sourcemaps
 	inlineSourceMapRe = regexp.MustCompile(`sourceMappingURL\s*=\s*data:application/json[^,]*;base64,([a-zA-Z0-9+/=]+)`)
+
+	// Matches the start of a JSONP response: an optional /**/ guard and a
+	// callback name, possibly dotted, applied to an object
+	jsonpRe = regexp.MustCompile(`^(?:/\*\*/\s*)?([A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)*)\s*\(\s*\{`)
 )
 
-// ParseFile reads and parses a sourcemap from a file path.
+// xssiGuards are the prefixes servers put before JSON so it can't be run as
+// a script. ")]}" may be followed by "'" and ",", which are skipped with it.
+var xssiGuards = []string{")]}", "while(1);", "while (1);", "for(;;);", "for (;;);", "&&&START&&&"}
+
+// xssiTrailers are the suffixes that close an XSSI guard, by guard.
+var xssiTrailers = map[string]string{"&&&START&&&": "&&&END&&&"}
+
+// ParseFile reads and parses a sourcemap from a file path. The file is
+// streamed, so only the decoded sourcemap is held in memory.
 func ParseFile(path string) (*SourceMap, error) {
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read sourcemap file: %w", err)
 	}
+	defer f.Close()
 
-	return Parse(data)
+	return ParseReader(f)
 }
 
-// Parse parses sourcemap JSON data.
+// Parse parses sourcemap JSON data. An XSSI guard prefix such as ")]}'" or
+// "while(1);" and a JSONP callback wrapped around the object are stripped
+// and noted in Wrapper. HTML pages (typically an SPA fallback served for a
+// missing .map) are rejected with ErrHTMLResponse, and other content that
+// isn't a JSON object with a "not a sourcemap" error.
 func Parse(data []byte) (*SourceMap, error) {
+	return ParseReader(bytes.NewReader(data))
+}
+
+// ParseReader behaves like Parse but reads the sourcemap from r. Fields are
+// decoded one at a time and sources and sourcesContent one entry at a
+// time, so beyond the result only the largest single entry is buffered.
+// Legacy version 1 maps yield only their sources list; see Issues.
+func ParseReader(r io.Reader) (*SourceMap, error) {
+	br := bufio.NewReader(r)
+	guard, err := skipPreamble(br)
+	if err != nil {
+		return nil, err
+	}
+	if header, _ := br.Peek(len(lineMapsHeader)); string(header) == lineMapsHeader {
+		return parseLineMaps(br)
+	}
+	callback := skipCallback(br)
+	if next, _ := br.Peek(1); string(next) != "{" {
+		return nil, notJSON(br)
+	}
+
 	var sm SourceMap
-	if err := json.Unmarshal(data, &sm); err != nil {
+	dec := json.NewDecoder(br)
+	if err := decodeSourceMap(dec, &sm); err != nil {
 		return nil, fmt.Errorf("failed to parse sourcemap JSON: %w", err)
 	}
+	if err := checkTrailer(io.MultiReader(dec.Buffered(), br), callback != "", xssiTrailers[guard]); err != nil {
+		return nil, fmt.Errorf("failed to parse sourcemap JSON: %w", err)
+	}
+	switch {
+	case guard != "" && callback != "":
+		sm.Wrapper = fmt.Sprintf("XSSI guard %q and JSONP callback %s()", guard, callback)
+	case guard != "":
+		sm.Wrapper = fmt.Sprintf("XSSI guard %q", guard)
+	case callback != "":
+		sm.Wrapper = fmt.Sprintf("JSONP callback %s()", callback)
+	}
+
+	if sm.Version == 0 && len(sm.Sources) == 0 && len(sm.Sections) == 0 {
+		return nil, fmt.Errorf("not a sourcemap: missing version and sources")
+	}
 
 	return &sm, nil
 }
 
+// skipPreamble consumes a byte order mark, leading whitespace, and an XSSI
+// guard, and rejects HTML. It returns the guard that was skipped, if any.
+func skipPreamble(br *bufio.Reader) (string, error) {
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+	skipSpace(br)
+	var skipped string
+	for _, guard := range xssiGuards {
+		if prefix, _ := br.Peek(len(guard)); string(prefix) != guard {
+			continue
+		}
+		br.Discard(len(guard))
+		skipped = guard
+		if guard == ")]}" {
+			// )]}' and )]}', whether the map follows on the next line or not
+			for _, c := range []string{"'", ","} {
+				if next, _ := br.Peek(1); string(next) == c {
+					br.Discard(1)
+					skipped += c
+				}
+			}
+		}
+		skipSpace(br)
+		break
+	}
+	if next, _ := br.Peek(1); string(next) == "<" {
+		return "", ErrHTMLResponse
+	}
+	return skipped, nil
+}
+
+// skipCallback consumes the start of a JSONP wrapper, up to the "(" before
+// the object, and returns the callback name, or "" if there is none.
+func skipCallback(br *bufio.Reader) string {
+	head, _ := br.Peek(256)
+	m := jsonpRe.FindSubmatchIndex(head)
+	if m == nil {
+		return ""
+	}
+	// Leave the "{" for the decoder
+	br.Discard(m[1] - 1)
+	return string(head[m[2]:m[3]])
+}
+
+// checkTrailer reads what follows the sourcemap object, which must be
+// whitespace, or for a JSONP response the ")" closing the callback and an
+// optional ";", followed by guardEnd, the end of the XSSI guard, if any.
+func checkTrailer(r io.Reader, callback bool, guardEnd string) error {
+	rest, err := io.ReadAll(io.LimitReader(r, 64))
+	if err != nil {
+		return err
+	}
+	rest = bytes.TrimSpace(rest)
+	if guardEnd != "" {
+		if !bytes.HasSuffix(rest, []byte(guardEnd)) {
+			return fmt.Errorf("XSSI guard is not closed by %s", guardEnd)
+		}
+		rest = bytes.TrimSpace(bytes.TrimSuffix(rest, []byte(guardEnd)))
+	}
+	if callback {
+		rest = bytes.TrimSpace(bytes.TrimSuffix(rest, []byte(";")))
+		if !bytes.Equal(rest, []byte(")")) {
+			return fmt.Errorf("JSONP callback is not closed after the sourcemap object")
+		}
+		return nil
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected data after the sourcemap object")
+	}
+	return nil
+}
+
+// notJSON describes content that doesn't start with a JSON object, quoting
+// the start of its first line.
+func notJSON(br *bufio.Reader) error {
+	head, _ := br.Peek(40)
+	if len(head) == 0 {
+		return fmt.Errorf("not a sourcemap: the content is empty")
+	}
+	if i := bytes.IndexAny(head, "\r\n"); i >= 0 {
+		head = head[:i]
+	}
+	return fmt.Errorf("not a sourcemap: expected a JSON object, found %q", head)
+}
+
+// skipSpace consumes leading JSON whitespace.
+func skipSpace(br *bufio.Reader) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			br.UnreadByte()
+			return
+		}
+	}
+}
+
+// decodeSourceMap decodes a sourcemap object field by field. Keys match
+// case-insensitively and unknown fields are skipped, as with json.Unmarshal.
+func decodeSourceMap(dec *json.Decoder, sm *SourceMap) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+
+		switch strings.ToLower(key) {
+		case "version":
+			err = dec.Decode(&sm.Version)
+		case "file":
+			err = dec.Decode(&sm.File)
+		case "sourceroot":
+			err = dec.Decode(&sm.SourceRoot)
+		case "sources":
+			sm.Sources, err = decodeStrings(dec)
+		case "sourcescontent":
+			sm.SourcesContent, err = decodeStrings(dec)
+		case "names":
+			sm.Names, err = decodeStrings(dec)
+		case "mappings":
+			err = dec.Decode(&sm.Mappings)
+		case "x_facebook_sources":
+			err = dec.Decode(&sm.XFacebookSources)
+		case "x_metro_module_paths":
+			sm.XMetroModulePaths, err = decodeStrings(dec)
+		case "ignorelist":
+			err = dec.Decode(&sm.IgnoreList)
+		case "x_google_ignorelist":
+			err = dec.Decode(&sm.XGoogleIgnoreList)
+		case "sections":
+			err = dec.Decode(&sm.Sections)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeStrings decodes an array of strings one element at a time. null
+// elements become empty strings and a null array yields nil.
+func decodeStrings(dec *json.Decoder) ([]string, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, fmt.Errorf("expected an array, got %v", tok)
+	}
+
+	out := []string{}
+	for dec.More() {
+		var s *string
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+		if s == nil {
+			out = append(out, "")
+		} else {
+			out = append(out, *s)
+		}
+	}
+	return out, expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token and checks that it is the delimiter d.
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if got, ok := tok.(json.Delim); !ok || got != d {
+		return fmt.Errorf("expected %q, got %v", d, tok)
+	}
+	return nil
+}
+
 // ExtractSourceMappingURL finds the sourceMappingURL comment in JS content.
 // Returns empty string if not found or if it's an inline data URI.
 func ExtractSourceMappingURL(jsContent string) string {
-	// Search from the end of the file (more efficient for large bundles)
-	lines := strings.Split(strings.TrimSpace(jsContent), "\n")
-
 	// Check last 10 lines (sourcemap comment is typically at the very end)
-	start := len(lines) - 10
-	if start < 0 {
-		start = 0
-	}
-
-	for i := len(lines) - 1; i >= start; i-- {
-		line := lines[i]
+	for _, line := range lastLines(jsContent, 10) {
 		matches := sourceMappingURLRe.FindStringSubmatch(line)
 		if len(matches) >= 2 {
-			url := strings.TrimSpace(matches[1])
+			url := cleanSourceMappingURL(matches[1])
+			if url == "" {
+				continue
+			}
 			// Skip data URIs - those are handled by ExtractInlineSourceMap
 			if strings.HasPrefix(url, "data:") {
 				return ""
@@ -65,37 +313,98 @@ func ExtractSourceMappingURL(jsContent string) string {
 	return ""
 }
 
-// ExtractInlineSourceMap extracts and decodes a base64 inline sourcemap from JS content.
-// Returns nil if no inline sourcemap is found.
-func ExtractInlineSourceMap(jsContent string) (*SourceMap, error) {
-	// Search from the end
-	lines := strings.Split(strings.TrimSpace(jsContent), "\n")
+// ExtractSourceMappingURLs returns every external sourcemap URL referenced
+// by sourceMappingURL comments near the end of JS content, without
+// duplicates, starting with the last line. Bundles concatenated from
+// several files can carry more than one.
+func ExtractSourceMappingURLs(jsContent string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, line := range lastLines(jsContent, 10) {
+		for _, matches := range sourceMappingURLRe.FindAllStringSubmatch(line, -1) {
+			url := cleanSourceMappingURL(matches[1])
+			if url == "" || strings.HasPrefix(url, "data:") || seen[url] {
+				continue
+			}
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
 
-	start := len(lines) - 10
-	if start < 0 {
-		start = 0
+// cleanSourceMappingURL strips what a sourceMappingURL capture can pick up
+// besides the URL: a carriage return, a closing "*/", quotes or parentheses
+// from code that wraps the comment in a string, and a following comment
+// such as //# sourceURL=... on the same line.
+func cleanSourceMappingURL(raw string) string {
+	url := raw
+	if loc := trailingCommentRe.FindStringIndex(url); loc != nil {
+		url = url[:loc[0]]
+	}
+	for {
+		trimmed := strings.TrimRight(url, "\r'\"`);,")
+		trimmed = strings.TrimSuffix(trimmed, "*/")
+		if trimmed == url {
+			break
+		}
+		url = trimmed
 	}
+	return strings.TrimLeft(url, "'\"`(")
+}
 
-	for i := len(lines) - 1; i >= start; i-- {
-		line := lines[i]
+// ExtractInlineSourceMap extracts and decodes a base64 inline sourcemap from JS content.
+// Returns nil if no inline sourcemap is found.
+func ExtractInlineSourceMap(jsContent string) (*SourceMap, error) {
+	for _, line := range lastLines(jsContent, 10) {
 		if !strings.Contains(line, "sourceMappingURL=data:application/json") {
 			continue
 		}
 
-		matches := inlineSourceMapRe.FindStringSubmatch(line)
-		if len(matches) >= 2 {
-			decoded, err := base64.StdEncoding.DecodeString(matches[1])
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode base64 sourcemap: %w", err)
-			}
-
-			return Parse(decoded)
+		loc := inlineSourceMapRe.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
 		}
+		// Decode while parsing rather than materializing the decoded JSON
+		encoded := strings.NewReader(line[loc[2]:loc[3]])
+		if err := checkBase64(line[loc[2]:loc[3]]); err != nil {
+			return nil, fmt.Errorf("failed to decode base64 sourcemap: %w", err)
+		}
+		return ParseReader(base64.NewDecoder(base64.StdEncoding, encoded))
 	}
 
 	return nil, nil
 }
 
+// lastLines returns up to n of the last lines of s, last first, ignoring
+// surrounding whitespace. The lines share memory with s, so a giant
+// single-line bundle is not copied.
+func lastLines(s string, n int) []string {
+	s = strings.TrimSpace(s)
+	lines := make([]string, 0, n)
+	for len(lines) < n {
+		i := strings.LastIndexByte(s, '\n')
+		lines = append(lines, s[i+1:])
+		if i < 0 {
+			break
+		}
+		s = s[:i]
+	}
+	return lines
+}
+
+// checkBase64 reports malformed padding up front. A streaming decoder would
+// otherwise surface it as a JSON syntax error partway through the data.
+func checkBase64(s string) error {
+	if len(s)%4 != 0 {
+		return base64.CorruptInputError(len(s) - len(s)%4)
+	}
+	if i := strings.IndexByte(s, '='); i >= 0 && i < len(s)-2 {
+		return base64.CorruptInputError(i)
+	}
+	return nil
+}
+
 // HasInlineSourceMap checks if JS content contains an inline sourcemap.
 func HasInlineSourceMap(jsContent string) bool {
 	return strings.Contains(jsContent, "sourceMappingURL=data:application/json")
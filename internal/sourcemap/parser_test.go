@@ -0,0 +1,246 @@
+package sourcemap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseWrapped(t *testing.T) {
+	tests := []struct {
+		file    string
+		wrapper string
+	}{
+		{"xssi.js.map", `XSSI guard ")]}'"`},
+		{"xssi-oneline.js.map", `XSSI guard ")]}'"`},
+		{"xssi-comma.js.map", `XSSI guard ")]}',"`},
+		{"while.js.map", `XSSI guard "while(1);"`},
+		{"start-end.js.map", `XSSI guard "&&&START&&&"`},
+		{"jsonp.js.map", "JSONP callback callback()"},
+		{"jsonp-dotted.js.map", "JSONP callback window.__maps.load()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			sm, err := ParseFile(filepath.Join("testdata", "wrapped", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sm.Wrapper != tt.wrapper {
+				t.Errorf("Wrapper = %q, want %q", sm.Wrapper, tt.wrapper)
+			}
+			if len(sm.Sources) != 1 || sm.Sources[0] != "src/a.js" || sm.Mappings != "AAAA" {
+				t.Errorf("parsed %+v", sm)
+			}
+		})
+	}
+}
+
+func TestParseWrappedMalformed(t *testing.T) {
+	const m = `{"version":3,"sources":["a.js"],"mappings":"AAAA"}`
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unclosed start", "&&&START&&&" + m},
+		{"unclosed callback", "callback(" + m},
+		{"data after the map", m + "garbage"},
+		{"guard without a map", ")]}'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Error("Parse succeeded")
+			}
+		})
+	}
+}
+
+// writeLargeMap writes a sourcemap of about size bytes to dir, spread over
+// sources of 1 MB each.
+func writeLargeMap(tb testing.TB, dir string, size int) string {
+	tb.Helper()
+	path := filepath.Join(dir, "large.js.map")
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	const chunk = 1 << 20
+	n := size / chunk
+	w.WriteString(`{"version":3,"sources":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		fmt.Fprintf(w, `"src/module_%d.js"`, i)
+	}
+	w.WriteString(`],"sourcesContent":[`)
+	line := strings.Repeat("x", 63) + `\n`
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(`"`)
+		for written := 0; written < chunk; written += len(line) {
+			w.WriteString(line)
+		}
+		w.WriteString(`"`)
+	}
+	w.WriteString(`],"mappings":"AAAA"}`)
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// peakHeap runs fn and returns the largest heap in use while it ran, in MB.
+func peakHeap(fn func()) float64 {
+	runtime.GC()
+	var base runtime.MemStats
+	runtime.ReadMemStats(&base)
+
+	var mu sync.Mutex
+	var peak uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(2 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			mu.Lock()
+			if m.HeapInuse > peak {
+				peak = m.HeapInuse
+			}
+			mu.Unlock()
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	fn()
+	close(done)
+	<-sampled
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak < base.HeapInuse {
+		return 0
+	}
+	return float64(peak-base.HeapInuse) / (1 << 20)
+}
+
+// BenchmarkParseLargeMap compares the peak heap of parsing a 200 MB map
+// streamed from disk with reading it into memory first. Run it with
+// -bench ParseLargeMap -benchtime 1x; peak-MB is the figure to compare.
+func BenchmarkParseLargeMap(b *testing.B) {
+	path := writeLargeMap(b, b.TempDir(), 200<<20)
+
+	b.Run("stream", func(b *testing.B) {
+		var peak float64
+		for i := 0; i < b.N; i++ {
+			peak = peakHeap(func() {
+				if _, err := ParseFile(path); err != nil {
+					b.Fatal(err)
+				}
+			})
+		}
+		b.ReportMetric(peak, "peak-MB")
+	})
+	b.Run("in-memory", func(b *testing.B) {
+		var peak float64
+		for i := 0; i < b.N; i++ {
+			peak = peakHeap(func() {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := Parse(data); err != nil {
+					b.Fatal(err)
+				}
+			})
+		}
+		b.ReportMetric(peak, "peak-MB")
+	})
+}
+
+// BenchmarkExtractInlineLargeMap extracts a 50 MB inline map from a
+// single-line bundle, which must not be split into a second full copy.
+func BenchmarkExtractInlineLargeMap(b *testing.B) {
+	data, err := os.ReadFile(writeLargeMap(b, b.TempDir(), 50<<20))
+	if err != nil {
+		b.Fatal(err)
+	}
+	script := "console.log(1);//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(data)
+	data = nil
+
+	var peak float64
+	for i := 0; i < b.N; i++ {
+		peak = peakHeap(func() {
+			sm, err := ExtractInlineSourceMap(script)
+			if err != nil || sm == nil {
+				b.Fatalf("ExtractInlineSourceMap = %v, %v", sm, err)
+			}
+		})
+	}
+	b.ReportMetric(peak, "peak-MB")
+}
+
+func TestExtractSourceMappingURL(t *testing.T) {
+	tests := []struct {
+		name string
+		js   string
+		want string
+	}{
+		{"plain", "var a;\n//# sourceMappingURL=app.js.map\n", "app.js.map"},
+		{"legacy @", "var a;\n//@ sourceMappingURL=app.js.map", "app.js.map"},
+		{"crlf", "var a;\r\n//# sourceMappingURL=app.js.map\r\n", "app.js.map"},
+		{"crlf without a final newline", "var a;\r\n//# sourceMappingURL=app.js.map\r", "app.js.map"},
+		{"block comment", "var a;\n/*# sourceMappingURL=app.css.map*/", "app.css.map"},
+		{"block comment with space", "var a;\n/*# sourceMappingURL=app.css.map */\r\n", "app.css.map"},
+		{"double quoted", `eval("var a;\n//# sourceMappingURL=app.js.map");`, "app.js.map"},
+		{"single quoted", `eval('var a;//# sourceMappingURL=app.js.map')`, "app.js.map"},
+		{"backquoted", "new Function(`//# sourceMappingURL=app.js.map`)", "app.js.map"},
+		{"parenthesized", `f("//# sourceMappingURL=app.js.map")`, "app.js.map"},
+		{"sourceURL after", "var a;\n//# sourceMappingURL=app.js.map//# sourceURL=webpack://app/app.js\n", "app.js.map"},
+		{"sourceURL block after", "var a;\n/*# sourceMappingURL=app.js.map*//*# sourceURL=app.js*/", "app.js.map"},
+		{"sourceURL before", "var a;\n//# sourceURL=app.js\n//# sourceMappingURL=https://cdn.example.com/app.js.map\r\n", "https://cdn.example.com/app.js.map"},
+		{"query string", "//# sourceMappingURL=app.js.map?v=3\r\n", "app.js.map?v=3"},
+		{"last of two", "//# sourceMappingURL=old.js.map\n//# sourceMappingURL=new.js.map\n", "new.js.map"},
+		{"data uri", "//# sourceMappingURL=data:application/json;base64,e30=\n", ""},
+		{"only punctuation", `//# sourceMappingURL="");`, ""},
+		{"none", "var a = 1;\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractSourceMappingURL(tt.js); got != tt.want {
+				t.Errorf("ExtractSourceMappingURL(%q) = %q, want %q", tt.js, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSourceMappingURLs(t *testing.T) {
+	js := "//# sourceMappingURL=a.js.map\r\n" +
+		"var b;\r\n//# sourceMappingURL=b.js.map//# sourceURL=b.js\r\n" +
+		`eval("//# sourceMappingURL=a.js.map");` + "\r\n"
+	want := []string{"a.js.map", "b.js.map"}
+	got := ExtractSourceMappingURLs(js)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("ExtractSourceMappingURLs = %q, want %q", got, want)
+	}
+}
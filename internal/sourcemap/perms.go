@@ -0,0 +1,114 @@
+package sourcemap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default permissions of restored files and directories, before the umask.
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// ParseMode parses octal permissions such as 0640 or 750. The mode must
+// include need, the owner permissions later runs require to rewrite what
+// they restored.
+func ParseMode(s string, need os.FileMode) (os.FileMode, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0o"), 8, 32)
+	if err != nil || n > 0777 {
+		return 0, fmt.Errorf("invalid mode %q (want octal permissions such as 0640)", s)
+	}
+	mode := os.FileMode(n)
+	if mode&need != need {
+		return 0, fmt.Errorf("mode %04o lacks %04o, which the owner needs to restore into it again", mode, need)
+	}
+	return mode, nil
+}
+
+// fileAttrs gives restored files and the directories they were written to
+// the permissions and modification time of RestoreOptions. Explicit modes
+// are set with chmod so the umask cannot narrow them; Windows has no
+// permission bits, so there they are ignored.
+type fileAttrs struct {
+	root       string // Restore directory; directories above it are left alone
+	fileMode   os.FileMode
+	dirMode    os.FileMode
+	chmodFiles bool
+	chmodDirs  bool
+	modTime    time.Time
+	dirs       map[string]bool // Directories written to, chmodded once each by finishDirs
+}
+
+func newFileAttrs(root string, opts *RestoreOptions) *fileAttrs {
+	a := &fileAttrs{root: root, fileMode: DefaultFileMode, dirMode: DefaultDirMode, dirs: make(map[string]bool)}
+	if opts == nil {
+		return a
+	}
+	if opts.FileMode != 0 {
+		a.fileMode = opts.FileMode
+		a.chmodFiles = runtime.GOOS != "windows"
+	}
+	if opts.DirMode != 0 {
+		a.dirMode = opts.DirMode
+		a.chmodDirs = runtime.GOOS != "windows"
+	}
+	a.modTime = opts.ModTime
+	return a
+}
+
+// mkdir creates the parent directories of path.
+func (a *fileAttrs) mkdir(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, a.dirMode); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	a.dirs[dir] = true
+	return nil
+}
+
+// write writes data to path with the file mode and modification time.
+func (a *fileAttrs) write(path string, data []byte) error {
+	if err := os.WriteFile(path, data, a.fileMode); err != nil {
+		return err
+	}
+	if a.chmodFiles {
+		if err := os.Chmod(path, a.fileMode); err != nil {
+			return err
+		}
+	}
+	if !a.modTime.IsZero() {
+		return os.Chtimes(path, a.modTime, a.modTime)
+	}
+	return nil
+}
+
+// finishDirs applies an explicit directory mode to every directory written
+// to and to its parents up to the restore directory.
+func (a *fileAttrs) finishDirs() error {
+	if !a.chmodDirs {
+		return nil
+	}
+	done := make(map[string]bool)
+	for dir := range a.dirs {
+		for !done[dir] {
+			if rel, err := filepath.Rel(a.root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+			done[dir] = true
+			if err := os.Chmod(dir, a.dirMode); err != nil {
+				return fmt.Errorf("failed to set mode of %s: %w", dir, err)
+			}
+			if dir == a.root {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+	return nil
+}
@@ -1,15 +1,23 @@
 package sourcemap
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/thesavant42/dejank/internal/disk"
+	"github.com/thesavant42/dejank/internal/filter"
 	"github.com/thesavant42/dejank/internal/format"
+	"github.com/thesavant42/dejank/internal/stats"
+	"github.com/thesavant42/dejank/internal/warn"
 )
 
 var (
@@ -22,6 +30,19 @@ var (
 	// module.exports = __webpack_public_path__ + "static/media/file.hash.ext"
 	webpackAssetExportRe = regexp.MustCompile(`(?:export\s+default|module\.exports\s*=)\s+(?:__webpack_public_path__\s*\+\s*)?"([^"]+)"`)
 
+	// Matches Vite and Rollup URL assets:
+	// export default new URL("assets/file.hash.ext", import.meta.url).href
+	importMetaAssetRe = regexp.MustCompile(`new\s+URL\(\s*["']([^"']+)["']\s*,\s*import\.meta\.url\s*\)`)
+
+	// Matches esbuild's file loader:
+	// var file_default = "./file-HASH.ext";
+	// export { file_default as default };
+	esbuildAssetRe = regexp.MustCompile(`var\s+(\w+)\s*=\s*"([^"]+)";\s*export\s*\{\s*(\w+)\s+as\s+default\s*\}`)
+
+	// Matches Parcel's URL assets:
+	// module.exports = require("./helpers/bundle-url").getBundleURL("a1b2") + "file.hash.ext";
+	parcelAssetRe = regexp.MustCompile(`getBundleURL\([^)]*\)\s*\+\s*["']([^"']+)["']`)
+
 	// Media file extensions that might be webpack loader stubs
 	mediaExtensions = map[string]bool{
 		".svg": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
@@ -29,6 +50,7 @@ var (
 		".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".otf": true,
 		".mp3": true, ".wav": true, ".ogg": true,
 		".mp4": true, ".webm": true,
+		".wasm": true,
 	}
 )
 
@@ -70,27 +92,135 @@ func isJavaScriptContent(content string) bool {
 	return false
 }
 
-// extractWebpackAssetURL extracts the asset URL from webpack loader stub content
-func extractWebpackAssetURL(content string) string {
-	matches := webpackAssetExportRe.FindStringSubmatch(content)
-	if len(matches) >= 2 {
-		return matches[1]
+// assetStub extracts an asset path from one bundler's stub form, or
+// returns "".
+type assetStub func(content string) string
+
+var (
+	webpackStub = func(content string) string { return submatch(webpackAssetExportRe, content, 1) }
+	urlStub     = func(content string) string { return submatch(importMetaAssetRe, content, 1) }
+	parcelStub  = func(content string) string { return submatch(parcelAssetRe, content, 1) }
+	esbuildStub = func(content string) string {
+		m := esbuildAssetRe.FindStringSubmatch(content)
+		if m == nil || m[1] != m[3] {
+			return ""
+		}
+		return m[2]
+	}
+)
+
+// assetStubOrder lists the stub forms to try for a bundler, its own
+// first, so that a stub matching more than one form is read the way its
+// bundler wrote it. Bundlers not listed, and maps whose bundler is
+// unknown, use webpack's order.
+var assetStubOrder = map[string][]assetStub{
+	BundlerWebpack: {webpackStub, urlStub, esbuildStub, parcelStub},
+	BundlerVite:    {urlStub, webpackStub, esbuildStub, parcelStub},
+	BundlerRollup:  {urlStub, webpackStub, esbuildStub, parcelStub},
+	BundlerEsbuild: {esbuildStub, webpackStub, urlStub, parcelStub},
+	BundlerParcel:  {parcelStub, webpackStub, urlStub, esbuildStub},
+}
+
+// extractAssetURL extracts the asset URL from a bundler's stub content,
+// trying the stub forms of bundler first.
+func extractAssetURL(content, bundler string) string {
+	order, ok := assetStubOrder[bundler]
+	if !ok {
+		order = assetStubOrder[BundlerWebpack]
+	}
+	for _, stub := range order {
+		if u := stub(content); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// submatch returns group n of re's first match in s, or "".
+func submatch(re *regexp.Regexp, s string, n int) string {
+	if m := re.FindStringSubmatch(s); m != nil {
+		return m[n]
 	}
 	return ""
 }
 
 // RestoreResult contains the result of a restore operation.
 type RestoreResult struct {
-	RestoredCount int
-	SkippedCount  int
-	AssetsFetched int
-	Errors        []error
+	RestoredCount  int
+	SkippedCount   int
+	MatchedCount   int // Sources that passed RestoreOptions.Only and Filter
+	FilteredCount  int // Sources excluded by RestoreOptions.Only or Filter
+	AssetsFetched  int
+	SourcesFetched int          // Sources without sourcesContent downloaded via SourceBaseURL
+	FormattedCount int          // JS/TS sources pretty-printed
+	VerbatimCount  int          // JS/TS sources written as the map has them: vendor code, or over RestoreOptions.FormatMaxSize
+	Files          []stats.File // Restore manifest: every file written, relative to the output directory
+	Errors         []error
+	Warnings       []warn.Warning // Sources skipped or written differently than asked, without failing
 }
 
 // RestoreOptions configures how sources are restored.
 type RestoreOptions struct {
 	BaseURL string       // Base URL for resolving relative asset paths
 	Fetcher AssetFetcher // HTTP client for fetching real assets (nil = skip fetching)
+
+	// Only restricts restore to sources whose raw sources[] entry matches
+	// one of these globs (see filter.MatchSource). Empty restores all.
+	Only []string
+
+	// Filter reports whether a source should be restored, given its output
+	// path relative to the restore directory (nil = restore all). It is
+	// applied after Only, so exclusions win over Only matches.
+	Filter func(path string) bool
+
+	// SourceBaseURL, when set together with Fetcher, is the sourcemap's own
+	// URL. Sources without embedded sourcesContent are then downloaded from
+	// their URL, resolved against sourceRoot and this URL.
+	SourceBaseURL string
+
+	// EOL rewrites the line endings of text sources to EOLLF or EOLCRLF and
+	// strips byte order marks. EOLKeep writes them as the map has them.
+	EOL string
+
+	// FileMode and DirMode set the permissions of restored files and of the
+	// directories they are written to regardless of the umask (0 = 0644
+	// and 0755 less the umask).
+	FileMode os.FileMode
+	DirMode  os.FileMode
+
+	// ModTime, when set, is given to every restored file instead of the
+	// time it was written.
+	ModTime time.Time
+
+	// FormatVendor pretty-prints vendor sources too. By default sources
+	// under node_modules and other package directories, or on the map's
+	// ignoreList, are written unformatted.
+	FormatVendor bool
+
+	// FormatMaxSize is the size in bytes above which a source is written
+	// unformatted, since generated files such as locale data can take the
+	// formatter minutes and gigabytes (0 = no limit).
+	FormatMaxSize int64
+}
+
+// DefaultFormatMaxSize is the FormatMaxSize used without options.
+const DefaultFormatMaxSize = 2 << 20
+
+// unnamedSourcePath names source i of a map when its path is empty or too
+// long, after a hash of its name, or of its content when it has no name.
+// Unlike its index, the hash stays the same when a rebuild reorders the
+// map's sources, so -git history shows the file changing rather than
+// moving. The index is used only when there is nothing to hash.
+func unnamedSourcePath(i int, source, content string) string {
+	key := source
+	if strings.TrimSpace(key) == "" {
+		key = content
+	}
+	if key == "" {
+		return fmt.Sprintf("source_%d.js", i)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "source_" + hex.EncodeToString(sum[:4]) + ".js"
 }
 
 // RestoreSources extracts all sources from a sourcemap to the output directory.
@@ -102,33 +232,72 @@ func RestoreSources(sm *SourceMap, outputDir string) RestoreResult {
 func RestoreSourcesWithOptions(sm *SourceMap, outputDir string, opts *RestoreOptions) RestoreResult {
 	result := RestoreResult{}
 
-	if len(sm.SourcesContent) == 0 {
+	fetchSources := opts != nil && opts.Fetcher != nil && opts.SourceBaseURL != ""
+	if len(sm.SourcesContent) == 0 && !fetchSources {
 		return result
 	}
 
+	attrs := newFileAttrs(outputDir, opts)
+	var bundler *string // Detected at the first asset stub
 	for i, source := range sm.Sources {
-		if i >= len(sm.SourcesContent) {
+		if i >= len(sm.SourcesContent) && !fetchSources {
 			break
 		}
 
-		content := sm.SourcesContent[i]
-		if content == "" {
+		var content string
+		if i < len(sm.SourcesContent) {
+			content = sm.SourcesContent[i]
+		}
+		if content == "" && !fetchSources {
 			result.SkippedCount++
 			continue
 		}
 
 		virtualPath := sanitizePath(source)
+		if named := sm.metroPath(i); named != "" {
+			virtualPath = named
+		}
 		if virtualPath == "" || len(virtualPath) > 255 {
-			virtualPath = fmt.Sprintf("source_%d.js", i)
+			virtualPath = unnamedSourcePath(i, source, content)
 		}
 
+		if opts != nil && len(opts.Only) > 0 && !matchesAny(opts.Only, source) {
+			result.FilteredCount++
+			continue
+		}
+		if opts != nil && opts.Filter != nil && !opts.Filter(filepath.ToSlash(virtualPath)) {
+			result.FilteredCount++
+			continue
+		}
+
+		// Download sources the map references but does not embed
+		downloaded := false
+		if content == "" {
+			data, err := fetchSource(sm.SourceRoot, source, opts)
+			if err != nil || len(data) == 0 {
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Errorf("failed to fetch source %s: %w", source, err))
+				}
+				result.SkippedCount++
+				continue
+			}
+			content = string(data)
+			downloaded = true
+		}
+		result.MatchedCount++
+
 		outPath := filepath.Join(outputDir, virtualPath)
 
 		// Check if this is a media file with JS stub content
 		if isMediaExtension(virtualPath) && isJavaScriptContent(content) {
 			if opts != nil && opts.Fetcher != nil && opts.BaseURL != "" {
 				// Try to fetch the real asset
-				if fetched := tryFetchRealAsset(content, outPath, opts); fetched {
+				if bundler == nil {
+					guess := sm.DetectBundler()
+					bundler = &guess.Name
+				}
+				if n, fetched := tryFetchRealAsset(content, *bundler, outPath, opts, attrs); fetched {
+					result.Files = append(result.Files, stats.File{Path: filepath.ToSlash(virtualPath), Bytes: n})
 					result.AssetsFetched++
 					result.RestoredCount++
 					continue
@@ -136,52 +305,167 @@ func RestoreSourcesWithOptions(sm *SourceMap, outputDir string, opts *RestoreOpt
 			}
 			// If we can't fetch, skip writing the stub file entirely
 			result.SkippedCount++
+			result.Warnings = append(result.Warnings, warn.New(warn.StubSkipped, filepath.ToSlash(virtualPath),
+				"source is a bundler stub for a media file and the real asset was not fetched; not written"))
+			continue
+		}
+
+		// Binary sources (wasm, images) are written byte for byte under an
+		// extension matching their content, never formatted
+		if data, ext, ok := binarySource(content); ok {
+			virtualPath = binaryPath(virtualPath, ext)
+			rawPath := filepath.Join(outputDir, virtualPath)
+			if err := writeRaw(rawPath, data, attrs); err != nil {
+				if err = disk.Check(rawPath, err); disk.Is(err) {
+					os.Remove(rawPath) // Don't leave a truncated file
+					result.Errors = append(result.Errors, err)
+					break
+				}
+				result.Errors = append(result.Errors, fmt.Errorf("failed to restore %s: %w", source, err))
+				continue
+			}
+			result.Files = append(result.Files, stats.File{Path: filepath.ToSlash(virtualPath), Bytes: int64(len(data)), Binary: true})
+			if downloaded {
+				result.SourcesFetched++
+			}
+			result.RestoredCount++
 			continue
 		}
 
-		if err := writeFile(outPath, content); err != nil {
+		eol := EOLKeep
+		if opts != nil {
+			eol = opts.EOL
+		}
+		var unformatted string
+		switch {
+		case !format.IsJSFile(virtualPath):
+		case (opts == nil || !opts.FormatVendor) && (stats.IsVendor(filepath.ToSlash(virtualPath)) || sm.ignored(i)):
+			unformatted = stats.UnformattedVendor
+		case tooLargeToFormat(len(content), opts):
+			unformatted = stats.UnformattedSize
+			result.Warnings = append(result.Warnings, warn.New(warn.FormatSkipped, filepath.ToSlash(virtualPath),
+				"written unformatted: %d bytes is over the formatting limit", len(content)))
+		}
+		edits, err := writeFile(outPath, content, eol, unformatted == "", attrs)
+		if err != nil {
+			// Every later write would fail the same way
+			if err = disk.Check(outPath, err); disk.Is(err) {
+				os.Remove(outPath) // Don't leave a truncated file
+				result.Errors = append(result.Errors, err)
+				break
+			}
 			result.Errors = append(result.Errors, fmt.Errorf("failed to restore %s: %w", source, err))
 			continue
 		}
 
+		if edits.formatErr != nil {
+			unformatted = stats.UnformattedError
+			result.Warnings = append(result.Warnings, warn.New(warn.FormatFallback, filepath.ToSlash(virtualPath),
+				"written unformatted: %v", edits.formatErr))
+		}
+		switch unformatted {
+		case "":
+			if format.IsJSFile(virtualPath) {
+				result.FormattedCount++
+			}
+		case stats.UnformattedVendor, stats.UnformattedSize:
+			result.VerbatimCount++
+		}
+
+		file := stats.File{Path: filepath.ToSlash(virtualPath), Bytes: edits.written, BOMStripped: edits.bomStripped, Unformatted: unformatted}
+		if edits.eolConverted {
+			file.EOLConverted = eol
+		}
+		result.Files = append(result.Files, file)
+		if downloaded {
+			result.SourcesFetched++
+		}
 		result.RestoredCount++
 	}
 
+	if err := attrs.finishDirs(); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
 	return result
 }
 
-// tryFetchRealAsset attempts to download the real asset from a webpack stub.
-// Returns true if successful.
-func tryFetchRealAsset(content, outPath string, opts *RestoreOptions) bool {
-	assetPath := extractWebpackAssetURL(content)
+// tooLargeToFormat reports whether a source of size bytes is over the
+// formatting limit of opts.
+func tooLargeToFormat(size int, opts *RestoreOptions) bool {
+	limit := int64(DefaultFormatMaxSize)
+	if opts != nil {
+		limit = opts.FormatMaxSize
+	}
+	return limit > 0 && int64(size) > limit
+}
+
+// matchesAny reports whether a raw source entry matches any of the globs.
+func matchesAny(globs []string, source string) bool {
+	for _, g := range globs {
+		if filter.MatchSource(g, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSource downloads a source that has no embedded content. The entry
+// is joined to sourceRoot and resolved against the sourcemap URL. Entries
+// that do not resolve to http(s), such as webpack:// pseudo-URLs, return nil.
+func fetchSource(sourceRoot, source string, opts *RestoreOptions) ([]byte, error) {
+	base, err := url.Parse(opts.SourceBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := source
+	if sourceRoot != "" && !strings.Contains(source, "://") {
+		ref = strings.TrimSuffix(sourceRoot, "/") + "/" + strings.TrimPrefix(source, "/")
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := base.ResolveReference(refURL)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return nil, nil
+	}
+
+	return opts.Fetcher.GetBytes(resolved.String())
+}
+
+// tryFetchRealAsset attempts to download the real asset from a bundler
+// stub. Returns the number of bytes written and true if successful.
+func tryFetchRealAsset(content, bundler, outPath string, opts *RestoreOptions, attrs *fileAttrs) (int64, bool) {
+	assetPath := extractAssetURL(content, bundler)
 	if assetPath == "" {
-		return false
+		return 0, false
 	}
 
 	// Resolve the asset URL against the base URL
 	assetURL, err := resolveAssetURL(opts.BaseURL, assetPath)
 	if err != nil {
-		return false
+		return 0, false
 	}
 
 	// Fetch the real asset
 	data, err := opts.Fetcher.GetBytes(assetURL)
 	if err != nil {
-		return false
+		return 0, false
 	}
 
 	// Create parent directories
-	dir := filepath.Dir(outPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return false
+	if err := attrs.mkdir(outPath); err != nil {
+		return 0, false
 	}
 
 	// Write the real asset data
-	if err := os.WriteFile(outPath, data, 0644); err != nil {
-		return false
+	if err := attrs.write(outPath, data); err != nil {
+		return 0, false
 	}
 
-	return true
+	return int64(len(data)), true
 }
 
 // resolveAssetURL resolves a relative asset path against a base URL.
@@ -196,7 +480,7 @@ func resolveAssetURL(baseURL, assetPath string) (string, error) {
 	resolved := &url.URL{
 		Scheme: base.Scheme,
 		Host:   base.Host,
-		Path:   "/" + strings.TrimPrefix(assetPath, "/"),
+		Path:   path.Clean("/" + strings.TrimPrefix(assetPath, "/")),
 	}
 
 	return resolved.String(), nil
@@ -212,6 +496,9 @@ func sanitizePath(source string) string {
 		path = strings.TrimPrefix(path, "./")
 	}
 
+	// Name Vue and Svelte component blocks after their component
+	path = componentPath(path)
+
 	// Normalize path separators
 	path = filepath.FromSlash(path)
 
@@ -219,10 +506,14 @@ func sanitizePath(source string) string {
 	parts := strings.Split(path, string(filepath.Separator))
 	sanitized := make([]string, 0, len(parts))
 
-	for _, part := range parts {
-		clean := sanitizePathSegment(part)
+	for i, part := range parts {
+		clean := SanitizePathSegment(part)
 		if clean != "" {
 			sanitized = append(sanitized, clean)
+		} else if i == len(parts)-1 {
+			// Nothing is left of the file name; dropping it would write
+			// the file over its directory, so let the caller name it
+			return ""
 		}
 	}
 
@@ -233,8 +524,12 @@ func sanitizePath(source string) string {
 	return filepath.Join(sanitized...)
 }
 
-// sanitizePathSegment cleans a single path segment.
-func sanitizePathSegment(segment string) string {
+// SanitizePathSegment cleans a single path segment: it drops characters
+// illegal on Windows, replaces spaces with underscores, and strips trailing
+// and repeated leading dots. A name with a single leading dot, such as .env
+// or .eslintrc.js, is kept as is; ..weird becomes .weird. Segments of only
+// dots, such as . and .., and invalid UTF-8 yield "".
+func SanitizePathSegment(segment string) string {
 	if !utf8.ValidString(segment) {
 		return ""
 	}
@@ -245,27 +540,48 @@ func sanitizePathSegment(segment string) string {
 	// Replace spaces with underscores
 	clean = strings.ReplaceAll(clean, " ", "_")
 
-	// Remove trailing dots
+	// Remove trailing dots, which Windows drops; this also empties . and ..
 	clean = strings.TrimRight(clean, ".")
 
-	// Remove leading dots (but preserve single dot for hidden files on Unix)
-	for len(clean) > 1 && clean[0] == '.' && clean[1] == '.' {
-		clean = clean[1:]
+	// Collapse repeated leading dots to one, keeping hidden files hidden
+	if strings.HasPrefix(clean, "..") {
+		clean = "." + strings.TrimLeft(clean, ".")
 	}
 
 	return clean
 }
 
 // writeFile writes content to a file, creating parent directories as needed.
-// JS/TS files are pretty-printed before writing.
-func writeFile(path, content string) error {
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// With pretty set JS/TS files are pretty-printed before writing. Line
+// endings are normalized to eol both before formatting and after, since the
+// formatter emits its own.
+func writeFile(path, content, eol string, pretty bool, attrs *fileAttrs) (textEdits, error) {
+	if err := attrs.mkdir(path); err != nil {
+		return textEdits{}, err
 	}
 
+	content, edits := normalizeText(content, eol)
+
 	// Pretty-print JS/TS files (non-JS files pass through unchanged)
-	formatted := format.Format(content, path)
+	formatted := content
+	if pretty {
+		var err error
+		formatted, err = format.Beautify(content, path)
+		edits.formatErr = err
+	}
+	if eol != EOLKeep {
+		formatted = convertEOL(formatted, eol)
+	}
 
-	return os.WriteFile(path, []byte(formatted), 0644)
+	edits.written = int64(len(formatted))
+	return edits, attrs.write(path, []byte(formatted))
+}
+
+// writeRaw writes data to a file unchanged, creating parent directories as
+// needed.
+func writeRaw(path string, data []byte, attrs *fileAttrs) error {
+	if err := attrs.mkdir(path); err != nil {
+		return err
+	}
+	return attrs.write(path, data)
 }
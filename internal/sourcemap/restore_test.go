@@ -0,0 +1,156 @@
+package sourcemap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnnamedSourcesKeepTheirNames(t *testing.T) {
+	long := "webpack:///" + strings.Repeat("a/", 200) + "index.js"
+	names := func(sources, contents []string) map[string]string {
+		dir := t.TempDir()
+		sm := &SourceMap{Version: 3, Sources: sources, SourcesContent: contents}
+		RestoreSources(sm, dir)
+		byContent := make(map[string]string)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			byContent[strings.TrimSpace(string(data))] = e.Name()
+		}
+		return byContent
+	}
+
+	first := names([]string{long, "", "src/a.js"}, []string{"var long = 1;", "var anon = 2;", "var a = 3;"})
+	second := names([]string{"src/a.js", "", long}, []string{"var a = 3;", "var anon = 2;", "var long = 1;"})
+	for content, name := range first {
+		if second[content] != name {
+			t.Errorf("%q was restored as %s, then as %s after the sources were reordered", content, name, second[content])
+		}
+	}
+	if first["var long = 1;"] == first["var anon = 2;"] {
+		t.Errorf("two unnamed sources share the name %s", first["var long = 1;"])
+	}
+	if !strings.HasPrefix(first["var long = 1;"], "source_") {
+		t.Errorf("overlong source restored as %s", first["var long = 1;"])
+	}
+}
+
+// generatedSource returns about size bytes of minified generated code,
+// locale data on a single line, the kind of source -format-max-size is for.
+func generatedSource(size int) string {
+	var b strings.Builder
+	b.WriteString("var L={};")
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, `L.l%d={code:"l%d",months:["Jan","Feb","Mar","Apr","May","Jun","Jul","Aug","Sep","Oct","Nov","Dec"],fmt:function(n){return n.toFixed(2)+" l%d"}};`, i, i, i)
+	}
+	return b.String()
+}
+
+func TestRestoreLeavesLargeSourcesUnformatted(t *testing.T) {
+	content := generatedSource(DefaultFormatMaxSize + 1)
+	sm := &SourceMap{Version: 3, Sources: []string{"src/locales.js"}, SourcesContent: []string{content}}
+	dir := t.TempDir()
+	result := RestoreSources(sm, dir)
+	if result.VerbatimCount != 1 {
+		t.Errorf("VerbatimCount = %d, want 1", result.VerbatimCount)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "src", "locales.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Error("source over DefaultFormatMaxSize was reformatted")
+	}
+}
+
+// BenchmarkRestoreLargeSource restores a 512 KB generated source, once
+// formatted and once over a FormatMaxSize, which writes it as it is.
+// Formatting time grows faster than the source: this one takes seconds
+// where the verbatim write takes milliseconds, and a source the size of
+// DefaultFormatMaxSize takes close to a minute.
+func BenchmarkRestoreLargeSource(b *testing.B) {
+	content := generatedSource(512 << 10)
+	sm := &SourceMap{Version: 3, Sources: []string{"src/locales.js"}, SourcesContent: []string{content}}
+	for _, bb := range []struct {
+		name  string
+		limit int64
+	}{
+		{"formatted", 0},
+		{"over-max-size", 256 << 10},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			opts := &RestoreOptions{FormatMaxSize: bb.limit}
+			var peak float64
+			for i := 0; i < b.N; i++ {
+				dir := b.TempDir()
+				if mb := peakHeap(func() { RestoreSourcesWithOptions(sm, dir, opts) }); mb > peak {
+					peak = mb
+				}
+			}
+			b.ReportMetric(peak, "peak-MB")
+		})
+	}
+}
+
+func TestSanitizePathSegment(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"index.js", "index.js"},
+		{".env", ".env"},
+		{".npmrc", ".npmrc"},
+		{".eslintrc.js", ".eslintrc.js"},
+		{"..weird", ".weird"},
+		{"...env", ".env"},
+		{"my file.js", "my_file.js"},
+		{"a<b>c:d\"e|f?g*h.js", "abcdefgh.js"},
+		{"tab\there", "tabhere"},
+		{"trailing.", "trailing"},
+		{"file.js...", "file.js"},
+		{".", ""},
+		{"..", ""},
+		{"...", ""},
+		{"<>:\"|?*", ""},
+		{"\x00\x1f", ""},
+		{"bad\xffutf8", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizePathSegment(tt.in); got != tt.want {
+			t.Errorf("SanitizePathSegment(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"webpack:///./src/app.js", "src/app.js"},
+		{"webpack://app/./src/.env", "app/src/.env"},
+		{"../../..weird/.npmrc", ".weird/.npmrc"},
+		{"src/../../etc/passwd", "src/etc/passwd"},
+		{"src/...", ""}, // The file name sanitizes to nothing
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := filepath.ToSlash(sanitizePath(tt.in)); got != tt.want {
+			t.Errorf("sanitizePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+(function(){var __SOURCEMAP__ = "{\"version\":3,\"file\":\"main.js\",\"sources\":[\"src/main.js\"],\"sourcesContent\":[\"const s = \x27\u{1F600} \uD83D\uDE00\x27;\\n\"],\"names\":[],\"mappings\":\"AAAA\"}";
+console.log("main");})();
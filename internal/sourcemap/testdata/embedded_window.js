@@ -0,0 +1,3 @@
+window.__sourcemaps__ = window.__sourcemaps__ || {};
+window.__sourcemaps__["app"] = '{"version":3,"file":"app.js","sources":["src/app.js"],"sourcesContent":["export const x = \'caf\\u00e9\';\\n"],"names":[],"mappings":"AAAA"}';
+window.__sourcemaps__["app-copy"] = '{"version":3,"file":"app.js","sources":["src/app.js"],"sourcesContent":["export const x = \'caf\\u00e9\';\\n"],"names":[],"mappings":"AAAA"}';
@@ -10,11 +10,15 @@ type SourceMap struct {
 	SourcesContent []string `json:"sourcesContent,omitempty"`
 	Names          []string `json:"names,omitempty"`
 	Mappings       string   `json:"mappings,omitempty"`
+	IgnoreList     []int    `json:"ignoreList,omitempty"` // Indexes into Sources of third-party code
 
 	// Non-standard fields for toolchain detection
-	XFacebookSources  interface{} `json:"x_facebook_sources,omitempty"`
-	XGoogleIgnoreList interface{} `json:"x_google_ignoreList,omitempty"`
-	Sections          []struct{}  `json:"sections,omitempty"`
+	XFacebookSources  FacebookSources `json:"x_facebook_sources,omitempty"`
+	XMetroModulePaths []string        `json:"x_metro_module_paths,omitempty"`
+	XGoogleIgnoreList interface{}     `json:"x_google_ignoreList,omitempty"`
+	Sections          []struct{}      `json:"sections,omitempty"`
+
+	Wrapper string `json:"-"` // XSSI guard or JSONP callback stripped before parsing, "" if none
 }
 
 // Metadata contains summary information about a sourcemap.
@@ -28,6 +32,10 @@ type Metadata struct {
 	SourceRoot        string
 	SectionCount      int
 	ToolchainHints    []string
+	Issues            []string       // Structural problems, see SourceMap.Issues
+	Wrapper           string         // See SourceMap.Wrapper
+	Metro             *MetroMetadata // Metro's x_facebook_sources and module paths, nil if absent
+	Bundler           BundlerGuess
 }
 
 // ExtractMetadata extracts summary metadata from a SourceMap.
@@ -42,10 +50,14 @@ func (sm *SourceMap) ExtractMetadata() Metadata {
 		SourceRoot:        sm.SourceRoot,
 		SectionCount:      len(sm.Sections),
 		ToolchainHints:    []string{},
+		Issues:            sm.Issues(),
+		Wrapper:           sm.Wrapper,
+		Metro:             sm.metroMetadata(),
+		Bundler:           sm.DetectBundler(),
 	}
 
 	// Detect toolchain hints
-	if sm.XFacebookSources != nil {
+	if meta.Metro != nil {
 		meta.ToolchainHints = append(meta.ToolchainHints, "Facebook (Metro bundler)")
 	}
 	if sm.XGoogleIgnoreList != nil {
@@ -60,6 +72,16 @@ func (sm *SourceMap) ExtractMetadata() Metadata {
 			break
 		}
 	}
+	scores := sm.bundlerScores()
+	for _, name := range bundlerOrder {
+		// Metro and webpack have hints of their own above
+		if scores[name] > 0 && name != BundlerMetro && name != BundlerWebpack {
+			meta.ToolchainHints = append(meta.ToolchainHints, name)
+		}
+	}
+	if meta.Bundler.Minified {
+		meta.ToolchainHints = append(meta.ToolchainHints, "Minified (terser/uglify)")
+	}
 
 	return meta
 }
@@ -68,3 +90,19 @@ func containsWebpack(s string) bool {
 	return len(s) >= 7 && (s == "webpack" || (len(s) > 7 && s[:8] == "webpack:"))
 }
 
+// ignored reports whether source i is on the map's ignoreList, or on the
+// x_google_ignoreList older Chrome DevTools tooling writes instead.
+func (sm *SourceMap) ignored(i int) bool {
+	for _, n := range sm.IgnoreList {
+		if n == i {
+			return true
+		}
+	}
+	list, _ := sm.XGoogleIgnoreList.([]interface{})
+	for _, v := range list {
+		if n, ok := v.(float64); ok && int(n) == i {
+			return true
+		}
+	}
+	return false
+}
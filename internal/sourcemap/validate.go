@@ -0,0 +1,91 @@
+package sourcemap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Issues describes what in the map's structure keeps its sources from being
+// restored as expected, one sentence each. A well-formed version 3 map with
+// sourcesContent has none.
+func (sm *SourceMap) Issues() []string {
+	var issues []string
+	switch {
+	case sm.Version == 0:
+		issues = append(issues, "map has no version field; read as version 3")
+	case sm.Version == 1 || sm.Version == 2:
+		issues = append(issues, fmt.Sprintf("sourcemap version %d not supported; only its sources list was read", sm.Version))
+	case sm.Version < 0 || sm.Version > 3:
+		issues = append(issues, fmt.Sprintf("unknown sourcemap version %d; read as version 3", sm.Version))
+	}
+
+	if sm.Wrapper != "" {
+		issues = append(issues, fmt.Sprintf("map is wrapped in %s; stripped before parsing", sm.Wrapper))
+	}
+
+	if len(sm.Sections) > 0 {
+		issues = append(issues, fmt.Sprintf("index map with %d section(s); sources inside sections are not restored", len(sm.Sections)))
+	}
+
+	switch {
+	case len(sm.Sources) == 0 && sm.Mappings != "":
+		issues = append(issues, "map has mappings but no sources; likely stripped")
+	case len(sm.Sources) == 0 && len(sm.Sections) == 0:
+		issues = append(issues, "map lists no sources")
+	case len(sm.SourcesContent) == 0 && (sm.Version == 0 || sm.Version == 3):
+		issues = append(issues, "map has no sourcesContent; its sources can only be downloaded from their URLs (map -fetch-sources)")
+	case len(sm.SourcesContent) > len(sm.Sources):
+		issues = append(issues, fmt.Sprintf("sourcesContent has %d entries for %d sources; the extra entries are ignored", len(sm.SourcesContent), len(sm.Sources)))
+	}
+	return issues
+}
+
+// lineMapsHeader starts a version 1 (Closure Compiler) sourcemap, which is
+// line-oriented text rather than a JSON object.
+const lineMapsHeader = "/** Begin line maps. **/"
+
+// parseLineMaps reads the file name and the sources list of a version 1
+// sourcemap: the first element of every entry under "Begin mapping
+// definitions". Its mappings are not decoded, so nothing can be restored
+// from it.
+func parseLineMaps(r io.Reader) (*SourceMap, error) {
+	sm := &SourceMap{Version: 1}
+	seen := make(map[string]bool)
+	definitions := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, lineMapsHeader):
+			var header struct {
+				File string `json:"file"`
+			}
+			json.Unmarshal([]byte(strings.TrimPrefix(line, lineMapsHeader)), &header)
+			sm.File = header.File
+		case strings.HasPrefix(line, "/** Begin mapping definitions."):
+			definitions = true
+		case strings.HasPrefix(line, "/** Begin"):
+			definitions = false
+		case definitions && line != "":
+			var entry []interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				return nil, fmt.Errorf("failed to parse version 1 mapping definition: %w", err)
+			}
+			if len(entry) > 0 {
+				if source, ok := entry[0].(string); ok && !seen[source] {
+					seen[source] = true
+					sm.Sources = append(sm.Sources, source)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read version 1 sourcemap: %w", err)
+	}
+	return sm, nil
+}
@@ -0,0 +1,220 @@
+package sourcemap
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultVerifySample is how many mappings Verify checks by default.
+const DefaultVerifySample = 500
+
+// maxMismatches is how many mismatched mappings a Consistency keeps as
+// examples.
+const maxMismatches = 10
+
+// Consistency is how well a sourcemap's mappings agree with the bundle it
+// is supposed to describe.
+type Consistency struct {
+	Mappings       int        // Mappings with a source in the map
+	Checked        int        // Mappings sampled
+	Matched        int        // Sampled mappings consistent with the bundle
+	OutOfRange     int        // Sampled mappings pointing past the end of a bundle line or of the bundle
+	WithoutContent int        // Sampled mappings whose source has no sourcesContent; only their position was checked
+	Mismatches     []Mismatch // The first mismatched mappings, as examples
+}
+
+// Mismatch is a sampled mapping that doesn't agree with the bundle.
+type Mismatch struct {
+	GeneratedLine   int    // 1-based
+	GeneratedColumn int    // 0-based, in UTF-16 code units
+	Source          string // Original source the mapping points into
+	Generated       string // Token at the generated position, "" if out of range
+	Original        string // Token at the original position, or the mapping's name
+	Reason          string
+}
+
+// Percent returns the share of sampled mappings that were consistent, or
+// 100 if none could be sampled.
+func (c Consistency) Percent() float64 {
+	if c.Checked == 0 {
+		return 100
+	}
+	return 100 * float64(c.Matched) / float64(c.Checked)
+}
+
+// minVerdictSample is how many mappings must be checked before a
+// Consistency judges a map to belong to its bundle.
+const minVerdictSample = 10
+
+// Verdict is what a Consistency concludes about a map and its bundle.
+type Verdict int
+
+const (
+	// Inconclusive means too few mappings were checked to judge.
+	Inconclusive Verdict = iota
+	// Belongs means the map plausibly describes the bundle.
+	Belongs
+	// Mismatched means the map is likely for a different bundle.
+	Mismatched
+)
+
+// Verdict judges whether the map plausibly describes the bundle. Builds
+// rewrite code, so even a map that belongs has mappings whose tokens
+// differ; one that doesn't belong points past the ends of lines or lands
+// on unrelated code most of the time. A map none of whose checked
+// mappings agree is Mismatched however few were checked; otherwise fewer
+// than minVerdictSample is Inconclusive.
+func (c Consistency) Verdict() Verdict {
+	switch {
+	case c.Checked == 0:
+		return Inconclusive
+	case c.Matched == 0:
+		return Mismatched
+	case c.Checked < minVerdictSample:
+		return Inconclusive
+	case c.OutOfRange*10 <= c.Checked && c.Matched*2 >= c.Checked:
+		return Belongs
+	}
+	return Mismatched
+}
+
+// Verify checks an evenly spaced sample of up to sample mappings of sm
+// against bundle, the generated code the map is for. A mapping is
+// consistent when its generated position is inside the bundle and the
+// token there agrees with the original: the same text, or an identifier
+// where the mapping names the original identifier, as minifiers rename
+// them. A sample of 0 or less uses DefaultVerifySample.
+func Verify(sm *SourceMap, bundle string, sample int) (Consistency, error) {
+	var c Consistency
+	mappings, err := sm.DecodeMappings()
+	if err != nil {
+		return c, fmt.Errorf("failed to decode mappings: %w", err)
+	}
+	withSource := mappings[:0]
+	for _, m := range mappings {
+		if m.Source >= 0 && m.Source < len(sm.Sources) {
+			withSource = append(withSource, m)
+		}
+	}
+	c.Mappings = len(withSource)
+	if sample <= 0 {
+		sample = DefaultVerifySample
+	}
+
+	generated := strings.Split(bundle, "\n")
+	original := make(map[int][]string)
+	// Spread the sample over all mappings; a whole-number stride would
+	// leave the end of the bundle out when it doesn't divide evenly
+	n := min(sample, len(withSource))
+	for k := 0; k < n; k++ {
+		m := withSource[k*len(withSource)/n]
+		c.Checked++
+
+		var gen string
+		ok := m.GeneratedLine < len(generated)
+		if ok {
+			gen, ok = tokenAt(generated[m.GeneratedLine], m.GeneratedColumn)
+		}
+		if !ok {
+			c.OutOfRange++
+			c.mismatch(sm, m, "", "", "generated position is past the end of the bundle line")
+			continue
+		}
+
+		var name string
+		if m.Name >= 0 && m.Name < len(sm.Names) {
+			name = sm.Names[m.Name]
+		}
+
+		if m.Source >= len(sm.SourcesContent) || sm.SourcesContent[m.Source] == "" {
+			c.WithoutContent++
+			if name == "" || isIdentifier(gen) {
+				c.Matched++
+			} else {
+				c.mismatch(sm, m, gen, name, "named mapping does not point at an identifier")
+			}
+			continue
+		}
+
+		lines, seen := original[m.Source]
+		if !seen {
+			lines = strings.Split(sm.SourcesContent[m.Source], "\n")
+			original[m.Source] = lines
+		}
+		var orig string
+		if m.OriginalLine < len(lines) {
+			orig, _ = tokenAt(lines[m.OriginalLine], m.OriginalColumn)
+		}
+
+		switch {
+		case gen == orig:
+			c.Matched++
+		case name != "" && isIdentifier(gen) && (orig == name || gen == name):
+			c.Matched++
+		default:
+			c.mismatch(sm, m, gen, orig, "tokens differ")
+		}
+	}
+	return c, nil
+}
+
+// mismatch records an example of a mapping that doesn't agree.
+func (c *Consistency) mismatch(sm *SourceMap, m Mapping, gen, orig, reason string) {
+	if len(c.Mismatches) >= maxMismatches {
+		return
+	}
+	c.Mismatches = append(c.Mismatches, Mismatch{
+		GeneratedLine:   m.GeneratedLine + 1,
+		GeneratedColumn: m.GeneratedColumn,
+		Source:          sm.Sources[m.Source],
+		Generated:       gen,
+		Original:        orig,
+		Reason:          reason,
+	})
+}
+
+// tokenAt returns the token starting at col, in UTF-16 code units, of
+// line: an identifier or number, or a single other character. It reports
+// false if col is past the end of the line.
+func tokenAt(line string, col int) (string, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	units := 0
+	for i, r := range line {
+		if units < col {
+			units++
+			if r > 0xFFFF {
+				units++
+			}
+			continue
+		}
+		if units > col {
+			// col splits a surrogate pair
+			return "", false
+		}
+		if !isIdentRune(r) {
+			return string(r), true
+		}
+		end := strings.IndexFunc(line[i:], func(r rune) bool { return !isIdentRune(r) })
+		if end < 0 {
+			return line[i:], true
+		}
+		return line[i : i+end], true
+	}
+	// A mapping may point just past the last character, e.g. at a
+	// missing semicolon
+	return "", units == col
+}
+
+// isIdentifier reports whether tok is an identifier or keyword.
+func isIdentifier(tok string) bool {
+	if tok == "" {
+		return false
+	}
+	r := []rune(tok)[0]
+	return isIdentRune(r) && !unicode.IsDigit(r)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,140 @@
+package sourcemap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	sm := &SourceMap{
+		Version:        3,
+		Sources:        []string{"src/answer.js"},
+		SourcesContent: []string{"const answer = 42;\n"},
+		Names:          []string{"answer"},
+		// const → const, a → answer (named), 42 → 42, ; → const
+		Mappings: "AAAA,MAAMA,EAAS,EAAf",
+	}
+	c, err := Verify(sm, "const a=42;", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Mappings != 4 || c.Checked != 4 || c.Matched != 3 || c.OutOfRange != 0 {
+		t.Errorf("Verify = %+v, want 3 of 4 matched", c)
+	}
+	if len(c.Mismatches) != 1 || c.Mismatches[0].Generated != ";" || c.Mismatches[0].Original != "const" {
+		t.Errorf("Mismatches = %+v", c.Mismatches)
+	}
+}
+
+func TestVerifySampleSpread(t *testing.T) {
+	// 999 mappings, one per generated line, against a bundle of 500 lines:
+	// an evenly spread sample of 500 finds the second half out of range
+	sm := &SourceMap{
+		Version:        3,
+		Sources:        []string{"a.js"},
+		SourcesContent: []string{"x\n"},
+		Mappings:       strings.Repeat("AAAA;", 998) + "AAAA",
+	}
+	bundle := strings.Repeat("x\n", 499) + "x"
+	c, err := Verify(sm, bundle, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Mappings != 999 || c.Checked != 500 {
+		t.Fatalf("Verify checked %d of %d mappings, want 500 of 999", c.Checked, c.Mappings)
+	}
+	if c.OutOfRange < 240 || c.OutOfRange > 260 {
+		t.Errorf("OutOfRange = %d, want about half the sample", c.OutOfRange)
+	}
+	if v := c.Verdict(); v != Mismatched {
+		t.Errorf("Verdict = %v for a map of twice the bundle's lines, want Mismatched", v)
+	}
+
+	// A sample larger than the mappings checks each once
+	if c, _ := Verify(sm, bundle, 5000); c.Checked != 999 {
+		t.Errorf("Checked = %d with a sample over the mapping count, want 999", c.Checked)
+	}
+}
+
+func TestTokenAt(t *testing.T) {
+	tests := []struct {
+		line string
+		col  int
+		want string
+		ok   bool
+	}{
+		{"const answer=42;", 0, "const", true},
+		{"const answer=42;", 6, "answer", true},
+		{"const answer=42;", 8, "swer", true},
+		{"const answer=42;", 12, "=", true},
+		{"const answer=42;", 13, "42", true},
+		{"const answer=42;", 5, " ", true},
+		{"const answer=42;", 16, "", true}, // Just past the end
+		{"const answer=42;", 17, "", false},
+		{"a=$el_1;\r", 2, "$el_1", true},
+		{"a=1;\r", 4, "", true}, // The CR isn't a column
+		{"x='é';y", 6, "y", true},
+		{"s='😀';t", 7, "t", true}, // The emoji is two UTF-16 units
+		{"s='😀';t", 4, "", false}, // Inside the surrogate pair
+		{"var café=1", 4, "café", true},
+		{"", 0, "", true},
+	}
+	for _, tt := range tests {
+		got, ok := tokenAt(tt.line, tt.col)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("tokenAt(%q, %d) = %q, %v, want %q, %v", tt.line, tt.col, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Consistency
+		want Verdict
+	}{
+		{"nothing checked", Consistency{}, Inconclusive},
+		{"too few to judge", Consistency{Checked: 9, Matched: 9}, Inconclusive},
+		{"too few, none matched", Consistency{Checked: 1, OutOfRange: 1}, Mismatched},
+		{"none matched", Consistency{Checked: 100}, Mismatched},
+		{"all matched", Consistency{Checked: 100, Matched: 100}, Belongs},
+		{"half matched", Consistency{Checked: 100, Matched: 50}, Belongs},
+		{"under half matched", Consistency{Checked: 100, Matched: 49}, Mismatched},
+		{"tenth out of range", Consistency{Checked: 100, Matched: 90, OutOfRange: 10}, Belongs},
+		{"over a tenth out of range", Consistency{Checked: 100, Matched: 89, OutOfRange: 11}, Mismatched},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Verdict(); got != tt.want {
+			t.Errorf("%s: Verdict() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifySmallMismatch(t *testing.T) {
+	// One mapping whose token doesn't agree is never judged a match
+	sm := &SourceMap{
+		Version:        3,
+		Sources:        []string{"a.js"},
+		SourcesContent: []string{"foo();\n"},
+		Mappings:       "AAAA",
+	}
+	c, err := Verify(sm, "bar();", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Checked != 1 || c.Matched != 0 {
+		t.Fatalf("Verify = %+v, want 1 checked and none matched", c)
+	}
+	if v := c.Verdict(); v != Mismatched {
+		t.Errorf("Verdict = %v, want Mismatched", v)
+	}
+
+	// One that does agree is too few to judge
+	c, err = Verify(sm, "foo();", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Verdict(); v != Inconclusive {
+		t.Errorf("Verdict = %v for one matching mapping, want Inconclusive", v)
+	}
+}
@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxNotable caps the notable files listed per package.
+const maxNotable = 10
+
+// workspaceDirs are directory names that hold one package per child in
+// monorepo layouts.
+var workspaceDirs = map[string]bool{
+	"packages": true,
+	"apps":     true,
+	"libs":     true,
+}
+
+// notableNames are file base names (without extension) that usually reveal
+// how a package is wired: its routes, API surface, and configuration.
+var notableNames = map[string]bool{
+	"routes": true, "router": true, "routing": true,
+	"api": true, "client": true, "endpoints": true, "urls": true,
+	"auth": true, "permissions": true, "middleware": true,
+	"config": true, "constants": true, "env": true, "settings": true,
+	"schema": true, "resolvers": true, "store": true, "server": true,
+}
+
+// Package is an internal package of a monorepo-style source tree.
+type Package struct {
+	Name    string   `json:"name"` // Directory name, including the scope for @scope/name
+	Dir     string   `json:"dir"`  // Slash-separated, relative to the restored sources
+	Files   int      `json:"files"`
+	Bytes   int64    `json:"bytes"`
+	Notable []string `json:"notable,omitempty"` // Paths relative to Dir, e.g. src/routes.ts
+}
+
+// Packages finds internal packages in a restore manifest: children of
+// packages/, apps/, libs/ and similar workspace directories, and any
+// directory with its own package.json when the tree has more than one.
+// Vendor files are ignored. Each file is counted toward the innermost
+// package containing it. Nothing is returned for a tree with a single
+// package.json and no workspace directories.
+func Packages(files []File) []Package {
+	var first []File
+	for _, f := range files {
+		if !IsVendor(f.Path) {
+			first = append(first, f)
+		}
+	}
+
+	dirs := make(map[string]bool)
+	manifests := make(map[string]bool)
+	for _, f := range first {
+		if dir := workspacePackage(f.Path); dir != "" {
+			dirs[dir] = true
+		}
+		if path.Base(f.Path) == "package.json" {
+			manifests[path.Dir(f.Path)] = true
+		}
+	}
+	if len(manifests) > 1 {
+		for dir := range manifests {
+			if dir != "." {
+				dirs[dir] = true
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	byDir := make(map[string]*Package, len(dirs))
+	for dir := range dirs {
+		byDir[dir] = &Package{Name: packageName(dir), Dir: dir}
+	}
+	for _, f := range first {
+		dir := innermost(f.Path, dirs)
+		if dir == "" {
+			continue
+		}
+		p := byDir[dir]
+		p.Files++
+		p.Bytes += f.Bytes
+		rel := strings.TrimPrefix(f.Path, dir+"/")
+		base := strings.ToLower(strings.TrimSuffix(path.Base(rel), path.Ext(rel)))
+		if notableNames[base] && len(p.Notable) < maxNotable {
+			p.Notable = append(p.Notable, rel)
+		}
+	}
+
+	out := make([]Package, 0, len(byDir))
+	for _, p := range byDir {
+		if p.Files == 0 {
+			continue
+		}
+		sort.Strings(p.Notable)
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Dir < out[j].Dir })
+	return out
+}
+
+// workspacePackage returns the package directory for a file under a
+// workspace directory, e.g. "packages/billing" for
+// "packages/billing/src/index.ts", or "" if there is none.
+func workspacePackage(p string) string {
+	segs := strings.Split(p, "/")
+	// The last segment is the file; a package needs a directory of its own
+	for i := 0; i+2 < len(segs); i++ {
+		if !workspaceDirs[segs[i]] {
+			continue
+		}
+		end := i + 2
+		if strings.HasPrefix(segs[i+1], "@") {
+			if i+3 >= len(segs) {
+				return ""
+			}
+			end = i + 3
+		}
+		return strings.Join(segs[:end], "/")
+	}
+	return ""
+}
+
+// innermost returns the deepest package directory containing p.
+func innermost(p string, dirs map[string]bool) string {
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if dirs[dir] {
+			return dir
+		}
+	}
+	return ""
+}
+
+// packageName names a package after its directory, keeping the scope of a
+// scoped directory.
+func packageName(dir string) string {
+	name := path.Base(dir)
+	if parent := path.Base(path.Dir(dir)); strings.HasPrefix(parent, "@") {
+		return parent + "/" + name
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackages(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []Package
+	}{
+		{
+			name: "packages",
+			files: []string{
+				"packages/billing/package.json",
+				"packages/billing/src/index.ts",
+				"packages/billing/src/routes.ts",
+				"packages/billing/src/Config.tsx",
+				"packages/ui/src/Button.tsx",
+				"packages/README.md",
+			},
+			want: []Package{
+				{Name: "billing", Dir: "packages/billing", Files: 4, Bytes: 40, Notable: []string{"src/Config.tsx", "src/routes.ts"}},
+				{Name: "ui", Dir: "packages/ui", Files: 1, Bytes: 10},
+			},
+		},
+		{
+			name: "apps and libs",
+			files: []string{
+				"apps/web/src/api/client.ts",
+				"apps/web/src/main.ts",
+				"libs/internal-auth/auth.ts",
+				"src/shared.ts",
+			},
+			want: []Package{
+				{Name: "web", Dir: "apps/web", Files: 2, Bytes: 20, Notable: []string{"src/api/client.ts"}},
+				{Name: "internal-auth", Dir: "libs/internal-auth", Files: 1, Bytes: 10, Notable: []string{"auth.ts"}},
+			},
+		},
+		{
+			name: "nested workspace",
+			files: []string{
+				"frontend/packages/store/store.ts",
+			},
+			want: []Package{
+				{Name: "store", Dir: "frontend/packages/store", Files: 1, Bytes: 10, Notable: []string{"store.ts"}},
+			},
+		},
+		{
+			name: "scoped",
+			files: []string{
+				"packages/@acme/ui/package.json",
+				"packages/@acme/ui/src/index.ts",
+				"packages/@acme/index.ts", // No package directory under the scope
+			},
+			want: []Package{
+				{Name: "@acme/ui", Dir: "packages/@acme/ui", Files: 2, Bytes: 20},
+			},
+		},
+		{
+			name: "package.json sources",
+			files: []string{
+				"package.json",
+				"index.js",
+				"server/package.json",
+				"server/urls.py",
+				"web/@acme/app/package.json",
+				"web/@acme/app/main.js",
+				"web/@acme/app/plugins/map/package.json",
+				"web/@acme/app/plugins/map/index.js",
+			},
+			want: []Package{
+				{Name: "server", Dir: "server", Files: 2, Bytes: 20, Notable: []string{"urls.py"}},
+				{Name: "@acme/app", Dir: "web/@acme/app", Files: 2, Bytes: 20},
+				{Name: "map", Dir: "web/@acme/app/plugins/map", Files: 2, Bytes: 20},
+			},
+		},
+		{
+			name: "single package.json",
+			files: []string{
+				"package.json",
+				"src/routes.ts",
+			},
+		},
+		{
+			name: "vendor",
+			files: []string{
+				"package.json",
+				"src/index.ts",
+				"node_modules/react/package.json",
+				"node_modules/@babel/runtime/package.json",
+				"node_modules/lib/packages/core/index.js",
+				"packages/api/node_modules/lodash/package.json",
+				"packages/api/node_modules/lodash/lodash.js",
+				"packages/api/api.ts",
+				"vendor/packages/jquery/jquery.js",
+			},
+			want: []Package{
+				{Name: "api", Dir: "packages/api", Files: 1, Bytes: 10, Notable: []string{"api.ts"}},
+			},
+		},
+		{
+			name: "only vendor",
+			files: []string{
+				"node_modules/a/package.json",
+				"node_modules/b/package.json",
+				"node_modules/apps/x/index.js",
+			},
+		},
+	}
+	for _, tt := range tests {
+		files := make([]File, len(tt.files))
+		for i, p := range tt.files {
+			files[i] = File{Path: p, Bytes: 10}
+		}
+		if got := Packages(files); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Packages = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPackagesNotableCap(t *testing.T) {
+	var files []File
+	for _, dir := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		files = append(files, File{Path: "packages/core/" + dir + "/routes.ts"})
+	}
+	got := Packages(files)
+	if len(got) != 1 || got[0].Files != 12 || len(got[0].Notable) != maxNotable {
+		t.Errorf("Packages = %+v, want 12 files and %d notable", got, maxNotable)
+	}
+}
@@ -0,0 +1,225 @@
+// Package stats summarizes restored sources by language, size, and origin.
+package stats
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Languages in the order they are reported.
+const (
+	LangTS     = "TS"
+	LangTSX    = "TSX"
+	LangJS     = "JS"
+	LangJSX    = "JSX"
+	LangCSS    = "CSS"
+	LangSCSS   = "SCSS"
+	LangJSON   = "JSON"
+	LangVue    = "Vue"
+	LangSvelte = "Svelte"
+	LangOther  = "other"
+)
+
+var languageOrder = []string{LangTS, LangTSX, LangJS, LangJSX, LangCSS, LangSCSS, LangJSON, LangVue, LangSvelte, LangOther}
+
+var extensionLanguages = map[string]string{
+	".ts":     LangTS,
+	".mts":    LangTS,
+	".cts":    LangTS,
+	".tsx":    LangTSX,
+	".js":     LangJS,
+	".mjs":    LangJS,
+	".cjs":    LangJS,
+	".jsx":    LangJSX,
+	".css":    LangCSS,
+	".scss":   LangSCSS,
+	".sass":   LangSCSS,
+	".json":   LangJSON,
+	".vue":    LangVue,
+	".svelte": LangSvelte,
+}
+
+// componentBlockRe matches the file a component block is restored to,
+// e.g. App.vue.script.ts or App.svelte.style.1.scss.
+var componentBlockRe = regexp.MustCompile(`^(.+\.(?:vue|svelte))\.(?:script|template|style|custom)(?:\.\d+)?\.[^./]+$`)
+
+// vendorDirs are path segments that mark third-party code.
+var vendorDirs = map[string]bool{
+	"node_modules":     true,
+	"bower_components": true,
+	"jspm_packages":    true,
+	"vendor":           true,
+}
+
+// DefaultLargest is how many of the largest files Compute keeps.
+const DefaultLargest = 10
+
+// File is a restored source file: a restore manifest entry.
+type File struct {
+	Path   string `json:"path"` // Slash-separated, relative to the restored sources
+	Bytes  int64  `json:"bytes"`
+	Binary bool   `json:"binary,omitempty"` // Written byte for byte, e.g. wasm or an image
+
+	BOMStripped  bool   `json:"bom_stripped,omitempty"`
+	EOLConverted string `json:"eol_converted,omitempty"` // Line endings rewritten to "lf" or "crlf"
+	Unformatted  string `json:"unformatted,omitempty"`   // Why a JS/TS source wasn't pretty-printed, an Unformatted* reason
+}
+
+// Reasons a JS/TS source is written without pretty-printing.
+const (
+	UnformattedVendor = "vendor" // Vendor code, formatted only with -format-vendor
+	UnformattedSize   = "size"   // Larger than -format-max-size
+	UnformattedError  = "error"  // The formatter failed on it
+)
+
+// Count is a file count and byte total.
+type Count struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Language is the Count for one language.
+type Language struct {
+	Name string `json:"name"`
+	Count
+}
+
+// Stats summarizes a set of restored sources.
+type Stats struct {
+	Total      Count      `json:"total"`
+	Languages  []Language `json:"languages"` // Only languages with files, in report order
+	FirstParty Count      `json:"first_party"`
+	Vendor     Count      `json:"vendor"` // node_modules and similar
+	Largest    []File     `json:"largest"`
+	Packages   []Package  `json:"packages,omitempty"`   // Internal monorepo packages
+	Components int        `json:"components,omitempty"` // Vue and Svelte single-file components
+}
+
+// Compute summarizes a restore manifest. Later entries for the same path
+// replace earlier ones, since a later map overwrites the file on disk.
+func Compute(files []File) *Stats {
+	latest := make(map[string]File, len(files))
+	for _, f := range files {
+		latest[f.Path] = f
+	}
+
+	s := &Stats{}
+	byLang := make(map[string]*Count)
+	all := make([]File, 0, len(latest))
+	components := make(map[string]bool)
+
+	for p, f := range latest {
+		n := f.Bytes
+		all = append(all, f)
+		add(&s.Total, n)
+
+		lang := LanguageOf(p)
+		if byLang[lang] == nil {
+			byLang[lang] = &Count{}
+		}
+		add(byLang[lang], n)
+
+		if IsVendor(p) {
+			add(&s.Vendor, n)
+		} else {
+			add(&s.FirstParty, n)
+		}
+		if c := componentOf(p); c != "" {
+			components[c] = true
+		}
+	}
+	s.Components = len(components)
+
+	for _, name := range languageOrder {
+		if c := byLang[name]; c != nil {
+			s.Languages = append(s.Languages, Language{Name: name, Count: *c})
+		}
+	}
+
+	s.Packages = Packages(all)
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Bytes != all[j].Bytes {
+			return all[i].Bytes > all[j].Bytes
+		}
+		return all[i].Path < all[j].Path
+	})
+	if len(all) > DefaultLargest {
+		all = all[:DefaultLargest]
+	}
+	s.Largest = all
+
+	return s
+}
+
+// FromDirectory builds a manifest from the files under dir and summarizes
+// it. Only file metadata is read, not contents. Dotfiles such as the
+// generated .env and a snapshot's .git directory are skipped.
+func FromDirectory(dir string) (*Stats, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files = append(files, File{Path: filepath.ToSlash(rel), Bytes: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Compute(files), nil
+}
+
+// LanguageOf classifies a path by extension.
+func LanguageOf(p string) string {
+	if lang, ok := extensionLanguages[strings.ToLower(path.Ext(p))]; ok {
+		return lang
+	}
+	return LangOther
+}
+
+// componentOf returns the Vue or Svelte component a restored file is or
+// is a block of, or "" if it is neither.
+func componentOf(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".vue", ".svelte":
+		return p
+	}
+	if m := componentBlockRe.FindStringSubmatch(p); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// IsVendor reports whether a path is inside a third-party package directory.
+func IsVendor(p string) bool {
+	for _, seg := range strings.Split(p, "/") {
+		if vendorDirs[seg] {
+			return true
+		}
+	}
+	return false
+}
+
+func add(c *Count, n int64) {
+	c.Files++
+	c.Bytes += n
+}
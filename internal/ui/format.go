@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FormatCount renders n with thousands separators, e.g. "12,438".
+func FormatCount[T ~int | ~int64](n T) string {
+	s := strconv.FormatInt(int64(n), 10)
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+// FormatBytes renders a byte count in binary units, e.g. "1.5 MiB", or "?"
+// if n is negative (unknown).
+func FormatBytes(n int64) string {
+	if n < 0 {
+		return "?"
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatDuration renders d in its two largest units, e.g. "850ms", "12.3s",
+// "4m5s", or "6h", without the zero units time.Duration.String keeps.
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return joinUnits(int64(d/time.Minute), "m", int64(d%time.Minute/time.Second), "s")
+	default:
+		d = d.Round(time.Minute)
+		return joinUnits(int64(d/time.Hour), "h", int64(d%time.Hour/time.Minute), "m")
+	}
+}
+
+// joinUnits renders "<major><unit>" followed by "<minor><unit>" when minor
+// isn't zero.
+func joinUnits(major int64, majorUnit string, minor int64, minorUnit string) string {
+	s := FormatCount(major) + majorUnit
+	if minor != 0 {
+		s += strconv.FormatInt(minor, 10) + minorUnit
+	}
+	return s
+}
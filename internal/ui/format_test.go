@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{12438, "12,438"},
+		{1234567, "1,234,567"},
+		{-1234, "-1,234"},
+		{-999, "-999"},
+	}
+	for _, tt := range tests {
+		if got := FormatCount(tt.n); got != tt.want {
+			t.Errorf("FormatCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{-1, "?"},
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{100 << 20, "100.0 MiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{850 * time.Millisecond, "850ms"},
+		{12345 * time.Millisecond, "12.3s"},
+		{4*time.Minute + 5*time.Second, "4m5s"},
+		{3 * time.Minute, "3m"},
+		{6 * time.Hour, "6h"},
+		{6*time.Hour + 30*time.Minute, "6h30m"},
+		{1500 * time.Hour, "1,500h"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSummaryLineWidth(t *testing.T) {
+	values := []interface{}{12438, 7, int64(1234567), "skipped (-no-restore)"}
+	width := SummaryWidth(values...)
+	if width != len("1,234,567") {
+		t.Fatalf("SummaryWidth = %d, want %d", width, len("1,234,567"))
+	}
+
+	var lines []string
+	for _, v := range values {
+		lines = append(lines, SummaryLineWidth("Label:", v, width))
+	}
+	want := strings.Join([]string{
+		"  Label:                12,438",
+		"  Label:                     7",
+		"  Label:             1,234,567",
+		"  Label:             skipped (-no-restore)",
+	}, "\n")
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("lines =\n%s\nwant\n%s", got, want)
+	}
+}
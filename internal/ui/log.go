@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Levels between Info and Warn for messages that are styled apart from
+// plain information but shown at the same verbosity.
+const (
+	LevelSuccess = slog.LevelInfo + 1 // A step finished, styled like Success
+	LevelNotice  = slog.LevelInfo + 2 // A minor problem, styled like Warning
+)
+
+// LogHandler renders slog records as styled terminal messages, e.g.
+// "[+] Downloaded script url=https://example.com/app.js". The record's
+// attributes follow the message as dimmed key=value pairs.
+type LogHandler struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	level slog.Leveler
+	attrs []string // Formatted attributes added with WithAttrs
+	group string
+}
+
+// NewLogHandler returns a handler writing records at level or above to
+// out. A nil out writes to os.Stdout as it is when each record is
+// handled, so output follows a later redirect of os.Stdout.
+func NewLogHandler(out io.Writer, level slog.Leveler) *LogHandler {
+	return &LogHandler{mu: &sync.Mutex{}, out: out, level: level}
+}
+
+// WithOutput returns a handler like h that writes to out.
+func (h *LogHandler) WithOutput(out io.Writer) *LogHandler {
+	h2 := *h
+	h2.out = out
+	return &h2
+}
+
+// Enabled reports whether records at l are written.
+func (h *LogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+// Handle writes r as one line.
+func (h *LogHandler) Handle(_ context.Context, r slog.Record) error {
+	var line string
+	switch {
+	case r.Level >= slog.LevelError:
+		line = Error(r.Message)
+	case r.Level >= LevelNotice:
+		line = Warning(r.Message)
+	case r.Level >= LevelSuccess:
+		line = Success(r.Message)
+	case r.Level >= slog.LevelInfo:
+		line = Info(r.Message)
+	default:
+		line = Debug(r.Message)
+	}
+
+	kv := append([]string{}, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		kv = append(kv, h.format(a))
+		return true
+	})
+	if len(kv) > 0 {
+		line += " " + DimStyle.Render(strings.Join(kv, " "))
+	}
+
+	out := h.out
+	if out == nil {
+		out = os.Stdout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintln(out, line)
+	return err
+}
+
+// format renders a as key=value, quoting values with spaces.
+func (h *LogHandler) format(a slog.Attr) string {
+	key := a.Key
+	if h.group != "" {
+		key = h.group + "." + key
+	}
+	value := a.Value.Resolve().String()
+	if value == "" || strings.ContainsAny(value, " \t\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	return key + "=" + value
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]string{}, h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, h.format(a))
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that prefixes the keys of later attributes
+// with name.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+	h2.group = name
+	return &h2
+}
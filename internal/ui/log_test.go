@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewLogHandler(&buf, slog.LevelInfo))
+
+	log.Info("Downloaded script", "url", "https://example.com/app.js", "path", "a b.js")
+	log.Debug("Hidden below debug", "count", 1)
+	log.With("host", "example.com").Log(context.Background(), LevelSuccess, "Restored sources", "count", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		`Downloaded script url=https://example.com/app.js path="a b.js"`,
+		`Restored sources host=example.com count=3`,
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("line %d = %q, want it to contain %q", i, lines[i], want)
+		}
+	}
+}
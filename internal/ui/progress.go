@@ -25,6 +25,7 @@ type progressModel struct {
 
 type tickMsg time.Time
 type updateMsg int
+type messageMsg string
 type quitMsg struct{}
 
 func (m progressModel) Init() tea.Cmd {
@@ -62,6 +63,10 @@ func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd := m.progress.SetPercent(m.percent)
 		return m, tea.Batch(cmd, m.waitForUpdate())
 
+	case messageMsg:
+		m.message = string(msg)
+		return m, nil
+
 	case quitMsg:
 		m.done = true
 		return m, tea.Quit
@@ -150,6 +155,11 @@ func (p *Progress) SetCurrent(n int) {
 	}
 }
 
+// SetMessage replaces the text shown before the bar
+func (p *Progress) SetMessage(message string) {
+	p.program.Send(messageMsg(message))
+}
+
 // Done completes the progress bar
 func (p *Progress) Done() {
 	p.current = p.total
@@ -3,9 +3,11 @@ package ui
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
 )
 
 // Cyberpunk 2077 / Luxium color palette
@@ -83,6 +85,7 @@ var (
 	PrefixSuccess = SuccessStyle.Render("[+]")
 	PrefixWarning = WarningStyle.Render("[-]")
 	PrefixError   = ErrorStyle.Render("[!]")
+	PrefixDebug   = DimStyle.Render("[.]")
 )
 
 // Banner returns the styled banner with version
@@ -113,16 +116,57 @@ func Error(msg string) string {
 	return fmt.Sprintf("%s %s", PrefixError, TextStyle.Render(msg))
 }
 
+// Debug renders a debug message with dim prefix and pink body
+func Debug(msg string) string {
+	return fmt.Sprintf("%s %s", PrefixDebug, TextStyle.Render(msg))
+}
+
 // Target formats a target URL/path with styled output
 func Target(target string) string {
 	return fmt.Sprintf("%s %s %s\n", PrefixInfo, TextStyle.Render("Target:"), URLStyle.Render(target))
 }
 
-// SummaryLine formats a summary line with label and value
+// SummaryLine formats a summary line with label and value. Integer values
+// get thousands separators.
 func SummaryLine(label string, value interface{}) string {
+	return SummaryLineWidth(label, value, 0)
+}
+
+// SummaryLineWidth is SummaryLine with an integer value right-aligned in a
+// column width characters wide, so the counts of consecutive lines line up.
+// Other values are left-aligned as usual.
+func SummaryLineWidth(label string, value interface{}, width int) string {
+	text, numeric := SummaryValue(value)
+	if numeric {
+		text = fmt.Sprintf("%*s", width, text)
+	}
 	return fmt.Sprintf("  %s %s",
 		LabelStyle.Render(fmt.Sprintf("%-18s", label)),
-		ValueStyle.Render(fmt.Sprintf("%v", value)))
+		ValueStyle.Render(text))
+}
+
+// SummaryValue formats value as SummaryLine shows it, and reports whether
+// it is an integer, which SummaryLineWidth aligns.
+func SummaryValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case int:
+		return FormatCount(v), true
+	case int64:
+		return FormatCount(v), true
+	}
+	return fmt.Sprintf("%v", value), false
+}
+
+// SummaryWidth returns the column width that right-aligns the integer
+// values among values.
+func SummaryWidth(values ...interface{}) int {
+	width := 0
+	for _, v := range values {
+		if text, numeric := SummaryValue(v); numeric {
+			width = max(width, len(text))
+		}
+	}
+	return width
 }
 
 // SummaryHeader returns the summary section header
@@ -130,10 +174,26 @@ func SummaryHeader() string {
 	return fmt.Sprintf("\n%s %s", PrefixInfo, AccentStyle.Render("Summary"))
 }
 
-// RenderSummaryBox wraps content in a styled summary box
+// RenderSummaryBox wraps content in a styled summary box. If the box would
+// be wider than the terminal, the lines are returned unboxed so that long
+// values wrap instead of breaking the border.
 func RenderSummaryBox(lines ...string) string {
 	content := lipgloss.JoinVertical(lipgloss.Left, lines...)
-	return SummaryBoxStyle.Render(content)
+	box := SummaryBoxStyle.Render(content)
+	if width := TerminalWidth(); width > 0 && lipgloss.Width(box) > width {
+		return "\n" + strings.Join(lines, "\n")
+	}
+	return box
+}
+
+// TerminalWidth returns the width of the terminal on stdout, or 0 if stdout
+// is not a terminal.
+func TerminalWidth() int {
+	width, _, err := term.GetSize(os.Stdout.Fd())
+	if err != nil {
+		return 0
+	}
+	return width
 }
 
 // FormatUsage styles a usage string, applying different colors to:
@@ -0,0 +1,165 @@
+package viewer
+
+// pageHead starts the page; its one verb is the HTML-escaped title. The
+// Content-Security-Policy keeps the page from loading anything, even if
+// escaping were ever bypassed.
+const pageHead = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="Content-Security-Policy" content="default-src 'none'; style-src 'unsafe-inline'; script-src 'unsafe-inline'; img-src data:">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<style>
+body { margin: 0; font: 14px/1.4 system-ui, sans-serif; color: #222; background: #fafafa; display: flex; height: 100vh; }
+#side { width: 34em; min-width: 16em; overflow: auto; border-right: 1px solid #ddd; padding: 0.5em; box-sizing: border-box; resize: horizontal; }
+#main { flex: 1; overflow: auto; padding: 0.5em 1em; }
+#search { width: 100%%; box-sizing: border-box; padding: 0.3em; margin-bottom: 0.5em; }
+details { margin-left: 0.9em; }
+summary { cursor: pointer; }
+.file { display: block; margin-left: 0.9em; cursor: pointer; white-space: nowrap; color: #0645ad; }
+.file.linked { color: #555; }
+.file.sel { background: #e0e8ff; }
+.file .n { color: #c00; font-size: 0.85em; }
+.size { color: #888; font-size: 0.85em; }
+pre { background: #fff; border: 1px solid #ddd; padding: 0.5em; overflow: auto; font: 12px/1.4 ui-monospace, monospace; counter-reset: line; }
+pre span { display: block; }
+pre span::before { counter-increment: line; content: counter(line); display: inline-block; width: 4em; color: #aaa; text-align: right; margin-right: 1em; }
+pre span.hit { background: #fff3b0; }
+table { border-collapse: collapse; width: 100%%; }
+td, th { text-align: left; padding: 0.2em 0.5em; border-bottom: 1px solid #eee; vertical-align: top; }
+td.m { font-family: ui-monospace, monospace; word-break: break-all; }
+a { cursor: pointer; }
+h1 { font-size: 1.2em; }
+h2 { font-size: 1.05em; }
+</style>
+</head>
+<body>
+<div id="side"><input id="search" type="search" placeholder="Filter paths"><div id="tree"></div></div>
+<div id="main"><h1 id="title"></h1><p id="stats"></p><div id="view"></div><h2>Findings</h2><div id="findings"></div></div>
+`
+
+// pageTail ends the page after the data elements: the script that renders
+// them. Everything from the data is inserted with textContent or as an
+// attribute value, never parsed as HTML.
+const pageTail = `<script>
+(function () {
+  "use strict";
+  function data(id) { return JSON.parse(document.getElementById(id).textContent); }
+  function el(tag, cls, text) {
+    var e = document.createElement(tag);
+    if (cls) e.className = cls;
+    if (text !== undefined) e.textContent = text;
+    return e;
+  }
+  function size(n) {
+    if (n < 1024) return n + " B";
+    if (n < 1048576) return (n / 1024).toFixed(1) + " KB";
+    return (n / 1048576).toFixed(1) + " MB";
+  }
+
+  var files = data("manifest"), found = data("findings");
+  var counts = {}, byPath = {};
+  found.forEach(function (f) { if (f.i) counts[f.f] = (counts[f.f] || 0) + 1; });
+  files.forEach(function (f) { byPath[f.p] = f; });
+
+  document.getElementById("title").textContent = document.title;
+  var total = files.reduce(function (n, f) { return n + f.s; }, 0);
+  var embedded = files.filter(function (f) { return f.e >= 0; }).length;
+  document.getElementById("stats").textContent = files.length + " files, " + size(total) +
+    "; " + embedded + " previewable; " + found.length + " findings";
+
+  // Tree of nested objects: subdirectories are under their name prefixed
+  // with "/", a directory's files under the key ""
+  var root = {};
+  files.forEach(function (f) {
+    var node = root, parts = f.p.split("/");
+    for (var i = 0; i < parts.length - 1; i++) {
+      if (!Object.prototype.hasOwnProperty.call(node, "/" + parts[i])) node["/" + parts[i]] = {};
+      node = node["/" + parts[i]];
+    }
+    (node[""] = node[""] || []).push(f);
+  });
+
+  var selected = null, rows = [];
+  function render(node, parent) {
+    Object.keys(node).filter(function (k) { return k !== ""; }).sort().forEach(function (k) {
+      var d = el("details"), s = el("summary", "", k.slice(1));
+      d.appendChild(s);
+      render(node[k], d);
+      parent.appendChild(d);
+    });
+    (node[""] || []).forEach(function (f) {
+      var a = el("a", f.e >= 0 ? "file" : "file linked", f.p.slice(f.p.lastIndexOf("/") + 1));
+      if (f.e < 0) { a.href = f.h; a.target = "_blank"; a.rel = "noopener"; }
+      else a.addEventListener("click", function () { show(f, 0); });
+      a.appendChild(el("span", "size", " " + size(f.s)));
+      if (counts[f.p]) a.appendChild(el("span", "n", " " + counts[f.p]));
+      a.title = f.p;
+      f.row = a;
+      rows.push(a);
+      parent.appendChild(a);
+    });
+  }
+  var tree = document.getElementById("tree");
+  render(root, tree);
+  if (tree.children.length === 1 && tree.firstChild.tagName === "DETAILS") tree.firstChild.open = true;
+
+  function show(f, line) {
+    if (selected) selected.classList.remove("sel");
+    selected = f.row;
+    selected.classList.add("sel");
+    var view = document.getElementById("view");
+    view.textContent = "";
+    view.appendChild(el("h2", "", f.p));
+    var pre = el("pre");
+    var lines = data("f" + f.e).split("\n"), target = null;
+    lines.forEach(function (text, i) {
+      var s = el("span", i + 1 === line ? "hit" : "", text);
+      if (i + 1 === line) target = s;
+      pre.appendChild(s);
+    });
+    view.appendChild(pre);
+    if (target) target.scrollIntoView({ block: "center" });
+    else document.getElementById("main").scrollTop = 0;
+  }
+
+  document.getElementById("search").addEventListener("input", function () {
+    var q = this.value.toLowerCase();
+    rows.forEach(function (a) { a.style.display = !q || a.title.toLowerCase().indexOf(q) >= 0 ? "" : "none"; });
+    tree.querySelectorAll("details").forEach(function (d) {
+      var any = Array.prototype.some.call(d.querySelectorAll("a.file"), function (a) { return a.style.display !== "none"; });
+      d.style.display = any ? "" : "none";
+      if (q) d.open = any;
+    });
+  });
+
+  var list = document.getElementById("findings");
+  if (!found.length) { list.textContent = "None."; return; }
+  var table = el("table"), head = el("tr");
+  ["Rule", "File", "Message"].forEach(function (h) { head.appendChild(el("th", "", h)); });
+  table.appendChild(head);
+  found.forEach(function (f) {
+    var tr = el("tr"), where = el("td");
+    var label = f.f + (f.l ? ":" + f.l : "");
+    var entry = f.i ? byPath[f.f] : null;
+    if (entry && entry.e >= 0) {
+      var a = el("a", "", label);
+      a.addEventListener("click", function () { show(entry, f.l); });
+      where.appendChild(a);
+    } else if (entry || !f.i) {
+      var link = el("a", "", label);
+      link.href = entry ? entry.h : f.f; link.target = "_blank"; link.rel = "noopener";
+      where.appendChild(link);
+    } else where.textContent = label;
+    tr.appendChild(el("td", "", f.r));
+    tr.appendChild(where);
+    tr.appendChild(el("td", "m", f.m));
+    table.appendChild(tr);
+  });
+  list.appendChild(table);
+})();
+</script>
+</body>
+</html>
+`
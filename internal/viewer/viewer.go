@@ -0,0 +1,189 @@
+// Package viewer writes a self-contained HTML page for browsing restored
+// sources: a collapsible file tree, a path search box, previews of small
+// files embedded in the page, and the findings of the run. The page loads
+// nothing from the network and renders file content only as text, since
+// restored sources are untrusted.
+package viewer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thesavant42/dejank/internal/findings"
+)
+
+// Default embedding limits.
+const (
+	DefaultMaxPreview = 64 << 10 // Largest file embedded for preview
+	DefaultBudget     = 16 << 20 // Total file content embedded in one page
+)
+
+// Options configures a page.
+type Options struct {
+	Title      string
+	SourcesDir string // Directory whose files are listed, e.g. restored_sources
+	PageDir    string // Directory the page is written to; files not embedded are linked relative to it
+	MaxPreview int64  // Files up to this size are embedded while Budget lasts (0 = DefaultMaxPreview)
+	Budget     int64  // Total bytes of content embedded (0 = DefaultBudget)
+	Findings   []findings.Finding
+}
+
+// Result reports what a page holds.
+type Result struct {
+	Files    int   // Files listed
+	Bytes    int64 // Their total size
+	Embedded int   // Files whose content is in the page
+	Findings int
+}
+
+// entry is one file of the page's manifest.
+type entry struct {
+	Path  string `json:"p"`           // Slash-separated, relative to SourcesDir
+	Size  int64  `json:"s"`           // Bytes
+	Embed int    `json:"e"`           // Index of the embedded content, -1 if not embedded
+	Href  string `json:"h,omitempty"` // Link to the file, relative to the page
+}
+
+// finding is one finding of the page.
+type finding struct {
+	Rule    string `json:"r"`
+	Message string `json:"m"`
+	File    string `json:"f"` // Manifest path, or the path relative to the page for files outside SourcesDir
+	Line    int    `json:"l,omitempty"`
+	Listed  bool   `json:"i,omitempty"` // File is in the manifest
+}
+
+// Write writes the page for opts to w. File content is streamed into the
+// page one file at a time, so memory use is bounded by MaxPreview rather
+// than by the size of the tree.
+func Write(w io.Writer, opts Options) (Result, error) {
+	if opts.MaxPreview <= 0 {
+		opts.MaxPreview = DefaultMaxPreview
+	}
+	if opts.Budget <= 0 {
+		opts.Budget = DefaultBudget
+	}
+
+	var result Result
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, pageHead, html.EscapeString(opts.Title))
+
+	var files []entry
+	budget := opts.Budget
+	enc := json.NewEncoder(bw)
+	err := filepath.WalkDir(opts.SourcesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, _ := filepath.Rel(opts.SourcesDir, path)
+		e := entry{Path: filepath.ToSlash(rel), Size: info.Size(), Embed: -1}
+		result.Files++
+		result.Bytes += info.Size()
+
+		if info.Size() <= opts.MaxPreview && info.Size() <= budget {
+			if content, ok := readText(path); ok {
+				e.Embed = result.Embedded
+				fmt.Fprintf(bw, `<script type="application/json" id="f%d">`, e.Embed)
+				// Encoder escapes <, >, and & so no content can close the
+				// script element
+				if err := enc.Encode(content); err != nil {
+					return err
+				}
+				bw.WriteString("</script>\n")
+				result.Embedded++
+				budget -= info.Size()
+			}
+		}
+		if e.Embed < 0 {
+			e.Href = relativeHref(opts.PageDir, path)
+		}
+		files = append(files, e)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	listed := make(map[string]bool, len(files))
+	for _, f := range files {
+		listed[f.Path] = true
+	}
+	found := make([]finding, 0, len(opts.Findings))
+	for _, f := range opts.Findings {
+		item := finding{Rule: f.RuleID, Message: f.Message, Line: f.Line}
+		if rel, err := filepath.Rel(opts.SourcesDir, f.File); err == nil && !strings.HasPrefix(rel, "..") && listed[filepath.ToSlash(rel)] {
+			item.File, item.Listed = filepath.ToSlash(rel), true
+		} else {
+			item.File = relativeHref(opts.PageDir, f.File)
+		}
+		found = append(found, item)
+	}
+	result.Findings = len(found)
+
+	if err := writeData(bw, "manifest", files); err != nil {
+		return result, err
+	}
+	if err := writeData(bw, "findings", found); err != nil {
+		return result, err
+	}
+	bw.WriteString(pageTail)
+	return result, bw.Flush()
+}
+
+// writeData embeds v as JSON in a script element with the given id.
+func writeData(w *bufio.Writer, id string, v interface{}) error {
+	fmt.Fprintf(w, `<script type="application/json" id="%s">`, id)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return err
+	}
+	_, err := w.WriteString("</script>\n")
+	return err
+}
+
+// readText reads the file at path, reporting false for binary content.
+func readText(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data, 0) != -1 || !utf8.Valid(data) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// relativeHref returns a URL path to target relative to dir. It always
+// starts with "./" or "../", so a file name like "javascript:x" can't be
+// read as a URL scheme.
+func relativeHref(dir, target string) string {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		rel = target
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	href := strings.Join(parts, "/")
+	if !strings.HasPrefix(href, "../") {
+		href = "./" + href
+	}
+	return href
+}
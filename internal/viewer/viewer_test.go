@@ -0,0 +1,172 @@
+package viewer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/thesavant42/dejank/internal/findings"
+)
+
+// dataElement matches the JSON data elements of a page.
+var dataElement = regexp.MustCompile(`(?s)<script type="application/json" id="([^"]*)">(.*?)</script>`)
+
+// render writes the page for opts and returns it with its data elements
+// by id.
+func render(t *testing.T, opts Options) (string, Result, map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	result, err := Write(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make(map[string]string)
+	for _, m := range dataElement.FindAllStringSubmatch(buf.String(), -1) {
+		data[m[1]] = m[2]
+	}
+	return buf.String(), result, data
+}
+
+func manifest(t *testing.T, data map[string]string) []entry {
+	t.Helper()
+	var files []entry
+	if err := json.Unmarshal([]byte(data["manifest"]), &files); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	return files
+}
+
+func writeSources(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriteEscaping(t *testing.T) {
+	root := t.TempDir()
+	sources := filepath.Join(root, "restored_sources")
+	hostile := "const s = \"</script><script>alert(1)</script>\";\n<!-- <script>\nconst sep = \"\u2028\u2029\";\n"
+	writeSources(t, sources, map[string]string{
+		"src/evil.js":      hostile,
+		"javascript:x":     "x",
+		`a"b<`:             "y",
+		"</script>.js":     "z",
+		"node_modules/big": strings.Repeat("a", 100),
+	})
+
+	page, result, data := render(t, Options{
+		Title:      "</title><script>alert(1)</script>",
+		SourcesDir: sources,
+		PageDir:    root,
+		MaxPreview: 90,
+		Findings: []findings.Finding{
+			{RuleID: findings.RuleSecret, Message: "</script><!--", File: filepath.Join(sources, "src", "evil.js"), Line: 1},
+		},
+	})
+	if result.Files != 5 || result.Embedded != 4 || result.Findings != 1 {
+		t.Errorf("Result = %+v, want 5 files, 4 embedded, 1 finding", result)
+	}
+
+	// Only the page's own script and the data elements open or close a
+	// script, and nothing opens a comment
+	if n := strings.Count(strings.ToLower(page), "</script"); n != len(data)+1 {
+		t.Errorf("page closes %d script elements, want %d", n, len(data)+1)
+	}
+	if strings.Contains(page, "<!--") {
+		t.Error("page contains a comment opener")
+	}
+	if strings.Contains(page, "<title></title>") || !strings.Contains(page, "&lt;/title&gt;") {
+		t.Error("title not escaped")
+	}
+	for id, content := range data {
+		if strings.ContainsAny(content, "<>&\u2028\u2029") {
+			t.Errorf("data element %s holds unescaped text: %q", id, content)
+		}
+	}
+
+	files := manifest(t, data)
+	byPath := make(map[string]entry)
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+	evil, ok := byPath["src/evil.js"]
+	if !ok || evil.Embed < 0 {
+		t.Fatalf("src/evil.js not embedded: %+v", files)
+	}
+	var content string
+	if err := json.Unmarshal([]byte(data["f"+strconv.Itoa(evil.Embed)]), &content); err != nil || content != hostile {
+		t.Errorf("embedded content = %q, %v, want it unchanged", content, err)
+	}
+	for _, name := range []string{"javascript:x", `a"b<`, "</script>.js"} {
+		if _, ok := byPath[name]; !ok {
+			t.Errorf("%q not listed", name)
+		}
+	}
+
+	// A file that isn't embedded is linked relative to the page, never as
+	// a URL with a scheme
+	big := byPath["node_modules/big"]
+	if big.Embed != -1 || big.Href != "./restored_sources/node_modules/big" {
+		t.Errorf("node_modules/big = %+v, want a relative link", big)
+	}
+	for name, want := range map[string]string{
+		"javascript:x": "./javascript:x",
+		`a"b<`:         "./a%22b%3C",
+	} {
+		if got := relativeHref(sources, filepath.Join(sources, name)); got != want {
+			t.Errorf("relativeHref(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteLimits(t *testing.T) {
+	root := t.TempDir()
+	writeSources(t, root, map[string]string{
+		"a.js":       strings.Repeat("a", 40),
+		"b.js":       strings.Repeat("b", 40),
+		"c.js":       strings.Repeat("c", 10),
+		"d.js":       strings.Repeat("d", 200),
+		"image.png":  "\x89PNG\x00\x00",
+		"latin1.txt": "caf\xe9",
+	})
+
+	// Files are walked in name order: a and b fill most of the budget, c
+	// still fits, d is over MaxPreview, and binary files are never embedded
+	_, result, data := render(t, Options{SourcesDir: root, PageDir: root, MaxPreview: 100, Budget: 90})
+	if result.Files != 6 || result.Embedded != 3 || result.Bytes != 40+40+10+200+6+4 {
+		t.Errorf("Result = %+v, want 6 files, 3 embedded", result)
+	}
+	want := map[string]bool{"a.js": true, "b.js": true, "c.js": true, "d.js": false, "image.png": false, "latin1.txt": false}
+	for _, f := range manifest(t, data) {
+		embedded := f.Embed >= 0
+		if embedded != want[f.Path] {
+			t.Errorf("%s embedded = %v, want %v", f.Path, embedded, want[f.Path])
+		}
+		if embedded == (f.Href != "") {
+			t.Errorf("%s: embedded %v with href %q", f.Path, embedded, f.Href)
+		}
+	}
+
+	// Once the budget runs out, every later file is linked
+	_, result, data = render(t, Options{SourcesDir: root, PageDir: root, MaxPreview: 100, Budget: 50})
+	if result.Embedded != 2 {
+		t.Errorf("Embedded = %d with a budget of 50, want a and c", result.Embedded)
+	}
+	for _, f := range manifest(t, data) {
+		if f.Path == "b.js" && (f.Embed >= 0 || f.Href != "./b.js") {
+			t.Errorf("b.js = %+v, want a link once the budget is spent", f)
+		}
+	}
+}
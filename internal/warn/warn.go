@@ -0,0 +1,73 @@
+// Package warn describes non-fatal problems found during a run: conditions
+// that were worked around or skipped rather than reported as errors.
+package warn
+
+import "fmt"
+
+// Category groups warnings of the same kind.
+type Category string
+
+// Warning categories.
+const (
+	StubSkipped      Category = "stub_skipped"      // Media source holding a bundler stub, not written
+	FormatFallback   Category = "format_fallback"   // Source written unformatted after the formatter failed
+	FormatSkipped    Category = "format_skipped"    // Source over -format-max-size written unformatted
+	Filtered         Category = "filtered"          // Script or map excluded by -include-url/-exclude-url
+	OutOfScope       Category = "out_of_scope"      // URL not fetched because -scope-file doesn't list its host
+	MapRedirected    Category = "map_redirected"    // Sourcemap served from a different URL
+	TargetRedirected Category = "target_redirected" // Target page redirected to a different host
+	Hook             Category = "hook"              // Hook that failed or was cancelled
+	Robots           Category = "robots"            // robots.txt that could not be fetched
+	Sitemap          Category = "sitemap"           // Sitemap or sitemap page that could not be loaded
+	Navigation       Category = "navigation"        // Page that never finished loading in the browser; the scripts it requested were used
+	Emulation        Category = "emulation"         // Mobile pass of -emulate both that could not load the page
+	Console          Category = "console"           // Browser console error or warning about a script or a failed load
+	PageSnapshot     Category = "page_snapshot"     // Screenshot or rendered DOM that could not be captured or saved
+	Interaction      Category = "interaction"       // -interact or -auto-scroll step that failed
+	Coverage         Category = "coverage"          // Coverage that could not be collected
+	Modules          Category = "modules"           // Module dump that was incomplete
+	CAS              Category = "cas"               // Content store that could not deduplicate
+	Checksum         Category = "checksum"          // Download modified or removed since its SHA-256 was recorded
+	MapFormat        Category = "map_format"        // Sourcemap of an unsupported version or with missing or stripped fields
+	Import           Category = "import"            // Proxy export item whose response could not be fully decoded
+	MapMismatch      Category = "map_mismatch"      // Sourcemap whose mappings don't agree with its script (-verify)
+	DuplicateDomain  Category = "duplicate_domain"  // Domain directory that likely holds the same site as another
+	MergeConflict    Category = "merge_conflict"    // Download that differs between merged domain directories, kept under both names
+	EnvConflict      Category = "env_conflict"      // Environment variable inlined with different values; the first one found is kept
+	DecoyMap         Category = "decoy_map"         // .map file holding a placeholder or encrypted blob instead of a sourcemap; skipped
+)
+
+// Warning is a non-fatal problem about a subject, such as a URL or a path.
+type Warning struct {
+	Category Category `json:"category"`
+	Subject  string   `json:"subject,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// New returns a warning with a formatted message.
+func New(category Category, subject, format string, args ...interface{}) Warning {
+	return Warning{Category: category, Subject: subject, Message: fmt.Sprintf(format, args...)}
+}
+
+// FromError returns a warning whose message is err's.
+func FromError(category Category, subject string, err error) Warning {
+	return Warning{Category: category, Subject: subject, Message: err.Error()}
+}
+
+// String formats the warning as "subject: message", or just the message
+// when it has no subject.
+func (w Warning) String() string {
+	if w.Subject == "" {
+		return w.Message
+	}
+	return fmt.Sprintf("%s: %s", w.Subject, w.Message)
+}
+
+// Count returns how many warnings fall in each category.
+func Count(warnings []Warning) map[Category]int {
+	counts := make(map[Category]int)
+	for _, w := range warnings {
+		counts[w.Category]++
+	}
+	return counts
+}